@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/app"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/migrate"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/migrations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrationsDir string
+
+	migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema",
+	}
+
+	migrateUpCmd = &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Args:  cobra.NoArgs,
+		Run:   migrateUp,
+	}
+
+	migrateDownCmd = &cobra.Command{
+		Use:   "down [steps]",
+		Short: "Revert the last applied migrations, one unless steps is given",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   migrateDown,
+	}
+
+	migrateStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "List the migrations and whether they are applied",
+		Args:  cobra.NoArgs,
+		Run:   migrateStatus,
+	}
+
+	migrateCreateCmd = &cobra.Command{
+		Use:   "create <name>",
+		Short: "Write empty up and down files for a new migration",
+		Args:  cobra.MinimumNArgs(1),
+		Run:   migrateCreate,
+	}
+)
+
+func init() {
+	migrateCreateCmd.Flags().StringVar(&migrationsDir, "dir", "migrations", "directory of the migration files")
+
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+	migrateCmd.AddCommand(migrateCreateCmd)
+}
+
+func migrateUp(_ *cobra.Command, _ []string) {
+	applied, err := newMigrator().Up(context.Background())
+	for _, migration := range applied {
+		fmt.Println("applied", migration)
+	}
+	if err != nil {
+		log.Fatalf("migration failed: %v", err)
+	}
+	if len(applied) == 0 {
+		fmt.Println("no pending migrations")
+	}
+}
+
+func migrateDown(_ *cobra.Command, args []string) {
+	steps := 1
+	if len(args) == 1 {
+		var err error
+		if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+			log.Fatalf("steps must be a positive number")
+		}
+	}
+
+	reverted, err := newMigrator().Down(context.Background(), steps)
+	for _, migration := range reverted {
+		fmt.Println("reverted", migration)
+	}
+	if err != nil {
+		log.Fatalf("migration failed: %v", err)
+	}
+	if len(reverted) == 0 {
+		fmt.Println("no applied migrations")
+	}
+}
+
+func migrateStatus(_ *cobra.Command, _ []string) {
+	statuses, err := newMigrator().Status(context.Background())
+	if err != nil {
+		log.Fatalf("failed to get migration status: %v", err)
+	}
+
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%-40s %s\n", status.Migration, state)
+	}
+}
+
+func migrateCreate(_ *cobra.Command, args []string) {
+	up, down, err := migrate.Create(migrationsDir, strings.Join(args, "_"))
+	if err != nil {
+		log.Fatalf("failed to create migration: %v", err)
+	}
+	fmt.Println("created", up)
+	fmt.Println("created", down)
+}
+
+func newMigrator() *migrate.Migrator {
+	cfg, err := config.InitConfig("config.yaml")
+	if err != nil {
+		log.Fatalf("failed to initialize config: %v", err)
+	}
+
+	db, err := app.ConnectToDatabase(cfg.Postgres)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+
+	migrator, err := migrate.NewMigrator(db, migrations.FS)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+	return migrator
+}
+
+// the service only runs against the schema it was built for. pending
+// migrations are applied when autoMigrate is set, otherwise they stop the
+// start
+func checkMigrations(db *sqlx.DB, autoMigrate bool) error {
+	migrator, err := migrate.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if autoMigrate {
+		if _, err := migrator.Up(ctx); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		names := make([]string, len(pending))
+		for i, migration := range pending {
+			names[i] = migration.String()
+		}
+		return fmt.Errorf("%d pending migrations (%s), run `migrate up` first", len(pending), strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ var (
 
 func init() {
 	rootCmd.AddCommand(startCmd)
+	rootCmd.AddCommand(migrateCmd)
 }
 
 func Execute() error {
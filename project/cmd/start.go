@@ -7,6 +7,7 @@ import (
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/app"
 	myhttp "github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/image"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/payment"
@@ -43,22 +44,50 @@ func start(_ *cobra.Command, _ []string) {
 		log.Fatalf("failed to initialize logger: %v", err)
 	}
 
+	if err := checkMigrations(db, cfg.Postgres.AutoMigrate); err != nil {
+		log.Fatalf("database schema is not up to date: %v", err)
+	}
+
 	redisClient := app.NewRedisClient(cfg.Redis)
 
-	userRepo := repositories.NewUserRepository(cfg.Postgres.AutoCreate, db)
-	apartmentRepo := repositories.NewApartmentRepository(cfg.Postgres.AutoCreate, db)
-	userApartmentRepo := repositories.NewUserApartmentRepository(cfg.Postgres.AutoCreate, db)
-	inviteLinkRepo := repositories.NewInvitationLinkRepository(redisClient, "invite_salt")
-	billRepo := repositories.NewBillRepository(cfg.Postgres.AutoCreate, db)
-	paymentRepo := repositories.NewPaymentRepository(cfg.Postgres.AutoCreate, db)
+	userRepo := repositories.NewUserRepository(db)
+	apartmentRepo := repositories.NewApartmentRepository(db)
+	userApartmentRepo := repositories.NewUserApartmentRepository(db)
+	inviteLinkRepo := repositories.NewInvitationLinkRepository(db)
+	billRepo := repositories.NewBillRepository(db)
+	paymentRepo := repositories.NewPaymentRepository(db)
+	billTemplateRepo := repositories.NewBillTemplateRepository(db)
+	paymentTransactionRepo := repositories.NewPaymentTransactionRepository(db)
+	installmentRepo := repositories.NewInstallmentRepository(db)
+	lateFeeRepo := repositories.NewLateFeeRepository(db)
+	ledgerRepo := repositories.NewLedgerRepository(db)
+	reportRepo := repositories.NewReportRepository(db)
+	handoverRepo := repositories.NewHandoverRepository(db)
+	unitRepo := repositories.NewUnitRepository(db)
+	outboxRepo := repositories.NewOutboxRepository(db)
+	reminderRepo := repositories.NewReminderRepository(db)
+	notificationTemplateRepo := repositories.NewNotificationTemplateRepository(db)
+	uow := repositories.NewUnitOfWork(db)
+	tokenRepo := repositories.NewTokenRepository(redisClient)
+
+	keys, err := middleware.NewKeySet(cfg.Auth)
+	if err != nil {
+		log.Fatalf("failed to load auth keys: %v", err)
+	}
 
 	notificationService := notification.NewNotification(
-		cfg.TelegramConfig,
 		userRepo,
+		apartmentRepo,
+		notificationTemplateRepo,
+		notificationChannels(cfg, userRepo)...,
 	)
 
 	imageService := image.NewImage(cfg.Minio.Endpoint, cfg.Minio.AccessKey, cfg.Minio.SecretKey, cfg.Minio.Bucket)
-	paymentService := payment.NewPayment(redisClient)
+	paymentService := payment.NewPayment(
+		cfg.Payment,
+		paymentTransactionRepo,
+		paymentProviders(cfg.Payment)...,
+	)
 	httpService := myhttp.NewApartmantService(
 		cfg,
 		db,
@@ -73,6 +102,19 @@ func start(_ *cobra.Command, _ []string) {
 		imageService,
 		paymentRepo,
 		paymentService,
+		billTemplateRepo,
+		installmentRepo,
+		lateFeeRepo,
+		ledgerRepo,
+		reportRepo,
+		handoverRepo,
+		unitRepo,
+		outboxRepo,
+		reminderRepo,
+		notificationTemplateRepo,
+		uow,
+		tokenRepo,
+		keys,
 	)
 
 	if err := httpService.Start("Apartment Service"); err != nil {
@@ -81,6 +123,53 @@ func start(_ *cobra.Command, _ []string) {
 	httpService.WaitForShutdown()
 }
 
+// the channels configured in cfg. a channel that can't start is left out
+// instead of stopping the service, its messages go to the next channel
+func notificationChannels(cfg *config.Config, userRepo repositories.UserRepository) []notification.Channel {
+	var channels []notification.Channel
+
+	telegram, err := notification.NewTelegramChannel(cfg.TelegramConfig, userRepo)
+	if err != nil {
+		logrus.WithError(err).Warn("Telegram notifications are disabled")
+	} else {
+		channels = append(channels, telegram)
+	}
+
+	if cfg.Email.Host != "" {
+		channels = append(channels, notification.NewEmailChannel(cfg.Email))
+	}
+
+	switch cfg.SMS.Gateway {
+	case "":
+	case notification.FakeSMSGatewayName:
+		channels = append(channels, notification.NewSMSChannel(notification.NewFakeSMSGateway()))
+	case notification.HTTPSMSGatewayName:
+		channels = append(channels, notification.NewSMSChannel(notification.NewHTTPSMSGateway(cfg.SMS)))
+	default:
+		log.Fatalf("unknown sms gateway %q", cfg.SMS.Gateway)
+	}
+	return channels
+}
+
+// the provider configured in cfg. payments are off without one
+func paymentProviders(cfg config.Payment) []payment.Provider {
+	switch cfg.Provider {
+	case "":
+		logrus.Warn("No payment provider is configured, payments are disabled")
+		return nil
+	case payment.FakeProviderName:
+		//anyone who knows the secret can sign a paid callback
+		if cfg.FakeSecret == "" {
+			log.Fatalf("the fake payment provider needs payment.fake_secret")
+		}
+		logrus.Warn("Payments go through the fake provider, which approves every payment and keeps its sessions in memory")
+		return []payment.Provider{payment.NewFakeProvider(cfg.FakeSecret)}
+	default:
+		log.Fatalf("unknown payment provider %q", cfg.Provider)
+		return nil
+	}
+}
+
 func InitLogger(level string) error {
 	logrus.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
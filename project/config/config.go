@@ -13,6 +13,13 @@ type Config struct {
 	Minio          Minio          `yaml:"minio"`
 	Redis          Redis          `yaml:"redis"`
 	TelegramConfig TelegramConfig `yaml:"telegram_config"`
+	Scheduler      Scheduler      `yaml:"scheduler"`
+	Payment        Payment        `yaml:"payment"`
+	Auth           Auth           `yaml:"auth"`
+	Email          Email          `yaml:"email"`
+	SMS            SMS            `yaml:"sms"`
+	Outbox         Outbox         `yaml:"outbox"`
+	Reminders      Reminders      `yaml:"reminders"`
 }
 
 type Server struct {
@@ -21,12 +28,12 @@ type Server struct {
 }
 
 type Postgres struct {
-	Host       string `yaml:"host"`
-	Port       string `yaml:"port"`
-	Username   string `yaml:"username"`
-	Password   string `yaml:"password"`
-	Database   string `yaml:"database"`
-	AutoCreate bool   `yaml:"autocreate"`
+	Host        string `yaml:"host"`
+	Port        string `yaml:"port"`
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	Database    string `yaml:"database"`
+	AutoMigrate bool   `yaml:"automigrate"` // apply pending migrations on start instead of refusing to start
 }
 
 type Minio struct {
@@ -42,10 +49,80 @@ type Redis struct {
 	DB       int    `yaml:"db"`
 }
 
+// TelegramConfig is the bot and how it gets its updates: by long polling,
+// which suits local development, or on a webhook telegram posts them to,
+// which every replica of the service can serve
 type TelegramConfig struct {
-	BotToken   string        `yaml:"bot_token"`
-	Timeout    time.Duration `yaml:"timeout"`
-	BotAddress string        `yaml:"bot_address"`
+	BotToken      string        `yaml:"bot_token"`
+	Timeout       time.Duration `yaml:"timeout"`
+	BotAddress    string        `yaml:"bot_address"`
+	Mode          string        `yaml:"mode"`           // polling or webhook, polling when unset
+	WebhookURL    string        `yaml:"webhook_url"`    // webhook, the public url of /api/v1/telegram/webhook
+	WebhookSecret string        `yaml:"webhook_secret"` // webhook, sent back by telegram with every update
+}
+
+// Email is the SMTP server notifications are mailed through
+type Email struct {
+	Host     string `yaml:"host"` // email notifications are off when unset
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"` // no authentication when unset
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// SMS is the gateway text messages are sent through
+type SMS struct {
+	Gateway string `yaml:"gateway"` // fake or http, sms notifications are off when unset
+	URL     string `yaml:"url"`     // http
+	APIKey  string `yaml:"api_key"` // http
+	Sender  string `yaml:"sender"`  // the number messages are sent from
+}
+
+// Outbox is how the outbox worker delivers queued notifications. a failed
+// attempt is retried after RetryDelay, doubling with every attempt, until
+// MaxAttempts were made
+type Outbox struct {
+	PollInterval time.Duration `yaml:"poll_interval"` // 5s when unset
+	RetryDelay   time.Duration `yaml:"retry_delay"`   // 30s when unset
+	MaxAttempts  int           `yaml:"max_attempts"`  // 8 when unset
+}
+
+// Reminders is when residents are reminded of their unpaid shares, in days
+// from the bill's due date: negative before it and 0 on the day. positive
+// offsets count from the billing deadline, for shares that are overdue
+type Reminders struct {
+	Offsets []int `yaml:"offsets"` // -7, 0 and 3 when unset
+}
+
+type Scheduler struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+type Payment struct {
+	Provider       string        `yaml:"provider"`
+	CallbackURL    string        `yaml:"callback_url"`
+	FakeSecret     string        `yaml:"fake_secret"`
+	TransactionTTL time.Duration `yaml:"transaction_ttl"` // 1h when unset, longer than the provider keeps its sessions
+}
+
+type Auth struct {
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`  // 15m when unset
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"` // 720h when unset
+	EncryptionKey   string        `yaml:"encryption_key"`    // encrypts the user id inside tokens
+	SigningKeyID    string        `yaml:"signing_key_id"`    // signs new tokens, the first key when unset
+	SigningKeys     []SigningKey  `yaml:"signing_keys"`      // every key tokens are accepted from
+}
+
+// SigningKey is a key access tokens are signed or verified with. a key
+// without its private half only verifies, which is how a rotated out key
+// keeps accepting the tokens it signed until they expire
+type SigningKey struct {
+	ID             string `yaml:"kid"`
+	Algorithm      string `yaml:"algorithm"`        // HS256, RS256 or EdDSA
+	Secret         string `yaml:"secret"`           // HS256
+	PrivateKeyFile string `yaml:"private_key_file"` // PEM, RS256 and EdDSA
+	PublicKeyFile  string `yaml:"public_key_file"`  // PEM, for verify-only keys
 }
 
 func InitConfig(filename string) (*Config, error) {
@@ -0,0 +1,238 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+)
+
+// the data of the buttons under /bills, followed by a colon and the payment's
+// id
+const payButton = "pay"
+
+// how many payments /history lists
+const historyLimit = 10
+
+const helpText = "*Commands*\n\n" +
+	"/bills - your unpaid bills\n" +
+	"/history - your latest payments\n" +
+	"/balance - your balance in each apartment\n" +
+	"/apartments - the apartments you live in\n" +
+	"/pay <id> - pay a bill, the ids are listed by /bills"
+
+// Commands answers the bot commands of residents and the buttons under its
+// messages with the same services the api uses
+type Commands struct {
+	userService       services.UserService
+	billService       services.BillService
+	apartmentService  services.ApartmentService
+	invitationService services.InvitationService
+	ledgerService     services.LedgerService
+}
+
+func NewCommands(
+	userService services.UserService,
+	billService services.BillService,
+	apartmentService services.ApartmentService,
+	invitationService services.InvitationService,
+	ledgerService services.LedgerService,
+) *Commands {
+	return &Commands{
+		userService:       userService,
+		billService:       billService,
+		apartmentService:  apartmentService,
+		invitationService: invitationService,
+		ledgerService:     ledgerService,
+	}
+}
+
+func (c *Commands) HandleCommand(ctx context.Context, user models.User, command, args string) notification.Message {
+	switch command {
+	case "bills":
+		return c.bills(ctx, user)
+	case "history":
+		return c.history(ctx, user)
+	case "balance":
+		return c.balance(ctx, user)
+	case "apartments":
+		return c.apartments(ctx, user)
+	case "pay":
+		paymentID, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(args), "#"))
+		if err != nil {
+			return notification.Message{Text: "Usage: /pay <id>, the ids are listed by /bills"}
+		}
+		return c.pay(ctx, user, paymentID)
+	}
+	return notification.Message{Text: helpText}
+}
+
+func (c *Commands) HandleButton(ctx context.Context, user models.User, data string) notification.Message {
+	action, value, _ := strings.Cut(data, ":")
+	switch action {
+	case payButton:
+		if paymentID, err := strconv.Atoi(value); err == nil {
+			return c.pay(ctx, user, paymentID)
+		}
+	case notification.AcceptInvitationButton:
+		return c.acceptInvitation(ctx, user, value)
+	case notification.RejectInvitationButton:
+		if invitationID, err := strconv.Atoi(value); err == nil {
+			return c.rejectInvitation(ctx, user, invitationID)
+		}
+	}
+	return notification.Message{Text: "This button no longer works."}
+}
+
+// links the chat to the account the code from the profile was made for
+func (c *Commands) LinkChat(ctx context.Context, chatID int64, code string) notification.Message {
+	user, err := c.userService.LinkTelegramChat(ctx, code, chatID)
+	if errors.Is(err, services.ErrInvalidLinkCode) {
+		return notification.Message{Text: "This code is wrong or has expired, get a new one on your profile."}
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("chat_id", chatID).Error("Bot failed to link chat")
+		return notification.Message{Text: "Something went wrong, please try again later."}
+	}
+	return notification.Message{Text: fmt.Sprintf(
+		"✅ This chat is linked to %s, you'll get your notifications here.\n\n%s",
+		notification.EscapeMarkdown(user.Username), helpText)}
+}
+
+func (c *Commands) bills(ctx context.Context, user models.User) notification.Message {
+	balances, err := c.billService.GetUnpaidBills(ctx, user.ID)
+	if err != nil {
+		return failure(user, "get your bills", err)
+	}
+	if len(balances) == 0 {
+		return notification.Message{Text: "You have no unpaid bills 🎉"}
+	}
+
+	var text strings.Builder
+	text.WriteString("*Unpaid bills*\n\n")
+	var buttons []notification.Button
+	for _, balance := range balances {
+		fmt.Fprintf(&text, "#%d bill %d: %s left to pay (%s)\n",
+			balance.PaymentID, balance.BillID, balance.Outstanding, balance.Status)
+		buttons = append(buttons, notification.Button{
+			Text: fmt.Sprintf("Pay #%d", balance.PaymentID),
+			Data: fmt.Sprintf("%s:%d", payButton, balance.PaymentID),
+		})
+	}
+	text.WriteString("\nPay one with /pay <id> or the buttons below.")
+	return notification.Message{Text: text.String(), Buttons: buttons}
+}
+
+func (c *Commands) history(ctx context.Context, user models.User) notification.Message {
+	history, err := c.billService.GetUserPaymentHistory(ctx, user.ID)
+	if err != nil {
+		return failure(user, "get your payment history", err)
+	}
+	if len(history) == 0 {
+		return notification.Message{Text: "You have no payments yet."}
+	}
+
+	var text strings.Builder
+	text.WriteString("*Payment history*\n\n")
+	for i, item := range history {
+		if i == historyLimit {
+			fmt.Fprintf(&text, "\n…and %d more", len(history)-historyLimit)
+			break
+		}
+		fmt.Fprintf(&text, "%s, %s bill due %s: %s (%s)\n",
+			notification.EscapeMarkdown(item.ApartmentName),
+			item.Bill.BillType,
+			item.Bill.DueDate,
+			item.Payment.Amount,
+			item.Payment.PaymentStatus)
+	}
+	return notification.Message{Text: text.String()}
+}
+
+// the balance is what the user owes each apartment, negative when they are
+// in credit
+func (c *Commands) balance(ctx context.Context, user models.User) notification.Message {
+	apartments, err := c.apartmentService.GetAllApartmentsForResident(ctx, user.ID)
+	if err != nil {
+		return failure(user, "get your apartments", err)
+	}
+	if len(apartments) == 0 {
+		return notification.Message{Text: "You aren't a resident of any apartment yet."}
+	}
+
+	var text strings.Builder
+	text.WriteString("*Balance*\n\n")
+	for _, apartment := range apartments {
+		balance, err := c.ledgerService.GetBalance(ctx, user.ID, user.ID, apartment.ID)
+		if err != nil {
+			return failure(user, "get your balance", err)
+		}
+		status := "settled"
+		if balance.IsPositive() {
+			status = "owed"
+		} else if balance.Amount < 0 {
+			status = "in credit"
+		}
+		fmt.Fprintf(&text, "%s: %s %s\n", notification.EscapeMarkdown(apartment.ApartmentName), balance, status)
+	}
+	return notification.Message{Text: text.String()}
+}
+
+func (c *Commands) apartments(ctx context.Context, user models.User) notification.Message {
+	apartments, err := c.apartmentService.GetAllApartmentsForResident(ctx, user.ID)
+	if err != nil {
+		return failure(user, "get your apartments", err)
+	}
+	if len(apartments) == 0 {
+		return notification.Message{Text: "You aren't a resident of any apartment yet."}
+	}
+
+	var text strings.Builder
+	text.WriteString("*Your apartments*\n\n")
+	for _, apartment := range apartments {
+		fmt.Fprintf(&text, "🏠 %s, %s\n",
+			notification.EscapeMarkdown(apartment.ApartmentName),
+			notification.EscapeMarkdown(apartment.Address))
+	}
+	return notification.Message{Text: text.String()}
+}
+
+// starts paying the outstanding balance of a payment and sends the link to
+// the provider. presses of the same button within a minute get the same
+// transaction back
+func (c *Commands) pay(ctx context.Context, user models.User, paymentID int) notification.Message {
+	idempotentKey := fmt.Sprintf("telegram-%d-%d", paymentID, time.Now().Unix()/60)
+	response, err := c.billService.PayBills(ctx, user.ID, []int{paymentID}, idempotentKey)
+	if err != nil {
+		return failure(user, "start the payment", err)
+	}
+	return notification.Message{Text: fmt.Sprintf(
+		"💳 Pay %v here:\n%v", response["total_amount"], response["redirect_url"])}
+}
+
+func (c *Commands) acceptInvitation(ctx context.Context, user models.User, token string) notification.Message {
+	if _, err := c.apartmentService.JoinApartment(ctx, user.ID, token); err != nil {
+		return failure(user, "accept the invitation", err)
+	}
+	return notification.Message{Text: "✅ You joined the apartment."}
+}
+
+func (c *Commands) rejectInvitation(ctx context.Context, user models.User, invitationID int) notification.Message {
+	if err := c.invitationService.RejectInvitation(ctx, user.ID, invitationID); err != nil {
+		return failure(user, "reject the invitation", err)
+	}
+	return notification.Message{Text: "The invitation was rejected."}
+}
+
+func failure(user models.User, action string, err error) notification.Message {
+	logrus.WithError(err).WithField("user_id", user.ID).Warnf("Bot failed to %s", action)
+	return notification.Message{Text: notification.EscapeMarkdown(fmt.Sprintf("Failed to %s: %s", action, err))}
+}
@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the services stand in for the real ones, only the methods the bot uses are
+// implemented
+type fakeUserService struct {
+	services.UserService
+	linked map[int64]string
+}
+
+func (f *fakeUserService) LinkTelegramChat(ctx context.Context, code string, chatID int64) (*models.User, error) {
+	if code != "K7M2Q9XA" {
+		return nil, services.ErrInvalidLinkCode
+	}
+	f.linked[chatID] = code
+	return &models.User{BaseModel: models.BaseModel{ID: 3}, Username: "neda_z"}, nil
+}
+
+type fakeBillService struct {
+	services.BillService
+	unpaid  []services.OutstandingBalance
+	paidIDs []int
+	payErr  error
+}
+
+func (f *fakeBillService) GetUnpaidBills(ctx context.Context, userID int) ([]services.OutstandingBalance, error) {
+	return f.unpaid, nil
+}
+
+func (f *fakeBillService) PayBills(ctx context.Context, userID int, paymentIDs []int, idempotentKey string) (map[string]interface{}, error) {
+	if f.payErr != nil {
+		return nil, f.payErr
+	}
+	f.paidIDs = append(f.paidIDs, paymentIDs...)
+	return map[string]interface{}{
+		"redirect_url": "https://pay.example.com/tx/7",
+		"total_amount": models.NewMoney(5000, "IRR"),
+	}, nil
+}
+
+type fakeApartmentService struct {
+	services.ApartmentService
+	apartments []models.Apartment
+	joined     []string
+}
+
+func (f *fakeApartmentService) GetAllApartmentsForResident(ctx context.Context, residentID int) ([]models.Apartment, error) {
+	return f.apartments, nil
+}
+
+func (f *fakeApartmentService) JoinApartment(ctx context.Context, userID int, token string) (map[string]interface{}, error) {
+	f.joined = append(f.joined, token)
+	return map[string]interface{}{"status": "joined apartment"}, nil
+}
+
+type fakeInvitationService struct {
+	services.InvitationService
+	rejected []int
+}
+
+func (f *fakeInvitationService) RejectInvitation(ctx context.Context, userID, invitationID int) error {
+	f.rejected = append(f.rejected, invitationID)
+	return nil
+}
+
+type fakeLedgerService struct {
+	services.LedgerService
+	balances map[int]models.Money
+}
+
+func (f *fakeLedgerService) GetBalance(ctx context.Context, requesterID, userID, apartmentID int) (models.Money, error) {
+	return f.balances[apartmentID], nil
+}
+
+func TestCommands(t *testing.T) {
+	ctx := context.Background()
+	user := models.User{BaseModel: models.BaseModel{ID: 3}}
+
+	bills := &fakeBillService{unpaid: []services.OutstandingBalance{
+		{PaymentID: 11, BillID: 4, Status: models.Pending, Outstanding: models.NewMoney(5000, "IRR")},
+	}}
+	apartments := &fakeApartmentService{apartments: []models.Apartment{
+		{BaseModel: models.BaseModel{ID: 1}, ApartmentName: "Sun_set", Address: "12 Main St"},
+		{BaseModel: models.BaseModel{ID: 2}, ApartmentName: "Oak", Address: "3 Elm St"},
+	}}
+	invitations := &fakeInvitationService{}
+	ledger := &fakeLedgerService{balances: map[int]models.Money{
+		1: models.NewMoney(1200, "IRR"),
+		2: models.NewMoney(-300, "IRR"),
+	}}
+	users := &fakeUserService{linked: map[int64]string{}}
+	commands := NewCommands(users, bills, apartments, invitations, ledger)
+
+	t.Run("link the chat with a code", func(t *testing.T) {
+		answer := commands.LinkChat(ctx, 42, "K7M2Q9XA")
+		assert.Contains(t, answer.Text, `linked to neda\_z`)
+		assert.Equal(t, map[int64]string{42: "K7M2Q9XA"}, users.linked)
+	})
+
+	t.Run("wrong or expired code", func(t *testing.T) {
+		answer := commands.LinkChat(ctx, 43, "AAAAAAAA")
+		assert.Contains(t, answer.Text, "wrong or has expired")
+		assert.NotContains(t, users.linked, int64(43))
+	})
+
+	t.Run("bills lists the unpaid shares with pay buttons", func(t *testing.T) {
+		answer := commands.HandleCommand(ctx, user, "bills", "")
+		assert.Contains(t, answer.Text, "#11 bill 4: 50.00 IRR left to pay")
+		require.Len(t, answer.Buttons, 1)
+		assert.Equal(t, "pay:11", answer.Buttons[0].Data)
+	})
+
+	t.Run("balance per apartment", func(t *testing.T) {
+		answer := commands.HandleCommand(ctx, user, "balance", "")
+		assert.Contains(t, answer.Text, `Sun\_set: 12.00 IRR owed`)
+		assert.Contains(t, answer.Text, "Oak: -3.00 IRR in credit")
+	})
+
+	t.Run("pay by command and button", func(t *testing.T) {
+		answer := commands.HandleCommand(ctx, user, "pay", " #11")
+		assert.Contains(t, answer.Text, "https://pay.example.com/tx/7")
+		commands.HandleButton(ctx, user, "pay:11")
+		assert.Equal(t, []int{11, 11}, bills.paidIDs)
+	})
+
+	t.Run("pay without an id shows the usage", func(t *testing.T) {
+		answer := commands.HandleCommand(ctx, user, "pay", "")
+		assert.Contains(t, answer.Text, "Usage: /pay <id>")
+	})
+
+	t.Run("failed payment", func(t *testing.T) {
+		bills.payErr = errors.New("payment 12 does not belong to user")
+		defer func() { bills.payErr = nil }()
+		answer := commands.HandleCommand(ctx, user, "pay", "12")
+		assert.Equal(t, "Failed to start the payment: payment 12 does not belong to user", answer.Text)
+	})
+
+	t.Run("invitation buttons", func(t *testing.T) {
+		commands.HandleButton(ctx, user, "accept:abc_def")
+		commands.HandleButton(ctx, user, "reject:9")
+		assert.Equal(t, []string{"abc_def"}, apartments.joined)
+		assert.Equal(t, []int{9}, invitations.rejected)
+	})
+
+	t.Run("unknown input", func(t *testing.T) {
+		assert.Contains(t, commands.HandleCommand(ctx, user, "help", "").Text, "/bills")
+		assert.Equal(t, "This button no longer works.", commands.HandleButton(ctx, user, "reject:x").Text)
+	})
+}
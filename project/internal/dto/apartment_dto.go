@@ -0,0 +1,44 @@
+package dto
+
+import "github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+
+type GrantRoleRequest struct {
+	Role        models.Role `json:"role"`
+	Permissions []string    `json:"permissions,omitempty"` // replaces the role's permissions when given
+}
+
+// MemberResponse is a member of an apartment with what they may do there
+type MemberResponse struct {
+	UserID       int                 `json:"user_id"`
+	Username     string              `json:"username"`
+	FullName     string              `json:"full_name"`
+	Role         models.Role         `json:"role"`
+	Permissions  []string            `json:"permissions"`
+	UnitID       *int                `json:"unit_id,omitempty"`
+	UnitRelation models.UnitRelation `json:"unit_relation,omitempty"`
+}
+
+// InviteRequest addresses an invitation to an email or phone number, which
+// may belong to someone who hasn't signed up yet
+type InviteRequest struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+type HandoverRequest struct {
+	UserID        int  `json:"user_id"`         // the member to hand the apartment over to
+	StayAsManager bool `json:"stay_as_manager"` // stay a co-manager after the handover instead of becoming a resident
+}
+
+type UnitRequest struct {
+	Number         string  `json:"number"`
+	Floor          int     `json:"floor"`
+	Area           float64 `json:"area"`
+	OccupantsCount int     `json:"occupants_count"`
+	ParkingSpots   int     `json:"parking_spots"`
+}
+
+type AssignUnitRequest struct {
+	UnitID   *int                `json:"unit_id"`  // null takes the member out of their unit
+	Relation models.UnitRelation `json:"relation"` // owner or resident, resident when empty
+}
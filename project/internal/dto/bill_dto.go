@@ -4,7 +4,7 @@ import "github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship
 
 type CreateBillRequest struct {
 	BillType        models.BillType `json:"bill_type"`
-	TotalAmount     float64         `json:"total_amount"`
+	TotalAmount     models.Money    `json:"total_amount"`
 	DueDate         string          `json:"due_date"`
 	BillingDeadline string          `json:"billing_deadline"`
 	Description     string          `json:"description"`
@@ -13,3 +13,49 @@ type CreateBillRequest struct {
 type PayBillsRequest struct {
 	BillIDs []int `json:"bill_ids"`
 }
+
+type CreateBillTemplateRequest struct {
+	BillType      models.BillType      `json:"bill_type"`
+	Amount        models.Money         `json:"amount"`
+	AmountFormula models.AmountFormula `json:"amount_formula"`
+	Cadence       models.Cadence       `json:"cadence"`
+	DueDayOffset  int                  `json:"due_day_offset"`
+	StartDate     string               `json:"start_date"` // YYYY-MM-DD on day 1-28, periods repeat on its day. defaults to the 1st of the current month
+	Description   string               `json:"description"`
+	AutoDivide    bool                 `json:"auto_divide"`
+}
+
+type RefundPaymentRequest struct {
+	Amount models.Money `json:"amount"`
+	Reason string       `json:"reason"`
+}
+
+type ChargebackRequest struct {
+	Reason string `json:"reason"`
+}
+
+type InstallmentRequest struct {
+	Amount  models.Money `json:"amount"`
+	DueDate string       `json:"due_date"` // YYYY-MM-DD
+}
+
+type InstallmentPlanRequest struct {
+	Installments []InstallmentRequest `json:"installments"`
+}
+
+type PartialPaymentRequest struct {
+	Amount models.Money `json:"amount"` // optional when the payment has an installment plan
+}
+
+type LateFeeRuleRequest struct {
+	FeeType         models.LateFeeType `json:"fee_type"`
+	Amount          models.Money       `json:"amount"`            // flat and daily rules
+	RateBasisPoints int64              `json:"rate_basis_points"` // percentage rules, 250 is 2.5%
+	GraceDays       int                `json:"grace_days"`
+	Cap             models.Money       `json:"cap"`
+	Active          *bool              `json:"active"` // defaults to true
+}
+
+type WaiveLateFeeRequest struct {
+	Reason string `json:"reason"`
+}
@@ -1,6 +1,10 @@
 package dto
 
-import "github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+import (
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
 
 type CreateUserRequest struct {
 	Username     string          `json:"username"`
@@ -10,6 +14,9 @@ type CreateUserRequest struct {
 	FullName     string          `json:"full_name"`
 	UserType     models.UserType `json:"user_type"`
 	TelegramUser string          `json:"telegram_user"`
+	// the token of an invitation sent to the email or phone number signing
+	// up, to join its apartment right away
+	InvitationToken string `json:"invitation_token,omitempty"`
 }
 
 type LoginRequest struct {
@@ -17,6 +24,12 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// RefreshTokenRequest is the body of both refresh and logout. logging out
+// without the refresh token only revokes the access token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type UpdateProfileRequest struct {
 	Username     string `json:"username"`
 	Email        string `json:"email"`
@@ -35,25 +48,50 @@ type UserInfo struct {
 	TelegramUser string          `json:"telegram_user"`
 }
 
+// NotificationPreferences is the channels a user wants to be notified on, in
+// the order they are tried, whether they are reminded of unpaid shares and
+// the language notifications are written in
+type NotificationPreferences struct {
+	Channels      []models.NotificationChannel `json:"channels"`
+	BillReminders *bool                        `json:"bill_reminders,omitempty"` // left as is when missing from an update
+	Locale        *models.Locale               `json:"locale,omitempty"`         // fa or en, left as is when missing from an update
+}
+
 type TelegramInfo struct {
 	Username  string `json:"username"`
-	Connected bool   `json:"connected"`
+	Connected bool   `json:"connected"` // a chat was linked with a code from the profile
+}
+
+// TelegramLinkCode is sent to the bot to link the chat to the account
+type TelegramLinkCode struct {
+	Code         string    `json:"code"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Instructions string    `json:"instructions"`
 }
 
 type SignUpResponse struct {
 	User                      UserInfo `json:"user"`
 	TelegramSetupRequired     bool     `json:"telegram_setup_required"`
 	TelegramSetupInstructions string   `json:"telegram_setup_instructions,omitempty"`
+	JoinedApartmentID         int      `json:"joined_apartment_id,omitempty"` // the apartment of the invitation signed up with
 }
 
 type LoginResponse struct {
-	Token    string       `json:"token"`
-	UserID   string       `json:"user_id"`
-	UserType string       `json:"user_type"`
-	Username string       `json:"username"`
-	Email    string       `json:"email"`
-	FullName string       `json:"full_name"`
-	Telegram TelegramInfo `json:"telegram"`
+	Token        string       `json:"token"`
+	ExpiresIn    int          `json:"expires_in"`
+	RefreshToken string       `json:"refresh_token"`
+	UserID       string       `json:"user_id"`
+	UserType     string       `json:"user_type"`
+	Username     string       `json:"username"`
+	Email        string       `json:"email"`
+	FullName     string       `json:"full_name"`
+	Telegram     TelegramInfo `json:"telegram"`
+}
+
+type TokenResponse struct {
+	Token        string `json:"token"`      // "Bearer " and the access token
+	ExpiresIn    int    `json:"expires_in"` // seconds until the access token expires
+	RefreshToken string `json:"refresh_token"`
 }
 
 type ProfileResponse struct {
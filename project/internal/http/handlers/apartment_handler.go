@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/utils"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
 )
 
@@ -45,7 +47,7 @@ func (h *ApartmentHandler) CreateApartment(w http.ResponseWriter, r *http.Reques
 
 	id, err := h.apartmentService.CreateApartment(r.Context(), userID, request.ApartmentName, request.Address, request.UnitsCount)
 	if err != nil {
-		http.Error(w, "Failed to create apartment: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to create apartment: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -67,7 +69,7 @@ func (h *ApartmentHandler) GetApartmentByID(w http.ResponseWriter, r *http.Reque
 	apartment, err := h.apartmentService.GetApartmentByID(r.Context(), id, managerId)
 
 	if err != nil {
-		http.Error(w, "Apartment not found"+err.Error(), http.StatusNotFound)
+		http.Error(w, "Apartment not found"+err.Error(), errorStatus(err, http.StatusNotFound))
 		return
 	}
 
@@ -86,7 +88,7 @@ func (h *ApartmentHandler) GetResidentsInApartment(w http.ResponseWriter, r *htt
 
 	residents, err := h.apartmentService.GetResidentsInApartment(r.Context(), apartmentID, managerId)
 	if err != nil {
-		http.Error(w, "Failed to get residents: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to get residents: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -107,7 +109,7 @@ func (h *ApartmentHandler) GetAllApartmentsForResident(w http.ResponseWriter, r
 
 	apartments, err := h.apartmentService.GetAllApartmentsForResident(r.Context(), residentID)
 	if err != nil {
-		http.Error(w, "Failed to get apartments: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to get apartments: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -124,7 +126,6 @@ func (h *ApartmentHandler) UpdateApartment(w http.ResponseWriter, r *http.Reques
 		ApartmentName string `json:"apartment_name"`
 		Address       string `json:"address"`
 		UnitsCount    int    `json:"units_count"`
-		ManagerID     int    `json:"manager_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -132,13 +133,20 @@ func (h *ApartmentHandler) UpdateApartment(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if request.ID == 0 || request.ApartmentName == "" || request.Address == "" || request.UnitsCount == 0 || request.ManagerID == 0 {
+	if request.ID == 0 || request.ApartmentName == "" || request.Address == "" || request.UnitsCount == 0 {
 		http.Error(w, "All fields are required", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.apartmentService.UpdateApartment(r.Context(), request.ID, request.ApartmentName, request.Address, request.UnitsCount, request.ManagerID); err != nil {
-		http.Error(w, "Failed to update apartment: "+err.Error(), http.StatusInternalServerError)
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "Failed to get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	userID, _ := strconv.Atoi(userIDString)
+
+	if err := h.apartmentService.UpdateApartment(r.Context(), request.ID, request.ApartmentName, request.Address, request.UnitsCount, userID); err != nil {
+		http.Error(w, "Failed to update apartment: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -155,7 +163,7 @@ func (h *ApartmentHandler) DeleteApartment(w http.ResponseWriter, r *http.Reques
 	managerId, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
 
 	if err := h.apartmentService.DeleteApartment(r.Context(), id, managerId); err != nil {
-		http.Error(w, "Failed to delete apartment: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to delete apartment: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -185,7 +193,33 @@ func (h *ApartmentHandler) InviteUserToApartment(w http.ResponseWriter, r *http.
 
 	response, err := h.apartmentService.InviteUserToApartment(r.Context(), managerID, apartmentID, telegramUsername)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *ApartmentHandler) InviteByContact(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.InviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	response, err := h.apartmentService.InviteByContact(r.Context(), managerID, apartmentID, req)
+	if err != nil {
+		http.Error(w, "Failed to invite: "+err.Error(), errorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -206,7 +240,7 @@ func (h *ApartmentHandler) JoinApartment(w http.ResponseWriter, r *http.Request)
 
 	response, err := h.apartmentService.JoinApartment(r.Context(), userID, invitationCode)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -231,7 +265,7 @@ func (h *ApartmentHandler) LeaveApartment(w http.ResponseWriter, r *http.Request
 	userID, _ := strconv.Atoi(userIDString)
 
 	if err := h.apartmentService.LeaveApartment(r.Context(), userID, apartmentID); err != nil {
-		http.Error(w, "Failed to leave apartment: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to leave apartment: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -239,3 +273,185 @@ func (h *ApartmentHandler) LeaveApartment(w http.ResponseWriter, r *http.Request
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "left apartment"})
 }
+
+func (h *ApartmentHandler) SetDivisionPolicy(w http.ResponseWriter, r *http.Request) {
+	apartmentIDStr := r.PathValue("apartment_id")
+	apartmentID, err := strconv.Atoi(apartmentIDStr)
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		DivisionPolicy models.DivisionPolicy `json:"division_policy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "Failed to get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	managerID, _ := strconv.Atoi(userIDString)
+
+	if err := h.apartmentService.SetDivisionPolicy(r.Context(), managerID, apartmentID, request.DivisionPolicy); err != nil {
+		http.Error(w, "Failed to set division policy: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"division_policy": string(request.DivisionPolicy)})
+}
+
+func (h *ApartmentHandler) UpdateResidentShare(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+	residentID, err := strconv.Atoi(r.PathValue("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		UnitArea       float64 `json:"unit_area"`
+		OccupantsCount int     `json:"occupants_count"`
+		SharePercent   float64 `json:"share_percent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "Failed to get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	managerID, _ := strconv.Atoi(userIDString)
+
+	share, err := h.apartmentService.UpdateResidentShare(r.Context(), managerID, apartmentID, residentID,
+		request.UnitArea, request.OccupantsCount, request.SharePercent)
+	if err != nil {
+		http.Error(w, "Failed to update resident share: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	utils.WriteSuccessResponse(w, "resident share updated", share)
+}
+
+func (h *ApartmentHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "Failed to get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	userID, _ := strconv.Atoi(userIDString)
+
+	members, err := h.apartmentService.GetMembers(r.Context(), userID, apartmentID)
+	if err != nil {
+		http.Error(w, "Failed to get members: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+func (h *ApartmentHandler) GetManagers(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "Failed to get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	userID, _ := strconv.Atoi(userIDString)
+
+	managers, err := h.apartmentService.GetManagers(r.Context(), userID, apartmentID)
+	if err != nil {
+		http.Error(w, "Failed to get managers: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(managers)
+}
+
+func (h *ApartmentHandler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+	memberID, err := strconv.Atoi(r.PathValue("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var request dto.GrantRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "Failed to get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	granterID, _ := strconv.Atoi(userIDString)
+
+	member, err := h.apartmentService.GrantRole(r.Context(), granterID, apartmentID, memberID, request)
+	if err != nil {
+		http.Error(w, "Failed to grant role: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(member)
+}
+
+func (h *ApartmentHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+	memberID, err := strconv.Atoi(r.PathValue("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "Failed to get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	granterID, _ := strconv.Atoi(userIDString)
+
+	if err := h.apartmentService.RevokeRole(r.Context(), granterID, apartmentID, memberID); err != nil {
+		http.Error(w, "Failed to revoke role: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
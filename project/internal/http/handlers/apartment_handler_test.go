@@ -75,8 +75,10 @@ func TestCreateApartment(t *testing.T) {
 			mockUserRepo := new(repositories.MockUserRepository)
 			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
 			mockInviteRepo := new(repositories.MockInviteLinkRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
 			mockNotif := new(notification.MockNotification)
 
+			mockUOW.On("Do", mock.Anything).Return(nil)
 			tt.mockSetup(mockUserAptRepo, mockUserRepo, mockAptRepo)
 
 			service := services.NewApartmentService(
@@ -84,6 +86,7 @@ func TestCreateApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				mockUOW,
 				mockNotif,
 			)
 			handler := NewApartmentHandler(service)
@@ -124,7 +127,7 @@ func TestGetApartmentByID(t *testing.T) {
 			queryParams: "id=1",
 			userID:      "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				aptRepo.On("GetApartmentByID", 1).Return(&models.Apartment{
 					BaseModel:     models.BaseModel{ID: 1},
 					ApartmentName: "Sunny Apartments",
@@ -143,11 +146,11 @@ func TestGetApartmentByID(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name:        "not manager of apartment",
+			name:        "not a member of apartment",
 			queryParams: "id=1",
 			userID:      "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.Permission(0), nil)
 			},
 			expectedStatus: http.StatusNotFound,
 		},
@@ -168,6 +171,7 @@ func TestGetApartmentByID(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				nil,
 				mockNotif,
 			)
 			handler := NewApartmentHandler(service)
@@ -200,7 +204,7 @@ func TestGetResidentsInApartment(t *testing.T) {
 			apartmentID: "1",
 			userID:      "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				userAptRepo.On("GetResidentsInApartment", 1).Return([]models.User{
 					{BaseModel: models.BaseModel{ID: 1}, Username: "user1"},
 					{BaseModel: models.BaseModel{ID: 2}, Username: "user2"},
@@ -216,11 +220,11 @@ func TestGetResidentsInApartment(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name:        "not manager of apartment",
+			name:        "not a member of apartment",
 			apartmentID: "1",
 			userID:      "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.Permission(0), nil)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -241,6 +245,7 @@ func TestGetResidentsInApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				nil,
 				mockNotif,
 			)
 			handler := NewApartmentHandler(service)
@@ -275,11 +280,11 @@ func TestInviteUserToApartment(t *testing.T) {
 			telegramUsername: "testuser",
 			userID:           "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				userRepo.On("GetUserByTelegramUser", "testuser").Return(&models.User{BaseModel: models.BaseModel{ID: 2}}, nil)
 				userAptRepo.On("IsUserInApartment", mock.Anything, 2, 1).Return(false, errors.New("not in apartment"))
-				inviteRepo.On("CreateInvitation", mock.Anything, 2, 1, 1).Return("invite123", nil)
-				notif.On("SendInvitation", mock.Anything, mock.Anything, 1, "testuser").Return(nil)
+				inviteRepo.On("CreateInvitation", mock.Anything, mock.Anything).Return(7, nil)
+				notif.On("SendInvitation", mock.Anything, mock.Anything).Return(nil)
 			},
 			expectedStatus: http.StatusCreated,
 		},
@@ -289,7 +294,7 @@ func TestInviteUserToApartment(t *testing.T) {
 			telegramUsername: "testuser",
 			userID:           "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				userRepo.On("GetUserByTelegramUser", "testuser").Return(&models.User{BaseModel: models.BaseModel{ID: 2}}, nil)
 				userAptRepo.On("IsUserInApartment", mock.Anything, 2, 1).Return(true, nil)
 			},
@@ -323,6 +328,9 @@ func TestInviteUserToApartment(t *testing.T) {
 			mockInviteRepo := new(repositories.MockInviteLinkRepository)
 			mockNotif := new(notification.MockNotification)
 
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+
 			tt.mockSetup(mockUserAptRepo, mockUserRepo, mockInviteRepo, mockNotif)
 
 			service := services.NewApartmentService(
@@ -330,6 +338,7 @@ func TestInviteUserToApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				mockUOW,
 				mockNotif,
 			)
 			handler := NewApartmentHandler(service)
@@ -366,7 +375,7 @@ func TestJoinApartment(t *testing.T) {
 			invitationCode: "validcode",
 			userID:         "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "validcode").Return(1, nil)
+				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "validcode", 1).Return(1, nil)
 				userAptRepo.On("IsUserInApartment", mock.Anything, 1, 1).Return(false, errors.New("not in apartment"))
 				userAptRepo.On("CreateUserApartment", mock.Anything, mock.Anything).Return(nil)
 				notif.On("SendNotification", mock.Anything, 1, mock.Anything).Return(nil)
@@ -378,7 +387,7 @@ func TestJoinApartment(t *testing.T) {
 			invitationCode: "invalidcode",
 			userID:         "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "invalidcode").Return(0, errors.New("invalid code"))
+				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "invalidcode", 1).Return(0, errors.New("invalid code"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -392,6 +401,9 @@ func TestJoinApartment(t *testing.T) {
 			mockInviteRepo := new(repositories.MockInviteLinkRepository)
 			mockNotif := new(notification.MockNotification)
 
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+
 			tt.mockSetup(mockUserAptRepo, mockInviteRepo, mockNotif)
 
 			service := services.NewApartmentService(
@@ -399,6 +411,7 @@ func TestJoinApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				mockUOW,
 				mockNotif,
 			)
 			handler := NewApartmentHandler(service)
@@ -425,14 +438,15 @@ func TestLeaveApartment(t *testing.T) {
 		name           string
 		queryParam     string
 		userID         string
-		mockSetup      func(*repositories.MockUserApartmentRepository)
+		mockSetup      func(*repositories.MockUserApartmentRepository, *repositories.MockApartmentRepo)
 		expectedStatus int
 	}{
 		{
 			name:       "successful leave",
 			queryParam: "apartment_id=1",
 			userID:     "1",
-			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
+				aptRepo.On("GetApartmentByID", 1).Return(&models.Apartment{ManagerID: 2}, nil)
 				userAptRepo.On("DeleteUserApartment", 1, 1).Return(nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -441,7 +455,7 @@ func TestLeaveApartment(t *testing.T) {
 			name:           "invalid apartment id",
 			queryParam:     "apartment_id=invalid",
 			userID:         "1",
-			mockSetup:      func(*repositories.MockUserApartmentRepository) {},
+			mockSetup:      func(*repositories.MockUserApartmentRepository, *repositories.MockApartmentRepo) {},
 			expectedStatus: http.StatusBadRequest,
 		},
 	}
@@ -454,13 +468,14 @@ func TestLeaveApartment(t *testing.T) {
 			mockInviteRepo := new(repositories.MockInviteLinkRepository)
 			mockNotif := new(notification.MockNotification)
 
-			tt.mockSetup(mockUserAptRepo)
+			tt.mockSetup(mockUserAptRepo, mockAptRepo)
 
 			service := services.NewApartmentService(
 				mockAptRepo,
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				nil,
 				mockNotif,
 			)
 			handler := NewApartmentHandler(service)
@@ -498,7 +513,7 @@ func TestUpdateApartment(t *testing.T) {
 			},
 			userID: "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				aptRepo.On("UpdateApartment", mock.Anything, mock.Anything).Return(nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -525,7 +540,7 @@ func TestUpdateApartment(t *testing.T) {
 			},
 			userID: "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ResidentRole.Permissions(), nil)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -546,6 +561,7 @@ func TestUpdateApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				nil,
 				mockNotif,
 			)
 			handler := NewApartmentHandler(service)
@@ -579,9 +595,9 @@ func TestDeleteApartment(t *testing.T) {
 			queryParam: "id=1",
 			userID:     "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
-				aptRepo.On("DeleteApartment", 1).Return(nil)
-				userAptRepo.On("DeleteApartmentFromUserApartments", 1).Return(nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.OwnerRole.Permissions(), nil)
+				aptRepo.On("DeleteApartment", mock.Anything, 1).Return(nil)
+				userAptRepo.On("DeleteApartmentFromUserApartments", mock.Anything, 1).Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -597,7 +613,7 @@ func TestDeleteApartment(t *testing.T) {
 			queryParam: "id=1",
 			userID:     "1",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ResidentRole.Permissions(), nil)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -610,6 +626,8 @@ func TestDeleteApartment(t *testing.T) {
 			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
 			mockInviteRepo := new(repositories.MockInviteLinkRepository)
 			mockNotif := new(notification.MockNotification)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil).Maybe()
 
 			tt.mockSetup(mockUserAptRepo, mockAptRepo)
 
@@ -618,6 +636,7 @@ func TestDeleteApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				mockUOW,
 				mockNotif,
 			)
 			handler := NewApartmentHandler(service)
@@ -686,6 +705,7 @@ func TestGetAllApartmentsForResident(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				nil,
 				mockNotif,
 			)
 			handler := NewApartmentHandler(service)
@@ -702,3 +722,79 @@ func TestGetAllApartmentsForResident(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateResidentShare(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		mockSetup      func(*repositories.MockUserApartmentRepository)
+		expectedStatus int
+		expectedShare  map[string]interface{}
+	}{
+		{
+			name:        "returns the updated share",
+			requestBody: map[string]interface{}{"unit_area": 85.5, "occupants_count": 3, "share_percent": 12.5},
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 4).Return(models.ManagerRole.Permissions(), nil)
+				userAptRepo.On("UpdateResidentShare", mock.Anything, mock.Anything).Return(nil)
+				userAptRepo.On("GetUserApartmentByID", 7, 4).Return(&models.User_apartment{
+					UserID:         7,
+					ApartmentID:    4,
+					Role:           models.ResidentRole,
+					UnitArea:       85.5,
+					OccupantsCount: 3,
+					SharePercent:   12.5,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedShare: map[string]interface{}{
+				"user_id":         float64(7),
+				"apartment_id":    float64(4),
+				"unit_area":       85.5,
+				"occupants_count": float64(3),
+				"share_percent":   12.5,
+			},
+		},
+		{
+			name:        "share above 100 percent",
+			requestBody: map[string]interface{}{"share_percent": 120},
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
+				//no mocks needed since validation fails before repository calls
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			tt.mockSetup(mockUserAptRepo)
+
+			service := services.NewApartmentService(nil, nil, mockUserAptRepo, nil, nil, nil)
+			handler := NewApartmentHandler(service)
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("PUT", "/apartment/4/residents/7/share", bytes.NewReader(body))
+			req.SetPathValue("apartment_id", "4")
+			req.SetPathValue("user_id", "7")
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "1"))
+			w := httptest.NewRecorder()
+
+			handler.UpdateResidentShare(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedShare != nil {
+				var response struct {
+					Success bool                   `json:"success"`
+					Data    map[string]interface{} `json:"data"`
+				}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.True(t, response.Success)
+				for field, value := range tt.expectedShare {
+					assert.Equal(t, value, response.Data[field], field)
+				}
+			}
+			mockUserAptRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -47,7 +48,11 @@ func (h *BillHandler) CreateBill(w http.ResponseWriter, r *http.Request) {
 
 	var req dto.CreateBillRequest
 	req.BillType = models.BillType(r.FormValue("bill_type"))
-	req.TotalAmount, _ = strconv.ParseFloat(r.FormValue("total_amount"), 64)
+	req.TotalAmount, err = models.ParseMoney(r.FormValue("total_amount"), r.FormValue("currency"))
+	if err != nil {
+		http.Error(w, "Invalid total amount: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 	req.DueDate = r.FormValue("due_date")
 	req.BillingDeadline = r.FormValue("billing_deadline")
 	req.Description = r.FormValue("description")
@@ -56,7 +61,7 @@ func (h *BillHandler) CreateBill(w http.ResponseWriter, r *http.Request) {
 
 	response, err := h.billService.CreateBill(r.Context(), userID, apartmentID, req, file, handler)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -98,7 +103,7 @@ func (h *BillHandler) DivideBillByType(w http.ResponseWriter, r *http.Request) {
 
 	response, err := h.billService.DivideBillByType(r.Context(), userID, apartmentID, billType)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -124,7 +129,7 @@ func (h *BillHandler) DivideAllBills(w http.ResponseWriter, r *http.Request) {
 
 	response, err := h.billService.DivideAllBills(r.Context(), userID, apartmentID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -141,9 +146,10 @@ func (h *BillHandler) GetBillByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.billService.GetBillByID(r.Context(), id)
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+	response, err := h.billService.GetBillByID(r.Context(), userID, id)
 	if err != nil {
-		http.Error(w, "Bill not found: "+err.Error(), http.StatusNotFound)
+		http.Error(w, "Bill not found: "+err.Error(), errorStatus(err, http.StatusNotFound))
 		return
 	}
 
@@ -159,9 +165,10 @@ func (h *BillHandler) GetBillsByApartment(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	bills, err := h.billService.GetBillsByApartmentID(r.Context(), apartmentID)
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+	bills, err := h.billService.GetBillsByApartmentID(r.Context(), userID, apartmentID)
 	if err != nil {
-		http.Error(w, "Failed to get bills: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to get bills: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -171,21 +178,22 @@ func (h *BillHandler) GetBillsByApartment(w http.ResponseWriter, r *http.Request
 
 func (h *BillHandler) UpdateBill(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		ID              int     `json:"id"`
-		ApartmentID     int     `json:"apartment_id"`
-		BillType        string  `json:"bill_type"`
-		TotalAmount     float64 `json:"total_amount"`
-		DueDate         string  `json:"due_date"`
-		BillingDeadline string  `json:"billing_deadline"`
-		Description     string  `json:"description"`
+		ID              int          `json:"id"`
+		ApartmentID     int          `json:"apartment_id"`
+		BillType        string       `json:"bill_type"`
+		TotalAmount     models.Money `json:"total_amount"`
+		DueDate         string       `json:"due_date"`
+		BillingDeadline string       `json:"billing_deadline"`
+		Description     string       `json:"description"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.billService.UpdateBill(r.Context(), req.ID, req.ApartmentID, req.BillType, req.TotalAmount, req.DueDate, req.BillingDeadline, req.Description); err != nil {
-		http.Error(w, "Failed to update bill", http.StatusInternalServerError)
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+	if err := h.billService.UpdateBill(r.Context(), userID, req.ID, req.ApartmentID, req.BillType, req.TotalAmount, req.DueDate, req.BillingDeadline, req.Description); err != nil {
+		http.Error(w, "Failed to update bill", errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -200,9 +208,10 @@ func (h *BillHandler) DeleteBill(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.billService.DeleteBill(r.Context(), id); err != nil {
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+	if err := h.billService.DeleteBill(r.Context(), userID, id); err != nil {
 		logrus.Error("Failed to delete bill:", err)
-		http.Error(w, "Failed to delete bill", http.StatusInternalServerError)
+		http.Error(w, "Failed to delete bill", errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -232,14 +241,15 @@ func (h *BillHandler) PayBill(w http.ResponseWriter, r *http.Request) {
 
 	payments := []int{paymentID}
 
-	if err := h.billService.PayBills(r.Context(), userID, payments, r.Context().Value(middleware.IdempotentKey).(string)); err != nil {
-		http.Error(w, "Payment failed: "+err.Error(), http.StatusInternalServerError)
+	response, err := h.billService.PayBills(r.Context(), userID, payments, r.Context().Value(middleware.IdempotentKey).(string))
+	if err != nil {
+		http.Error(w, "Payment failed: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "payment successful"})
+	json.NewEncoder(w).Encode(response)
 }
 
 func (h *BillHandler) PayBatchBills(w http.ResponseWriter, r *http.Request) {
@@ -247,7 +257,50 @@ func (h *BillHandler) PayBatchBills(w http.ResponseWriter, r *http.Request) {
 
 	response, err := h.billService.PayBatchBills(r.Context(), userID, r.Context().Value(middleware.IdempotentKey).(string))
 	if err != nil {
-		http.Error(w, "Batch payment failed: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Batch payment failed: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// pays part of a single payment. without an amount the rest of the next
+// installment is paid
+func (h *BillHandler) PayPartial(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(r.PathValue("payment_id"))
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.PartialPaymentRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	response, err := h.billService.PayPartial(r.Context(), userID, paymentID, req.Amount, r.Context().Value(middleware.IdempotentKey).(string))
+	if err != nil {
+		http.Error(w, "Payment failed: "+err.Error(), paymentErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// the payment provider redirects the resident back here after paying
+func (h *BillHandler) PaymentCallback(w http.ResponseWriter, r *http.Request) {
+	response, err := h.billService.ConfirmPayment(r.Context(), r.PathValue("provider"), r.URL.Query())
+	if err != nil {
+		http.Error(w, "Payment verification failed: "+err.Error(), errorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -256,12 +309,149 @@ func (h *BillHandler) PayBatchBills(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func (h *BillHandler) RefundPayment(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(r.PathValue("payment_id"))
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.RefundPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	payment, err := h.billService.RefundPayment(r.Context(), managerID, paymentID, req.Amount, req.Reason)
+	if err != nil {
+		http.Error(w, "Refund failed: "+err.Error(), paymentErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+func (h *BillHandler) RecordChargeback(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(r.PathValue("payment_id"))
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.ChargebackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	payment, err := h.billService.RecordChargeback(r.Context(), managerID, paymentID, req.Reason)
+	if err != nil {
+		http.Error(w, "Failed to record chargeback: "+err.Error(), paymentErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+func (h *BillHandler) GetPaymentTransitions(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(r.PathValue("payment_id"))
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	transitions, err := h.billService.GetPaymentTransitions(r.Context(), managerID, paymentID)
+	if err != nil {
+		http.Error(w, "Failed to get payment history: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transitions)
+}
+
+// illegal status changes are the caller's fault, anything else is ours
+func (h *BillHandler) SetInstallmentPlan(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(r.PathValue("payment_id"))
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.InstallmentPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	installments, err := h.billService.SetInstallmentPlan(r.Context(), managerID, paymentID, req)
+	if err != nil {
+		http.Error(w, "Failed to set installment plan: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(installments)
+}
+
+func (h *BillHandler) GetInstallmentPlan(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(r.PathValue("payment_id"))
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	installments, err := h.billService.GetInstallmentPlan(r.Context(), userID, paymentID)
+	if err != nil {
+		http.Error(w, "Failed to get installment plan: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(installments)
+}
+
+func (h *BillHandler) DeleteInstallmentPlan(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(r.PathValue("payment_id"))
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	if err := h.billService.DeleteInstallmentPlan(r.Context(), managerID, paymentID); err != nil {
+		http.Error(w, "Failed to delete installment plan: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func paymentErrorStatus(err error) int {
+	if errors.Is(err, models.ErrInvalidPaymentTransition) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 func (h *BillHandler) GetUnpaidBills(w http.ResponseWriter, r *http.Request) {
 	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
 
 	bills, err := h.billService.GetUnpaidBills(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Failed to get unpaid bills: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to get unpaid bills: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -274,7 +464,7 @@ func (h *BillHandler) GetUserPaymentHistory(w http.ResponseWriter, r *http.Reque
 
 	history, err := h.billService.GetUserPaymentHistory(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Failed to get payment history: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to get payment history: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+)
+
+type BillTemplateHandler struct {
+	billTemplateService services.BillTemplateService
+}
+
+func NewBillTemplateHandler(billTemplateService services.BillTemplateService) *BillTemplateHandler {
+	return &BillTemplateHandler{
+		billTemplateService: billTemplateService,
+	}
+}
+
+func (h *BillTemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.CreateBillTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "Failed to get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	managerID, _ := strconv.Atoi(userIDString)
+
+	id, err := h.billTemplateService.CreateTemplate(r.Context(), managerID, apartmentID, req)
+	if err != nil {
+		http.Error(w, "Failed to create bill template: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int{"id": id})
+}
+
+func (h *BillTemplateHandler) GetTemplatesByApartment(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "Failed to get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	managerID, _ := strconv.Atoi(userIDString)
+
+	templates, err := h.billTemplateService.GetTemplatesByApartment(r.Context(), managerID, apartmentID)
+	if err != nil {
+		http.Error(w, "Failed to get bill templates: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+func (h *BillTemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID, err := strconv.Atoi(r.PathValue("template_id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	userIDString, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "Failed to get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	managerID, _ := strconv.Atoi(userIDString)
+
+	if err := h.billTemplateService.DeleteTemplate(r.Context(), managerID, templateID); err != nil {
+		http.Error(w, "Failed to delete bill template: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
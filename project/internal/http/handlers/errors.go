@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+)
+
+// the status to answer a failed service call with: 403 when the apartment
+// policy denied it, 404 when there is no handover, invitation or template to
+// act on,
+// 409 when the invitation was closed already, fallback otherwise
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, services.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrNoPendingHandover), errors.Is(err, services.ErrInvitationNotFound),
+		errors.Is(err, services.ErrTemplateNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repositories.ErrInvitationNotOpen):
+		return http.StatusConflict
+	}
+	return fallback
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+)
+
+type HandoverHandler struct {
+	handoverService services.HandoverService
+}
+
+func NewHandoverHandler(handoverService services.HandoverService) *HandoverHandler {
+	return &HandoverHandler{
+		handoverService: handoverService,
+	}
+}
+
+func (h *HandoverHandler) OfferHandover(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.HandoverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	handover, err := h.handoverService.OfferHandover(r.Context(), managerID, apartmentID, req)
+	if err != nil {
+		http.Error(w, "Failed to offer handover: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(handover)
+}
+
+func (h *HandoverHandler) GetPendingHandover(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	handover, err := h.handoverService.GetPendingHandover(r.Context(), userID, apartmentID)
+	if err != nil {
+		http.Error(w, "Failed to get handover: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(handover)
+}
+
+func (h *HandoverHandler) CancelHandover(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	if err := h.handoverService.CancelHandover(r.Context(), managerID, apartmentID); err != nil {
+		http.Error(w, "Failed to cancel handover: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *HandoverHandler) AcceptHandover(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	handover, err := h.handoverService.AcceptHandover(r.Context(), userID, apartmentID)
+	if err != nil {
+		http.Error(w, "Failed to accept handover: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(handover)
+}
+
+func (h *HandoverHandler) DeclineHandover(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	if err := h.handoverService.DeclineHandover(r.Context(), userID, apartmentID); err != nil {
+		http.Error(w, "Failed to decline handover: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
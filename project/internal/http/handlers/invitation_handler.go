@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+)
+
+type InvitationHandler struct {
+	invitationService services.InvitationService
+}
+
+func NewInvitationHandler(invitationService services.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+	}
+}
+
+func (h *InvitationHandler) GetApartmentInvitations(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	invitations, err := h.invitationService.GetApartmentInvitations(r.Context(), managerID, apartmentID)
+	if err != nil {
+		http.Error(w, "Failed to get invitations: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func (h *InvitationHandler) ResendInvitation(w http.ResponseWriter, r *http.Request) {
+	invitationID, err := strconv.Atoi(r.PathValue("invitation_id"))
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	invitation, err := h.invitationService.ResendInvitation(r.Context(), managerID, invitationID)
+	if err != nil {
+		http.Error(w, "Failed to resend invitation: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitation)
+}
+
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	invitationID, err := strconv.Atoi(r.PathValue("invitation_id"))
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	if err := h.invitationService.RevokeInvitation(r.Context(), managerID, invitationID); err != nil {
+		http.Error(w, "Failed to revoke invitation: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *InvitationHandler) GetMyInvitations(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	invitations, err := h.invitationService.GetMyInvitations(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get invitations: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func (h *InvitationHandler) RejectInvitation(w http.ResponseWriter, r *http.Request) {
+	invitationID, err := strconv.Atoi(r.PathValue("invitation_id"))
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	if err := h.invitationService.RejectInvitation(r.Context(), userID, invitationID); err != nil {
+		http.Error(w, "Failed to reject invitation: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+)
+
+type LateFeeHandler struct {
+	lateFeeService services.LateFeeService
+}
+
+func NewLateFeeHandler(lateFeeService services.LateFeeService) *LateFeeHandler {
+	return &LateFeeHandler{
+		lateFeeService: lateFeeService,
+	}
+}
+
+func (h *LateFeeHandler) SetRule(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.LateFeeRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	rule, err := h.lateFeeService.SetRule(r.Context(), managerID, apartmentID, req)
+	if err != nil {
+		http.Error(w, "Failed to set late fee rule: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *LateFeeHandler) GetRule(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	rule, err := h.lateFeeService.GetRule(r.Context(), managerID, apartmentID)
+	if err != nil {
+		http.Error(w, "Failed to get late fee rule: "+err.Error(), errorStatus(err, http.StatusNotFound))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *LateFeeHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	if err := h.lateFeeService.DeleteRule(r.Context(), managerID, apartmentID); err != nil {
+		http.Error(w, "Failed to delete late fee rule: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *LateFeeHandler) WaiveLateFee(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(r.PathValue("payment_id"))
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.WaiveLateFeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	payment, err := h.lateFeeService.WaiveLateFee(r.Context(), managerID, paymentID, req.Reason)
+	if err != nil {
+		http.Error(w, "Failed to waive late fee: "+err.Error(), paymentErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+func (h *LateFeeHandler) GetLateFeeEvents(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(r.PathValue("payment_id"))
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	events, err := h.lateFeeService.GetLateFeeEvents(r.Context(), userID, paymentID)
+	if err != nil {
+		http.Error(w, "Failed to get late fee history: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
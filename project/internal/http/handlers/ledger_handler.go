@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+)
+
+type LedgerHandler struct {
+	ledgerService services.LedgerService
+}
+
+func NewLedgerHandler(ledgerService services.LedgerService) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerService: ledgerService,
+	}
+}
+
+func (h *LedgerHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	requesterID, userID, apartmentID, ok := ledgerAccount(w, r)
+	if !ok {
+		return
+	}
+
+	balance, err := h.ledgerService.GetBalance(r.Context(), requesterID, userID, apartmentID)
+	if err != nil {
+		http.Error(w, "Failed to get balance: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":      userID,
+		"apartment_id": apartmentID,
+		"balance":      balance,
+	})
+}
+
+// statement for ?from=YYYY-MM-DD&to=YYYY-MM-DD, the current month by default
+func (h *LedgerHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	requesterID, userID, apartmentID, ok := ledgerAccount(w, r)
+	if !ok {
+		return
+	}
+
+	statement, err := h.ledgerService.GetStatement(r.Context(), requesterID, userID, apartmentID,
+		r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "Failed to get statement: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statement)
+}
+
+// the account a ledger request is about. residents ask for their own
+// account, managers name the resident in the path
+func ledgerAccount(w http.ResponseWriter, r *http.Request) (int, int, int, bool) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return 0, 0, 0, false
+	}
+
+	requesterID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	userID := requesterID
+	if r.PathValue("user_id") != "" {
+		userID, err = strconv.Atoi(r.PathValue("user_id"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return 0, 0, 0, false
+		}
+	}
+	return requesterID, userID, apartmentID, true
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+)
+
+type NotificationTemplateHandler struct {
+	templateService services.NotificationTemplateService
+}
+
+func NewNotificationTemplateHandler(templateService services.NotificationTemplateService) *NotificationTemplateHandler {
+	return &NotificationTemplateHandler{
+		templateService: templateService,
+	}
+}
+
+func (h *NotificationTemplateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	templates, err := h.templateService.GetTemplates(r.Context(), managerID, apartmentID)
+	if err != nil {
+		http.Error(w, "Failed to get notification templates: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+func (h *NotificationTemplateHandler) SetTemplate(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req notification.Template
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+	name, locale := r.PathValue("name"), models.Locale(r.PathValue("locale"))
+
+	template, err := h.templateService.SetTemplate(r.Context(), managerID, apartmentID, name, locale, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidTemplate) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, "Failed to save notification template: "+err.Error(), errorStatus(err, status))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+func (h *NotificationTemplateHandler) ResetTemplate(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+	name, locale := r.PathValue("name"), models.Locale(r.PathValue("locale"))
+
+	if err := h.templateService.ResetTemplate(r.Context(), managerID, apartmentID, name, locale); err != nil {
+		http.Error(w, "Failed to reset notification template: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
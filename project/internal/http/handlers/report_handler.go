@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/report"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+	"github.com/sirupsen/logrus"
+)
+
+type ReportHandler struct {
+	reportService services.ReportService
+}
+
+func NewReportHandler(reportService services.ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// report for ?group_by=month|bill_type|resident&from=YYYY-MM&to=YYYY-MM,
+// as JSON or, with ?format=csv|pdf, as a file download
+func (h *ReportHandler) GetApartmentReport(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" && format != "pdf" {
+		http.Error(w, "Invalid format (use json, csv or pdf)", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	apartmentReport, err := h.reportService.GetApartmentReport(r.Context(), managerID, apartmentID,
+		models.ReportGrouping(query.Get("group_by")), query.Get("from"), query.Get("to"))
+	if err != nil {
+		http.Error(w, "Failed to get report: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	if format == "" || format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(apartmentReport)
+		return
+	}
+
+	table := reportTable(apartmentReport)
+	filename := fmt.Sprintf("apartment-%d-%s-%s-%s.%s", apartmentID, apartmentReport.GroupBy,
+		apartmentReport.From, apartmentReport.To, format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		err = report.WriteCSV(w, table)
+	} else {
+		w.Header().Set("Content-Type", "application/pdf")
+		err = report.WritePDF(w, table)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("apartment_id", apartmentID).Error("Failed to write report")
+	}
+}
+
+// lays the report out with the total as its last row
+func reportTable(apartmentReport *models.ApartmentReport) report.Table {
+	label := string(apartmentReport.GroupBy)
+	table := report.Table{
+		Title: fmt.Sprintf("Apartment %d report by %s, %s to %s", apartmentReport.ApartmentID,
+			label, apartmentReport.From, apartmentReport.To),
+		Headers: []string{label, "billed", "collected", "outstanding"},
+	}
+	for _, row := range apartmentReport.Rows {
+		table.Rows = append(table.Rows, reportCells(row))
+	}
+	table.Rows = append(table.Rows, reportCells(apartmentReport.Total))
+	return table
+}
+
+func reportCells(row models.ReportRow) []string {
+	return []string{row.Label, row.Billed.String(), row.Collected.String(), row.Outstanding.String()}
+}
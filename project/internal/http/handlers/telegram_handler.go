@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+)
+
+// the header telegram sends the webhook's secret token in
+const telegramSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// updates are far smaller, anything bigger isn't from telegram
+const maxUpdateSize = 1 << 20
+
+type TelegramHandler struct {
+	cfg      config.TelegramConfig
+	receiver notification.WebhookReceiver
+}
+
+// receiver is nil when nothing receives the bot's updates
+func NewTelegramHandler(cfg config.TelegramConfig, receiver notification.WebhookReceiver) *TelegramHandler {
+	return &TelegramHandler{
+		cfg:      cfg,
+		receiver: receiver,
+	}
+}
+
+// takes the updates telegram posts to the bot's webhook. only requests with
+// the configured secret token are let through, and only in webhook mode
+func (h *TelegramHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	if h.receiver == nil || h.cfg.Mode != notification.TelegramWebhookMode {
+		http.NotFound(w, r)
+		return
+	}
+
+	secret := r.Header.Get(telegramSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(h.cfg.WebhookSecret)) != 1 {
+		http.Error(w, "Invalid secret token", http.StatusUnauthorized)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUpdateSize))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.receiver.ReceiveUpdate(r.Context(), payload); err != nil {
+		status := http.StatusServiceUnavailable
+		if errors.Is(err, notification.ErrInvalidUpdate) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, "Failed to receive update: "+err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
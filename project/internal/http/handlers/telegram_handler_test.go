@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeWebhookReceiver struct {
+	payloads []string
+}
+
+func (f *fakeWebhookReceiver) ReceiveUpdate(ctx context.Context, payload []byte) error {
+	if !strings.HasPrefix(string(payload), "{") {
+		return fmt.Errorf("%w: not json", notification.ErrInvalidUpdate)
+	}
+	f.payloads = append(f.payloads, string(payload))
+	return nil
+}
+
+func TestTelegramWebhook(t *testing.T) {
+	webhook := config.TelegramConfig{Mode: notification.TelegramWebhookMode, WebhookSecret: "s3cret"}
+	tests := []struct {
+		name           string
+		cfg            config.TelegramConfig
+		secret         string
+		body           string
+		expectedStatus int
+		received       bool
+	}{
+		{name: "update with the secret token", cfg: webhook, secret: "s3cret", body: `{"update_id": 1}`, expectedStatus: http.StatusOK, received: true},
+		{name: "wrong secret token", cfg: webhook, secret: "guess", body: `{"update_id": 1}`, expectedStatus: http.StatusUnauthorized},
+		{name: "no secret token", cfg: webhook, body: `{"update_id": 1}`, expectedStatus: http.StatusUnauthorized},
+		{name: "not an update", cfg: webhook, secret: "s3cret", body: "hello", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "polling mode",
+			cfg:            config.TelegramConfig{Mode: notification.TelegramPollingMode, WebhookSecret: "s3cret"},
+			secret:         "s3cret",
+			body:           `{"update_id": 1}`,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := &fakeWebhookReceiver{}
+			handler := NewTelegramHandler(tt.cfg, receiver)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/telegram/webhook", strings.NewReader(tt.body))
+			if tt.secret != "" {
+				req.Header.Set("X-Telegram-Bot-Api-Secret-Token", tt.secret)
+			}
+			rr := httptest.NewRecorder()
+			handler.Webhook(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.received, len(receiver.payloads) == 1)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
+)
+
+type UnitHandler struct {
+	unitService services.UnitService
+}
+
+func NewUnitHandler(unitService services.UnitService) *UnitHandler {
+	return &UnitHandler{
+		unitService: unitService,
+	}
+}
+
+func (h *UnitHandler) CreateUnit(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UnitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	unit, err := h.unitService.CreateUnit(r.Context(), managerID, apartmentID, req)
+	if err != nil {
+		http.Error(w, "Failed to create unit: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(unit)
+}
+
+func (h *UnitHandler) GetUnits(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	units, err := h.unitService.GetUnits(r.Context(), userID, apartmentID)
+	if err != nil {
+		http.Error(w, "Failed to get units: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(units)
+}
+
+func (h *UnitHandler) UpdateUnit(w http.ResponseWriter, r *http.Request) {
+	unitID, err := strconv.Atoi(r.PathValue("unit_id"))
+	if err != nil {
+		http.Error(w, "Invalid unit ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UnitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	unit, err := h.unitService.UpdateUnit(r.Context(), managerID, unitID, req)
+	if err != nil {
+		http.Error(w, "Failed to update unit: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(unit)
+}
+
+func (h *UnitHandler) DeleteUnit(w http.ResponseWriter, r *http.Request) {
+	unitID, err := strconv.Atoi(r.PathValue("unit_id"))
+	if err != nil {
+		http.Error(w, "Invalid unit ID", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	if err := h.unitService.DeleteUnit(r.Context(), managerID, unitID); err != nil {
+		http.Error(w, "Failed to delete unit: "+err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UnitHandler) AssignMember(w http.ResponseWriter, r *http.Request) {
+	apartmentID, err := strconv.Atoi(r.PathValue("apartment_id"))
+	if err != nil {
+		http.Error(w, "Invalid apartment ID", http.StatusBadRequest)
+		return
+	}
+	memberID, err := strconv.Atoi(r.PathValue("user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.AssignUnitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	managerID, _ := strconv.Atoi(r.Context().Value(middleware.UserIDKey).(string))
+
+	if err := h.unitService.AssignMember(r.Context(), managerID, apartmentID, memberID, req); err != nil {
+		http.Error(w, "Failed to assign unit: "+err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
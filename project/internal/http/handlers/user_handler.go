@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -158,6 +159,91 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	utils.WriteSuccessResponse(w, "profile updated successfully", response)
 }
 
+func (h *UserHandler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	response, err := h.userService.GetNotificationPreferences(r.Context(), userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, "notification preferences retrieved successfully", response)
+}
+
+func (h *UserHandler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	var req dto.NotificationPreferences
+	if err := utils.DecodeJSONBody(w, r, &req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	response, err := h.userService.UpdateNotificationPreferences(r.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidNotificationChannels), errors.Is(err, services.ErrInvalidLocale):
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		case err.Error() == "user not found":
+			utils.WriteErrorResponse(w, http.StatusNotFound, "user not found")
+		default:
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "failed to update notification preferences")
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, "notification preferences updated successfully", response)
+}
+
+// a one-time code the user sends to the bot to link their telegram chat
+func (h *UserHandler) CreateTelegramLinkCode(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	response, err := h.userService.CreateTelegramLinkCode(r.Context(), userID, h.botAddress)
+	if err != nil {
+		if err.Error() == "user not found" {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "user not found")
+		} else {
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "failed to create telegram link code")
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, "telegram link code created successfully", response)
+}
+
+func (h *UserHandler) UnlinkTelegram(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	if err := h.userService.UnlinkTelegramChat(r.Context(), userID); err != nil {
+		if err.Error() == "user not found" {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "user not found")
+		} else {
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "failed to unlink telegram")
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, "telegram unlinked successfully", nil)
+}
+
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	userIDStr := r.PathValue("user_id")
 	userID, err := strconv.Atoi(userIDStr)
@@ -204,3 +290,58 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	utils.WriteSuccessResponse(w, "user deleted successfully", nil)
 }
+
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req dto.RefreshTokenRequest
+	if err := utils.DecodeJSONBody(w, r, &req); err != nil {
+		return
+	}
+
+	if !utils.ValidateRequiredFields(w, map[string]string{
+		"refresh_token": req.RefreshToken,
+	}) {
+		return
+	}
+
+	response, err := h.userService.RefreshToken(r.Context(), req.RefreshToken)
+	if err != nil {
+		if err.Error() == "invalid refresh token" {
+			utils.WriteErrorResponse(w, http.StatusUnauthorized, "invalid refresh token")
+		} else {
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "failed to refresh token")
+		}
+		log.WithError(err).Error("failed to refresh token")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, "token refreshed successfully", response)
+}
+
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getCurrentUserID(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	claims, ok := r.Context().Value(middleware.ClaimsKey).(*middleware.CustomClaims)
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+
+	//the refresh token is optional, without it only the access token is revoked
+	var req dto.RefreshTokenRequest
+	if r.ContentLength != 0 {
+		if err := utils.DecodeJSONBody(w, r, &req); err != nil {
+			return
+		}
+	}
+
+	if err := h.userService.Logout(r.Context(), userID, claims, req.RefreshToken); err != nil {
+		log.WithError(err).Error("failed to log out")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "failed to log out")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, "logged out successfully", nil)
+}
@@ -73,6 +73,56 @@ func (m *MockUserService) DeleteUser(ctx context.Context, userID int) error {
 	return args.Error(0)
 }
 
+func (m *MockUserService) RefreshToken(ctx context.Context, refreshToken string) (*dto.TokenResponse, error) {
+	args := m.Called(ctx, refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.TokenResponse), args.Error(1)
+}
+
+func (m *MockUserService) Logout(ctx context.Context, userID int, claims *middleware.CustomClaims, refreshToken string) error {
+	args := m.Called(ctx, userID, claims, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockUserService) GetNotificationPreferences(ctx context.Context, userID int) (*dto.NotificationPreferences, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.NotificationPreferences), args.Error(1)
+}
+
+func (m *MockUserService) UpdateNotificationPreferences(ctx context.Context, userID int, req dto.NotificationPreferences) (*dto.NotificationPreferences, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.NotificationPreferences), args.Error(1)
+}
+
+func (m *MockUserService) CreateTelegramLinkCode(ctx context.Context, userID int, botAddress string) (*dto.TelegramLinkCode, error) {
+	args := m.Called(ctx, userID, botAddress)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.TelegramLinkCode), args.Error(1)
+}
+
+func (m *MockUserService) LinkTelegramChat(ctx context.Context, code string, chatID int64) (*models.User, error) {
+	args := m.Called(ctx, code, chatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) UnlinkTelegramChat(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
 func TestUserHandler_SignUp(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -323,6 +373,73 @@ func TestUserHandler_GetProfile(t *testing.T) {
 	}
 }
 
+func TestUserHandler_TelegramLink(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		mockSetup      func(*MockUserService)
+		expectedStatus int
+	}{
+		{
+			name:   "code for the bot",
+			method: http.MethodPost,
+			mockSetup: func(m *MockUserService) {
+				m.On("CreateTelegramLinkCode", mock.Anything, 1, "https://t.me/testbot").
+					Return(&dto.TelegramLinkCode{Code: "K7M2Q9XA"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "code fails to be saved",
+			method: http.MethodPost,
+			mockSetup: func(m *MockUserService) {
+				m.On("CreateTelegramLinkCode", mock.Anything, 1, "https://t.me/testbot").
+					Return(nil, fmt.Errorf("failed to create telegram link code: redis down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:   "unlink",
+			method: http.MethodDelete,
+			mockSetup: func(m *MockUserService) {
+				m.On("UnlinkTelegramChat", mock.Anything, 1).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "unlink a missing user",
+			method: http.MethodDelete,
+			mockSetup: func(m *MockUserService) {
+				m.On("UnlinkTelegramChat", mock.Anything, 1).Return(fmt.Errorf("user not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockUserService{}
+			tt.mockSetup(mockService)
+
+			handler := NewUserHandler(mockService, "https://t.me/testbot")
+
+			req := httptest.NewRequest(tt.method, "/profile/telegram", nil)
+			ctx := context.WithValue(req.Context(), middleware.UserIDKey, "1")
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			if tt.method == http.MethodPost {
+				handler.CreateTelegramLinkCode(w, req)
+			} else {
+				handler.UnlinkTelegram(w, req)
+			}
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestUserHandler_UpdateProfile(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -2,8 +2,11 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"strconv"
 
 	"errors"
 	"net/http"
@@ -19,6 +22,7 @@ type contextKey string
 
 const UserIDKey contextKey = "user_id"
 const IdempotentKey contextKey = "idempotent_key"
+const ClaimsKey contextKey = "claims"
 
 type CustomClaims struct {
 	EncryptedUserID string          `json:"uid"`
@@ -26,9 +30,16 @@ type CustomClaims struct {
 	jwt.RegisteredClaims
 }
 
-var jwtSecret = []byte("arcaptcha-project")
+// RevocationChecker tells whether an access token was revoked before it
+// expired, by logging out or because its user was deleted
+type RevocationChecker interface {
+	IsTokenRevoked(ctx context.Context, tokenID string, userID int, issuedAt time.Time) (bool, error)
+}
 
-func JWTAuthMiddleware(userMode ...models.UserType) func(http.Handler) http.Handler {
+// checks the bearer token of the request against keys and puts the user id
+// and the claims in its context. revoked tokens are rejected when revocations
+// is set
+func JWTAuthMiddleware(keys *KeySet, revocations RevocationChecker, userMode ...models.UserType) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -38,59 +49,92 @@ func JWTAuthMiddleware(userMode ...models.UserType) func(http.Handler) http.Hand
 			}
 
 			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-			log.Printf("Validating token: %s", tokenStr)
 
-			userID, err := ValidateToken(tokenStr, userMode...)
+			userID, claims, err := keys.ParseToken(tokenStr, userMode...)
 			if err != nil {
 				log.Printf("Token validation failed: %v", err)
 				http.Error(w, "Invalid or expired token: "+err.Error(), http.StatusUnauthorized)
 				return
 			}
 
+			if revocations != nil {
+				id, _ := strconv.Atoi(userID)
+				revoked, err := revocations.IsTokenRevoked(r.Context(), claims.ID, id, claims.IssuedAt.Time)
+				if err != nil {
+					//failing closed, a revoked token must not get through
+					//while Redis is down
+					log.Printf("Token revocation check failed: %v", err)
+					http.Error(w, "failed to verify token", http.StatusServiceUnavailable)
+					return
+				}
+				if revoked {
+					http.Error(w, "Invalid or expired token: token has been revoked", http.StatusUnauthorized)
+					return
+				}
+			}
+
 			log.Printf("Token validated successfully for user: %s", userID)
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			ctx = context.WithValue(ctx, ClaimsKey, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-func GenerateToken(userID string, userType models.UserType) (string, error) {
-	encryptedID, err := utils.Encrypt(userID)
+// issues an access token valid for ttl, signed with the active key. every
+// token gets a random id, which is what revoking it refers to
+func (k *KeySet) GenerateToken(userID string, userType models.UserType, ttl time.Duration) (string, *CustomClaims, error) {
+	encryptedID, err := utils.Encrypt(k.aesKey, userID)
 	if err != nil {
-		return "", fmt.Errorf("failed to encrypt user ID: %w", err)
+		return "", nil, fmt.Errorf("failed to encrypt user ID: %w", err)
 	}
 
-	expirationTime := time.Now().Add(24 * time.Hour)
+	tokenID := make([]byte, 16)
+	if _, err := rand.Read(tokenID); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token ID: %w", err)
+	}
 
+	now := time.Now()
 	claims := &CustomClaims{
 		EncryptedUserID: encryptedID,
 		UserType:        userType,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        hex.EncodeToString(tokenID),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token := jwt.NewWithClaims(k.active.method, claims)
+	token.Header["kid"] = k.active.id
+	signed, err := token.SignedString(k.active.signKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
-func ValidateToken(tokenStr string, userType ...models.UserType) (string, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+func (k *KeySet) ValidateToken(tokenStr string, userType ...models.UserType) (string, error) {
+	userID, _, err := k.ParseToken(tokenStr, userType...)
+	return userID, err
+}
+
+// checks the signature, expiry and user type of a token and returns the
+// decrypted user id with the claims
+func (k *KeySet) ParseToken(tokenStr string, userType ...models.UserType) (string, *CustomClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &CustomClaims{}, k.verifyKey)
 
 	if err != nil {
-		return "", fmt.Errorf("token parsing failed: %w", err) // Wrap the error
+		return "", nil, fmt.Errorf("token parsing failed: %w", err) // Wrap the error
 	}
 
 	if !token.Valid {
-		return "", errors.New("invalid token")
+		return "", nil, errors.New("invalid token")
 	}
 
 	claims, ok := token.Claims.(*CustomClaims)
 	if !ok {
-		return "", errors.New("invalid token claims")
+		return "", nil, errors.New("invalid token claims")
 	}
 
 	//skipping user type validation if no types are specified
@@ -103,16 +147,37 @@ func ValidateToken(tokenStr string, userType ...models.UserType) (string, error)
 			}
 		}
 		if !validType {
-			return "", fmt.Errorf("not authorized for user type: %s", claims.UserType)
+			return "", nil, fmt.Errorf("not authorized for user type: %s", claims.UserType)
 		}
 	}
 
-	decryptedID, err := utils.Decrypt(claims.EncryptedUserID)
+	decryptedID, err := utils.Decrypt(k.aesKey, claims.EncryptedUserID)
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt user ID: %w", err)
+		return "", nil, fmt.Errorf("failed to decrypt user ID: %w", err)
 	}
 
-	return decryptedID, nil
+	return decryptedID, claims, nil
+}
+
+// lets only the given user types through. goes after JWTAuthMiddleware, for
+// routes that aren't about one apartment and so have no role to check
+func RequireUserType(userTypes ...models.UserType) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(ClaimsKey).(*CustomClaims)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			for _, userType := range userTypes {
+				if claims.UserType == userType {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "Forbidden - not allowed for user type: "+string(claims.UserType), http.StatusForbidden)
+		})
+	}
 }
 
 func IdempotentKeyMiddleware(next http.Handler) http.Handler {
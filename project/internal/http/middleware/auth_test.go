@@ -5,14 +5,29 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/stretchr/testify/assert"
 )
 
+func testKeySet(t *testing.T) *KeySet {
+	keys, err := NewKeySet(config.Auth{
+		EncryptionKey: "test-encryption-key",
+		SigningKeys: []config.SigningKey{
+			{ID: "test", Algorithm: "HS256", Secret: "test-secret-that-is-at-least-32-chars"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load keys: %v", err)
+	}
+	return keys
+}
+
 func TestJWTAuthMiddleware(t *testing.T) {
-	jwtSecret = []byte("arcaptcha-project")
-	validToken, err := GenerateToken("123", models.Manager)
+	keys := testKeySet(t)
+	validToken, _, err := keys.GenerateToken("123", models.Manager, time.Hour)
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
@@ -65,7 +80,7 @@ func TestJWTAuthMiddleware(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var ctx context.Context
-			handler := JWTAuthMiddleware(models.Manager)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler := JWTAuthMiddleware(keys, nil, models.Manager)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				ctx = r.Context()
 				userID := ctx.Value(UserIDKey)
 				t.Logf("Context userID: %v", userID)
@@ -91,39 +106,95 @@ func TestJWTAuthMiddleware(t *testing.T) {
 	}
 }
 
+type revokedTokens map[string]bool
+
+func (r revokedTokens) IsTokenRevoked(_ context.Context, tokenID string, _ int, _ time.Time) (bool, error) {
+	return r[tokenID], nil
+}
+
+func TestJWTAuthMiddleware_RevokedToken(t *testing.T) {
+	keys := testKeySet(t)
+	token, claims, err := keys.GenerateToken("123", models.Resident, time.Hour)
+	assert.NoError(t, err)
+	other, _, err := keys.GenerateToken("123", models.Resident, time.Hour)
+	assert.NoError(t, err)
+
+	handler := JWTAuthMiddleware(keys, revokedTokens{claims.ID: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "123", r.Context().Value(UserIDKey))
+		assert.NotNil(t, r.Context().Value(ClaimsKey))
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for bearer, expected := range map[string]int{token: http.StatusUnauthorized, other: http.StatusOK} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, expected, w.Code)
+	}
+}
+
+func TestRequireUserType(t *testing.T) {
+	handler := RequireUserType(models.Manager)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for name, tc := range map[string]struct {
+		claims   *CustomClaims
+		expected int
+	}{
+		"manager":   {&CustomClaims{UserType: models.Manager}, http.StatusOK},
+		"resident":  {&CustomClaims{UserType: models.Resident}, http.StatusForbidden},
+		"no claims": {nil, http.StatusUnauthorized},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), ClaimsKey, tc.claims))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tc.expected, w.Code)
+		})
+	}
+}
+
 func TestGenerateAndValidateToken(t *testing.T) {
 	userID := "123"
 	userType := models.Manager
+	keys := testKeySet(t)
 
-	token, err := GenerateToken(userID, userType)
+	token, _, err := keys.GenerateToken(userID, userType, time.Hour)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 
-	validatedID, err := ValidateToken(token, userType)
+	validatedID, err := keys.ValidateToken(token, userType)
 	assert.NoError(t, err)
 	assert.Equal(t, userID, validatedID)
 
-	_, err = ValidateToken(token, models.Resident)
+	_, err = keys.ValidateToken(token, models.Resident)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not authorized for user type")
 
-	validatedID, err = ValidateToken(token)
+	validatedID, err = keys.ValidateToken(token)
 	assert.NoError(t, err)
 	assert.Equal(t, userID, validatedID)
 
-	validatedID, err = ValidateToken(token, models.Manager, models.Resident)
+	validatedID, err = keys.ValidateToken(token, models.Manager, models.Resident)
 	assert.NoError(t, err)
 	assert.Equal(t, userID, validatedID)
 }
 
 func TestGenerateAndValidateToken_InvalidToken(t *testing.T) {
+	keys := testKeySet(t)
+
 	//malformed token
-	_, err := ValidateToken("invalid.token.blahblah")
+	_, err := keys.ValidateToken("invalid.token.blahblah")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "token parsing failed")
 
 	//empty token
-	_, err = ValidateToken("")
+	_, err = keys.ValidateToken("")
 	assert.Error(t, err)
 }
 
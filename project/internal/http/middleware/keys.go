@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/utils"
+)
+
+// KeySet holds the keys of the access tokens: new tokens are signed with the
+// active key and carry its id in the kid header, and a token is accepted
+// when it was signed by any key of the set
+type KeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	aesKey []byte // encrypts the user id inside the tokens
+}
+
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}      // nil for a key that only verifies
+	verifyKey interface{}      // the secret or the public key
+	publicKey crypto.PublicKey // published in the JWKS, nil for HS256
+}
+
+// JWK is a public key in the JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // Ed25519
+	X         string `json:"x,omitempty"`   // Ed25519 public key
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewKeySet(cfg config.Auth) (*KeySet, error) {
+	if cfg.EncryptionKey == "" {
+		return nil, errors.New("auth.encryption_key is required")
+	}
+	if len(cfg.SigningKeys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+
+	keys := &KeySet{
+		keys:   make(map[string]*signingKey, len(cfg.SigningKeys)),
+		aesKey: utils.AESKey(cfg.EncryptionKey),
+	}
+	for _, keyCfg := range cfg.SigningKeys {
+		key, err := loadSigningKey(keyCfg)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", keyCfg.ID, err)
+		}
+		if _, ok := keys.keys[key.id]; ok {
+			return nil, fmt.Errorf("signing key id %q is used twice", key.id)
+		}
+		keys.keys[key.id] = key
+	}
+
+	activeID := cfg.SigningKeyID
+	if activeID == "" {
+		activeID = cfg.SigningKeys[0].ID
+	}
+	active, ok := keys.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found", activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key to sign with", activeID)
+	}
+	keys.active = active
+	return keys, nil
+}
+
+func loadSigningKey(cfg config.SigningKey) (*signingKey, error) {
+	if cfg.ID == "" {
+		return nil, errors.New("kid is required")
+	}
+	key := &signingKey{id: cfg.ID}
+
+	switch cfg.Algorithm {
+	case "HS256":
+		if len(cfg.Secret) < 32 {
+			return nil, errors.New("HS256 secret must be at least 32 characters")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(cfg.Secret)
+		key.verifyKey = key.signKey
+		return key, nil
+
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+		if cfg.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey, key.publicKey = private, &private.PublicKey
+		} else {
+			pem, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if key.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(pem); err != nil {
+				return nil, err
+			}
+		}
+
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+		if cfg.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey, key.publicKey = private, private.(ed25519.PrivateKey).Public()
+		} else {
+			pem, err := os.ReadFile(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if key.publicKey, err = jwt.ParseEdPublicKeyFromPEM(pem); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q, use HS256, RS256 or EdDSA", cfg.Algorithm)
+	}
+
+	key.verifyKey = key.publicKey
+	return key, nil
+}
+
+// picks the key a token names in its kid header. the algorithm has to be
+// the key's own, a public key must never be taken for an HMAC secret
+func (k *KeySet) verifyKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("signing key %q does not use %s", kid, token.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+// the public keys of the set for other services to verify our tokens with.
+// HS256 secrets are never published
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		jwk := JWK{KeyID: key.id, Algorithm: key.method.Alg(), Use: "sig"}
+		switch public := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID
+	})
+	return jwks
+}
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writes the private and public halves of key as PEM files and returns their
+// paths
+func writeKeyFiles(t *testing.T, name string, private interface{}, public interface{}) (string, string) {
+	dir := t.TempDir()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	privateFile := filepath.Join(dir, name+".pem")
+	publicFile := filepath.Join(dir, name+".pub.pem")
+	require.NoError(t, os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600))
+	require.NoError(t, os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o644))
+	return privateFile, publicFile
+}
+
+func TestKeySet_AsymmetricKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPrivate, _ := writeKeyFiles(t, "rsa", rsaKey, &rsaKey.PublicKey)
+
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edPrivate, _ := writeKeyFiles(t, "ed", edKey, edPublic)
+
+	tests := []struct {
+		name string
+		key  config.SigningKey
+		alg  string
+	}{
+		{name: "RS256", key: config.SigningKey{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: rsaPrivate}, alg: "RS256"},
+		{name: "EdDSA", key: config.SigningKey{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: edPrivate}, alg: "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := NewKeySet(config.Auth{EncryptionKey: "key", SigningKeys: []config.SigningKey{tt.key}})
+			require.NoError(t, err)
+
+			token, _, err := keys.GenerateToken("42", models.Resident, time.Hour)
+			require.NoError(t, err)
+
+			userID, claims, err := keys.ParseToken(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "42", userID)
+			assert.Equal(t, models.Resident, claims.UserType)
+
+			jwks := keys.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, tt.key.ID, jwks.Keys[0].KeyID)
+			assert.Equal(t, tt.alg, jwks.Keys[0].Algorithm)
+			assert.Equal(t, "sig", jwks.Keys[0].Use)
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	oldPrivate, oldPublic := writeKeyFiles(t, "old", oldKey, &oldKey.PublicKey)
+	newPublic, newKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	newPrivate, _ := writeKeyFiles(t, "new", newKey, newPublic)
+
+	before, err := NewKeySet(config.Auth{
+		EncryptionKey: "key",
+		SigningKeys:   []config.SigningKey{{ID: "old", Algorithm: "RS256", PrivateKeyFile: oldPrivate}},
+	})
+	require.NoError(t, err)
+	oldToken, _, err := before.GenerateToken("7", models.Manager, time.Hour)
+	require.NoError(t, err)
+
+	//the new key signs while the old one is kept to verify what it issued
+	after, err := NewKeySet(config.Auth{
+		EncryptionKey: "key",
+		SigningKeyID:  "new",
+		SigningKeys: []config.SigningKey{
+			{ID: "old", Algorithm: "RS256", PublicKeyFile: oldPublic},
+			{ID: "new", Algorithm: "EdDSA", PrivateKeyFile: newPrivate},
+		},
+	})
+	require.NoError(t, err)
+
+	userID, err := after.ValidateToken(oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "7", userID)
+
+	newToken, _, err := after.GenerateToken("7", models.Manager, time.Hour)
+	require.NoError(t, err)
+	_, err = before.ValidateToken(newToken)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown signing key")
+
+	assert.Len(t, after.JWKS().Keys, 2)
+}
+
+func TestKeySet_RejectsMismatchedAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, rsaPublic := writeKeyFiles(t, "rsa", rsaKey, &rsaKey.PublicKey)
+
+	//an HS256 token claiming the kid of an RSA key must not be verified
+	//with the public key as its secret
+	forger, err := NewKeySet(config.Auth{
+		EncryptionKey: "key",
+		SigningKeys:   []config.SigningKey{{ID: "rsa", Algorithm: "HS256", Secret: "test-secret-that-is-at-least-32-chars"}},
+	})
+	require.NoError(t, err)
+	token, _, err := forger.GenerateToken("1", models.Manager, time.Hour)
+	require.NoError(t, err)
+
+	keys, err := NewKeySet(config.Auth{
+		EncryptionKey: "key",
+		SigningKeys: []config.SigningKey{
+			{ID: "hs", Algorithm: "HS256", Secret: "another-secret-that-is-at-least-32"},
+			{ID: "rsa", Algorithm: "RS256", PublicKeyFile: rsaPublic},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = keys.ValidateToken(token)
+	assert.Error(t, err)
+
+	//HS256 secrets are never published
+	jwks := keys.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "RSA", jwks.Keys[0].KeyType)
+}
+
+// the example configuration has to start as it is
+func TestNewKeySet_ExampleConfig(t *testing.T) {
+	cfg, err := config.InitConfig(filepath.Join("..", "..", "..", "config", "config.example.yml"))
+	require.NoError(t, err)
+
+	keys, err := NewKeySet(cfg.Auth)
+	require.NoError(t, err)
+
+	token, _, err := keys.GenerateToken("1", models.Resident, time.Minute)
+	require.NoError(t, err)
+	userID, err := keys.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", userID)
+}
+
+func TestNewKeySet_InvalidConfig(t *testing.T) {
+	secret := config.SigningKey{ID: "hs", Algorithm: "HS256", Secret: "test-secret-that-is-at-least-32-chars"}
+
+	tests := []struct {
+		name          string
+		cfg           config.Auth
+		expectedError string
+	}{
+		{
+			name:          "no encryption key",
+			cfg:           config.Auth{SigningKeys: []config.SigningKey{secret}},
+			expectedError: "encryption_key",
+		},
+		{
+			name:          "no signing keys",
+			cfg:           config.Auth{EncryptionKey: "key"},
+			expectedError: "at least one signing key",
+		},
+		{
+			name:          "unknown active key",
+			cfg:           config.Auth{EncryptionKey: "key", SigningKeyID: "other", SigningKeys: []config.SigningKey{secret}},
+			expectedError: "not found",
+		},
+		{
+			name: "short secret",
+			cfg: config.Auth{EncryptionKey: "key", SigningKeys: []config.SigningKey{
+				{ID: "hs", Algorithm: "HS256", Secret: "short"},
+			}},
+			expectedError: "at least 32 characters",
+		},
+		{
+			name: "unsupported algorithm",
+			cfg: config.Auth{EncryptionKey: "key", SigningKeys: []config.SigningKey{
+				{ID: "es", Algorithm: "ES256"},
+			}},
+			expectedError: "unsupported algorithm",
+		},
+		{
+			name:          "duplicate kid",
+			cfg:           config.Auth{EncryptionKey: "key", SigningKeys: []config.SigningKey{secret, secret}},
+			expectedError: "used twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeySet(tt.cfg)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
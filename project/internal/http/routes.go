@@ -11,6 +11,10 @@ import (
 func (s *ApartmantService) SetupRoutes(mux *http.ServeMux) {
 	v1 := utils.APIPrefix(mux)
 
+	mux.HandleFunc("/.well-known/jwks.json", utils.MethodHandler(map[string]http.HandlerFunc{
+		"GET": s.jwksHandler.GetJWKS,
+	}))
+
 	// public routes
 	v1.HandleFunc("/user/signup", utils.MethodHandler(map[string]http.HandlerFunc{
 		"POST": s.userHandler.SignUp,
@@ -18,34 +22,121 @@ func (s *ApartmantService) SetupRoutes(mux *http.ServeMux) {
 	v1.HandleFunc("/user/login", utils.MethodHandler(map[string]http.HandlerFunc{
 		"POST": s.userHandler.Login,
 	}))
+	v1.HandleFunc("/user/refresh", utils.MethodHandler(map[string]http.HandlerFunc{
+		"POST": s.userHandler.RefreshToken,
+	}))
+	v1.Handle("/user/logout", middleware.JWTAuthMiddleware(s.keys, s.tokenRepo, models.Manager, models.Resident)(utils.MethodHandler(map[string]http.HandlerFunc{
+		"POST": s.userHandler.Logout,
+	})))
+	v1.HandleFunc("/payment/callback/{provider}", utils.MethodHandler(map[string]http.HandlerFunc{
+		"GET": s.billHandler.PaymentCallback,
+	}))
+	// telegram proves the update is its own with the webhook's secret token
+	v1.HandleFunc("/telegram/webhook", utils.MethodHandler(map[string]http.HandlerFunc{
+		"POST": s.telegramHandler.Webhook,
+	}))
 
-	// manager routes
+	// manager routes. what a user may do in an apartment comes from their
+	// role there, so residents holding a role get in too. routes about users
+	// and creating apartments stay with managers
 	managerRoutes := http.NewServeMux()
-	v1.Handle("/manager/", http.StripPrefix("/manager", middleware.JWTAuthMiddleware(models.Manager)(managerRoutes)))
+	managersOnly := middleware.RequireUserType(models.Manager)
+	v1.Handle("/manager/", http.StripPrefix("/manager", middleware.JWTAuthMiddleware(s.keys, s.tokenRepo, models.Manager, models.Resident)(managerRoutes)))
 
-	managerRoutes.HandleFunc("/user/get-all", utils.MethodHandler(map[string]http.HandlerFunc{
+	managerRoutes.Handle("/user/get-all", managersOnly(utils.MethodHandler(map[string]http.HandlerFunc{
 		"GET": s.userHandler.GetAllUsers,
-	}))
-	managerRoutes.HandleFunc("/user/{user_id}", utils.MethodHandler(map[string]http.HandlerFunc{
+	})))
+	managerRoutes.Handle("/user/{user_id}", managersOnly(utils.MethodHandler(map[string]http.HandlerFunc{
 		"GET":    s.userHandler.GetUser,
 		"DELETE": s.userHandler.DeleteUser,
-	}))
+	})))
 
 	managerRoutes.HandleFunc("/apartment", s.methodHandler(map[string]http.HandlerFunc{
-		"POST":   s.apartmentHandler.CreateApartment,
+		"POST":   managersOnly(http.HandlerFunc(s.apartmentHandler.CreateApartment)).ServeHTTP,
 		"GET":    s.apartmentHandler.GetApartmentByID,
 		"PUT":    s.apartmentHandler.UpdateApartment,
 		"DELETE": s.apartmentHandler.DeleteApartment,
 	}))
-	managerRoutes.HandleFunc("/apartments/get-all/resident/{user_id}", s.methodHandler(map[string]http.HandlerFunc{
+	managerRoutes.Handle("/apartments/get-all/resident/{user_id}", managersOnly(s.methodHandler(map[string]http.HandlerFunc{
 		"GET": s.apartmentHandler.GetAllApartmentsForResident,
-	}))
+	})))
 	managerRoutes.HandleFunc("/apartment/{apartment_id}/residents", s.methodHandler(map[string]http.HandlerFunc{
 		"GET": s.apartmentHandler.GetResidentsInApartment,
 	}))
 	managerRoutes.HandleFunc("/apartment/{apartment_id}/invite/resident/{telegram_username}", s.methodHandler(map[string]http.HandlerFunc{
 		"POST": s.apartmentHandler.InviteUserToApartment,
 	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/members", s.methodHandler(map[string]http.HandlerFunc{
+		"GET": s.apartmentHandler.GetMembers,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/members/{user_id}/role", s.methodHandler(map[string]http.HandlerFunc{
+		"PUT":    s.apartmentHandler.GrantRole,
+		"DELETE": s.apartmentHandler.RevokeRole,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/managers", s.methodHandler(map[string]http.HandlerFunc{
+		"GET": s.apartmentHandler.GetManagers,
+	}))
+	// the incoming manager may be a resident account, they accept and
+	// decline here too
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/handover", s.methodHandler(map[string]http.HandlerFunc{
+		"POST":   s.handoverHandler.OfferHandover,
+		"GET":    s.handoverHandler.GetPendingHandover,
+		"DELETE": s.handoverHandler.CancelHandover,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/handover/accept", s.methodHandler(map[string]http.HandlerFunc{
+		"POST": s.handoverHandler.AcceptHandover,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/handover/decline", s.methodHandler(map[string]http.HandlerFunc{
+		"POST": s.handoverHandler.DeclineHandover,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/invite", s.methodHandler(map[string]http.HandlerFunc{
+		"POST": s.apartmentHandler.InviteByContact,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/invitations", s.methodHandler(map[string]http.HandlerFunc{
+		"GET": s.invitationHandler.GetApartmentInvitations,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/notification-templates", s.methodHandler(map[string]http.HandlerFunc{
+		"GET": s.notificationTemplateHandler.GetTemplates,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/notification-templates/{name}/{locale}", s.methodHandler(map[string]http.HandlerFunc{
+		"PUT":    s.notificationTemplateHandler.SetTemplate,
+		"DELETE": s.notificationTemplateHandler.ResetTemplate,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/notifications/failed", s.methodHandler(map[string]http.HandlerFunc{
+		"GET": s.outboxHandler.GetFailedDeliveries,
+	}))
+	managerRoutes.HandleFunc("/invitation/{invitation_id}", s.methodHandler(map[string]http.HandlerFunc{
+		"DELETE": s.invitationHandler.RevokeInvitation,
+	}))
+	managerRoutes.HandleFunc("/invitation/{invitation_id}/resend", s.methodHandler(map[string]http.HandlerFunc{
+		"POST": s.invitationHandler.ResendInvitation,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/units", s.methodHandler(map[string]http.HandlerFunc{
+		"POST": s.unitHandler.CreateUnit,
+		"GET":  s.unitHandler.GetUnits,
+	}))
+	managerRoutes.HandleFunc("/unit/{unit_id}", s.methodHandler(map[string]http.HandlerFunc{
+		"PUT":    s.unitHandler.UpdateUnit,
+		"DELETE": s.unitHandler.DeleteUnit,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/members/{user_id}/unit", s.methodHandler(map[string]http.HandlerFunc{
+		"PUT": s.unitHandler.AssignMember,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/division-policy", s.methodHandler(map[string]http.HandlerFunc{
+		"PUT": s.apartmentHandler.SetDivisionPolicy,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/residents/{user_id}/share", s.methodHandler(map[string]http.HandlerFunc{
+		"PUT": s.apartmentHandler.UpdateResidentShare,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/residents/{user_id}/balance", s.methodHandler(map[string]http.HandlerFunc{
+		"GET": s.ledgerHandler.GetBalance,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/residents/{user_id}/statement", s.methodHandler(map[string]http.HandlerFunc{
+		"GET": s.ledgerHandler.GetStatement,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/reports", s.methodHandler(map[string]http.HandlerFunc{
+		"GET": s.reportHandler.GetApartmentReport,
+	}))
 	managerRoutes.HandleFunc("/bill/{apartment_id}/create", utils.MethodHandler(map[string]http.HandlerFunc{
 		"POST": s.billHandler.CreateBill,
 	}))
@@ -66,21 +157,76 @@ func (s *ApartmantService) SetupRoutes(mux *http.ServeMux) {
 	managerRoutes.HandleFunc("/bills/get-all", utils.MethodHandler(map[string]http.HandlerFunc{
 		"GET": s.billHandler.GetBillsByApartment,
 	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/bill-templates", utils.MethodHandler(map[string]http.HandlerFunc{
+		"POST": s.billTemplateHandler.CreateTemplate,
+		"GET":  s.billTemplateHandler.GetTemplatesByApartment,
+	}))
+	managerRoutes.HandleFunc("/bill-template/{template_id}", utils.MethodHandler(map[string]http.HandlerFunc{
+		"DELETE": s.billTemplateHandler.DeleteTemplate,
+	}))
+	managerRoutes.HandleFunc("/apartment/{apartment_id}/late-fee", utils.MethodHandler(map[string]http.HandlerFunc{
+		"PUT":    s.lateFeeHandler.SetRule,
+		"GET":    s.lateFeeHandler.GetRule,
+		"DELETE": s.lateFeeHandler.DeleteRule,
+	}))
+	managerRoutes.HandleFunc("/payment/{payment_id}/late-fee", utils.MethodHandler(map[string]http.HandlerFunc{
+		"GET": s.lateFeeHandler.GetLateFeeEvents,
+	}))
+	managerRoutes.HandleFunc("/payment/{payment_id}/late-fee/waive", utils.MethodHandler(map[string]http.HandlerFunc{
+		"POST": s.lateFeeHandler.WaiveLateFee,
+	}))
+	managerRoutes.HandleFunc("/payment/{payment_id}/refund", utils.MethodHandler(map[string]http.HandlerFunc{
+		"POST": s.billHandler.RefundPayment,
+	}))
+	managerRoutes.HandleFunc("/payment/{payment_id}/chargeback", utils.MethodHandler(map[string]http.HandlerFunc{
+		"POST": s.billHandler.RecordChargeback,
+	}))
+	managerRoutes.HandleFunc("/payment/{payment_id}/transitions", utils.MethodHandler(map[string]http.HandlerFunc{
+		"GET": s.billHandler.GetPaymentTransitions,
+	}))
+	managerRoutes.HandleFunc("/payment/{payment_id}/installments", utils.MethodHandler(map[string]http.HandlerFunc{
+		"PUT":    s.billHandler.SetInstallmentPlan,
+		"GET":    s.billHandler.GetInstallmentPlan,
+		"DELETE": s.billHandler.DeleteInstallmentPlan,
+	}))
 	// resident routes
 	residentRoutes := http.NewServeMux()
-	v1.Handle("/resident/", http.StripPrefix("/resident", middleware.JWTAuthMiddleware(models.Resident, models.Manager)(residentRoutes)))
+	v1.Handle("/resident/", http.StripPrefix("/resident", middleware.JWTAuthMiddleware(s.keys, s.tokenRepo, models.Resident, models.Manager)(residentRoutes)))
 
 	residentRoutes.HandleFunc("/profile", utils.MethodHandler(map[string]http.HandlerFunc{
 		"GET": s.userHandler.GetProfile,
 		"PUT": s.userHandler.UpdateProfile,
 	}))
+	residentRoutes.HandleFunc("/profile/notifications", utils.MethodHandler(map[string]http.HandlerFunc{
+		"GET": s.userHandler.GetNotificationPreferences,
+		"PUT": s.userHandler.UpdateNotificationPreferences,
+	}))
+	residentRoutes.HandleFunc("/profile/telegram", utils.MethodHandler(map[string]http.HandlerFunc{
+		"DELETE": s.userHandler.UnlinkTelegram,
+	}))
+	residentRoutes.HandleFunc("/profile/telegram/link-code", utils.MethodHandler(map[string]http.HandlerFunc{
+		"POST": s.userHandler.CreateTelegramLinkCode,
+	}))
 	residentRoutes.HandleFunc("/apartment/invite/{invitation_code}", s.methodHandler(map[string]http.HandlerFunc{
 		"GET": s.apartmentHandler.JoinApartment,
 	}))
+	residentRoutes.HandleFunc("/invitations", s.methodHandler(map[string]http.HandlerFunc{
+		"GET": s.invitationHandler.GetMyInvitations,
+	}))
+	residentRoutes.HandleFunc("/invitation/{invitation_id}/reject", s.methodHandler(map[string]http.HandlerFunc{
+		"POST": s.invitationHandler.RejectInvitation,
+	}))
 	residentRoutes.HandleFunc("/apartment/leave", s.methodHandler(map[string]http.HandlerFunc{
 		"POST": s.apartmentHandler.LeaveApartment,
 	}))
 
+	residentRoutes.HandleFunc("/ledger/{apartment_id}/balance", utils.MethodHandler(map[string]http.HandlerFunc{
+		"GET": s.ledgerHandler.GetBalance,
+	}))
+	residentRoutes.HandleFunc("/ledger/{apartment_id}/statement", utils.MethodHandler(map[string]http.HandlerFunc{
+		"GET": s.ledgerHandler.GetStatement,
+	}))
+
 	residentRoutes.HandleFunc("/bills/pay/{payment_id}",
 		middleware.IdempotentKeyMiddleware(
 			utils.MethodHandler(map[string]http.HandlerFunc{
@@ -89,6 +235,14 @@ func (s *ApartmantService) SetupRoutes(mux *http.ServeMux) {
 		).ServeHTTP,
 	)
 
+	residentRoutes.HandleFunc("/bills/pay/{payment_id}/partial",
+		middleware.IdempotentKeyMiddleware(
+			utils.MethodHandler(map[string]http.HandlerFunc{
+				"POST": s.billHandler.PayPartial,
+			}),
+		).ServeHTTP,
+	)
+
 	residentRoutes.HandleFunc("/bills/pay-batch",
 		middleware.IdempotentKeyMiddleware(
 			utils.MethodHandler(map[string]http.HandlerFunc{
@@ -100,6 +254,12 @@ func (s *ApartmantService) SetupRoutes(mux *http.ServeMux) {
 	residentRoutes.HandleFunc("/bills/get-unpaid", utils.MethodHandler(map[string]http.HandlerFunc{
 		"GET": s.billHandler.GetUnpaidBills,
 	}))
+	residentRoutes.HandleFunc("/payment/{payment_id}/installments", utils.MethodHandler(map[string]http.HandlerFunc{
+		"GET": s.billHandler.GetInstallmentPlan,
+	}))
+	residentRoutes.HandleFunc("/payment/{payment_id}/late-fee", utils.MethodHandler(map[string]http.HandlerFunc{
+		"GET": s.lateFeeHandler.GetLateFeeEvents,
+	}))
 	residentRoutes.HandleFunc("/bills/payment-history", utils.MethodHandler(map[string]http.HandlerFunc{
 		"GET": s.billHandler.GetUserPaymentHistory,
 	}))
@@ -0,0 +1,16 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ServeMux panics on patterns that conflict with each other
+func TestSetupRoutesRegistersWithoutConflicts(t *testing.T) {
+	s := &ApartmantService{}
+	assert.NotPanics(t, func() {
+		s.SetupRoutes(http.NewServeMux())
+	})
+}
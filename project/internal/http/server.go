@@ -13,6 +13,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/minio/minio-go/v7"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/bot"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/handlers"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/utils"
@@ -20,28 +21,54 @@ import (
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/payment"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/scheduler"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/services"
 	goredis "github.com/redis/go-redis/v9"
 )
 
 type ApartmantService struct {
-	server              *http.Server
-	cfg                 *config.Config
-	shutdownWG          sync.WaitGroup
-	shutdownCtx         context.Context
-	cancelFunc          context.CancelFunc
-	db                  *sqlx.DB
-	minioClient         *minio.Client
-	redisClient         *goredis.Client
-	userHandler         *handlers.UserHandler
-	apartmentHandler    *handlers.ApartmentHandler
-	billHandler         *handlers.BillHandler
-	userService         services.UserService
-	apartmentService    services.ApartmentService
-	billService         services.BillService
-	notificationService notification.Notification
-	imageService        image.Image
-	paymentService      payment.Payment
+	server                      *http.Server
+	cfg                         *config.Config
+	shutdownWG                  sync.WaitGroup
+	shutdownCtx                 context.Context
+	cancelFunc                  context.CancelFunc
+	db                          *sqlx.DB
+	minioClient                 *minio.Client
+	redisClient                 *goredis.Client
+	tokenRepo                   repositories.TokenRepository
+	keys                        *middleware.KeySet
+	userHandler                 *handlers.UserHandler
+	jwksHandler                 *handlers.JWKSHandler
+	apartmentHandler            *handlers.ApartmentHandler
+	billHandler                 *handlers.BillHandler
+	billTemplateHandler         *handlers.BillTemplateHandler
+	lateFeeHandler              *handlers.LateFeeHandler
+	ledgerHandler               *handlers.LedgerHandler
+	reportHandler               *handlers.ReportHandler
+	handoverHandler             *handlers.HandoverHandler
+	unitHandler                 *handlers.UnitHandler
+	invitationHandler           *handlers.InvitationHandler
+	outboxHandler               *handlers.OutboxHandler
+	notificationTemplateHandler *handlers.NotificationTemplateHandler
+	telegramHandler             *handlers.TelegramHandler
+	userService                 services.UserService
+	apartmentService            services.ApartmentService
+	billService                 services.BillService
+	billTemplateService         services.BillTemplateService
+	lateFeeService              services.LateFeeService
+	ledgerService               services.LedgerService
+	reportService               services.ReportService
+	handoverService             services.HandoverService
+	unitService                 services.UnitService
+	invitationService           services.InvitationService
+	outboxService               services.OutboxService
+	notificationTemplateService services.NotificationTemplateService
+	reminderService             services.ReminderService
+	outboxWorker                *notification.OutboxWorker
+	botCommands                 *bot.Commands
+	notificationService         notification.Notification
+	imageService                image.Image
+	paymentService              payment.Payment
 }
 
 func NewApartmantService(
@@ -58,48 +85,140 @@ func NewApartmantService(
 	imageService image.Image,
 	paymentRepo repositories.PaymentRepository,
 	paymentService payment.Payment,
+	billTemplateRepo repositories.BillTemplateRepository,
+	installmentRepo repositories.InstallmentRepository,
+	lateFeeRepo repositories.LateFeeRepository,
+	ledgerRepo repositories.LedgerRepository,
+	reportRepo repositories.ReportRepository,
+	handoverRepo repositories.HandoverRepository,
+	unitRepo repositories.UnitRepository,
+	outboxRepo repositories.OutboxRepository,
+	reminderRepo repositories.ReminderRepository,
+	notificationTemplateRepo repositories.NotificationTemplateRepository,
+	uow repositories.UnitOfWork,
+	tokenRepo repositories.TokenRepository,
+	keys *middleware.KeySet,
 ) *ApartmantService {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	userService := services.NewUserService(userRepo, userApartmentRepo)
+	//services only queue their notifications, the worker sends them
+	outbox := notification.NewOutbox(outboxRepo)
+	outboxWorker := notification.NewOutboxWorker(cfg.Outbox, outboxRepo, notificationService, inviteLinkRepo)
+
+	userService := services.NewUserService(userRepo, userApartmentRepo, tokenRepo, inviteLinkRepo, uow, keys, cfg.Auth)
 	apartmentService := services.NewApartmentService(
 		apartmentRepo,
 		userRepo,
 		userApartmentRepo,
 		inviteLinkRepo,
-		notificationService,
+		uow,
+		outbox,
 	)
 	billService := services.NewBillService(
 		billRepo,
 		userRepo,
 		apartmentRepo,
 		userApartmentRepo,
+		unitRepo,
 		paymentRepo,
+		installmentRepo,
+		ledgerRepo,
+		uow,
 		imageService,
 		paymentService,
-		notificationService,
+		outbox,
+	)
+
+	billTemplateService := services.NewBillTemplateService(
+		billTemplateRepo,
+		billRepo,
+		apartmentRepo,
+		userApartmentRepo,
+		uow,
+		billService,
+	)
+
+	lateFeeService := services.NewLateFeeService(
+		lateFeeRepo,
+		paymentRepo,
+		billRepo,
+		userApartmentRepo,
+		ledgerRepo,
+		uow,
+	)
+	ledgerService := services.NewLedgerService(ledgerRepo, userApartmentRepo)
+	reportService := services.NewReportService(reportRepo, userApartmentRepo)
+	handoverService := services.NewHandoverService(
+		handoverRepo,
+		apartmentRepo,
+		userApartmentRepo,
+		uow,
+		outbox,
 	)
+	unitService := services.NewUnitService(unitRepo, userApartmentRepo)
+	invitationService := services.NewInvitationService(inviteLinkRepo, userApartmentRepo, uow, outbox)
+	outboxService := services.NewOutboxService(outboxRepo, userApartmentRepo)
+	notificationTemplateService := services.NewNotificationTemplateService(notificationTemplateRepo, userApartmentRepo)
+	reminderService := services.NewReminderService(cfg.Reminders, reminderRepo, uow, outbox)
+	botCommands := bot.NewCommands(userService, billService, apartmentService, invitationService, ledgerService)
 
 	userHandler := handlers.NewUserHandler(userService, cfg.TelegramConfig.BotAddress)
+	jwksHandler := handlers.NewJWKSHandler(keys)
 	apartmentHandler := handlers.NewApartmentHandler(apartmentService)
 	billHandler := handlers.NewBillHandler(billService)
+	billTemplateHandler := handlers.NewBillTemplateHandler(billTemplateService)
+	lateFeeHandler := handlers.NewLateFeeHandler(lateFeeService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	reportHandler := handlers.NewReportHandler(reportService)
+	handoverHandler := handlers.NewHandoverHandler(handoverService)
+	unitHandler := handlers.NewUnitHandler(unitService)
+	invitationHandler := handlers.NewInvitationHandler(invitationService)
+	outboxHandler := handlers.NewOutboxHandler(outboxService)
+	notificationTemplateHandler := handlers.NewNotificationTemplateHandler(notificationTemplateService)
+	webhookReceiver, _ := notificationService.(notification.WebhookReceiver)
+	telegramHandler := handlers.NewTelegramHandler(cfg.TelegramConfig, webhookReceiver)
 
 	return &ApartmantService{
-		cfg:                 cfg,
-		shutdownCtx:         ctx,
-		cancelFunc:          cancel,
-		db:                  db,
-		minioClient:         minioClient,
-		redisClient:         redisClient,
-		userHandler:         userHandler,
-		apartmentHandler:    apartmentHandler,
-		billHandler:         billHandler,
-		userService:         userService,
-		apartmentService:    apartmentService,
-		billService:         billService,
-		notificationService: notificationService,
-		imageService:        imageService,
-		paymentService:      paymentService,
+		cfg:                         cfg,
+		shutdownCtx:                 ctx,
+		cancelFunc:                  cancel,
+		db:                          db,
+		minioClient:                 minioClient,
+		redisClient:                 redisClient,
+		tokenRepo:                   tokenRepo,
+		keys:                        keys,
+		userHandler:                 userHandler,
+		jwksHandler:                 jwksHandler,
+		apartmentHandler:            apartmentHandler,
+		billHandler:                 billHandler,
+		billTemplateHandler:         billTemplateHandler,
+		lateFeeHandler:              lateFeeHandler,
+		ledgerHandler:               ledgerHandler,
+		reportHandler:               reportHandler,
+		handoverHandler:             handoverHandler,
+		unitHandler:                 unitHandler,
+		invitationHandler:           invitationHandler,
+		outboxHandler:               outboxHandler,
+		notificationTemplateHandler: notificationTemplateHandler,
+		telegramHandler:             telegramHandler,
+		userService:                 userService,
+		apartmentService:            apartmentService,
+		billService:                 billService,
+		billTemplateService:         billTemplateService,
+		lateFeeService:              lateFeeService,
+		ledgerService:               ledgerService,
+		reportService:               reportService,
+		handoverService:             handoverService,
+		unitService:                 unitService,
+		invitationService:           invitationService,
+		outboxService:               outboxService,
+		notificationTemplateService: notificationTemplateService,
+		reminderService:             reminderService,
+		outboxWorker:                outboxWorker,
+		botCommands:                 botCommands,
+		notificationService:         notificationService,
+		imageService:                imageService,
+		paymentService:              paymentService,
 	}
 }
 
@@ -117,7 +236,14 @@ func (s *ApartmantService) Start(serviceName string) error {
 	}
 
 	s.setupSignalHandling()
-	go s.notificationService.ListenForUpdates(context.Background())
+	go s.notificationService.ListenForUpdates(s.shutdownCtx, s.botCommands)
+	s.startScheduler()
+
+	s.shutdownWG.Add(1)
+	go func() {
+		defer s.shutdownWG.Done()
+		s.outboxWorker.Run(s.shutdownCtx)
+	}()
 
 	s.shutdownWG.Add(1)
 	go func() {
@@ -132,6 +258,37 @@ func (s *ApartmantService) Start(serviceName string) error {
 	return nil
 }
 
+// runs the background jobs until the service shuts down
+func (s *ApartmantService) startScheduler() {
+	if !s.cfg.Scheduler.Enabled {
+		return
+	}
+
+	sched := scheduler.NewScheduler(s.cfg.Scheduler.Interval)
+	sched.AddJob("bill-templates", func(ctx context.Context, now time.Time) error {
+		_, err := s.billTemplateService.GenerateDueBills(ctx, now)
+		return err
+	})
+	sched.AddJob("late-fees", func(ctx context.Context, now time.Time) error {
+		_, err := s.lateFeeService.ApplyLateFees(ctx, now)
+		return err
+	})
+	sched.AddJob("payment-expiry", func(ctx context.Context, now time.Time) error {
+		_, err := s.billService.ExpireStalePayments(ctx, now)
+		return err
+	})
+	sched.AddJob("bill-reminders", func(ctx context.Context, now time.Time) error {
+		_, err := s.reminderService.SendDueReminders(ctx, now)
+		return err
+	})
+
+	s.shutdownWG.Add(1)
+	go func() {
+		defer s.shutdownWG.Done()
+		sched.Run(s.shutdownCtx)
+	}()
+}
+
 func (s *ApartmantService) methodHandler(methods map[string]http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		handler, exists := methods[r.Method]
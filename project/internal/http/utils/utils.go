@@ -10,15 +10,14 @@ import (
 	"io"
 )
 
-// for creating a proper 32-byte key from your existing key
-func getAESKey() []byte {
-	hash := sha256.Sum256([]byte("secret-for-user-id-encryption"))
+// derives a 32 byte AES key from the configured secret, which can be any
+// length
+func AESKey(secret string) []byte {
+	hash := sha256.Sum256([]byte(secret))
 	return hash[:]
 }
 
-func Encrypt(plainText string) (string, error) {
-	aesKey := getAESKey() //exactly 32 bytes
-
+func Encrypt(aesKey []byte, plainText string) (string, error) {
 	block, err := aes.NewCipher(aesKey)
 	if err != nil {
 		return "", err
@@ -38,9 +37,7 @@ func Encrypt(plainText string) (string, error) {
 	return base64.URLEncoding.EncodeToString(cipherText), nil
 }
 
-func Decrypt(cipherText string) (string, error) {
-	aesKey := getAESKey() // This ensures exactly 32 bytes
-
+func Decrypt(aesKey []byte, cipherText string) (string, error) {
 	data, err := base64.URLEncoding.DecodeString(cipherText)
 	if err != nil {
 		return "", err
@@ -0,0 +1,245 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = fstest.MapFS{
+	"0002_add_units.up.sql":        {Data: []byte("CREATE TABLE units(id SERIAL PRIMARY KEY);")},
+	"0002_add_units.down.sql":      {Data: []byte("DROP TABLE units;")},
+	"0001_initial_schema.up.sql":   {Data: []byte("CREATE TABLE users(id SERIAL PRIMARY KEY);")},
+	"0001_initial_schema.down.sql": {Data: []byte("DROP TABLE users;")},
+	"0003_backfill.up.sql":         {Data: []byte("UPDATE users SET id = id;")},
+	"README.md":                    {Data: []byte("not a migration")},
+}
+
+func setupTestDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	return sqlx.NewDb(db, "postgres"), mock
+}
+
+func expectApplied(mock sqlmock.Sqlmock, versions ...int64) {
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range versions {
+		rows.AddRow(version, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").WillReturnRows(rows)
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testMigrations)
+
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	assert.Equal(t, "0001_initial_schema", migrations[0].String())
+	assert.Equal(t, "DROP TABLE units;", migrations[1].Down)
+	assert.Empty(t, migrations[2].Down)
+}
+
+func TestLoadRejectsBrokenDirectories(t *testing.T) {
+	tests := []struct {
+		name          string
+		fsys          fstest.MapFS
+		expectedError string
+	}{
+		{
+			name: "down without up",
+			fsys: fstest.MapFS{
+				"0001_users.down.sql": {Data: []byte("DROP TABLE users;")},
+			},
+			expectedError: "has no up file",
+		},
+		{
+			name: "version used twice",
+			fsys: fstest.MapFS{
+				"0001_users.up.sql": {Data: []byte("CREATE TABLE users();")},
+				"0001_bills.up.sql": {Data: []byte("CREATE TABLE bills();")},
+			},
+			expectedError: "is used by both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+	for i, migration := range loaded {
+		assert.Equal(t, int64(i+1), migration.Version, "migration versions must have no gaps")
+		assert.NotEmpty(t, migration.Down, "%s has no down file", migration)
+	}
+}
+
+func TestMigrator_Up(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	expectApplied(mock, 1)
+	for _, migration := range []struct {
+		version int64
+		name    string
+		sql     string
+	}{
+		{2, "add_units", "CREATE TABLE units"},
+		{3, "backfill", "UPDATE users SET id = id"},
+	} {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WithArgs(migrationLockID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM schema_migrations WHERE version = \$1\)`).
+			WithArgs(migration.version).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(migration.sql).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(migration.version, migration.name).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	migrator, err := NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+	applied, err := migrator.Up(context.Background())
+
+	assert.NoError(t, err)
+	require.Len(t, applied, 2)
+	assert.Equal(t, int64(3), applied[1].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpSkipsMigrationAppliedByAnotherInstance(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	expectApplied(mock, 1, 2)
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectCommit()
+
+	migrator, err := NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+	applied, err := migrator.Up(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpRollsBackFailedMigration(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	expectApplied(mock, 1, 2)
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("UPDATE users").WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	migrator, err := NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to apply migration 0003_backfill")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Down(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	expectApplied(mock, 1, 2)
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("DROP TABLE units").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM schema_migrations WHERE version = \$1`).WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	migrator, err := NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+	reverted, err := migrator.Down(context.Background(), 1)
+
+	assert.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, "add_units", reverted[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_DownWithoutDownFile(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	expectApplied(mock, 1, 2, 3)
+
+	migrator, err := NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+	_, err = migrator.Down(context.Background(), 1)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "can't be reverted")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Status(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	expectApplied(mock, 1)
+
+	migrator, err := NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+	statuses, err := migrator.Status(context.Background())
+
+	assert.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[0].AppliedAt.IsZero())
+	assert.False(t, statuses[1].Applied)
+	assert.False(t, statuses[2].Applied)
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0007_units.up.sql"), []byte("SELECT 1;"), 0o644))
+
+	up, down, err := Create(dir, "Add late-fee index")
+
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "0008_add_late_fee_index.up.sql"), up)
+	assert.Equal(t, filepath.Join(dir, "0008_add_late_fee_index.down.sql"), down)
+	assert.FileExists(t, up)
+	assert.FileExists(t, down)
+
+	_, _, err = Create(dir, "--")
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runs the embedded migrations against a real database. MIGRATIONS_TEST_DSN
+// has to point at an empty database, which is left empty again
+func TestEmbeddedMigrationsUpAndDownOnPostgres(t *testing.T) {
+	dsn := os.Getenv("MIGRATIONS_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MIGRATIONS_TEST_DSN is not set")
+	}
+
+	ctx := context.Background()
+	db, err := sqlx.Connect("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrator, err := NewMigrator(db, migrations.FS)
+	require.NoError(t, err)
+	all, err := Load(migrations.FS)
+	require.NoError(t, err)
+
+	for round := 1; round <= 2; round++ {
+		applied, err := migrator.Up(ctx)
+		require.NoError(t, err, "round %d", round)
+		require.Len(t, applied, len(all), "round %d", round)
+
+		reverted, err := migrator.Down(ctx, len(all))
+		require.NoError(t, err, "round %d", round)
+		require.Len(t, reverted, len(all), "round %d", round)
+
+		var tables []string
+		err = db.SelectContext(ctx, &tables, `SELECT table_name FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_name <> 'schema_migrations'`)
+		require.NoError(t, err)
+		assert.Empty(t, tables, "round %d left tables behind", round)
+	}
+}
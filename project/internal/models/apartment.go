@@ -2,8 +2,30 @@ package models
 
 type Apartment struct {
 	BaseModel
-	ApartmentName string `json:"apartment_name" db:"apartment_name"`
-	Address       string `json:"address" db:"address"`
-	UnitsCount    int    `json:"units_count" db:"units_count"`
-	ManagerID     int    `json:"manager_id" db:"manager_id"`
+	ApartmentName  string         `json:"apartment_name" db:"apartment_name"`
+	Address        string         `json:"address" db:"address"`
+	UnitsCount     int            `json:"units_count" db:"units_count"`
+	ManagerID      int            `json:"manager_id" db:"manager_id"`
+	DivisionPolicy DivisionPolicy `json:"division_policy" db:"division_policy"`
+}
+
+// how bills of an apartment are split between its residents
+type DivisionPolicy string
+
+const (
+	EqualDivision     DivisionPolicy = "equal"
+	AreaDivision      DivisionPolicy = "area"      // by unit square meters
+	OccupantsDivision DivisionPolicy = "occupants" // by number of people living in the unit
+	CustomDivision    DivisionPolicy = "custom"    // explicit per-resident percentages
+
+	//per unit policies charge every occupied unit once and split its share
+	//between the members living there
+	UnitDivision          DivisionPolicy = "unit"           // the same for every unit
+	UnitAreaDivision      DivisionPolicy = "unit_area"      // by the unit's square meters
+	UnitOccupantsDivision DivisionPolicy = "unit_occupants" // by the number of people living in the unit
+)
+
+// true for the policies that divide between units rather than members
+func (p DivisionPolicy) PerUnit() bool {
+	return p == UnitDivision || p == UnitAreaDivision || p == UnitOccupantsDivision
 }
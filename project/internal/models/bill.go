@@ -4,7 +4,7 @@ type Bill struct {
 	BaseModel
 	ApartmentID     int      `json:"apartment_id" db:"apartment_id"`
 	BillType        BillType `json:"bill_type" db:"bill_type"`
-	TotalAmount     float64  `json:"total_amount" db:"total_amount"`
+	TotalAmount     Money    `json:"total_amount" db:"total_amount"`
 	DueDate         string   `json:"due_date" db:"due_date"`
 	BillingDeadline string   `json:"billing_deadline" db:"billing_deadline"`
 	Description     string   `json:"description" db:"description"`
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// BillTemplate describes a bill that is generated again every period
+type BillTemplate struct {
+	BaseModel
+	ApartmentID   int           `json:"apartment_id" db:"apartment_id"`
+	BillType      BillType      `json:"bill_type" db:"bill_type"`
+	Amount        Money         `json:"amount" db:"amount"`
+	AmountFormula AmountFormula `json:"amount_formula" db:"amount_formula"`
+	Cadence       Cadence       `json:"cadence" db:"cadence"`
+	DueDayOffset  int           `json:"due_day_offset" db:"due_day_offset"` // days after the period start
+	Description   string        `json:"description" db:"description"`
+	AutoDivide    bool          `json:"auto_divide" db:"auto_divide"`
+	Active        bool          `json:"active" db:"active"`
+	NextRunAt     time.Time     `json:"next_run_at" db:"next_run_at"` // start of the next period to generate
+}
+
+// how the amount of a template turns into the total of a generated bill
+type AmountFormula string
+
+const (
+	FixedAmount       AmountFormula = "fixed"
+	PerUnitAmount     AmountFormula = "per_unit"     // amount times the units count of the apartment
+	PerResidentAmount AmountFormula = "per_resident" // amount times the number of residents
+)
+
+type Cadence string
+
+const (
+	Monthly   Cadence = "monthly"
+	Quarterly Cadence = "quarterly"
+	Yearly    Cadence = "yearly"
+)
+
+// returns the start of the period following the one starting at from
+func (c Cadence) Next(from time.Time) time.Time {
+	switch c {
+	case Quarterly:
+		return from.AddDate(0, 3, 0)
+	case Yearly:
+		return from.AddDate(1, 0, 0)
+	default:
+		return from.AddDate(0, 1, 0)
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type HandoverStatus string
+
+const (
+	HandoverPending   HandoverStatus = "pending"
+	HandoverAccepted  HandoverStatus = "accepted"
+	HandoverDeclined  HandoverStatus = "declined"
+	HandoverCancelled HandoverStatus = "cancelled" // taken back by the outgoing manager
+)
+
+// ManagerHandover is an offer by the apartment's manager to hand the
+// apartment over to another member. nothing changes until the incoming
+// manager accepts, which makes them the apartment's ManagerID and owner
+type ManagerHandover struct {
+	BaseModel
+	ApartmentID   int            `json:"apartment_id" db:"apartment_id"`
+	FromUserID    int            `json:"from_user_id" db:"from_user_id"`
+	ToUserID      int            `json:"to_user_id" db:"to_user_id"`
+	StayAsManager bool           `json:"stay_as_manager" db:"stay_as_manager"` // the outgoing manager stays a co-manager instead of becoming a resident
+	Status        HandoverStatus `json:"status" db:"status"`
+	RespondedAt   *time.Time     `json:"responded_at,omitempty" db:"responded_at"`
+}
@@ -2,19 +2,28 @@ package models
 
 import "time"
 
+// InvitationLink is an invitation to join an apartment, sent to a user or to
+// the email or phone number of someone who hasn't signed up yet, in which
+// case ReceiverID is 0 until they accept. it stays open, pending or
+// notified, until the receiver accepts or rejects it, the manager revokes it
+// or it expires
 type InvitationLink struct {
 	BaseModel
-	SenderID           int              `json:"sender_id"`
-	SenderUsername     string           `json:"sender_username"`   //for notifications
-	ReceiverUsername   string           `json:"receiver_username"` // telegram username
-	ReceiverChatID     int64            `json:"receiver_chat_id"`  //for direct messaging
-	ApartmentID        int              `json:"apartment_id"`
-	ApartmentName      string           `json:"apartment_name"` //for notifications
-	Token              string           `json:"token"`
-	ExpiresAt          time.Time        `json:"expires_at"`
-	Status             InvitationStatus `json:"status"`
-	InviteURL          string           `json:"invite_url"`           // full invitation URL
-	NotificationSentAt *time.Time       `json:"notification_sent_at"` //tracking if notification was sent
+	SenderID           int              `json:"sender_id" db:"sender_id"`
+	SenderUsername     string           `json:"sender_username" db:"sender_username"` //for notifications
+	ReceiverID         int              `json:"receiver_id" db:"receiver_id"`
+	ReceiverEmail      string           `json:"receiver_email,omitempty" db:"receiver_email"`
+	ReceiverPhone      string           `json:"receiver_phone,omitempty" db:"receiver_phone"`
+	ReceiverUsername   string           `json:"receiver_username" db:"receiver_username"` // telegram username
+	ReceiverChatID     int64            `json:"receiver_chat_id" db:"receiver_chat_id"`   //for direct messaging
+	ApartmentID        int              `json:"apartment_id" db:"apartment_id"`
+	ApartmentName      string           `json:"apartment_name" db:"apartment_name"` //for notifications
+	Token              string           `json:"token" db:"token"`
+	ExpiresAt          time.Time        `json:"expires_at" db:"expires_at"`
+	Status             InvitationStatus `json:"status" db:"status"`
+	InviteURL          string           `json:"invite_url" db:"-"`                              // full invitation URL
+	NotificationSentAt *time.Time       `json:"notification_sent_at" db:"notification_sent_at"` //tracking if notification was sent
+	RespondedAt        *time.Time       `json:"responded_at,omitempty" db:"responded_at"`       // when it was accepted, rejected or revoked
 }
 
 type InvitationStatus string
@@ -25,4 +34,10 @@ const (
 	InvitationStatusRejected InvitationStatus = "rejected"
 	InvitationStatusExpired  InvitationStatus = "expired"
 	InvitationStatusNotified InvitationStatus = "notified"
+	InvitationStatusRevoked  InvitationStatus = "revoked" // taken back by a manager
 )
+
+// whether the invitation can still be accepted, rejected or revoked
+func (s InvitationStatus) IsOpen() bool {
+	return s == InvitationStatusPending || s == InvitationStatusNotified
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Installment is one part of a manager-defined plan for paying a resident's
+// share in several steps. installments are paid in sequence order
+type Installment struct {
+	ID        int       `json:"id" db:"id"`
+	PaymentID int       `json:"payment_id" db:"payment_id"`
+	Sequence  int       `json:"sequence" db:"sequence"`
+	Amount    Money     `json:"amount" db:"amount"`
+	DueDate   string    `json:"due_date" db:"due_date"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// the first installment that is not fully covered by paid, together with
+// what is left to pay on it. nil when the whole plan is paid
+func NextInstallment(installments []Installment, paid Money) (*Installment, Money) {
+	var covered int64
+	for i := range installments {
+		covered += installments[i].Amount.Amount
+		if covered > paid.Amount {
+			return &installments[i], NewMoney(covered-paid.Amount, installments[i].Amount.Currency)
+		}
+	}
+	return nil, Money{}
+}
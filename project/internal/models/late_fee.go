@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+type LateFeeType string
+
+const (
+	FlatLateFee       LateFeeType = "flat"       // one fixed fee once the deadline passed
+	PercentageLateFee LateFeeType = "percentage" // once, a percentage of the share
+	DailyLateFee      LateFeeType = "daily"      // a fixed fee for every day past the deadline
+)
+
+// LateFeeRule is how an apartment charges residents who have not paid their
+// share by the bill's billing deadline (or due date when it has none)
+type LateFeeRule struct {
+	BaseModel
+	ApartmentID     int         `json:"apartment_id" db:"apartment_id"`
+	FeeType         LateFeeType `json:"fee_type" db:"fee_type"`
+	Amount          Money       `json:"amount" db:"amount"`                       // the flat fee, or the fee per day for daily rules
+	RateBasisPoints int64       `json:"rate_basis_points" db:"rate_basis_points"` // percentage rules, 250 is 2.5%
+	GraceDays       int         `json:"grace_days" db:"grace_days"`
+	Cap             Money       `json:"cap" db:"cap"` // the most a payment can be charged, zero for no cap
+	Active          bool        `json:"active" db:"active"`
+}
+
+// the whole late fee a share owes at now for a deadline. fees are
+// recomputed from the deadline every time, so applying them again is harmless
+func (r LateFeeRule) FeeFor(share Money, deadline, now time.Time) Money {
+	//the fee starts the day after the deadline and the grace days
+	start := deadline.AddDate(0, 0, 1+r.GraceDays)
+	if now.Before(start) {
+		return NewMoney(0, share.Currency)
+	}
+
+	var fee int64
+	switch r.FeeType {
+	case FlatLateFee:
+		fee = r.Amount.Amount
+	case PercentageLateFee:
+		fee = share.Amount * r.RateBasisPoints / 10000
+	case DailyLateFee:
+		days := int64(now.Sub(start)/(24*time.Hour)) + 1
+		fee = r.Amount.Amount * days
+	}
+
+	if r.Cap.IsPositive() && fee > r.Cap.Amount {
+		fee = r.Cap.Amount
+	}
+	return NewMoney(fee, share.Currency)
+}
+
+type LateFeeEventKind string
+
+const (
+	LateFeeAccrued LateFeeEventKind = "accrued"
+	LateFeeWaived  LateFeeEventKind = "waived"
+)
+
+// LateFeeEvent is one recorded change of a payment's late fee
+type LateFeeEvent struct {
+	ID        int              `json:"id" db:"id"`
+	PaymentID int              `json:"payment_id" db:"payment_id"`
+	Kind      LateFeeEventKind `json:"kind" db:"kind"`
+	Amount    Money            `json:"amount" db:"amount"`
+	Actor     string           `json:"actor" db:"actor"`
+	Reason    string           `json:"reason" db:"reason"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
+
+// OverduePayment is an unpaid payment whose bill is past its deadline
+type OverduePayment struct {
+	Payment
+	Deadline string `json:"deadline" db:"deadline"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLateFeeRuleFeeFor(t *testing.T) {
+	share := NewMoney(200000, "IRR")
+	deadline := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		rule LateFeeRule
+		now  time.Time
+		want int64
+	}{
+		{
+			name: "on the deadline",
+			rule: LateFeeRule{FeeType: FlatLateFee, Amount: NewMoney(5000, "IRR")},
+			now:  time.Date(2025, 3, 10, 23, 0, 0, 0, time.UTC),
+			want: 0,
+		},
+		{
+			name: "flat fee the day after",
+			rule: LateFeeRule{FeeType: FlatLateFee, Amount: NewMoney(5000, "IRR")},
+			now:  time.Date(2025, 3, 11, 1, 0, 0, 0, time.UTC),
+			want: 5000,
+		},
+		{
+			name: "within the grace days",
+			rule: LateFeeRule{FeeType: FlatLateFee, Amount: NewMoney(5000, "IRR"), GraceDays: 3},
+			now:  time.Date(2025, 3, 13, 12, 0, 0, 0, time.UTC),
+			want: 0,
+		},
+		{
+			name: "percentage of the share",
+			rule: LateFeeRule{FeeType: PercentageLateFee, RateBasisPoints: 250},
+			now:  time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+			want: 5000,
+		},
+		{
+			name: "daily accrual after grace",
+			rule: LateFeeRule{FeeType: DailyLateFee, Amount: NewMoney(1000, "IRR"), GraceDays: 2},
+			now:  time.Date(2025, 3, 17, 8, 0, 0, 0, time.UTC),
+			want: 5000,
+		},
+		{
+			name: "daily accrual stops at the cap",
+			rule: LateFeeRule{FeeType: DailyLateFee, Amount: NewMoney(1000, "IRR"), Cap: NewMoney(7000, "IRR")},
+			now:  time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+			want: 7000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, NewMoney(tt.want, "IRR"), tt.rule.FeeFor(share, deadline, tt.now))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+type LedgerEntryType string
+
+const (
+	ChargeEntry  LedgerEntryType = "charge"  // a resident's share of a bill
+	FeeEntry     LedgerEntryType = "fee"     // a late fee
+	RefundEntry  LedgerEntryType = "refund"  // money given back, or taken back by a chargeback
+	PaymentEntry LedgerEntryType = "payment" // money the resident paid
+	CreditEntry  LedgerEntryType = "credit"  // a waived fee or a cancelled charge
+)
+
+// charges, fees and refunds raise what the resident owes, payments and
+// credits lower it
+func (t LedgerEntryType) IsDebit() bool {
+	return t == ChargeEntry || t == FeeEntry || t == RefundEntry
+}
+
+// LedgerEntry is one movement on a resident's account in an apartment.
+// Amount is always positive, the entry type tells its direction
+type LedgerEntry struct {
+	ID          int             `json:"id" db:"id"`
+	UserID      int             `json:"user_id" db:"user_id"`
+	ApartmentID int             `json:"apartment_id" db:"apartment_id"`
+	PaymentID   *int            `json:"payment_id,omitempty" db:"payment_id"`
+	EntryType   LedgerEntryType `json:"entry_type" db:"entry_type"`
+	Amount      Money           `json:"amount" db:"amount"`
+	Description string          `json:"description" db:"description"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	Balance     Money           `json:"balance" db:"-"` // running balance after the entry, filled in for statements
+}
+
+// the signed effect of the entry on the balance
+func (e LedgerEntry) SignedAmount() int64 {
+	if e.EntryType.IsDebit() {
+		return e.Amount.Amount
+	}
+	return -e.Amount.Amount
+}
+
+// Statement is a resident's account in an apartment over a date range. a
+// positive balance is owed by the resident, a negative one is credit that
+// pays their next charges
+type Statement struct {
+	UserID         int           `json:"user_id"`
+	ApartmentID    int           `json:"apartment_id"`
+	From           string        `json:"from"`
+	To             string        `json:"to"`
+	OpeningBalance Money         `json:"opening_balance"`
+	Entries        []LedgerEntry `json:"entries"`
+	ClosingBalance Money         `json:"closing_balance"`
+}
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultCurrency = "IRR"
+
+// Money is an exact amount in minor units (hundredths of the currency unit)
+// together with its ISO 4217 currency code. in the database it is stored as a
+// BIGINT amount column next to a currency column.
+type Money struct {
+	Amount   int64  `db:"amount"`
+	Currency string `db:"currency"`
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// parses a decimal string like "100", "100.5" or "-12.34" without going
+// through float64
+func ParseMoney(value, currency string) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Money{}, errors.New("empty amount")
+	}
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	//only the one leading minus is a sign, ParseInt would take more
+	whole, frac, hasFrac := strings.Cut(value, ".")
+	if !isDigits(whole) || (hasFrac && (!isDigits(frac) || len(frac) > 2)) {
+		return Money{}, fmt.Errorf("invalid amount %q", value)
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", value)
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", value)
+	}
+	if units > (math.MaxInt64-cents)/100 {
+		return Money{}, fmt.Errorf("amount %q is too large", value)
+	}
+
+	amount := units*100 + cents
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}, nil
+}
+
+// whether s is a non-empty run of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// formats the amount as a plain decimal, e.g. "100.50"
+func (m Money) Decimal() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.currency(other)}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount - other.Amount, Currency: m.currency(other)}, nil
+}
+
+func (m Money) Multiply(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// Allocate splits m proportionally to weights. every share is a whole number of
+// minor units; the units lost to rounding go one by one to the largest
+// remainders (earlier positions win ties), so the shares always add up to m.
+func (m Money) Allocate(weights []float64) ([]Money, error) {
+	if len(weights) == 0 {
+		return nil, errors.New("no weights to allocate by")
+	}
+
+	var weightSum float64
+	for _, w := range weights {
+		if w < 0 {
+			return nil, errors.New("weights must not be negative")
+		}
+		weightSum += w
+	}
+	if weightSum == 0 {
+		return nil, errors.New("weights must not all be zero")
+	}
+
+	total := m.Amount
+	negative := total < 0
+	if negative {
+		total = -total
+	}
+
+	units := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	var allocated int64
+	for i, w := range weights {
+		exact := float64(total) * w / weightSum
+		units[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(units[i])
+		allocated += units[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for k := 0; allocated < total; k++ {
+		units[order[k%len(order)]]++
+		allocated++
+	}
+
+	shares := make([]Money, len(weights))
+	for i, u := range units {
+		if negative {
+			u = -u
+		}
+		shares[i] = Money{Amount: u, Currency: m.Currency}
+	}
+	return shares, nil
+}
+
+// amounts are exposed over the API as exact decimal strings
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// accepts {"amount": "12.50", "currency": "IRR"} as well as a bare "12.50" or
+// 12.5, falling back to the default currency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var obj struct {
+		Amount   json.RawMessage `json:"amount"`
+		Currency string          `json:"currency"`
+	}
+	raw := data
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		raw = obj.Amount
+	}
+
+	value := strings.Trim(string(raw), `"`)
+	if value == "" || value == "null" {
+		*m = Money{Currency: obj.Currency}
+		return nil
+	}
+
+	parsed, err := ParseMoney(value, obj.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) sameCurrency(other Money) error {
+	if m.Currency != "" && other.Currency != "" && m.Currency != other.Currency {
+		return fmt.Errorf("currency mismatch: %s and %s", m.Currency, other.Currency)
+	}
+	return nil
+}
+
+// a zero Money has no currency yet, so take whichever side has one
+func (m Money) currency(other Money) string {
+	if m.Currency != "" {
+		return m.Currency
+	}
+	return other.Currency
+}
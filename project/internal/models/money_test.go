@@ -0,0 +1,118 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		currency string
+		expected Money
+		wantErr  bool
+	}{
+		{name: "whole amount", value: "100", expected: NewMoney(10000, DefaultCurrency)},
+		{name: "one decimal", value: "100.5", currency: "usd", expected: NewMoney(10050, "USD")},
+		{name: "two decimals", value: "0.07", expected: NewMoney(7, DefaultCurrency)},
+		{name: "negative", value: "-12.34", expected: NewMoney(-1234, DefaultCurrency)},
+		{name: "too many decimals", value: "1.234", wantErr: true},
+		{name: "not a number", value: "abc", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+		{name: "double minus", value: "--1.50", wantErr: true},
+		{name: "plus sign", value: "+5", wantErr: true},
+		{name: "minus then plus", value: "-+5", wantErr: true},
+		{name: "signed decimals", value: "1.+5", wantErr: true},
+		{name: "inner space", value: "1 000", wantErr: true},
+		{name: "no whole part", value: ".50", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			money, err := ParseMoney(tt.value, tt.currency)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, money)
+		})
+	}
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		weights  []float64
+		expected []int64
+	}{
+		{
+			name:     "equal split with remainder",
+			amount:   10000,
+			weights:  []float64{1, 1, 1},
+			expected: []int64{3334, 3333, 3333},
+		},
+		{
+			name:     "by area",
+			amount:   25000,
+			weights:  []float64{120, 80},
+			expected: []int64{15000, 10000},
+		},
+		{
+			name:     "largest remainder wins the extra unit",
+			amount:   1000,
+			weights:  []float64{1, 2},
+			expected: []int64{333, 667},
+		},
+		{
+			name:     "zero weight pays nothing",
+			amount:   5001,
+			weights:  []float64{0, 1, 1},
+			expected: []int64{0, 2501, 2500},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := NewMoney(tt.amount, "IRR").Allocate(tt.weights)
+			assert.NoError(t, err)
+
+			var total int64
+			for i, share := range shares {
+				assert.Equal(t, tt.expected[i], share.Amount)
+				assert.Equal(t, "IRR", share.Currency)
+				total += share.Amount
+			}
+			assert.Equal(t, tt.amount, total)
+		})
+	}
+
+	_, err := NewMoney(100, "IRR").Allocate([]float64{0, 0})
+	assert.Error(t, err)
+}
+
+func TestMoneyAdd(t *testing.T) {
+	sum, err := Money{}.Add(NewMoney(150, "IRR"))
+	assert.NoError(t, err)
+	assert.Equal(t, NewMoney(150, "IRR"), sum)
+
+	_, err = NewMoney(100, "IRR").Add(NewMoney(100, "USD"))
+	assert.Error(t, err)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(NewMoney(10050, "IRR"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount": "100.50", "currency": "IRR"}`, string(data))
+
+	var money Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": "12.5", "currency": "usd"}`), &money))
+	assert.Equal(t, NewMoney(1250, "USD"), money)
+
+	assert.NoError(t, json.Unmarshal([]byte(`99.99`), &money))
+	assert.Equal(t, NewMoney(9999, DefaultCurrency), money)
+}
@@ -0,0 +1,33 @@
+package models
+
+// NotificationChannel is a way of reaching a user
+type NotificationChannel string
+
+const (
+	TelegramChannel NotificationChannel = "telegram"
+	EmailChannel    NotificationChannel = "email"
+	SMSChannel      NotificationChannel = "sms"
+)
+
+// the order channels are tried in for users who haven't chosen their own
+var DefaultNotificationChannels = []NotificationChannel{TelegramChannel, EmailChannel, SMSChannel}
+
+func (c NotificationChannel) IsValid() bool {
+	return c == TelegramChannel || c == EmailChannel || c == SMSChannel
+}
+
+// Locale is the language notifications are written in for a user
+type Locale string
+
+const (
+	Persian Locale = "fa"
+	English Locale = "en"
+)
+
+// the locale of users who haven't chosen one and of people who haven't
+// signed up yet
+const DefaultLocale = Persian
+
+func (l Locale) IsValid() bool {
+	return l == Persian || l == English
+}
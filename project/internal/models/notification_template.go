@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// the notifications there are templates for
+const (
+	InvitationTemplate         = "invitation"
+	InvitationRejectedTemplate = "invitation_rejected"
+	JoinedApartmentTemplate    = "joined_apartment"
+	RoleGrantedTemplate        = "role_granted"
+	RoleRevokedTemplate        = "role_revoked"
+	HandoverOfferedTemplate    = "handover_offered"
+	HandoverCancelledTemplate  = "handover_cancelled"
+	HandoverDeclinedTemplate   = "handover_declined"
+	HandoverCompletedTemplate  = "handover_completed" // to the outgoing manager
+	NewManagerTemplate         = "new_manager"        // to the incoming manager
+	NewBillTemplate            = "new_bill"
+	BillReminderTemplate       = "bill_reminder"
+	TextTemplate               = "text" // a text written before templates existed, sent as is
+)
+
+// Notice is a notification to render from a template in the recipient's
+// locale. Data fills in the template's fields and is escaped for markdown,
+// dates in it are formatted 2006-01-02
+type Notice struct {
+	Template    string            `json:"template"`
+	ApartmentID int               `json:"apartment_id,omitempty"` // whose templates are used instead of the built-in ones
+	Data        map[string]string `json:"data,omitempty"`
+}
+
+// NotificationTemplate is an apartment's own wording of a notification in one
+// locale, used instead of the built-in one
+type NotificationTemplate struct {
+	ApartmentID int       `json:"apartment_id" db:"apartment_id"`
+	Name        string    `json:"name" db:"name"`
+	Locale      Locale    `json:"locale" db:"locale"`
+	Subject     string    `json:"subject" db:"subject"` // of emails
+	Body        string    `json:"body" db:"body"`       // markdown, in go text/template syntax
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type OutboxKind string
+
+const (
+	OutboxMessageKind    OutboxKind = "message"    // a Notice to a user
+	OutboxInvitationKind OutboxKind = "invitation" // an InvitationLink
+	OutboxBillKind       OutboxKind = "bill"       // a bill and the user's share of it
+)
+
+type OutboxStatus string
+
+const (
+	OutboxPending   OutboxStatus = "pending"
+	OutboxDelivered OutboxStatus = "delivered"
+	OutboxDead      OutboxStatus = "dead" // gave up after too many failed attempts
+)
+
+// OutboxMessage is a notification waiting to be delivered. it is written in
+// the transaction of the change it tells about and sent by the outbox worker
+// afterwards, again and again with growing delays until it goes through or
+// runs out of attempts
+type OutboxMessage struct {
+	BaseModel
+	Kind          OutboxKind      `json:"kind" db:"kind"`
+	UserID        int             `json:"user_id,omitempty" db:"user_id"`           // 0 for invitations to people who haven't signed up
+	ApartmentID   int             `json:"apartment_id,omitempty" db:"apartment_id"` // 0 when the message isn't about an apartment
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Status        OutboxStatus    `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty" db:"last_error"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// BillNotice is the payload of an OutboxBillKind message
+type BillNotice struct {
+	Bill   Bill  `json:"bill"`
+	Amount Money `json:"amount"`
+}
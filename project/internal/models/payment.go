@@ -1,12 +1,19 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"strconv"
+	"time"
+)
 
 type Payment struct {
 	BaseModel
 	BillID        int           `json:"bill_id" db:"bill_id"`
 	UserID        int           `json:"user_id" db:"user_id"`
-	Amount        string        `json:"amount" db:"amount"` // using string to handle decimal values
+	Amount        Money         `json:"amount" db:"amount"`
+	PaidAmount    Money         `json:"paid_amount" db:"paid_amount"` // paid so far, less than Amount while paying in installments
+	LateFee       Money         `json:"late_fee" db:"late_fee"`       // charged on top of Amount after the billing deadline
+	LateFeeWaived bool          `json:"late_fee_waived" db:"late_fee_waived"`
 	PaidAt        time.Time     `json:"paid_at" db:"paid_at"`
 	PaymentStatus PaymentStatus `json:"payment_status" db:"payment_status"`
 }
@@ -14,7 +21,93 @@ type Payment struct {
 type PaymentStatus string
 
 const (
-	Pending PaymentStatus = "pending"
-	Paid    PaymentStatus = "paid"
-	Failed  PaymentStatus = "failed"
+	Pending           PaymentStatus = "pending"
+	Processing        PaymentStatus = "processing"
+	PartiallyPaid     PaymentStatus = "partially_paid"
+	Paid              PaymentStatus = "paid"
+	Failed            PaymentStatus = "failed"
+	PartiallyRefunded PaymentStatus = "partially_refunded"
+	Refunded          PaymentStatus = "refunded"
+	Chargeback        PaymentStatus = "chargeback"
 )
+
+// the statuses a payment may move to from each status. a new payment can be
+// paid from the resident's credit without a provider, a failed payment can
+// be tried again, a partially paid one waits for its next installment (or is
+// settled by waiving its late fee) and a partially refunded one can be
+// refunded more than once
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	Pending:           {Processing, Paid, PartiallyPaid},
+	Processing:        {Paid, PartiallyPaid, Failed},
+	Failed:            {Processing},
+	PartiallyPaid:     {Processing, Paid},
+	Paid:              {PartiallyRefunded, Refunded, Chargeback},
+	PartiallyRefunded: {PartiallyRefunded, Refunded, Chargeback},
+}
+
+// the share together with its late fee
+func (p Payment) TotalDue() Money {
+	return NewMoney(p.Amount.Amount+p.LateFee.Amount, p.Amount.Currency)
+}
+
+// what is left to pay on the payment
+func (p Payment) Outstanding() Money {
+	return NewMoney(p.TotalDue().Amount-p.PaidAmount.Amount, p.Amount.Currency)
+}
+
+var ErrInvalidPaymentTransition = errors.New("invalid payment status transition")
+
+func (s PaymentStatus) CanTransitionTo(to PaymentStatus) bool {
+	for _, allowed := range paymentTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// PaymentTransition is one recorded change of a payment's status
+type PaymentTransition struct {
+	ID         int           `json:"id" db:"id"`
+	PaymentID  int           `json:"payment_id" db:"payment_id"`
+	FromStatus PaymentStatus `json:"from_status" db:"from_status"`
+	ToStatus   PaymentStatus `json:"to_status" db:"to_status"`
+	Amount     Money         `json:"amount" db:"amount"` // the refunded amount, zero for other transitions
+	Actor      string        `json:"actor" db:"actor"`
+	Reason     string        `json:"reason" db:"reason"`
+	CreatedAt  time.Time     `json:"created_at" db:"created_at"`
+}
+
+// PaymentRefund is money given back on a payment. it is recorded pending
+// before the provider is asked for the money, so refunds still in flight
+// count against what is left to refund
+type PaymentRefund struct {
+	ID        int          `json:"id" db:"id"`
+	PaymentID int          `json:"payment_id" db:"payment_id"`
+	Amount    Money        `json:"amount" db:"amount"`
+	ToCredit  bool         `json:"to_credit" db:"to_credit"` // given back as ledger credit, the payment was paid from credit
+	Status    RefundStatus `json:"status" db:"status"`
+	Actor     string       `json:"actor" db:"actor"`
+	Reason    string       `json:"reason" db:"reason"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "pending"
+	RefundCompleted RefundStatus = "completed"
+	RefundFailed    RefundStatus = "failed"
+)
+
+// actors recorded on payment transitions
+const SystemActor = "system"
+
+func UserActor(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+func ProviderActor(provider string) string {
+	return "provider:" + provider
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentStatusCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from    PaymentStatus
+		to      PaymentStatus
+		allowed bool
+	}{
+		{Pending, Processing, true},
+		{Pending, Paid, true},
+		{Pending, Failed, false},
+		{Processing, Paid, true},
+		{Processing, Failed, true},
+		{Failed, Processing, true},
+		{PartiallyPaid, Paid, true},
+		{PartiallyPaid, Failed, false},
+		{Paid, Pending, false},
+		{Paid, Refunded, true},
+		{Paid, Chargeback, true},
+		{PartiallyRefunded, PartiallyRefunded, true},
+		{PartiallyRefunded, Paid, false},
+		{Refunded, Chargeback, false},
+		{Chargeback, Paid, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.allowed, tt.from.CanTransitionTo(tt.to))
+		})
+	}
+}
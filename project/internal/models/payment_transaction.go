@@ -0,0 +1,29 @@
+package models
+
+// PaymentTransaction is one attempt to pay a set of payments through a
+// payment provider
+type PaymentTransaction struct {
+	BaseModel
+	UserID         int               `json:"user_id" db:"user_id"`
+	PaymentIDs     []int             `json:"payment_ids" db:"payment_ids"`
+	PaymentAmounts []Money           `json:"payment_amounts" db:"payment_amounts"` // what each payment was charged, in the order of PaymentIDs
+	Provider       string            `json:"provider" db:"provider"`
+	Reference      string            `json:"reference" db:"reference"` // the provider's id for this transaction
+	RedirectURL    string            `json:"redirect_url" db:"redirect_url"`
+	Amount         Money             `json:"amount" db:"amount"`
+	RefundedAmount Money             `json:"refunded_amount" db:"refunded_amount"`
+	Status         TransactionStatus `json:"status" db:"status"`
+	TrackingCode   string            `json:"tracking_code" db:"tracking_code"`
+	IdempotencyKey string            `json:"-" db:"idempotency_key"`
+}
+
+type TransactionStatus string
+
+const (
+	TransactionInitiated         TransactionStatus = "initiated"
+	TransactionSucceeded         TransactionStatus = "succeeded"
+	TransactionFailed            TransactionStatus = "failed"
+	TransactionPartiallyRefunded TransactionStatus = "partially_refunded"
+	TransactionRefunded          TransactionStatus = "refunded"
+	TransactionExpired           TransactionStatus = "expired" // abandoned before its provider settled it
+)
@@ -0,0 +1,16 @@
+package models
+
+// DefaultReminderOffsets are the days, counted from a bill's due date, on
+// which residents are reminded of an unpaid share: a week before, on the day
+// and three days after the billing deadline
+var DefaultReminderOffsets = []int{-7, 0, 3}
+
+// ReminderCandidate is an unpaid payment of a resident who wants reminders
+type ReminderCandidate struct {
+	Payment
+	ApartmentID int      `json:"apartment_id" db:"apartment_id"`
+	BillType    BillType `json:"bill_type" db:"bill_type"`
+	DueDate     string   `json:"due_date" db:"due_date"`
+	Deadline    string   `json:"deadline" db:"deadline"`       // the billing deadline, or the due date when the bill has none
+	LastOffset  *int     `json:"last_offset" db:"last_offset"` // of the latest reminder sent, nil before the first
+}
@@ -0,0 +1,36 @@
+package models
+
+// ReportGrouping is how an apartment report breaks down its totals
+type ReportGrouping string
+
+const (
+	ReportByMonth    ReportGrouping = "month"     // by the month of the bill's due date
+	ReportByBillType ReportGrouping = "bill_type" // by BillType
+	ReportByResident ReportGrouping = "resident"  // by the resident who owes the share
+)
+
+func (g ReportGrouping) IsValid() bool {
+	return g == ReportByMonth || g == ReportByBillType || g == ReportByResident
+}
+
+// ReportRow is the totals of one group. Billed is what was charged including
+// late fees, Collected what was paid and not given back, Outstanding what
+// residents still owe on their shares
+type ReportRow struct {
+	Key         string `json:"key" db:"key"`     // YYYY-MM, the bill type or the resident's user id
+	Label       string `json:"label" db:"label"` // the key for people, the username for residents
+	Billed      Money  `json:"billed" db:"billed"`
+	Collected   Money  `json:"collected" db:"collected"`
+	Outstanding Money  `json:"outstanding" db:"outstanding"`
+}
+
+// ApartmentReport is the billed, collected and outstanding totals of an
+// apartment's bills due between From and To
+type ApartmentReport struct {
+	ApartmentID int            `json:"apartment_id"`
+	GroupBy     ReportGrouping `json:"group_by"`
+	From        string         `json:"from"`
+	To          string         `json:"to"`
+	Rows        []ReportRow    `json:"rows"`
+	Total       ReportRow      `json:"total"`
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permission is a bitmask of what a member may do in an apartment, one bit
+// per action from least to most significant
+type Permission uint32
+
+const (
+	PermViewApartment   Permission = 1 << iota // the apartment, its residents and members
+	PermManageApartment                        // details, division policy and resident shares
+	PermDeleteApartment
+	PermInviteMembers
+	PermManageBills    // bills, bill templates and late fee rules
+	PermManagePayments // refunds, chargebacks, waivers and installment plans
+	PermViewFinances   // every bill, payment, ledger and report of the apartment
+	PermManageRoles
+	PermPayBills // gets a share of the apartment's bills
+)
+
+var permissionNames = []struct {
+	perm Permission
+	name string
+}{
+	{PermViewApartment, "view_apartment"},
+	{PermManageApartment, "manage_apartment"},
+	{PermDeleteApartment, "delete_apartment"},
+	{PermInviteMembers, "invite_members"},
+	{PermManageBills, "manage_bills"},
+	{PermManagePayments, "manage_payments"},
+	{PermViewFinances, "view_finances"},
+	{PermManageRoles, "manage_roles"},
+	{PermPayBills, "pay_bills"},
+}
+
+// true when every bit of p is set
+func (m Permission) Has(p Permission) bool {
+	return m&p == p
+}
+
+// the names of the permissions set in the mask
+func (m Permission) Names() []string {
+	names := []string{}
+	for _, permission := range permissionNames {
+		if m.Has(permission.perm) {
+			names = append(names, permission.name)
+		}
+	}
+	return names
+}
+
+func (m Permission) String() string {
+	return strings.Join(m.Names(), ",")
+}
+
+// the mask of a list of permission names
+func ParsePermissions(names []string) (Permission, error) {
+	var mask Permission
+	for _, name := range names {
+		found := false
+		for _, permission := range permissionNames {
+			if permission.name == name {
+				mask |= permission.perm
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown permission %q", name)
+		}
+	}
+	return mask, nil
+}
+
+// Role is what a member is to an apartment. every role comes with a default
+// set of permissions
+type Role string
+
+const (
+	OwnerRole     Role = "owner"     // created the apartment, can do everything
+	ManagerRole   Role = "manager"   // runs the apartment day to day
+	TreasurerRole Role = "treasurer" // handles the money without managing the apartment
+	ResidentRole  Role = "resident"  // what members join as
+	ViewerRole    Role = "viewer"    // sees the apartment without paying its bills
+)
+
+var rolePermissions = map[Role]Permission{
+	OwnerRole: PermViewApartment | PermManageApartment | PermDeleteApartment | PermInviteMembers |
+		PermManageBills | PermManagePayments | PermViewFinances | PermManageRoles | PermPayBills,
+	ManagerRole: PermViewApartment | PermManageApartment | PermInviteMembers |
+		PermManageBills | PermManagePayments | PermViewFinances | PermManageRoles | PermPayBills,
+	TreasurerRole: PermViewApartment | PermManagePayments | PermViewFinances | PermPayBills,
+	ResidentRole:  PermViewApartment | PermPayBills,
+	ViewerRole:    PermViewApartment,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// the permissions the role comes with
+func (r Role) Permissions() Permission {
+	return rolePermissions[r]
+}
+
+// owners and managers are what the apartment used to call its managers
+func (r Role) IsManager() bool {
+	return r == OwnerRole || r == ManagerRole
+}
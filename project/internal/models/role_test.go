@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRolePermissions(t *testing.T) {
+	assert.True(t, OwnerRole.Permissions().Has(ManagerRole.Permissions()))
+	assert.False(t, ManagerRole.Permissions().Has(PermDeleteApartment))
+	assert.True(t, TreasurerRole.Permissions().Has(PermManagePayments|PermViewFinances))
+	assert.False(t, TreasurerRole.Permissions().Has(PermManageBills))
+	assert.Equal(t, []string{"view_apartment", "pay_bills"}, ResidentRole.Permissions().Names())
+	assert.False(t, ViewerRole.Permissions().Has(PermPayBills))
+
+	assert.True(t, OwnerRole.IsManager())
+	assert.True(t, ManagerRole.IsManager())
+	assert.False(t, TreasurerRole.IsManager())
+	assert.False(t, Role("admin").IsValid())
+	assert.Zero(t, Role("admin").Permissions())
+}
+
+func TestParsePermissions(t *testing.T) {
+	mask, err := ParsePermissions([]string{"view_finances", "view_apartment", "view_finances"})
+	require.NoError(t, err)
+	assert.Equal(t, PermViewApartment|PermViewFinances, mask)
+	assert.Equal(t, "view_apartment,view_finances", mask.String())
+
+	_, err = ParsePermissions([]string{"view_apartment", "rule_the_world"})
+	assert.ErrorContains(t, err, `unknown permission "rule_the_world"`)
+}
+
+func TestEffectivePermissions(t *testing.T) {
+	assert.Equal(t, ResidentRole.Permissions(), User_apartment{}.EffectivePermissions())
+	assert.Equal(t, TreasurerRole.Permissions(), User_apartment{Role: TreasurerRole}.EffectivePermissions())
+	assert.Equal(t, PermViewApartment, User_apartment{Role: ManagerRole, Permissions: PermViewApartment}.EffectivePermissions())
+}
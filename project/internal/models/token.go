@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RefreshSession is what the server keeps for a refresh token. the token
+// itself is only handed to the client, the session is stored under its hash
+type RefreshSession struct {
+	UserID        int       `json:"user_id"`
+	UserType      UserType  `json:"user_type"`
+	AccessTokenID string    `json:"access_token_id"` // the access token issued together with it
+	IssuedAt      time.Time `json:"issued_at"`
+}
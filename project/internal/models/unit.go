@@ -0,0 +1,25 @@
+package models
+
+// Unit is one flat of an apartment building. an apartment has at most
+// UnitsCount of them
+type Unit struct {
+	BaseModel
+	ApartmentID    int     `json:"apartment_id" db:"apartment_id"`
+	Number         string  `json:"number" db:"number"` // unique within the apartment, like "3" or "B12"
+	Floor          int     `json:"floor" db:"floor"`
+	Area           float64 `json:"area" db:"area"` // square meters
+	OccupantsCount int     `json:"occupants_count" db:"occupants_count"`
+	ParkingSpots   int     `json:"parking_spots" db:"parking_spots"`
+}
+
+// UnitRelation is how a member is attached to their unit
+type UnitRelation string
+
+const (
+	UnitOwner    UnitRelation = "owner"    // owns the unit, pays its share when nobody lives there
+	UnitResident UnitRelation = "resident" // lives in the unit and pays its share
+)
+
+func (r UnitRelation) IsValid() bool {
+	return r == UnitOwner || r == UnitResident
+}
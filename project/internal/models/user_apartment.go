@@ -2,7 +2,26 @@ package models
 
 type User_apartment struct {
 	BaseModel
-	UserID      int  `json:"user_id" db:"user_id"`
-	ApartmentID int  `json:"apartment_id" db:"apartment_id"`
-	IsManager   bool `json:"is_manager" db:"is_manager"`
+	UserID         int          `json:"user_id" db:"user_id"`
+	ApartmentID    int          `json:"apartment_id" db:"apartment_id"`
+	IsManager      bool         `json:"is_manager" db:"is_manager"`
+	Role           Role         `json:"role" db:"role"`
+	Permissions    Permission   `json:"-" db:"permissions"`                   // overrides the role's permissions when set
+	UnitArea       float64      `json:"unit_area" db:"unit_area"`             // square meters, used by the area policy
+	OccupantsCount int          `json:"occupants_count" db:"occupants_count"` // used by the occupants policy
+	SharePercent   float64      `json:"share_percent" db:"share_percent"`     // used by the custom policy
+	UnitID         *int         `json:"unit_id,omitempty" db:"unit_id"`
+	UnitRelation   UnitRelation `json:"unit_relation,omitempty" db:"unit_relation"`
+}
+
+// what the member may do in the apartment: the permissions granted to them,
+// or their role's when none were. a member without a role is a resident
+func (ua User_apartment) EffectivePermissions() Permission {
+	if ua.Permissions != 0 {
+		return ua.Permissions
+	}
+	if ua.Role == "" {
+		return ResidentRole.Permissions()
+	}
+	return ua.Role.Permissions()
 }
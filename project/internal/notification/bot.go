@@ -0,0 +1,24 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+// the data of the buttons under invitations, followed by a colon and the
+// invitation's token or id
+const (
+	AcceptInvitationButton = "accept"
+	RejectInvitationButton = "reject"
+)
+
+// BotHandler answers what users whose chat is linked to their account send
+// to the bot: commands, with the text after them as args, and presses of the
+// buttons under its messages. it also links chats to accounts with the code
+// sent from them
+type BotHandler interface {
+	HandleCommand(ctx context.Context, user models.User, command, args string) Message
+	HandleButton(ctx context.Context, user models.User, data string) Message
+	LinkChat(ctx context.Context, chatID int64, code string) Message
+}
@@ -0,0 +1,50 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+// ErrUnreachable is returned by a channel that has no address for the
+// recipient, like telegram for a user who never started the bot. the next
+// channel is tried instead
+var ErrUnreachable = errors.New("recipient can't be reached on this channel")
+
+// Channel delivers messages to users one way, telegram, email or sms
+type Channel interface {
+	Name() models.NotificationChannel
+	Send(ctx context.Context, recipient models.User, message Message) error
+}
+
+// Message is what is sent, the same on every channel
+type Message struct {
+	Subject string   // the email subject, the other channels leave it out
+	Text    string   // may use telegram markdown, plainText strips it for the other channels
+	Buttons []Button // telegram shows them under the text, the other channels leave them out
+}
+
+// Button is an action offered with a message. pressing it hands Data to the
+// BotHandler
+type Button struct {
+	Text string
+	Data string // at most 64 bytes
+}
+
+// escaped characters are kept, without their backslash
+var markdown = strings.NewReplacer("\\*", "*", "\\_", "_", "\\`", "`", "\\[", "[", "*", "", "_", "", "`", "")
+
+// the text without the markdown telegram renders
+func (m Message) plainText() string {
+	return markdown.Replace(m.Text)
+}
+
+var markdownEscaper = strings.NewReplacer("*", "\\*", "_", "\\_", "`", "\\`", "[", "\\[")
+
+// EscapeMarkdown keeps telegram from reading markdown in text users wrote,
+// like apartment names, put into a message
+func EscapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
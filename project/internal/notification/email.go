@@ -0,0 +1,79 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+// EmailChannel mails messages as plain text through an SMTP server
+type EmailChannel struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewEmailChannel(cfg config.Email) *EmailChannel {
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &EmailChannel{
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		auth: auth,
+		from: cfg.From,
+	}
+}
+
+func (c *EmailChannel) Name() models.NotificationChannel {
+	return models.EmailChannel
+}
+
+func (c *EmailChannel) Send(ctx context.Context, recipient models.User, message Message) error {
+	if recipient.Email == "" {
+		return ErrUnreachable
+	}
+
+	from, err := mail.ParseAddress(c.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %q: %w", c.from, err)
+	}
+	to := mail.Address{Name: recipient.FullName, Address: recipient.Email}
+
+	if err := smtp.SendMail(c.addr, c.auth, from.Address, []string{to.Address}, buildEmail(*from, to, message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// the message with the headers mail clients need to show utf-8 text, the
+// subject is encoded since it may not be ascii
+func buildEmail(from, to mail.Address, message Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from.String() + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", message.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.plainText(), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
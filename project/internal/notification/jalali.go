@@ -0,0 +1,53 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var jalaliMonths = []string{
+	"فروردین", "اردیبهشت", "خرداد", "تیر", "مرداد", "شهریور",
+	"مهر", "آبان", "آذر", "دی", "بهمن", "اسفند",
+}
+
+var persianDigits = strings.NewReplacer(
+	"0", "۰", "1", "۱", "2", "۲", "3", "۳", "4", "۴",
+	"5", "۵", "6", "۶", "7", "۷", "8", "۸", "9", "۹",
+)
+
+// the jalali (solar hijri) year, month and day of a gregorian date
+func toJalali(t time.Time) (year, month, day int) {
+	monthDays := []int{0, 31, 59, 90, 120, 151, 181, 212, 243, 273, 304, 334}
+	gy, gm, gd := t.Year(), int(t.Month()), t.Day()
+
+	leapYear := gy
+	if gm > 2 {
+		leapYear++
+	}
+	days := 355666 + 365*gy + (leapYear+3)/4 - (leapYear+99)/100 + (leapYear+399)/400 + gd + monthDays[gm-1]
+
+	year = -1595 + 33*(days/12053)
+	days %= 12053
+	year += 4 * (days / 1461)
+	days %= 1461
+	if days > 365 {
+		year += (days - 1) / 365
+		days = (days - 1) % 365
+	}
+	if days < 186 {
+		return year, 1 + days/31, 1 + days%31
+	}
+	return year, 7 + (days-186)/30, 1 + (days-186)%30
+}
+
+// the date in the jalali calendar with persian digits, e.g. "۱ فروردین ۱۴۰۴",
+// followed by the time of day when withTime is set
+func formatJalali(t time.Time, withTime bool) string {
+	year, month, day := toJalali(t)
+	formatted := fmt.Sprintf("%d %s %d", day, jalaliMonths[month-1], year)
+	if withTime {
+		formatted += t.Format(" 15:04")
+	}
+	return persianDigits.Replace(formatted)
+}
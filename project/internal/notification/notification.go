@@ -2,130 +2,250 @@ package notification
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"time"
+	"strconv"
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
 
-	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
 )
 
 type Notification interface {
-	SendNotification(ctx context.Context, userID int, message string) error
-	SendInvitation(ctx context.Context, inviteURL string, apartmentID int, receiverUsername string) error
-	SendBillNotification(ctx context.Context, userID int, bill models.Bill, amount float64) error
-	ListenForUpdates(ctx context.Context)
+	SendNotification(ctx context.Context, userID int, notice models.Notice) error
+	SendInvitation(ctx context.Context, invitation models.InvitationLink) error
+	SendBillNotification(ctx context.Context, userID int, bill models.Bill, amount models.Money) error
+	ListenForUpdates(ctx context.Context, handler BotHandler)
+}
+
+// a channel that also takes messages from users, like the telegram bot
+type updateListener interface {
+	ListenForUpdates(ctx context.Context, handler BotHandler)
 }
 
 type notificationImpl struct {
-	userRepo repositories.UserRepository
-	bot      *tgbotapi.BotAPI
+	userRepo      repositories.UserRepository
+	apartmentRepo repositories.ApartmentRepository
+	templateRepo  repositories.NotificationTemplateRepository
+	channels      map[models.NotificationChannel]Channel
 }
 
-func NewNotification(cfg config.TelegramConfig, userRepo repositories.UserRepository) Notification {
-	bot, err := tgbotapi.NewBotAPI(cfg.BotToken)
-	if err != nil {
-		log.Fatalf("Failed to create bot: %v", err)
+// sends every message on the first of the recipient's channels that reaches
+// them, written in their locale from the apartment's template or the
+// built-in one. channels left out are off, users who chose only those are
+// not notified at all
+func NewNotification(
+	userRepo repositories.UserRepository,
+	apartmentRepo repositories.ApartmentRepository,
+	templateRepo repositories.NotificationTemplateRepository,
+	channels ...Channel,
+) Notification {
+	n := &notificationImpl{
+		userRepo:      userRepo,
+		apartmentRepo: apartmentRepo,
+		templateRepo:  templateRepo,
+		channels:      make(map[models.NotificationChannel]Channel),
 	}
-
-	return &notificationImpl{
-		userRepo: userRepo,
-		bot:      bot,
+	for _, channel := range channels {
+		n.channels[channel.Name()] = channel
 	}
+	return n
 }
 
-func (n *notificationImpl) sendMessage(chatID int64, message string) error {
-	msg := tgbotapi.NewMessage(chatID, message)
-	msg.ParseMode = "Markdown"
+func (n *notificationImpl) SendNotification(ctx context.Context, userID int, notice models.Notice) error {
+	return n.notifyUser(ctx, userID, notice, nil)
+}
 
-	_, err := n.bot.Send(msg)
-	if err != nil {
-		return fmt.Errorf("failed to send message via tgbot: %w", err)
+// invitations to people who haven't signed up yet go to the email or phone
+// number they were addressed to, in the default locale
+func (n *notificationImpl) SendInvitation(ctx context.Context, invitation models.InvitationLink) error {
+	notice := models.Notice{
+		Template:    models.InvitationTemplate,
+		ApartmentID: invitation.ApartmentID,
+		Data: map[string]string{
+			"invite_url": invitation.InviteURL,
+			"expires_at": invitation.ExpiresAt.Format("2006-01-02 15:04"),
+		},
+	}
+	if invitation.ApartmentName != "" {
+		notice.Data["apartment"] = invitation.ApartmentName
 	}
-	return nil
-}
 
-func (n *notificationImpl) SendNotification(ctx context.Context, userID int, message string) error {
-	user, err := n.userRepo.GetUserByID(userID)
-	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+	if invitation.ReceiverID != 0 {
+		//users can answer right under the message in the bot
+		return n.notifyUser(ctx, invitation.ReceiverID, notice, func(message *Message, locale models.Locale) {
+			labels := invitationButtonLabels[locale]
+			message.Buttons = []Button{
+				{Text: labels[0], Data: AcceptInvitationButton + ":" + invitation.Token},
+				{Text: labels[1], Data: RejectInvitationButton + ":" + strconv.Itoa(invitation.ID)},
+			}
+		})
 	}
 
-	if user.TelegramChatID == 0 {
-		return fmt.Errorf("user hasn't started the bot yet")
+	message, err := n.render(ctx, notice, models.DefaultLocale)
+	if err != nil {
+		return err
 	}
+	recipient := models.User{Email: invitation.ReceiverEmail, Phone: invitation.ReceiverPhone}
+	return n.deliver(ctx, recipient, models.DefaultNotificationChannels, message)
+}
 
-	return n.sendMessage(user.TelegramChatID, message)
+func (n *notificationImpl) SendBillNotification(ctx context.Context, userID int, bill models.Bill, amount models.Money) error {
+	return n.notifyUser(ctx, userID, models.Notice{
+		Template:    models.NewBillTemplate,
+		ApartmentID: bill.ApartmentID,
+		Data: map[string]string{
+			"bill_type":   string(bill.BillType),
+			"amount":      amount.String(),
+			"due_date":    bill.DueDate,
+			"description": bill.Description,
+		},
+	}, nil)
 }
 
-func (n *notificationImpl) SendInvitation(ctx context.Context, inviteURL string, apartmentID int, receiverUsername string) error {
-	receiver, err := n.userRepo.GetUserByTelegramUser(receiverUsername)
-	if err != nil {
-		return fmt.Errorf("failed to get receiver user: %w", err)
+// runs the listeners of the channels that take messages from users until
+// they stop, answering them with handler
+func (n *notificationImpl) ListenForUpdates(ctx context.Context, handler BotHandler) {
+	done := make(chan struct{})
+	listening := 0
+	for _, channel := range n.channels {
+		if listener, ok := channel.(updateListener); ok {
+			listening++
+			go func() {
+				listener.ListenForUpdates(ctx, handler)
+				done <- struct{}{}
+			}()
+		}
 	}
-
-	if receiver.TelegramChatID == 0 {
-		return fmt.Errorf("receiver hasn't started the bot yet")
+	for ; listening > 0; listening-- {
+		<-done
 	}
+}
 
-	expiresAt := time.Now().Add(24 * time.Hour)
-	message := fmt.Sprintf(
-		"🏠 *New Apartment Invitation*\n\n"+
-			"You've been invited to join apartment *%d*!\n\n"+
-			"🔗 Accept Invitation: %s\n\n"+
-			"⏰ Expires: %s",
-		apartmentID,
-		inviteURL,
-		expiresAt.Format("2006-01-02 15:04:05"),
-	)
-
-	return n.sendMessage(receiver.TelegramChatID, message)
+// hands an update posted to a webhook to the channel that receives them
+func (n *notificationImpl) ReceiveUpdate(ctx context.Context, payload []byte) error {
+	for _, channel := range n.channels {
+		if receiver, ok := channel.(WebhookReceiver); ok {
+			return receiver.ReceiveUpdate(ctx, payload)
+		}
+	}
+	return errors.New("no channel receives webhook updates")
 }
 
-func (n *notificationImpl) SendBillNotification(ctx context.Context, userID int, bill models.Bill, amount float64) error {
+// sends the notice on the channels the user chose, or the default ones, in
+// their locale. decorate, when given, adds to the rendered message
+func (n *notificationImpl) notifyUser(ctx context.Context, userID int, notice models.Notice, decorate func(*Message, models.Locale)) error {
 	user, err := n.userRepo.GetUserByID(userID)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
+	logger := logrus.WithField("user_id", userID)
 
-	if user.TelegramChatID == 0 {
-		return fmt.Errorf("user hasn't started the bot yet")
+	order, err := n.userRepo.GetNotificationChannels(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to get notification channels, using the default ones")
+	}
+	if len(order) == 0 {
+		order = models.DefaultNotificationChannels
 	}
 
-	message := fmt.Sprintf(
-		"*New Bill Notification*\n\n"+
-			"Type: %s\n"+
-			"Your Share: %.2f\n"+
-			"Due Date: %s\n"+
-			"Description: %s\n",
-		bill.BillType, amount, bill.DueDate, bill.Description)
+	locale, err := n.userRepo.GetLocale(ctx, userID)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to get locale, using the default one")
+	}
+	if !locale.IsValid() {
+		locale = models.DefaultLocale
+	}
 
-	return n.sendMessage(user.TelegramChatID, message)
+	message, err := n.render(ctx, notice, locale)
+	if err != nil {
+		return err
+	}
+	if decorate != nil {
+		decorate(&message, locale)
+	}
+	return n.deliver(ctx, *user, order, message)
 }
 
-func (n *notificationImpl) ListenForUpdates(ctx context.Context) {
-	updateConfig := tgbotapi.NewUpdate(0)
-	updateConfig.Timeout = 30
-
-	updates := n.bot.GetUpdatesChan(updateConfig)
+// writes the notice in the locale from the apartment's template or, when it
+// has none or it fails, the built-in one. the apartment's name is filled in
+// as apartment unless the notice has it
+func (n *notificationImpl) render(ctx context.Context, notice models.Notice, locale models.Locale) (Message, error) {
+	//texts queued before templates existed are markdown already
+	if notice.Template == models.TextTemplate {
+		return Message{Subject: "Apartment notification", Text: notice.Data["text"]}, nil
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"template":     notice.Template,
+		"locale":       locale,
+		"apartment_id": notice.ApartmentID,
+	})
+
+	data := make(map[string]string, len(notice.Data)+1)
+	for key, value := range notice.Data {
+		data[key] = value
+	}
+	if _, ok := data["apartment"]; !ok && notice.ApartmentID != 0 {
+		data["apartment"] = strconv.Itoa(notice.ApartmentID)
+		if apartment, err := n.apartmentRepo.GetApartmentByID(notice.ApartmentID); err == nil {
+			data["apartment"] = apartment.ApartmentName
+		} else {
+			logger.WithError(err).Warn("Failed to get apartment name for notification")
+		}
+	}
+	escaped := make(map[string]string, len(data))
+	for key, value := range data {
+		escaped[key] = EscapeMarkdown(value)
+	}
 
-	for update := range updates {
-		if update.Message != nil {
-			go n.handleMessage(ctx, n.bot, update)
+	if notice.ApartmentID != 0 {
+		override, err := n.templateRepo.GetTemplate(ctx, notice.ApartmentID, notice.Template, locale)
+		switch {
+		case err == nil:
+			message, err := executeTemplate(Template{Subject: override.Subject, Body: override.Body}, locale, data, escaped)
+			if err == nil {
+				return message, nil
+			}
+			logger.WithError(err).Warn("Failed to render the apartment's template, using the built-in one")
+		case !errors.Is(err, sql.ErrNoRows):
+			logger.WithError(err).Warn("Failed to get the apartment's template, using the built-in one")
 		}
 	}
+
+	template, ok := DefaultTemplate(notice.Template, locale)
+	if !ok {
+		return Message{}, fmt.Errorf("unknown notification template %q", notice.Template)
+	}
+	return executeTemplate(template, locale, data, escaped)
 }
 
-func (n *notificationImpl) handleMessage(ctx context.Context, bot *tgbotapi.BotAPI, update tgbotapi.Update) {
-	if update.Message.IsCommand() && update.Message.Command() == "start" {
-		chatID := update.Message.Chat.ID
-		username := update.SentFrom().UserName
-		n.userRepo.UpdateTelegramChatID(ctx, username, chatID)
-		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Welcome, %s! Bot is active.", username))
-		bot.Send(msg)
-		return
+// tries the channels in order until one delivers the message. a channel that
+// can't reach the recipient or fails passes it on to the next
+func (n *notificationImpl) deliver(ctx context.Context, recipient models.User, order []models.NotificationChannel, message Message) error {
+	var errs []error
+	for _, name := range order {
+		channel, ok := n.channels[name]
+		if !ok {
+			continue
+		}
+
+		err := channel.Send(ctx, recipient, message)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrUnreachable) {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"user_id": recipient.ID,
+				"channel": name,
+			}).Warn("Failed to send notification, trying the next channel")
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+
+	if len(errs) == 0 {
+		return fmt.Errorf("none of the channels %v is configured", order)
 	}
+	return fmt.Errorf("no channel could deliver the notification: %w", errors.Join(errs...))
 }
@@ -0,0 +1,318 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// stands in for the telegram bot, which needs a real token
+type telegramStub struct {
+	sent     []int64
+	messages []Message
+}
+
+func (c *telegramStub) Name() models.NotificationChannel {
+	return models.TelegramChannel
+}
+
+func (c *telegramStub) Send(ctx context.Context, recipient models.User, message Message) error {
+	if recipient.TelegramChatID == 0 {
+		return ErrUnreachable
+	}
+	c.sent = append(c.sent, recipient.TelegramChatID)
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+func newTestSink(t *testing.T) *SMTPSink {
+	sink, err := NewSMTPSink()
+	require.NoError(t, err)
+	t.Cleanup(func() { sink.Close() })
+	return sink
+}
+
+func emailChannelFor(t *testing.T, sink *SMTPSink) *EmailChannel {
+	host, port, ok := strings.Cut(sink.Addr(), ":")
+	require.True(t, ok)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return NewEmailChannel(config.Email{Host: host, Port: portNumber, From: "Apartment <no-reply@example.com>"})
+}
+
+// a Notification whose apartments have no templates of their own
+func newTestNotification(userRepo *repositories.MockUserRepository, channels ...Channel) Notification {
+	templateRepo := new(repositories.MockNotificationTemplateRepository)
+	templateRepo.On("GetTemplate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, sql.ErrNoRows)
+	return NewNotification(userRepo, new(repositories.MockApartmentRepo), templateRepo, channels...)
+}
+
+func TestSendNotificationChannelOrder(t *testing.T) {
+	user := models.User{
+		BaseModel: models.BaseModel{ID: 1},
+		Email:     "resident@example.com",
+		Phone:     "+989123456789",
+	}
+	tests := []struct {
+		name          string
+		chatID        int64
+		preferences   []models.NotificationChannel
+		emailDown     bool
+		expectedVia   models.NotificationChannel
+		expectedError string
+	}{
+		{name: "telegram first by default", chatID: 42, expectedVia: models.TelegramChannel},
+		{name: "falls back to email without a telegram chat", expectedVia: models.EmailChannel},
+		{
+			name:        "user's own order",
+			chatID:      42,
+			preferences: []models.NotificationChannel{models.SMSChannel, models.TelegramChannel},
+			expectedVia: models.SMSChannel,
+		},
+		{name: "falls back to sms when the mail server is down", emailDown: true, expectedVia: models.SMSChannel},
+		{
+			name:          "no chosen channel reaches the user",
+			preferences:   []models.NotificationChannel{models.TelegramChannel},
+			expectedError: "no channel could deliver",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipient := user
+			recipient.TelegramChatID = tt.chatID
+			userRepo := new(repositories.MockUserRepository)
+			userRepo.On("GetUserByID", 1).Return(&recipient, nil)
+			userRepo.On("GetNotificationChannels", mock.Anything, 1).Return(tt.preferences, nil)
+			userRepo.On("GetLocale", mock.Anything, 1).Return(models.English, nil)
+
+			telegram := &telegramStub{}
+			sink := newTestSink(t)
+			email := emailChannelFor(t, sink)
+			if tt.emailDown {
+				sink.Close()
+			}
+			sms := NewFakeSMSGateway()
+
+			service := newTestNotification(userRepo, telegram, email, NewSMSChannel(sms))
+			err := service.SendNotification(context.Background(), 1, models.Notice{
+				Template: models.JoinedApartmentTemplate,
+				Data:     map[string]string{"apartment": "Sunset"},
+			})
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+
+			sent := map[models.NotificationChannel]int{
+				models.TelegramChannel: len(telegram.sent),
+				models.EmailChannel:    len(sink.Messages()),
+				models.SMSChannel:      len(sms.Messages()),
+			}
+			for channel, count := range sent {
+				if channel == tt.expectedVia {
+					assert.Equal(t, 1, count, "sent via %s", channel)
+				} else {
+					assert.Zero(t, count, "sent via %s", channel)
+				}
+			}
+		})
+	}
+}
+
+func TestSendInvitationToContact(t *testing.T) {
+	sms := NewFakeSMSGateway()
+	service := newTestNotification(new(repositories.MockUserRepository), &telegramStub{}, NewSMSChannel(sms))
+
+	err := service.SendInvitation(context.Background(), models.InvitationLink{
+		ReceiverPhone: "+989123456789",
+		ApartmentID:   2,
+		ApartmentName: "Sunset",
+		InviteURL:     "http://localhost:8080/api/v1/resident/apartment/invite/abc",
+		ExpiresAt:     time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+	})
+
+	assert.NoError(t, err)
+	require.Len(t, sms.Messages(), 1)
+	message := sms.Messages()[0]
+	assert.Equal(t, "+989123456789", message.Phone)
+	//people who haven't signed up get the default locale
+	assert.Contains(t, message.Text, "شما به ساختمان Sunset دعوت شده‌اید")
+	assert.Contains(t, message.Text, "۱۱ اسفند ۱۴۰۳ ۱۲:۰۰")
+	assert.Contains(t, message.Text, "/apartment/invite/abc")
+	assert.NotContains(t, message.Text, "*")
+}
+
+func TestSendInvitationButtons(t *testing.T) {
+	userRepo := new(repositories.MockUserRepository)
+	userRepo.On("GetUserByID", 5).Return(&models.User{BaseModel: models.BaseModel{ID: 5}, TelegramChatID: 42}, nil)
+	userRepo.On("GetNotificationChannels", mock.Anything, 5).Return(nil, nil)
+	userRepo.On("GetLocale", mock.Anything, 5).Return(models.English, nil)
+	telegram := &telegramStub{}
+	service := newTestNotification(userRepo, telegram)
+
+	err := service.SendInvitation(context.Background(), models.InvitationLink{
+		BaseModel:     models.BaseModel{ID: 9},
+		ReceiverID:    5,
+		ApartmentName: "Sunset",
+		Token:         "abc",
+	})
+
+	assert.NoError(t, err)
+	require.Len(t, telegram.messages, 1)
+	assert.Equal(t, []Button{
+		{Text: "✅ Accept", Data: "accept:abc"},
+		{Text: "❌ Reject", Data: "reject:9"},
+	}, telegram.messages[0].Buttons)
+}
+
+func TestSendBillNotificationTemplates(t *testing.T) {
+	bill := models.Bill{
+		ApartmentID: 2,
+		BillType:    models.WaterBill,
+		DueDate:     "2025-03-21",
+		Description: "pipes_fixed *again*",
+	}
+	tests := []struct {
+		name     string
+		locale   models.Locale
+		override *models.NotificationTemplate
+		expected []string
+	}{
+		{
+			name:     "english",
+			locale:   models.English,
+			expected: []string{"*New Bill Notification*", "Type: water", "Due Date: 2025-03-21", "Your Share: 50.00 IRR"},
+		},
+		{
+			name:     "persian with jalali due date",
+			locale:   models.Persian,
+			expected: []string{"*قبض جدید*", "نوع: آب", "سررسید: ۱ فروردین ۱۴۰۴", "سهم شما: ۵۰.۰۰ IRR"},
+		},
+		{
+			name:     "user text is escaped",
+			locale:   models.English,
+			expected: []string{`Description: pipes\_fixed \*again\*`},
+		},
+		{
+			name:     "apartment's own template",
+			locale:   models.Persian,
+			override: &models.NotificationTemplate{Subject: "{{.apartment}}", Body: "قبض {{billType .bill_type}} ساختمان {{.apartment}} تا {{date .due_date}}"},
+			expected: []string{`قبض آب ساختمان Sun\_set تا ۱ فروردین ۱۴۰۴`},
+		},
+		{
+			name:     "broken template of the apartment falls back to the built-in one",
+			locale:   models.English,
+			override: &models.NotificationTemplate{Body: "{{.amount | nothing}}"},
+			expected: []string{"*New Bill Notification*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := new(repositories.MockUserRepository)
+			userRepo.On("GetUserByID", 5).Return(&models.User{BaseModel: models.BaseModel{ID: 5}, TelegramChatID: 42}, nil)
+			userRepo.On("GetNotificationChannels", mock.Anything, 5).Return(nil, nil)
+			userRepo.On("GetLocale", mock.Anything, 5).Return(tt.locale, nil)
+			apartmentRepo := new(repositories.MockApartmentRepo)
+			apartmentRepo.On("GetApartmentByID", 2).Return(&models.Apartment{ApartmentName: "Sun_set"}, nil)
+			templateRepo := new(repositories.MockNotificationTemplateRepository)
+			if tt.override != nil {
+				templateRepo.On("GetTemplate", mock.Anything, 2, models.NewBillTemplate, tt.locale).Return(tt.override, nil)
+			} else {
+				templateRepo.On("GetTemplate", mock.Anything, 2, models.NewBillTemplate, tt.locale).Return(nil, sql.ErrNoRows)
+			}
+			telegram := &telegramStub{}
+
+			service := NewNotification(userRepo, apartmentRepo, templateRepo, telegram)
+			err := service.SendBillNotification(context.Background(), 5, bill, models.NewMoney(5000, "IRR"))
+
+			require.NoError(t, err)
+			require.Len(t, telegram.messages, 1)
+			for _, text := range tt.expected {
+				assert.Contains(t, telegram.messages[0].Text, text)
+			}
+			if tt.override != nil && tt.override.Subject != "" {
+				assert.Equal(t, "Sun_set", telegram.messages[0].Subject)
+			}
+		})
+	}
+}
+
+func TestFormatJalali(t *testing.T) {
+	tests := []struct {
+		date     time.Time
+		expected string
+	}{
+		{time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC), "۳۰ اسفند ۱۴۰۳"},
+		{time.Date(2025, 3, 21, 0, 0, 0, 0, time.UTC), "۱ فروردین ۱۴۰۴"},
+		{time.Date(2024, 9, 22, 0, 0, 0, 0, time.UTC), "۱ مهر ۱۴۰۳"},
+		{time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), "۲۴ مهر ۱۴۰۵"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, formatJalali(tt.date, false), tt.date.Format("2006-01-02"))
+	}
+}
+
+func TestTelegramReceiveUpdate(t *testing.T) {
+	channel := &TelegramChannel{webhookUpdates: make(chan tgbotapi.Update, 1)}
+	service := newTestNotification(new(repositories.MockUserRepository), channel)
+	receiver, ok := service.(WebhookReceiver)
+	require.True(t, ok)
+
+	err := receiver.ReceiveUpdate(context.Background(), []byte(`{"update_id": 7, "message": {"text": "/bills"}}`))
+	require.NoError(t, err)
+	update := <-channel.webhookUpdates
+	assert.Equal(t, 7, update.UpdateID)
+	assert.Equal(t, "/bills", update.Message.Text)
+
+	err = receiver.ReceiveUpdate(context.Background(), []byte("not json"))
+	assert.ErrorIs(t, err, ErrInvalidUpdate)
+
+	//nobody takes updates from a full queue
+	channel.webhookUpdates <- tgbotapi.Update{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = receiver.ReceiveUpdate(ctx, []byte(`{"update_id": 8}`))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	polling := &TelegramChannel{}
+	assert.Error(t, polling.ReceiveUpdate(context.Background(), []byte(`{"update_id": 9}`)))
+}
+
+func TestEmailChannel(t *testing.T) {
+	sink := newTestSink(t)
+	channel := emailChannelFor(t, sink)
+
+	err := channel.Send(context.Background(), models.User{FullName: "Sara", Email: "sara@example.com"}, Message{
+		Subject: "قبض جدید",
+		Text:    "*New Bill Notification*\n.\nYour Share: 5000 IRR",
+	})
+	assert.NoError(t, err)
+
+	require.Len(t, sink.Messages(), 1)
+	mail := sink.Messages()[0]
+	assert.Equal(t, "no-reply@example.com", mail.From)
+	assert.Equal(t, []string{"sara@example.com"}, mail.To)
+	assert.Contains(t, mail.Data, "Subject: =?utf-8?q?")
+	assert.Contains(t, mail.Data, "New Bill Notification\r\n.\r\nYour Share: 5000 IRR")
+
+	err = channel.Send(context.Background(), models.User{FullName: "Sara"}, Message{Text: "hi"})
+	assert.True(t, errors.Is(err, ErrUnreachable))
+}
@@ -0,0 +1,243 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+)
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultRetryDelay   = 30 * time.Second
+	defaultMaxAttempts  = 8
+	maxRetryDelay       = 6 * time.Hour
+
+	outboxBatchSize = 50
+	//how long a claimed message is left to its worker before another one
+	//may try it
+	outboxLease = 5 * time.Minute
+)
+
+// the payload of an OutboxMessageKind message queued before notices had
+// templates
+type textNotice struct {
+	Text string `json:"text"`
+}
+
+type outboxImpl struct {
+	repo repositories.OutboxRepository
+}
+
+// NewOutbox returns a Notification that queues messages in the outbox
+// instead of sending them. called with the context of a unit of work a
+// message is only queued when the work commits, and the OutboxWorker sends it
+// afterwards
+func NewOutbox(repo repositories.OutboxRepository) Notification {
+	return &outboxImpl{repo: repo}
+}
+
+func (o *outboxImpl) SendNotification(ctx context.Context, userID int, notice models.Notice) error {
+	return o.enqueue(ctx, models.OutboxMessageKind, userID, notice.ApartmentID, notice)
+}
+
+func (o *outboxImpl) SendInvitation(ctx context.Context, invitation models.InvitationLink) error {
+	return o.enqueue(ctx, models.OutboxInvitationKind, invitation.ReceiverID, invitation.ApartmentID, invitation)
+}
+
+func (o *outboxImpl) SendBillNotification(ctx context.Context, userID int, bill models.Bill, amount models.Money) error {
+	return o.enqueue(ctx, models.OutboxBillKind, userID, bill.ApartmentID, models.BillNotice{Bill: bill, Amount: amount})
+}
+
+// the outbox takes no messages from users
+func (o *outboxImpl) ListenForUpdates(ctx context.Context, handler BotHandler) {}
+
+func (o *outboxImpl) enqueue(ctx context.Context, kind models.OutboxKind, userID, apartmentID int, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	_, err = o.repo.EnqueueMessage(ctx, models.OutboxMessage{
+		Kind:        kind,
+		UserID:      userID,
+		ApartmentID: apartmentID,
+		Payload:     data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue notification: %w", err)
+	}
+	return nil
+}
+
+// OutboxWorker delivers the messages of the outbox through a Notification
+// that sends them. a failed attempt is retried with exponential backoff and
+// the message is dead-lettered once it runs out of attempts
+type OutboxWorker struct {
+	repo           repositories.OutboxRepository
+	notifier       Notification
+	inviteLinkRepo repositories.InviteLinkRepo
+	pollInterval   time.Duration
+	retryDelay     time.Duration
+	maxAttempts    int
+}
+
+func NewOutboxWorker(
+	cfg config.Outbox,
+	repo repositories.OutboxRepository,
+	notifier Notification,
+	inviteLinkRepo repositories.InviteLinkRepo,
+) *OutboxWorker {
+	w := &OutboxWorker{
+		repo:           repo,
+		notifier:       notifier,
+		inviteLinkRepo: inviteLinkRepo,
+		pollInterval:   cfg.PollInterval,
+		retryDelay:     cfg.RetryDelay,
+		maxAttempts:    cfg.MaxAttempts,
+	}
+	if w.pollInterval <= 0 {
+		w.pollInterval = defaultPollInterval
+	}
+	if w.retryDelay <= 0 {
+		w.retryDelay = defaultRetryDelay
+	}
+	if w.maxAttempts <= 0 {
+		w.maxAttempts = defaultMaxAttempts
+	}
+	return w
+}
+
+// delivers what is due every poll interval until the context is cancelled
+func (w *OutboxWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.DeliverDue(ctx, time.Now()); err != nil {
+			logrus.WithError(err).Error("Failed to deliver outbox messages")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// makes one attempt at every message due at now and returns how many went
+// through
+func (w *OutboxWorker) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	messages, err := w.repo.ClaimDueMessages(ctx, now, outboxLease, outboxBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	delivered := 0
+	for _, message := range messages {
+		if ctx.Err() != nil {
+			break
+		}
+		logger := logrus.WithFields(logrus.Fields{
+			"outbox_id": message.ID,
+			"kind":      message.Kind,
+			"attempt":   message.Attempts + 1,
+		})
+
+		sendErr := w.deliver(ctx, message)
+		if sendErr == nil {
+			if err := w.repo.MarkMessageDelivered(ctx, message.ID); err != nil {
+				logger.WithError(err).Error("Failed to mark outbox message as delivered")
+			}
+			delivered++
+			continue
+		}
+
+		if message.Attempts+1 >= w.maxAttempts {
+			logger.WithError(sendErr).Error("Giving up on outbox message")
+			if err := w.repo.MarkMessageDead(ctx, message.ID, sendErr.Error()); err != nil {
+				logger.WithError(err).Error("Failed to dead-letter outbox message")
+			}
+			continue
+		}
+
+		retryAt := now.Add(w.backoff(message.Attempts))
+		logger.WithError(sendErr).WithField("retry_at", retryAt).Warn("Failed to deliver outbox message")
+		if err := w.repo.ScheduleMessageRetry(ctx, message.ID, sendErr.Error(), retryAt); err != nil {
+			logger.WithError(err).Error("Failed to schedule outbox message retry")
+		}
+	}
+	return delivered, nil
+}
+
+// the wait before the next attempt of a message that failed attempts+1 times
+func (w *OutboxWorker) backoff(attempts int) time.Duration {
+	delay := w.retryDelay
+	for i := 0; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+func (w *OutboxWorker) deliver(ctx context.Context, message models.OutboxMessage) error {
+	switch message.Kind {
+	case models.OutboxMessageKind:
+		var notice models.Notice
+		if err := json.Unmarshal(message.Payload, &notice); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		if notice.Template == "" {
+			var text textNotice
+			if err := json.Unmarshal(message.Payload, &text); err != nil {
+				return fmt.Errorf("invalid payload: %w", err)
+			}
+			notice = models.Notice{Template: models.TextTemplate, Data: map[string]string{"text": text.Text}}
+		}
+		return w.notifier.SendNotification(ctx, message.UserID, notice)
+
+	case models.OutboxBillKind:
+		var notice models.BillNotice
+		if err := json.Unmarshal(message.Payload, &notice); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return w.notifier.SendBillNotification(ctx, message.UserID, notice.Bill, notice.Amount)
+
+	case models.OutboxInvitationKind:
+		var invitation models.InvitationLink
+		if err := json.Unmarshal(message.Payload, &invitation); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return w.deliverInvitation(ctx, invitation)
+	}
+	return fmt.Errorf("unknown outbox message kind %q", message.Kind)
+}
+
+// sends an invitation that is still open and marks it notified. one that
+// was answered, revoked or expired in the meantime is dropped
+func (w *OutboxWorker) deliverInvitation(ctx context.Context, invitation models.InvitationLink) error {
+	current, err := w.inviteLinkRepo.GetInvitationByID(ctx, invitation.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get invitation: %w", err)
+	}
+	if !current.Status.IsOpen() {
+		return nil
+	}
+	invitation.ExpiresAt = current.ExpiresAt
+	invitation.ApartmentName = current.ApartmentName
+
+	if err := w.notifier.SendInvitation(ctx, invitation); err != nil {
+		return err
+	}
+	err = w.inviteLinkRepo.MarkInvitationNotified(ctx, invitation.ID)
+	if err != nil && !errors.Is(err, repositories.ErrInvitationNotOpen) {
+		logrus.WithError(err).Warnf("Failed to mark invitation %d as notified", invitation.ID)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func outboxMessage(t *testing.T, id int, kind models.OutboxKind, attempts int, payload interface{}) models.OutboxMessage {
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	return models.OutboxMessage{
+		BaseModel: models.BaseModel{ID: id},
+		Kind:      kind,
+		UserID:    5,
+		Payload:   data,
+		Status:    models.OutboxPending,
+		Attempts:  attempts,
+	}
+}
+
+func TestOutboxEnqueuesInsteadOfSending(t *testing.T) {
+	repo := new(repositories.MockOutboxRepository)
+	repo.On("EnqueueMessage", mock.Anything, mock.MatchedBy(func(message models.OutboxMessage) bool {
+		return message.Kind == models.OutboxBillKind && message.UserID == 5 && message.ApartmentID == 2
+	})).Return(1, nil)
+
+	err := NewOutbox(repo).SendBillNotification(context.Background(), 5, models.Bill{ApartmentID: 2}, models.NewMoney(5000, "IRR"))
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestOutboxWorkerDeliverDue(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	botDown := errors.New("bot down")
+
+	tests := []struct {
+		name     string
+		attempts int
+		sendErr  error
+		setup    func(repo *repositories.MockOutboxRepository)
+	}{
+		{
+			name: "delivered",
+			setup: func(repo *repositories.MockOutboxRepository) {
+				repo.On("MarkMessageDelivered", mock.Anything, 1).Return(nil)
+			},
+		},
+		{
+			name:     "retried with backoff",
+			attempts: 2,
+			sendErr:  botDown,
+			setup: func(repo *repositories.MockOutboxRepository) {
+				repo.On("ScheduleMessageRetry", mock.Anything, 1, "bot down", now.Add(2*time.Minute)).Return(nil)
+			},
+		},
+		{
+			name:     "dead after the last attempt",
+			attempts: 7,
+			sendErr:  botDown,
+			setup: func(repo *repositories.MockOutboxRepository) {
+				repo.On("MarkMessageDead", mock.Anything, 1, "bot down").Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(repositories.MockOutboxRepository)
+			notifier := new(MockNotification)
+
+			notice := models.Notice{Template: models.JoinedApartmentTemplate, ApartmentID: 2}
+			message := outboxMessage(t, 1, models.OutboxMessageKind, tt.attempts, notice)
+			repo.On("ClaimDueMessages", mock.Anything, now, outboxLease, outboxBatchSize).Return([]models.OutboxMessage{message}, nil)
+			notifier.On("SendNotification", mock.Anything, 5, notice).Return(tt.sendErr)
+			tt.setup(repo)
+
+			worker := NewOutboxWorker(config.Outbox{RetryDelay: 30 * time.Second, MaxAttempts: 8}, repo, notifier, nil)
+			delivered, err := worker.DeliverDue(context.Background(), now)
+
+			assert.NoError(t, err)
+			if tt.sendErr == nil {
+				assert.Equal(t, 1, delivered)
+			} else {
+				assert.Zero(t, delivered)
+			}
+			repo.AssertExpectations(t)
+			notifier.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOutboxWorkerDeliversTextQueuedBeforeTemplates(t *testing.T) {
+	now := time.Now()
+	repo := new(repositories.MockOutboxRepository)
+	notifier := new(MockNotification)
+
+	repo.On("ClaimDueMessages", mock.Anything, now, outboxLease, outboxBatchSize).
+		Return([]models.OutboxMessage{outboxMessage(t, 1, models.OutboxMessageKind, 0, textNotice{Text: "hi"})}, nil)
+	notifier.On("SendNotification", mock.Anything, 5, models.Notice{
+		Template: models.TextTemplate,
+		Data:     map[string]string{"text": "hi"},
+	}).Return(nil)
+	repo.On("MarkMessageDelivered", mock.Anything, 1).Return(nil)
+
+	delivered, err := NewOutboxWorker(config.Outbox{}, repo, notifier, nil).DeliverDue(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	notifier.AssertExpectations(t)
+}
+
+func TestOutboxWorkerInvitations(t *testing.T) {
+	now := time.Now()
+	invitation := models.InvitationLink{BaseModel: models.BaseModel{ID: 7}, ReceiverID: 5, ApartmentID: 2}
+
+	t.Run("open invitation is sent and marked notified", func(t *testing.T) {
+		repo := new(repositories.MockOutboxRepository)
+		inviteRepo := new(repositories.MockInviteLinkRepository)
+		notifier := new(MockNotification)
+
+		repo.On("ClaimDueMessages", mock.Anything, now, outboxLease, outboxBatchSize).
+			Return([]models.OutboxMessage{outboxMessage(t, 1, models.OutboxInvitationKind, 0, invitation)}, nil)
+		inviteRepo.On("GetInvitationByID", mock.Anything, 7).
+			Return(&models.InvitationLink{Status: models.InvitationStatusPending, ApartmentName: "Sunset"}, nil)
+		notifier.On("SendInvitation", mock.Anything, mock.MatchedBy(func(sent models.InvitationLink) bool {
+			return sent.ID == 7 && sent.ReceiverID == 5 && sent.ApartmentName == "Sunset"
+		})).Return(nil)
+		inviteRepo.On("MarkInvitationNotified", mock.Anything, 7).Return(nil)
+		repo.On("MarkMessageDelivered", mock.Anything, 1).Return(nil)
+
+		_, err := NewOutboxWorker(config.Outbox{}, repo, notifier, inviteRepo).DeliverDue(context.Background(), now)
+
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+		inviteRepo.AssertExpectations(t)
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("revoked invitation is dropped", func(t *testing.T) {
+		repo := new(repositories.MockOutboxRepository)
+		inviteRepo := new(repositories.MockInviteLinkRepository)
+		notifier := new(MockNotification)
+
+		repo.On("ClaimDueMessages", mock.Anything, now, outboxLease, outboxBatchSize).
+			Return([]models.OutboxMessage{outboxMessage(t, 1, models.OutboxInvitationKind, 0, invitation)}, nil)
+		inviteRepo.On("GetInvitationByID", mock.Anything, 7).Return(&models.InvitationLink{Status: models.InvitationStatusRevoked}, nil)
+		repo.On("MarkMessageDelivered", mock.Anything, 1).Return(nil)
+
+		_, err := NewOutboxWorker(config.Outbox{}, repo, notifier, inviteRepo).DeliverDue(context.Background(), now)
+
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+		notifier.AssertNotCalled(t, "SendInvitation", mock.Anything, mock.Anything)
+	})
+}
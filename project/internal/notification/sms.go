@@ -0,0 +1,138 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+const (
+	FakeSMSGatewayName = "fake"
+	HTTPSMSGatewayName = "http"
+)
+
+// SMSGateway is a provider that delivers text messages to phone numbers
+type SMSGateway interface {
+	Name() string
+	Send(ctx context.Context, phone, text string) error
+}
+
+// SMSChannel texts messages to the user's phone through a gateway
+type SMSChannel struct {
+	gateway SMSGateway
+}
+
+func NewSMSChannel(gateway SMSGateway) *SMSChannel {
+	return &SMSChannel{gateway: gateway}
+}
+
+func (c *SMSChannel) Name() models.NotificationChannel {
+	return models.SMSChannel
+}
+
+func (c *SMSChannel) Send(ctx context.Context, recipient models.User, message Message) error {
+	if recipient.Phone == "" {
+		return ErrUnreachable
+	}
+	if err := c.gateway.Send(ctx, recipient.Phone, message.plainText()); err != nil {
+		return fmt.Errorf("failed to send sms via %s: %w", c.gateway.Name(), err)
+	}
+	return nil
+}
+
+// HTTPSMSGateway posts every message as json to the configured url, with the
+// api key as a bearer token
+type HTTPSMSGateway struct {
+	url    string
+	apiKey string
+	sender string
+	client *http.Client
+}
+
+func NewHTTPSMSGateway(cfg config.SMS) *HTTPSMSGateway {
+	return &HTTPSMSGateway{
+		url:    cfg.URL,
+		apiKey: cfg.APIKey,
+		sender: cfg.Sender,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (g *HTTPSMSGateway) Name() string {
+	return HTTPSMSGatewayName
+}
+
+func (g *HTTPSMSGateway) Send(ctx context.Context, phone, text string) error {
+	body, err := json.Marshal(map[string]string{
+		"from": g.sender,
+		"to":   phone,
+		"text": text,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+g.apiKey)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("gateway responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// FakeSMSGateway is an in-process gateway for development and tests. it
+// keeps the messages instead of sending them and logs each one
+type FakeSMSGateway struct {
+	mu       sync.Mutex
+	messages []SMS
+}
+
+// SMS is a text message the fake gateway was asked to send
+type SMS struct {
+	Phone string
+	Text  string
+}
+
+func NewFakeSMSGateway() *FakeSMSGateway {
+	return &FakeSMSGateway{}
+}
+
+func (g *FakeSMSGateway) Name() string {
+	return FakeSMSGatewayName
+}
+
+func (g *FakeSMSGateway) Send(ctx context.Context, phone, text string) error {
+	g.mu.Lock()
+	g.messages = append(g.messages, SMS{Phone: phone, Text: text})
+	g.mu.Unlock()
+
+	logrus.WithField("phone", phone).Infof("Fake sms: %s", text)
+	return nil
+}
+
+// the messages sent so far, oldest first
+func (g *FakeSMSGateway) Messages() []SMS {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]SMS(nil), g.messages...)
+}
@@ -0,0 +1,151 @@
+package notification
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// SMTPSink is an in-memory SMTP server for development and tests. it accepts
+// every mail without authentication and keeps it instead of delivering it,
+// point an EmailChannel at Addr to see what would have been sent
+type SMTPSink struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []SinkMessage
+	wg       sync.WaitGroup
+}
+
+// SinkMessage is one mail the sink received, Data holds the headers and body
+// as the client sent them
+type SinkMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// starts a sink on a free port of the loopback interface
+func NewSMTPSink() (*SMTPSink, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SMTPSink{listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+func (s *SMTPSink) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// the mails received so far, oldest first
+func (s *SMTPSink) Messages() []SinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SinkMessage(nil), s.messages...)
+}
+
+// stops accepting connections and waits for the open ones to end
+func (s *SMTPSink) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *SMTPSink) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer c.Close()
+			s.handle(c)
+		}()
+	}
+}
+
+// speaks just enough SMTP for net/smtp.SendMail
+func (s *SMTPSink) handle(c net.Conn) {
+	r := bufio.NewReader(c)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(c, format+"\r\n", args...)
+	}
+
+	reply("220 localhost SMTP sink ready")
+	var message SinkMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "HELO", "NOOP":
+			reply("250 OK")
+		case "RSET":
+			message = SinkMessage{}
+			reply("250 OK")
+		case "MAIL":
+			message = SinkMessage{From: angleAddress(line)}
+			reply("250 OK")
+		case "RCPT":
+			message.To = append(message.To, angleAddress(line))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+			message.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			message = SinkMessage{}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// the dot terminated body of a DATA command with the dot stuffing undone
+func readData(r *bufio.Reader) (string, error) {
+	var b strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return b.String(), nil
+		}
+		b.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+// the address between the angle brackets of a MAIL or RCPT command
+func angleAddress(line string) string {
+	start, end := strings.Index(line, "<"), strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
@@ -0,0 +1,238 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+)
+
+const (
+	TelegramPollingMode = "polling"
+	TelegramWebhookMode = "webhook"
+)
+
+// how many webhook updates wait for the listener before the webhook asks
+// telegram to send them again
+const webhookBuffer = 100
+
+// ErrInvalidUpdate is returned for a webhook payload that isn't an update
+var ErrInvalidUpdate = errors.New("invalid telegram update")
+
+// WebhookReceiver takes the updates telegram posts to the bot's webhook
+type WebhookReceiver interface {
+	ReceiveUpdate(ctx context.Context, payload []byte) error
+}
+
+// TelegramChannel sends messages through the bot to users who linked their
+// chat with it, and links the chat of whoever sends it a code from their
+// profile
+type TelegramChannel struct {
+	userRepo       repositories.UserRepository
+	bot            *tgbotapi.BotAPI
+	webhookURL     string
+	webhookSecret  string
+	webhookUpdates chan tgbotapi.Update // nil when polling
+}
+
+func NewTelegramChannel(cfg config.TelegramConfig, userRepo repositories.UserRepository) (*TelegramChannel, error) {
+	if cfg.BotToken == "" {
+		return nil, errors.New("no bot token configured")
+	}
+
+	c := &TelegramChannel{userRepo: userRepo}
+	switch cfg.Mode {
+	case "", TelegramPollingMode:
+	case TelegramWebhookMode:
+		if cfg.WebhookURL == "" || cfg.WebhookSecret == "" {
+			return nil, errors.New("webhook mode needs a webhook url and secret")
+		}
+		c.webhookURL = cfg.WebhookURL
+		c.webhookSecret = cfg.WebhookSecret
+		c.webhookUpdates = make(chan tgbotapi.Update, webhookBuffer)
+	default:
+		return nil, fmt.Errorf("unknown telegram mode %q", cfg.Mode)
+	}
+
+	bot, err := tgbotapi.NewBotAPI(cfg.BotToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bot: %w", err)
+	}
+	c.bot = bot
+	return c, nil
+}
+
+func (c *TelegramChannel) Name() models.NotificationChannel {
+	return models.TelegramChannel
+}
+
+func (c *TelegramChannel) Send(ctx context.Context, recipient models.User, message Message) error {
+	if recipient.TelegramChatID == 0 {
+		return ErrUnreachable
+	}
+
+	if _, err := c.bot.Send(newTelegramMessage(recipient.TelegramChatID, message)); err != nil {
+		return fmt.Errorf("failed to send message via tgbot: %w", err)
+	}
+	return nil
+}
+
+// answers the updates of the bot on behalf of handler until the context is
+// cancelled. they come from the webhook in webhook mode, which is registered
+// with telegram first, and by long polling otherwise
+func (c *TelegramChannel) ListenForUpdates(ctx context.Context, handler BotHandler) {
+	var updates <-chan tgbotapi.Update
+	if c.webhookUpdates != nil {
+		if err := c.setWebhook(); err != nil {
+			logrus.WithError(err).Error("Failed to register telegram webhook")
+			return
+		}
+		updates = c.webhookUpdates
+	} else {
+		//telegram refuses to be polled while a webhook is set
+		if _, err := c.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			logrus.WithError(err).Warn("Failed to remove telegram webhook")
+		}
+		updateConfig := tgbotapi.NewUpdate(0)
+		updateConfig.Timeout = 30
+		updates = c.bot.GetUpdatesChan(updateConfig)
+		defer c.bot.StopReceivingUpdates()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update := <-updates:
+			go c.handleUpdate(ctx, handler, update)
+		}
+	}
+}
+
+// queues an update posted to the webhook for the listener. it fails when the
+// queue stays full until the request is cancelled, telegram then sends the
+// update again later
+func (c *TelegramChannel) ReceiveUpdate(ctx context.Context, payload []byte) error {
+	if c.webhookUpdates == nil {
+		return errors.New("telegram updates are polled, not received on the webhook")
+	}
+
+	var update tgbotapi.Update
+	if err := json.Unmarshal(payload, &update); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidUpdate, err)
+	}
+
+	select {
+	case c.webhookUpdates <- update:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// points telegram at the webhook. the library's WebhookConfig has no secret
+// token, so the request is made by hand
+func (c *TelegramChannel) setWebhook() error {
+	params := tgbotapi.Params{
+		"url":          c.webhookURL,
+		"secret_token": c.webhookSecret,
+	}
+	if err := params.AddInterface("allowed_updates", []string{"message", "callback_query"}); err != nil {
+		return err
+	}
+	_, err := c.bot.MakeRequest("setWebhook", params)
+	return err
+}
+
+// /link or /start with a code links the chat to the account the code was
+// made for, /start with it comes from links to the bot like
+// t.me/<bot>?start=<code>. other messages and button presses go to the
+// handler with the user the chat is linked to. text that isn't a command is
+// answered like /help
+func (c *TelegramChannel) handleUpdate(ctx context.Context, handler BotHandler, update tgbotapi.Update) {
+	switch {
+	case update.CallbackQuery != nil:
+		query := update.CallbackQuery
+		//stops the spinner on the pressed button
+		if _, err := c.bot.Request(tgbotapi.NewCallback(query.ID, "")); err != nil {
+			logrus.WithError(err).Warn("Failed to answer telegram button press")
+		}
+		if query.Message == nil || handler == nil {
+			return
+		}
+		c.reply(ctx, query.Message.Chat.ID, func(user models.User) Message {
+			return handler.HandleButton(ctx, user, query.Data)
+		})
+
+	case update.Message == nil:
+
+	case handler == nil:
+
+	case isLinkCommand(update.Message):
+		chatID := update.Message.Chat.ID
+		message := handler.LinkChat(ctx, chatID, update.Message.CommandArguments())
+		if _, err := c.bot.Send(newTelegramMessage(chatID, message)); err != nil {
+			logrus.WithError(err).WithField("chat_id", chatID).Error("Failed to answer telegram message")
+		}
+
+	default:
+		command, args := "help", ""
+		if update.Message.IsCommand() {
+			command, args = update.Message.Command(), update.Message.CommandArguments()
+		}
+		c.reply(ctx, update.Message.Chat.ID, func(user models.User) Message {
+			return handler.HandleCommand(ctx, user, command, args)
+		})
+	}
+}
+
+// sends the chat the answer for the user it is linked to
+func (c *TelegramChannel) reply(ctx context.Context, chatID int64, answer func(user models.User) Message) {
+	var message Message
+	user, err := c.userRepo.GetUserByTelegramChatID(ctx, chatID)
+	switch {
+	case err == nil:
+		message = answer(*user)
+	case errors.Is(err, sql.ErrNoRows):
+		message = Message{Text: "This chat isn't linked to an account yet. Get a code on your profile and send it here as /link <code>."}
+	default:
+		logrus.WithError(err).WithField("chat_id", chatID).Error("Failed to get user of telegram chat")
+		message = Message{Text: "Something went wrong, please try again later."}
+	}
+
+	if _, err := c.bot.Send(newTelegramMessage(chatID, message)); err != nil {
+		logrus.WithError(err).WithField("chat_id", chatID).Error("Failed to answer telegram message")
+	}
+}
+
+func isLinkCommand(message *tgbotapi.Message) bool {
+	if !message.IsCommand() || strings.TrimSpace(message.CommandArguments()) == "" {
+		return false
+	}
+	return message.Command() == "link" || message.Command() == "start"
+}
+
+// the message in markdown with its buttons as an inline keyboard, one per
+// row
+func newTelegramMessage(chatID int64, message Message) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatID, message.Text)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+
+	if len(message.Buttons) > 0 {
+		rows := make([][]tgbotapi.InlineKeyboardButton, len(message.Buttons))
+		for i, button := range message.Buttons {
+			rows[i] = tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(button.Text, button.Data))
+		}
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	return msg
+}
@@ -0,0 +1,233 @@
+package notification
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+// Template is the subject and markdown body of a notification in one locale,
+// written in go text/template syntax. the notice's data fills them in, as
+// {{.apartment}}, and can be formatted with
+//   - date and datetime: a 2006-01-02 or 2006-01-02 15:04 date, in the jalali
+//     calendar for persian
+//   - billType and role: the name of a bill type or role in the locale
+//   - digits: numbers in the locale's digits
+type Template struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+var builtinTemplates = map[string]map[models.Locale]Template{
+	models.InvitationTemplate: {
+		models.English: {
+			Subject: "Apartment invitation",
+			Body: "🏠 *New Apartment Invitation*\n\n" +
+				"You've been invited to join apartment *{{.apartment}}*!\n\n" +
+				"🔗 Accept Invitation: {{.invite_url}}\n\n" +
+				"⏰ Expires: {{datetime .expires_at}}",
+		},
+		models.Persian: {
+			Subject: "دعوت به ساختمان",
+			Body: "🏠 *دعوت‌نامه جدید*\n\n" +
+				"شما به ساختمان *{{.apartment}}* دعوت شده‌اید!\n\n" +
+				"🔗 پذیرفتن دعوت: {{.invite_url}}\n\n" +
+				"⏰ مهلت: {{datetime .expires_at}}",
+		},
+	},
+	models.InvitationRejectedTemplate: {
+		models.English: {Subject: "Invitation rejected", Body: "Your invitation to apartment {{.apartment}} was rejected"},
+		models.Persian: {Subject: "دعوت رد شد", Body: "دعوت شما به ساختمان {{.apartment}} رد شد"},
+	},
+	models.JoinedApartmentTemplate: {
+		models.English: {Subject: "Welcome", Body: "You joined apartment {{.apartment}}"},
+		models.Persian: {Subject: "خوش آمدید", Body: "شما به ساختمان {{.apartment}} پیوستید"},
+	},
+	models.RoleGrantedTemplate: {
+		models.English: {Subject: "New role", Body: "You are now {{role .role}} of apartment {{.apartment}}"},
+		models.Persian: {Subject: "نقش جدید", Body: "شما اکنون {{role .role}} ساختمان {{.apartment}} هستید"},
+	},
+	models.RoleRevokedTemplate: {
+		models.English: {Subject: "Role revoked", Body: "Your role in apartment {{.apartment}} was revoked, you are now {{role .role}}"},
+		models.Persian: {Subject: "لغو نقش", Body: "نقش شما در ساختمان {{.apartment}} لغو شد، اکنون {{role .role}} هستید"},
+	},
+	models.HandoverOfferedTemplate: {
+		models.English: {Subject: "Management handover", Body: "You were offered the management of apartment {{.apartment}}, accept or decline the handover"},
+		models.Persian: {Subject: "واگذاری مدیریت", Body: "مدیریت ساختمان {{.apartment}} به شما پیشنهاد شد، آن را بپذیرید یا رد کنید"},
+	},
+	models.HandoverCancelledTemplate: {
+		models.English: {Subject: "Handover cancelled", Body: "The handover of apartment {{.apartment}} to you was cancelled"},
+		models.Persian: {Subject: "لغو واگذاری", Body: "واگذاری مدیریت ساختمان {{.apartment}} به شما لغو شد"},
+	},
+	models.HandoverDeclinedTemplate: {
+		models.English: {Subject: "Handover declined", Body: "The handover of apartment {{.apartment}} was declined"},
+		models.Persian: {Subject: "رد واگذاری", Body: "واگذاری مدیریت ساختمان {{.apartment}} رد شد"},
+	},
+	models.HandoverCompletedTemplate: {
+		models.English: {Subject: "Handover completed", Body: "Apartment {{.apartment}} was handed over, you are now {{role .role}} there"},
+		models.Persian: {Subject: "واگذاری انجام شد", Body: "مدیریت ساختمان {{.apartment}} واگذار شد، اکنون {{role .role}} آن هستید"},
+	},
+	models.NewManagerTemplate: {
+		models.English: {Subject: "New manager", Body: "You are now the manager of apartment {{.apartment}}"},
+		models.Persian: {Subject: "مدیر جدید", Body: "شما اکنون مدیر ساختمان {{.apartment}} هستید"},
+	},
+	models.NewBillTemplate: {
+		models.English: {
+			Subject: "New bill",
+			Body: "*New Bill Notification*\n\n" +
+				"Type: {{billType .bill_type}}\n" +
+				"Your Share: {{.amount}}\n" +
+				"Due Date: {{date .due_date}}\n" +
+				"Description: {{.description}}\n",
+		},
+		models.Persian: {
+			Subject: "قبض جدید",
+			Body: "*قبض جدید*\n\n" +
+				"نوع: {{billType .bill_type}}\n" +
+				"سهم شما: {{digits .amount}}\n" +
+				"سررسید: {{date .due_date}}\n" +
+				"توضیحات: {{.description}}\n",
+		},
+	},
+	// when is upcoming, tomorrow, today, grace (past the due date but not
+	// the deadline) or overdue
+	models.BillReminderTemplate: {
+		models.English: {
+			Subject: "Bill reminder",
+			Body: "⏰ *Bill Reminder*\n\n" +
+				"Your share of the {{billType .bill_type}} bill " +
+				`{{if eq .when "upcoming"}}is due in {{.days}} days, on {{date .due_date}}` +
+				`{{else if eq .when "tomorrow"}}is due tomorrow` +
+				`{{else if eq .when "today"}}is due today` +
+				`{{else if eq .when "overdue"}}is overdue since {{date .deadline}}` +
+				`{{else}}was due on {{date .due_date}}, pay it by {{date .deadline}}{{end}}.` + "\n" +
+				"Left to pay: {{.outstanding}}",
+		},
+		models.Persian: {
+			Subject: "یادآوری قبض",
+			Body: "⏰ *یادآوری قبض*\n\n" +
+				"سهم شما از قبض {{billType .bill_type}} " +
+				`{{if eq .when "upcoming"}}{{digits .days}} روز دیگر، در {{date .due_date}} سررسید می‌شود` +
+				`{{else if eq .when "tomorrow"}}فردا سررسید می‌شود` +
+				`{{else if eq .when "today"}}امروز سررسید می‌شود` +
+				`{{else if eq .when "overdue"}}از {{date .deadline}} معوق است` +
+				`{{else}}در {{date .due_date}} سررسید شد، تا {{date .deadline}} آن را بپردازید{{end}}.` + "\n" +
+				"مانده: {{digits .outstanding}}",
+		},
+	},
+}
+
+var persianBillTypes = map[string]string{
+	string(models.WaterBill):       "آب",
+	string(models.ElectricityBill): "برق",
+	string(models.GasBill):         "گاز",
+	string(models.MaintenanceBill): "نگهداری",
+	string(models.OtherBill):       "سایر",
+}
+
+var persianRoles = map[string]string{
+	string(models.OwnerRole):     "مالک",
+	string(models.ManagerRole):   "مدیر",
+	string(models.TreasurerRole): "خزانه‌دار",
+	string(models.ResidentRole):  "ساکن",
+	string(models.ViewerRole):    "ناظر",
+}
+
+// the labels of the buttons under invitations
+var invitationButtonLabels = map[models.Locale][2]string{
+	models.English: {"✅ Accept", "❌ Reject"},
+	models.Persian: {"✅ پذیرفتن", "❌ رد کردن"},
+}
+
+// the names of the notifications there are templates for, sorted
+func TemplateNames() []string {
+	names := make([]string, 0, len(builtinTemplates))
+	for name := range builtinTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// the built-in template of the notification in the locale
+func DefaultTemplate(name string, locale models.Locale) (Template, bool) {
+	template, ok := builtinTemplates[name][locale]
+	return template, ok
+}
+
+// checks that a template written for the notification in the locale is one
+// that can be rendered
+func ValidateTemplate(name string, locale models.Locale, template Template) error {
+	if _, ok := DefaultTemplate(name, locale); !ok {
+		return fmt.Errorf("there is no %q notification in locale %q", name, locale)
+	}
+	if strings.TrimSpace(template.Body) == "" {
+		return fmt.Errorf("the body is empty")
+	}
+	_, err := executeTemplate(template, locale, map[string]string{}, map[string]string{})
+	return err
+}
+
+// fills in the subject with data and the body with the same data escaped
+// for markdown
+func executeTemplate(tmpl Template, locale models.Locale, data, escaped map[string]string) (Message, error) {
+	subject, err := executeText(tmpl.Subject, locale, data)
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid subject: %w", err)
+	}
+	body, err := executeText(tmpl.Body, locale, escaped)
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid body: %w", err)
+	}
+	return Message{Subject: subject, Text: body}, nil
+}
+
+func executeText(text string, locale models.Locale, data map[string]string) (string, error) {
+	tmpl, err := template.New("").Funcs(templateFuncs(locale)).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func templateFuncs(locale models.Locale) template.FuncMap {
+	persian := locale == models.Persian
+	translate := func(names map[string]string) func(string) string {
+		return func(value string) string {
+			if name, ok := names[value]; ok && persian {
+				return name
+			}
+			return value
+		}
+	}
+	formatDate := func(layout string, withTime bool) func(string) string {
+		return func(value string) string {
+			t, err := time.Parse(layout, value)
+			if err != nil || !persian {
+				return value
+			}
+			return formatJalali(t, withTime)
+		}
+	}
+
+	return template.FuncMap{
+		"date":     formatDate("2006-01-02", false),
+		"datetime": formatDate("2006-01-02 15:04", true),
+		"billType": translate(persianBillTypes),
+		"role":     translate(persianRoles),
+		"digits": func(value string) string {
+			if persian {
+				return persianDigits.Replace(value)
+			}
+			return value
+		},
+	}
+}
@@ -0,0 +1,149 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+const FakeProviderName = "fake"
+
+// FakeProvider is an in-process gateway for development and tests. it
+// approves every payment right away: the redirect url points straight at the
+// callback with a signed approval, so no network access is needed
+type FakeProvider struct {
+	secret   []byte
+	mu       sync.Mutex
+	sessions map[string]*fakeSession
+	tracking int
+}
+
+type fakeSession struct {
+	amount   models.Money
+	refunded models.Money
+	paid     bool
+}
+
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{
+		secret:   []byte(secret),
+		sessions: make(map[string]*fakeSession),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (p *FakeProvider) Initiate(ctx context.Context, req InitiateRequest) (*InitiateResult, error) {
+	if !req.Amount.IsPositive() {
+		return nil, errors.New("amount must be positive")
+	}
+
+	callbackURL, err := url.Parse(req.CallbackURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callback url: %w", err)
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	reference := fmt.Sprintf("fake-%d-%s", req.TransactionID, hex.EncodeToString(buf))
+
+	p.mu.Lock()
+	p.sessions[reference] = &fakeSession{
+		amount:   req.Amount,
+		refunded: models.NewMoney(0, req.Amount.Currency),
+	}
+	p.mu.Unlock()
+
+	query := callbackURL.Query()
+	for key, values := range p.CallbackParams(reference, true) {
+		query[key] = values
+	}
+	callbackURL.RawQuery = query.Encode()
+
+	return &InitiateResult{
+		Reference:   reference,
+		RedirectURL: callbackURL.String(),
+	}, nil
+}
+
+// CallbackParams builds the query the gateway would send back to the
+// callback url, approved or declined
+func (p *FakeProvider) CallbackParams(reference string, approved bool) url.Values {
+	status := "NOK"
+	if approved {
+		status = "OK"
+	}
+	return url.Values{
+		"reference": {reference},
+		"status":    {status},
+		"signature": {p.sign(reference, status)},
+	}
+}
+
+func (p *FakeProvider) VerifyCallback(ctx context.Context, params url.Values) (*VerifyResult, error) {
+	reference := params.Get("reference")
+	status := params.Get("status")
+	if !hmac.Equal([]byte(params.Get("signature")), []byte(p.sign(reference, status))) {
+		return nil, errors.New("invalid callback signature")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	session, exists := p.sessions[reference]
+	if !exists {
+		return nil, fmt.Errorf("unknown payment reference %s", reference)
+	}
+	if status != "OK" {
+		return &VerifyResult{Reference: reference}, nil
+	}
+
+	session.paid = true
+	p.tracking++
+	return &VerifyResult{
+		Reference:    reference,
+		Success:      true,
+		TrackingCode: fmt.Sprintf("FAKE-%06d", p.tracking),
+	}, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, reference string, amount models.Money) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	session, exists := p.sessions[reference]
+	if !exists {
+		return fmt.Errorf("unknown payment reference %s", reference)
+	}
+	if !session.paid {
+		return errors.New("payment was not completed")
+	}
+
+	refunded, err := session.refunded.Add(amount)
+	if err != nil {
+		return err
+	}
+	if refunded.Amount > session.amount.Amount {
+		return errors.New("refund exceeds the paid amount")
+	}
+	session.refunded = refunded
+	return nil
+}
+
+func (p *FakeProvider) sign(reference, status string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(reference + "|" + status))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -2,44 +2,261 @@ package payment
 
 import (
 	"context"
-	"log"
-	"strconv"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
 )
 
 type Payment interface {
-	PayBills(paymentIDs []int, idempotentKey string) error
+	Initiate(ctx context.Context, userID int, paymentIDs []int, amounts []models.Money, idempotentKey string) (*models.PaymentTransaction, error)
+	VerifyCallback(ctx context.Context, providerName string, params url.Values) (*models.PaymentTransaction, bool, error)
+	Refund(ctx context.Context, transactionID int, amount models.Money) (*models.PaymentTransaction, error)
+	RefundPayment(ctx context.Context, paymentID int, amount models.Money) (*models.PaymentTransaction, error)
+	PaidByProvider(ctx context.Context, paymentID int) (bool, error)
+	StaleTransactions(ctx context.Context, now time.Time) ([]models.PaymentTransaction, error)
+	Expire(ctx context.Context, transactionID int) (bool, error)
 }
 
+// how long a resident has to finish paying with the provider when
+// payment.transaction_ttl is unset
+const defaultTransactionTTL = time.Hour
+
 type paymentImpl struct {
-	redis *redis.Client
+	providers       map[string]Provider
+	defaultProvider string
+	callbackURL     string
+	transactionTTL  time.Duration
+	txRepo          repositories.PaymentTransactionRepository
 }
 
-func NewPayment(redis *redis.Client) Payment {
-	return &paymentImpl{
-		redis: redis,
+func NewPayment(cfg config.Payment, txRepo repositories.PaymentTransactionRepository, providers ...Provider) Payment {
+	p := &paymentImpl{
+		providers:       make(map[string]Provider),
+		defaultProvider: cfg.Provider,
+		callbackURL:     strings.TrimSuffix(cfg.CallbackURL, "/"),
+		transactionTTL:  cfg.TransactionTTL,
+		txRepo:          txRepo,
+	}
+	if p.transactionTTL <= 0 {
+		p.transactionTTL = defaultTransactionTTL
+	}
+	for _, provider := range providers {
+		p.providers[provider.Name()] = provider
+	}
+	if p.defaultProvider == "" && len(providers) > 0 {
+		p.defaultProvider = providers[0].Name()
 	}
+	return p
 }
 
-func (p *paymentImpl) PayBills(paymentIDs []int, idempotentKey string) error {
-	for _, billID := range paymentIDs {
-		key := billPaymentKey(billID, idempotentKey)
-		if p.redis.Exists(context.Background(), key).Val() > 0 {
-			log.Printf("Payment for bill %d with idempotent key %s already processed", billID, idempotentKey)
-			continue
+// starts a transaction for the given payments, charging each its amount,
+// with the default provider. the same idempotent key always returns the
+// transaction started first, so a retried request does not charge the
+// resident twice
+func (p *paymentImpl) Initiate(ctx context.Context, userID int, paymentIDs []int, amounts []models.Money, idempotentKey string) (*models.PaymentTransaction, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"idempotent_key": idempotentKey,
+	})
+
+	if existing, err := p.txRepo.GetTransactionByIdempotencyKey(ctx, userID, idempotentKey); err == nil {
+		logger.WithField("transaction_id", existing.ID).Info("Payment already initiated with this idempotent key")
+		return existing, nil
+	}
+
+	if len(paymentIDs) == 0 {
+		return nil, errors.New("no payments to pay")
+	}
+	if len(amounts) != len(paymentIDs) {
+		return nil, errors.New("every payment needs an amount")
+	}
+	var amount models.Money
+	for _, paymentAmount := range amounts {
+		sum, err := amount.Add(paymentAmount)
+		if err != nil {
+			return nil, err
 		}
-		log.Printf("Processing payment for bill %d with idempotent key %s", billID, idempotentKey)
+		amount = sum
+	}
+
+	provider, exists := p.providers[p.defaultProvider]
+	if !exists {
+		return nil, fmt.Errorf("payment provider %q is not configured", p.defaultProvider)
+	}
 
-		//marking the payment as processed in redis
-		if err := p.redis.Set(context.Background(), key, "processed", 0).Err(); err != nil {
-			return err
+	transaction := models.PaymentTransaction{
+		UserID:         userID,
+		PaymentIDs:     paymentIDs,
+		PaymentAmounts: amounts,
+		Provider:       provider.Name(),
+		Amount:         amount,
+		RefundedAmount: models.NewMoney(0, amount.Currency),
+		Status:         models.TransactionInitiated,
+		IdempotencyKey: idempotentKey,
+	}
+
+	id, err := p.txRepo.CreateTransaction(ctx, transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment transaction: %w", err)
+	}
+	transaction.ID = id
+
+	result, err := provider.Initiate(ctx, InitiateRequest{
+		TransactionID: id,
+		Amount:        amount,
+		Description:   fmt.Sprintf("payment of %d bills", len(paymentIDs)),
+		CallbackURL:   p.callbackURL + "/" + provider.Name(),
+	})
+	if err != nil {
+		logger.WithError(err).Error("Payment provider failed to initiate payment")
+		transaction.Status = models.TransactionFailed
+		if err := p.txRepo.UpdateTransactionStatus(ctx, transaction); err != nil {
+			logger.WithError(err).Error("Failed to mark payment transaction as failed")
 		}
+		return nil, fmt.Errorf("failed to initiate payment: %w", err)
+	}
+
+	if err := p.txRepo.SetTransactionReference(ctx, id, result.Reference, result.RedirectURL); err != nil {
+		return nil, fmt.Errorf("failed to save payment reference: %w", err)
 	}
+	transaction.Reference = result.Reference
+	transaction.RedirectURL = result.RedirectURL
 
-	return nil
+	logger.WithField("transaction_id", id).Info("Payment initiated")
+	return &transaction, nil
 }
 
-func billPaymentKey(paymentID int, idempotentKey string) string {
-	return "bill_payment:" + strconv.Itoa(paymentID) + ":" + idempotentKey
+// checks a provider callback and records the outcome on the transaction.
+// settled is only true for the callback that moved the transaction out of
+// initiated, a repeated callback returns the transaction unchanged
+func (p *paymentImpl) VerifyCallback(ctx context.Context, providerName string, params url.Values) (*models.PaymentTransaction, bool, error) {
+	provider, exists := p.providers[providerName]
+	if !exists {
+		return nil, false, fmt.Errorf("unknown payment provider %q", providerName)
+	}
+
+	result, err := provider.VerifyCallback(ctx, params)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to verify payment: %w", err)
+	}
+
+	transaction, err := p.txRepo.GetTransactionByReference(ctx, providerName, result.Reference)
+	if err != nil {
+		return nil, false, fmt.Errorf("payment transaction not found: %w", err)
+	}
+	if transaction.Status == models.TransactionExpired && result.Success {
+		logrus.WithField("transaction_id", transaction.ID).
+			Error("Provider captured an expired payment transaction, it has to be reconciled by hand")
+	}
+	if transaction.Status != models.TransactionInitiated {
+		return transaction, false, nil
+	}
+
+	transaction.Status = models.TransactionFailed
+	if result.Success {
+		transaction.Status = models.TransactionSucceeded
+		transaction.TrackingCode = result.TrackingCode
+	}
+	if err := p.txRepo.UpdateTransactionStatus(ctx, *transaction); err != nil {
+		return nil, false, fmt.Errorf("failed to update payment transaction: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"transaction_id": transaction.ID,
+		"status":         transaction.Status,
+	}).Info("Payment verified")
+	return transaction, true, nil
+}
+
+// gives back part or all of a successful transaction
+func (p *paymentImpl) Refund(ctx context.Context, transactionID int, amount models.Money) (*models.PaymentTransaction, error) {
+	transaction, err := p.txRepo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("payment transaction not found: %w", err)
+	}
+	if transaction.Status != models.TransactionSucceeded && transaction.Status != models.TransactionPartiallyRefunded {
+		return nil, errors.New("only successful payments can be refunded")
+	}
+	if !amount.IsPositive() {
+		return nil, errors.New("refund amount must be positive")
+	}
+
+	refunded, err := transaction.RefundedAmount.Add(amount)
+	if err != nil {
+		return nil, err
+	}
+	if refunded.Amount > transaction.Amount.Amount {
+		return nil, errors.New("refund exceeds the paid amount")
+	}
+
+	provider, exists := p.providers[transaction.Provider]
+	if !exists {
+		return nil, fmt.Errorf("unknown payment provider %q", transaction.Provider)
+	}
+	if err := provider.Refund(ctx, transaction.Reference, amount); err != nil {
+		return nil, fmt.Errorf("refund failed: %w", err)
+	}
+
+	transaction.RefundedAmount = refunded
+	transaction.Status = models.TransactionPartiallyRefunded
+	if refunded.Amount == transaction.Amount.Amount {
+		transaction.Status = models.TransactionRefunded
+	}
+	if err := p.txRepo.UpdateTransactionStatus(ctx, *transaction); err != nil {
+		return nil, fmt.Errorf("failed to update payment transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+// refunds part of the transaction that paid the given payment
+func (p *paymentImpl) RefundPayment(ctx context.Context, paymentID int, amount models.Money) (*models.PaymentTransaction, error) {
+	transaction, err := p.txRepo.GetPaidTransactionByPayment(ctx, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("no successful transaction found for payment %d: %w", paymentID, err)
+	}
+	return p.Refund(ctx, transaction.ID, amount)
+}
+
+// whether a provider transaction paid the payment, a payment paid from the
+// resident's credit has none
+func (p *paymentImpl) PaidByProvider(ctx context.Context, paymentID int) (bool, error) {
+	_, err := p.txRepo.GetPaidTransactionByPayment(ctx, paymentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get transaction of payment %d: %w", paymentID, err)
+	}
+	return true, nil
+}
+
+// the transactions still waiting for their provider longer than the
+// transaction ttl, the resident abandoned them or the provider lost them
+func (p *paymentImpl) StaleTransactions(ctx context.Context, now time.Time) ([]models.PaymentTransaction, error) {
+	transactions, err := p.txRepo.GetStaleTransactions(ctx, now.Add(-p.transactionTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stale payment transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// gives up on a transaction its provider never settled. expired is false
+// when a callback settled it first
+func (p *paymentImpl) Expire(ctx context.Context, transactionID int) (bool, error) {
+	expired, err := p.txRepo.ExpireTransaction(ctx, transactionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to expire payment transaction: %w", err)
+	}
+	if expired {
+		logrus.WithField("transaction_id", transactionID).Info("Payment transaction expired")
+	}
+	return expired, nil
 }
@@ -1,6 +1,11 @@
 package payment
 
 import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -8,52 +13,68 @@ type MockPayment struct {
 	mock.Mock
 }
 
-func (m *MockPayment) PayBills(billIDs []int, idempotentKey string) error {
-	args := m.Called(billIDs, idempotentKey)
-	return args.Error(0)
-}
-
 func NewMockPayment() *MockPayment {
 	return &MockPayment{}
 }
 
-func (m *MockPayment) ExpectPayBills(billIDs []int, idempotentKey string, returnError error) *mock.Call {
-	return m.On("PayBills", billIDs, idempotentKey).Return(returnError)
+func (m *MockPayment) Initiate(ctx context.Context, userID int, paymentIDs []int, amounts []models.Money, idempotentKey string) (*models.PaymentTransaction, error) {
+	args := m.Called(ctx, userID, paymentIDs, amounts, idempotentKey)
+	if transaction, ok := args.Get(0).(*models.PaymentTransaction); ok {
+		return transaction, args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
-func (m *MockPayment) ExpectPayBillsOnce(billIDs []int, idempotentKey string, returnError error) *mock.Call {
-	return m.On("PayBills", billIDs, idempotentKey).Return(returnError).Once()
+func (m *MockPayment) VerifyCallback(ctx context.Context, providerName string, params url.Values) (*models.PaymentTransaction, bool, error) {
+	args := m.Called(ctx, providerName, params)
+	if transaction, ok := args.Get(0).(*models.PaymentTransaction); ok {
+		return transaction, args.Bool(1), args.Error(2)
+	}
+	return nil, args.Bool(1), args.Error(2)
 }
 
-func (m *MockPayment) ExpectPayBillsTimes(times int, billIDs []int, idempotentKey string, returnError error) *mock.Call {
-	return m.On("PayBills", billIDs, idempotentKey).Return(returnError).Times(times)
+func (m *MockPayment) Refund(ctx context.Context, transactionID int, amount models.Money) (*models.PaymentTransaction, error) {
+	args := m.Called(ctx, transactionID, amount)
+	if transaction, ok := args.Get(0).(*models.PaymentTransaction); ok {
+		return transaction, args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
-func (m *MockPayment) ExpectPayBillsWithAnyArgs(returnError error) *mock.Call {
-	return m.On("PayBills", mock.Anything, mock.Anything).Return(returnError)
+func (m *MockPayment) RefundPayment(ctx context.Context, paymentID int, amount models.Money) (*models.PaymentTransaction, error) {
+	args := m.Called(ctx, paymentID, amount)
+	if transaction, ok := args.Get(0).(*models.PaymentTransaction); ok {
+		return transaction, args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
-func (m *MockPayment) ExpectPayBillsWithAnyArgsOnce(returnError error) *mock.Call {
-	return m.On("PayBills", mock.Anything, mock.Anything).Return(returnError).Once()
+func (m *MockPayment) PaidByProvider(ctx context.Context, paymentID int) (bool, error) {
+	args := m.Called(ctx, paymentID)
+	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockPayment) ExpectPayBillsWithSpecificBillIDs(billIDs []int, returnError error) *mock.Call {
-	return m.On("PayBills", billIDs, mock.AnythingOfType("string")).Return(returnError)
+func (m *MockPayment) StaleTransactions(ctx context.Context, now time.Time) ([]models.PaymentTransaction, error) {
+	args := m.Called(ctx, now)
+	if transactions, ok := args.Get(0).([]models.PaymentTransaction); ok {
+		return transactions, args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
-func (m *MockPayment) ExpectPayBillsWithSpecificIdempotentKey(idempotentKey string, returnError error) *mock.Call {
-	return m.On("PayBills", mock.AnythingOfType("[]int"), idempotentKey).Return(returnError)
+func (m *MockPayment) Expire(ctx context.Context, transactionID int) (bool, error) {
+	args := m.Called(ctx, transactionID)
+	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockPayment) ExpectNoPayBillsCalls(t mock.TestingT) {
-	m.AssertNotCalled(t, "PayBills")
+func (m *MockPayment) ExpectInitiate(userID int, paymentIDs []int, idempotentKey string, transaction *models.PaymentTransaction, returnError error) *mock.Call {
+	return m.On("Initiate", mock.Anything, userID, paymentIDs, mock.Anything, idempotentKey).Return(transaction, returnError)
 }
 
-func (m *MockPayment) ExpectPayBillsCalledWith(t mock.TestingT, billIDs []int, idempotentKey string) {
-	m.AssertCalled(t, "PayBills", billIDs, idempotentKey)
+func (m *MockPayment) ExpectVerifyCallback(providerName string, transaction *models.PaymentTransaction, settled bool, returnError error) *mock.Call {
+	return m.On("VerifyCallback", mock.Anything, providerName, mock.Anything).Return(transaction, settled, returnError)
 }
 
-func (m *MockPayment) ExpectPayBillsCalledOnceWith(t mock.TestingT, billIDs []int, idempotentKey string) {
-	m.AssertNumberOfCalls(t, "PayBills", 1)
-	m.AssertCalled(t, "PayBills", billIDs, idempotentKey)
+func (m *MockPayment) ExpectNoInitiateCalls(t mock.TestingT) {
+	m.AssertNotCalled(t, "Initiate")
 }
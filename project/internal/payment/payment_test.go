@@ -0,0 +1,213 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestPayment(txRepo *repositories.MockPaymentTransactionRepository) (Payment, *FakeProvider) {
+	provider := NewFakeProvider("secret")
+	cfg := config.Payment{CallbackURL: "http://localhost:8080/api/v1/payment/callback/"}
+	return NewPayment(cfg, txRepo, provider), provider
+}
+
+func TestPaymentFlowWithFakeProvider(t *testing.T) {
+	ctx := context.Background()
+	txRepo := new(repositories.MockPaymentTransactionRepository)
+	service, _ := newTestPayment(txRepo)
+	amounts := []models.Money{models.NewMoney(50000, "IRR"), models.NewMoney(100000, "IRR")}
+
+	var stored models.PaymentTransaction
+	txRepo.On("GetTransactionByIdempotencyKey", ctx, 1, "key-1").Return(nil, errors.New("not found"))
+	txRepo.On("CreateTransaction", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(models.PaymentTransaction)
+		stored.ID = 3
+	}).Return(3, nil)
+	txRepo.On("SetTransactionReference", ctx, 3, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored.Reference = args.String(2)
+		stored.RedirectURL = args.String(3)
+	}).Return(nil)
+
+	transaction, err := service.Initiate(ctx, 1, []int{4, 5}, amounts, "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionInitiated, transaction.Status)
+	assert.Equal(t, models.NewMoney(150000, "IRR"), stored.Amount)
+	assert.Equal(t, amounts, stored.PaymentAmounts)
+	assert.True(t, strings.HasPrefix(transaction.RedirectURL, "http://localhost:8080/api/v1/payment/callback/fake?"))
+
+	//following the redirect the way the resident's browser would
+	redirect, err := url.Parse(transaction.RedirectURL)
+	assert.NoError(t, err)
+
+	txRepo.On("GetTransactionByReference", ctx, FakeProviderName, stored.Reference).Return(&stored, nil)
+	txRepo.On("UpdateTransactionStatus", ctx, mock.MatchedBy(func(tx models.PaymentTransaction) bool {
+		return tx.ID == 3 && tx.Status == models.TransactionSucceeded && tx.TrackingCode != ""
+	})).Return(nil).Once()
+
+	verified, settled, err := service.VerifyCallback(ctx, FakeProviderName, redirect.Query())
+	assert.NoError(t, err)
+	assert.True(t, settled)
+	assert.Equal(t, models.TransactionSucceeded, verified.Status)
+	assert.Equal(t, []int{4, 5}, verified.PaymentIDs)
+
+	txRepo.On("GetTransactionByID", ctx, 3).Return(verified, nil)
+	txRepo.On("UpdateTransactionStatus", ctx, mock.MatchedBy(func(tx models.PaymentTransaction) bool {
+		return tx.Status == models.TransactionPartiallyRefunded
+	})).Return(nil).Once()
+
+	refunded, err := service.Refund(ctx, 3, models.NewMoney(50000, "IRR"))
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(50000, "IRR"), refunded.RefundedAmount)
+
+	_, err = service.Refund(ctx, 3, models.NewMoney(100001, "IRR"))
+	assert.Error(t, err)
+	txRepo.AssertExpectations(t)
+}
+
+func TestInitiateReusesIdempotentTransaction(t *testing.T) {
+	ctx := context.Background()
+	txRepo := new(repositories.MockPaymentTransactionRepository)
+	service, _ := newTestPayment(txRepo)
+
+	existing := &models.PaymentTransaction{BaseModel: models.BaseModel{ID: 3}, RedirectURL: "http://pay"}
+	txRepo.On("GetTransactionByIdempotencyKey", ctx, 1, "key-1").Return(existing, nil)
+
+	transaction, err := service.Initiate(ctx, 1, []int{4}, []models.Money{models.NewMoney(100, "IRR")}, "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, existing, transaction)
+	txRepo.AssertNotCalled(t, "CreateTransaction", mock.Anything, mock.Anything)
+}
+
+func TestPaidByProvider(t *testing.T) {
+	ctx := context.Background()
+	txRepo := new(repositories.MockPaymentTransactionRepository)
+	service, _ := newTestPayment(txRepo)
+
+	txRepo.On("GetPaidTransactionByPayment", ctx, 4).Return(&models.PaymentTransaction{BaseModel: models.BaseModel{ID: 3}}, nil)
+	txRepo.On("GetPaidTransactionByPayment", ctx, 5).Return(nil, sql.ErrNoRows)
+	txRepo.On("GetPaidTransactionByPayment", ctx, 6).Return(nil, errors.New("connection refused"))
+
+	paid, err := service.PaidByProvider(ctx, 4)
+	assert.NoError(t, err)
+	assert.True(t, paid)
+
+	//paid from credit
+	paid, err = service.PaidByProvider(ctx, 5)
+	assert.NoError(t, err)
+	assert.False(t, paid)
+
+	_, err = service.PaidByProvider(ctx, 6)
+	assert.Error(t, err)
+}
+
+func TestVerifyCallback(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		params         func(*FakeProvider, string) url.Values
+		current        models.TransactionStatus
+		expectedStatus models.TransactionStatus
+		expectUpdate   bool
+		expectedError  string
+	}{
+		{
+			name:           "declined payment",
+			params:         func(p *FakeProvider, ref string) url.Values { return p.CallbackParams(ref, false) },
+			current:        models.TransactionInitiated,
+			expectedStatus: models.TransactionFailed,
+			expectUpdate:   true,
+		},
+		{
+			name:           "repeated callback does not change the transaction",
+			params:         func(p *FakeProvider, ref string) url.Values { return p.CallbackParams(ref, true) },
+			current:        models.TransactionSucceeded,
+			expectedStatus: models.TransactionSucceeded,
+		},
+		{
+			name:           "callback after the transaction expired does not settle it",
+			params:         func(p *FakeProvider, ref string) url.Values { return p.CallbackParams(ref, true) },
+			current:        models.TransactionExpired,
+			expectedStatus: models.TransactionExpired,
+		},
+		{
+			name: "tampered signature",
+			params: func(p *FakeProvider, ref string) url.Values {
+				params := p.CallbackParams(ref, false)
+				params.Set("status", "OK")
+				return params
+			},
+			expectedError: "invalid callback signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txRepo := new(repositories.MockPaymentTransactionRepository)
+			service, provider := newTestPayment(txRepo)
+
+			result, err := provider.Initiate(ctx, InitiateRequest{
+				TransactionID: 3,
+				Amount:        models.NewMoney(100, "IRR"),
+				CallbackURL:   "http://localhost/callback",
+			})
+			assert.NoError(t, err)
+
+			txRepo.On("GetTransactionByReference", ctx, FakeProviderName, result.Reference).
+				Return(&models.PaymentTransaction{BaseModel: models.BaseModel{ID: 3}, Status: tt.current}, nil)
+			if tt.expectUpdate {
+				txRepo.On("UpdateTransactionStatus", ctx, mock.Anything).Return(nil)
+			}
+
+			transaction, settled, err := service.VerifyCallback(ctx, FakeProviderName, tt.params(provider, result.Reference))
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, transaction.Status)
+			assert.Equal(t, tt.expectUpdate, settled)
+			if !tt.expectUpdate {
+				txRepo.AssertNotCalled(t, "UpdateTransactionStatus", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestStaleTransactions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("an hour when the ttl is unset", func(t *testing.T) {
+		txRepo := new(repositories.MockPaymentTransactionRepository)
+		service, _ := newTestPayment(txRepo)
+		stale := []models.PaymentTransaction{{BaseModel: models.BaseModel{ID: 3}, Status: models.TransactionInitiated}}
+		txRepo.On("GetStaleTransactions", ctx, now.Add(-time.Hour)).Return(stale, nil)
+
+		transactions, err := service.StaleTransactions(ctx, now)
+		assert.NoError(t, err)
+		assert.Equal(t, stale, transactions)
+	})
+
+	t.Run("configured ttl", func(t *testing.T) {
+		txRepo := new(repositories.MockPaymentTransactionRepository)
+		service := NewPayment(config.Payment{TransactionTTL: 20 * time.Minute}, txRepo, NewFakeProvider("secret"))
+		txRepo.On("GetStaleTransactions", ctx, now.Add(-20*time.Minute)).Return(nil, nil)
+
+		transactions, err := service.StaleTransactions(ctx, now)
+		assert.NoError(t, err)
+		assert.Empty(t, transactions)
+	})
+}
@@ -0,0 +1,36 @@
+package payment
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+// Provider is a payment gateway. a payment starts with Initiate, the resident
+// is sent to the returned redirect url and the gateway sends them back to the
+// callback url, where VerifyCallback decides whether the money was taken
+type Provider interface {
+	Name() string
+	Initiate(ctx context.Context, req InitiateRequest) (*InitiateResult, error)
+	VerifyCallback(ctx context.Context, params url.Values) (*VerifyResult, error)
+	Refund(ctx context.Context, reference string, amount models.Money) error
+}
+
+type InitiateRequest struct {
+	TransactionID int
+	Amount        models.Money
+	Description   string
+	CallbackURL   string
+}
+
+type InitiateResult struct {
+	Reference   string
+	RedirectURL string
+}
+
+type VerifyResult struct {
+	Reference    string
+	Success      bool
+	TrackingCode string
+}
@@ -0,0 +1,153 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// a4 page laid out in a fixed width font, so columns line up by padding
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	margin       = 40
+	fontSize     = 9
+	lineHeight   = 12
+	lineChars    = (pageWidth - 2*margin) * 10 / (6 * fontSize) // courier glyphs are 0.6 em wide
+	linesPerPage = (pageHeight - 2*margin) / lineHeight
+	columnGap    = 2
+)
+
+// writes the table as a PDF document in the built-in Courier font. the
+// header row is repeated on every page. the standard fonts only cover
+// latin-1, so other characters come out as '?'
+func WritePDF(w io.Writer, table Table) error {
+	var pages [][]string
+	widths := columnWidths(table)
+	header := formatRow(table.Headers, widths)
+	rule := strings.Repeat("-", len(header))
+
+	page := []string{}
+	if table.Title != "" {
+		page = append(page, table.Title, "")
+	}
+	page = append(page, header, rule)
+	for _, row := range table.Rows {
+		if len(page) == linesPerPage {
+			pages = append(pages, page)
+			page = []string{header, rule}
+		}
+		page = append(page, formatRow(row, widths))
+	}
+	pages = append(pages, page)
+
+	return writeDocument(w, pages)
+}
+
+// the width of each column, its widest cell. when the table is wider than
+// the page the label column gives up the difference
+func columnWidths(table Table) []int {
+	widths := make([]int, len(table.Headers))
+	for _, row := range append([][]string{table.Headers}, table.Rows...) {
+		for i, cell := range row {
+			if i < len(widths) && len([]rune(cell)) > widths[i] {
+				widths[i] = len([]rune(cell))
+			}
+		}
+	}
+
+	total := 0
+	for _, width := range widths {
+		total += width + columnGap
+	}
+	if over := total - lineChars; over > 0 && len(widths) > 0 {
+		widths[0] -= over
+		if widths[0] < 1 {
+			widths[0] = 1
+		}
+	}
+	return widths
+}
+
+// the label is left aligned and cut to fit, the amounts right aligned
+func formatRow(row []string, widths []int) string {
+	var line strings.Builder
+	for i, width := range widths {
+		cell := ""
+		if i < len(row) {
+			cell = row[i]
+		}
+		if cellRunes := []rune(cell); len(cellRunes) > width {
+			cell = string(cellRunes[:width])
+		}
+		padding := strings.Repeat(" ", width-len([]rune(cell)))
+		if i == 0 {
+			line.WriteString(cell + padding)
+		} else {
+			line.WriteString(strings.Repeat(" ", columnGap) + padding + cell)
+		}
+	}
+	return strings.TrimRight(line.String(), " ")
+}
+
+// lays out the objects of the document: the catalog, the page tree, the
+// font, and a page and content stream for every page, followed by the
+// cross-reference table pointing at each of them
+func writeDocument(w io.Writer, pages [][]string) error {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, lines := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i))
+
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, lineHeight, margin, pageHeight-margin)
+		for _, line := range lines {
+			fmt.Fprintf(&content, "(%s) '\n", escapeText(line))
+		}
+		content.WriteString("ET")
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func escapeText(text string) string {
+	var escaped strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			escaped.WriteRune('\\')
+			escaped.WriteRune(r)
+		case r < 32 || r > 126:
+			escaped.WriteRune('?')
+		default:
+			escaped.WriteRune(r)
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,27 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// Table is a report laid out for export, a header row followed by data rows
+// of the same width. the first column is a label, the rest are amounts
+type Table struct {
+	Title   string
+	Headers []string
+	Rows    [][]string
+}
+
+// writes the header and rows as CSV. the title is left out so the output
+// loads straight into a spreadsheet
+func WriteCSV(w io.Writer, table Table) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(table.Headers); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(table.Rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTable = Table{
+	Title:   "Apartment 2 by month",
+	Headers: []string{"month", "billed", "collected", "outstanding"},
+	Rows: [][]string{
+		{"2025-01", "1500.00 IRR", "1500.00 IRR", "0.00 IRR"},
+		{"2025-02", "1800.00 IRR", "900.00 IRR", "900.00 IRR"},
+	},
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, Table{
+		Headers: testTable.Headers,
+		Rows:    append(testTable.Rows, []string{"water, gas", "1.00 IRR", "0.00 IRR", "1.00 IRR"}),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "month,billed,collected,outstanding\n"+
+		"2025-01,1500.00 IRR,1500.00 IRR,0.00 IRR\n"+
+		"2025-02,1800.00 IRR,900.00 IRR,900.00 IRR\n"+
+		"\"water, gas\",1.00 IRR,0.00 IRR,1.00 IRR\n", buf.String())
+}
+
+func TestWritePDF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WritePDF(&buf, testTable))
+	doc := buf.String()
+
+	assert.True(t, strings.HasPrefix(doc, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(doc, "%%EOF\n"))
+	assert.Contains(t, doc, "/Count 1")
+	assert.Contains(t, doc, "(Apartment 2 by month) '")
+	assert.Contains(t, doc, "(2025-02  1800.00 IRR   900.00 IRR   900.00 IRR) '")
+
+	//every cross-reference entry points at the start of its object
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(doc)
+	require.Len(t, startxref, 2)
+	xref, _ := strconv.Atoi(startxref[1])
+	require.True(t, strings.HasPrefix(doc[xref:], "xref\n"))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(doc[xref:], -1)
+	assert.Len(t, entries, 5)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		assert.True(t, strings.HasPrefix(doc[offset:], fmt.Sprintf("%d 0 obj", i+1)), "object %d", i+1)
+	}
+}
+
+func TestWritePDFPaginates(t *testing.T) {
+	table := Table{Headers: []string{"resident", "billed"}}
+	for i := 0; i < 100; i++ {
+		table.Rows = append(table.Rows, []string{fmt.Sprintf("user%d", i), "1.00 IRR"})
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePDF(&buf, table))
+
+	assert.Contains(t, buf.String(), "/Count 2")
+	assert.Equal(t, 2, strings.Count(buf.String(), "(resident    billed) '"))
+}
+
+func TestEscapeText(t *testing.T) {
+	assert.Equal(t, `gas \(north\) \\ ????`, escapeText(`gas (north) \ نداز`))
+}
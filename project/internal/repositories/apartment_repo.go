@@ -3,41 +3,25 @@ package repositories
 import (
 	"context"
 	"fmt"
-	"log"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 )
 
-const (
-	CREATE_APARTMENTS_TABLE = `CREATE TABLE IF NOT EXISTS apartments(
-		id SERIAL PRIMARY KEY,
-		apartment_name VARCHAR(100) NOT NULL,
-		address TEXT NOT NULL,
-		units_count INTEGER NOT NULL,
-		manager_id INTEGER REFERENCES users(id),
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-)
-
 type ApartmentRepository interface {
 	CreateApartment(ctx context.Context, apartment models.Apartment) (int, error)
 	GetApartmentByID(id int) (*models.Apartment, error)
 	UpdateApartment(ctx context.Context, apartment models.Apartment) error
-	DeleteApartment(id int) error
+	DeleteApartment(ctx context.Context, id int) error
+	UpdateDivisionPolicy(ctx context.Context, apartmentID int, policy models.DivisionPolicy) error
+	SetApartmentManager(ctx context.Context, apartmentID, managerID int) error
 }
 
 type apartmentRepositoryImpl struct {
 	db *sqlx.DB
 }
 
-func NewApartmentRepository(autoCreate bool, db *sqlx.DB) ApartmentRepository {
-	if autoCreate {
-		if _, err := db.Exec(CREATE_APARTMENTS_TABLE); err != nil {
-			log.Fatalf("failed to create apartments table: %v", err)
-		}
-	}
+func NewApartmentRepository(db *sqlx.DB) ApartmentRepository {
 	return &apartmentRepositoryImpl{db: db}
 }
 
@@ -46,7 +30,7 @@ func (r *apartmentRepositoryImpl) CreateApartment(ctx context.Context, apartment
 		VALUES ($1, $2, $3, $4)
 		RETURNING id`
 	var id int
-	err := r.db.QueryRowContext(ctx, query,
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
 		apartment.ApartmentName,
 		apartment.Address,
 		apartment.UnitsCount,
@@ -59,7 +43,7 @@ func (r *apartmentRepositoryImpl) CreateApartment(ctx context.Context, apartment
 
 func (r *apartmentRepositoryImpl) GetApartmentByID(id int) (*models.Apartment, error) {
 	var apartment models.Apartment
-	query := `SELECT id, apartment_name, address, units_count, manager_id, created_at, updated_at
+	query := `SELECT id, apartment_name, address, units_count, manager_id, division_policy, created_at, updated_at
 		FROM apartments WHERE id = $1`
 	err := r.db.Get(&apartment, query, id)
 	if err != nil {
@@ -68,22 +52,35 @@ func (r *apartmentRepositoryImpl) GetApartmentByID(id int) (*models.Apartment, e
 	return &apartment, nil
 }
 
+// updates the apartment's details. its manager only changes through
+// SetApartmentManager, and units_count can't go below the units it has
 func (r *apartmentRepositoryImpl) UpdateApartment(ctx context.Context, apartment models.Apartment) error {
 	query := `UPDATE apartments SET apartment_name = $1, address = $2,
-		units_count = $3, manager_id = $4, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $5`
-	_, err := r.db.ExecContext(ctx, query,
+		units_count = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND $3 >= (SELECT COUNT(*) FROM units WHERE apartment_id = $4)`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query,
 		apartment.ApartmentName,
 		apartment.Address,
 		apartment.UnitsCount,
-		apartment.ManagerID,
 		apartment.ID)
-	return err
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("no apartment found with id %d and at most %d units", apartment.ID, apartment.UnitsCount)
+	}
+	return nil
 }
 
-func (r *apartmentRepositoryImpl) DeleteApartment(id int) error {
+func (r *apartmentRepositoryImpl) DeleteApartment(ctx context.Context, id int) error {
 	query := `DELETE FROM apartments WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -99,3 +96,43 @@ func (r *apartmentRepositoryImpl) DeleteApartment(id int) error {
 
 	return nil
 }
+
+func (r *apartmentRepositoryImpl) UpdateDivisionPolicy(ctx context.Context, apartmentID int, policy models.DivisionPolicy) error {
+	query := `UPDATE apartments SET division_policy = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, policy, apartmentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("no apartment found with id %d", apartmentID)
+	}
+
+	return nil
+}
+
+func (r *apartmentRepositoryImpl) SetApartmentManager(ctx context.Context, apartmentID, managerID int) error {
+	query := `UPDATE apartments SET manager_id = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, managerID, apartmentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("no apartment found with id %d", apartmentID)
+	}
+
+	return nil
+}
@@ -26,7 +26,17 @@ func (m *MockApartmentRepo) UpdateApartment(ctx context.Context, apartment model
 	return args.Error(0)
 }
 
-func (m *MockApartmentRepo) DeleteApartment(id int) error {
-	args := m.Called(id)
+func (m *MockApartmentRepo) DeleteApartment(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockApartmentRepo) UpdateDivisionPolicy(ctx context.Context, apartmentID int, policy models.DivisionPolicy) error {
+	args := m.Called(ctx, apartmentID, policy)
+	return args.Error(0)
+}
+
+func (m *MockApartmentRepo) SetApartmentManager(ctx context.Context, apartmentID, managerID int) error {
+	args := m.Called(ctx, apartmentID, managerID)
 	return args.Error(0)
 }
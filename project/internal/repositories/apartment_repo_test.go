@@ -18,7 +18,7 @@ func TestApartmentRepository_CreateApartment(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewApartmentRepository(false, sqlxDB)
+	repo := NewApartmentRepository(sqlxDB)
 
 	apartment := models.Apartment{
 		ApartmentName: "Erfan Apartments",
@@ -55,25 +55,26 @@ func TestApartmentRepository_GetApartmentByID(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewApartmentRepository(false, sqlxDB)
+	repo := NewApartmentRepository(sqlxDB)
 
 	now := time.Now()
 
 	t.Run("success", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "apartment_name", "address", "units_count", "manager_id", "created_at", "updated_at"}).
-			AddRow(1, "Erfan Apartments", "123 Enghelab St", 10, 1, now, now)
+		rows := sqlmock.NewRows([]string{"id", "apartment_name", "address", "units_count", "manager_id", "division_policy", "created_at", "updated_at"}).
+			AddRow(1, "Erfan Apartments", "123 Enghelab St", 10, 1, "area", now, now)
 
-		mock.ExpectQuery(`SELECT id, apartment_name, address, units_count, manager_id, created_at, updated_at FROM apartments WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT id, apartment_name, address, units_count, manager_id, division_policy, created_at, updated_at FROM apartments WHERE id = \$1`).
 			WithArgs(1).
 			WillReturnRows(rows)
 
 		apartment, err := repo.GetApartmentByID(1)
 		assert.NoError(t, err)
 		assert.Equal(t, "Erfan Apartments", apartment.ApartmentName)
+		assert.Equal(t, models.AreaDivision, apartment.DivisionPolicy)
 	})
 
 	t.Run("not found", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT id, apartment_name, address, units_count, manager_id, created_at, updated_at FROM apartments WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT id, apartment_name, address, units_count, manager_id, division_policy, created_at, updated_at FROM apartments WHERE id = \$1`).
 			WithArgs(2).
 			WillReturnError(sql.ErrNoRows)
 
@@ -91,7 +92,7 @@ func TestApartmentRepository_UpdateApartment(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewApartmentRepository(false, sqlxDB)
+	repo := NewApartmentRepository(sqlxDB)
 
 	apartment := models.Apartment{
 		BaseModel: models.BaseModel{
@@ -105,7 +106,7 @@ func TestApartmentRepository_UpdateApartment(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
 		mock.ExpectExec(`UPDATE apartments SET`).
-			WithArgs(apartment.ApartmentName, apartment.Address, apartment.UnitsCount, apartment.ManagerID, apartment.ID).
+			WithArgs(apartment.ApartmentName, apartment.Address, apartment.UnitsCount, apartment.ID).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		err := repo.UpdateApartment(context.Background(), apartment)
@@ -114,13 +115,22 @@ func TestApartmentRepository_UpdateApartment(t *testing.T) {
 
 	t.Run("error", func(t *testing.T) {
 		mock.ExpectExec(`UPDATE apartments SET`).
-			WithArgs(apartment.ApartmentName, apartment.Address, apartment.UnitsCount, apartment.ManagerID, apartment.ID).
+			WithArgs(apartment.ApartmentName, apartment.Address, apartment.UnitsCount, apartment.ID).
 			WillReturnError(sql.ErrConnDone)
 
 		err := repo.UpdateApartment(context.Background(), apartment)
 		assert.Error(t, err)
 	})
 
+	t.Run("fewer units than it has", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE apartments SET (.+) WHERE id = \$4 AND \$3 >= \(SELECT COUNT\(\*\) FROM units`).
+			WithArgs(apartment.ApartmentName, apartment.Address, apartment.UnitsCount, apartment.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.UpdateApartment(context.Background(), apartment)
+		assert.Error(t, err)
+	})
+
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -130,14 +140,14 @@ func TestApartmentRepository_DeleteApartment(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewApartmentRepository(false, sqlxDB)
+	repo := NewApartmentRepository(sqlxDB)
 
 	t.Run("success", func(t *testing.T) {
 		mock.ExpectExec(`DELETE FROM apartments WHERE id = \$1`).
 			WithArgs(1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.DeleteApartment(1)
+		err := repo.DeleteApartment(context.Background(), 1)
 		assert.NoError(t, err)
 	})
 
@@ -146,7 +156,65 @@ func TestApartmentRepository_DeleteApartment(t *testing.T) {
 			WithArgs(2).
 			WillReturnError(sql.ErrConnDone)
 
-		err := repo.DeleteApartment(2)
+		err := repo.DeleteApartment(context.Background(), 2)
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApartmentRepository_UpdateDivisionPolicy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewApartmentRepository(sqlxDB)
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE apartments SET division_policy`).
+			WithArgs(models.OccupantsDivision, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.UpdateDivisionPolicy(context.Background(), 1, models.OccupantsDivision)
+		assert.NoError(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE apartments SET division_policy`).
+			WithArgs(models.OccupantsDivision, 2).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.UpdateDivisionPolicy(context.Background(), 2, models.OccupantsDivision)
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApartmentRepository_SetApartmentManager(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewApartmentRepository(sqlxDB)
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE apartments SET manager_id`).
+			WithArgs(3, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.SetApartmentManager(context.Background(), 1, 3)
+		assert.NoError(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE apartments SET manager_id`).
+			WithArgs(3, 2).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.SetApartmentManager(context.Background(), 2, 3)
 		assert.Error(t, err)
 	})
 
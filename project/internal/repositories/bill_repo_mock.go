@@ -31,11 +31,28 @@ func (m *MockBillRepository) UpdateBill(ctx context.Context, bill models.Bill) e
 	return args.Error(0)
 }
 
-func (m *MockBillRepository) DeleteBill(id int) {
-	m.Called(id)
+func (m *MockBillRepository) DeleteBill(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
 }
 
 func (m *MockBillRepository) GetPaymentByBillAndUser(billID, userID int) (*models.Payment, error) {
 	args := m.Called(billID, userID)
 	return args.Get(0).(*models.Payment), args.Error(1)
 }
+
+func (m *MockBillRepository) GetUndividedBillsByTypeAndApartment(apartmentID int, billType models.BillType) ([]models.Bill, error) {
+	args := m.Called(apartmentID, billType)
+	if bills, ok := args.Get(0).([]models.Bill); ok {
+		return bills, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBillRepository) GetUndividedBillsByApartment(apartmentID int) ([]models.Bill, error) {
+	args := m.Called(apartmentID)
+	if bills, ok := args.Get(0).([]models.Bill); ok {
+		return bills, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -22,48 +22,12 @@ func setupTestDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
 }
 
 func TestNewBillRepository(t *testing.T) {
-	tests := []struct {
-		name       string
-		autoCreate bool
-		setupMock  func(sqlmock.Sqlmock)
-		wantPanic  bool
-	}{
-		{
-			name:       "AutoCreate true - success",
-			autoCreate: true,
-			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec("CREATE TABLE IF NOT EXISTS bills").
-					WillReturnResult(sqlmock.NewResult(0, 0))
-			},
-			wantPanic: false,
-		},
-		{
-			name:       "AutoCreate false - no table creation",
-			autoCreate: false,
-			setupMock:  func(mock sqlmock.Sqlmock) {},
-			wantPanic:  false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			db, mock := setupTestDB(t)
-			defer db.Close()
-
-			tt.setupMock(mock)
-
-			if tt.wantPanic {
-				assert.Panics(t, func() {
-					NewBillRepository(tt.autoCreate, db)
-				})
-			} else {
-				repo := NewBillRepository(tt.autoCreate, db)
-				assert.NotNil(t, repo)
-			}
+	db, mock := setupTestDB(t)
+	defer db.Close()
 
-			assert.NoError(t, mock.ExpectationsWereMet())
-		})
-	}
+	repo := NewBillRepository(db)
+	assert.NotNil(t, repo)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestBillRepository_CreateBill_Basic(t *testing.T) {
@@ -73,7 +37,7 @@ func TestBillRepository_CreateBill_Basic(t *testing.T) {
 	bill := models.Bill{
 		ApartmentID:     1,
 		BillType:        models.WaterBill,
-		TotalAmount:     100.50,
+		TotalAmount:     models.NewMoney(10050, "IRR"),
 		DueDate:         "2024-01-15",
 		BillingDeadline: "2024-01-10",
 		Description:     "Water bill for January",
@@ -107,14 +71,14 @@ func TestBillRepository_GetBillByID(t *testing.T) {
 			id:   1,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
-					"id", "apartment_id", "bill_type", "total_amount", "due_date",
+					"id", "apartment_id", "bill_type", "total_amount.amount", "total_amount.currency", "due_date",
 					"billing_deadline", "description", "image_url", "created_at", "updated_at",
 				}).AddRow(
-					1, 1, "water", 100.50, "2024-01-15",
+					1, 1, "water", 10050, "IRR", "2024-01-15",
 					"2024-01-10", "Water bill", "https://example.com/bill.jpg",
 					time.Now(), time.Now(),
 				)
-				mock.ExpectQuery(`SELECT id, apartment_id, bill_type, total_amount, due_date, billing_deadline, description, image_url, created_at, updated_at FROM bills WHERE id = \$1`).
+				mock.ExpectQuery(`SELECT id, apartment_id, bill_type, total_amount AS "total_amount.amount", currency AS "total_amount.currency", due_date, billing_deadline, description, image_url, created_at, updated_at FROM bills WHERE id = \$1`).
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -124,7 +88,7 @@ func TestBillRepository_GetBillByID(t *testing.T) {
 				},
 				ApartmentID:     1,
 				BillType:        models.WaterBill,
-				TotalAmount:     100.50,
+				TotalAmount:     models.NewMoney(10050, "IRR"),
 				DueDate:         "2024-01-15",
 				BillingDeadline: "2024-01-10",
 				Description:     "Water bill",
@@ -136,7 +100,7 @@ func TestBillRepository_GetBillByID(t *testing.T) {
 			name: "Bill not found",
 			id:   999,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, apartment_id, bill_type, total_amount, due_date, billing_deadline, description, image_url, created_at, updated_at FROM bills WHERE id = \$1`).
+				mock.ExpectQuery(`SELECT id, apartment_id, bill_type, total_amount AS "total_amount.amount", currency AS "total_amount.currency", due_date, billing_deadline, description, image_url, created_at, updated_at FROM bills WHERE id = \$1`).
 					WithArgs(999).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -186,13 +150,13 @@ func TestBillRepository_GetBillsByApartmentID(t *testing.T) {
 			apartmentID: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
-					"id", "apartment_id", "bill_type", "total_amount", "due_date",
+					"id", "apartment_id", "bill_type", "total_amount.amount", "total_amount.currency", "due_date",
 					"billing_deadline", "description", "image_url", "created_at", "updated_at",
 				}).
-					AddRow(1, 1, "water", 100.50, "2024-01-15", "2024-01-10", "Water bill", "url1", time.Now(), time.Now()).
-					AddRow(2, 1, "electricity", 75.25, "2024-01-20", "2024-01-15", "Electricity bill", "url2", time.Now(), time.Now())
+					AddRow(1, 1, "water", 10050, "IRR", "2024-01-15", "2024-01-10", "Water bill", "url1", time.Now(), time.Now()).
+					AddRow(2, 1, "electricity", 7525, "IRR", "2024-01-20", "2024-01-15", "Electricity bill", "url2", time.Now(), time.Now())
 
-				mock.ExpectQuery(`SELECT id, apartment_id, bill_type, total_amount, due_date, billing_deadline, description, image_url, created_at, updated_at FROM bills WHERE apartment_id = \$1`).
+				mock.ExpectQuery(`SELECT id, apartment_id, bill_type, total_amount AS "total_amount.amount", currency AS "total_amount.currency", due_date, billing_deadline, description, image_url, created_at, updated_at FROM bills WHERE apartment_id = \$1`).
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
@@ -201,7 +165,7 @@ func TestBillRepository_GetBillsByApartmentID(t *testing.T) {
 					BaseModel:       models.BaseModel{ID: 1},
 					ApartmentID:     1,
 					BillType:        models.WaterBill,
-					TotalAmount:     100.50,
+					TotalAmount:     models.NewMoney(10050, "IRR"),
 					DueDate:         "2024-01-15",
 					BillingDeadline: "2024-01-10",
 					Description:     "Water bill",
@@ -211,7 +175,7 @@ func TestBillRepository_GetBillsByApartmentID(t *testing.T) {
 					BaseModel:       models.BaseModel{ID: 2},
 					ApartmentID:     1,
 					BillType:        models.ElectricityBill,
-					TotalAmount:     75.25,
+					TotalAmount:     models.NewMoney(7525, "IRR"),
 					DueDate:         "2024-01-20",
 					BillingDeadline: "2024-01-15",
 					Description:     "Electricity bill",
@@ -224,10 +188,10 @@ func TestBillRepository_GetBillsByApartmentID(t *testing.T) {
 			name:        "No bills found",
 			apartmentID: 999,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, apartment_id, bill_type, total_amount, due_date, billing_deadline, description, image_url, created_at, updated_at FROM bills WHERE apartment_id = \$1`).
+				mock.ExpectQuery(`SELECT id, apartment_id, bill_type, total_amount AS "total_amount.amount", currency AS "total_amount.currency", due_date, billing_deadline, description, image_url, created_at, updated_at FROM bills WHERE apartment_id = \$1`).
 					WithArgs(999).
 					WillReturnRows(sqlmock.NewRows([]string{
-						"id", "apartment_id", "bill_type", "total_amount", "due_date",
+						"id", "apartment_id", "bill_type", "total_amount.amount", "total_amount.currency", "due_date",
 						"billing_deadline", "description", "image_url", "created_at", "updated_at",
 					}))
 			},
@@ -238,7 +202,7 @@ func TestBillRepository_GetBillsByApartmentID(t *testing.T) {
 			name:        "Database error",
 			apartmentID: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, apartment_id, bill_type, total_amount, due_date, billing_deadline, description, image_url, created_at, updated_at FROM bills WHERE apartment_id = \$1`).
+				mock.ExpectQuery(`SELECT id, apartment_id, bill_type, total_amount AS "total_amount.amount", currency AS "total_amount.currency", due_date, billing_deadline, description, image_url, created_at, updated_at FROM bills WHERE apartment_id = \$1`).
 					WithArgs(1).
 					WillReturnError(sql.ErrConnDone)
 			},
@@ -289,7 +253,7 @@ func TestBillRepository_UpdateBill(t *testing.T) {
 				BaseModel:       models.BaseModel{ID: 1},
 				ApartmentID:     1,
 				BillType:        models.WaterBill,
-				TotalAmount:     150.75,
+				TotalAmount:     models.NewMoney(15075, "IRR"),
 				DueDate:         "2024-01-20",
 				BillingDeadline: "2024-01-15",
 				Description:     "Updated water bill",
@@ -373,7 +337,7 @@ func TestBillRepository_DeleteBill(t *testing.T) {
 			repo := &billRepositoryImpl{db: db}
 
 			// DeleteBill doesn't return an error, so we just call it
-			repo.DeleteBill(tt.id)
+			repo.DeleteBill(context.Background(), tt.id)
 
 			assert.NoError(t, mock.ExpectationsWereMet())
 		})
@@ -395,11 +359,11 @@ func TestBillRepository_GetPaymentByBillAndUser(t *testing.T) {
 			userID: 1,
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
-					"id", "bill_id", "user_id", "amount", "paid_at", "payment_status", "created_at", "updated_at",
+					"id", "bill_id", "user_id", "amount.amount", "amount.currency", "paid_at", "payment_status", "created_at", "updated_at",
 				}).AddRow(
-					1, 1, 1, 100.50, time.Now(), "completed", time.Now(), time.Now(),
+					1, 1, 1, 10050, "IRR", time.Now(), "completed", time.Now(), time.Now(),
 				)
-				mock.ExpectQuery(`SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE bill_id = \$1 AND user_id = \$2`).
+				mock.ExpectQuery(`SELECT id, bill_id, user_id, amount AS "amount.amount", currency AS "amount.currency", paid_at, payment_status, created_at, updated_at FROM payments WHERE bill_id = \$1 AND user_id = \$2`).
 					WithArgs(1, 1).
 					WillReturnRows(rows)
 			},
@@ -407,7 +371,7 @@ func TestBillRepository_GetPaymentByBillAndUser(t *testing.T) {
 				BaseModel: models.BaseModel{ID: 1},
 				BillID:    1,
 				UserID:    1,
-				Amount:    "100.50",
+				Amount:    models.NewMoney(10050, "IRR"),
 			},
 			wantErr: false,
 		},
@@ -416,7 +380,7 @@ func TestBillRepository_GetPaymentByBillAndUser(t *testing.T) {
 			billID: 1,
 			userID: 999,
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(`SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE bill_id = \$1 AND user_id = \$2`).
+				mock.ExpectQuery(`SELECT id, bill_id, user_id, amount AS "amount.amount", currency AS "amount.currency", paid_at, payment_status, created_at, updated_at FROM payments WHERE bill_id = \$1 AND user_id = \$2`).
 					WithArgs(1, 999).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+const (
+	billTemplateColumns = `id, apartment_id, bill_type, amount AS "amount.amount", currency AS "amount.currency",
+		amount_formula, cadence, due_day_offset, description, auto_divide, active, next_run_at, created_at, updated_at`
+)
+
+type BillTemplateRepository interface {
+	CreateBillTemplate(ctx context.Context, template models.BillTemplate) (int, error)
+	GetBillTemplateByID(id int) (*models.BillTemplate, error)
+	GetBillTemplatesByApartment(apartmentID int) ([]models.BillTemplate, error)
+	GetDueBillTemplates(ctx context.Context, now time.Time) ([]models.BillTemplate, error)
+	AdvanceBillTemplate(ctx context.Context, id int, from, to time.Time) (bool, error)
+	DeleteBillTemplate(id int) error
+}
+
+type billTemplateRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewBillTemplateRepository(db *sqlx.DB) BillTemplateRepository {
+	return &billTemplateRepositoryImpl{db: db}
+}
+
+func (r *billTemplateRepositoryImpl) CreateBillTemplate(ctx context.Context, template models.BillTemplate) (int, error) {
+	query := `INSERT INTO bill_templates (apartment_id, bill_type, amount, currency, amount_formula, cadence,
+				due_day_offset, description, auto_divide, active, next_run_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	var id int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		template.ApartmentID,
+		template.BillType,
+		template.Amount.Amount,
+		template.Amount.Currency,
+		template.AmountFormula,
+		template.Cadence,
+		template.DueDayOffset,
+		template.Description,
+		template.AutoDivide,
+		template.Active,
+		template.NextRunAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *billTemplateRepositoryImpl) GetBillTemplateByID(id int) (*models.BillTemplate, error) {
+	var template models.BillTemplate
+	query := `SELECT ` + billTemplateColumns + ` FROM bill_templates WHERE id = $1`
+	err := r.db.Get(&template, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *billTemplateRepositoryImpl) GetBillTemplatesByApartment(apartmentID int) ([]models.BillTemplate, error) {
+	var templates []models.BillTemplate
+	query := `SELECT ` + billTemplateColumns + ` FROM bill_templates WHERE apartment_id = $1 ORDER BY id`
+	err := r.db.Select(&templates, query, apartmentID)
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// active templates with at least one period that has started but was not generated yet
+func (r *billTemplateRepositoryImpl) GetDueBillTemplates(ctx context.Context, now time.Time) ([]models.BillTemplate, error) {
+	var templates []models.BillTemplate
+	query := `SELECT ` + billTemplateColumns + ` FROM bill_templates
+			  WHERE active = TRUE AND next_run_at <= $1 ORDER BY next_run_at`
+	err := conn(ctx, r.db).SelectContext(ctx, &templates, query, now)
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// moves next_run_at from one period to another. it only succeeds while the
+// template still points at from, so when several instances run the scheduler
+// each period is claimed by exactly one of them
+func (r *billTemplateRepositoryImpl) AdvanceBillTemplate(ctx context.Context, id int, from, to time.Time) (bool, error) {
+	query := `UPDATE bill_templates SET next_run_at = $1, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $2 AND next_run_at = $3`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, to, id, from)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (r *billTemplateRepositoryImpl) DeleteBillTemplate(id int) error {
+	query := `DELETE FROM bill_templates WHERE id = $1`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("no bill template found with id %d", id)
+	}
+	return nil
+}
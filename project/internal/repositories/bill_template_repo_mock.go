@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBillTemplateRepository struct {
+	mock.Mock
+}
+
+func (m *MockBillTemplateRepository) CreateBillTemplate(ctx context.Context, template models.BillTemplate) (int, error) {
+	args := m.Called(ctx, template)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBillTemplateRepository) GetBillTemplateByID(id int) (*models.BillTemplate, error) {
+	args := m.Called(id)
+	if template, ok := args.Get(0).(*models.BillTemplate); ok {
+		return template, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBillTemplateRepository) GetBillTemplatesByApartment(apartmentID int) ([]models.BillTemplate, error) {
+	args := m.Called(apartmentID)
+	if templates, ok := args.Get(0).([]models.BillTemplate); ok {
+		return templates, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBillTemplateRepository) GetDueBillTemplates(ctx context.Context, now time.Time) ([]models.BillTemplate, error) {
+	args := m.Called(ctx, now)
+	if templates, ok := args.Get(0).([]models.BillTemplate); ok {
+		return templates, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBillTemplateRepository) AdvanceBillTemplate(ctx context.Context, id int, from, to time.Time) (bool, error) {
+	args := m.Called(ctx, id, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockBillTemplateRepository) DeleteBillTemplate(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var billTemplateRowColumns = []string{
+	"id", "apartment_id", "bill_type", "amount.amount", "amount.currency", "amount_formula", "cadence",
+	"due_day_offset", "description", "auto_divide", "active", "next_run_at", "created_at", "updated_at",
+}
+
+func TestNewBillTemplateRepository(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewBillTemplateRepository(db)
+	assert.NotNil(t, repo)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBillTemplateRepository_CreateBillTemplate(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	nextRunAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	template := models.BillTemplate{
+		ApartmentID:   1,
+		BillType:      models.MaintenanceBill,
+		Amount:        models.NewMoney(500000, "IRR"),
+		AmountFormula: models.FixedAmount,
+		Cadence:       models.Monthly,
+		DueDayOffset:  10,
+		Description:   "Building maintenance",
+		AutoDivide:    true,
+		Active:        true,
+		NextRunAt:     nextRunAt,
+	}
+
+	mock.ExpectQuery("INSERT INTO bill_templates").
+		WithArgs(1, models.MaintenanceBill, int64(500000), "IRR", models.FixedAmount, models.Monthly,
+			10, "Building maintenance", true, true, nextRunAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	repo := &billTemplateRepositoryImpl{db: db}
+	id, err := repo.CreateBillTemplate(context.Background(), template)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBillTemplateRepository_GetDueBillTemplates(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)
+	nextRunAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows(billTemplateRowColumns).
+		AddRow(1, 1, "water", 120000, "IRR", "per_unit", "monthly", 5, "Water", false, true, nextRunAt, now, now)
+	mock.ExpectQuery(`SELECT (.+) FROM bill_templates WHERE active = TRUE AND next_run_at <= \$1`).
+		WithArgs(now).
+		WillReturnRows(rows)
+
+	repo := &billTemplateRepositoryImpl{db: db}
+	templates, err := repo.GetDueBillTemplates(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Len(t, templates, 1)
+	assert.Equal(t, models.NewMoney(120000, "IRR"), templates[0].Amount)
+	assert.Equal(t, models.PerUnitAmount, templates[0].AmountFormula)
+	assert.Equal(t, nextRunAt, templates[0].NextRunAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBillTemplateRepository_AdvanceBillTemplate(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		setupMock   func(sqlmock.Sqlmock)
+		wantClaimed bool
+		wantErr     bool
+	}{
+		{
+			name: "claimed",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE bill_templates SET next_run_at = \$1`).
+					WithArgs(to, 1, from).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantClaimed: true,
+		},
+		{
+			name: "already claimed by another instance",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE bill_templates SET next_run_at = \$1`).
+					WithArgs(to, 1, from).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantClaimed: false,
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE bill_templates SET next_run_at = \$1`).
+					WithArgs(to, 1, from).
+					WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupTestDB(t)
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			repo := &billTemplateRepositoryImpl{db: db}
+			claimed, err := repo.AdvanceBillTemplate(context.Background(), 1, from, to)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantClaimed, claimed)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBillTemplateRepository_DeleteBillTemplate(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM bill_templates WHERE id = \$1`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &billTemplateRepositoryImpl{db: db}
+	err := repo.DeleteBillTemplate(7)
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+var ErrHandoverNotPending = errors.New("handover is no longer pending")
+
+type HandoverRepository interface {
+	CreateHandover(ctx context.Context, handover models.ManagerHandover) (int, error)
+	GetPendingHandover(ctx context.Context, apartmentID int) (*models.ManagerHandover, error)
+	CloseHandover(ctx context.Context, id int, status models.HandoverStatus) error
+}
+
+type handoverRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewHandoverRepository(db *sqlx.DB) HandoverRepository {
+	return &handoverRepositoryImpl{db: db}
+}
+
+func (r *handoverRepositoryImpl) CreateHandover(ctx context.Context, handover models.ManagerHandover) (int, error) {
+	query := `INSERT INTO manager_handovers (apartment_id, from_user_id, to_user_id, stay_as_manager, status)
+			  VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	var id int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		handover.ApartmentID,
+		handover.FromUserID,
+		handover.ToUserID,
+		handover.StayAsManager,
+		models.HandoverPending,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// the apartment's open offer, sql.ErrNoRows when there is none
+func (r *handoverRepositoryImpl) GetPendingHandover(ctx context.Context, apartmentID int) (*models.ManagerHandover, error) {
+	var handover models.ManagerHandover
+	query := `SELECT id, apartment_id, from_user_id, to_user_id, stay_as_manager, status, responded_at, created_at, updated_at
+			  FROM manager_handovers WHERE apartment_id = $1 AND status = 'pending'`
+	err := conn(ctx, r.db).GetContext(ctx, &handover, query, apartmentID)
+	if err != nil {
+		return nil, err
+	}
+	return &handover, nil
+}
+
+// moves a pending handover to its final status. ErrHandoverNotPending when
+// it was closed in the meantime
+func (r *handoverRepositoryImpl) CloseHandover(ctx context.Context, id int, status models.HandoverStatus) error {
+	query := `UPDATE manager_handovers SET status = $1, responded_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $2 AND status = 'pending'`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, status, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrHandoverNotPending
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockHandoverRepository struct {
+	mock.Mock
+}
+
+func (m *MockHandoverRepository) CreateHandover(ctx context.Context, handover models.ManagerHandover) (int, error) {
+	args := m.Called(ctx, handover)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockHandoverRepository) GetPendingHandover(ctx context.Context, apartmentID int) (*models.ManagerHandover, error) {
+	args := m.Called(ctx, apartmentID)
+	if handover, ok := args.Get(0).(*models.ManagerHandover); ok {
+		return handover, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockHandoverRepository) CloseHandover(ctx context.Context, id int, status models.HandoverStatus) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
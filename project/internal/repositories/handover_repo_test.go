@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandoverRepository_CreateHandover(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO manager_handovers`).
+		WithArgs(1, 2, 3, true, models.HandoverPending).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	repo := NewHandoverRepository(db)
+	id, err := repo.CreateHandover(context.Background(), models.ManagerHandover{
+		ApartmentID:   1,
+		FromUserID:    2,
+		ToUserID:      3,
+		StayAsManager: true,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandoverRepository_GetPendingHandover(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+	repo := NewHandoverRepository(db)
+
+	columns := []string{"id", "apartment_id", "from_user_id", "to_user_id", "stay_as_manager", "status", "responded_at", "created_at", "updated_at"}
+	mock.ExpectQuery(`SELECT (.+) FROM manager_handovers WHERE apartment_id = \$1 AND status = 'pending'`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, 1, 2, 3, false, "pending", nil, time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT (.+) FROM manager_handovers`).
+		WithArgs(2).
+		WillReturnError(sql.ErrNoRows)
+
+	handover, err := repo.GetPendingHandover(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, handover.ToUserID)
+	assert.Equal(t, models.HandoverPending, handover.Status)
+	assert.Nil(t, handover.RespondedAt)
+
+	_, err = repo.GetPendingHandover(context.Background(), 2)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandoverRepository_CloseHandover(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+	repo := NewHandoverRepository(db)
+
+	mock.ExpectExec(`UPDATE manager_handovers SET status = \$1(.+)WHERE id = \$2 AND status = 'pending'`).
+		WithArgs(models.HandoverAccepted, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE manager_handovers`).
+		WithArgs(models.HandoverDeclined, 5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.CloseHandover(context.Background(), 5, models.HandoverAccepted))
+	assert.ErrorIs(t, repo.CloseHandover(context.Background(), 5, models.HandoverDeclined), ErrHandoverNotPending)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
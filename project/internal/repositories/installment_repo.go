@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+type InstallmentRepository interface {
+	ReplaceInstallmentPlan(ctx context.Context, paymentID int, installments []models.Installment) error
+	GetInstallmentsByPayment(paymentID int) ([]models.Installment, error)
+	DeleteInstallmentPlan(paymentID int) error
+}
+
+type installmentRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewInstallmentRepository(db *sqlx.DB) InstallmentRepository {
+	return &installmentRepositoryImpl{db: db}
+}
+
+// replaces the payment's plan with the given installments in one transaction
+func (r *installmentRepositoryImpl) ReplaceInstallmentPlan(ctx context.Context, paymentID int, installments []models.Installment) (err error) {
+	tx, finish, err := beginTx(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM installments WHERE payment_id = $1`, paymentID)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO installments (payment_id, sequence, amount, currency, due_date)
+			  VALUES ($1, $2, $3, $4, $5)`
+	for _, installment := range installments {
+		_, err = tx.ExecContext(ctx, query,
+			paymentID,
+			installment.Sequence,
+			installment.Amount.Amount,
+			installment.Amount.Currency,
+			installment.DueDate,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *installmentRepositoryImpl) GetInstallmentsByPayment(paymentID int) ([]models.Installment, error) {
+	var installments []models.Installment
+	query := `SELECT id, payment_id, sequence, amount AS "amount.amount", currency AS "amount.currency",
+			  TO_CHAR(due_date, 'YYYY-MM-DD') AS due_date, created_at
+			  FROM installments WHERE payment_id = $1 ORDER BY sequence`
+	err := r.db.Select(&installments, query, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	return installments, nil
+}
+
+func (r *installmentRepositoryImpl) DeleteInstallmentPlan(paymentID int) error {
+	query := `DELETE FROM installments WHERE payment_id = $1`
+	_, err := r.db.Exec(query, paymentID)
+	return err
+}
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInstallmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockInstallmentRepository) ReplaceInstallmentPlan(ctx context.Context, paymentID int, installments []models.Installment) error {
+	args := m.Called(ctx, paymentID, installments)
+	return args.Error(0)
+}
+
+func (m *MockInstallmentRepository) GetInstallmentsByPayment(paymentID int) ([]models.Installment, error) {
+	args := m.Called(paymentID)
+	if installments, ok := args.Get(0).([]models.Installment); ok {
+		return installments, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockInstallmentRepository) DeleteInstallmentPlan(paymentID int) error {
+	args := m.Called(paymentID)
+	return args.Error(0)
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInstallmentRepository(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewInstallmentRepository(db)
+	assert.NotNil(t, repo)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInstallmentRepository_ReplaceInstallmentPlan(t *testing.T) {
+	installments := []models.Installment{
+		{Sequence: 1, Amount: models.NewMoney(6000, "IRR"), DueDate: "2025-01-10"},
+		{Sequence: 2, Amount: models.NewMoney(4000, "IRR"), DueDate: "2025-02-10"},
+	}
+
+	t.Run("replaces the plan", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM installments WHERE payment_id = \$1`).
+			WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO installments").
+			WithArgs(5, 1, int64(6000), "IRR", "2025-01-10").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO installments").
+			WithArgs(5, 2, int64(4000), "IRR", "2025-02-10").
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		repo := &installmentRepositoryImpl{db: db}
+		err := repo.ReplaceInstallmentPlan(context.Background(), 5, installments)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back on error", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM installments WHERE payment_id = \$1`).
+			WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO installments").
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		repo := &installmentRepositoryImpl{db: db}
+		err := repo.ReplaceInstallmentPlan(context.Background(), 5, installments)
+
+		assert.Equal(t, sql.ErrConnDone, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInstallmentRepository_GetInstallmentsByPayment(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "payment_id", "sequence", "amount.amount", "amount.currency", "due_date", "created_at"}).
+		AddRow(1, 5, 1, 6000, "IRR", "2025-01-10", now).
+		AddRow(2, 5, 2, 4000, "IRR", "2025-02-10", now)
+	mock.ExpectQuery(`SELECT (.+) FROM installments WHERE payment_id = \$1 ORDER BY sequence`).
+		WithArgs(5).
+		WillReturnRows(rows)
+
+	repo := &installmentRepositoryImpl{db: db}
+	installments, err := repo.GetInstallmentsByPayment(5)
+
+	assert.NoError(t, err)
+	assert.Len(t, installments, 2)
+	assert.Equal(t, models.NewMoney(4000, "IRR"), installments[1].Amount)
+	assert.Equal(t, "2025-02-10", installments[1].DueDate)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
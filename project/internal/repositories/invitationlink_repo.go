@@ -2,73 +2,178 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
 	"errors"
-	"fmt"
 	"time"
 
-	goredis "github.com/redis/go-redis/v9"
-	"github.com/speps/go-hashids/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 )
 
+var (
+	ErrInvitationNotOpen = errors.New("invitation is no longer open")
+	ErrInvitationExists  = errors.New("the user already has an open invitation to this apartment")
+)
+
+// open invitations past their expiry are reported as expired without being
+// updated, so nothing has to sweep them
+const selectInvitation = `SELECT i.id, i.sender_id, s.username AS sender_username,
+			  COALESCE(i.receiver_id, 0) AS receiver_id, i.receiver_email, i.receiver_phone,
+			  COALESCE(r.telegram_user, '') AS receiver_username, COALESCE(r.telegram_chat_id, 0) AS receiver_chat_id,
+			  i.apartment_id, a.apartment_name, i.token, i.expires_at,
+			  CASE WHEN i.status IN ('pending', 'notified') AND i.expires_at <= CURRENT_TIMESTAMP THEN 'expired' ELSE i.status END AS status,
+			  i.notification_sent_at, i.responded_at, i.created_at, i.updated_at
+			  FROM invitation_links i
+			  JOIN users s ON s.id = i.sender_id
+			  LEFT JOIN users r ON r.id = i.receiver_id
+			  JOIN apartments a ON a.id = i.apartment_id`
+
 type InviteLinkRepo interface {
-	CreateInvitation(ctx context.Context, userID, apartmentID, managerID int) (string, error)
-	ValidateAndConsumeInvitation(ctx context.Context, code string) (int, error)
+	CreateInvitation(ctx context.Context, invitation models.InvitationLink) (int, error)
+	GetInvitationByID(ctx context.Context, id int) (*models.InvitationLink, error)
+	GetInvitationsByApartment(ctx context.Context, apartmentID int) ([]models.InvitationLink, error)
+	GetOpenInvitationsForUser(ctx context.Context, userID int) ([]models.InvitationLink, error)
+	MarkInvitationNotified(ctx context.Context, id int) error
+	RenewInvitation(ctx context.Context, id int, expiresAt time.Time) error
+	CloseInvitation(ctx context.Context, id int, status models.InvitationStatus) error
+	ValidateAndConsumeInvitation(ctx context.Context, token string, userID int) (int, error)
 }
 
 type invitationLinkRepository struct {
-	redisClient *goredis.Client
-	expiration  time.Duration
-	hashID      *hashids.HashID
+	db *sqlx.DB
 }
 
-func NewInvitationLinkRepository(redisClient *goredis.Client, salt string) InviteLinkRepo {
-	hd := hashids.NewData()
-	hd.Salt = salt
-	hd.MinLength = 8
-	hashID, _ := hashids.NewWithData(hd)
-
-	return &invitationLinkRepository{
-		redisClient: redisClient,
-		expiration:  24 * time.Hour,
-		hashID:      hashID,
-	}
+func NewInvitationLinkRepository(db *sqlx.DB) InviteLinkRepo {
+	return &invitationLinkRepository{db: db}
 }
 
-func (r *invitationLinkRepository) CreateInvitation(ctx context.Context, userID, apartmentID, managerID int) (string, error) {
-	code, err := r.hashID.Encode([]int{userID, apartmentID, managerID})
+// stores a pending invitation for a user, or for an email or phone number
+// when ReceiverID is 0. earlier open invitations of the receiver to the
+// apartment that expired are closed first, ErrInvitationExists when one is
+// still open
+func (r *invitationLinkRepository) CreateInvitation(ctx context.Context, invitation models.InvitationLink) (id int, err error) {
+	tx, finish, err := beginTx(ctx, r.db)
 	if err != nil {
-		return "", fmt.Errorf("failed to encode invitation: %w", err)
+		return 0, err
 	}
+	defer finish(&err)
 
-	key := r.redisKey(userID, apartmentID, managerID)
-	err = r.redisClient.Set(ctx, key, "1", r.expiration).Err()
+	_, err = tx.ExecContext(ctx, `UPDATE invitation_links SET status = 'expired', updated_at = CURRENT_TIMESTAMP
+			  WHERE apartment_id = $1 AND status IN ('pending', 'notified') AND expires_at <= CURRENT_TIMESTAMP
+			  AND (receiver_id = $2 OR (receiver_id IS NULL AND ((receiver_email <> '' AND receiver_email = $3) OR (receiver_phone <> '' AND receiver_phone = $4))))`,
+		invitation.ApartmentID, invitation.ReceiverID, invitation.ReceiverEmail, invitation.ReceiverPhone)
 	if err != nil {
-		return "", fmt.Errorf("failed to save invitation: %w", err)
+		return 0, err
 	}
 
-	return code, nil
+	query := `INSERT INTO invitation_links (sender_id, receiver_id, receiver_email, receiver_phone, apartment_id, token, status, expires_at)
+			  VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8) ON CONFLICT DO NOTHING RETURNING id`
+	err = tx.QueryRowContext(ctx, query,
+		invitation.SenderID,
+		invitation.ReceiverID,
+		invitation.ReceiverEmail,
+		invitation.ReceiverPhone,
+		invitation.ApartmentID,
+		invitation.Token,
+		models.InvitationStatusPending,
+		invitation.ExpiresAt,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvitationExists
+	}
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
 }
 
-func (r *invitationLinkRepository) ValidateAndConsumeInvitation(ctx context.Context, code string) (int, error) {
-	ids, err := r.hashID.DecodeWithError(code)
-	if err != nil || len(ids) != 3 {
-		return 0, errors.New("invalid or tampered code")
+func (r *invitationLinkRepository) GetInvitationByID(ctx context.Context, id int) (*models.InvitationLink, error) {
+	var invitation models.InvitationLink
+	err := conn(ctx, r.db).GetContext(ctx, &invitation, selectInvitation+` WHERE i.id = $1`, id)
+	if err != nil {
+		return nil, err
 	}
+	return &invitation, nil
+}
 
-	userID, apartmentID, managerID := ids[0], ids[1], ids[2]
-	key := r.redisKey(userID, apartmentID, managerID)
+// every invitation of the apartment, newest first
+func (r *invitationLinkRepository) GetInvitationsByApartment(ctx context.Context, apartmentID int) ([]models.InvitationLink, error) {
+	var invitations []models.InvitationLink
+	err := conn(ctx, r.db).SelectContext(ctx, &invitations, selectInvitation+` WHERE i.apartment_id = $1 ORDER BY i.created_at DESC, i.id DESC`, apartmentID)
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
 
-	deleted, err := r.redisClient.Del(ctx, key).Result()
+// the invitations sent to the user that they can still accept or reject,
+// newest first. those sent to an email or phone number aren't listed, users'
+// contact fields aren't verified and only the token proves the invitation
+// reached them
+func (r *invitationLinkRepository) GetOpenInvitationsForUser(ctx context.Context, userID int) ([]models.InvitationLink, error) {
+	var invitations []models.InvitationLink
+	query := selectInvitation + ` WHERE i.receiver_id = $1 AND i.status IN ('pending', 'notified') AND i.expires_at > CURRENT_TIMESTAMP
+			  ORDER BY i.created_at DESC, i.id DESC`
+	err := conn(ctx, r.db).SelectContext(ctx, &invitations, query, userID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to access Redis: %w", err)
+		return nil, err
 	}
-	if deleted == 0 {
+	return invitations, nil
+}
+
+// records that the receiver was told about an open invitation
+func (r *invitationLinkRepository) MarkInvitationNotified(ctx context.Context, id int) error {
+	query := `UPDATE invitation_links SET status = 'notified', notification_sent_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND status IN ('pending', 'notified')`
+	return r.updateOpen(ctx, query, id)
+}
+
+// moves the expiry of an open invitation, including one that expired but
+// wasn't replaced yet
+func (r *invitationLinkRepository) RenewInvitation(ctx context.Context, id int, expiresAt time.Time) error {
+	query := `UPDATE invitation_links SET expires_at = $2, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND status IN ('pending', 'notified')`
+	return r.updateOpen(ctx, query, id, expiresAt)
+}
+
+// moves an open invitation to its final status. ErrInvitationNotOpen when it
+// was closed in the meantime or has expired
+func (r *invitationLinkRepository) CloseInvitation(ctx context.Context, id int, status models.InvitationStatus) error {
+	query := `UPDATE invitation_links SET status = $2, responded_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND status IN ('pending', 'notified') AND expires_at > CURRENT_TIMESTAMP`
+	return r.updateOpen(ctx, query, id, status)
+}
+
+// accepts the open invitation with this token sent to the user, or to an
+// email or phone number, which the token was delivered to, and returns its
+// apartment
+func (r *invitationLinkRepository) ValidateAndConsumeInvitation(ctx context.Context, token string, userID int) (int, error) {
+	query := `UPDATE invitation_links i SET status = 'accepted', receiver_id = $1, responded_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE i.token = $2 AND (i.receiver_id = $1 OR i.receiver_id IS NULL) AND i.status IN ('pending', 'notified') AND i.expires_at > CURRENT_TIMESTAMP
+			  RETURNING i.apartment_id`
+	var apartmentID int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userID, token).Scan(&apartmentID)
+	if errors.Is(err, sql.ErrNoRows) {
 		return 0, errors.New("invitation not found or already used")
 	}
-
+	if err != nil {
+		return 0, err
+	}
 	return apartmentID, nil
 }
 
-func (r *invitationLinkRepository) redisKey(userID, apartmentID, managerID int) string {
-	return fmt.Sprintf("invitation:%d:%d:%d", userID, apartmentID, managerID)
+func (r *invitationLinkRepository) updateOpen(ctx context.Context, query string, args ...interface{}) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrInvitationNotOpen
+	}
+	return nil
 }
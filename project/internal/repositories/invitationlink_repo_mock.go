@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"time"
 
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -10,13 +12,52 @@ type MockInviteLinkRepository struct {
 	mock.Mock
 }
 
-func (m *MockInviteLinkRepository) CreateInvitation(ctx context.Context, userID, apartmentID, managerID int) (string, error) {
-	args := m.Called(ctx, userID, apartmentID, managerID)
-	return args.String(0), args.Error(1)
+func (m *MockInviteLinkRepository) CreateInvitation(ctx context.Context, invitation models.InvitationLink) (int, error) {
+	args := m.Called(ctx, invitation)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockInviteLinkRepository) GetInvitationByID(ctx context.Context, id int) (*models.InvitationLink, error) {
+	args := m.Called(ctx, id)
+	if invitation, ok := args.Get(0).(*models.InvitationLink); ok {
+		return invitation, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockInviteLinkRepository) GetInvitationsByApartment(ctx context.Context, apartmentID int) ([]models.InvitationLink, error) {
+	args := m.Called(ctx, apartmentID)
+	if invitations, ok := args.Get(0).([]models.InvitationLink); ok {
+		return invitations, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockInviteLinkRepository) GetOpenInvitationsForUser(ctx context.Context, userID int) ([]models.InvitationLink, error) {
+	args := m.Called(ctx, userID)
+	if invitations, ok := args.Get(0).([]models.InvitationLink); ok {
+		return invitations, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockInviteLinkRepository) MarkInvitationNotified(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockInviteLinkRepository) RenewInvitation(ctx context.Context, id int, expiresAt time.Time) error {
+	args := m.Called(ctx, id, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockInviteLinkRepository) CloseInvitation(ctx context.Context, id int, status models.InvitationStatus) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
 }
 
-func (m *MockInviteLinkRepository) ValidateAndConsumeInvitation(ctx context.Context, code string) (int, error) {
-	args := m.Called(ctx, code)
+func (m *MockInviteLinkRepository) ValidateAndConsumeInvitation(ctx context.Context, code string, userID int) (int, error) {
+	args := m.Called(ctx, code, userID)
 	return args.Int(0), args.Error(1)
 }
 
@@ -24,10 +65,10 @@ func NewMockInviteLinkRepository() *MockInviteLinkRepository {
 	return &MockInviteLinkRepository{}
 }
 
-func (m *MockInviteLinkRepository) ExpectCreateInvitation(ctx context.Context, userID, apartmentID, managerID int, returnCode string, returnError error) *mock.Call {
-	return m.On("CreateInvitation", ctx, userID, apartmentID, managerID).Return(returnCode, returnError)
+func (m *MockInviteLinkRepository) ExpectCreateInvitation(ctx context.Context, invitation interface{}, returnID int, returnError error) *mock.Call {
+	return m.On("CreateInvitation", ctx, invitation).Return(returnID, returnError)
 }
 
-func (m *MockInviteLinkRepository) ExpectValidateAndConsumeInvitation(ctx context.Context, code string, returnApartmentID int, returnError error) *mock.Call {
-	return m.On("ValidateAndConsumeInvitation", ctx, code).Return(returnApartmentID, returnError)
+func (m *MockInviteLinkRepository) ExpectValidateAndConsumeInvitation(ctx context.Context, code string, userID int, returnApartmentID int, returnError error) *mock.Call {
+	return m.On("ValidateAndConsumeInvitation", ctx, code, userID).Return(returnApartmentID, returnError)
 }
@@ -2,207 +2,153 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
-	"github.com/go-redis/redismock/v9"
-	"github.com/redis/go-redis/v9"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestInvitationLinkRepository_CreateInvitation(t *testing.T) {
-	db, mock := redismock.NewClientMock()
-	defer db.Close()
-
-	repo := &invitationLinkRepository{
-		redisClient: db,
-		expiration:  24 * time.Hour,
+	expiresAt := time.Now().Add(24 * time.Hour)
+	invitation := models.InvitationLink{
+		SenderID:    3,
+		ReceiverID:  1,
+		ApartmentID: 2,
+		Token:       "abc",
+		ExpiresAt:   expiresAt,
 	}
-	repo = NewInvitationLinkRepository(db, "test-salt").(*invitationLinkRepository)
-
-	userID := 1
-	apartmentID := 2
-	managerID := 3
-	ctx := context.Background()
 
 	t.Run("success", func(t *testing.T) {
-		expectedKey := "invitation:1:2:3"
-		mock.ExpectSet(expectedKey, "1", 24*time.Hour).SetVal("OK")
-
-		code, err := repo.CreateInvitation(ctx, userID, apartmentID, managerID)
+		db, mock := setupTestDB(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE invitation_links SET status = 'expired'`).
+			WithArgs(2, 1, "", "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO invitation_links (.+) VALUES \(\$1, NULLIF\(\$2, 0\)(.+) ON CONFLICT DO NOTHING RETURNING id`).
+			WithArgs(3, 1, "", "", 2, "abc", models.InvitationStatusPending, expiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectCommit()
+
+		id, err := NewInvitationLinkRepository(db).CreateInvitation(context.Background(), invitation)
 		assert.NoError(t, err)
-		assert.NotEmpty(t, code)
-		assert.GreaterOrEqual(t, len(code), 8)
+		assert.Equal(t, 7, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("redis error", func(t *testing.T) {
-		expectedKey := "invitation:1:2:3"
-		mock.ExpectSet(expectedKey, "1", 24*time.Hour).SetErr(redis.Nil)
-
-		code, err := repo.CreateInvitation(ctx, userID, apartmentID, managerID)
-		assert.Error(t, err)
-		assert.Empty(t, code)
-		assert.Contains(t, err.Error(), "failed to save invitation")
-	})
-
-	assert.NoError(t, mock.ExpectationsWereMet())
-}
-
-func TestInvitationLinkRepository_ValidateAndConsumeInvitation(t *testing.T) {
-	db, mock := redismock.NewClientMock()
-	defer db.Close()
-
-	repo := NewInvitationLinkRepository(db, "test-salt").(*invitationLinkRepository)
-
-	userID := 1
-	apartmentID := 2
-	managerID := 3
-	ctx := context.Background()
-
-	t.Run("success", func(t *testing.T) {
-		expectedKey := "invitation:1:2:3"
-		mock.ExpectSet(expectedKey, "1", 24*time.Hour).SetVal("OK")
-
-		code, err := repo.CreateInvitation(ctx, userID, apartmentID, managerID)
-		require.NoError(t, err)
-
-		//testing validation and consumption
-		mock.ExpectDel(expectedKey).SetVal(1)
-
-		resultApartmentID, err := repo.ValidateAndConsumeInvitation(ctx, code)
-		assert.NoError(t, err)
-		assert.Equal(t, apartmentID, resultApartmentID)
-	})
-
-	t.Run("invalid code", func(t *testing.T) {
-		invalidCode := "invalid-code"
-
-		resultApartmentID, err := repo.ValidateAndConsumeInvitation(ctx, invalidCode)
-		assert.Error(t, err)
-		assert.Equal(t, 0, resultApartmentID)
-		assert.Contains(t, err.Error(), "invalid or tampered code")
-	})
-
-	t.Run("invitation not found", func(t *testing.T) {
-		//a valid code first
-		expectedKey := "invitation:1:2:3"
-		mock.ExpectSet(expectedKey, "1", 24*time.Hour).SetVal("OK")
-
-		code, err := repo.CreateInvitation(ctx, userID, apartmentID, managerID)
-		require.NoError(t, err)
-
-		//mocking deletion returning 0 (key not found)
-		mock.ExpectDel(expectedKey).SetVal(0)
-
-		resultApartmentID, err := repo.ValidateAndConsumeInvitation(ctx, code)
-		assert.Error(t, err)
-		assert.Equal(t, 0, resultApartmentID)
-		assert.Contains(t, err.Error(), "invitation not found or already used")
-	})
-
-	t.Run("redis error during deletion", func(t *testing.T) {
-		expectedKey := "invitation:1:2:3"
-		mock.ExpectSet(expectedKey, "1", 24*time.Hour).SetVal("OK")
-
-		code, err := repo.CreateInvitation(ctx, userID, apartmentID, managerID)
-		require.NoError(t, err)
+	t.Run("already invited", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		defer db.Close()
 
-		//mocking deletion error
-		mock.ExpectDel(expectedKey).SetErr(redis.Nil)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE invitation_links SET status = 'expired'`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`INSERT INTO invitation_links`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
 
-		resultApartmentID, err := repo.ValidateAndConsumeInvitation(ctx, code)
-		assert.Error(t, err)
-		assert.Equal(t, 0, resultApartmentID)
-		assert.Contains(t, err.Error(), "failed to access Redis")
+		_, err := NewInvitationLinkRepository(db).CreateInvitation(context.Background(), invitation)
+		assert.ErrorIs(t, err, ErrInvitationExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
-
-	t.Run("empty code", func(t *testing.T) {
-		resultApartmentID, err := repo.ValidateAndConsumeInvitation(ctx, "")
-		assert.Error(t, err)
-		assert.Equal(t, 0, resultApartmentID)
-		assert.Contains(t, err.Error(), "invalid or tampered code")
-	})
-
-	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestInvitationLinkRepository_redisKey(t *testing.T) {
-	db, _ := redismock.NewClientMock()
+func TestInvitationLinkRepository_GetInvitations(t *testing.T) {
+	db, mock := setupTestDB(t)
 	defer db.Close()
-
-	repo := &invitationLinkRepository{
-		redisClient: db,
-		expiration:  24 * time.Hour,
-	}
-
-	userID := 123
-	apartmentID := 456
-	managerID := 789
-
-	expectedKey := "invitation:123:456:789"
-	actualKey := repo.redisKey(userID, apartmentID, managerID)
-
-	assert.Equal(t, expectedKey, actualKey)
+	repo := NewInvitationLinkRepository(db)
+
+	columns := []string{"id", "sender_id", "sender_username", "receiver_id", "receiver_email", "receiver_phone", "receiver_username", "receiver_chat_id",
+		"apartment_id", "apartment_name", "token", "expires_at", "status", "notification_sent_at", "responded_at", "created_at", "updated_at"}
+	now := time.Now()
+	mock.ExpectQuery(`SELECT (.+) FROM invitation_links i (.+) WHERE i.id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, 3, "manager", 1, "", "", "resident_tg", 42, 2, "Sunset", "abc", now, "notified", now, nil, now, now))
+	mock.ExpectQuery(`SELECT (.+) FROM invitation_links i (.+) WHERE i.id = \$1`).
+		WithArgs(8).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT (.+) WHERE i.apartment_id = \$1 ORDER BY`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, 3, "manager", 1, "", "", "resident_tg", 42, 2, "Sunset", "abc", now, "expired", nil, nil, now, now).
+			AddRow(6, 3, "manager", 0, "neighbour@example.com", "", "", 0, 2, "Sunset", "def", now, "rejected", nil, now, now, now))
+	mock.ExpectQuery(`SELECT (.+) WHERE i.receiver_id = \$1 AND i.status IN \('pending', 'notified'\) AND i.expires_at > CURRENT_TIMESTAMP`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	invitation, err := repo.GetInvitationByID(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, "Sunset", invitation.ApartmentName)
+	assert.Equal(t, int64(42), invitation.ReceiverChatID)
+	assert.Equal(t, models.InvitationStatusNotified, invitation.Status)
+	assert.NotNil(t, invitation.NotificationSentAt)
+
+	_, err = repo.GetInvitationByID(context.Background(), 8)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	invitations, err := repo.GetInvitationsByApartment(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Len(t, invitations, 2)
+	assert.Equal(t, models.InvitationStatusExpired, invitations[0].Status)
+	assert.Equal(t, "neighbour@example.com", invitations[1].ReceiverEmail)
+
+	invitations, err = repo.GetOpenInvitationsForUser(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Empty(t, invitations)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestNewInvitationLinkRepository(t *testing.T) {
-	db, _ := redismock.NewClientMock()
+func TestInvitationLinkRepository_CloseInvitation(t *testing.T) {
+	db, mock := setupTestDB(t)
 	defer db.Close()
-
-	salt := "test-salt"
-	repo := NewInvitationLinkRepository(db, salt)
-
-	assert.NotNil(t, repo)
-
-	var _ InviteLinkRepo = repo
+	repo := NewInvitationLinkRepository(db)
+
+	mock.ExpectExec(`UPDATE invitation_links SET status = \$2, responded_at = CURRENT_TIMESTAMP`).
+		WithArgs(7, models.InvitationStatusRevoked).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE invitation_links SET status = \$2`).
+		WithArgs(8, models.InvitationStatusRejected).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE invitation_links SET status = 'notified', notification_sent_at = CURRENT_TIMESTAMP`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE invitation_links SET expires_at = \$2`).
+		WithArgs(7, sqlmock.AnyArg()).
+		WillReturnError(errors.New("db down"))
+
+	assert.NoError(t, repo.CloseInvitation(context.Background(), 7, models.InvitationStatusRevoked))
+	assert.ErrorIs(t, repo.CloseInvitation(context.Background(), 8, models.InvitationStatusRejected), ErrInvitationNotOpen)
+	assert.NoError(t, repo.MarkInvitationNotified(context.Background(), 7))
+	assert.Error(t, repo.RenewInvitation(context.Background(), 7, time.Now()))
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestInvitationLinkRepository_Integration(t *testing.T) {
-	db, mock := redismock.NewClientMock()
+func TestInvitationLinkRepository_ValidateAndConsumeInvitation(t *testing.T) {
+	db, mock := setupTestDB(t)
 	defer db.Close()
-
-	repo := NewInvitationLinkRepository(db, "integration-test-salt")
-	ctx := context.Background()
-
-	userID := 1
-	apartmentID := 2
-	managerID := 3
-
-	t.Run("create and validate invitation flow", func(t *testing.T) {
-		expectedKey := "invitation:1:2:3"
-
-		mock.ExpectSet(expectedKey, "1", 24*time.Hour).SetVal("OK")
-		code, err := repo.CreateInvitation(ctx, userID, apartmentID, managerID)
-		assert.NoError(t, err)
-		assert.NotEmpty(t, code)
-
-		//validating and consuming invitation
-		mock.ExpectDel(expectedKey).SetVal(1)
-		resultApartmentID, err := repo.ValidateAndConsumeInvitation(ctx, code)
-		assert.NoError(t, err)
-		assert.Equal(t, apartmentID, resultApartmentID)
-	})
-
-	t.Run("double consumption should fail", func(t *testing.T) {
-		expectedKey := "invitation:1:2:3"
-
-		mock.ExpectSet(expectedKey, "1", 24*time.Hour).SetVal("OK")
-		code, err := repo.CreateInvitation(ctx, userID, apartmentID, managerID)
-		assert.NoError(t, err)
-
-		//first consumption - success
-		mock.ExpectDel(expectedKey).SetVal(1)
-		_, err = repo.ValidateAndConsumeInvitation(ctx, code)
-		assert.NoError(t, err)
-
-		//second consumption - should fail (key already deleted)
-		mock.ExpectDel(expectedKey).SetVal(0)
-		resultApartmentID, err := repo.ValidateAndConsumeInvitation(ctx, code)
-		assert.Error(t, err)
-		assert.Equal(t, 0, resultApartmentID)
-		assert.Contains(t, err.Error(), "invitation not found or already used")
-	})
-
+	repo := NewInvitationLinkRepository(db)
+
+	mock.ExpectQuery(`UPDATE invitation_links i SET status = 'accepted', receiver_id = \$1(.+)WHERE i.token = \$2 AND \(i.receiver_id = \$1 OR i.receiver_id IS NULL\)(.+)RETURNING i.apartment_id`).
+		WithArgs(1, "abc").
+		WillReturnRows(sqlmock.NewRows([]string{"apartment_id"}).AddRow(2))
+	mock.ExpectQuery(`UPDATE invitation_links i SET status = 'accepted'`).
+		WithArgs(1, "abc").
+		WillReturnRows(sqlmock.NewRows([]string{"apartment_id"}))
+
+	apartmentID, err := repo.ValidateAndConsumeInvitation(context.Background(), "abc", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, apartmentID)
+
+	//already used, expired or sent to another user
+	_, err = repo.ValidateAndConsumeInvitation(context.Background(), "abc", 1)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invitation not found or already used")
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+type LateFeeRepository interface {
+	SetLateFeeRule(ctx context.Context, rule models.LateFeeRule) (int, error)
+	GetLateFeeRule(apartmentID int) (*models.LateFeeRule, error)
+	GetActiveLateFeeRules(ctx context.Context) ([]models.LateFeeRule, error)
+	DeleteLateFeeRule(apartmentID int) error
+	GetOverduePayments(ctx context.Context, apartmentID int, now time.Time) ([]models.OverduePayment, error)
+	AccrueLateFee(ctx context.Context, paymentID int, fee models.Money, reason string) (models.Money, error)
+	WaiveLateFee(ctx context.Context, paymentID int, actor, reason string) (models.Money, error)
+	GetLateFeeEvents(paymentID int) ([]models.LateFeeEvent, error)
+}
+
+type lateFeeRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewLateFeeRepository(db *sqlx.DB) LateFeeRepository {
+	return &lateFeeRepositoryImpl{db: db}
+}
+
+// creates the apartment's rule or replaces the one it has
+func (r *lateFeeRepositoryImpl) SetLateFeeRule(ctx context.Context, rule models.LateFeeRule) (int, error) {
+	query := `INSERT INTO late_fee_rules (apartment_id, fee_type, amount, currency, rate_basis_points, grace_days, cap, active)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  ON CONFLICT (apartment_id) DO UPDATE SET
+			  fee_type = EXCLUDED.fee_type, amount = EXCLUDED.amount, currency = EXCLUDED.currency,
+			  rate_basis_points = EXCLUDED.rate_basis_points, grace_days = EXCLUDED.grace_days,
+			  cap = EXCLUDED.cap, active = EXCLUDED.active, updated_at = CURRENT_TIMESTAMP
+			  RETURNING id`
+	var id int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		rule.ApartmentID,
+		rule.FeeType,
+		rule.Amount.Amount,
+		rule.Amount.Currency,
+		rule.RateBasisPoints,
+		rule.GraceDays,
+		rule.Cap.Amount,
+		rule.Active,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+const lateFeeRuleColumns = `id, apartment_id, fee_type, amount AS "amount.amount", currency AS "amount.currency",
+			  rate_basis_points, grace_days, cap AS "cap.amount", currency AS "cap.currency",
+			  active, created_at, updated_at`
+
+func (r *lateFeeRepositoryImpl) GetLateFeeRule(apartmentID int) (*models.LateFeeRule, error) {
+	var rule models.LateFeeRule
+	query := `SELECT ` + lateFeeRuleColumns + ` FROM late_fee_rules WHERE apartment_id = $1`
+	err := r.db.Get(&rule, query, apartmentID)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *lateFeeRepositoryImpl) GetActiveLateFeeRules(ctx context.Context) ([]models.LateFeeRule, error) {
+	var rules []models.LateFeeRule
+	query := `SELECT ` + lateFeeRuleColumns + ` FROM late_fee_rules WHERE active = TRUE`
+	err := conn(ctx, r.db).SelectContext(ctx, &rules, query)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *lateFeeRepositoryImpl) DeleteLateFeeRule(apartmentID int) error {
+	query := `DELETE FROM late_fee_rules WHERE apartment_id = $1`
+	result, err := r.db.Exec(query, apartmentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("no late fee rule found for apartment %d", apartmentID)
+	}
+	return nil
+}
+
+// unpaid payments of the apartment whose bill's billing deadline (or due
+// date when it has none) is before now and whose late fee was not waived
+func (r *lateFeeRepositoryImpl) GetOverduePayments(ctx context.Context, apartmentID int, now time.Time) ([]models.OverduePayment, error) {
+	var payments []models.OverduePayment
+	query := `SELECT p.id, p.bill_id, p.user_id, p.amount AS "amount.amount", p.currency AS "amount.currency",
+			  p.paid_amount AS "paid_amount.amount", p.currency AS "paid_amount.currency",
+			  p.late_fee AS "late_fee.amount", p.currency AS "late_fee.currency", p.late_fee_waived,
+			  p.paid_at, p.payment_status, p.created_at, p.updated_at,
+			  TO_CHAR(COALESCE(b.billing_deadline, b.due_date), 'YYYY-MM-DD') AS deadline
+			  FROM payments p JOIN bills b ON b.id = p.bill_id
+			  WHERE b.apartment_id = $1 AND p.payment_status IN ('pending', 'failed', 'partially_paid')
+			  AND NOT p.late_fee_waived AND COALESCE(b.billing_deadline, b.due_date) < $2`
+	err := conn(ctx, r.db).SelectContext(ctx, &payments, query, apartmentID, now)
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// raises the payment's late fee to fee and records the difference. a fee
+// that is not higher than the current one, a waived fee or a payment that is
+// no longer unpaid is left alone. returns the added amount
+func (r *lateFeeRepositoryImpl) AccrueLateFee(ctx context.Context, paymentID int, fee models.Money, reason string) (added models.Money, err error) {
+	tx, finish, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Money{}, err
+	}
+	defer finish(&err)
+
+	var current struct {
+		Status  models.PaymentStatus `db:"payment_status"`
+		LateFee int64                `db:"late_fee"`
+		Waived  bool                 `db:"late_fee_waived"`
+	}
+	err = tx.GetContext(ctx, &current, `SELECT payment_status, late_fee, late_fee_waived FROM payments WHERE id = $1 FOR UPDATE`, paymentID)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	unpaid := current.Status == models.Pending || current.Status == models.Failed || current.Status == models.PartiallyPaid
+	if current.Waived || !unpaid || fee.Amount <= current.LateFee {
+		return models.NewMoney(0, fee.Currency), nil
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE payments SET late_fee = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, fee.Amount, paymentID)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	added = models.NewMoney(fee.Amount-current.LateFee, fee.Currency)
+	err = r.insertLateFeeEvent(ctx, tx, paymentID, models.LateFeeAccrued, added, models.SystemActor, reason)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return added, nil
+}
+
+// drops what is still unpaid of the payment's late fee and stops it from
+// accruing again. a fee the resident already paid is kept. returns the
+// waived amount
+func (r *lateFeeRepositoryImpl) WaiveLateFee(ctx context.Context, paymentID int, actor, reason string) (waived models.Money, err error) {
+	tx, finish, err := beginTx(ctx, r.db)
+	if err != nil {
+		return models.Money{}, err
+	}
+	defer finish(&err)
+
+	var current struct {
+		Amount     int64  `db:"amount"`
+		Currency   string `db:"currency"`
+		PaidAmount int64  `db:"paid_amount"`
+		LateFee    int64  `db:"late_fee"`
+	}
+	err = tx.GetContext(ctx, &current, `SELECT amount, currency, paid_amount, late_fee FROM payments WHERE id = $1 FOR UPDATE`, paymentID)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	paidFee := current.PaidAmount - current.Amount
+	if paidFee < 0 {
+		paidFee = 0
+	}
+	waived = models.NewMoney(current.LateFee-paidFee, current.Currency)
+
+	_, err = tx.ExecContext(ctx, `UPDATE payments SET late_fee = $1, late_fee_waived = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, paidFee, paymentID)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	err = r.insertLateFeeEvent(ctx, tx, paymentID, models.LateFeeWaived, waived, actor, reason)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return waived, nil
+}
+
+func (r *lateFeeRepositoryImpl) insertLateFeeEvent(ctx context.Context, tx *sqlx.Tx, paymentID int, kind models.LateFeeEventKind, amount models.Money, actor, reason string) error {
+	query := `INSERT INTO late_fee_events (payment_id, kind, amount, currency, actor, reason)
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := tx.ExecContext(ctx, query, paymentID, kind, amount.Amount, amount.Currency, actor, reason)
+	return err
+}
+
+func (r *lateFeeRepositoryImpl) GetLateFeeEvents(paymentID int) ([]models.LateFeeEvent, error) {
+	var events []models.LateFeeEvent
+	query := `SELECT id, payment_id, kind, amount AS "amount.amount", currency AS "amount.currency",
+			  actor, reason, created_at
+			  FROM late_fee_events WHERE payment_id = $1 ORDER BY id`
+	err := r.db.Select(&events, query, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLateFeeRepository struct {
+	mock.Mock
+}
+
+func (m *MockLateFeeRepository) SetLateFeeRule(ctx context.Context, rule models.LateFeeRule) (int, error) {
+	args := m.Called(ctx, rule)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLateFeeRepository) GetLateFeeRule(apartmentID int) (*models.LateFeeRule, error) {
+	args := m.Called(apartmentID)
+	if rule, ok := args.Get(0).(*models.LateFeeRule); ok {
+		return rule, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLateFeeRepository) GetActiveLateFeeRules(ctx context.Context) ([]models.LateFeeRule, error) {
+	args := m.Called(ctx)
+	if rules, ok := args.Get(0).([]models.LateFeeRule); ok {
+		return rules, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLateFeeRepository) DeleteLateFeeRule(apartmentID int) error {
+	args := m.Called(apartmentID)
+	return args.Error(0)
+}
+
+func (m *MockLateFeeRepository) GetOverduePayments(ctx context.Context, apartmentID int, now time.Time) ([]models.OverduePayment, error) {
+	args := m.Called(ctx, apartmentID, now)
+	if payments, ok := args.Get(0).([]models.OverduePayment); ok {
+		return payments, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLateFeeRepository) AccrueLateFee(ctx context.Context, paymentID int, fee models.Money, reason string) (models.Money, error) {
+	args := m.Called(ctx, paymentID, fee, reason)
+	return args.Get(0).(models.Money), args.Error(1)
+}
+
+func (m *MockLateFeeRepository) WaiveLateFee(ctx context.Context, paymentID int, actor, reason string) (models.Money, error) {
+	args := m.Called(ctx, paymentID, actor, reason)
+	return args.Get(0).(models.Money), args.Error(1)
+}
+
+func (m *MockLateFeeRepository) GetLateFeeEvents(paymentID int) ([]models.LateFeeEvent, error) {
+	args := m.Called(paymentID)
+	if events, ok := args.Get(0).([]models.LateFeeEvent); ok {
+		return events, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
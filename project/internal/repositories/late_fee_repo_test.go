@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLateFeeRepository(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewLateFeeRepository(db)
+	assert.NotNil(t, repo)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLateFeeRepository_SetLateFeeRule(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	rule := models.LateFeeRule{
+		ApartmentID: 2,
+		FeeType:     models.DailyLateFee,
+		Amount:      models.NewMoney(1000, "IRR"),
+		GraceDays:   3,
+		Cap:         models.NewMoney(20000, "IRR"),
+		Active:      true,
+	}
+
+	mock.ExpectQuery("INSERT INTO late_fee_rules (.+) ON CONFLICT \\(apartment_id\\) DO UPDATE").
+		WithArgs(2, models.DailyLateFee, int64(1000), "IRR", int64(0), 3, int64(20000), true).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+
+	repo := &lateFeeRepositoryImpl{db: db}
+	id, err := repo.SetLateFeeRule(context.Background(), rule)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLateFeeRepository_GetOverduePayments(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount.amount", "amount.currency", "late_fee.amount", "payment_status", "deadline"}).
+		AddRow(1, 3, 7, 50000, "IRR", 2000, "pending", "2025-03-01")
+	mock.ExpectQuery(`SELECT (.+) FROM payments p JOIN bills b ON b.id = p.bill_id WHERE b.apartment_id = \$1`).
+		WithArgs(2, now).
+		WillReturnRows(rows)
+
+	repo := &lateFeeRepositoryImpl{db: db}
+	payments, err := repo.GetOverduePayments(context.Background(), 2, now)
+
+	assert.NoError(t, err)
+	assert.Len(t, payments, 1)
+	assert.Equal(t, "2025-03-01", payments[0].Deadline)
+	assert.Equal(t, models.NewMoney(50000, "IRR"), payments[0].Amount)
+	assert.Equal(t, int64(2000), payments[0].LateFee.Amount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLateFeeRepository_AccrueLateFee(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    models.PaymentStatus
+		lateFee   int64
+		waived    bool
+		wantAdded int64
+	}{
+		{name: "raises the fee", status: models.Pending, lateFee: 2000, wantAdded: 1000},
+		{name: "fee already applied", status: models.Pending, lateFee: 3000},
+		{name: "waived fee", status: models.Failed, waived: true},
+		{name: "payment in progress", status: models.Processing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupTestDB(t)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT payment_status, late_fee, late_fee_waived FROM payments WHERE id = \$1 FOR UPDATE`).
+				WithArgs(5).
+				WillReturnRows(sqlmock.NewRows([]string{"payment_status", "late_fee", "late_fee_waived"}).
+					AddRow(tt.status, tt.lateFee, tt.waived))
+			if tt.wantAdded > 0 {
+				mock.ExpectExec(`UPDATE payments SET late_fee = \$1`).
+					WithArgs(int64(3000), 5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO late_fee_events").
+					WithArgs(5, models.LateFeeAccrued, tt.wantAdded, "IRR", models.SystemActor, "3 days late").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+			mock.ExpectCommit()
+
+			repo := &lateFeeRepositoryImpl{db: db}
+			added, err := repo.AccrueLateFee(context.Background(), 5, models.NewMoney(3000, "IRR"), "3 days late")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantAdded, added.Amount)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLateFeeRepository_WaiveLateFee(t *testing.T) {
+	t.Run("keeps the part of the fee already paid", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT amount, currency, paid_amount, late_fee FROM payments WHERE id = \$1 FOR UPDATE`).
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"amount", "currency", "paid_amount", "late_fee"}).
+				AddRow(10000, "IRR", 10500, 3000))
+		mock.ExpectExec(`UPDATE payments SET late_fee = \$1, late_fee_waived = TRUE`).
+			WithArgs(int64(500), 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO late_fee_events").
+			WithArgs(5, models.LateFeeWaived, int64(2500), "IRR", "user:1", "hospital stay").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		repo := &lateFeeRepositoryImpl{db: db}
+		waived, err := repo.WaiveLateFee(context.Background(), 5, "user:1", "hospital stay")
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.NewMoney(2500, "IRR"), waived)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back on error", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT amount, currency, paid_amount, late_fee FROM payments WHERE id = \$1 FOR UPDATE`).
+			WithArgs(5).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		repo := &lateFeeRepositoryImpl{db: db}
+		_, err := repo.WaiveLateFee(context.Background(), 5, "user:1", "")
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestLateFeeRepository_DeleteLateFeeRule(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM late_fee_rules WHERE apartment_id = \$1`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &lateFeeRepositoryImpl{db: db}
+	err := repo.DeleteLateFeeRule(2)
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+const (
+	//debits raise the balance, everything else lowers it
+	ledgerBalanceQuery = `SELECT COALESCE(SUM(CASE WHEN entry_type IN ('charge', 'fee', 'refund') THEN amount ELSE -amount END), 0) AS amount,
+			  COALESCE(MAX(currency), 'IRR') AS currency
+			  FROM ledger_entries WHERE user_id = $1 AND apartment_id = $2`
+)
+
+type LedgerRepository interface {
+	AddLedgerEntry(ctx context.Context, entry models.LedgerEntry) (int, error)
+	GetBalance(ctx context.Context, userID, apartmentID int) (models.Money, error)
+	GetBalanceBefore(ctx context.Context, userID, apartmentID int, before time.Time) (models.Money, error)
+	GetLedgerEntries(ctx context.Context, userID, apartmentID int, from, to time.Time) ([]models.LedgerEntry, error)
+}
+
+type ledgerRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewLedgerRepository(db *sqlx.DB) LedgerRepository {
+	return &ledgerRepositoryImpl{db: db}
+}
+
+func (r *ledgerRepositoryImpl) AddLedgerEntry(ctx context.Context, entry models.LedgerEntry) (int, error) {
+	query := `INSERT INTO ledger_entries (user_id, apartment_id, payment_id, entry_type, amount, currency, description)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	var id int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		entry.UserID,
+		entry.ApartmentID,
+		entry.PaymentID,
+		entry.EntryType,
+		entry.Amount.Amount,
+		entry.Amount.Currency,
+		entry.Description,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// the resident's current balance in the apartment, positive when they owe
+func (r *ledgerRepositoryImpl) GetBalance(ctx context.Context, userID, apartmentID int) (models.Money, error) {
+	var balance models.Money
+	err := conn(ctx, r.db).GetContext(ctx, &balance, ledgerBalanceQuery, userID, apartmentID)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return balance, nil
+}
+
+// the balance made up by the entries before the given time
+func (r *ledgerRepositoryImpl) GetBalanceBefore(ctx context.Context, userID, apartmentID int, before time.Time) (models.Money, error) {
+	var balance models.Money
+	err := conn(ctx, r.db).GetContext(ctx, &balance, ledgerBalanceQuery+` AND created_at < $3`, userID, apartmentID, before)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return balance, nil
+}
+
+// entries from (inclusive) to (exclusive) in the order they were made
+func (r *ledgerRepositoryImpl) GetLedgerEntries(ctx context.Context, userID, apartmentID int, from, to time.Time) ([]models.LedgerEntry, error) {
+	var entries []models.LedgerEntry
+	query := `SELECT id, user_id, apartment_id, payment_id, entry_type,
+			  amount AS "amount.amount", currency AS "amount.currency", description, created_at
+			  FROM ledger_entries WHERE user_id = $1 AND apartment_id = $2 AND created_at >= $3 AND created_at < $4
+			  ORDER BY created_at, id`
+	err := conn(ctx, r.db).SelectContext(ctx, &entries, query, userID, apartmentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
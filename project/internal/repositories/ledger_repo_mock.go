@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLedgerRepository struct {
+	mock.Mock
+}
+
+func (m *MockLedgerRepository) AddLedgerEntry(ctx context.Context, entry models.LedgerEntry) (int, error) {
+	args := m.Called(ctx, entry)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLedgerRepository) GetBalance(ctx context.Context, userID, apartmentID int) (models.Money, error) {
+	args := m.Called(ctx, userID, apartmentID)
+	return args.Get(0).(models.Money), args.Error(1)
+}
+
+func (m *MockLedgerRepository) GetBalanceBefore(ctx context.Context, userID, apartmentID int, before time.Time) (models.Money, error) {
+	args := m.Called(ctx, userID, apartmentID, before)
+	return args.Get(0).(models.Money), args.Error(1)
+}
+
+func (m *MockLedgerRepository) GetLedgerEntries(ctx context.Context, userID, apartmentID int, from, to time.Time) ([]models.LedgerEntry, error) {
+	args := m.Called(ctx, userID, apartmentID, from, to)
+	if entries, ok := args.Get(0).([]models.LedgerEntry); ok {
+		return entries, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
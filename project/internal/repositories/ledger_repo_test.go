@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLedgerRepository(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewLedgerRepository(db)
+	assert.NotNil(t, repo)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerRepository_AddLedgerEntry(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	paymentID := 5
+	entry := models.LedgerEntry{
+		UserID:      1,
+		ApartmentID: 2,
+		PaymentID:   &paymentID,
+		EntryType:   models.ChargeEntry,
+		Amount:      models.NewMoney(25000, "IRR"),
+		Description: "water bill",
+	}
+
+	mock.ExpectQuery("INSERT INTO ledger_entries").
+		WithArgs(1, 2, &paymentID, models.ChargeEntry, int64(25000), "IRR", "water bill").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+
+	repo := &ledgerRepositoryImpl{db: db}
+	id, err := repo.AddLedgerEntry(context.Background(), entry)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 8, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerRepository_GetBalanceBefore(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	before := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(CASE WHEN entry_type IN \('charge', 'fee', 'refund'\) THEN amount ELSE -amount END\), 0\) AS amount, (.+) AND created_at < \$3`).
+		WithArgs(1, 2, before).
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "currency"}).AddRow(-3000, "IRR"))
+
+	repo := &ledgerRepositoryImpl{db: db}
+	balance, err := repo.GetBalanceBefore(context.Background(), 1, 2, before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(-3000, "IRR"), balance)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerRepository_GetLedgerEntries(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "user_id", "apartment_id", "payment_id", "entry_type", "amount.amount", "amount.currency", "description", "created_at"}).
+		AddRow(1, 1, 2, 5, "charge", 25000, "IRR", "water bill", from).
+		AddRow(2, 1, 2, nil, "credit", 1000, "IRR", "goodwill", from)
+	mock.ExpectQuery(`SELECT (.+) FROM ledger_entries WHERE user_id = \$1 AND apartment_id = \$2 AND created_at >= \$3 AND created_at < \$4 ORDER BY created_at, id`).
+		WithArgs(1, 2, from, to).
+		WillReturnRows(rows)
+
+	repo := &ledgerRepositoryImpl{db: db}
+	entries, err := repo.GetLedgerEntries(context.Background(), 1, 2, from, to)
+
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, 5, *entries[0].PaymentID)
+	assert.Nil(t, entries[1].PaymentID)
+	assert.Equal(t, models.CreditEntry, entries[1].EntryType)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
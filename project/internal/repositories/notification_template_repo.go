@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+const notificationTemplateColumns = `apartment_id, name, locale, subject, body, created_at, updated_at`
+
+type NotificationTemplateRepository interface {
+	GetTemplate(ctx context.Context, apartmentID int, name string, locale models.Locale) (*models.NotificationTemplate, error)
+	GetTemplates(ctx context.Context, apartmentID int) ([]models.NotificationTemplate, error)
+	SetTemplate(ctx context.Context, template models.NotificationTemplate) error
+	DeleteTemplate(ctx context.Context, apartmentID int, name string, locale models.Locale) error
+}
+
+type notificationTemplateRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewNotificationTemplateRepository(db *sqlx.DB) NotificationTemplateRepository {
+	return &notificationTemplateRepositoryImpl{db: db}
+}
+
+// the apartment's own template, sql.ErrNoRows when it uses the built-in one
+func (r *notificationTemplateRepositoryImpl) GetTemplate(ctx context.Context, apartmentID int, name string, locale models.Locale) (*models.NotificationTemplate, error) {
+	query := `SELECT ` + notificationTemplateColumns + ` FROM notification_templates
+			  WHERE apartment_id = $1 AND name = $2 AND locale = $3`
+	var template models.NotificationTemplate
+	if err := conn(ctx, r.db).GetContext(ctx, &template, query, apartmentID, name, locale); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *notificationTemplateRepositoryImpl) GetTemplates(ctx context.Context, apartmentID int) ([]models.NotificationTemplate, error) {
+	query := `SELECT ` + notificationTemplateColumns + ` FROM notification_templates
+			  WHERE apartment_id = $1 ORDER BY name, locale`
+	var templates []models.NotificationTemplate
+	if err := conn(ctx, r.db).SelectContext(ctx, &templates, query, apartmentID); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// adds the apartment's template or replaces the one it had
+func (r *notificationTemplateRepositoryImpl) SetTemplate(ctx context.Context, template models.NotificationTemplate) error {
+	query := `INSERT INTO notification_templates (apartment_id, name, locale, subject, body)
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (apartment_id, name, locale)
+			  DO UPDATE SET subject = EXCLUDED.subject, body = EXCLUDED.body, updated_at = CURRENT_TIMESTAMP`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		template.ApartmentID,
+		template.Name,
+		template.Locale,
+		template.Subject,
+		template.Body,
+	)
+	return err
+}
+
+func (r *notificationTemplateRepositoryImpl) DeleteTemplate(ctx context.Context, apartmentID int, name string, locale models.Locale) error {
+	query := `DELETE FROM notification_templates WHERE apartment_id = $1 AND name = $2 AND locale = $3`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, apartmentID, name, locale)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
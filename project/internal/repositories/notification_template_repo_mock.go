@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockNotificationTemplateRepository struct {
+	mock.Mock
+}
+
+func (m *MockNotificationTemplateRepository) GetTemplate(ctx context.Context, apartmentID int, name string, locale models.Locale) (*models.NotificationTemplate, error) {
+	args := m.Called(ctx, apartmentID, name, locale)
+	if template, ok := args.Get(0).(*models.NotificationTemplate); ok {
+		return template, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationTemplateRepository) GetTemplates(ctx context.Context, apartmentID int) ([]models.NotificationTemplate, error) {
+	args := m.Called(ctx, apartmentID)
+	if templates, ok := args.Get(0).([]models.NotificationTemplate); ok {
+		return templates, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockNotificationTemplateRepository) SetTemplate(ctx context.Context, template models.NotificationTemplate) error {
+	args := m.Called(ctx, template)
+	return args.Error(0)
+}
+
+func (m *MockNotificationTemplateRepository) DeleteTemplate(ctx context.Context, apartmentID int, name string, locale models.Locale) error {
+	args := m.Called(ctx, apartmentID, name, locale)
+	return args.Error(0)
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationTemplateRepository_GetTemplate(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+	repo := NewNotificationTemplateRepository(db)
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"apartment_id", "name", "locale", "subject", "body", "created_at", "updated_at"}).
+		AddRow(2, "new_bill", "fa", "قبض", "قبض {{.bill_type}}", now, now)
+	mock.ExpectQuery(`SELECT (.+) FROM notification_templates WHERE apartment_id = \$1 AND name = \$2 AND locale = \$3`).
+		WithArgs(2, "new_bill", models.Persian).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT (.+) FROM notification_templates`).
+		WithArgs(2, "invitation", models.Persian).
+		WillReturnError(sql.ErrNoRows)
+
+	template, err := repo.GetTemplate(context.Background(), 2, "new_bill", models.Persian)
+	assert.NoError(t, err)
+	assert.Equal(t, "قبض {{.bill_type}}", template.Body)
+
+	_, err = repo.GetTemplate(context.Background(), 2, "invitation", models.Persian)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotificationTemplateRepository_SetAndDeleteTemplate(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+	repo := NewNotificationTemplateRepository(db)
+
+	mock.ExpectExec(`INSERT INTO notification_templates (.+) ON CONFLICT \(apartment_id, name, locale\) DO UPDATE`).
+		WithArgs(2, "new_bill", models.English, "Bill", "New {{.bill_type}} bill").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM notification_templates`).
+		WithArgs(2, "new_bill", models.English).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM notification_templates`).
+		WithArgs(2, "new_bill", models.English).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.SetTemplate(context.Background(), models.NotificationTemplate{
+		ApartmentID: 2,
+		Name:        "new_bill",
+		Locale:      models.English,
+		Subject:     "Bill",
+		Body:        "New {{.bill_type}} bill",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.DeleteTemplate(context.Background(), 2, "new_bill", models.English))
+	assert.ErrorIs(t, repo.DeleteTemplate(context.Background(), 2, "new_bill", models.English), sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+const outboxColumns = `id, kind, COALESCE(user_id, 0) AS user_id, COALESCE(apartment_id, 0) AS apartment_id,
+			  payload, status, attempts, next_attempt_at, last_error, delivered_at, created_at, updated_at`
+
+type OutboxRepository interface {
+	EnqueueMessage(ctx context.Context, message models.OutboxMessage) (int, error)
+	ClaimDueMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxMessage, error)
+	MarkMessageDelivered(ctx context.Context, id int) error
+	ScheduleMessageRetry(ctx context.Context, id int, lastError string, at time.Time) error
+	MarkMessageDead(ctx context.Context, id int, lastError string) error
+	GetDeadMessages(ctx context.Context, apartmentID int) ([]models.OutboxMessage, error)
+}
+
+type outboxRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewOutboxRepository(db *sqlx.DB) OutboxRepository {
+	return &outboxRepositoryImpl{db: db}
+}
+
+// adds a message to the outbox, due right away. called inside a unit of work
+// the message is only kept when the work commits
+func (r *outboxRepositoryImpl) EnqueueMessage(ctx context.Context, message models.OutboxMessage) (int, error) {
+	query := `INSERT INTO notification_outbox (kind, user_id, apartment_id, payload, status)
+			  VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), $4, $5) RETURNING id`
+	var id int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		message.Kind,
+		message.UserID,
+		message.ApartmentID,
+		[]byte(message.Payload),
+		models.OutboxPending,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// takes up to limit pending messages that are due, oldest first, and moves
+// their next attempt lease into the future so other workers skip them. a
+// worker that dies before reporting back leaves them to be retried when the
+// lease runs out
+func (r *outboxRepositoryImpl) ClaimDueMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxMessage, error) {
+	query := `UPDATE notification_outbox SET next_attempt_at = $1, updated_at = CURRENT_TIMESTAMP
+			  WHERE id IN (
+			      SELECT id FROM notification_outbox
+			      WHERE status = 'pending' AND next_attempt_at <= $2
+			      ORDER BY next_attempt_at LIMIT $3
+			      FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + outboxColumns
+	var messages []models.OutboxMessage
+	err := conn(ctx, r.db).SelectContext(ctx, &messages, query, now.Add(lease), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *outboxRepositoryImpl) MarkMessageDelivered(ctx context.Context, id int) error {
+	query := `UPDATE notification_outbox SET status = 'delivered', attempts = attempts + 1, last_error = '',
+			  delivered_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	return err
+}
+
+// records a failed attempt and when to try again
+func (r *outboxRepositoryImpl) ScheduleMessageRetry(ctx context.Context, id int, lastError string, at time.Time) error {
+	query := `UPDATE notification_outbox SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2,
+			  updated_at = CURRENT_TIMESTAMP WHERE id = $3`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, lastError, at, id)
+	return err
+}
+
+// records the last failed attempt and gives up on the message
+func (r *outboxRepositoryImpl) MarkMessageDead(ctx context.Context, id int, lastError string) error {
+	query := `UPDATE notification_outbox SET status = 'dead', attempts = attempts + 1, last_error = $1,
+			  updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, lastError, id)
+	return err
+}
+
+// the messages given up on that were about the apartment or went to one of
+// its members, newest first
+func (r *outboxRepositoryImpl) GetDeadMessages(ctx context.Context, apartmentID int) ([]models.OutboxMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM notification_outbox
+			  WHERE status = 'dead' AND (apartment_id = $1 OR (apartment_id IS NULL AND user_id IN (
+			      SELECT user_id FROM user_apartments WHERE apartment_id = $1
+			  )))
+			  ORDER BY updated_at DESC`
+	var messages []models.OutboxMessage
+	if err := conn(ctx, r.db).SelectContext(ctx, &messages, query, apartmentID); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
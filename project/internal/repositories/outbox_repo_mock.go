@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) EnqueueMessage(ctx context.Context, message models.OutboxMessage) (int, error) {
+	args := m.Called(ctx, message)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOutboxRepository) ClaimDueMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxMessage, error) {
+	args := m.Called(ctx, now, lease, limit)
+	if messages, ok := args.Get(0).([]models.OutboxMessage); ok {
+		return messages, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOutboxRepository) MarkMessageDelivered(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) ScheduleMessageRetry(ctx context.Context, id int, lastError string, at time.Time) error {
+	args := m.Called(ctx, id, lastError, at)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) MarkMessageDead(ctx context.Context, id int, lastError string) error {
+	args := m.Called(ctx, id, lastError)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) GetDeadMessages(ctx context.Context, apartmentID int) ([]models.OutboxMessage, error) {
+	args := m.Called(ctx, apartmentID)
+	if messages, ok := args.Get(0).([]models.OutboxMessage); ok {
+		return messages, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var outboxTestColumns = []string{"id", "kind", "user_id", "apartment_id", "payload", "status", "attempts",
+	"next_attempt_at", "last_error", "delivered_at", "created_at", "updated_at"}
+
+func TestOutboxRepository_EnqueueMessage(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	payload := json.RawMessage(`{"text":"hello"}`)
+	mock.ExpectQuery(`INSERT INTO notification_outbox`).
+		WithArgs(models.OutboxMessageKind, 3, 0, []byte(payload), models.OutboxPending).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+
+	repo := NewOutboxRepository(db)
+	id, err := repo.EnqueueMessage(context.Background(), models.OutboxMessage{
+		Kind:    models.OutboxMessageKind,
+		UserID:  3,
+		Payload: payload,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 8, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepository_ClaimDueMessages(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`UPDATE notification_outbox SET next_attempt_at = \$1(.+)FOR UPDATE SKIP LOCKED`).
+		WithArgs(now.Add(time.Minute), now, 10).
+		WillReturnRows(sqlmock.NewRows(outboxTestColumns).
+			AddRow(8, "message", 3, 0, []byte(`{"text":"hello"}`), "pending", 2, now.Add(time.Minute), "timeout", nil, now, now))
+
+	repo := NewOutboxRepository(db)
+	messages, err := repo.ClaimDueMessages(context.Background(), now, time.Minute, 10)
+
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, 2, messages[0].Attempts)
+	assert.JSONEq(t, `{"text":"hello"}`, string(messages[0].Payload))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepository_Attempts(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+	repo := NewOutboxRepository(db)
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec(`UPDATE notification_outbox SET status = 'delivered'`).
+		WithArgs(8).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE notification_outbox SET attempts = attempts \+ 1, last_error = \$1, next_attempt_at = \$2`).
+		WithArgs("timeout", at, 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE notification_outbox SET status = 'dead'`).
+		WithArgs("timeout", 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.MarkMessageDelivered(context.Background(), 8))
+	assert.NoError(t, repo.ScheduleMessageRetry(context.Background(), 9, "timeout", at))
+	assert.NoError(t, repo.MarkMessageDead(context.Background(), 10, "timeout"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepository_GetDeadMessages(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT (.+) FROM notification_outbox\s+WHERE status = 'dead' AND \(apartment_id = \$1 OR`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(outboxTestColumns).
+			AddRow(8, "invitation", 0, 2, []byte(`{}`), "dead", 8, now, "no channel could deliver", nil, now, now))
+
+	repo := NewOutboxRepository(db)
+	messages, err := repo.GetDeadMessages(context.Background(), 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, models.OutboxDead, messages[0].Status)
+	assert.Equal(t, 2, messages[0].ApartmentID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,34 +2,27 @@ package repositories
 
 import (
 	"context"
-	"log"
+	"fmt"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 )
 
-const (
-	CREATE_PAYMENTS_TABLE = `CREATE TABLE IF NOT EXISTS payments(
-		id SERIAL PRIMARY KEY,
-		bill_id INTEGER REFERENCES bills(id) ON DELETE CASCADE,
-		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
-		amount DECIMAL(12, 2) NOT NULL,
-		paid_at TIMESTAMP WITH TIME ZONE,
-		payment_status VARCHAR(50) NOT NULL,
-		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-	);`
-)
-
 type PaymentRepository interface {
 	CreatePayment(ctx context.Context, payment models.Payment) (int, error)
-	GetPaymentByID(id int) (*models.Payment, error)
+	GetPaymentByID(ctx context.Context, id int) (*models.Payment, error)
+	GetPaymentForUpdate(ctx context.Context, id int) (*models.Payment, error)
 	GetPaymentByBillAndUser(billID, userID int) (*models.Payment, error)
 	GetPaymentsByUser(userID int) ([]models.Payment, error)
 	GetPendingPaymentsByUser(userID int) ([]models.Payment, error)
 	GetPaymentsByBill(billID int) ([]models.Payment, error)
-	UpdatePaymentStatus(ctx context.Context, payment models.Payment) error
-	UpdatePaymentsStatus(ctx context.Context, payments []models.Payment) error
+	UpdatePaymentStatus(ctx context.Context, payment models.Payment, transition models.PaymentTransition) error
+	UpdatePaymentsStatus(ctx context.Context, payments []models.Payment, transition models.PaymentTransition) error
+	GetPaymentTransitions(paymentID int) ([]models.PaymentTransition, error)
+	CreateRefund(ctx context.Context, refund models.PaymentRefund) (int, error)
+	SumRefunds(ctx context.Context, paymentID int, statuses ...models.RefundStatus) (int64, error)
+	UpdateRefundStatus(ctx context.Context, id int, status models.RefundStatus) error
 	DeletePayment(id int) error
 }
 
@@ -37,24 +30,21 @@ type paymentRepositoryImpl struct {
 	db *sqlx.DB
 }
 
-func NewPaymentRepository(autoCreate bool, db *sqlx.DB) PaymentRepository {
-	if autoCreate {
-		if _, err := db.Exec(CREATE_PAYMENTS_TABLE); err != nil {
-			log.Fatalf("failed to create payments table: %v", err)
-		}
-	}
+func NewPaymentRepository(db *sqlx.DB) PaymentRepository {
 	return &paymentRepositoryImpl{db: db}
 }
 
 func (r *paymentRepositoryImpl) CreatePayment(ctx context.Context, payment models.Payment) (int, error) {
-	query := `INSERT INTO payments (bill_id, user_id, amount, paid_at, payment_status) 
-			  VALUES ($1, $2, $3, $4, $5) 
+	query := `INSERT INTO payments (bill_id, user_id, amount, currency, paid_amount, paid_at, payment_status) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7) 
 			  RETURNING id`
 	var id int
-	if err := r.db.QueryRowContext(ctx, query,
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query,
 		payment.BillID,
 		payment.UserID,
-		payment.Amount,
+		payment.Amount.Amount,
+		payment.Amount.Currency,
+		payment.PaidAmount.Amount,
 		payment.PaidAt,
 		payment.PaymentStatus).Scan(&id); err != nil {
 		return 0, err
@@ -62,11 +52,29 @@ func (r *paymentRepositoryImpl) CreatePayment(ctx context.Context, payment model
 	return id, nil
 }
 
-func (r *paymentRepositoryImpl) GetPaymentByID(id int) (*models.Payment, error) {
+func (r *paymentRepositoryImpl) GetPaymentByID(ctx context.Context, id int) (*models.Payment, error) {
 	var payment models.Payment
-	query := `SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at 
+	query := `SELECT id, bill_id, user_id, amount AS "amount.amount", currency AS "amount.currency",
+			  paid_amount AS "paid_amount.amount", currency AS "paid_amount.currency",
+			  late_fee AS "late_fee.amount", currency AS "late_fee.currency", late_fee_waived,
+			  paid_at, payment_status, created_at, updated_at
 			  FROM payments WHERE id = $1`
-	err := r.db.Get(&payment, query, id)
+	err := conn(ctx, r.db).GetContext(ctx, &payment, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// loads a payment and locks its row until the unit of work it runs in ends
+func (r *paymentRepositoryImpl) GetPaymentForUpdate(ctx context.Context, id int) (*models.Payment, error) {
+	var payment models.Payment
+	query := `SELECT id, bill_id, user_id, amount AS "amount.amount", currency AS "amount.currency",
+			  paid_amount AS "paid_amount.amount", currency AS "paid_amount.currency",
+			  late_fee AS "late_fee.amount", currency AS "late_fee.currency", late_fee_waived,
+			  paid_at, payment_status, created_at, updated_at
+			  FROM payments WHERE id = $1 FOR UPDATE`
+	err := conn(ctx, r.db).GetContext(ctx, &payment, query, id)
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +83,10 @@ func (r *paymentRepositoryImpl) GetPaymentByID(id int) (*models.Payment, error)
 
 func (r *paymentRepositoryImpl) GetPaymentByBillAndUser(billID, userID int) (*models.Payment, error) {
 	var payment models.Payment
-	query := `SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at 
+	query := `SELECT id, bill_id, user_id, amount AS "amount.amount", currency AS "amount.currency",
+			  paid_amount AS "paid_amount.amount", currency AS "paid_amount.currency",
+			  late_fee AS "late_fee.amount", currency AS "late_fee.currency", late_fee_waived,
+			  paid_at, payment_status, created_at, updated_at
 			  FROM payments WHERE bill_id = $1 AND user_id = $2`
 	err := r.db.Get(&payment, query, billID, userID)
 	if err != nil {
@@ -86,7 +97,10 @@ func (r *paymentRepositoryImpl) GetPaymentByBillAndUser(billID, userID int) (*mo
 
 func (r *paymentRepositoryImpl) GetPaymentsByUser(userID int) ([]models.Payment, error) {
 	var payments []models.Payment
-	query := `SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at 
+	query := `SELECT id, bill_id, user_id, amount AS "amount.amount", currency AS "amount.currency",
+			  paid_amount AS "paid_amount.amount", currency AS "paid_amount.currency",
+			  late_fee AS "late_fee.amount", currency AS "late_fee.currency", late_fee_waived,
+			  paid_at, payment_status, created_at, updated_at
 			  FROM payments WHERE user_id = $1`
 	err := r.db.Select(&payments, query, userID)
 	if err != nil {
@@ -95,10 +109,14 @@ func (r *paymentRepositoryImpl) GetPaymentsByUser(userID int) ([]models.Payment,
 	return payments, nil
 }
 
+// payments that still have an outstanding balance and no payment in progress
 func (r *paymentRepositoryImpl) GetPendingPaymentsByUser(userID int) ([]models.Payment, error) {
 	var payments []models.Payment
-	query := `SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at 
-			  FROM payments WHERE user_id = $1 and payment_status = 'pending'`
+	query := `SELECT id, bill_id, user_id, amount AS "amount.amount", currency AS "amount.currency",
+			  paid_amount AS "paid_amount.amount", currency AS "paid_amount.currency",
+			  late_fee AS "late_fee.amount", currency AS "late_fee.currency", late_fee_waived,
+			  paid_at, payment_status, created_at, updated_at
+			  FROM payments WHERE user_id = $1 and payment_status IN ('pending', 'failed', 'partially_paid')`
 	err := r.db.Select(&payments, query, userID)
 	if err != nil {
 		return nil, err
@@ -108,7 +126,10 @@ func (r *paymentRepositoryImpl) GetPendingPaymentsByUser(userID int) ([]models.P
 
 func (r *paymentRepositoryImpl) GetPaymentsByBill(billID int) ([]models.Payment, error) {
 	var payments []models.Payment
-	query := `SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at 
+	query := `SELECT id, bill_id, user_id, amount AS "amount.amount", currency AS "amount.currency",
+			  paid_amount AS "paid_amount.amount", currency AS "paid_amount.currency",
+			  late_fee AS "late_fee.amount", currency AS "late_fee.currency", late_fee_waived,
+			  paid_at, payment_status, created_at, updated_at
 			  FROM payments WHERE bill_id = $1`
 	err := r.db.Select(&payments, query, billID)
 	if err != nil {
@@ -117,45 +138,143 @@ func (r *paymentRepositoryImpl) GetPaymentsByBill(billID int) ([]models.Payment,
 	return payments, nil
 }
 
-func (r *paymentRepositoryImpl) UpdatePaymentStatus(ctx context.Context, payment models.Payment) error {
-	query := `UPDATE payments SET 
-			  payment_status = :payment_status,
-			  paid_at = :paid_at,
-			  updated_at = CURRENT_TIMESTAMP
-			  WHERE id = :id`
-	_, err := r.db.NamedExecContext(ctx, query, payment)
-	return err
+// moves a payment to payment.PaymentStatus. transition carries the actor,
+// reason and refunded amount, the statuses are filled in here. when the
+// payment becomes paid or partially paid, payment.PaidAmount is the new total
+// paid so far
+func (r *paymentRepositoryImpl) UpdatePaymentStatus(ctx context.Context, payment models.Payment, transition models.PaymentTransition) error {
+	return r.UpdatePaymentsStatus(ctx, []models.Payment{payment}, transition)
 }
 
-func (r *paymentRepositoryImpl) UpdatePaymentsStatus(ctx context.Context, payments []models.Payment) error {
-	tx, err := r.db.BeginTxx(ctx, nil)
+// moves all payments to their new status in one transaction. a transition
+// the state machine does not allow fails the whole batch, while setting a
+// payment to the status it already has is a no-op
+func (r *paymentRepositoryImpl) UpdatePaymentsStatus(ctx context.Context, payments []models.Payment, transition models.PaymentTransition) (err error) {
+	tx, finish, err := beginTx(ctx, r.db)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-			return
-		}
-		err = tx.Commit()
-	}()
+	defer finish(&err)
 
+	//paid_at and paid_amount only change when money comes in and are kept
+	//through refunds
 	query := `UPDATE payments SET 
 			  payment_status = :payment_status,
-			  paid_at = :paid_at,
+			  paid_at = CASE WHEN :payment_status = 'paid' THEN :paid_at ELSE paid_at END,
+			  paid_amount = CASE WHEN :payment_status IN ('paid', 'partially_paid') THEN :paid_amount.amount ELSE paid_amount END,
 			  updated_at = CURRENT_TIMESTAMP
 			  WHERE id = :id`
 
+	transitionQuery := `INSERT INTO payment_transitions (payment_id, from_status, to_status, amount, currency, actor, reason)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
 	for _, payment := range payments {
+		var current struct {
+			Status     models.PaymentStatus `db:"payment_status"`
+			PaidAmount int64                `db:"paid_amount"`
+		}
+		err = tx.GetContext(ctx, &current, `SELECT payment_status, paid_amount FROM payments WHERE id = $1 FOR UPDATE`, payment.ID)
+		if err != nil {
+			return err
+		}
+
+		//incoming money is recorded on the transition as the installment paid
+		amount := transition.Amount
+		if payment.PaymentStatus == models.Paid || payment.PaymentStatus == models.PartiallyPaid {
+			amount = models.NewMoney(payment.PaidAmount.Amount-current.PaidAmount, payment.PaidAmount.Currency)
+		}
+
+		if !current.Status.CanTransitionTo(payment.PaymentStatus) {
+			if current.Status == payment.PaymentStatus {
+				continue
+			}
+			err = fmt.Errorf("%w: payment %d from %s to %s",
+				models.ErrInvalidPaymentTransition, payment.ID, current.Status, payment.PaymentStatus)
+			return err
+		}
+
 		_, err = tx.NamedExecContext(ctx, query, payment)
 		if err != nil {
 			return err
 		}
+
+		_, err = tx.ExecContext(ctx, transitionQuery,
+			payment.ID,
+			current.Status,
+			payment.PaymentStatus,
+			amount.Amount,
+			transitionCurrency(amount, payment.Amount),
+			transition.Actor,
+			transition.Reason,
+		)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+func (r *paymentRepositoryImpl) GetPaymentTransitions(paymentID int) ([]models.PaymentTransition, error) {
+	var transitions []models.PaymentTransition
+	query := `SELECT id, payment_id, from_status, to_status, amount AS "amount.amount", currency AS "amount.currency",
+			  actor, reason, created_at
+			  FROM payment_transitions WHERE payment_id = $1 ORDER BY id`
+	err := r.db.Select(&transitions, query, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
+
+func (r *paymentRepositoryImpl) CreateRefund(ctx context.Context, refund models.PaymentRefund) (int, error) {
+	query := `INSERT INTO payment_refunds (payment_id, amount, currency, to_credit, status, actor, reason)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	var id int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		refund.PaymentID,
+		refund.Amount.Amount,
+		refund.Amount.Currency,
+		refund.ToCredit,
+		refund.Status,
+		refund.Actor,
+		refund.Reason).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// the total of the payment's refunds in any of the given statuses
+func (r *paymentRepositoryImpl) SumRefunds(ctx context.Context, paymentID int, statuses ...models.RefundStatus) (int64, error) {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+	query := `SELECT COALESCE(SUM(amount), 0) FROM payment_refunds WHERE payment_id = $1 AND status = ANY($2)`
+	var total int64
+	if err := conn(ctx, r.db).GetContext(ctx, &total, query, paymentID, pq.Array(names)); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (r *paymentRepositoryImpl) UpdateRefundStatus(ctx context.Context, id int, status models.RefundStatus) error {
+	query := `UPDATE payment_refunds SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, status, id)
+	return err
+}
+
+// transitions without an amount are recorded in the payment's currency
+func transitionCurrency(amount, paymentAmount models.Money) string {
+	if amount.Currency != "" {
+		return amount.Currency
+	}
+	if paymentAmount.Currency != "" {
+		return paymentAmount.Currency
+	}
+	return models.DefaultCurrency
+}
+
 func (r *paymentRepositoryImpl) DeletePayment(id int) error {
 	query := `DELETE FROM payments WHERE id = $1`
 	_, err := r.db.Exec(query, id)
@@ -16,8 +16,16 @@ func (m *MockPaymentRepository) CreatePayment(ctx context.Context, payment model
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockPaymentRepository) GetPaymentByID(id int) (*models.Payment, error) {
-	args := m.Called(id)
+func (m *MockPaymentRepository) GetPaymentByID(ctx context.Context, id int) (*models.Payment, error) {
+	args := m.Called(ctx, id)
+	if payment, ok := args.Get(0).(*models.Payment); ok {
+		return payment, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetPaymentForUpdate(ctx context.Context, id int) (*models.Payment, error) {
+	args := m.Called(ctx, id)
 	if payment, ok := args.Get(0).(*models.Payment); ok {
 		return payment, args.Error(1)
 	}
@@ -56,13 +64,36 @@ func (m *MockPaymentRepository) GetPaymentsByBill(billID int) ([]models.Payment,
 	return nil, args.Error(1)
 }
 
-func (m *MockPaymentRepository) UpdatePaymentStatus(ctx context.Context, payment models.Payment) error {
-	args := m.Called(ctx, payment)
+func (m *MockPaymentRepository) UpdatePaymentStatus(ctx context.Context, payment models.Payment, transition models.PaymentTransition) error {
+	args := m.Called(ctx, payment, transition)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) UpdatePaymentsStatus(ctx context.Context, payments []models.Payment, transition models.PaymentTransition) error {
+	args := m.Called(ctx, payments, transition)
 	return args.Error(0)
 }
 
-func (m *MockPaymentRepository) UpdatePaymentsStatus(ctx context.Context, payments []models.Payment) error {
-	args := m.Called(ctx, payments)
+func (m *MockPaymentRepository) GetPaymentTransitions(paymentID int) ([]models.PaymentTransition, error) {
+	args := m.Called(paymentID)
+	if transitions, ok := args.Get(0).([]models.PaymentTransition); ok {
+		return transitions, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentRepository) CreateRefund(ctx context.Context, refund models.PaymentRefund) (int, error) {
+	args := m.Called(ctx, refund)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) SumRefunds(ctx context.Context, paymentID int, statuses ...models.RefundStatus) (int64, error) {
+	args := m.Called(ctx, paymentID, statuses)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockPaymentRepository) UpdateRefundStatus(ctx context.Context, id int, status models.RefundStatus) error {
+	args := m.Called(ctx, id, status)
 	return args.Error(0)
 }
 
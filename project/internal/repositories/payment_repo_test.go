@@ -8,6 +8,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -25,23 +26,9 @@ func TestNewPaymentRepository(t *testing.T) {
 	db, mock := setupPaymentTestDB(t)
 	defer db.Close()
 
-	t.Run("with autoCreate true", func(t *testing.T) {
-		mock.ExpectExec("CREATE TABLE IF NOT EXISTS payments").WillReturnResult(sqlmock.NewResult(0, 0))
-
-		repo := NewPaymentRepository(true, db)
-		assert.NotNil(t, repo)
-
-		err := mock.ExpectationsWereMet()
-		assert.NoError(t, err)
-	})
-
-	t.Run("with autoCreate false", func(t *testing.T) {
-		repo := NewPaymentRepository(false, db)
-		assert.NotNil(t, repo)
-
-		err := mock.ExpectationsWereMet()
-		assert.NoError(t, err)
-	})
+	repo := NewPaymentRepository(db)
+	assert.NotNil(t, repo)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestPaymentRepository_CreatePayment(t *testing.T) {
@@ -54,7 +41,7 @@ func TestPaymentRepository_CreatePayment(t *testing.T) {
 	payment := models.Payment{
 		BillID:        1,
 		UserID:        1,
-		Amount:        "100.50",
+		Amount:        models.NewMoney(10050, "IRR"),
 		PaidAt:        time.Now(),
 		PaymentStatus: models.Pending,
 	}
@@ -62,7 +49,7 @@ func TestPaymentRepository_CreatePayment(t *testing.T) {
 	t.Run("successful creation", func(t *testing.T) {
 		expectedID := 1
 		mock.ExpectQuery("INSERT INTO payments").
-			WithArgs(payment.BillID, payment.UserID, payment.Amount, payment.PaidAt, payment.PaymentStatus).
+			WithArgs(payment.BillID, payment.UserID, payment.Amount.Amount, payment.Amount.Currency, int64(0), payment.PaidAt, payment.PaymentStatus).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(expectedID))
 
 		id, err := repo.CreatePayment(ctx, payment)
@@ -76,7 +63,7 @@ func TestPaymentRepository_CreatePayment(t *testing.T) {
 
 	t.Run("database error", func(t *testing.T) {
 		mock.ExpectQuery("INSERT INTO payments").
-			WithArgs(payment.BillID, payment.UserID, payment.Amount, payment.PaidAt, payment.PaymentStatus).
+			WithArgs(payment.BillID, payment.UserID, payment.Amount.Amount, payment.Amount.Currency, int64(0), payment.PaidAt, payment.PaymentStatus).
 			WillReturnError(sql.ErrConnDone)
 
 		id, err := repo.CreatePayment(ctx, payment)
@@ -106,20 +93,20 @@ func TestPaymentRepository_GetPaymentByID(t *testing.T) {
 			},
 			BillID:        1,
 			UserID:        1,
-			Amount:        "100.50",
+			Amount:        models.NewMoney(10050, "IRR"),
 			PaidAt:        time.Now(),
 			PaymentStatus: models.Paid,
 		}
 
-		rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount", "paid_at", "payment_status", "created_at", "updated_at"}).
-			AddRow(expectedPayment.ID, expectedPayment.BillID, expectedPayment.UserID, expectedPayment.Amount,
+		rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount.amount", "amount.currency", "paid_at", "payment_status", "created_at", "updated_at"}).
+			AddRow(expectedPayment.ID, expectedPayment.BillID, expectedPayment.UserID, expectedPayment.Amount.Amount, expectedPayment.Amount.Currency,
 				expectedPayment.PaidAt, expectedPayment.PaymentStatus, expectedPayment.CreatedAt, expectedPayment.UpdatedAt)
 
-		mock.ExpectQuery("SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE id = \\$1").
+		mock.ExpectQuery("SELECT id, bill_id, user_id, amount AS \"amount.amount\", currency AS \"amount.currency\", paid_amount AS \"paid_amount.amount\", currency AS \"paid_amount.currency\", late_fee AS \"late_fee.amount\", currency AS \"late_fee.currency\", late_fee_waived, paid_at, payment_status, created_at, updated_at FROM payments WHERE id = \\$1").
 			WithArgs(paymentID).
 			WillReturnRows(rows)
 
-		payment, err := repo.GetPaymentByID(paymentID)
+		payment, err := repo.GetPaymentByID(context.Background(), paymentID)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, payment)
@@ -134,11 +121,11 @@ func TestPaymentRepository_GetPaymentByID(t *testing.T) {
 	})
 
 	t.Run("payment not found", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE id = \\$1").
+		mock.ExpectQuery("SELECT id, bill_id, user_id, amount AS \"amount.amount\", currency AS \"amount.currency\", paid_amount AS \"paid_amount.amount\", currency AS \"paid_amount.currency\", late_fee AS \"late_fee.amount\", currency AS \"late_fee.currency\", late_fee_waived, paid_at, payment_status, created_at, updated_at FROM payments WHERE id = \\$1").
 			WithArgs(paymentID).
 			WillReturnError(sql.ErrNoRows)
 
-		payment, err := repo.GetPaymentByID(paymentID)
+		payment, err := repo.GetPaymentByID(context.Background(), paymentID)
 
 		assert.Error(t, err)
 		assert.Nil(t, payment)
@@ -165,16 +152,16 @@ func TestPaymentRepository_GetPaymentByBillAndUser(t *testing.T) {
 			},
 			BillID:        billID,
 			UserID:        userID,
-			Amount:        "100.50",
+			Amount:        models.NewMoney(10050, "IRR"),
 			PaidAt:        time.Now(),
 			PaymentStatus: models.Paid,
 		}
 
-		rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount", "paid_at", "payment_status", "created_at", "updated_at"}).
-			AddRow(expectedPayment.ID, expectedPayment.BillID, expectedPayment.UserID, expectedPayment.Amount,
+		rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount.amount", "amount.currency", "paid_at", "payment_status", "created_at", "updated_at"}).
+			AddRow(expectedPayment.ID, expectedPayment.BillID, expectedPayment.UserID, expectedPayment.Amount.Amount, expectedPayment.Amount.Currency,
 				expectedPayment.PaidAt, expectedPayment.PaymentStatus, expectedPayment.CreatedAt, expectedPayment.UpdatedAt)
 
-		mock.ExpectQuery("SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE bill_id = \\$1 AND user_id = \\$2").
+		mock.ExpectQuery("SELECT id, bill_id, user_id, amount AS \"amount.amount\", currency AS \"amount.currency\", paid_amount AS \"paid_amount.amount\", currency AS \"paid_amount.currency\", late_fee AS \"late_fee.amount\", currency AS \"late_fee.currency\", late_fee_waived, paid_at, payment_status, created_at, updated_at FROM payments WHERE bill_id = \\$1 AND user_id = \\$2").
 			WithArgs(billID, userID).
 			WillReturnRows(rows)
 
@@ -198,11 +185,11 @@ func TestPaymentRepository_GetPaymentsByUser(t *testing.T) {
 	userID := 1
 
 	t.Run("successful retrieval", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount", "paid_at", "payment_status", "created_at", "updated_at"}).
-			AddRow(1, 1, userID, "100.50", time.Now(), models.Paid, time.Now(), time.Now()).
-			AddRow(2, 2, userID, "200.00", time.Now(), models.Pending, time.Now(), time.Now())
+		rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount.amount", "amount.currency", "paid_at", "payment_status", "created_at", "updated_at"}).
+			AddRow(1, 1, userID, 10050, "IRR", time.Now(), models.Paid, time.Now(), time.Now()).
+			AddRow(2, 2, userID, 20000, "IRR", time.Now(), models.Pending, time.Now(), time.Now())
 
-		mock.ExpectQuery("SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE user_id = \\$1").
+		mock.ExpectQuery("SELECT id, bill_id, user_id, amount AS \"amount.amount\", currency AS \"amount.currency\", paid_amount AS \"paid_amount.amount\", currency AS \"paid_amount.currency\", late_fee AS \"late_fee.amount\", currency AS \"late_fee.currency\", late_fee_waived, paid_at, payment_status, created_at, updated_at FROM payments WHERE user_id = \\$1").
 			WithArgs(userID).
 			WillReturnRows(rows)
 
@@ -218,9 +205,9 @@ func TestPaymentRepository_GetPaymentsByUser(t *testing.T) {
 	})
 
 	t.Run("no payments found", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount", "paid_at", "payment_status", "created_at", "updated_at"})
+		rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount.amount", "amount.currency", "paid_at", "payment_status", "created_at", "updated_at"})
 
-		mock.ExpectQuery("SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE user_id = \\$1").
+		mock.ExpectQuery("SELECT id, bill_id, user_id, amount AS \"amount.amount\", currency AS \"amount.currency\", paid_amount AS \"paid_amount.amount\", currency AS \"paid_amount.currency\", late_fee AS \"late_fee.amount\", currency AS \"late_fee.currency\", late_fee_waived, paid_at, payment_status, created_at, updated_at FROM payments WHERE user_id = \\$1").
 			WithArgs(userID).
 			WillReturnRows(rows)
 
@@ -234,6 +221,22 @@ func TestPaymentRepository_GetPaymentsByUser(t *testing.T) {
 	})
 }
 
+func expectPaymentTransition(mock sqlmock.Sqlmock, payment models.Payment, from models.PaymentStatus, actor string) {
+	expectPaymentTransitionWithAmount(mock, payment, from, 0, 0, actor)
+}
+
+func expectPaymentTransitionWithAmount(mock sqlmock.Sqlmock, payment models.Payment, from models.PaymentStatus, paidBefore, amount int64, actor string) {
+	mock.ExpectQuery("SELECT payment_status, paid_amount FROM payments WHERE id = \\$1 FOR UPDATE").
+		WithArgs(payment.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_status", "paid_amount"}).AddRow(from, paidBefore))
+	mock.ExpectExec("UPDATE payments SET").
+		WithArgs(payment.PaymentStatus, payment.PaymentStatus, payment.PaidAt, payment.PaymentStatus, payment.PaidAmount.Amount, payment.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO payment_transitions").
+		WithArgs(payment.ID, from, payment.PaymentStatus, amount, "IRR", actor, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
 func TestPaymentRepository_UpdatePaymentStatus(t *testing.T) {
 	db, mock := setupPaymentTestDB(t)
 	defer db.Close()
@@ -246,13 +249,64 @@ func TestPaymentRepository_UpdatePaymentStatus(t *testing.T) {
 		PaymentStatus: models.Paid,
 		PaidAt:        time.Now(),
 	}
+	transition := models.PaymentTransition{Actor: "provider:fake"}
 
 	t.Run("successful update", func(t *testing.T) {
-		mock.ExpectExec("UPDATE payments SET").
-			WithArgs(payment.PaymentStatus, payment.PaidAt, payment.ID).
-			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectBegin()
+		expectPaymentTransition(mock, payment, models.Processing, "provider:fake")
+		mock.ExpectCommit()
+
+		err := repo.UpdatePaymentStatus(ctx, payment, transition)
+
+		assert.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+
+	t.Run("installment records the amount paid", func(t *testing.T) {
+		partial := models.Payment{
+			BaseModel:     models.BaseModel{ID: 1},
+			Amount:        models.NewMoney(10000, "IRR"),
+			PaidAmount:    models.NewMoney(7000, "IRR"),
+			PaymentStatus: models.PartiallyPaid,
+		}
+
+		mock.ExpectBegin()
+		expectPaymentTransitionWithAmount(mock, partial, models.Processing, 4000, 3000, "provider:fake")
+		mock.ExpectCommit()
 
-		err := repo.UpdatePaymentStatus(ctx, payment)
+		err := repo.UpdatePaymentStatus(ctx, partial, transition)
+
+		assert.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+
+	t.Run("illegal transition", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT payment_status, paid_amount FROM payments WHERE id = \\$1 FOR UPDATE").
+			WithArgs(payment.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"payment_status", "paid_amount"}).AddRow(models.Refunded, 0))
+		mock.ExpectRollback()
+
+		err := repo.UpdatePaymentStatus(ctx, payment, transition)
+
+		assert.ErrorIs(t, err, models.ErrInvalidPaymentTransition)
+
+		err = mock.ExpectationsWereMet()
+		assert.NoError(t, err)
+	})
+
+	t.Run("same status is a no-op", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT payment_status, paid_amount FROM payments WHERE id = \\$1 FOR UPDATE").
+			WithArgs(payment.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"payment_status", "paid_amount"}).AddRow(models.Paid, 0))
+		mock.ExpectCommit()
+
+		err := repo.UpdatePaymentStatus(ctx, payment, transition)
 
 		assert.NoError(t, err)
 
@@ -261,11 +315,13 @@ func TestPaymentRepository_UpdatePaymentStatus(t *testing.T) {
 	})
 
 	t.Run("database error", func(t *testing.T) {
-		mock.ExpectExec("UPDATE payments SET").
-			WithArgs(payment.PaymentStatus, payment.PaidAt, payment.ID).
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT payment_status, paid_amount FROM payments WHERE id = \\$1 FOR UPDATE").
+			WithArgs(payment.ID).
 			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
 
-		err := repo.UpdatePaymentStatus(ctx, payment)
+		err := repo.UpdatePaymentStatus(ctx, payment, transition)
 
 		assert.Error(t, err)
 		assert.Equal(t, sql.ErrConnDone, err)
@@ -285,28 +341,22 @@ func TestPaymentRepository_UpdatePaymentsStatus(t *testing.T) {
 	payments := []models.Payment{
 		{
 			BaseModel:     models.BaseModel{ID: 1},
-			PaymentStatus: models.Paid,
-			PaidAt:        time.Now(),
+			PaymentStatus: models.Processing,
 		},
 		{
 			BaseModel:     models.BaseModel{ID: 2},
-			PaymentStatus: models.Paid,
-			PaidAt:        time.Now(),
+			PaymentStatus: models.Processing,
 		},
 	}
+	transition := models.PaymentTransition{Actor: "user:3"}
 
 	t.Run("successful batch update", func(t *testing.T) {
 		mock.ExpectBegin()
-
-		for _, payment := range payments {
-			mock.ExpectExec("UPDATE payments SET").
-				WithArgs(payment.PaymentStatus, payment.PaidAt, payment.ID).
-				WillReturnResult(sqlmock.NewResult(0, 1))
-		}
-
+		expectPaymentTransition(mock, payments[0], models.Pending, "user:3")
+		expectPaymentTransition(mock, payments[1], models.Failed, "user:3")
 		mock.ExpectCommit()
 
-		err := repo.UpdatePaymentsStatus(ctx, payments)
+		err := repo.UpdatePaymentsStatus(ctx, payments, transition)
 
 		assert.NoError(t, err)
 
@@ -316,18 +366,18 @@ func TestPaymentRepository_UpdatePaymentsStatus(t *testing.T) {
 
 	t.Run("transaction rollback on error", func(t *testing.T) {
 		mock.ExpectBegin()
+		expectPaymentTransition(mock, payments[0], models.Pending, "user:3")
 
+		mock.ExpectQuery("SELECT payment_status, paid_amount FROM payments WHERE id = \\$1 FOR UPDATE").
+			WithArgs(payments[1].ID).
+			WillReturnRows(sqlmock.NewRows([]string{"payment_status", "paid_amount"}).AddRow(models.Pending, 0))
 		mock.ExpectExec("UPDATE payments SET").
-			WithArgs(payments[0].PaymentStatus, payments[0].PaidAt, payments[0].ID).
-			WillReturnResult(sqlmock.NewResult(0, 1))
-
-		mock.ExpectExec("UPDATE payments SET").
-			WithArgs(payments[1].PaymentStatus, payments[1].PaidAt, payments[1].ID).
+			WithArgs(payments[1].PaymentStatus, payments[1].PaymentStatus, payments[1].PaidAt, payments[1].PaymentStatus, int64(0), payments[1].ID).
 			WillReturnError(sql.ErrConnDone)
 
 		mock.ExpectRollback()
 
-		err := repo.UpdatePaymentsStatus(ctx, payments)
+		err := repo.UpdatePaymentsStatus(ctx, payments, transition)
 
 		assert.Error(t, err)
 		assert.Equal(t, sql.ErrConnDone, err)
@@ -337,6 +387,99 @@ func TestPaymentRepository_UpdatePaymentsStatus(t *testing.T) {
 	})
 }
 
+func TestPaymentRepository_GetPaymentTransitions(t *testing.T) {
+	db, mock := setupPaymentTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "payment_id", "from_status", "to_status", "amount.amount", "amount.currency", "actor", "reason", "created_at"}).
+		AddRow(1, 5, "pending", "processing", 0, "IRR", "user:3", "", now).
+		AddRow(2, 5, "paid", "partially_refunded", 2500, "IRR", "user:1", "overcharged", now)
+	mock.ExpectQuery("SELECT (.+) FROM payment_transitions WHERE payment_id = \\$1 ORDER BY id").
+		WithArgs(5).
+		WillReturnRows(rows)
+
+	repo := &paymentRepositoryImpl{db: db}
+	transitions, err := repo.GetPaymentTransitions(5)
+
+	assert.NoError(t, err)
+	assert.Len(t, transitions, 2)
+	assert.Equal(t, models.PartiallyRefunded, transitions[1].ToStatus)
+	assert.Equal(t, models.NewMoney(2500, "IRR"), transitions[1].Amount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPaymentRepository_GetPaymentForUpdate(t *testing.T) {
+	db, mock := setupPaymentTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount.amount", "amount.currency",
+		"paid_amount.amount", "paid_amount.currency", "late_fee.amount", "late_fee.currency", "late_fee_waived",
+		"paid_at", "payment_status", "created_at", "updated_at"}).
+		AddRow(5, 9, 2, 10000, "IRR", 10000, "IRR", 0, "IRR", false, now, "paid", now, now)
+	mock.ExpectQuery("SELECT (.+) FROM payments WHERE id = \\$1 FOR UPDATE").
+		WithArgs(5).
+		WillReturnRows(rows)
+
+	repo := &paymentRepositoryImpl{db: db}
+	payment, err := repo.GetPaymentForUpdate(context.Background(), 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.Paid, payment.PaymentStatus)
+	assert.Equal(t, models.NewMoney(10000, "IRR"), payment.PaidAmount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPaymentRepository_Refunds(t *testing.T) {
+	db, mock := setupPaymentTestDB(t)
+	defer db.Close()
+
+	repo := &paymentRepositoryImpl{db: db}
+	ctx := context.Background()
+
+	t.Run("create refund", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO payment_refunds").
+			WithArgs(5, int64(4000), "IRR", false, models.RefundPending, "user:1", "overcharged").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+		id, err := repo.CreateRefund(ctx, models.PaymentRefund{
+			PaymentID: 5,
+			Amount:    models.NewMoney(4000, "IRR"),
+			Status:    models.RefundPending,
+			Actor:     "user:1",
+			Reason:    "overcharged",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("sum refunds", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(amount\\), 0\\) FROM payment_refunds WHERE payment_id = \\$1 AND status = ANY\\(\\$2\\)").
+			WithArgs(5, pq.Array([]string{"pending", "completed"})).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(7000))
+
+		total, err := repo.SumRefunds(ctx, 5, models.RefundPending, models.RefundCompleted)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7000), total)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("update refund status", func(t *testing.T) {
+		mock.ExpectExec("UPDATE payment_refunds SET status = \\$1").
+			WithArgs(models.RefundFailed, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.UpdateRefundStatus(ctx, 3, models.RefundFailed)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestPaymentRepository_DeletePayment(t *testing.T) {
 	db, mock := setupPaymentTestDB(t)
 	defer db.Close()
@@ -381,10 +524,10 @@ func TestPaymentRepository_GetPendingPaymentsByUser(t *testing.T) {
 
 	t.Run("successful retrieval", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{
-			"id", "bill_id", "user_id", "amount", "paid_at", "payment_status", "created_at", "updated_at",
-		}).AddRow(1, 1, userID, "50.00", time.Now(), models.Pending, time.Now(), time.Now())
+			"id", "bill_id", "user_id", "amount.amount", "amount.currency", "paid_at", "payment_status", "created_at", "updated_at",
+		}).AddRow(1, 1, userID, 5000, "IRR", time.Now(), models.Pending, time.Now(), time.Now())
 
-		mock.ExpectQuery("SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE user_id = \\$1 and payment_status = 'pending'").
+		mock.ExpectQuery("SELECT id, bill_id, user_id, amount AS \"amount.amount\", currency AS \"amount.currency\", paid_amount AS \"paid_amount.amount\", currency AS \"paid_amount.currency\", late_fee AS \"late_fee.amount\", currency AS \"late_fee.currency\", late_fee_waived, paid_at, payment_status, created_at, updated_at FROM payments WHERE user_id = \\$1 and payment_status IN \\('pending', 'failed', 'partially_paid'\\)").
 			WithArgs(userID).
 			WillReturnRows(rows)
 
@@ -400,10 +543,10 @@ func TestPaymentRepository_GetPendingPaymentsByUser(t *testing.T) {
 
 	t.Run("no pending payments", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{
-			"id", "bill_id", "user_id", "amount", "paid_at", "payment_status", "created_at", "updated_at",
+			"id", "bill_id", "user_id", "amount.amount", "amount.currency", "paid_at", "payment_status", "created_at", "updated_at",
 		})
 
-		mock.ExpectQuery("SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE user_id = \\$1 and payment_status = 'pending'").
+		mock.ExpectQuery("SELECT id, bill_id, user_id, amount AS \"amount.amount\", currency AS \"amount.currency\", paid_amount AS \"paid_amount.amount\", currency AS \"paid_amount.currency\", late_fee AS \"late_fee.amount\", currency AS \"late_fee.currency\", late_fee_waived, paid_at, payment_status, created_at, updated_at FROM payments WHERE user_id = \\$1 and payment_status IN \\('pending', 'failed', 'partially_paid'\\)").
 			WithArgs(userID).
 			WillReturnRows(rows)
 
@@ -426,10 +569,10 @@ func TestPaymentRepository_GetPaymentsByBill(t *testing.T) {
 
 	t.Run("successful retrieval", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{
-			"id", "bill_id", "user_id", "amount", "paid_at", "payment_status", "created_at", "updated_at",
-		}).AddRow(1, billID, 1, "75.00", time.Now(), models.Paid, time.Now(), time.Now())
+			"id", "bill_id", "user_id", "amount.amount", "amount.currency", "paid_at", "payment_status", "created_at", "updated_at",
+		}).AddRow(1, billID, 1, 7500, "IRR", time.Now(), models.Paid, time.Now(), time.Now())
 
-		mock.ExpectQuery("SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE bill_id = \\$1").
+		mock.ExpectQuery("SELECT id, bill_id, user_id, amount AS \"amount.amount\", currency AS \"amount.currency\", paid_amount AS \"paid_amount.amount\", currency AS \"paid_amount.currency\", late_fee AS \"late_fee.amount\", currency AS \"late_fee.currency\", late_fee_waived, paid_at, payment_status, created_at, updated_at FROM payments WHERE bill_id = \\$1").
 			WithArgs(billID).
 			WillReturnRows(rows)
 
@@ -445,10 +588,10 @@ func TestPaymentRepository_GetPaymentsByBill(t *testing.T) {
 
 	t.Run("no payments for bill", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{
-			"id", "bill_id", "user_id", "amount", "paid_at", "payment_status", "created_at", "updated_at",
+			"id", "bill_id", "user_id", "amount.amount", "amount.currency", "paid_at", "payment_status", "created_at", "updated_at",
 		})
 
-		mock.ExpectQuery("SELECT id, bill_id, user_id, amount, paid_at, payment_status, created_at, updated_at FROM payments WHERE bill_id = \\$1").
+		mock.ExpectQuery("SELECT id, bill_id, user_id, amount AS \"amount.amount\", currency AS \"amount.currency\", paid_amount AS \"paid_amount.amount\", currency AS \"paid_amount.currency\", late_fee AS \"late_fee.amount\", currency AS \"late_fee.currency\", late_fee_waived, paid_at, payment_status, created_at, updated_at FROM payments WHERE bill_id = \\$1").
 			WithArgs(billID).
 			WillReturnRows(rows)
 
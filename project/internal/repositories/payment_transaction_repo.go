@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+const (
+	paymentTransactionColumns = `id, user_id, payment_ids, payment_amounts, provider, COALESCE(reference, ''), COALESCE(redirect_url, ''),
+		amount, currency, refunded_amount, status, COALESCE(tracking_code, ''), idempotency_key, created_at, updated_at`
+)
+
+type PaymentTransactionRepository interface {
+	CreateTransaction(ctx context.Context, transaction models.PaymentTransaction) (int, error)
+	GetTransactionByID(ctx context.Context, id int) (*models.PaymentTransaction, error)
+	GetTransactionByReference(ctx context.Context, provider, reference string) (*models.PaymentTransaction, error)
+	GetTransactionByIdempotencyKey(ctx context.Context, userID int, idempotencyKey string) (*models.PaymentTransaction, error)
+	GetPaidTransactionByPayment(ctx context.Context, paymentID int) (*models.PaymentTransaction, error)
+	GetStaleTransactions(ctx context.Context, before time.Time) ([]models.PaymentTransaction, error)
+	SetTransactionReference(ctx context.Context, id int, reference, redirectURL string) error
+	UpdateTransactionStatus(ctx context.Context, transaction models.PaymentTransaction) error
+	ExpireTransaction(ctx context.Context, id int) (bool, error)
+}
+
+type paymentTransactionRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewPaymentTransactionRepository(db *sqlx.DB) PaymentTransactionRepository {
+	return &paymentTransactionRepositoryImpl{db: db}
+}
+
+func (r *paymentTransactionRepositoryImpl) CreateTransaction(ctx context.Context, transaction models.PaymentTransaction) (int, error) {
+	query := `INSERT INTO payment_transactions (user_id, payment_ids, payment_amounts, provider, amount, currency, status, idempotency_key)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	paymentAmounts := make([]int64, len(transaction.PaymentAmounts))
+	for i, amount := range transaction.PaymentAmounts {
+		paymentAmounts[i] = amount.Amount
+	}
+	var id int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		transaction.UserID,
+		pq.Array(toInt64s(transaction.PaymentIDs)),
+		pq.Array(paymentAmounts),
+		transaction.Provider,
+		transaction.Amount.Amount,
+		transaction.Amount.Currency,
+		transaction.Status,
+		transaction.IdempotencyKey,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *paymentTransactionRepositoryImpl) GetTransactionByID(ctx context.Context, id int) (*models.PaymentTransaction, error) {
+	query := `SELECT ` + paymentTransactionColumns + ` FROM payment_transactions WHERE id = $1`
+	return scanPaymentTransaction(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+}
+
+func (r *paymentTransactionRepositoryImpl) GetTransactionByReference(ctx context.Context, provider, reference string) (*models.PaymentTransaction, error) {
+	query := `SELECT ` + paymentTransactionColumns + ` FROM payment_transactions WHERE provider = $1 AND reference = $2`
+	return scanPaymentTransaction(conn(ctx, r.db).QueryRowContext(ctx, query, provider, reference))
+}
+
+func (r *paymentTransactionRepositoryImpl) GetTransactionByIdempotencyKey(ctx context.Context, userID int, idempotencyKey string) (*models.PaymentTransaction, error) {
+	query := `SELECT ` + paymentTransactionColumns + ` FROM payment_transactions WHERE user_id = $1 AND idempotency_key = $2`
+	return scanPaymentTransaction(conn(ctx, r.db).QueryRowContext(ctx, query, userID, idempotencyKey))
+}
+
+// the successful transaction that paid the given payment
+func (r *paymentTransactionRepositoryImpl) GetPaidTransactionByPayment(ctx context.Context, paymentID int) (*models.PaymentTransaction, error) {
+	query := `SELECT ` + paymentTransactionColumns + ` FROM payment_transactions
+			  WHERE $1 = ANY(payment_ids) AND status IN ('succeeded', 'partially_refunded')
+			  ORDER BY id DESC LIMIT 1`
+	return scanPaymentTransaction(conn(ctx, r.db).QueryRowContext(ctx, query, paymentID))
+}
+
+// the transactions started before the given time that are still waiting for
+// their provider
+func (r *paymentTransactionRepositoryImpl) GetStaleTransactions(ctx context.Context, before time.Time) ([]models.PaymentTransaction, error) {
+	query := `SELECT ` + paymentTransactionColumns + ` FROM payment_transactions
+			  WHERE status = 'initiated' AND created_at < $1 ORDER BY id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []models.PaymentTransaction
+	for rows.Next() {
+		transaction, err := scanPaymentTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, *transaction)
+	}
+	return transactions, rows.Err()
+}
+
+func (r *paymentTransactionRepositoryImpl) SetTransactionReference(ctx context.Context, id int, reference, redirectURL string) error {
+	query := `UPDATE payment_transactions SET reference = $1, redirect_url = $2, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $3`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, reference, redirectURL, id)
+	if err != nil {
+		return err
+	}
+	return checkTransactionUpdated(result, id)
+}
+
+func (r *paymentTransactionRepositoryImpl) UpdateTransactionStatus(ctx context.Context, transaction models.PaymentTransaction) error {
+	query := `UPDATE payment_transactions SET status = $1, tracking_code = $2, refunded_amount = $3,
+			  updated_at = CURRENT_TIMESTAMP WHERE id = $4`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query,
+		transaction.Status,
+		transaction.TrackingCode,
+		transaction.RefundedAmount.Amount,
+		transaction.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return checkTransactionUpdated(result, transaction.ID)
+}
+
+// moves a transaction that is still initiated to expired. false means its
+// provider settled it first
+func (r *paymentTransactionRepositoryImpl) ExpireTransaction(ctx context.Context, id int) (bool, error) {
+	query := `UPDATE payment_transactions SET status = 'expired', updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND status = 'initiated'`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPaymentTransaction(row rowScanner) (*models.PaymentTransaction, error) {
+	var transaction models.PaymentTransaction
+	var paymentIDs, paymentAmounts pq.Int64Array
+	err := row.Scan(
+		&transaction.ID,
+		&transaction.UserID,
+		&paymentIDs,
+		&paymentAmounts,
+		&transaction.Provider,
+		&transaction.Reference,
+		&transaction.RedirectURL,
+		&transaction.Amount.Amount,
+		&transaction.Amount.Currency,
+		&transaction.RefundedAmount.Amount,
+		&transaction.Status,
+		&transaction.TrackingCode,
+		&transaction.IdempotencyKey,
+		&transaction.CreatedAt,
+		&transaction.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.RefundedAmount.Currency = transaction.Amount.Currency
+	for _, id := range paymentIDs {
+		transaction.PaymentIDs = append(transaction.PaymentIDs, int(id))
+	}
+	for _, amount := range paymentAmounts {
+		transaction.PaymentAmounts = append(transaction.PaymentAmounts, models.NewMoney(amount, transaction.Amount.Currency))
+	}
+	return &transaction, nil
+}
+
+func checkTransactionUpdated(result sql.Result, id int) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("no payment transaction found with id %d", id)
+	}
+	return nil
+}
+
+func toInt64s(ids []int) []int64 {
+	result := make([]int64, len(ids))
+	for i, id := range ids {
+		result[i] = int64(id)
+	}
+	return result
+}
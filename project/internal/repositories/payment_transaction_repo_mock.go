@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPaymentTransactionRepository struct {
+	mock.Mock
+}
+
+func (m *MockPaymentTransactionRepository) CreateTransaction(ctx context.Context, transaction models.PaymentTransaction) (int, error) {
+	args := m.Called(ctx, transaction)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) GetTransactionByID(ctx context.Context, id int) (*models.PaymentTransaction, error) {
+	args := m.Called(ctx, id)
+	if transaction, ok := args.Get(0).(*models.PaymentTransaction); ok {
+		return transaction, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) GetTransactionByReference(ctx context.Context, provider, reference string) (*models.PaymentTransaction, error) {
+	args := m.Called(ctx, provider, reference)
+	if transaction, ok := args.Get(0).(*models.PaymentTransaction); ok {
+		return transaction, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) GetTransactionByIdempotencyKey(ctx context.Context, userID int, idempotencyKey string) (*models.PaymentTransaction, error) {
+	args := m.Called(ctx, userID, idempotencyKey)
+	if transaction, ok := args.Get(0).(*models.PaymentTransaction); ok {
+		return transaction, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) GetPaidTransactionByPayment(ctx context.Context, paymentID int) (*models.PaymentTransaction, error) {
+	args := m.Called(ctx, paymentID)
+	if transaction, ok := args.Get(0).(*models.PaymentTransaction); ok {
+		return transaction, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) SetTransactionReference(ctx context.Context, id int, reference, redirectURL string) error {
+	args := m.Called(ctx, id, reference, redirectURL)
+	return args.Error(0)
+}
+
+func (m *MockPaymentTransactionRepository) UpdateTransactionStatus(ctx context.Context, transaction models.PaymentTransaction) error {
+	args := m.Called(ctx, transaction)
+	return args.Error(0)
+}
+
+func (m *MockPaymentTransactionRepository) GetStaleTransactions(ctx context.Context, before time.Time) ([]models.PaymentTransaction, error) {
+	args := m.Called(ctx, before)
+	if transactions, ok := args.Get(0).([]models.PaymentTransaction); ok {
+		return transactions, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentTransactionRepository) ExpireTransaction(ctx context.Context, id int) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,191 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var paymentTransactionRowColumns = []string{
+	"id", "user_id", "payment_ids", "payment_amounts", "provider", "reference", "redirect_url", "amount", "currency",
+	"refunded_amount", "status", "tracking_code", "idempotency_key", "created_at", "updated_at",
+}
+
+func TestNewPaymentTransactionRepository(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewPaymentTransactionRepository(db)
+	assert.NotNil(t, repo)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPaymentTransactionRepository_CreateTransaction(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	transaction := models.PaymentTransaction{
+		UserID:         1,
+		PaymentIDs:     []int{4, 5},
+		PaymentAmounts: []models.Money{models.NewMoney(10000, "IRR"), models.NewMoney(15000, "IRR")},
+		Provider:       "fake",
+		Amount:         models.NewMoney(25000, "IRR"),
+		Status:         models.TransactionInitiated,
+		IdempotencyKey: "key-1",
+	}
+
+	mock.ExpectQuery("INSERT INTO payment_transactions").
+		WithArgs(1, pq.Array([]int64{4, 5}), pq.Array([]int64{10000, 15000}), "fake", int64(25000), "IRR", models.TransactionInitiated, "key-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+
+	repo := &paymentTransactionRepositoryImpl{db: db}
+	id, err := repo.CreateTransaction(context.Background(), transaction)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 8, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPaymentTransactionRepository_GetTransactionByReference(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		want      *models.PaymentTransaction
+		wantErr   bool
+	}{
+		{
+			name: "found",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(paymentTransactionRowColumns).
+					AddRow(8, 1, "{4,5}", "{10000,15000}", "fake", "ref-1", "http://pay", 25000, "IRR", 0, "initiated", "", "key-1", now, now)
+				mock.ExpectQuery(`SELECT (.+) FROM payment_transactions WHERE provider = \$1 AND reference = \$2`).
+					WithArgs("fake", "ref-1").
+					WillReturnRows(rows)
+			},
+			want: &models.PaymentTransaction{
+				BaseModel:      models.BaseModel{ID: 8, CreatedAt: now, UpdatedAt: now},
+				UserID:         1,
+				PaymentIDs:     []int{4, 5},
+				PaymentAmounts: []models.Money{models.NewMoney(10000, "IRR"), models.NewMoney(15000, "IRR")},
+				Provider:       "fake",
+				Reference:      "ref-1",
+				RedirectURL:    "http://pay",
+				Amount:         models.NewMoney(25000, "IRR"),
+				RefundedAmount: models.NewMoney(0, "IRR"),
+				Status:         models.TransactionInitiated,
+				IdempotencyKey: "key-1",
+			},
+		},
+		{
+			name: "not found",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT (.+) FROM payment_transactions WHERE provider = \$1 AND reference = \$2`).
+					WithArgs("fake", "ref-1").
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupTestDB(t)
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			repo := &paymentTransactionRepositoryImpl{db: db}
+			transaction, err := repo.GetTransactionByReference(context.Background(), "fake", "ref-1")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, transaction)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPaymentTransactionRepository_UpdateTransactionStatus(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	transaction := models.PaymentTransaction{
+		BaseModel:      models.BaseModel{ID: 8},
+		Status:         models.TransactionSucceeded,
+		TrackingCode:   "FAKE-1",
+		RefundedAmount: models.NewMoney(0, "IRR"),
+	}
+
+	mock.ExpectExec(`UPDATE payment_transactions SET status = \$1`).
+		WithArgs(models.TransactionSucceeded, "FAKE-1", int64(0), 8).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &paymentTransactionRepositoryImpl{db: db}
+	err := repo.UpdateTransactionStatus(context.Background(), transaction)
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPaymentTransactionRepository_GetStaleTransactions(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	before := time.Now().Add(-time.Hour)
+	created := before.Add(-time.Minute)
+	rows := sqlmock.NewRows(paymentTransactionRowColumns).
+		AddRow(8, 1, "{4}", "{25000}", "fake", "ref-1", "http://pay", 25000, "IRR", 0, "initiated", "", "key-1", created, created)
+	mock.ExpectQuery(`SELECT (.+) FROM payment_transactions\s+WHERE status = 'initiated' AND created_at < \$1`).
+		WithArgs(before).
+		WillReturnRows(rows)
+
+	repo := &paymentTransactionRepositoryImpl{db: db}
+	transactions, err := repo.GetStaleTransactions(context.Background(), before)
+
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, 8, transactions[0].ID)
+	assert.Equal(t, []int{4}, transactions[0].PaymentIDs)
+	assert.Equal(t, models.TransactionInitiated, transactions[0].Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPaymentTransactionRepository_ExpireTransaction(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		want         bool
+	}{
+		{name: "initiated transaction expires", rowsAffected: 1, want: true},
+		{name: "settled transaction is left alone", rowsAffected: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupTestDB(t)
+			defer db.Close()
+
+			mock.ExpectExec(`UPDATE payment_transactions SET status = 'expired'(.+)WHERE id = \$1 AND status = 'initiated'`).
+				WithArgs(8).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			repo := &paymentTransactionRepositoryImpl{db: db}
+			expired, err := repo.ExpireTransaction(context.Background(), 8)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, expired)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+type ReminderRepository interface {
+	GetReminderCandidates(ctx context.Context, dueBy time.Time, lastOffset int) ([]models.ReminderCandidate, error)
+	RecordReminder(ctx context.Context, paymentID, offsetDays int) (bool, error)
+}
+
+type reminderRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewReminderRepository(db *sqlx.DB) ReminderRepository {
+	return &reminderRepositoryImpl{db: db}
+}
+
+// unpaid payments whose bill is due by dueBy, of residents who didn't turn
+// reminders off and who weren't sent the reminder at lastOffset yet
+func (r *reminderRepositoryImpl) GetReminderCandidates(ctx context.Context, dueBy time.Time, lastOffset int) ([]models.ReminderCandidate, error) {
+	var candidates []models.ReminderCandidate
+	query := `SELECT p.id, p.bill_id, p.user_id, p.amount AS "amount.amount", p.currency AS "amount.currency",
+			  p.paid_amount AS "paid_amount.amount", p.currency AS "paid_amount.currency",
+			  p.late_fee AS "late_fee.amount", p.currency AS "late_fee.currency", p.late_fee_waived,
+			  p.paid_at, p.payment_status, p.created_at, p.updated_at, b.apartment_id, b.bill_type,
+			  TO_CHAR(b.due_date, 'YYYY-MM-DD') AS due_date,
+			  TO_CHAR(COALESCE(b.billing_deadline, b.due_date), 'YYYY-MM-DD') AS deadline,
+			  (SELECT MAX(offset_days) FROM payment_reminders r WHERE r.payment_id = p.id) AS last_offset
+			  FROM payments p JOIN bills b ON b.id = p.bill_id JOIN users u ON u.id = p.user_id
+			  WHERE p.payment_status IN ('pending', 'failed', 'partially_paid') AND u.bill_reminders
+			  AND b.due_date <= $1
+			  AND NOT EXISTS (SELECT 1 FROM payment_reminders r WHERE r.payment_id = p.id AND r.offset_days >= $2)`
+	err := conn(ctx, r.db).SelectContext(ctx, &candidates, query, dueBy, lastOffset)
+	if err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// records that the reminder at offsetDays was sent for the payment. false
+// when it already was, by another run or instance
+func (r *reminderRepositoryImpl) RecordReminder(ctx context.Context, paymentID, offsetDays int) (bool, error) {
+	query := `INSERT INTO payment_reminders (payment_id, offset_days) VALUES ($1, $2)
+			  ON CONFLICT (payment_id, offset_days) DO NOTHING`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, paymentID, offsetDays)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReminderRepository struct {
+	mock.Mock
+}
+
+func (m *MockReminderRepository) GetReminderCandidates(ctx context.Context, dueBy time.Time, lastOffset int) ([]models.ReminderCandidate, error) {
+	args := m.Called(ctx, dueBy, lastOffset)
+	if candidates, ok := args.Get(0).([]models.ReminderCandidate); ok {
+		return candidates, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReminderRepository) RecordReminder(ctx context.Context, paymentID, offsetDays int) (bool, error) {
+	args := m.Called(ctx, paymentID, offsetDays)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReminderRepository_GetReminderCandidates(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	dueBy := time.Date(2025, 3, 8, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "bill_id", "user_id", "amount.amount", "amount.currency", "payment_status",
+		"apartment_id", "bill_type", "due_date", "deadline", "last_offset"}).
+		AddRow(1, 3, 7, 50000, "IRR", "pending", 2, "water", "2025-03-08", "2025-03-10", nil).
+		AddRow(2, 3, 8, 50000, "IRR", "partially_paid", 2, "water", "2025-03-08", "2025-03-10", -7)
+	mock.ExpectQuery(`SELECT (.+) FROM payments p JOIN bills b ON b.id = p.bill_id JOIN users u ON u.id = p.user_id(.+)u.bill_reminders`).
+		WithArgs(dueBy, 3).
+		WillReturnRows(rows)
+
+	repo := NewReminderRepository(db)
+	candidates, err := repo.GetReminderCandidates(context.Background(), dueBy, 3)
+
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 2)
+	assert.Nil(t, candidates[0].LastOffset)
+	assert.Equal(t, "2025-03-10", candidates[0].Deadline)
+	if assert.NotNil(t, candidates[1].LastOffset) {
+		assert.Equal(t, -7, *candidates[1].LastOffset)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReminderRepository_RecordReminder(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+	repo := NewReminderRepository(db)
+
+	mock.ExpectExec(`INSERT INTO payment_reminders (.+) ON CONFLICT \(payment_id, offset_days\) DO NOTHING`).
+		WithArgs(1, 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO payment_reminders`).
+		WithArgs(1, 0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	recorded, err := repo.RecordReminder(context.Background(), 1, 0)
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+
+	recorded, err = repo.RecordReminder(context.Background(), 1, 0)
+	assert.NoError(t, err)
+	assert.False(t, recorded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+const (
+	//one row per payment with what it adds to each total. refunds are taken
+	//from the transitions that recorded them, a chargeback takes back all of
+	//it, and only shares still being paid count as outstanding
+	reportPaymentTotals = `SELECT p.id, p.bill_id, p.user_id, p.late_fee,
+			  p.amount + p.late_fee AS billed,
+			  CASE WHEN p.payment_status = 'chargeback' THEN 0
+				   ELSE p.paid_amount - COALESCE((SELECT SUM(t.amount) FROM payment_transitions t
+					   WHERE t.payment_id = p.id AND t.to_status IN ('partially_refunded', 'refunded')), 0)
+			  END AS collected,
+			  CASE WHEN p.payment_status IN ('pending', 'processing', 'failed', 'partially_paid')
+				   THEN p.amount + p.late_fee - p.paid_amount ELSE 0
+			  END AS outstanding
+			  FROM payments p`
+
+	//bills that were never divided still count as billed
+	reportByBillQuery = `SELECT %[1]s AS key, %[1]s AS label,
+			  SUM(b.total_amount + COALESCE(pt.late_fee, 0)) AS "billed.amount", MAX(b.currency) AS "billed.currency",
+			  COALESCE(SUM(pt.collected), 0) AS "collected.amount", MAX(b.currency) AS "collected.currency",
+			  COALESCE(SUM(pt.outstanding), 0) AS "outstanding.amount", MAX(b.currency) AS "outstanding.currency"
+			  FROM bills b
+			  LEFT JOIN (
+				  SELECT bill_id, SUM(late_fee) AS late_fee, SUM(collected) AS collected, SUM(outstanding) AS outstanding
+				  FROM (` + reportPaymentTotals + `) payment_totals GROUP BY bill_id
+			  ) pt ON pt.bill_id = b.id
+			  WHERE b.apartment_id = $1 AND b.due_date >= $2 AND b.due_date < $3
+			  GROUP BY 1 ORDER BY 1`
+
+	reportByResidentQuery = `SELECT pt.user_id::TEXT AS key, u.username AS label,
+			  SUM(pt.billed) AS "billed.amount", MAX(b.currency) AS "billed.currency",
+			  SUM(pt.collected) AS "collected.amount", MAX(b.currency) AS "collected.currency",
+			  SUM(pt.outstanding) AS "outstanding.amount", MAX(b.currency) AS "outstanding.currency"
+			  FROM (` + reportPaymentTotals + `) pt
+			  JOIN bills b ON b.id = pt.bill_id
+			  JOIN users u ON u.id = pt.user_id
+			  WHERE b.apartment_id = $1 AND b.due_date >= $2 AND b.due_date < $3
+			  GROUP BY pt.user_id, u.username ORDER BY u.username`
+)
+
+// ReportRepository aggregates the bills and payments tables, it owns no
+// tables of its own
+type ReportRepository interface {
+	GetApartmentReport(ctx context.Context, apartmentID int, groupBy models.ReportGrouping, from, to time.Time) ([]models.ReportRow, error)
+}
+
+type reportRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewReportRepository(db *sqlx.DB) ReportRepository {
+	return &reportRepositoryImpl{db: db}
+}
+
+// the totals of the apartment's bills due from (inclusive) to (exclusive),
+// one row per group
+func (r *reportRepositoryImpl) GetApartmentReport(ctx context.Context, apartmentID int, groupBy models.ReportGrouping, from, to time.Time) ([]models.ReportRow, error) {
+	var query string
+	switch groupBy {
+	case models.ReportByMonth:
+		query = fmt.Sprintf(reportByBillQuery, "TO_CHAR(b.due_date, 'YYYY-MM')")
+	case models.ReportByBillType:
+		query = fmt.Sprintf(reportByBillQuery, "b.bill_type")
+	case models.ReportByResident:
+		query = reportByResidentQuery
+	default:
+		return nil, fmt.Errorf("unknown report grouping %q", groupBy)
+	}
+
+	var rows []models.ReportRow
+	if err := conn(ctx, r.db).SelectContext(ctx, &rows, query, apartmentID, from, to); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReportRepository struct {
+	mock.Mock
+}
+
+func (m *MockReportRepository) GetApartmentReport(ctx context.Context, apartmentID int, groupBy models.ReportGrouping, from, to time.Time) ([]models.ReportRow, error) {
+	args := m.Called(ctx, apartmentID, groupBy, from, to)
+	if rows, ok := args.Get(0).([]models.ReportRow); ok {
+		return rows, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
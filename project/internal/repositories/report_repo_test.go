@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportRepository_GetApartmentReport(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"key", "label", "billed.amount", "billed.currency", "collected.amount", "collected.currency", "outstanding.amount", "outstanding.currency"}
+
+	tests := []struct {
+		name          string
+		groupBy       models.ReportGrouping
+		expectedQuery string
+		expectedError string
+	}{
+		{
+			name:          "by month",
+			groupBy:       models.ReportByMonth,
+			expectedQuery: `SELECT TO_CHAR\(b.due_date, 'YYYY-MM'\) AS key, (.+) FROM bills b LEFT JOIN (.+) WHERE b.apartment_id = \$1 AND b.due_date >= \$2 AND b.due_date < \$3 GROUP BY 1`,
+		},
+		{
+			name:          "by bill type",
+			groupBy:       models.ReportByBillType,
+			expectedQuery: `SELECT b.bill_type AS key, (.+) FROM bills b LEFT JOIN (.+) GROUP BY 1`,
+		},
+		{
+			name:          "by resident",
+			groupBy:       models.ReportByResident,
+			expectedQuery: `SELECT pt.user_id::TEXT AS key, u.username AS label, (.+) JOIN users u ON u.id = pt.user_id (.+) GROUP BY pt.user_id, u.username`,
+		},
+		{
+			name:          "unknown grouping",
+			groupBy:       "floor",
+			expectedError: "unknown report grouping",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupTestDB(t)
+			defer db.Close()
+
+			if tt.expectedQuery != "" {
+				mock.ExpectQuery(tt.expectedQuery).
+					WithArgs(2, from, to).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow("k", "label", 150000, "IRR", 100000, "IRR", 50000, "IRR"))
+			}
+
+			repo := &reportRepositoryImpl{db: db}
+			rows, err := repo.GetApartmentReport(context.Background(), 2, tt.groupBy, from, to)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []models.ReportRow{{
+				Key:         "k",
+				Label:       "label",
+				Billed:      models.NewMoney(150000, "IRR"),
+				Collected:   models.NewMoney(100000, "IRR"),
+				Outstanding: models.NewMoney(50000, "IRR"),
+			}}, rows)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found or already used")
+	ErrLinkCodeNotFound     = errors.New("telegram link code not found or already used")
+)
+
+// TokenRepository keeps the refresh sessions, the revoked access tokens and
+// the codes that link telegram chats in Redis. entries expire with the
+// tokens they are about
+type TokenRepository interface {
+	SaveRefreshToken(ctx context.Context, token string, session models.RefreshSession, ttl time.Duration) error
+	// returns the session of the token and deletes it, so each refresh token
+	// works once
+	ConsumeRefreshToken(ctx context.Context, token string) (*models.RefreshSession, error)
+	RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error
+	// revokes every token of the user issued until now. ttl must cover the
+	// longest lived of them
+	RevokeUserTokens(ctx context.Context, userID int, ttl time.Duration) error
+	IsTokenRevoked(ctx context.Context, tokenID string, userID int, issuedAt time.Time) (bool, error)
+	SaveTelegramLinkCode(ctx context.Context, code string, userID int, ttl time.Duration) error
+	// returns the user the code was made for and deletes it, so each code
+	// links one chat
+	ConsumeTelegramLinkCode(ctx context.Context, code string) (int, error)
+}
+
+type tokenRepositoryImpl struct {
+	redisClient *goredis.Client
+}
+
+func NewTokenRepository(redisClient *goredis.Client) TokenRepository {
+	return &tokenRepositoryImpl{redisClient: redisClient}
+}
+
+func (r *tokenRepositoryImpl) SaveRefreshToken(ctx context.Context, token string, session models.RefreshSession, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode refresh session: %w", err)
+	}
+	if err := r.redisClient.Set(ctx, refreshTokenKey(token), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save refresh token: %w", err)
+	}
+	return nil
+}
+
+func (r *tokenRepositoryImpl) ConsumeRefreshToken(ctx context.Context, token string) (*models.RefreshSession, error) {
+	data, err := r.redisClient.GetDel(ctx, refreshTokenKey(token)).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to access Redis: %w", err)
+	}
+
+	var session models.RefreshSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode refresh session: %w", err)
+	}
+	return &session, nil
+}
+
+func (r *tokenRepositoryImpl) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil // already expired
+	}
+	if err := r.redisClient.Set(ctx, revokedTokenKey(tokenID), "1", ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+func (r *tokenRepositoryImpl) RevokeUserTokens(ctx context.Context, userID int, ttl time.Duration) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := r.redisClient.Set(ctx, revokedUserKey(userID), now, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
+
+func (r *tokenRepositoryImpl) IsTokenRevoked(ctx context.Context, tokenID string, userID int, issuedAt time.Time) (bool, error) {
+	revoked, err := r.redisClient.Exists(ctx, revokedTokenKey(tokenID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to access Redis: %w", err)
+	}
+	if revoked > 0 {
+		return true, nil
+	}
+
+	revokedBefore, err := r.redisClient.Get(ctx, revokedUserKey(userID)).Int64()
+	if errors.Is(err, goredis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to access Redis: %w", err)
+	}
+	//token times have a one second resolution, a token issued in the same
+	//second as the revocation counts as revoked
+	return issuedAt.Unix() <= revokedBefore, nil
+}
+
+func (r *tokenRepositoryImpl) SaveTelegramLinkCode(ctx context.Context, code string, userID int, ttl time.Duration) error {
+	if err := r.redisClient.Set(ctx, telegramLinkCodeKey(code), userID, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save telegram link code: %w", err)
+	}
+	return nil
+}
+
+func (r *tokenRepositoryImpl) ConsumeTelegramLinkCode(ctx context.Context, code string) (int, error) {
+	userID, err := r.redisClient.GetDel(ctx, telegramLinkCodeKey(code)).Int()
+	if errors.Is(err, goredis.Nil) {
+		return 0, ErrLinkCodeNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to access Redis: %w", err)
+	}
+	return userID, nil
+}
+
+// only a hash of the token is stored, a dump of Redis doesn't hand out
+// working refresh tokens
+func refreshTokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "refresh_token:" + hex.EncodeToString(sum[:])
+}
+
+func revokedTokenKey(tokenID string) string {
+	return "revoked_token:" + tokenID
+}
+
+func revokedUserKey(userID int) string {
+	return fmt.Sprintf("revoked_user:%d", userID)
+}
+
+// hashed like refresh tokens, the code is as good as the account's telegram
+// link until it is used
+func telegramLinkCodeKey(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return "telegram_link:" + hex.EncodeToString(sum[:])
+}
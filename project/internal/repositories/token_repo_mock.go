@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRepository) SaveRefreshToken(ctx context.Context, token string, session models.RefreshSession, ttl time.Duration) error {
+	args := m.Called(ctx, token, session, ttl)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) ConsumeRefreshToken(ctx context.Context, token string) (*models.RefreshSession, error) {
+	args := m.Called(ctx, token)
+	if session, ok := args.Get(0).(*models.RefreshSession); ok {
+		return session, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTokenRepository) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	args := m.Called(ctx, tokenID, ttl)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeUserTokens(ctx context.Context, userID int, ttl time.Duration) error {
+	args := m.Called(ctx, userID, ttl)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) IsTokenRevoked(ctx context.Context, tokenID string, userID int, issuedAt time.Time) (bool, error) {
+	args := m.Called(ctx, tokenID, userID, issuedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRepository) SaveTelegramLinkCode(ctx context.Context, code string, userID int, ttl time.Duration) error {
+	args := m.Called(ctx, code, userID, ttl)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) ConsumeTelegramLinkCode(ctx context.Context, code string) (int, error) {
+	args := m.Called(ctx, code)
+	return args.Int(0), args.Error(1)
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenRepository_RefreshToken(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+
+	repo := NewTokenRepository(db)
+	ctx := context.Background()
+	session := models.RefreshSession{
+		UserID:        1,
+		UserType:      models.Resident,
+		AccessTokenID: "abc",
+		IssuedAt:      time.Unix(1700000000, 0).UTC(),
+	}
+	data, err := json.Marshal(session)
+	require.NoError(t, err)
+	key := refreshTokenKey("secret")
+	assert.NotContains(t, key, "secret")
+
+	mock.ExpectSet(key, data, time.Hour).SetVal("OK")
+	require.NoError(t, repo.SaveRefreshToken(ctx, "secret", session, time.Hour))
+
+	mock.ExpectGetDel(key).SetVal(string(data))
+	consumed, err := repo.ConsumeRefreshToken(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, session, *consumed)
+
+	//the token works once
+	mock.ExpectGetDel(key).RedisNil()
+	_, err = repo.ConsumeRefreshToken(ctx, "secret")
+	assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTokenRepository_TelegramLinkCode(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+
+	repo := NewTokenRepository(db)
+	ctx := context.Background()
+	key := telegramLinkCodeKey("K7M2Q9XA")
+	assert.NotContains(t, key, "K7M2Q9XA")
+
+	mock.ExpectSet(key, 3, 10*time.Minute).SetVal("OK")
+	require.NoError(t, repo.SaveTelegramLinkCode(ctx, "K7M2Q9XA", 3, 10*time.Minute))
+
+	mock.ExpectGetDel(key).SetVal("3")
+	userID, err := repo.ConsumeTelegramLinkCode(ctx, "K7M2Q9XA")
+	require.NoError(t, err)
+	assert.Equal(t, 3, userID)
+
+	//the code links one chat
+	mock.ExpectGetDel(key).RedisNil()
+	_, err = repo.ConsumeTelegramLinkCode(ctx, "K7M2Q9XA")
+	assert.ErrorIs(t, err, ErrLinkCodeNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTokenRepository_IsTokenRevoked(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+
+	repo := NewTokenRepository(db)
+	ctx := context.Background()
+	revokedAt := time.Unix(1700000000, 0)
+
+	t.Run("revoked token", func(t *testing.T) {
+		mock.ExpectExists("revoked_token:abc").SetVal(1)
+
+		revoked, err := repo.IsTokenRevoked(ctx, "abc", 1, revokedAt)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("token issued before its user was revoked", func(t *testing.T) {
+		mock.ExpectExists("revoked_token:abc").SetVal(0)
+		mock.ExpectGet("revoked_user:1").SetVal(strconv.FormatInt(revokedAt.Unix(), 10))
+
+		revoked, err := repo.IsTokenRevoked(ctx, "abc", 1, revokedAt.Add(-time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("token issued after", func(t *testing.T) {
+		mock.ExpectExists("revoked_token:abc").SetVal(0)
+		mock.ExpectGet("revoked_user:1").SetVal(strconv.FormatInt(revokedAt.Unix(), 10))
+
+		revoked, err := repo.IsTokenRevoked(ctx, "abc", 1, revokedAt.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	t.Run("nothing revoked", func(t *testing.T) {
+		mock.ExpectExists("revoked_token:abc").SetVal(0)
+		mock.ExpectGet("revoked_user:1").RedisNil()
+
+		revoked, err := repo.IsTokenRevoked(ctx, "abc", 1, revokedAt)
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	t.Run("expired tokens are not stored", func(t *testing.T) {
+		assert.NoError(t, repo.RevokeToken(ctx, "abc", -time.Second))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
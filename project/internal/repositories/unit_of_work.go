@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type txKey struct{}
+
+// UnitOfWork runs work that spans several repositories in one database
+// transaction. repository methods called with the context handed to the work
+// take part in it, so either all of their changes are kept or none
+type UnitOfWork interface {
+	// commits when work returns nil and rolls back otherwise. work started
+	// inside another unit of work joins the outer transaction
+	Do(ctx context.Context, work func(ctx context.Context) error) error
+}
+
+type unitOfWorkImpl struct {
+	db *sqlx.DB
+}
+
+func NewUnitOfWork(db *sqlx.DB) UnitOfWork {
+	return &unitOfWorkImpl{db: db}
+}
+
+func (u *unitOfWorkImpl) Do(ctx context.Context, work func(ctx context.Context) error) (err error) {
+	tx, finish, err := beginTx(ctx, u.db)
+	if err != nil {
+		return err
+	}
+	defer finish(&err)
+
+	return work(context.WithValue(ctx, txKey{}, tx))
+}
+
+// what the repositories run their queries on, implemented by both *sqlx.DB
+// and *sqlx.Tx
+type queryer interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// the transaction of the unit of work ctx belongs to, or the pool outside of
+// one
+func conn(ctx context.Context, db *sqlx.DB) queryer {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// starts a transaction for a repository method that needs one of its own,
+// or joins the unit of work ctx belongs to. finish must be deferred with the
+// method's named error: it commits or rolls back a transaction started here
+// and leaves a joined one to its unit of work
+func beginTx(ctx context.Context, db *sqlx.DB) (*sqlx.Tx, func(*error), error) {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx, func(*error) {}, nil
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tx, func(err *error) {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if *err != nil {
+			tx.Rollback()
+			return
+		}
+		if commitErr := tx.Commit(); commitErr != nil {
+			*err = fmt.Errorf("failed to commit transaction: %w", commitErr)
+		}
+	}, nil
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockUnitOfWork runs the work right away, without a transaction, unless
+// Do is set up to fail
+type MockUnitOfWork struct {
+	mock.Mock
+}
+
+func (m *MockUnitOfWork) Do(ctx context.Context, work func(ctx context.Context) error) error {
+	args := m.Called(ctx)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return work(ctx)
+}
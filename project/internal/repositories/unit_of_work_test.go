@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitOfWork_CommitsRepositoryChanges(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM user_apartments WHERE apartment_id = \\$1").WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM apartments WHERE id = \\$1").WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	uow := NewUnitOfWork(db)
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		if err := (&userApartmentRepositoryImpl{db: db}).DeleteApartmentFromUserApartments(ctx, 2); err != nil {
+			return err
+		}
+		return (&apartmentRepositoryImpl{db: db}).DeleteApartment(ctx, 2)
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnitOfWork_RollsBackOnError(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM user_apartments").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM apartments").WillReturnError(errors.New("db down"))
+	mock.ExpectRollback()
+
+	uow := NewUnitOfWork(db)
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		if err := (&userApartmentRepositoryImpl{db: db}).DeleteApartmentFromUserApartments(ctx, 2); err != nil {
+			return err
+		}
+		return (&apartmentRepositoryImpl{db: db}).DeleteApartment(ctx, 2)
+	})
+
+	assert.EqualError(t, err, "db down")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnitOfWork_RollsBackOnPanic(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	uow := NewUnitOfWork(db)
+	assert.Panics(t, func() {
+		uow.Do(context.Background(), func(ctx context.Context) error {
+			panic("boom")
+		})
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnitOfWork_RepositoryTransactionsJoinTheUnitOfWork(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	//a single begin and commit, the installment plan doesn't start its own
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM installments").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO installments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO ledger_entries").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	uow := NewUnitOfWork(db)
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		err := (&installmentRepositoryImpl{db: db}).ReplaceInstallmentPlan(ctx, 5, []models.Installment{
+			{Sequence: 1, Amount: models.NewMoney(1000, "IRR"), DueDate: "2025-01-10"},
+		})
+		if err != nil {
+			return err
+		}
+		return uow.Do(ctx, func(ctx context.Context) error {
+			_, err := (&ledgerRepositoryImpl{db: db}).AddLedgerEntry(ctx, models.LedgerEntry{
+				UserID:      1,
+				ApartmentID: 2,
+				EntryType:   models.ChargeEntry,
+				Amount:      models.NewMoney(1000, "IRR"),
+			})
+			return err
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+)
+
+var ErrApartmentFull = errors.New("apartment already has all of its units")
+
+type UnitRepository interface {
+	CreateUnit(ctx context.Context, unit models.Unit) (int, error)
+	GetUnitByID(ctx context.Context, id int) (*models.Unit, error)
+	GetUnitsByApartment(ctx context.Context, apartmentID int) ([]models.Unit, error)
+	UpdateUnit(ctx context.Context, unit models.Unit) error
+	DeleteUnit(ctx context.Context, id int) error
+}
+
+type unitRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewUnitRepository(db *sqlx.DB) UnitRepository {
+	return &unitRepositoryImpl{db: db}
+}
+
+// adds a unit unless the apartment already has UnitsCount of them. the
+// apartment row is locked so units added at the same time can't go over
+func (r *unitRepositoryImpl) CreateUnit(ctx context.Context, unit models.Unit) (id int, err error) {
+	tx, finish, err := beginTx(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	defer finish(&err)
+
+	var capacity int
+	err = tx.GetContext(ctx, &capacity, `SELECT units_count FROM apartments WHERE id = $1 FOR UPDATE`, unit.ApartmentID)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = tx.GetContext(ctx, &count, `SELECT COUNT(*) FROM units WHERE apartment_id = $1`, unit.ApartmentID)
+	if err != nil {
+		return 0, err
+	}
+	if count >= capacity {
+		return 0, ErrApartmentFull
+	}
+
+	query := `INSERT INTO units (apartment_id, number, floor, area, occupants_count, parking_spots)
+			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	err = tx.QueryRowContext(ctx, query,
+		unit.ApartmentID,
+		unit.Number,
+		unit.Floor,
+		unit.Area,
+		unit.OccupantsCount,
+		unit.ParkingSpots,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+const unitColumns = `id, apartment_id, number, floor, area, occupants_count, parking_spots, created_at, updated_at`
+
+func (r *unitRepositoryImpl) GetUnitByID(ctx context.Context, id int) (*models.Unit, error) {
+	var unit models.Unit
+	query := `SELECT ` + unitColumns + ` FROM units WHERE id = $1`
+	err := conn(ctx, r.db).GetContext(ctx, &unit, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+func (r *unitRepositoryImpl) GetUnitsByApartment(ctx context.Context, apartmentID int) ([]models.Unit, error) {
+	var units []models.Unit
+	query := `SELECT ` + unitColumns + ` FROM units WHERE apartment_id = $1 ORDER BY floor, number`
+	err := conn(ctx, r.db).SelectContext(ctx, &units, query, apartmentID)
+	if err != nil {
+		return nil, err
+	}
+	return units, nil
+}
+
+func (r *unitRepositoryImpl) UpdateUnit(ctx context.Context, unit models.Unit) error {
+	query := `UPDATE units SET number = $1, floor = $2, area = $3, occupants_count = $4, parking_spots = $5,
+			  updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $6`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query,
+		unit.Number,
+		unit.Floor,
+		unit.Area,
+		unit.OccupantsCount,
+		unit.ParkingSpots,
+		unit.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("no unit found with id %d", unit.ID)
+	}
+	return nil
+}
+
+// removes the unit, its members stay in the apartment without one
+func (r *unitRepositoryImpl) DeleteUnit(ctx context.Context, id int) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM units WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("no unit found with id %d", id)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUnitRepository struct {
+	mock.Mock
+}
+
+func (m *MockUnitRepository) CreateUnit(ctx context.Context, unit models.Unit) (int, error) {
+	args := m.Called(ctx, unit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUnitRepository) GetUnitByID(ctx context.Context, id int) (*models.Unit, error) {
+	args := m.Called(ctx, id)
+	if unit, ok := args.Get(0).(*models.Unit); ok {
+		return unit, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUnitRepository) GetUnitsByApartment(ctx context.Context, apartmentID int) ([]models.Unit, error) {
+	args := m.Called(ctx, apartmentID)
+	if units, ok := args.Get(0).([]models.Unit); ok {
+		return units, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUnitRepository) UpdateUnit(ctx context.Context, unit models.Unit) error {
+	args := m.Called(ctx, unit)
+	return args.Error(0)
+}
+
+func (m *MockUnitRepository) DeleteUnit(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
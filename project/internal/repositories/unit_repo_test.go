@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitRepository_CreateUnit(t *testing.T) {
+	unit := models.Unit{ApartmentID: 1, Number: "B2", Floor: 2, Area: 85.5, OccupantsCount: 3, ParkingSpots: 1}
+
+	t.Run("room left", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT units_count FROM apartments WHERE id = \$1 FOR UPDATE`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"units_count"}).AddRow(10))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM units WHERE apartment_id = \$1`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(9))
+		mock.ExpectQuery(`INSERT INTO units`).
+			WithArgs(1, "B2", 2, 85.5, 3, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectCommit()
+
+		id, err := NewUnitRepository(db).CreateUnit(context.Background(), unit)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("apartment full", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT units_count FROM apartments`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"units_count"}).AddRow(10))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM units`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+		mock.ExpectRollback()
+
+		_, err := NewUnitRepository(db).CreateUnit(context.Background(), unit)
+		assert.ErrorIs(t, err, ErrApartmentFull)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUnitRepository_GetUnitsByApartment(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+
+	columns := []string{"id", "apartment_id", "number", "floor", "area", "occupants_count", "parking_spots", "created_at", "updated_at"}
+	mock.ExpectQuery(`SELECT (.+) FROM units WHERE apartment_id = \$1 ORDER BY floor, number`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "1", 0, 60.0, 2, 0, time.Now(), time.Now()).
+			AddRow(2, 1, "2", 1, 90.0, 4, 1, time.Now(), time.Now()))
+
+	units, err := NewUnitRepository(db).GetUnitsByApartment(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, units, 2)
+	assert.Equal(t, 90.0, units[1].Area)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnitRepository_UpdateAndDeleteUnit(t *testing.T) {
+	db, mock := setupTestDB(t)
+	defer db.Close()
+	repo := NewUnitRepository(db)
+
+	mock.ExpectExec(`UPDATE units SET number = \$1`).
+		WithArgs("3", 1, 70.0, 2, 0, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM units WHERE id = \$1`).
+		WithArgs(6).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.UpdateUnit(context.Background(), models.Unit{
+		BaseModel: models.BaseModel{ID: 5}, Number: "3", Floor: 1, Area: 70, OccupantsCount: 2,
+	}))
+	assert.Error(t, repo.DeleteUnit(context.Background(), 6))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
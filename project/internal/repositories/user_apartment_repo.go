@@ -2,60 +2,51 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
 	"errors"
-	"log"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 )
 
-const (
-	CREATE_USER_APARTMENT_TABLE = `CREATE TABLE IF NOT EXISTS user_apartments(
-		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
-		apartment_id INTEGER REFERENCES apartments(id) ON DELETE CASCADE,
-		is_manager BOOLEAN DEFAULT FALSE,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		PRIMARY KEY (user_id, apartment_id)
-	);`
-)
-
 type UserApartmentRepository interface {
 	CreateUserApartment(ctx context.Context, user_apartment models.User_apartment) error
 	GetResidentsInApartment(apartmentID int) ([]models.User, error)
 	GetUserApartmentByID(userID, apartmentID int) (*models.User_apartment, error)
 	UpdateUserApartment(ctx context.Context, user_apartment models.User_apartment) error
 	DeleteUserApartment(userID, apartmentID int) error
-	DeleteUserFromApartments(userID int) error
+	DeleteUserFromApartments(ctx context.Context, userID int) error
 	GetAllApartmentsForAResident(residentID int) ([]models.Apartment, error)
-	IsUserManagerOfApartment(ctx context.Context, userID, apartmentID int) (bool, error)
+	GetMemberPermissions(ctx context.Context, userID, apartmentID int) (models.Permission, error)
+	SetMemberRole(ctx context.Context, userID, apartmentID int, role models.Role, permissions models.Permission) error
 	IsUserInApartment(ctx context.Context, userID, apartmentID int) (bool, error)
-	DeleteApartmentFromUserApartments(apartmentID int) error
+	DeleteApartmentFromUserApartments(ctx context.Context, apartmentID int) error
+	GetUserApartmentsByApartment(apartmentID int) ([]models.User_apartment, error)
+	UpdateResidentShare(ctx context.Context, user_apartment models.User_apartment) error
+	SetMemberUnit(ctx context.Context, userID, apartmentID int, unitID *int, relation models.UnitRelation) error
 }
 
 type userApartmentRepositoryImpl struct {
 	db *sqlx.DB
 }
 
-func NewUserApartmentRepository(autoCreate bool, db *sqlx.DB) UserApartmentRepository {
-	if autoCreate {
-		if _, err := db.Exec(CREATE_USER_APARTMENT_TABLE); err != nil {
-			log.Fatalf("failed to create user_apartments table: %v", err)
-		}
-	}
+func NewUserApartmentRepository(db *sqlx.DB) UserApartmentRepository {
 	return &userApartmentRepositoryImpl{db: db}
 }
 
 func (r *userApartmentRepositoryImpl) CreateUserApartment(ctx context.Context, user_apartment models.User_apartment) error {
-	query := `INSERT INTO user_apartments (user_id, apartment_id, is_manager) 
-			  VALUES (:user_id, :apartment_id, :is_manager)`
-	_, err := r.db.NamedExecContext(ctx, query, user_apartment)
+	if user_apartment.Role == "" {
+		user_apartment.Role = models.ResidentRole
+	}
+	query := `INSERT INTO user_apartments (user_id, apartment_id, is_manager, role, permissions) 
+			  VALUES (:user_id, :apartment_id, :is_manager, :role, :permissions)`
+	_, err := conn(ctx, r.db).NamedExecContext(ctx, query, user_apartment)
 	return err
 }
 
 func (r *userApartmentRepositoryImpl) GetUserApartmentByID(userID, apartmentID int) (*models.User_apartment, error) {
 	var userApartment models.User_apartment
-	query := `SELECT user_id, apartment_id, is_manager, created_at, updated_at 
+	query := `SELECT user_id, apartment_id, is_manager, role, permissions, unit_id, unit_relation, created_at, updated_at 
 			  FROM user_apartments WHERE user_id = $1 AND apartment_id = $2`
 	err := r.db.Get(&userApartment, query, userID, apartmentID)
 	if err != nil {
@@ -68,7 +59,7 @@ func (r *userApartmentRepositoryImpl) UpdateUserApartment(ctx context.Context, u
 	query := `UPDATE user_apartments 
 			  SET is_manager = :is_manager, updated_at = CURRENT_TIMESTAMP 
 			  WHERE user_id = :user_id AND apartment_id = :apartment_id`
-	_, err := r.db.NamedExecContext(ctx, query, user_apartment)
+	_, err := conn(ctx, r.db).NamedExecContext(ctx, query, user_apartment)
 	return err
 }
 
@@ -104,18 +95,41 @@ func (r *userApartmentRepositoryImpl) GetAllApartmentsForAResident(residentID in
 	return apartments, nil
 }
 
-func (r *userApartmentRepositoryImpl) IsUserManagerOfApartment(ctx context.Context, userID, apartmentID int) (bool, error) {
-	var isManager bool
-	query := `SELECT is_manager FROM user_apartments 
+// what the user may do in the apartment, nothing when they aren't a member
+func (r *userApartmentRepositoryImpl) GetMemberPermissions(ctx context.Context, userID, apartmentID int) (models.Permission, error) {
+	var member models.User_apartment
+	query := `SELECT role, permissions FROM user_apartments 
 			  WHERE user_id = $1 AND apartment_id = $2`
-	err := r.db.GetContext(ctx, &isManager, query, userID, apartmentID)
-	if err != nil || !isManager {
-		if !isManager {
-			return false, errors.New("not manager")
-		}
-		return false, err
+	err := conn(ctx, r.db).GetContext(ctx, &member, query, userID, apartmentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
 	}
-	return true, nil
+	if err != nil {
+		return 0, err
+	}
+	return member.EffectivePermissions(), nil
+}
+
+// gives a member a role, with permissions overriding the role's when not 0.
+// is_manager follows the role for code that still reads it
+func (r *userApartmentRepositoryImpl) SetMemberRole(ctx context.Context, userID, apartmentID int, role models.Role, permissions models.Permission) error {
+	query := `UPDATE user_apartments 
+			  SET role = $1, permissions = $2, is_manager = $3, updated_at = CURRENT_TIMESTAMP 
+			  WHERE user_id = $4 AND apartment_id = $5`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, role, permissions, role.IsManager(), userID, apartmentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("not in apartment")
+	}
+	return nil
 }
 
 func (r *userApartmentRepositoryImpl) IsUserInApartment(ctx context.Context, userID, apartmentID int) (bool, error) {
@@ -124,7 +138,7 @@ func (r *userApartmentRepositoryImpl) IsUserInApartment(ctx context.Context, use
 		SELECT 1 FROM user_apartments 
 		WHERE user_id = $1 AND apartment_id = $2
 	)`
-	err := r.db.GetContext(ctx, &exists, query, userID, apartmentID)
+	err := conn(ctx, r.db).GetContext(ctx, &exists, query, userID, apartmentID)
 	if err != nil || !exists {
 		if !exists {
 			return false, errors.New("not in apartment")
@@ -134,17 +148,77 @@ func (r *userApartmentRepositoryImpl) IsUserInApartment(ctx context.Context, use
 	return true, nil
 }
 
-func (r *userApartmentRepositoryImpl) DeleteUserFromApartments(userID int) error {
+func (r *userApartmentRepositoryImpl) DeleteUserFromApartments(ctx context.Context, userID int) error {
 	query := `DELETE FROM user_apartments WHERE user_id = $1`
-	_, err := r.db.Exec(query, userID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID)
 	return err
 }
 
-func (r *userApartmentRepositoryImpl) DeleteApartmentFromUserApartments(apartmentID int) error {
+func (r *userApartmentRepositoryImpl) DeleteApartmentFromUserApartments(ctx context.Context, apartmentID int) error {
 	query := `DELETE FROM user_apartments WHERE apartment_id = $1`
-	_, err := r.db.Exec(query, apartmentID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, apartmentID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// returns every membership of the apartment along with the division weights
+func (r *userApartmentRepositoryImpl) GetUserApartmentsByApartment(apartmentID int) ([]models.User_apartment, error) {
+	var userApartments []models.User_apartment
+	query := `SELECT user_id, apartment_id, is_manager, role, permissions, unit_area, occupants_count, share_percent,
+			  unit_id, unit_relation, created_at, updated_at
+			  FROM user_apartments WHERE apartment_id = $1
+			  ORDER BY user_id`
+	err := r.db.Select(&userApartments, query, apartmentID)
+	if err != nil {
+		return nil, err
+	}
+	return userApartments, nil
+}
+
+func (r *userApartmentRepositoryImpl) UpdateResidentShare(ctx context.Context, user_apartment models.User_apartment) error {
+	query := `UPDATE user_apartments 
+			  SET unit_area = :unit_area, occupants_count = :occupants_count, share_percent = :share_percent,
+			  updated_at = CURRENT_TIMESTAMP 
+			  WHERE user_id = :user_id AND apartment_id = :apartment_id`
+	result, err := conn(ctx, r.db).NamedExecContext(ctx, query, user_apartment)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("not in apartment")
+	}
+	return nil
+}
+
+// attaches the member to a unit of the apartment, or detaches them when
+// unitID is nil
+func (r *userApartmentRepositoryImpl) SetMemberUnit(ctx context.Context, userID, apartmentID int, unitID *int, relation models.UnitRelation) error {
+	if unitID == nil {
+		relation = ""
+	}
+	query := `UPDATE user_apartments 
+			  SET unit_id = $1, unit_relation = $2, updated_at = CURRENT_TIMESTAMP 
+			  WHERE user_id = $3 AND apartment_id = $4`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, unitID, relation, userID, apartmentID)
 	if err != nil {
 		return err
 	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("not in apartment")
+	}
 	return nil
 }
@@ -42,8 +42,8 @@ func (m *MockUserApartmentRepository) DeleteUserApartment(userID, apartmentID in
 	return args.Error(0)
 }
 
-func (m *MockUserApartmentRepository) DeleteUserFromApartments(userID int) error {
-	args := m.Called(userID)
+func (m *MockUserApartmentRepository) DeleteUserFromApartments(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
 	return args.Error(0)
 }
 
@@ -55,9 +55,17 @@ func (m *MockUserApartmentRepository) GetAllApartmentsForAResident(residentID in
 	return args.Get(0).([]models.Apartment), args.Error(1)
 }
 
-func (m *MockUserApartmentRepository) IsUserManagerOfApartment(ctx context.Context, userID, apartmentID int) (bool, error) {
+func (m *MockUserApartmentRepository) GetMemberPermissions(ctx context.Context, userID, apartmentID int) (models.Permission, error) {
 	args := m.Called(ctx, userID, apartmentID)
-	return args.Bool(0), args.Error(1)
+	if permissions, ok := args.Get(0).(models.Permission); ok {
+		return permissions, args.Error(1)
+	}
+	return 0, args.Error(1)
+}
+
+func (m *MockUserApartmentRepository) SetMemberRole(ctx context.Context, userID, apartmentID int, role models.Role, permissions models.Permission) error {
+	args := m.Called(ctx, userID, apartmentID, role, permissions)
+	return args.Error(0)
 }
 
 func (m *MockUserApartmentRepository) IsUserInApartment(ctx context.Context, userID, apartmentID int) (bool, error) {
@@ -65,7 +73,25 @@ func (m *MockUserApartmentRepository) IsUserInApartment(ctx context.Context, use
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockUserApartmentRepository) DeleteApartmentFromUserApartments(apartmentID int) error {
+func (m *MockUserApartmentRepository) DeleteApartmentFromUserApartments(ctx context.Context, apartmentID int) error {
+	args := m.Called(ctx, apartmentID)
+	return args.Error(0)
+}
+
+func (m *MockUserApartmentRepository) GetUserApartmentsByApartment(apartmentID int) ([]models.User_apartment, error) {
 	args := m.Called(apartmentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User_apartment), args.Error(1)
+}
+
+func (m *MockUserApartmentRepository) UpdateResidentShare(ctx context.Context, userApartment models.User_apartment) error {
+	args := m.Called(ctx, userApartment)
+	return args.Error(0)
+}
+
+func (m *MockUserApartmentRepository) SetMemberUnit(ctx context.Context, userID, apartmentID int, unitID *int, relation models.UnitRelation) error {
+	args := m.Called(ctx, userID, apartmentID, unitID, relation)
 	return args.Error(0)
 }
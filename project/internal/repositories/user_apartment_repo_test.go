@@ -19,7 +19,7 @@ func TestUserApartmentRepository_CreateUserApartment(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserApartmentRepository(false, sqlxDB)
+	repo := NewUserApartmentRepository(sqlxDB)
 
 	userApartment := models.User_apartment{
 		UserID:      1,
@@ -29,7 +29,7 @@ func TestUserApartmentRepository_CreateUserApartment(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
 		mock.ExpectExec(`INSERT INTO user_apartments`).
-			WithArgs(userApartment.UserID, userApartment.ApartmentID, userApartment.IsManager).
+			WithArgs(userApartment.UserID, userApartment.ApartmentID, userApartment.IsManager, models.ResidentRole, models.Permission(0)).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		err := repo.CreateUserApartment(context.Background(), userApartment)
@@ -38,7 +38,7 @@ func TestUserApartmentRepository_CreateUserApartment(t *testing.T) {
 
 	t.Run("database error", func(t *testing.T) {
 		mock.ExpectExec(`INSERT INTO user_apartments`).
-			WithArgs(userApartment.UserID, userApartment.ApartmentID, userApartment.IsManager).
+			WithArgs(userApartment.UserID, userApartment.ApartmentID, userApartment.IsManager, models.ResidentRole, models.Permission(0)).
 			WillReturnError(sql.ErrConnDone)
 
 		err := repo.CreateUserApartment(context.Background(), userApartment)
@@ -54,17 +54,17 @@ func TestUserApartmentRepository_GetUserApartmentByID(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserApartmentRepository(false, sqlxDB)
+	repo := NewUserApartmentRepository(sqlxDB)
 
 	userID := 1
 	apartmentID := 2
 	now := time.Now()
 
 	t.Run("success", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"user_id", "apartment_id", "is_manager", "created_at", "updated_at"}).
-			AddRow(userID, apartmentID, true, now, now)
+		rows := sqlmock.NewRows([]string{"user_id", "apartment_id", "is_manager", "role", "permissions", "unit_id", "unit_relation", "created_at", "updated_at"}).
+			AddRow(userID, apartmentID, true, "owner", 0, 3, "owner", now, now)
 
-		mock.ExpectQuery(`SELECT user_id, apartment_id, is_manager, created_at, updated_at FROM user_apartments`).
+		mock.ExpectQuery(`SELECT user_id, apartment_id, is_manager, role, permissions, unit_id, unit_relation, created_at, updated_at FROM user_apartments`).
 			WithArgs(userID, apartmentID).
 			WillReturnRows(rows)
 
@@ -74,10 +74,13 @@ func TestUserApartmentRepository_GetUserApartmentByID(t *testing.T) {
 		assert.Equal(t, userID, result.UserID)
 		assert.Equal(t, apartmentID, result.ApartmentID)
 		assert.True(t, result.IsManager)
+		assert.Equal(t, models.OwnerRole, result.Role)
+		assert.Equal(t, 3, *result.UnitID)
+		assert.Equal(t, models.UnitOwner, result.UnitRelation)
 	})
 
 	t.Run("not found", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT user_id, apartment_id, is_manager, created_at, updated_at FROM user_apartments`).
+		mock.ExpectQuery(`SELECT user_id, apartment_id, is_manager, role, permissions, unit_id, unit_relation, created_at, updated_at FROM user_apartments`).
 			WithArgs(userID, apartmentID).
 			WillReturnError(sql.ErrNoRows)
 
@@ -95,7 +98,7 @@ func TestUserApartmentRepository_UpdateUserApartment(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserApartmentRepository(false, sqlxDB)
+	repo := NewUserApartmentRepository(sqlxDB)
 
 	userApartment := models.User_apartment{
 		UserID:      1,
@@ -130,7 +133,7 @@ func TestUserApartmentRepository_DeleteUserApartment(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserApartmentRepository(false, sqlxDB)
+	repo := NewUserApartmentRepository(sqlxDB)
 
 	userID := 1
 	apartmentID := 2
@@ -162,7 +165,7 @@ func TestUserApartmentRepository_GetResidentsInApartment(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserApartmentRepository(false, sqlxDB)
+	repo := NewUserApartmentRepository(sqlxDB)
 
 	apartmentID := 2
 	now := time.Now()
@@ -204,7 +207,7 @@ func TestUserApartmentRepository_GetAllApartmentsForAResident(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserApartmentRepository(false, sqlxDB)
+	repo := NewUserApartmentRepository(sqlxDB)
 
 	residentID := 1
 	now := time.Now()
@@ -240,68 +243,119 @@ func TestUserApartmentRepository_GetAllApartmentsForAResident(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestUserApartmentRepository_IsUserManagerOfApartment(t *testing.T) {
+func TestUserApartmentRepository_GetMemberPermissions(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserApartmentRepository(false, sqlxDB)
+	repo := NewUserApartmentRepository(sqlxDB)
 
 	userID := 1
 	apartmentID := 2
 
-	t.Run("is manager", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT is_manager FROM user_apartments`).
+	t.Run("role permissions", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT role, permissions FROM user_apartments`).
 			WithArgs(userID, apartmentID).
-			WillReturnRows(sqlmock.NewRows([]string{"is_manager"}).AddRow(true))
+			WillReturnRows(sqlmock.NewRows([]string{"role", "permissions"}).AddRow("treasurer", 0))
 
-		isManager, err := repo.IsUserManagerOfApartment(context.Background(), userID, apartmentID)
+		permissions, err := repo.GetMemberPermissions(context.Background(), userID, apartmentID)
 		assert.NoError(t, err)
-		assert.True(t, isManager)
+		assert.Equal(t, models.TreasurerRole.Permissions(), permissions)
 	})
 
-	t.Run("not manager but exists", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT is_manager FROM user_apartments`).
+	t.Run("granted permissions", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT role, permissions FROM user_apartments`).
 			WithArgs(userID, apartmentID).
-			WillReturnRows(sqlmock.NewRows([]string{"is_manager"}).AddRow(false))
+			WillReturnRows(sqlmock.NewRows([]string{"role", "permissions"}).
+				AddRow("resident", int(models.PermViewApartment|models.PermViewFinances)))
 
-		isManager, err := repo.IsUserManagerOfApartment(context.Background(), userID, apartmentID)
-		assert.Error(t, err)
-		assert.False(t, isManager)
-		assert.Contains(t, err.Error(), "not manager")
+		permissions, err := repo.GetMemberPermissions(context.Background(), userID, apartmentID)
+		assert.NoError(t, err)
+		assert.True(t, permissions.Has(models.PermViewFinances))
+		assert.False(t, permissions.Has(models.PermPayBills))
 	})
 
 	t.Run("user not in apartment", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT is_manager FROM user_apartments`).
+		mock.ExpectQuery(`SELECT role, permissions FROM user_apartments`).
 			WithArgs(userID, apartmentID).
 			WillReturnError(sql.ErrNoRows)
 
-		isManager, err := repo.IsUserManagerOfApartment(context.Background(), userID, apartmentID)
-		assert.Error(t, err)
-		assert.False(t, isManager)
+		permissions, err := repo.GetMemberPermissions(context.Background(), userID, apartmentID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.Permission(0), permissions)
 	})
 
 	t.Run("database error", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT is_manager FROM user_apartments`).
+		mock.ExpectQuery(`SELECT role, permissions FROM user_apartments`).
 			WithArgs(userID, apartmentID).
 			WillReturnError(sql.ErrConnDone)
 
-		isManager, err := repo.IsUserManagerOfApartment(context.Background(), userID, apartmentID)
+		_, err := repo.GetMemberPermissions(context.Background(), userID, apartmentID)
 		assert.Error(t, err)
-		assert.False(t, isManager)
 	})
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestUserApartmentRepository_SetMemberRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewUserApartmentRepository(sqlxDB)
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE user_apartments SET role = \$1, permissions = \$2, is_manager = \$3`).
+			WithArgs(models.ManagerRole, models.Permission(0), true, 1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.SetMemberRole(context.Background(), 1, 2, models.ManagerRole, 0)
+		assert.NoError(t, err)
+	})
+
+	t.Run("not in apartment", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE user_apartments SET role`).
+			WithArgs(models.TreasurerRole, models.Permission(0), false, 3, 2).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.SetMemberRole(context.Background(), 3, 2, models.TreasurerRole, 0)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not in apartment")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserApartmentRepository_SetMemberUnit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewUserApartmentRepository(sqlxDB)
+	unitID := 4
+
+	mock.ExpectExec(`UPDATE user_apartments SET unit_id = \$1, unit_relation = \$2`).
+		WithArgs(&unitID, models.UnitOwner, 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE user_apartments SET unit_id`).
+		WithArgs(nil, models.UnitRelation(""), 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.SetMemberUnit(context.Background(), 1, 2, &unitID, models.UnitOwner))
+	assert.NoError(t, repo.SetMemberUnit(context.Background(), 1, 2, nil, models.UnitResident))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUserApartmentRepository_IsUserInApartment(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserApartmentRepository(false, sqlxDB)
+	repo := NewUserApartmentRepository(sqlxDB)
 
 	userID := 1
 	apartmentID := 2
@@ -339,3 +393,82 @@ func TestUserApartmentRepository_IsUserInApartment(t *testing.T) {
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestUserApartmentRepository_GetUserApartmentsByApartment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewUserApartmentRepository(sqlxDB)
+
+	apartmentID := 2
+	now := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"user_id", "apartment_id", "is_manager", "unit_area", "occupants_count", "share_percent", "created_at", "updated_at"}).
+			AddRow(1, apartmentID, true, 120.5, 4, 60, now, now).
+			AddRow(3, apartmentID, false, 80, 2, 40, now, now)
+
+		mock.ExpectQuery(`SELECT user_id, apartment_id, is_manager, role, permissions, unit_area, occupants_count, share_percent, unit_id, unit_relation, created_at, updated_at FROM user_apartments`).
+			WithArgs(apartmentID).
+			WillReturnRows(rows)
+
+		userApartments, err := repo.GetUserApartmentsByApartment(apartmentID)
+		assert.NoError(t, err)
+		assert.Len(t, userApartments, 2)
+		assert.Equal(t, 120.5, userApartments[0].UnitArea)
+		assert.Equal(t, 2, userApartments[1].OccupantsCount)
+		assert.Equal(t, float64(40), userApartments[1].SharePercent)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT user_id, apartment_id, is_manager, role, permissions, unit_area, occupants_count, share_percent, unit_id, unit_relation, created_at, updated_at FROM user_apartments`).
+			WithArgs(apartmentID).
+			WillReturnError(sql.ErrConnDone)
+
+		userApartments, err := repo.GetUserApartmentsByApartment(apartmentID)
+		assert.Error(t, err)
+		assert.Nil(t, userApartments)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserApartmentRepository_UpdateResidentShare(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewUserApartmentRepository(sqlxDB)
+
+	userApartment := models.User_apartment{
+		UserID:         1,
+		ApartmentID:    2,
+		UnitArea:       95.5,
+		OccupantsCount: 3,
+		SharePercent:   25,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE user_apartments SET unit_area`).
+			WithArgs(userApartment.UnitArea, userApartment.OccupantsCount, userApartment.SharePercent, userApartment.UserID, userApartment.ApartmentID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.UpdateResidentShare(context.Background(), userApartment)
+		assert.NoError(t, err)
+	})
+
+	t.Run("not in apartment", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE user_apartments SET unit_area`).
+			WithArgs(userApartment.UnitArea, userApartment.OccupantsCount, userApartment.SharePercent, userApartment.UserID, userApartment.ApartmentID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.UpdateResidentShare(context.Background(), userApartment)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not in apartment")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
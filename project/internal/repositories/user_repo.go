@@ -4,51 +4,38 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 )
 
-const (
-	CREATE_USERS_TABLE = `CREATE TABLE IF NOT EXISTS users(
-        id SERIAL PRIMARY KEY,
-        username VARCHAR(100) UNIQUE NOT NULL,
-        password VARCHAR(2000) NOT NULL,
-        email VARCHAR(100) UNIQUE NOT NULL,
-        phone VARCHAR(20) UNIQUE NOT NULL,
-        full_name VARCHAR(100) NOT NULL,
-        user_type VARCHAR(20) NOT NULL,
-        telegram_user VARCHAR(100) UNIQUE,
-        telegram_chat_id BIGINT DEFAULT 0,
-        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-    );`
-)
-
 type UserRepository interface {
 	CreateUser(ctx context.Context, user models.User) (int, error)
 	GetUserByID(id int) (*models.User, error)
 	UpdateUser(ctx context.Context, user models.User) error
-	DeleteUser(id int) error
+	DeleteUser(ctx context.Context, id int) error
 	GetAllUsers(ctx context.Context) ([]models.User, error)
 	GetUserByUsername(username string) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
 	GetUserByPhone(phone string) (*models.User, error)
 	GetUserByTelegramUser(telegramUser string) (*models.User, error)
-	UpdateTelegramChatID(ctx context.Context, telegramUsername string, chatID int64) error
+	LinkTelegramChat(ctx context.Context, userID int, chatID int64) error
+	UnlinkTelegramChat(ctx context.Context, userID int) error
+	GetUserByTelegramChatID(ctx context.Context, chatID int64) (*models.User, error)
+	GetNotificationChannels(ctx context.Context, userID int) ([]models.NotificationChannel, error)
+	SetNotificationChannels(ctx context.Context, userID int, channels []models.NotificationChannel) error
+	GetBillReminders(ctx context.Context, userID int) (bool, error)
+	SetBillReminders(ctx context.Context, userID int, enabled bool) error
+	GetLocale(ctx context.Context, userID int) (models.Locale, error)
+	SetLocale(ctx context.Context, userID int, locale models.Locale) error
 }
 
 type userRepositoryImpl struct {
 	db *sqlx.DB
 }
 
-func NewUserRepository(autoCreate bool, db *sqlx.DB) UserRepository {
-	if autoCreate {
-		if _, err := db.Exec(CREATE_USERS_TABLE); err != nil {
-			log.Fatalf("failed to create users table: %v", err)
-		}
-	}
+func NewUserRepository(db *sqlx.DB) UserRepository {
 	return &userRepositoryImpl{db: db}
 }
 
@@ -57,7 +44,7 @@ func (r *userRepositoryImpl) CreateUser(ctx context.Context, user models.User) (
 	          VALUES ($1, $2, $3, $4, $5, $6, $7) 
 	          RETURNING id`
 	var id int
-	err := r.db.QueryRowContext(ctx, query,
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
 		user.Username,
 		user.Password,
 		user.Email,
@@ -99,13 +86,13 @@ func (r *userRepositoryImpl) UpdateUser(ctx context.Context, user models.User) e
 		updated_at = CURRENT_TIMESTAMP 
 		WHERE id = :id`
 
-	_, err := r.db.NamedExecContext(ctx, query, user)
+	_, err := conn(ctx, r.db).NamedExecContext(ctx, query, user)
 	return err
 }
 
-func (r *userRepositoryImpl) DeleteUser(id int) error {
+func (r *userRepositoryImpl) DeleteUser(ctx context.Context, id int) error {
 	query := `DELETE FROM users WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -116,7 +103,7 @@ func (r *userRepositoryImpl) DeleteUser(id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("no user found with id %d", id)
+		return fmt.Errorf("no user found with id %d: %w", id, sql.ErrNoRows)
 	}
 
 	return nil
@@ -127,7 +114,7 @@ func (r *userRepositoryImpl) GetAllUsers(ctx context.Context) ([]models.User, er
 	          telegram_user, telegram_chat_id, created_at, updated_at 
 	          FROM users`
 	var users []models.User
-	if err := r.db.SelectContext(ctx, &users, query); err != nil {
+	if err := conn(ctx, r.db).SelectContext(ctx, &users, query); err != nil {
 		return nil, err
 	}
 	return users, nil
@@ -177,8 +164,143 @@ func (r *userRepositoryImpl) GetUserByTelegramUser(telegramUser string) (*models
 	return &user, nil
 }
 
-func (r *userRepositoryImpl) UpdateTelegramChatID(ctx context.Context, telegramUsername string, chatID int64) error {
-	query := `UPDATE users SET telegram_chat_id = $1, updated_at = CURRENT_TIMESTAMP WHERE telegram_user = $2`
-	_, err := r.db.ExecContext(ctx, query, chatID, telegramUsername)
-	return err
+// links the chat to the user, taking it from the account it was linked to
+// before. run it in a transaction, a chat is linked to one account at most
+func (r *userRepositoryImpl) LinkTelegramChat(ctx context.Context, userID int, chatID int64) error {
+	query := `UPDATE users SET telegram_chat_id = 0, updated_at = CURRENT_TIMESTAMP 
+	          WHERE telegram_chat_id = $1 AND id <> $2`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, chatID, userID); err != nil {
+		return err
+	}
+
+	query = `UPDATE users SET telegram_chat_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, chatID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *userRepositoryImpl) UnlinkTelegramChat(ctx context.Context, userID int) error {
+	query := `UPDATE users SET telegram_chat_id = 0, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// the user whose telegram chat with the bot is chatID
+func (r *userRepositoryImpl) GetUserByTelegramChatID(ctx context.Context, chatID int64) (*models.User, error) {
+	query := `SELECT id, username, password, email, phone, full_name, user_type, 
+	          telegram_user, telegram_chat_id, created_at, updated_at 
+	          FROM users WHERE telegram_chat_id = $1`
+	var user models.User
+	if err := conn(ctx, r.db).GetContext(ctx, &user, query, chatID); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// the channels the user chose in the order they want them tried, empty when
+// they haven't chosen
+func (r *userRepositoryImpl) GetNotificationChannels(ctx context.Context, userID int) ([]models.NotificationChannel, error) {
+	query := `SELECT notification_channels FROM users WHERE id = $1`
+	var names pq.StringArray
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(&names); err != nil {
+		return nil, err
+	}
+
+	channels := make([]models.NotificationChannel, len(names))
+	for i, name := range names {
+		channels[i] = models.NotificationChannel(name)
+	}
+	return channels, nil
+}
+
+func (r *userRepositoryImpl) SetNotificationChannels(ctx context.Context, userID int, channels []models.NotificationChannel) error {
+	names := make([]string, len(channels))
+	for i, channel := range channels {
+		names[i] = string(channel)
+	}
+
+	query := `UPDATE users SET notification_channels = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, pq.StringArray(names), userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// whether the user is reminded of their unpaid shares
+func (r *userRepositoryImpl) GetBillReminders(ctx context.Context, userID int) (bool, error) {
+	query := `SELECT bill_reminders FROM users WHERE id = $1`
+	var enabled bool
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(&enabled); err != nil {
+		return false, err
+	}
+	return enabled, nil
+}
+
+func (r *userRepositoryImpl) SetBillReminders(ctx context.Context, userID int, enabled bool) error {
+	query := `UPDATE users SET bill_reminders = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, enabled, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// the language the user's notifications are written in
+func (r *userRepositoryImpl) GetLocale(ctx context.Context, userID int) (models.Locale, error) {
+	query := `SELECT locale FROM users WHERE id = $1`
+	var locale models.Locale
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(&locale); err != nil {
+		return "", err
+	}
+	return locale, nil
+}
+
+func (r *userRepositoryImpl) SetLocale(ctx context.Context, userID int, locale models.Locale) error {
+	query := `UPDATE users SET locale = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, locale, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
@@ -29,8 +29,8 @@ func (m *MockUserRepository) UpdateUser(ctx context.Context, user models.User) e
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) DeleteUser(id int) error {
-	args := m.Called(id)
+func (m *MockUserRepository) DeleteUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
@@ -74,7 +74,56 @@ func (m *MockUserRepository) GetUserByTelegramUser(telegramUser string) (*models
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) UpdateTelegramChatID(ctx context.Context, telegramUsername string, chatID int64) error {
-	args := m.Called(ctx, telegramUsername, chatID)
+func (m *MockUserRepository) LinkTelegramChat(ctx context.Context, userID int, chatID int64) error {
+	args := m.Called(ctx, userID, chatID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UnlinkTelegramChat(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetUserByTelegramChatID(ctx context.Context, chatID int64) (*models.User, error) {
+	args := m.Called(ctx, chatID)
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) GetNotificationChannels(ctx context.Context, userID int) ([]models.NotificationChannel, error) {
+	args := m.Called(ctx, userID)
+	if channels, ok := args.Get(0).([]models.NotificationChannel); ok {
+		return channels, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) SetNotificationChannels(ctx context.Context, userID int, channels []models.NotificationChannel) error {
+	args := m.Called(ctx, userID, channels)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetBillReminders(ctx context.Context, userID int) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) SetBillReminders(ctx context.Context, userID int, enabled bool) error {
+	args := m.Called(ctx, userID, enabled)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetLocale(ctx context.Context, userID int) (models.Locale, error) {
+	args := m.Called(ctx, userID)
+	if locale, ok := args.Get(0).(models.Locale); ok {
+		return locale, args.Error(1)
+	}
+	return "", args.Error(1)
+}
+
+func (m *MockUserRepository) SetLocale(ctx context.Context, userID int, locale models.Locale) error {
+	args := m.Called(ctx, userID, locale)
 	return args.Error(0)
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -18,7 +19,7 @@ func TestUserRepository_CreateUser(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserRepository(false, sqlxDB)
+	repo := NewUserRepository(sqlxDB)
 
 	user := models.User{
 		Username:     "testuser",
@@ -74,7 +75,7 @@ func TestUserRepository_GetUserByTelegramUser(t *testing.T) {
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserRepository(false, sqlxDB)
+	repo := NewUserRepository(sqlxDB)
 
 	now := time.Now()
 	telegramUser := "testtelegram"
@@ -110,34 +111,148 @@ func TestUserRepository_GetUserByTelegramUser(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestUserRepository_UpdateTelegramChatID(t *testing.T) {
+func TestUserRepository_GetUserByTelegramChatID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewUserRepository(sqlx.NewDb(db, "sqlmock"))
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{
+		"id", "username", "password", "email", "phone", "full_name",
+		"user_type", "telegram_user", "telegram_chat_id", "created_at", "updated_at",
+	}).
+		AddRow(1, "testuser", "hashedpassword", "test@example.com",
+			"1234567890", "Test User", "resident", "testtelegram", 12345, now, now)
+	mock.ExpectQuery(`SELECT (.+) FROM users WHERE telegram_chat_id`).
+		WithArgs(int64(12345)).
+		WillReturnRows(rows)
+
+	user, err := repo.GetUserByTelegramChatID(context.Background(), 12345)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_TelegramChat(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
 
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	repo := NewUserRepository(false, sqlxDB)
+	repo := NewUserRepository(sqlxDB)
 
-	telegramUsername := "testuser"
 	chatID := int64(12345)
 
-	t.Run("success", func(t *testing.T) {
-		mock.ExpectExec(`UPDATE users SET telegram_chat_id`).
-			WithArgs(chatID, telegramUsername).
+	t.Run("link takes the chat from its previous account", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE users SET telegram_chat_id = 0, .* WHERE telegram_chat_id = \$1 AND id <> \$2`).
+			WithArgs(chatID, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE users SET telegram_chat_id = \$1, .* WHERE id = \$2`).
+			WithArgs(chatID, 1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.UpdateTelegramChatID(context.Background(), telegramUsername, chatID)
+		err := repo.LinkTelegramChat(context.Background(), 1, chatID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("link to a missing user", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE users SET telegram_chat_id = 0`).
+			WithArgs(chatID, 99).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`UPDATE users SET telegram_chat_id = \$1`).
+			WithArgs(chatID, 99).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.LinkTelegramChat(context.Background(), 99, chatID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("unlink", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE users SET telegram_chat_id = 0, .* WHERE id = \$1`).
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.UnlinkTelegramChat(context.Background(), 1)
 		assert.NoError(t, err)
 	})
 
 	t.Run("error", func(t *testing.T) {
-		mock.ExpectExec(`UPDATE users SET telegram_chat_id`).
-			WithArgs(chatID, telegramUsername).
+		mock.ExpectExec(`UPDATE users SET telegram_chat_id = 0`).
+			WithArgs(1).
 			WillReturnError(sql.ErrConnDone)
 
-		err := repo.UpdateTelegramChatID(context.Background(), telegramUsername, chatID)
+		err := repo.UnlinkTelegramChat(context.Background(), 1)
 		assert.Error(t, err)
 	})
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestUserRepository_NotificationChannels(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	repo := NewUserRepository(sqlxDB)
+
+	t.Run("get", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT notification_channels FROM users`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"notification_channels"}).AddRow("{email,telegram}"))
+
+		channels, err := repo.GetNotificationChannels(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.NotificationChannel{models.EmailChannel, models.TelegramChannel}, channels)
+	})
+
+	t.Run("set", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE users SET notification_channels`).
+			WithArgs(pq.StringArray{"sms"}, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.SetNotificationChannels(context.Background(), 1, []models.NotificationChannel{models.SMSChannel})
+		assert.NoError(t, err)
+	})
+
+	t.Run("set for missing user", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE users SET notification_channels`).
+			WithArgs(pq.StringArray{"sms"}, 9).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.SetNotificationChannels(context.Background(), 9, []models.NotificationChannel{models.SMSChannel})
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+
+	t.Run("bill reminders", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT bill_reminders FROM users`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"bill_reminders"}).AddRow(true))
+		mock.ExpectExec(`UPDATE users SET bill_reminders`).
+			WithArgs(false, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		enabled, err := repo.GetBillReminders(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.True(t, enabled)
+		assert.NoError(t, repo.SetBillReminders(context.Background(), 1, false))
+	})
+
+	t.Run("locale", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT locale FROM users`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"locale"}).AddRow("fa"))
+		mock.ExpectExec(`UPDATE users SET locale`).
+			WithArgs(models.English, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		locale, err := repo.GetLocale(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, models.Persian, locale)
+		assert.NoError(t, repo.SetLocale(context.Background(), 1, models.English))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultInterval = 10 * time.Minute
+
+// a job gets the current time and does whatever became due until then
+type JobFunc func(ctx context.Context, now time.Time) error
+
+type job struct {
+	name string
+	run  JobFunc
+}
+
+// Scheduler runs its jobs one after another, once right at start (to catch up
+// after downtime) and then on every tick until the context is cancelled
+type Scheduler struct {
+	interval time.Duration
+	jobs     []job
+}
+
+func NewScheduler(interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Scheduler{
+		interval: interval,
+	}
+}
+
+func (s *Scheduler) AddJob(name string, run JobFunc) {
+	s.jobs = append(s.jobs, job{name: name, run: run})
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.runJobs(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runJobs(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) runJobs(ctx context.Context) {
+	now := time.Now()
+	for _, j := range s.jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := j.run(ctx, now); err != nil {
+			logrus.WithError(err).WithField("job", j.name).Error("Scheduled job failed")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerRunsJobsAtStartAndOnTick(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	record := func(name string, err error) JobFunc {
+		return func(ctx context.Context, now time.Time) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[name]++
+			if calls["first"] >= 2 && calls["second"] >= 2 {
+				cancel()
+			}
+			return err
+		}
+	}
+
+	s := NewScheduler(5 * time.Millisecond)
+	s.AddJob("first", record("first", errors.New("failing jobs do not stop the others")))
+	s.AddJob("second", record("second", nil))
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after the context was cancelled")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.GreaterOrEqual(t, calls["first"], 2)
+	assert.GreaterOrEqual(t, calls["second"], 2)
+}
+
+func TestNewSchedulerDefaultsInterval(t *testing.T) {
+	assert.Equal(t, defaultInterval, NewScheduler(0).interval)
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
@@ -22,8 +24,15 @@ type ApartmentService interface {
 	UpdateApartment(ctx context.Context, id int, apartmentName, address string, unitsCount, managerID int) error
 	DeleteApartment(ctx context.Context, id, managerId int) error
 	InviteUserToApartment(ctx context.Context, managerID, apartmentID int, telegramUsername string) (map[string]interface{}, error)
+	InviteByContact(ctx context.Context, managerID, apartmentID int, req dto.InviteRequest) (map[string]interface{}, error)
 	JoinApartment(ctx context.Context, userID int, token string) (map[string]interface{}, error)
 	LeaveApartment(ctx context.Context, userID, apartmentID int) error
+	SetDivisionPolicy(ctx context.Context, managerID, apartmentID int, policy models.DivisionPolicy) error
+	UpdateResidentShare(ctx context.Context, managerID, apartmentID, residentID int, unitArea float64, occupantsCount int, sharePercent float64) (*models.User_apartment, error)
+	GetMembers(ctx context.Context, userID, apartmentID int) ([]dto.MemberResponse, error)
+	GetManagers(ctx context.Context, userID, apartmentID int) ([]dto.MemberResponse, error)
+	GrantRole(ctx context.Context, granterID, apartmentID, memberID int, req dto.GrantRoleRequest) (*dto.MemberResponse, error)
+	RevokeRole(ctx context.Context, granterID, apartmentID, memberID int) error
 }
 
 type apartmentServiceImpl struct {
@@ -31,6 +40,7 @@ type apartmentServiceImpl struct {
 	userRepo            repositories.UserRepository
 	userApartmentRepo   repositories.UserApartmentRepository
 	inviteLinkRepo      repositories.InviteLinkRepo
+	uow                 repositories.UnitOfWork
 	notificationService notification.Notification
 }
 
@@ -39,6 +49,7 @@ func NewApartmentService(
 	userRepo repositories.UserRepository,
 	userApartmentRepo repositories.UserApartmentRepository,
 	inviteLinkRepo repositories.InviteLinkRepo,
+	uow repositories.UnitOfWork,
 	notificationService notification.Notification,
 ) ApartmentService {
 	return &apartmentServiceImpl{
@@ -46,6 +57,7 @@ func NewApartmentService(
 		userRepo:            userRepo,
 		userApartmentRepo:   userApartmentRepo,
 		inviteLinkRepo:      inviteLinkRepo,
+		uow:                 uow,
 		notificationService: notificationService,
 	}
 }
@@ -64,32 +76,40 @@ func (s *apartmentServiceImpl) CreateApartment(ctx context.Context, userID int,
 		ManagerID:     userID,
 	}
 
-	id, err := s.apartmentRepo.CreateApartment(ctx, apartment)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to create apartment")
-		return 0, fmt.Errorf("failed to create apartment: %w", err)
-	}
-
-	logrus.Infof("Apartment created with ID %d", id)
-
-	userApartment := models.User_apartment{
-		UserID:      userID,
-		ApartmentID: id,
-		IsManager:   true,
-	}
+	//an apartment without its owner's membership couldn't be managed by
+	//anyone, so both are created or neither
+	var id int
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = s.apartmentRepo.CreateApartment(ctx, apartment)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to create apartment")
+			return fmt.Errorf("failed to create apartment: %w", err)
+		}
 
-	if err := s.userApartmentRepo.CreateUserApartment(ctx, userApartment); err != nil {
-		logrus.WithError(err).Error("Failed to assign manager to apartment")
-		return 0, fmt.Errorf("failed to assign manager to apartment: %w", err)
+		userApartment := models.User_apartment{
+			UserID:      userID,
+			ApartmentID: id,
+			IsManager:   true,
+			Role:        models.OwnerRole,
+		}
+		if err := s.userApartmentRepo.CreateUserApartment(ctx, userApartment); err != nil {
+			logrus.WithError(err).Error("Failed to assign manager to apartment")
+			return fmt.Errorf("failed to assign manager to apartment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	logrus.Infof("User %d assigned as manager for apartment %d", userID, id)
+	logrus.Infof("Apartment %d created with user %d as its owner", id, userID)
 	return id, nil
 }
 
 func (s *apartmentServiceImpl) GetApartmentByID(ctx context.Context, id, managerId int) (*models.Apartment, error) {
 	logrus.Infof("Fetching apartment by ID %d", id)
-	if ok, err := s.userApartmentRepo.IsUserManagerOfApartment(ctx, managerId, id); err != nil || !ok {
+	if err := authorize(ctx, s.userApartmentRepo, managerId, id, models.PermViewApartment); err != nil {
 		return nil, fmt.Errorf("") // error khali bayad bashe
 	}
 	apartment, err := s.apartmentRepo.GetApartmentByID(id)
@@ -102,7 +122,7 @@ func (s *apartmentServiceImpl) GetApartmentByID(ctx context.Context, id, manager
 
 func (s *apartmentServiceImpl) GetResidentsInApartment(ctx context.Context, apartmentID, managerId int) ([]models.User, error) {
 	logrus.Infof("Fetching residents for apartment %d", apartmentID)
-	if ok, err := s.userApartmentRepo.IsUserManagerOfApartment(ctx, managerId, apartmentID); err != nil || !ok {
+	if err := authorize(ctx, s.userApartmentRepo, managerId, apartmentID, models.PermViewApartment); err != nil {
 		return nil, fmt.Errorf("") // error khali bayad bashe
 	}
 	residents, err := s.userApartmentRepo.GetResidentsInApartment(apartmentID)
@@ -123,9 +143,11 @@ func (s *apartmentServiceImpl) GetAllApartmentsForResident(ctx context.Context,
 	return apartments, nil
 }
 
+// updates the apartment's details. who manages it only changes through a
+// handover
 func (s *apartmentServiceImpl) UpdateApartment(ctx context.Context, id int, apartmentName, address string, unitsCount, managerID int) error {
 	logrus.Infof("Updating apartment %d by manager %d", id, managerID)
-	if ok, err := s.userApartmentRepo.IsUserManagerOfApartment(ctx, managerID, id); err != nil || !ok {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, id, models.PermManageApartment); err != nil {
 		return fmt.Errorf("") // error khali bayad bashe
 	}
 
@@ -137,7 +159,6 @@ func (s *apartmentServiceImpl) UpdateApartment(ctx context.Context, id int, apar
 		ApartmentName: apartmentName,
 		Address:       address,
 		UnitsCount:    unitsCount,
-		ManagerID:     managerID,
 	}
 
 	if err := s.apartmentRepo.UpdateApartment(ctx, apartment); err != nil {
@@ -150,18 +171,25 @@ func (s *apartmentServiceImpl) UpdateApartment(ctx context.Context, id int, apar
 func (s *apartmentServiceImpl) DeleteApartment(ctx context.Context, id, managerId int) error {
 	logrus.Infof("Deleting apartment %d", id)
 
-	if ok, err := s.userApartmentRepo.IsUserManagerOfApartment(ctx, managerId, id); err != nil || !ok {
+	if err := authorize(ctx, s.userApartmentRepo, managerId, id, models.PermDeleteApartment); err != nil {
 		return fmt.Errorf("") // error khali bayad bashe
 	}
 
-	if err := s.apartmentRepo.DeleteApartment(id); err != nil {
-		logrus.WithError(err).Errorf("Failed to delete apartment %d", id)
-		return fmt.Errorf("failed to delete apartment: %w", err)
-	}
-	err := s.userApartmentRepo.DeleteApartmentFromUserApartments(id)
+	//both go or neither does, a half deleted apartment would leave its
+	//residents members of nothing
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.apartmentRepo.DeleteApartment(ctx, id); err != nil {
+			logrus.WithError(err).Errorf("Failed to delete apartment %d", id)
+			return fmt.Errorf("failed to delete apartment: %w", err)
+		}
+		if err := s.userApartmentRepo.DeleteApartmentFromUserApartments(ctx, id); err != nil {
+			logrus.WithError(err).Errorf("Failed to remove apartment from user apartments %d", id)
+			return fmt.Errorf("failed to remove apartment from user apartments: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		logrus.WithError(err).Errorf("Failed to remove apartment from user apartments %d", id)
-		return fmt.Errorf("failed to remove apartment from user apartments: %w", err)
+		return err
 	}
 	logrus.Infof("Apartment %d deleted successfully", id)
 	return nil
@@ -174,14 +202,9 @@ func (s *apartmentServiceImpl) InviteUserToApartment(ctx context.Context, manage
 		"telegramUsername": telegramUsername,
 	}).Info("Inviting user to apartment")
 
-	isManager, err := s.userApartmentRepo.IsUserManagerOfApartment(ctx, managerID, apartmentID)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to verify manager status")
-		return nil, fmt.Errorf("failed to verify apartment manager status: %w", err)
-	}
-	if !isManager {
-		logrus.Warn("Non-manager attempted to invite user")
-		return nil, fmt.Errorf("only apartment managers can send invitations")
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermInviteMembers); err != nil {
+		logrus.WithError(err).Warn("User not allowed to invite to apartment")
+		return nil, fmt.Errorf("not allowed to send invitations: %w", err)
 	}
 
 	receiver, err := s.userRepo.GetUserByTelegramUser(telegramUsername)
@@ -202,29 +225,66 @@ func (s *apartmentServiceImpl) InviteUserToApartment(ctx context.Context, manage
 		return nil, fmt.Errorf("user is already a resident of this apartment")
 	}
 
-	generatedCode, err := s.inviteLinkRepo.CreateInvitation(ctx, receiver.ID, apartmentID, managerID)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to create invitation")
-		return nil, errors.New("failed to created invitation")
+	invitation := models.InvitationLink{
+		SenderID:         managerID,
+		ReceiverID:       receiver.ID,
+		ReceiverUsername: telegramUsername,
+		ReceiverChatID:   receiver.TelegramChatID,
+		ApartmentID:      apartmentID,
+	}
+	if err := s.createAndSendInvitation(ctx, &invitation); err != nil {
+		return nil, err
 	}
 
-	fmt.Println("Generated invitation code:", generatedCode)
+	logrus.Infof("Invitation sent successfully to %s", telegramUsername)
 
-	inviteURL := fmt.Sprintf("http://localhost:8080/api/v1/resident/apartment/invite/%s", generatedCode)
+	return map[string]interface{}{
+		"status":        "invitation sent",
+		"invitation_id": invitation.ID,
+		"expires_at":    invitation.ExpiresAt,
+	}, nil
+}
 
-	fmt.Println("Invite URL: ", inviteURL)
+// invites whoever the email or phone number belongs to. the invitation is
+// delivered to that address and accepted with its token, by signing up with
+// it or opening it once signed in. it isn't matched to a user with the same
+// email or phone number, those aren't verified
+func (s *apartmentServiceImpl) InviteByContact(ctx context.Context, managerID, apartmentID int, req dto.InviteRequest) (map[string]interface{}, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"managerID":   managerID,
+		"apartmentID": apartmentID,
+		"email":       req.Email,
+		"phone":       req.Phone,
+	})
+	logger.Info("Inviting contact to apartment")
+
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermInviteMembers); err != nil {
+		return nil, fmt.Errorf("not allowed to send invitations: %w", err)
+	}
 
-	err = s.notificationService.SendInvitation(ctx, inviteURL, apartmentID, telegramUsername)
+	email, phone, err := normalizeContact(req)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to send Telegram invitation")
-		return nil, errors.New("invitation created but failed to send notification")
+		return nil, err
+	}
+	invitation := models.InvitationLink{
+		SenderID:      managerID,
+		ReceiverEmail: email,
+		ReceiverPhone: phone,
+		ApartmentID:   apartmentID,
 	}
 
-	logrus.Infof("Invitation sent successfully to %s", telegramUsername)
+	if err := s.createAndSendInvitation(ctx, &invitation); err != nil {
+		return nil, err
+	}
 
+	//the invitation stands even when no channel can deliver it, the manager
+	//can pass the URL on instead
+	logger.WithField("invitationID", invitation.ID).Info("Invitation created")
 	return map[string]interface{}{
-		"status":     "invitation sent",
-		"expires_at": time.Now().Add(24 * time.Hour),
+		"status":        "invitation sent",
+		"invitation_id": invitation.ID,
+		"invite_url":    invitationURL(invitation.Token),
+		"expires_at":    invitation.ExpiresAt,
 	}, nil
 }
 
@@ -234,37 +294,48 @@ func (s *apartmentServiceImpl) JoinApartment(ctx context.Context, userID int, in
 		"invitationCode": invitationCode,
 	}).Info("User attempting to join apartment")
 
-	apartmentID, err := s.inviteLinkRepo.ValidateAndConsumeInvitation(ctx, invitationCode)
-	if err != nil {
-		logrus.WithError(err).Error("Invitation validation failed")
-		return nil, err
-	}
+	//the invitation stays open when joining fails
+	var apartmentID int
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		apartmentID, err = s.inviteLinkRepo.ValidateAndConsumeInvitation(ctx, invitationCode, userID)
+		if err != nil {
+			logrus.WithError(err).Error("Invitation validation failed")
+			return err
+		}
 
-	isResident, err := s.userApartmentRepo.IsUserInApartment(ctx, userID, apartmentID)
-	if err != nil {
-		if err.Error() != "not in apartment" {
-			logrus.WithError(err).Error("Failed to check if user is resident")
-			return nil, fmt.Errorf("failed to check resident status: %w", err)
+		isResident, err := s.userApartmentRepo.IsUserInApartment(ctx, userID, apartmentID)
+		if err != nil {
+			if err.Error() != "not in apartment" {
+				logrus.WithError(err).Error("Failed to check if user is resident")
+				return fmt.Errorf("failed to check resident status: %w", err)
+			}
+		}
+		if isResident {
+			logrus.Warn("User is already a resident of this apartment")
+			return fmt.Errorf("you are already a resident of this apartment")
 		}
-	}
-	if isResident {
-		logrus.Warn("User is already a resident of this apartment")
-		return nil, fmt.Errorf("you are already a resident of this apartment")
-	}
 
-	userApartment := models.User_apartment{
-		UserID:      userID,
-		ApartmentID: apartmentID,
-		IsManager:   false,
-	}
+		userApartment := models.User_apartment{
+			UserID:      userID,
+			ApartmentID: apartmentID,
+			IsManager:   false,
+			Role:        models.ResidentRole,
+		}
 
-	if err := s.userApartmentRepo.CreateUserApartment(ctx, userApartment); err != nil {
-		logrus.WithError(err).Error("Failed to join apartment")
-		return nil, fmt.Errorf("failed to join apartment: %w", err)
+		if err := s.userApartmentRepo.CreateUserApartment(ctx, userApartment); err != nil {
+			logrus.WithError(err).Error("Failed to join apartment")
+			return fmt.Errorf("failed to join apartment: %w", err)
+		}
+		return s.notificationService.SendNotification(ctx, userID, models.Notice{
+			Template:    models.JoinedApartmentTemplate,
+			ApartmentID: apartmentID,
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	s.notificationService.SendNotification(ctx, userID, "You joined apartment "+strconv.Itoa(apartmentID))
-
 	logrus.Infof("User %d joined apartment %d", userID, apartmentID)
 	return map[string]interface{}{
 		"status": "joined apartment",
@@ -274,9 +345,337 @@ func (s *apartmentServiceImpl) JoinApartment(ctx context.Context, userID int, in
 func (s *apartmentServiceImpl) LeaveApartment(ctx context.Context, userID, apartmentID int) error {
 	logrus.Infof("User %d is leaving apartment %d", userID, apartmentID)
 
+	apartment, err := s.apartmentRepo.GetApartmentByID(apartmentID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Failed to fetch apartment %d", apartmentID)
+		return fmt.Errorf("failed to get apartment: %w", err)
+	}
+	if apartment.ManagerID == userID {
+		return fmt.Errorf("the apartment's manager must hand it over before leaving")
+	}
+
 	if err := s.userApartmentRepo.DeleteUserApartment(userID, apartmentID); err != nil {
 		logrus.WithError(err).Error("Failed to leave apartment")
 		return fmt.Errorf("failed to leave apartment: %w", err)
 	}
 	return nil
 }
+
+func (s *apartmentServiceImpl) SetDivisionPolicy(ctx context.Context, managerID, apartmentID int, policy models.DivisionPolicy) error {
+	logrus.WithFields(logrus.Fields{
+		"managerID":   managerID,
+		"apartmentID": apartmentID,
+		"policy":      policy,
+	}).Info("Setting apartment division policy")
+
+	validPolicies := map[models.DivisionPolicy]bool{
+		models.EqualDivision:         true,
+		models.AreaDivision:          true,
+		models.OccupantsDivision:     true,
+		models.CustomDivision:        true,
+		models.UnitDivision:          true,
+		models.UnitAreaDivision:      true,
+		models.UnitOccupantsDivision: true,
+	}
+	if !validPolicies[policy] {
+		return fmt.Errorf("invalid division policy")
+	}
+
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageApartment); err != nil {
+		return fmt.Errorf("not allowed to change the division policy: %w", err)
+	}
+
+	if err := s.apartmentRepo.UpdateDivisionPolicy(ctx, apartmentID, policy); err != nil {
+		logrus.WithError(err).Errorf("Failed to update division policy of apartment %d", apartmentID)
+		return fmt.Errorf("failed to update division policy: %w", err)
+	}
+	return nil
+}
+
+// sets what the resident's share of divided bills is based on and returns
+// their updated membership
+func (s *apartmentServiceImpl) UpdateResidentShare(ctx context.Context, managerID, apartmentID, residentID int, unitArea float64, occupantsCount int, sharePercent float64) (*models.User_apartment, error) {
+	logrus.WithFields(logrus.Fields{
+		"managerID":   managerID,
+		"apartmentID": apartmentID,
+		"residentID":  residentID,
+	}).Info("Updating resident share")
+
+	if unitArea < 0 || occupantsCount < 0 || sharePercent < 0 || sharePercent > 100 {
+		return nil, fmt.Errorf("invalid share values")
+	}
+
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageApartment); err != nil {
+		return nil, fmt.Errorf("not allowed to change resident shares: %w", err)
+	}
+
+	userApartment := models.User_apartment{
+		UserID:         residentID,
+		ApartmentID:    apartmentID,
+		UnitArea:       unitArea,
+		OccupantsCount: occupantsCount,
+		SharePercent:   sharePercent,
+	}
+
+	if err := s.userApartmentRepo.UpdateResidentShare(ctx, userApartment); err != nil {
+		logrus.WithError(err).Errorf("Failed to update share of resident %d in apartment %d", residentID, apartmentID)
+		return nil, fmt.Errorf("failed to update resident share: %w", err)
+	}
+
+	updated, err := s.userApartmentRepo.GetUserApartmentByID(residentID, apartmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resident share: %w", err)
+	}
+	return updated, nil
+}
+
+// every member of the apartment with their role and permissions
+func (s *apartmentServiceImpl) GetMembers(ctx context.Context, userID, apartmentID int) ([]dto.MemberResponse, error) {
+	if err := authorize(ctx, s.userApartmentRepo, userID, apartmentID, models.PermViewApartment); err != nil {
+		return nil, fmt.Errorf("not allowed to view members: %w", err)
+	}
+
+	memberships, err := s.userApartmentRepo.GetUserApartmentsByApartment(apartmentID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Failed to get members of apartment %d", apartmentID)
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	users, err := s.userApartmentRepo.GetResidentsInApartment(apartmentID)
+	if err != nil {
+		logrus.WithError(err).Errorf("Failed to get residents of apartment %d", apartmentID)
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	usersByID := make(map[int]models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	members := make([]dto.MemberResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		members = append(members, memberResponse(membership, usersByID[membership.UserID]))
+	}
+	return members, nil
+}
+
+// the members who run the apartment: its owners and co-managers
+func (s *apartmentServiceImpl) GetManagers(ctx context.Context, userID, apartmentID int) ([]dto.MemberResponse, error) {
+	members, err := s.GetMembers(ctx, userID, apartmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	managers := []dto.MemberResponse{}
+	for _, member := range members {
+		if member.Role.IsManager() {
+			managers = append(managers, member)
+		}
+	}
+	return managers, nil
+}
+
+// gives a member a role, optionally with its own set of permissions. nobody
+// can change their own role, hand out permissions they don't have or change
+// the role of a member who can do things they can't, so an owner can only
+// be demoted by another owner
+func (s *apartmentServiceImpl) GrantRole(ctx context.Context, granterID, apartmentID, memberID int, req dto.GrantRoleRequest) (*dto.MemberResponse, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"granterID":   granterID,
+		"apartmentID": apartmentID,
+		"memberID":    memberID,
+		"role":        req.Role,
+	})
+	logger.Info("Granting apartment role")
+
+	if !req.Role.IsValid() {
+		return nil, fmt.Errorf("invalid role %q", req.Role)
+	}
+	var permissions models.Permission
+	if len(req.Permissions) > 0 {
+		var err error
+		if permissions, err = models.ParsePermissions(req.Permissions); err != nil {
+			return nil, err
+		}
+	}
+
+	granted := permissions
+	if granted == 0 {
+		granted = req.Role.Permissions()
+	}
+	member, err := s.checkRoleChange(ctx, granterID, apartmentID, memberID, granted)
+	if err != nil {
+		logger.WithError(err).Warn("Role grant rejected")
+		return nil, err
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.userApartmentRepo.SetMemberRole(ctx, memberID, apartmentID, req.Role, permissions); err != nil {
+			logger.WithError(err).Error("Failed to grant role")
+			return fmt.Errorf("failed to grant role: %w", err)
+		}
+		return s.notificationService.SendNotification(ctx, memberID, models.Notice{
+			Template:    models.RoleGrantedTemplate,
+			ApartmentID: apartmentID,
+			Data:        map[string]string{"role": string(req.Role)},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	member.Role, member.Permissions = req.Role, permissions
+
+	user, err := s.userRepo.GetUserByID(memberID)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to get member for response")
+		user = &models.User{}
+	}
+	response := memberResponse(*member, *user)
+	return &response, nil
+}
+
+// takes a granted role back, the member is left with what they joined as
+func (s *apartmentServiceImpl) RevokeRole(ctx context.Context, granterID, apartmentID, memberID int) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"granterID":   granterID,
+		"apartmentID": apartmentID,
+		"memberID":    memberID,
+	})
+	logger.Info("Revoking apartment role")
+
+	member, err := s.checkRoleChange(ctx, granterID, apartmentID, memberID, 0)
+	if err != nil {
+		logger.WithError(err).Warn("Role revoke rejected")
+		return err
+	}
+
+	//viewers stay viewers, revoking must never add permissions
+	base := models.ResidentRole
+	if member.Role == models.ViewerRole {
+		base = models.ViewerRole
+	}
+	if member.Role == base && member.Permissions == 0 {
+		return fmt.Errorf("member has no granted role to revoke")
+	}
+
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.userApartmentRepo.SetMemberRole(ctx, memberID, apartmentID, base, 0); err != nil {
+			logger.WithError(err).Error("Failed to revoke role")
+			return fmt.Errorf("failed to revoke role: %w", err)
+		}
+		return s.notificationService.SendNotification(ctx, memberID, models.Notice{
+			Template:    models.RoleRevokedTemplate,
+			ApartmentID: apartmentID,
+			Data:        map[string]string{"role": string(base)},
+		})
+	})
+}
+
+// checks that granterID may change memberID's role in the apartment to one
+// with the granted permissions, and returns the member's current membership
+func (s *apartmentServiceImpl) checkRoleChange(ctx context.Context, granterID, apartmentID, memberID int, granted models.Permission) (*models.User_apartment, error) {
+	if err := authorize(ctx, s.userApartmentRepo, granterID, apartmentID, models.PermManageRoles); err != nil {
+		return nil, fmt.Errorf("not allowed to manage roles: %w", err)
+	}
+	if granterID == memberID {
+		return nil, fmt.Errorf("%w: you can't change your own role", ErrForbidden)
+	}
+
+	member, err := s.userApartmentRepo.GetUserApartmentByID(memberID, apartmentID)
+	if err != nil {
+		return nil, fmt.Errorf("user is not a member of this apartment")
+	}
+
+	own, err := s.userApartmentRepo.GetMemberPermissions(ctx, granterID, apartmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permissions: %w", err)
+	}
+	if !own.Has(granted) {
+		return nil, fmt.Errorf("%w: can't grant %s", ErrForbidden, granted&^own)
+	}
+	if !own.Has(member.EffectivePermissions()) {
+		return nil, fmt.Errorf("%w: member can do more than you", ErrForbidden)
+	}
+	return member, nil
+}
+
+func memberResponse(membership models.User_apartment, user models.User) dto.MemberResponse {
+	role := membership.Role
+	if role == "" {
+		role = models.ResidentRole
+	}
+	return dto.MemberResponse{
+		UserID:       membership.UserID,
+		Username:     user.Username,
+		FullName:     user.FullName,
+		Role:         role,
+		Permissions:  membership.EffectivePermissions().Names(),
+		UnitID:       membership.UnitID,
+		UnitRelation: membership.UnitRelation,
+	}
+}
+
+// stores the invitation and queues it for its receiver, both or neither
+func (s *apartmentServiceImpl) createAndSendInvitation(ctx context.Context, invitation *models.InvitationLink) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.createInvitation(ctx, invitation); err != nil {
+			return err
+		}
+		if err := sendInvitation(ctx, s.notificationService, invitation); err != nil {
+			logrus.WithError(err).Error("Failed to send invitation")
+			return err
+		}
+		return nil
+	})
+}
+
+// gives the invitation its token and expiry and stores it
+func (s *apartmentServiceImpl) createInvitation(ctx context.Context, invitation *models.InvitationLink) error {
+	token, err := newInvitationToken()
+	if err != nil {
+		return err
+	}
+	invitation.Token = token
+	invitation.ExpiresAt = time.Now().Add(invitationTTL)
+	invitation.Status = models.InvitationStatusPending
+
+	invitation.ID, err = s.inviteLinkRepo.CreateInvitation(ctx, *invitation)
+	if errors.Is(err, repositories.ErrInvitationExists) {
+		return fmt.Errorf("%w, resend it instead", err)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create invitation")
+		return errors.New("failed to created invitation")
+	}
+	return nil
+}
+
+// the email lowercased and the phone number without spaces or dashes, at
+// least one of them is required
+func normalizeContact(req dto.InviteRequest) (string, string, error) {
+	email := normalizeEmail(req.Email)
+	phone := normalizePhone(req.Phone)
+	if email == "" && phone == "" {
+		return "", "", fmt.Errorf("an email or phone number is required")
+	}
+	if email != "" {
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			return "", "", fmt.Errorf("invalid email address")
+		}
+	}
+	if phone != "" {
+		digits := strings.TrimPrefix(phone, "+")
+		if len(digits) < 7 || len(digits) > 15 || strings.Trim(digits, "0123456789") != "" {
+			return "", "", fmt.Errorf("invalid phone number")
+		}
+	}
+	return email, phone, nil
+}
+
+// emails are stored and compared lowercased
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// phone numbers are stored and compared without spaces or dashes
+func normalizePhone(phone string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(phone))
+}
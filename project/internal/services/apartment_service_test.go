@@ -5,6 +5,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
@@ -74,8 +75,10 @@ func TestCreateApartment(t *testing.T) {
 			mockUserRepo := new(repositories.MockUserRepository)
 			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
 			mockInviteRepo := new(repositories.MockInviteLinkRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
 			mockNotif := new(notification.MockNotification)
 
+			mockUOW.On("Do", mock.Anything).Return(nil)
 			tt.mockSetup(mockAptRepo, mockUserAptRepo)
 
 			service := NewApartmentService(
@@ -83,6 +86,7 @@ func TestCreateApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				mockUOW,
 				mockNotif,
 			)
 
@@ -97,6 +101,8 @@ func TestCreateApartment(t *testing.T) {
 				assert.Equal(t, tt.expectedID, id)
 			}
 
+			//both inserts run in one transaction
+			mockUOW.AssertNumberOfCalls(t, "Do", 1)
 			mockAptRepo.AssertExpectations(t)
 			mockUserAptRepo.AssertExpectations(t)
 		})
@@ -117,7 +123,7 @@ func TestGetApartmentByID(t *testing.T) {
 			id:        1,
 			managerID: 1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				aptRepo.On("GetApartmentByID", 1).Return(&models.Apartment{
 					BaseModel:     models.BaseModel{ID: 1},
 					ApartmentName: "Sunny Apartments",
@@ -135,11 +141,11 @@ func TestGetApartmentByID(t *testing.T) {
 			},
 		},
 		{
-			name:      "not manager of apartment",
+			name:      "not a member of apartment",
 			id:        1,
 			managerID: 1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.Permission(0), nil)
 			},
 			expectedError: "",
 		},
@@ -148,7 +154,7 @@ func TestGetApartmentByID(t *testing.T) {
 			id:        1,
 			managerID: 1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, errors.New("database error"))
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.Permission(0), errors.New("database error"))
 			},
 			expectedError: "",
 		},
@@ -157,7 +163,7 @@ func TestGetApartmentByID(t *testing.T) {
 			id:        1,
 			managerID: 1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				aptRepo.On("GetApartmentByID", 1).Return((*models.Apartment)(nil), errors.New("not found"))
 			},
 			expectedError: "failed to get apartment",
@@ -180,6 +186,7 @@ func TestGetApartmentByID(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				nil,
 				mockNotif,
 			)
 
@@ -219,7 +226,7 @@ func TestGetResidentsInApartment(t *testing.T) {
 			apartmentID: 1,
 			managerID:   1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				userAptRepo.On("GetResidentsInApartment", 1).Return([]models.User{
 					{BaseModel: models.BaseModel{ID: 1}, Username: "user1"},
 					{BaseModel: models.BaseModel{ID: 2}, Username: "user2"},
@@ -231,11 +238,11 @@ func TestGetResidentsInApartment(t *testing.T) {
 			},
 		},
 		{
-			name:        "not manager of apartment",
+			name:        "not a member of apartment",
 			apartmentID: 1,
 			managerID:   1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.Permission(0), nil)
 			},
 			expectedError: "",
 		},
@@ -244,7 +251,7 @@ func TestGetResidentsInApartment(t *testing.T) {
 			apartmentID: 1,
 			managerID:   1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, errors.New("database error"))
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.Permission(0), errors.New("database error"))
 			},
 			expectedError: "",
 		},
@@ -253,7 +260,7 @@ func TestGetResidentsInApartment(t *testing.T) {
 			apartmentID: 1,
 			managerID:   1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				userAptRepo.On("GetResidentsInApartment", 1).Return(nil, errors.New("database error"))
 			},
 			expectedError: "failed to get residents",
@@ -276,6 +283,7 @@ func TestGetResidentsInApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				nil,
 				mockNotif,
 			)
 
@@ -319,13 +327,12 @@ func TestUpdateApartment(t *testing.T) {
 			unitsCount:    20,
 			managerID:     1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				aptRepo.On("UpdateApartment", mock.Anything, mock.MatchedBy(func(apt models.Apartment) bool {
 					return apt.ID == 1 &&
 						apt.ApartmentName == "Updated Name" &&
 						apt.Address == "Updated Address" &&
-						apt.UnitsCount == 20 &&
-						apt.ManagerID == 1
+						apt.UnitsCount == 20
 				})).Return(nil)
 			},
 		},
@@ -337,7 +344,7 @@ func TestUpdateApartment(t *testing.T) {
 			unitsCount:    20,
 			managerID:     1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ResidentRole.Permissions(), nil)
 			},
 			expectedError: "", // Empty error message as per the service implementation
 		},
@@ -349,7 +356,7 @@ func TestUpdateApartment(t *testing.T) {
 			unitsCount:    20,
 			managerID:     1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, errors.New("database error"))
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.Permission(0), errors.New("database error"))
 			},
 			expectedError: "", // Empty error message as per the service implementation
 		},
@@ -361,7 +368,7 @@ func TestUpdateApartment(t *testing.T) {
 			unitsCount:    20,
 			managerID:     1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				aptRepo.On("UpdateApartment", mock.Anything, mock.Anything).Return(errors.New("database error"))
 			},
 			expectedError: "failed to update apartment",
@@ -384,6 +391,7 @@ func TestUpdateApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				nil,
 				mockNotif,
 			)
 
@@ -411,6 +419,7 @@ func TestDeleteApartment(t *testing.T) {
 		id            int
 		managerID     int
 		mockSetup     func(*repositories.MockUserApartmentRepository, *repositories.MockApartmentRepo)
+		txErr         error
 		expectedError string
 	}{
 		{
@@ -418,9 +427,9 @@ func TestDeleteApartment(t *testing.T) {
 			id:        1,
 			managerID: 1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
-				aptRepo.On("DeleteApartment", 1).Return(nil)
-				userAptRepo.On("DeleteApartmentFromUserApartments", 1).Return(nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.OwnerRole.Permissions(), nil)
+				aptRepo.On("DeleteApartment", mock.Anything, 1).Return(nil)
+				userAptRepo.On("DeleteApartmentFromUserApartments", mock.Anything, 1).Return(nil)
 			},
 		},
 		{
@@ -428,7 +437,7 @@ func TestDeleteApartment(t *testing.T) {
 			id:        1,
 			managerID: 1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ResidentRole.Permissions(), nil)
 			},
 			expectedError: "",
 		},
@@ -437,7 +446,7 @@ func TestDeleteApartment(t *testing.T) {
 			id:        1,
 			managerID: 1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, errors.New("database error"))
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.Permission(0), errors.New("database error"))
 			},
 			expectedError: "",
 		},
@@ -446,8 +455,8 @@ func TestDeleteApartment(t *testing.T) {
 			id:        1,
 			managerID: 1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
-				aptRepo.On("DeleteApartment", 1).Return(errors.New("database error"))
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.OwnerRole.Permissions(), nil)
+				aptRepo.On("DeleteApartment", mock.Anything, 1).Return(errors.New("database error"))
 			},
 			expectedError: "failed to delete apartment",
 		},
@@ -456,12 +465,22 @@ func TestDeleteApartment(t *testing.T) {
 			id:        1,
 			managerID: 1,
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
-				aptRepo.On("DeleteApartment", 1).Return(nil)
-				userAptRepo.On("DeleteApartmentFromUserApartments", 1).Return(errors.New("database error"))
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.OwnerRole.Permissions(), nil)
+				aptRepo.On("DeleteApartment", mock.Anything, 1).Return(nil)
+				userAptRepo.On("DeleteApartmentFromUserApartments", mock.Anything, 1).Return(errors.New("database error"))
 			},
 			expectedError: "failed to remove apartment from user apartments",
 		},
+		{
+			name:      "transaction can't start",
+			id:        1,
+			managerID: 1,
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.OwnerRole.Permissions(), nil)
+			},
+			txErr:         errors.New("connection refused"),
+			expectedError: "connection refused",
+		},
 	}
 
 	for _, tt := range tests {
@@ -472,6 +491,8 @@ func TestDeleteApartment(t *testing.T) {
 			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
 			mockInviteRepo := new(repositories.MockInviteLinkRepository)
 			mockNotif := new(notification.MockNotification)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(tt.txErr).Maybe()
 
 			tt.mockSetup(mockUserAptRepo, mockAptRepo)
 
@@ -480,6 +501,7 @@ func TestDeleteApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				mockUOW,
 				mockNotif,
 			)
 
@@ -517,11 +539,15 @@ func TestInviteUserToApartment(t *testing.T) {
 			apartmentID:      1,
 			telegramUsername: "testuser",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				userRepo.On("GetUserByTelegramUser", "testuser").Return(&models.User{BaseModel: models.BaseModel{ID: 2}}, nil)
 				userAptRepo.On("IsUserInApartment", mock.Anything, 2, 1).Return(false, errors.New("not in apartment"))
-				inviteRepo.On("CreateInvitation", mock.Anything, 2, 1, 1).Return("invite123", nil)
-				notif.On("SendInvitation", mock.Anything, mock.Anything, 1, "testuser").Return(nil)
+				inviteRepo.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(invitation models.InvitationLink) bool {
+					return invitation.SenderID == 1 && invitation.ReceiverID == 2 && invitation.ApartmentID == 1 && invitation.Token != ""
+				})).Return(7, nil)
+				notif.On("SendInvitation", mock.Anything, mock.MatchedBy(func(invitation models.InvitationLink) bool {
+					return invitation.ID == 7 && invitation.ReceiverID == 2 && invitation.InviteURL != ""
+				})).Return(nil)
 			},
 			expectedResult: map[string]interface{}{
 				"status":     "invitation sent",
@@ -534,9 +560,9 @@ func TestInviteUserToApartment(t *testing.T) {
 			apartmentID:      1,
 			telegramUsername: "testuser",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ResidentRole.Permissions(), nil)
 			},
-			expectedError: "only apartment managers can send invitations",
+			expectedError: "not allowed to send invitations",
 		},
 		{
 			name:             "error verifying manager status",
@@ -544,9 +570,9 @@ func TestInviteUserToApartment(t *testing.T) {
 			apartmentID:      1,
 			telegramUsername: "testuser",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(false, errors.New("database error"))
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.Permission(0), errors.New("database error"))
 			},
-			expectedError: "failed to verify apartment manager status",
+			expectedError: "failed to check permissions",
 		},
 		{
 			name:             "user not found",
@@ -554,7 +580,7 @@ func TestInviteUserToApartment(t *testing.T) {
 			apartmentID:      1,
 			telegramUsername: "testuser",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				userRepo.On("GetUserByTelegramUser", "testuser").Return(nil, errors.New("not found"))
 			},
 			expectedError: "user with this Telegram username not found",
@@ -565,7 +591,7 @@ func TestInviteUserToApartment(t *testing.T) {
 			apartmentID:      1,
 			telegramUsername: "testuser",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				userRepo.On("GetUserByTelegramUser", "testuser").Return(&models.User{BaseModel: models.BaseModel{ID: 2}}, nil)
 				userAptRepo.On("IsUserInApartment", mock.Anything, 2, 1).Return(true, nil)
 			},
@@ -577,26 +603,39 @@ func TestInviteUserToApartment(t *testing.T) {
 			apartmentID:      1,
 			telegramUsername: "testuser",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				userRepo.On("GetUserByTelegramUser", "testuser").Return(&models.User{BaseModel: models.BaseModel{ID: 2}}, nil)
 				userAptRepo.On("IsUserInApartment", mock.Anything, 2, 1).Return(false, errors.New("not in apartment"))
-				inviteRepo.On("CreateInvitation", mock.Anything, 2, 1, 1).Return("", errors.New("creation failed"))
+				inviteRepo.On("CreateInvitation", mock.Anything, mock.Anything).Return(0, errors.New("creation failed"))
 			},
 			expectedError: "failed to created invitation",
 		},
+		{
+			name:             "already invited",
+			managerID:        1,
+			apartmentID:      1,
+			telegramUsername: "testuser",
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
+				userRepo.On("GetUserByTelegramUser", "testuser").Return(&models.User{BaseModel: models.BaseModel{ID: 2}}, nil)
+				userAptRepo.On("IsUserInApartment", mock.Anything, 2, 1).Return(false, errors.New("not in apartment"))
+				inviteRepo.On("CreateInvitation", mock.Anything, mock.Anything).Return(0, repositories.ErrInvitationExists)
+			},
+			expectedError: "resend it instead",
+		},
 		{
 			name:             "failed to send notification",
 			managerID:        1,
 			apartmentID:      1,
 			telegramUsername: "testuser",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				userAptRepo.On("IsUserManagerOfApartment", mock.Anything, 1, 1).Return(true, nil)
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
 				userRepo.On("GetUserByTelegramUser", "testuser").Return(&models.User{BaseModel: models.BaseModel{ID: 2}}, nil)
 				userAptRepo.On("IsUserInApartment", mock.Anything, 2, 1).Return(false, errors.New("not in apartment"))
-				inviteRepo.On("CreateInvitation", mock.Anything, 2, 1, 1).Return("invite123", nil)
-				notif.On("SendInvitation", mock.Anything, mock.Anything, 1, "testuser").Return(errors.New("send failed"))
+				inviteRepo.On("CreateInvitation", mock.Anything, mock.Anything).Return(7, nil)
+				notif.On("SendInvitation", mock.Anything, mock.Anything).Return(errors.New("failed to queue notification"))
 			},
-			expectedError: "invitation created but failed to send notification",
+			expectedError: "failed to queue notification",
 		},
 	}
 
@@ -607,8 +646,10 @@ func TestInviteUserToApartment(t *testing.T) {
 			mockUserRepo := new(repositories.MockUserRepository)
 			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
 			mockInviteRepo := new(repositories.MockInviteLinkRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
 			mockNotif := new(notification.MockNotification)
 
+			mockUOW.On("Do", mock.Anything).Return(nil)
 			tt.mockSetup(mockUserAptRepo, mockUserRepo, mockInviteRepo, mockNotif)
 
 			service := NewApartmentService(
@@ -616,6 +657,7 @@ func TestInviteUserToApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				mockUOW,
 				mockNotif,
 			)
 
@@ -628,6 +670,7 @@ func TestInviteUserToApartment(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, "invitation sent", result["status"])
+				assert.Equal(t, 7, result["invitation_id"])
 				assert.NotNil(t, result["expires_at"])
 			}
 
@@ -639,6 +682,96 @@ func TestInviteUserToApartment(t *testing.T) {
 	}
 }
 
+func TestInviteByContact(t *testing.T) {
+	tests := []struct {
+		name           string
+		req            dto.InviteRequest
+		mockSetup      func(*repositories.MockUserRepository, *repositories.MockInviteLinkRepository, *notification.MockNotification)
+		expectedStatus string
+		expectedError  string
+	}{
+		{
+			name: "someone who hasn't signed up",
+			req:  dto.InviteRequest{Email: " Neighbour@Example.com "},
+			mockSetup: func(userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
+				inviteRepo.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(invitation models.InvitationLink) bool {
+					return invitation.ReceiverID == 0 && invitation.ReceiverEmail == "neighbour@example.com" && invitation.Token != ""
+				})).Return(7, nil)
+				notif.On("SendInvitation", mock.Anything, mock.MatchedBy(func(invitation models.InvitationLink) bool {
+					return invitation.ReceiverEmail == "neighbour@example.com"
+				})).Return(nil)
+			},
+			expectedStatus: "invitation sent",
+		},
+		{
+			name: "phone number is not matched to the user who has it",
+			req:  dto.InviteRequest{Phone: "+98 912-345-6789"},
+			mockSetup: func(userRepo *repositories.MockUserRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
+				inviteRepo.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(invitation models.InvitationLink) bool {
+					return invitation.ReceiverID == 0 && invitation.ReceiverPhone == "+989123456789"
+				})).Return(7, nil)
+				notif.On("SendInvitation", mock.Anything, mock.MatchedBy(func(invitation models.InvitationLink) bool {
+					return invitation.ReceiverID == 0 && invitation.ReceiverPhone == "+989123456789"
+				})).Return(nil)
+			},
+			expectedStatus: "invitation sent",
+		},
+		{
+			name: "no contact",
+			req:  dto.InviteRequest{},
+			mockSetup: func(*repositories.MockUserRepository, *repositories.MockInviteLinkRepository, *notification.MockNotification) {
+			},
+			expectedError: "an email or phone number is required",
+		},
+		{
+			name: "invalid email",
+			req:  dto.InviteRequest{Email: "neighbour"},
+			mockSetup: func(*repositories.MockUserRepository, *repositories.MockInviteLinkRepository, *notification.MockNotification) {
+			},
+			expectedError: "invalid email address",
+		},
+		{
+			name: "invalid phone number",
+			req:  dto.InviteRequest{Phone: "12ab"},
+			mockSetup: func(*repositories.MockUserRepository, *repositories.MockInviteLinkRepository, *notification.MockNotification) {
+			},
+			expectedError: "invalid phone number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := new(repositories.MockUserRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockInviteRepo := new(repositories.MockInviteLinkRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockNotif := new(notification.MockNotification)
+
+			mockUOW.On("Do", mock.Anything).Return(nil)
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
+			mockUserAptRepo.On("IsUserInApartment", mock.Anything, mock.Anything, 1).Return(false, errors.New("not in apartment"))
+			tt.mockSetup(mockUserRepo, mockInviteRepo, mockNotif)
+
+			service := NewApartmentService(nil, mockUserRepo, mockUserAptRepo, mockInviteRepo, mockUOW, mockNotif)
+			result, err := service.InviteByContact(context.Background(), 1, 1, tt.req)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				mockInviteRepo.AssertNotCalled(t, "CreateInvitation", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, result["status"])
+			assert.Contains(t, result["invite_url"], "/resident/apartment/invite/")
+			mockUserRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything)
+			mockUserRepo.AssertNotCalled(t, "GetUserByPhone", mock.Anything)
+			mockInviteRepo.AssertExpectations(t)
+			mockNotif.AssertExpectations(t)
+		})
+	}
+}
+
 func TestJoinApartment(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -653,12 +786,15 @@ func TestJoinApartment(t *testing.T) {
 			userID:         1,
 			invitationCode: "validcode",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "validcode").Return(1, nil)
+				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "validcode", 1).Return(1, nil)
 				userAptRepo.On("IsUserInApartment", mock.Anything, 1, 1).Return(false, errors.New("not in apartment"))
 				userAptRepo.On("CreateUserApartment", mock.Anything, mock.MatchedBy(func(ua models.User_apartment) bool {
 					return ua.UserID == 1 && ua.ApartmentID == 1 && !ua.IsManager
 				})).Return(nil)
-				notif.On("SendNotification", mock.Anything, 1, "You joined apartment 1").Return(nil)
+				notif.On("SendNotification", mock.Anything, 1, models.Notice{
+					Template:    models.JoinedApartmentTemplate,
+					ApartmentID: 1,
+				}).Return(nil)
 			},
 			expectedResult: map[string]interface{}{
 				"status": "joined apartment",
@@ -669,7 +805,7 @@ func TestJoinApartment(t *testing.T) {
 			userID:         1,
 			invitationCode: "invalidcode",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "invalidcode").Return(0, errors.New("invalid code"))
+				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "invalidcode", 1).Return(0, errors.New("invalid code"))
 			},
 			expectedError: "invalid code",
 		},
@@ -678,7 +814,7 @@ func TestJoinApartment(t *testing.T) {
 			userID:         1,
 			invitationCode: "validcode",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "validcode").Return(1, nil)
+				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "validcode", 1).Return(1, nil)
 				userAptRepo.On("IsUserInApartment", mock.Anything, 1, 1).Return(true, nil)
 			},
 			expectedError: "you are already a resident of this apartment",
@@ -688,7 +824,7 @@ func TestJoinApartment(t *testing.T) {
 			userID:         1,
 			invitationCode: "validcode",
 			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, inviteRepo *repositories.MockInviteLinkRepository, notif *notification.MockNotification) {
-				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "validcode").Return(1, nil)
+				inviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "validcode", 1).Return(1, nil)
 				userAptRepo.On("IsUserInApartment", mock.Anything, 1, 1).Return(false, errors.New("not in apartment"))
 				userAptRepo.On("CreateUserApartment", mock.Anything, mock.MatchedBy(func(ua models.User_apartment) bool {
 					return ua.UserID == 1 && ua.ApartmentID == 1 && !ua.IsManager
@@ -707,6 +843,9 @@ func TestJoinApartment(t *testing.T) {
 			mockInviteRepo := new(repositories.MockInviteLinkRepository)
 			mockNotif := new(notification.MockNotification)
 
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+
 			tt.mockSetup(mockUserAptRepo, mockInviteRepo, mockNotif)
 
 			service := NewApartmentService(
@@ -714,6 +853,7 @@ func TestJoinApartment(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				mockUOW,
 				mockNotif,
 			)
 
@@ -740,22 +880,33 @@ func TestLeaveApartment(t *testing.T) {
 		name          string
 		userID        int
 		apartmentID   int
-		mockSetup     func(*repositories.MockUserApartmentRepository)
+		mockSetup     func(*repositories.MockUserApartmentRepository, *repositories.MockApartmentRepo)
 		expectedError string
 	}{
 		{
 			name:        "successful leave",
 			userID:      1,
 			apartmentID: 1,
-			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
+				aptRepo.On("GetApartmentByID", 1).Return(&models.Apartment{ManagerID: 2}, nil)
 				userAptRepo.On("DeleteUserApartment", 1, 1).Return(nil)
 			},
 		},
+		{
+			name:        "manager must hand over first",
+			userID:      1,
+			apartmentID: 1,
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
+				aptRepo.On("GetApartmentByID", 1).Return(&models.Apartment{ManagerID: 1}, nil)
+			},
+			expectedError: "hand it over before leaving",
+		},
 		{
 			name:        "failed to leave",
 			userID:      1,
 			apartmentID: 1,
-			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository) {
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, aptRepo *repositories.MockApartmentRepo) {
+				aptRepo.On("GetApartmentByID", 1).Return(&models.Apartment{ManagerID: 2}, nil)
 				userAptRepo.On("DeleteUserApartment", 1, 1).Return(errors.New("database error"))
 			},
 			expectedError: "failed to leave apartment",
@@ -771,13 +922,14 @@ func TestLeaveApartment(t *testing.T) {
 			mockInviteRepo := new(repositories.MockInviteLinkRepository)
 			mockNotif := new(notification.MockNotification)
 
-			tt.mockSetup(mockUserAptRepo)
+			tt.mockSetup(mockUserAptRepo, mockAptRepo)
 
 			service := NewApartmentService(
 				mockAptRepo,
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				nil,
 				mockNotif,
 			)
 
@@ -843,6 +995,7 @@ func TestGetAllApartmentsForResident(t *testing.T) {
 				mockUserRepo,
 				mockUserAptRepo,
 				mockInviteRepo,
+				nil,
 				mockNotif,
 			)
 
@@ -861,3 +1014,196 @@ func TestGetAllApartmentsForResident(t *testing.T) {
 		})
 	}
 }
+
+func TestGrantRole(t *testing.T) {
+	member := func(role models.Role) *models.User_apartment {
+		return &models.User_apartment{UserID: 2, ApartmentID: 1, Role: role}
+	}
+
+	tests := []struct {
+		name          string
+		granterID     int
+		req           dto.GrantRoleRequest
+		mockSetup     func(*repositories.MockUserApartmentRepository, *repositories.MockUserRepository, *notification.MockNotification)
+		expectedError string
+		forbidden     bool
+	}{
+		{
+			name:      "owner makes a resident treasurer",
+			granterID: 1,
+			req:       dto.GrantRoleRequest{Role: models.TreasurerRole},
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.OwnerRole.Permissions(), nil)
+				userAptRepo.On("GetUserApartmentByID", 2, 1).Return(member(models.ResidentRole), nil)
+				userAptRepo.On("SetMemberRole", mock.Anything, 2, 1, models.TreasurerRole, models.Permission(0)).Return(nil)
+				userRepo.On("GetUserByID", 2).Return(&models.User{BaseModel: models.BaseModel{ID: 2}, Username: "sara"}, nil)
+				notif.On("SendNotification", mock.Anything, 2, models.Notice{
+					Template:    models.RoleGrantedTemplate,
+					ApartmentID: 1,
+					Data:        map[string]string{"role": "treasurer"},
+				}).Return(nil)
+			},
+		},
+		{
+			name:      "custom permissions",
+			granterID: 1,
+			req:       dto.GrantRoleRequest{Role: models.ViewerRole, Permissions: []string{"view_apartment", "view_finances"}},
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
+				userAptRepo.On("GetUserApartmentByID", 2, 1).Return(member(models.ResidentRole), nil)
+				userAptRepo.On("SetMemberRole", mock.Anything, 2, 1, models.ViewerRole, models.PermViewApartment|models.PermViewFinances).Return(nil)
+				userRepo.On("GetUserByID", 2).Return(&models.User{BaseModel: models.BaseModel{ID: 2}}, nil)
+				notif.On("SendNotification", mock.Anything, 2, mock.Anything).Return(nil)
+			},
+		},
+		{
+			name:      "unknown role",
+			granterID: 1,
+			req:       dto.GrantRoleRequest{Role: "janitor"},
+			mockSetup: func(*repositories.MockUserApartmentRepository, *repositories.MockUserRepository, *notification.MockNotification) {
+			},
+			expectedError: "invalid role",
+		},
+		{
+			name:      "unknown permission",
+			granterID: 1,
+			req:       dto.GrantRoleRequest{Role: models.ViewerRole, Permissions: []string{"fly"}},
+			mockSetup: func(*repositories.MockUserApartmentRepository, *repositories.MockUserRepository, *notification.MockNotification) {
+			},
+			expectedError: "unknown permission",
+		},
+		{
+			name:      "resident can't manage roles",
+			granterID: 1,
+			req:       dto.GrantRoleRequest{Role: models.ViewerRole},
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ResidentRole.Permissions(), nil)
+			},
+			expectedError: "not allowed to manage roles",
+			forbidden:     true,
+		},
+		{
+			name:      "own role",
+			granterID: 2,
+			req:       dto.GrantRoleRequest{Role: models.OwnerRole},
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 2, 1).Return(models.ManagerRole.Permissions(), nil)
+			},
+			expectedError: "your own role",
+			forbidden:     true,
+		},
+		{
+			name:      "manager can't make an owner",
+			granterID: 1,
+			req:       dto.GrantRoleRequest{Role: models.OwnerRole},
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
+				userAptRepo.On("GetUserApartmentByID", 2, 1).Return(member(models.ResidentRole), nil)
+			},
+			expectedError: "can't grant delete_apartment",
+			forbidden:     true,
+		},
+		{
+			name:      "manager can't demote an owner",
+			granterID: 1,
+			req:       dto.GrantRoleRequest{Role: models.ResidentRole},
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
+				userAptRepo.On("GetUserApartmentByID", 2, 1).Return(member(models.OwnerRole), nil)
+			},
+			expectedError: "member can do more than you",
+			forbidden:     true,
+		},
+		{
+			name:      "not a member",
+			granterID: 1,
+			req:       dto.GrantRoleRequest{Role: models.ManagerRole},
+			mockSetup: func(userAptRepo *repositories.MockUserApartmentRepository, userRepo *repositories.MockUserRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.OwnerRole.Permissions(), nil)
+				userAptRepo.On("GetUserApartmentByID", 2, 1).Return(nil, errors.New("not found"))
+			},
+			expectedError: "not a member of this apartment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := new(repositories.MockUserRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockNotif := new(notification.MockNotification)
+
+			mockUOW.On("Do", mock.Anything).Return(nil)
+			tt.mockSetup(mockUserAptRepo, mockUserRepo, mockNotif)
+
+			service := NewApartmentService(nil, mockUserRepo, mockUserAptRepo, nil, mockUOW, mockNotif)
+			result, err := service.GrantRole(context.Background(), tt.granterID, 1, 2, tt.req)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				assert.Equal(t, tt.forbidden, errors.Is(err, ErrForbidden))
+				mockUserAptRepo.AssertNotCalled(t, "SetMemberRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 2, result.UserID)
+			assert.Equal(t, tt.req.Role, result.Role)
+			mockUserAptRepo.AssertExpectations(t)
+			mockNotif.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRevokeRole(t *testing.T) {
+	tests := []struct {
+		name          string
+		member        models.User_apartment
+		expectedRole  models.Role
+		expectedError string
+	}{
+		{
+			name:         "treasurer goes back to resident",
+			member:       models.User_apartment{Role: models.TreasurerRole},
+			expectedRole: models.ResidentRole,
+		},
+		{
+			name:         "viewer loses custom permissions",
+			member:       models.User_apartment{Role: models.ViewerRole, Permissions: models.PermViewApartment | models.PermViewFinances},
+			expectedRole: models.ViewerRole,
+		},
+		{
+			name:          "nothing to revoke",
+			member:        models.User_apartment{Role: models.ResidentRole},
+			expectedError: "no granted role",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockNotif := new(notification.MockNotification)
+
+			mockUOW.On("Do", mock.Anything).Return(nil)
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.OwnerRole.Permissions(), nil)
+			mockUserAptRepo.On("GetUserApartmentByID", 2, 1).Return(&tt.member, nil)
+			if tt.expectedError == "" {
+				mockUserAptRepo.On("SetMemberRole", mock.Anything, 2, 1, tt.expectedRole, models.Permission(0)).Return(nil)
+				mockNotif.On("SendNotification", mock.Anything, 2, mock.Anything).Return(nil)
+			}
+
+			service := NewApartmentService(nil, nil, mockUserAptRepo, nil, mockUOW, mockNotif)
+			err := service.RevokeRole(context.Background(), 1, 1, 2)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			mockUserAptRepo.AssertExpectations(t)
+			mockNotif.AssertExpectations(t)
+		})
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
-	"strconv"
+	"net/url"
 	"time"
 
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
@@ -20,17 +21,27 @@ import (
 
 type BillService interface {
 	CreateBill(ctx context.Context, userID, apartmentID int, req dto.CreateBillRequest, file io.ReadCloser, handler *multipart.FileHeader) (map[string]interface{}, error)
-	GetBillByID(ctx context.Context, id int) (map[string]interface{}, error)
-	GetBillsByApartmentID(ctx context.Context, apartmentID int) ([]models.Bill, error)
-	UpdateBill(ctx context.Context, id, apartmentID int, billType string, totalAmount float64, dueDate, billingDeadline, description string) error
-	DeleteBill(ctx context.Context, id int) error
-	PayBills(ctx context.Context, userID int, paymentIDs []int, idempotentKey string) error
+	GetBillByID(ctx context.Context, userID, id int) (map[string]interface{}, error)
+	GetBillsByApartmentID(ctx context.Context, userID, apartmentID int) ([]models.Bill, error)
+	UpdateBill(ctx context.Context, userID, id, apartmentID int, billType string, totalAmount models.Money, dueDate, billingDeadline, description string) error
+	DeleteBill(ctx context.Context, userID, id int) error
+	PayBills(ctx context.Context, userID int, paymentIDs []int, idempotentKey string) (map[string]interface{}, error)
 	PayBatchBills(ctx context.Context, userID int, idempotentKey string) (map[string]interface{}, error)
-	GetUnpaidBills(ctx context.Context, userID int) ([]models.Payment, error)
+	ConfirmPayment(ctx context.Context, providerName string, params url.Values) (map[string]interface{}, error)
+	ExpireStalePayments(ctx context.Context, now time.Time) (int, error)
+	RefundPayment(ctx context.Context, managerID, paymentID int, amount models.Money, reason string) (*models.Payment, error)
+	RecordChargeback(ctx context.Context, managerID, paymentID int, reason string) (*models.Payment, error)
+	GetPaymentTransitions(ctx context.Context, managerID, paymentID int) ([]models.PaymentTransition, error)
+	PayPartial(ctx context.Context, userID, paymentID int, amount models.Money, idempotentKey string) (map[string]interface{}, error)
+	GetUnpaidBills(ctx context.Context, userID int) ([]OutstandingBalance, error)
+	SetInstallmentPlan(ctx context.Context, managerID, paymentID int, req dto.InstallmentPlanRequest) ([]models.Installment, error)
+	GetInstallmentPlan(ctx context.Context, userID, paymentID int) ([]models.Installment, error)
+	DeleteInstallmentPlan(ctx context.Context, managerID, paymentID int) error
 	GetBillWithPaymentStatus(ctx context.Context, userID, billID int) (map[string]interface{}, error)
 	GetUserPaymentHistory(ctx context.Context, userID int) ([]PaymentHistoryItem, error)
 	DivideBillByType(ctx context.Context, userID, apartmentID int, billType models.BillType) (map[string]interface{}, error)
 	DivideAllBills(ctx context.Context, userID, apartmentID int) (map[string]interface{}, error)
+	DivideBill(ctx context.Context, bill models.Bill) error
 }
 
 type PaymentHistoryItem struct {
@@ -39,12 +50,29 @@ type PaymentHistoryItem struct {
 	ApartmentName string         `json:"apartment_name"`
 }
 
+// OutstandingBalance is what is left to pay on one of a resident's shares
+type OutstandingBalance struct {
+	PaymentID          int                  `json:"payment_id"`
+	BillID             int                  `json:"bill_id"`
+	Status             models.PaymentStatus `json:"status"`
+	Amount             models.Money         `json:"amount"`
+	PaidAmount         models.Money         `json:"paid_amount"`
+	LateFee            models.Money         `json:"late_fee"`
+	Outstanding        models.Money         `json:"outstanding"`
+	NextInstallment    *models.Installment  `json:"next_installment,omitempty"`
+	NextInstallmentDue *models.Money        `json:"next_installment_due,omitempty"` // left to pay on the next installment
+}
+
 type billServiceImpl struct {
 	repo                repositories.BillRepository
 	userRepo            repositories.UserRepository
 	apartmentRepo       repositories.ApartmentRepository
 	userApartmentRepo   repositories.UserApartmentRepository
+	unitRepo            repositories.UnitRepository
 	paymentRepo         repositories.PaymentRepository
+	installmentRepo     repositories.InstallmentRepository
+	ledgerRepo          repositories.LedgerRepository
+	uow                 repositories.UnitOfWork
 	imageService        image.Image
 	paymentService      payment.Payment
 	notificationService notification.Notification
@@ -55,7 +83,11 @@ func NewBillService(
 	userRepo repositories.UserRepository,
 	apartmentRepo repositories.ApartmentRepository,
 	userApartmentRepo repositories.UserApartmentRepository,
+	unitRepo repositories.UnitRepository,
 	paymentRepo repositories.PaymentRepository,
+	installmentRepo repositories.InstallmentRepository,
+	ledgerRepo repositories.LedgerRepository,
+	uow repositories.UnitOfWork,
 	imageService image.Image,
 	paymentService payment.Payment,
 	notificationService notification.Notification,
@@ -65,7 +97,11 @@ func NewBillService(
 		userRepo:            userRepo,
 		apartmentRepo:       apartmentRepo,
 		userApartmentRepo:   userApartmentRepo,
+		unitRepo:            unitRepo,
 		paymentRepo:         paymentRepo,
+		installmentRepo:     installmentRepo,
+		ledgerRepo:          ledgerRepo,
+		uow:                 uow,
 		imageService:        imageService,
 		paymentService:      paymentService,
 		notificationService: notificationService,
@@ -89,17 +125,12 @@ func (s *billServiceImpl) CreateBill(ctx context.Context, userID, apartmentID in
 		return nil, fmt.Errorf("the apartment id is incorrect: %w", err)
 	}
 
-	isManager, err := s.userApartmentRepo.IsUserManagerOfApartment(ctx, userID, apartmentID)
-	if err != nil {
-		logger.WithError(err).Error("Failed to verify manager status")
-		return nil, fmt.Errorf("failed to verify manager status: %w", err)
-	}
-	if !isManager {
-		logger.Warn("Non-manager user attempted to create bill")
-		return nil, fmt.Errorf("only apartment managers can create bills")
+	if err := authorize(ctx, s.userApartmentRepo, userID, apartmentID, models.PermManageBills); err != nil {
+		logger.WithError(err).Warn("User not allowed to create bill")
+		return nil, fmt.Errorf("not allowed to create bills: %w", err)
 	}
 
-	if req.BillType == "" || req.TotalAmount <= 0 || req.DueDate == "" {
+	if req.BillType == "" || !req.TotalAmount.IsPositive() || req.DueDate == "" {
 		logger.Error("Missing required fields for bill creation")
 		return nil, fmt.Errorf("missing required fields")
 	}
@@ -191,24 +222,15 @@ func (s *billServiceImpl) DivideBillByType(ctx context.Context, userID, apartmen
 
 	logger.Info("Starting bill division by type")
 
-	isManager, err := s.userApartmentRepo.IsUserManagerOfApartment(ctx, userID, apartmentID)
-	if err != nil {
-		logger.WithError(err).Error("Failed to verify manager status")
-		return nil, fmt.Errorf("failed to verify manager status: %w", err)
-	}
-	if !isManager {
-		logger.Warn("Non-manager user attempted to divide bills")
-		return nil, fmt.Errorf("only apartment managers can divide bills")
+	if err := authorize(ctx, s.userApartmentRepo, userID, apartmentID, models.PermManageBills); err != nil {
+		logger.WithError(err).Warn("User not allowed to divide bills")
+		return nil, fmt.Errorf("not allowed to divide bills: %w", err)
 	}
 
-	residents, err := s.userApartmentRepo.GetResidentsInApartment(apartmentID)
+	apartment, residents, weights, err := s.getDivisionWeights(ctx, apartmentID)
 	if err != nil {
-		logger.WithError(err).Error("Failed to get residents")
-		return nil, fmt.Errorf("failed to get residents: %w", err)
-	}
-	if len(residents) == 0 {
-		logger.Warn("No residents found in apartment")
-		return nil, fmt.Errorf("no residents found in apartment")
+		logger.WithError(err).Error("Failed to prepare bill division")
+		return nil, err
 	}
 
 	logger.WithField("residents_count", len(residents)).Debug("Retrieved residents for bill division")
@@ -226,79 +248,20 @@ func (s *billServiceImpl) DivideBillByType(ctx context.Context, userID, apartmen
 
 	logger.WithField("bills_count", len(bills)).Info("Processing undivided bills")
 
-	var processedBills []int
-	var failedBills []int
-	var totalFailedPayments int
-
-	for _, bill := range bills {
-		billLogger := logger.WithFields(logrus.Fields{
-			"bill_id":     bill.ID,
-			"bill_amount": bill.TotalAmount,
-		})
-
-		amountPerResident := bill.TotalAmount / float64(len(residents))
-		billProcessed := true
-
-		for _, resident := range residents {
-			//checking if payment record already exists
-			existingPayment, _ := s.paymentRepo.GetPaymentByBillAndUser(bill.ID, resident.ID)
-			if existingPayment != nil {
-				continue // if payment record already exists
-			}
-
-			payment := models.Payment{
-				BaseModel: models.BaseModel{
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
-				},
-				BillID:        bill.ID,
-				UserID:        resident.ID,
-				Amount:        fmt.Sprintf("%.2f", amountPerResident),
-				PaymentStatus: models.Pending,
-			}
-
-			_, err := s.paymentRepo.CreatePayment(ctx, payment)
-			if err != nil {
-				billLogger.WithError(err).WithField("resident_id", resident.ID).Error("Failed to create payment record")
-				totalFailedPayments++
-				billProcessed = false
-				continue
-			}
-
-			//sending notification
-			if err := s.notificationService.SendBillNotification(ctx, resident.ID, bill, amountPerResident); err != nil {
-				billLogger.WithError(err).WithField("resident_id", resident.ID).Warn("Failed to send notification")
-			}
-		}
-
-		if billProcessed {
-			processedBills = append(processedBills, bill.ID)
-			billLogger.Debug("Bill processed successfully")
-		} else {
-			failedBills = append(failedBills, bill.ID)
-			billLogger.Error("Bill processing failed")
-		}
+	processedBills, err := s.divideBills(ctx, logger, bills, residents, weights)
+	if err != nil {
+		return nil, err
 	}
 
-	logger.WithFields(logrus.Fields{
-		"processed_count": len(processedBills),
-		"failed_count":    len(failedBills),
-	}).Info("Bill division completed")
+	logger.WithField("processed_count", len(processedBills)).Info("Bill division completed")
 
-	response := map[string]interface{}{
+	return map[string]interface{}{
 		"bill_type":       billType,
 		"residents_count": len(residents),
+		"division_policy": apartment.DivisionPolicy,
 		"processed_bills": processedBills,
 		"processed_count": len(processedBills),
-	}
-
-	if len(failedBills) > 0 {
-		response["warning"] = fmt.Sprintf("Failed to process %d bills completely", len(failedBills))
-		response["failed_bills"] = failedBills
-		response["failed_payments_count"] = totalFailedPayments
-	}
-
-	return response, nil
+	}, nil
 }
 
 func (s *billServiceImpl) DivideAllBills(ctx context.Context, userID, apartmentID int) (map[string]interface{}, error) {
@@ -309,25 +272,15 @@ func (s *billServiceImpl) DivideAllBills(ctx context.Context, userID, apartmentI
 
 	logger.Info("Starting division of all bills")
 
-	isManager, err := s.userApartmentRepo.IsUserManagerOfApartment(ctx, userID, apartmentID)
-	if err != nil {
-		logger.WithError(err).Error("Failed to verify manager status")
-		return nil, fmt.Errorf("failed to verify manager status: %w", err)
-	}
-	if !isManager {
-		logger.Warn("Non-manager user attempted to divide all bills")
-		return nil, fmt.Errorf("only apartment managers can divide bills")
+	if err := authorize(ctx, s.userApartmentRepo, userID, apartmentID, models.PermManageBills); err != nil {
+		logger.WithError(err).Warn("User not allowed to divide all bills")
+		return nil, fmt.Errorf("not allowed to divide bills: %w", err)
 	}
 
-	// Get current residents
-	residents, err := s.userApartmentRepo.GetResidentsInApartment(apartmentID)
+	apartment, residents, weights, err := s.getDivisionWeights(ctx, apartmentID)
 	if err != nil {
-		logger.WithError(err).Error("Failed to get residents")
-		return nil, fmt.Errorf("failed to get residents: %w", err)
-	}
-	if len(residents) == 0 {
-		logger.Warn("No residents found in apartment")
-		return nil, fmt.Errorf("no residents found in apartment")
+		logger.WithError(err).Error("Failed to prepare bill division")
+		return nil, err
 	}
 
 	//all undivided bills for the apartment
@@ -346,88 +299,206 @@ func (s *billServiceImpl) DivideAllBills(ctx context.Context, userID, apartmentI
 		"bills_count":     len(bills),
 	}).Info("Processing all undivided bills")
 
-	var processedBills []int
-	var failedBills []int
-	var totalFailedPayments int
 	billTypeCount := make(map[models.BillType]int)
-
 	for _, bill := range bills {
-		amountPerResident := bill.TotalAmount / float64(len(residents))
-		billProcessed := true
 		billTypeCount[bill.BillType]++
+	}
 
-		for _, resident := range residents {
-			existingPayment, _ := s.paymentRepo.GetPaymentByBillAndUser(bill.ID, resident.ID)
-			if existingPayment != nil {
-				continue
-			}
-
-			payment := models.Payment{
-				BaseModel: models.BaseModel{
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
-				},
-				BillID:        bill.ID,
-				UserID:        resident.ID,
-				Amount:        fmt.Sprintf("%.2f", amountPerResident),
-				PaymentStatus: models.Pending,
-			}
-
-			_, err := s.paymentRepo.CreatePayment(ctx, payment)
-			if err != nil {
-				logger.WithError(err).WithFields(logrus.Fields{
-					"bill_id":     bill.ID,
-					"resident_id": resident.ID,
-				}).Error("Failed to create payment record")
-				totalFailedPayments++
-				billProcessed = false
-				continue
-			}
-
-			//sending notification
-			if err := s.notificationService.SendBillNotification(ctx, resident.ID, bill, amountPerResident); err != nil {
-				logger.WithError(err).WithFields(logrus.Fields{
-					"bill_id":     bill.ID,
-					"resident_id": resident.ID,
-				}).Warn("Failed to send notification")
-			}
-		}
-
-		if billProcessed {
-			processedBills = append(processedBills, bill.ID)
-		} else {
-			failedBills = append(failedBills, bill.ID)
-		}
+	processedBills, err := s.divideBills(ctx, logger, bills, residents, weights)
+	if err != nil {
+		return nil, err
 	}
 
 	logger.WithFields(logrus.Fields{
 		"processed_count":      len(processedBills),
-		"failed_count":         len(failedBills),
 		"bill_types_processed": billTypeCount,
 	}).Info("All bills division completed")
 
-	response := map[string]interface{}{
+	return map[string]interface{}{
 		"residents_count":      len(residents),
+		"division_policy":      apartment.DivisionPolicy,
 		"processed_bills":      processedBills,
 		"processed_count":      len(processedBills),
 		"bill_types_processed": billTypeCount,
+	}, nil
+}
+
+// divides a single bill without a manager check, used for bills generated by
+// the scheduler
+func (s *billServiceImpl) DivideBill(ctx context.Context, bill models.Bill) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"apartment_id": bill.ApartmentID,
+		"bill_id":      bill.ID,
+	})
+
+	_, residents, weights, err := s.getDivisionWeights(ctx, bill.ApartmentID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to prepare bill division")
+		return err
 	}
 
-	if len(failedBills) > 0 {
-		response["warning"] = fmt.Sprintf("Failed to process %d bills completely", len(failedBills))
-		response["failed_bills"] = failedBills
-		response["failed_payments_count"] = totalFailedPayments
+	_, err = s.divideBills(ctx, logger, []models.Bill{bill}, residents, weights)
+	return err
+}
+
+// a resident's share of a divided bill
+type billShare struct {
+	bill   models.Bill
+	userID int
+	amount models.Money
+}
+
+// divides the bills in one transaction, so a failure leaves all of them
+// undivided instead of some residents charged and others not. the residents'
+// notifications are queued in the same transaction. returns the ids of the
+// bills
+func (s *billServiceImpl) divideBills(ctx context.Context, logger *logrus.Entry, bills []models.Bill, residents []models.User_apartment, weights []float64) ([]int, error) {
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		for _, bill := range bills {
+			shares, err := s.divideBill(ctx, logger, bill, residents, weights)
+			if err != nil {
+				return err
+			}
+			for _, share := range shares {
+				if err := s.notificationService.SendBillNotification(ctx, share.userID, share.bill, share.amount); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("Bill division failed, no payments were created")
+		return nil, fmt.Errorf("failed to divide bills: %w", err)
 	}
 
-	return response, nil
+	billIDs := make([]int, len(bills))
+	for i, bill := range bills {
+		billIDs[i] = bill.ID
+	}
+	return billIDs, nil
 }
 
-func (s *billServiceImpl) GetBillByID(ctx context.Context, id int) (map[string]interface{}, error) {
+// creates the pending payment and the charge of every resident with a
+// non-zero share of the bill. returns the shares it created
+func (s *billServiceImpl) divideBill(ctx context.Context, logger *logrus.Entry, bill models.Bill, residents []models.User_apartment, weights []float64) ([]billShare, error) {
+	billLogger := logger.WithFields(logrus.Fields{
+		"bill_id":     bill.ID,
+		"bill_amount": bill.TotalAmount,
+	})
+
+	amounts, err := bill.TotalAmount.Allocate(weights)
+	if err != nil {
+		billLogger.WithError(err).Error("Failed to split bill amount")
+		return nil, fmt.Errorf("failed to split bill %d: %w", bill.ID, err)
+	}
+
+	var shares []billShare
+	for i, resident := range residents {
+		if amounts[i].IsZero() {
+			continue // nothing to pay under the current policy
+		}
+
+		//checking if payment record already exists
+		existingPayment, _ := s.paymentRepo.GetPaymentByBillAndUser(bill.ID, resident.UserID)
+		if existingPayment != nil {
+			continue
+		}
+
+		payment := models.Payment{
+			BaseModel: models.BaseModel{
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+			BillID:        bill.ID,
+			UserID:        resident.UserID,
+			Amount:        amounts[i],
+			PaymentStatus: models.Pending,
+		}
+		credit := s.availableCredit(ctx, billLogger, bill.ApartmentID, payment)
+
+		paymentID, err := s.paymentRepo.CreatePayment(ctx, payment)
+		if err != nil {
+			billLogger.WithError(err).WithField("resident_id", resident.UserID).Error("Failed to create payment record")
+			return nil, fmt.Errorf("failed to create payment of bill %d for resident %d: %w", bill.ID, resident.UserID, err)
+		}
+		payment.ID = paymentID
+
+		err = addLedgerEntry(ctx, s.ledgerRepo, models.LedgerEntry{
+			UserID:      resident.UserID,
+			ApartmentID: bill.ApartmentID,
+			PaymentID:   &paymentID,
+			EntryType:   models.ChargeEntry,
+			Amount:      amounts[i],
+			Description: fmt.Sprintf("%s bill %d", bill.BillType, bill.ID),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if credit.IsPositive() {
+			if err := s.applyCredit(ctx, payment, credit); err != nil {
+				billLogger.WithError(err).WithField("resident_id", resident.UserID).Error("Failed to apply credit")
+				return nil, err
+			}
+		}
+
+		shares = append(shares, billShare{bill: bill, userID: resident.UserID, amount: amounts[i]})
+	}
+
+	billLogger.Debug("Bill processed successfully")
+	return shares, nil
+}
+
+// the part of a new payment the resident's credit in the apartment covers,
+// which is what overpayments and cancelled charges left on their ledger. it
+// is read before the payment is charged
+func (s *billServiceImpl) availableCredit(ctx context.Context, logger *logrus.Entry, apartmentID int, payment models.Payment) models.Money {
+	none := models.NewMoney(0, payment.Amount.Currency)
+	balance, err := s.ledgerRepo.GetBalance(ctx, payment.UserID, apartmentID)
+	if err != nil {
+		logger.WithError(err).WithField("resident_id", payment.UserID).Warn("Failed to get resident balance")
+		return none
+	}
+	if balance.Amount >= 0 || balance.Currency != payment.Amount.Currency {
+		return none
+	}
+
+	credit := -balance.Amount
+	if credit > payment.Amount.Amount {
+		credit = payment.Amount.Amount
+	}
+	return models.NewMoney(credit, payment.Amount.Currency)
+}
+
+// pays a new pending payment from the resident's credit
+func (s *billServiceImpl) applyCredit(ctx context.Context, payment models.Payment, credit models.Money) error {
+	payment.PaidAmount = credit
+	payment.PaymentStatus = models.PartiallyPaid
+	if credit.Amount == payment.Amount.Amount {
+		payment.PaymentStatus = models.Paid
+		payment.PaidAt = time.Now()
+	}
+
+	err := s.paymentRepo.UpdatePaymentStatus(ctx, payment, models.PaymentTransition{
+		Actor:  models.SystemActor,
+		Reason: "credit applied",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply credit to payment %d: %w", payment.ID, err)
+	}
+	return nil
+}
+
+func (s *billServiceImpl) GetBillByID(ctx context.Context, userID, id int) (map[string]interface{}, error) {
 	bill, err := s.repo.GetBillByID(id)
 	if err != nil {
 		logrus.WithError(err).WithField("bill_id", id).Error("Failed to get bill by ID")
 		return nil, fmt.Errorf("failed to get bill: %w", err)
 	}
+	if err := authorize(ctx, s.userApartmentRepo, userID, bill.ApartmentID, models.PermViewFinances); err != nil {
+		return nil, fmt.Errorf("not allowed to view bills: %w", err)
+	}
 
 	var imageURL string
 	if bill.ImageURL != "" {
@@ -454,7 +525,11 @@ func (s *billServiceImpl) GetBillByID(ctx context.Context, id int) (map[string]i
 	}, nil
 }
 
-func (s *billServiceImpl) GetBillsByApartmentID(ctx context.Context, apartmentID int) ([]models.Bill, error) {
+func (s *billServiceImpl) GetBillsByApartmentID(ctx context.Context, userID, apartmentID int) ([]models.Bill, error) {
+	if err := authorize(ctx, s.userApartmentRepo, userID, apartmentID, models.PermViewFinances); err != nil {
+		return nil, fmt.Errorf("not allowed to view bills: %w", err)
+	}
+
 	bills, err := s.repo.GetBillsByApartmentID(apartmentID)
 	if err != nil {
 		logrus.WithError(err).WithField("apartment_id", apartmentID).Error("Failed to get bills by apartment ID")
@@ -463,8 +538,9 @@ func (s *billServiceImpl) GetBillsByApartmentID(ctx context.Context, apartmentID
 	return bills, nil
 }
 
-func (s *billServiceImpl) UpdateBill(ctx context.Context, id, apartmentID int, billType string, totalAmount float64, dueDate, billingDeadline, description string) error {
+func (s *billServiceImpl) UpdateBill(ctx context.Context, userID, id, apartmentID int, billType string, totalAmount models.Money, dueDate, billingDeadline, description string) error {
 	logger := logrus.WithFields(logrus.Fields{
+		"user_id":      userID,
 		"bill_id":      id,
 		"apartment_id": apartmentID,
 		"bill_type":    billType,
@@ -473,6 +549,19 @@ func (s *billServiceImpl) UpdateBill(ctx context.Context, id, apartmentID int, b
 
 	logger.Info("Updating bill")
 
+	existing, err := s.repo.GetBillByID(id)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get bill for update")
+		return fmt.Errorf("failed to get bill: %w", err)
+	}
+	if existing.ApartmentID != apartmentID {
+		return fmt.Errorf("bill %d does not belong to apartment %d", id, apartmentID)
+	}
+	if err := authorize(ctx, s.userApartmentRepo, userID, apartmentID, models.PermManageBills); err != nil {
+		logger.WithError(err).Warn("User not allowed to update bill")
+		return fmt.Errorf("not allowed to update bills: %w", err)
+	}
+
 	bill := models.Bill{
 		BaseModel: models.BaseModel{
 			ID:        id,
@@ -495,8 +584,11 @@ func (s *billServiceImpl) UpdateBill(ctx context.Context, id, apartmentID int, b
 	return nil
 }
 
-func (s *billServiceImpl) DeleteBill(ctx context.Context, id int) error {
-	logger := logrus.WithField("bill_id", id)
+func (s *billServiceImpl) DeleteBill(ctx context.Context, userID, id int) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"user_id": userID,
+		"bill_id": id,
+	})
 	logger.Info("Deleting bill")
 
 	bill, err := s.repo.GetBillByID(id)
@@ -504,10 +596,42 @@ func (s *billServiceImpl) DeleteBill(ctx context.Context, id int) error {
 		logger.WithError(err).Error("Failed to get bill for deletion")
 		return fmt.Errorf("failed to get bill: %w", err)
 	}
+	if err := authorize(ctx, s.userApartmentRepo, userID, bill.ApartmentID, models.PermManageBills); err != nil {
+		logger.WithError(err).Warn("User not allowed to delete bill")
+		return fmt.Errorf("not allowed to delete bills: %w", err)
+	}
+
+	payments, err := s.paymentRepo.GetPaymentsByBill(id)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get payments of bill")
+		return fmt.Errorf("failed to get payments: %w", err)
+	}
 
-	if err := s.repo.DeleteBill(id); err != nil {
-		logger.WithError(err).Error("Failed to delete bill from database")
-		return fmt.Errorf("failed to delete bill: %w", err)
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.DeleteBill(ctx, id); err != nil {
+			logger.WithError(err).Error("Failed to delete bill from database")
+			return fmt.Errorf("failed to delete bill: %w", err)
+		}
+
+		//the charges are cancelled, whatever was paid on them becomes credit
+		for _, payment := range payments {
+			paymentID := payment.ID
+			err := addLedgerEntry(ctx, s.ledgerRepo, models.LedgerEntry{
+				UserID:      payment.UserID,
+				ApartmentID: bill.ApartmentID,
+				PaymentID:   &paymentID,
+				EntryType:   models.CreditEntry,
+				Amount:      payment.TotalDue(),
+				Description: fmt.Sprintf("%s bill %d deleted", bill.BillType, bill.ID),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	if bill.ImageURL != "" {
@@ -522,38 +646,32 @@ func (s *billServiceImpl) DeleteBill(ctx context.Context, id int) error {
 	return nil
 }
 
-func (s *billServiceImpl) PayBills(ctx context.Context, userID int, paymentIDs []int, idempotentKey string) error {
+func (s *billServiceImpl) PayBills(ctx context.Context, userID int, paymentIDs []int, idempotentKey string) (map[string]interface{}, error) {
 	logger := logrus.WithFields(logrus.Fields{
 		"user_id": userID,
 	})
 
 	logger.Info("Processing bill payment")
 
-	if err := s.paymentService.PayBills(paymentIDs, idempotentKey); err != nil {
-		logger.WithError(err).Error("Payment processing failed")
-		return fmt.Errorf("payment failed: %w", err)
-	}
-
 	var payments []models.Payment
 	for _, paymentID := range paymentIDs {
-		payments = append(payments, models.Payment{
-			BaseModel: models.BaseModel{
-				UpdatedAt: time.Now(),
-				ID:        paymentID,
-			},
-			UserID:        userID,
-			PaidAt:        time.Now(),
-			PaymentStatus: models.Paid,
-		})
-	}
-
-	if err := s.paymentRepo.UpdatePaymentsStatus(ctx, payments); err != nil {
-		logger.WithError(err).Error("Failed to update payment status")
-		return fmt.Errorf("failed to update payments status: %w", err)
+		payment, err := s.paymentRepo.GetPaymentByID(ctx, paymentID)
+		if err != nil {
+			logger.WithError(err).WithField("payment_id", paymentID).Warn("Payment not found")
+			return nil, fmt.Errorf("payment %d not found", paymentID)
+		}
+		if payment.UserID != userID {
+			return nil, fmt.Errorf("payment %d does not belong to user", paymentID)
+		}
+		//a processing payment is let through so that a retry with the same
+		//idempotent key gets its transaction back
+		if !payment.PaymentStatus.CanTransitionTo(models.Processing) && payment.PaymentStatus != models.Processing {
+			return nil, fmt.Errorf("payment %d is %s and cannot be paid", paymentID, payment.PaymentStatus)
+		}
+		payments = append(payments, *payment)
 	}
 
-	logger.Info("Bill payment completed successfully")
-	return nil
+	return s.checkout(ctx, logger, userID, payments, idempotentKey)
 }
 
 func (s *billServiceImpl) PayBatchBills(ctx context.Context, userID int, idempotentKey string) (map[string]interface{}, error) {
@@ -563,69 +681,691 @@ func (s *billServiceImpl) PayBatchBills(ctx context.Context, userID int, idempot
 
 	logger.Info("Processing batch bill payment")
 
-	var totalAmount float64
-	paymentss, err := s.paymentRepo.GetPendingPaymentsByUser(userID)
+	payments, err := s.paymentRepo.GetPendingPaymentsByUser(userID)
 	if err != nil {
 		return nil, errors.New("internal server error")
 	}
-	paymentIds := make([]int, 10)
 
-	for _, payment := range paymentss {
-		paymentIds = append(paymentIds, payment.ID)
+	return s.checkout(ctx, logger, userID, payments, idempotentKey)
+}
+
+// starts a provider payment for the outstanding balance of the given
+// payments and moves them to processing. they stay there until the provider
+// confirms the payment through the callback
+func (s *billServiceImpl) checkout(ctx context.Context, logger *logrus.Entry, userID int, payments []models.Payment, idempotentKey string) (map[string]interface{}, error) {
+	var totalAmount models.Money
+	var payable []models.Payment
+	var amounts []models.Money
+	for _, payment := range payments {
+		if !payment.PaymentStatus.CanTransitionTo(models.Processing) {
+			continue
+		}
 
-		amount, err := strconv.ParseFloat(payment.Amount, 64)
+		sum, err := totalAmount.Add(payment.Outstanding())
 		if err != nil {
+			logger.WithError(err).WithField("payment_id", payment.ID).Warn("Skipping payment in a different currency")
 			continue
 		}
 
-		totalAmount += amount
+		payable = append(payable, payment)
+		amounts = append(amounts, payment.Outstanding())
+		totalAmount = sum
+	}
+
+	return s.startPayment(ctx, logger, userID, payable, amounts, idempotentKey)
+}
+
+// pays part of a payment's outstanding balance. without an amount the
+// remainder of the next installment of the payment's plan is paid
+func (s *billServiceImpl) PayPartial(ctx context.Context, userID, paymentID int, amount models.Money, idempotentKey string) (map[string]interface{}, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"payment_id": paymentID,
+	})
+
+	logger.Info("Processing partial bill payment")
+
+	payment, err := s.paymentRepo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("payment %d not found", paymentID)
+	}
+	if payment.UserID != userID {
+		return nil, fmt.Errorf("payment %d does not belong to user", paymentID)
+	}
+
+	var payable []models.Payment
+	var amounts []models.Money
+	if payment.PaymentStatus.CanTransitionTo(models.Processing) {
+		if amount.IsZero() {
+			installments, err := s.installmentRepo.GetInstallmentsByPayment(paymentID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get installment plan: %w", err)
+			}
+			next, remaining := models.NextInstallment(installments, payment.PaidAmount)
+			if next == nil {
+				return nil, fmt.Errorf("payment %d has no installment due, an amount is required", paymentID)
+			}
+			amount = remaining
+		}
+
+		if amount.Currency == "" {
+			amount.Currency = payment.Amount.Currency
+		}
+		if !amount.IsPositive() {
+			return nil, fmt.Errorf("amount must be positive")
+		}
+		if amount.Currency != payment.Amount.Currency {
+			return nil, fmt.Errorf("amount must be in %s", payment.Amount.Currency)
+		}
+		if amount.Amount > payment.Outstanding().Amount {
+			return nil, fmt.Errorf("amount exceeds the outstanding balance of %s", payment.Outstanding())
+		}
+		payable = append(payable, *payment)
+		amounts = append(amounts, amount)
+	} else if payment.PaymentStatus != models.Processing {
+		return nil, fmt.Errorf("payment %d is %s and cannot be paid", paymentID, payment.PaymentStatus)
 	}
 
-	if len(paymentIds) == 0 {
-		logger.Warn("No valid unpaid bills found for batch payment")
-		return nil, fmt.Errorf("no valid unpaid bills found")
+	return s.startPayment(ctx, logger, userID, payable, amounts, idempotentKey)
+}
+
+// charges each payable payment the amount at the same position of amounts
+func (s *billServiceImpl) startPayment(ctx context.Context, logger *logrus.Entry, userID int, payable []models.Payment, amounts []models.Money, idempotentKey string) (map[string]interface{}, error) {
+	var paymentIDs []int
+	for _, payment := range payable {
+		paymentIDs = append(paymentIDs, payment.ID)
 	}
 
 	logger.WithFields(logrus.Fields{
-		"valid_bills_count": len(paymentIds),
-		"total_amount":      totalAmount,
-	}).Info("Processing batch payment for valid bills")
+		"bills_count": len(paymentIDs),
+		"amounts":     amounts,
+	}).Info("Initiating payment")
 
-	if err := s.paymentService.PayBills(paymentIds, idempotentKey); err != nil {
-		logger.WithError(err).Error("Batch payment processing failed")
-		return nil, fmt.Errorf("batch payment failed: %w", err)
+	transaction, err := s.paymentService.Initiate(ctx, userID, paymentIDs, amounts, idempotentKey)
+	if err != nil {
+		logger.WithError(err).Error("Payment processing failed")
+		if len(paymentIDs) == 0 {
+			return nil, fmt.Errorf("no valid unpaid bills found")
+		}
+		return nil, fmt.Errorf("payment failed: %w", err)
+	}
+
+	//a retried request gets the transaction started first, whose payments
+	//are already processing
+	if transaction.Status == models.TransactionInitiated && len(payable) > 0 {
+		for i := range payable {
+			payable[i].PaymentStatus = models.Processing
+		}
+		err := s.paymentRepo.UpdatePaymentsStatus(ctx, payable, models.PaymentTransition{
+			Actor:  models.UserActor(userID),
+			Reason: fmt.Sprintf("payment transaction %d initiated", transaction.ID),
+		})
+		if err != nil {
+			logger.WithError(err).Error("Failed to mark payments as processing")
+			return nil, fmt.Errorf("failed to update payments status: %w", err)
+		}
 	}
 
+	return map[string]interface{}{
+		"status":         transaction.Status,
+		"transaction_id": transaction.ID,
+		"redirect_url":   transaction.RedirectURL,
+		"total_amount":   transaction.Amount,
+	}, nil
+}
+
+// handles the provider's callback and settles the payments of the
+// transaction. each payment is paid the amount it was charged when the
+// transaction started, which may be a single installment. the transaction, its payments and their ledger entries are updated
+// together, a failure leaves the transaction initiated for the provider to
+// call back again
+func (s *billServiceImpl) ConfirmPayment(ctx context.Context, providerName string, params url.Values) (map[string]interface{}, error) {
+	logger := logrus.WithField("provider", providerName)
+
+	var response map[string]interface{}
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		transaction, settled, err := s.paymentService.VerifyCallback(ctx, providerName, params)
+		if err != nil {
+			logger.WithError(err).Warn("Payment verification failed")
+			return fmt.Errorf("payment verification failed: %w", err)
+		}
+
+		logger = logger.WithFields(logrus.Fields{
+			"transaction_id": transaction.ID,
+			"user_id":        transaction.UserID,
+			"status":         transaction.Status,
+		})
+
+		response = map[string]interface{}{
+			"status":         transaction.Status,
+			"transaction_id": transaction.ID,
+			"tracking_code":  transaction.TrackingCode,
+			"total_amount":   transaction.Amount,
+		}
+		if !settled {
+			logger.Info("Payment transaction was already settled")
+			return nil
+		}
+
+		return s.settleTransaction(ctx, logger, providerName, transaction)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// moves the payments of a verified transaction to their settled status and
+// records what was paid on the ledger. paying more than a payment owes (say
+// a fee was waived while it was processing) books the rest as credit
+func (s *billServiceImpl) settleTransaction(ctx context.Context, logger *logrus.Entry, providerName string, transaction *models.PaymentTransaction) error {
 	var payments []models.Payment
-	for _, paymentID := range paymentIds {
-		payments = append(payments, models.Payment{
-			BaseModel: models.BaseModel{
-				ID:        paymentID,
-				UpdatedAt: time.Now(),
-			},
-			UserID:        userID,
-			PaidAt:        time.Now(),
-			PaymentStatus: models.Paid,
+	credits := make([]models.Money, len(transaction.PaymentIDs))
+	overpaid := make([]models.Money, len(transaction.PaymentIDs))
+	for i, paymentID := range transaction.PaymentIDs {
+		payment, err := s.paymentRepo.GetPaymentByID(ctx, paymentID)
+		if err != nil {
+			logger.WithError(err).WithField("payment_id", paymentID).Error("Payment of transaction not found")
+			return fmt.Errorf("payment %d not found: %w", paymentID, err)
+		}
+
+		payment.UpdatedAt = time.Now()
+		if transaction.Status == models.TransactionSucceeded {
+			charged := chargedAmount(transaction, i, *payment)
+			credits[i] = charged
+			if charged.Amount > payment.Outstanding().Amount {
+				credits[i] = payment.Outstanding()
+			}
+			overpaid[i] = models.NewMoney(charged.Amount-credits[i].Amount, payment.Amount.Currency)
+			payment.PaidAmount = models.NewMoney(payment.PaidAmount.Amount+credits[i].Amount, payment.Amount.Currency)
+			payment.PaidAt = time.Now()
+		}
+		payment.PaymentStatus = settledStatus(*payment)
+		payments = append(payments, *payment)
+	}
+
+	err := s.paymentRepo.UpdatePaymentsStatus(ctx, payments, models.PaymentTransition{
+		Actor:  models.ProviderActor(providerName),
+		Reason: fmt.Sprintf("payment transaction %d %s", transaction.ID, transaction.Status),
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to update payment status")
+		return fmt.Errorf("failed to update payments status: %w", err)
+	}
+
+	if transaction.Status != models.TransactionSucceeded {
+		logger.Warn("Bill payment was not completed")
+		return nil
+	}
+	for i, payment := range payments {
+		if credits[i].IsPositive() {
+			err := s.recordPaymentEntry(ctx, payment, models.PaymentEntry, credits[i],
+				fmt.Sprintf("payment transaction %d", transaction.ID))
+			if err != nil {
+				return err
+			}
+		}
+		if overpaid[i].IsPositive() {
+			err := s.recordPaymentEntry(ctx, payment, models.CreditEntry, overpaid[i],
+				fmt.Sprintf("overpayment of payment transaction %d", transaction.ID))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	logger.Info("Bill payment completed successfully")
+	return nil
+}
+
+// the amount the i-th payment of the transaction was charged. transactions
+// started before the amounts were recorded only know their total, which for
+// a batch was every outstanding balance
+func chargedAmount(transaction *models.PaymentTransaction, i int, payment models.Payment) models.Money {
+	if len(transaction.PaymentAmounts) == len(transaction.PaymentIDs) {
+		return transaction.PaymentAmounts[i]
+	}
+	if len(transaction.PaymentIDs) == 1 {
+		return transaction.Amount
+	}
+	return payment.Outstanding()
+}
+
+// expires the transactions whose provider never called back and fails the
+// payments they left processing, so they can be paid again
+func (s *billServiceImpl) ExpireStalePayments(ctx context.Context, now time.Time) (int, error) {
+	transactions, err := s.paymentService.StaleTransactions(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, transaction := range transactions {
+		logger := logrus.WithFields(logrus.Fields{
+			"transaction_id": transaction.ID,
+			"user_id":        transaction.UserID,
+		})
+
+		var done bool
+		err := s.uow.Do(ctx, func(ctx context.Context) error {
+			if done, err = s.paymentService.Expire(ctx, transaction.ID); err != nil || !done {
+				return err
+			}
+			return s.expireTransactionPayments(ctx, transaction, now)
 		})
+		if err != nil {
+			logger.WithError(err).Error("Failed to expire payment transaction")
+			continue
+		}
+		if done {
+			expired++
+		}
+	}
+
+	if expired > 0 {
+		logrus.WithField("transactions_count", expired).Info("Expired stale payment transactions")
 	}
+	return expired, nil
+}
 
-	if err := s.paymentRepo.UpdatePaymentsStatus(ctx, payments); err != nil {
-		logger.WithError(err).Error("Failed to update batch payment status")
-		return nil, fmt.Errorf("failed to update payments status: %w", err)
+func (s *billServiceImpl) expireTransactionPayments(ctx context.Context, transaction models.PaymentTransaction, now time.Time) error {
+	var payments []models.Payment
+	for _, paymentID := range transaction.PaymentIDs {
+		payment, err := s.paymentRepo.GetPaymentByID(ctx, paymentID)
+		if err != nil {
+			return fmt.Errorf("payment %d not found: %w", paymentID, err)
+		}
+		if payment.PaymentStatus != models.Processing {
+			continue
+		}
+		payment.PaymentStatus = settledStatus(*payment)
+		payment.UpdatedAt = now
+		payments = append(payments, *payment)
+	}
+	if len(payments) == 0 {
+		return nil
+	}
+
+	err := s.paymentRepo.UpdatePaymentsStatus(ctx, payments, models.PaymentTransition{
+		Actor:  models.SystemActor,
+		Reason: fmt.Sprintf("payment transaction %d expired before its provider settled it", transaction.ID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update payments status: %w", err)
+	}
+	return nil
+}
+
+// the status a processing payment ends up in once its transaction settled.
+// a failed installment leaves what was paid before in place
+func settledStatus(payment models.Payment) models.PaymentStatus {
+	switch {
+	case payment.PaidAmount.Amount >= payment.TotalDue().Amount:
+		return models.Paid
+	case payment.PaidAmount.IsPositive():
+		return models.PartiallyPaid
+	default:
+		return models.Failed
+	}
+}
+
+// gives back part or all of a paid payment through the provider that took
+// it, or as ledger credit when the payment was paid from credit. the refund
+// is recorded pending under the payment's row lock before the provider is
+// called, so concurrent refunds cannot together give back more than was
+// paid, and is completed or marked failed once the provider answers
+func (s *billServiceImpl) RefundPayment(ctx context.Context, managerID, paymentID int, amount models.Money, reason string) (*models.Payment, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"manager_id": managerID,
+		"payment_id": paymentID,
+	})
+
+	if _, err := s.getManagedPayment(ctx, managerID, paymentID, models.PermManagePayments); err != nil {
+		return nil, err
+	}
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("refund amount must be positive")
+	}
+
+	var payment *models.Payment
+	refund := models.PaymentRefund{
+		PaymentID: paymentID,
+		Status:    models.RefundPending,
+		Actor:     models.UserActor(managerID),
+		Reason:    reason,
+	}
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		locked, err := s.paymentRepo.GetPaymentForUpdate(ctx, paymentID)
+		if err != nil {
+			return fmt.Errorf("payment not found: %w", err)
+		}
+		if !locked.PaymentStatus.CanTransitionTo(models.PartiallyRefunded) {
+			return fmt.Errorf("%w: %s payments cannot be refunded", models.ErrInvalidPaymentTransition, locked.PaymentStatus)
+		}
+		if amount.Currency != "" && amount.Currency != locked.Amount.Currency {
+			return fmt.Errorf("refund must be in %s", locked.Amount.Currency)
+		}
+
+		//what was paid includes a late fee settled with the share
+		reserved, err := s.paymentRepo.SumRefunds(ctx, paymentID, models.RefundPending, models.RefundCompleted)
+		if err != nil {
+			return fmt.Errorf("failed to get refunds: %w", err)
+		}
+		if reserved+amount.Amount > locked.PaidAmount.Amount {
+			return fmt.Errorf("refund exceeds the paid amount")
+		}
+
+		paidByProvider, err := s.paymentService.PaidByProvider(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+		refund.Amount = models.NewMoney(amount.Amount, locked.Amount.Currency)
+		refund.ToCredit = !paidByProvider
+		if refund.ID, err = s.paymentRepo.CreateRefund(ctx, refund); err != nil {
+			return fmt.Errorf("failed to record refund: %w", err)
+		}
+
+		payment = locked
+		if refund.ToCredit {
+			payment, err = s.completeRefund(ctx, refund)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !refund.ToCredit {
+		if _, err := s.paymentService.RefundPayment(ctx, paymentID, refund.Amount); err != nil {
+			logger.WithError(err).Error("Payment provider refund failed")
+			if err := s.paymentRepo.UpdateRefundStatus(ctx, refund.ID, models.RefundFailed); err != nil {
+				logger.WithError(err).WithField("refund_id", refund.ID).Error("Failed to release failed refund")
+			}
+			return nil, fmt.Errorf("refund failed: %w", err)
+		}
+
+		err = s.uow.Do(ctx, func(ctx context.Context) error {
+			var err error
+			payment, err = s.completeRefund(ctx, refund)
+			return err
+		})
+		if err != nil {
+			//the money is back with the resident, the pending refund is left
+			//for an operator to complete
+			logger.WithError(err).WithField("refund_id", refund.ID).Error("Provider refunded the payment but the refund was not recorded")
+			return nil, err
+		}
 	}
 
 	logger.WithFields(logrus.Fields{
-		"total_amount": totalAmount,
-	}).Info("Batch payment completed successfully")
+		"amount":    refund.Amount,
+		"to_credit": refund.ToCredit,
+	}).Info("Payment refunded")
+	return payment, nil
+}
 
-	return map[string]interface{}{
-		"status":       "batch payment successful",
-		"total_amount": totalAmount,
-	}, nil
+// records a refund the provider paid out, or one given back as credit, on
+// the payment and the resident's ledger
+func (s *billServiceImpl) completeRefund(ctx context.Context, refund models.PaymentRefund) (*models.Payment, error) {
+	payment, err := s.paymentRepo.GetPaymentForUpdate(ctx, refund.PaymentID)
+	if err != nil {
+		return nil, fmt.Errorf("payment not found: %w", err)
+	}
+	if err := s.paymentRepo.UpdateRefundStatus(ctx, refund.ID, models.RefundCompleted); err != nil {
+		return nil, fmt.Errorf("failed to complete refund: %w", err)
+	}
+	refunded, err := s.paymentRepo.SumRefunds(ctx, payment.ID, models.RefundCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %w", err)
+	}
+
+	payment.PaymentStatus = models.PartiallyRefunded
+	if refunded >= payment.PaidAmount.Amount {
+		payment.PaymentStatus = models.Refunded
+	}
+	err = s.paymentRepo.UpdatePaymentStatus(ctx, *payment, models.PaymentTransition{
+		Amount: refund.Amount,
+		Actor:  refund.Actor,
+		Reason: refund.Reason,
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("payment_id", payment.ID).Error("Failed to record refund")
+		return nil, fmt.Errorf("failed to update payment status: %w", err)
+	}
+
+	if refund.ToCredit {
+		err = s.recordPaymentEntry(ctx, *payment, models.CreditEntry, refund.Amount, "refund to credit: "+refund.Reason)
+	} else {
+		err = s.recordPaymentEntry(ctx, *payment, models.RefundEntry, refund.Amount, "refund: "+refund.Reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// records that the resident's bank reversed a paid payment
+func (s *billServiceImpl) RecordChargeback(ctx context.Context, managerID, paymentID int, reason string) (*models.Payment, error) {
+	payment, err := s.getManagedPayment(ctx, managerID, paymentID, models.PermManagePayments)
+	if err != nil {
+		return nil, err
+	}
+
+	//what was refunded before is already on the ledger
+	refunded, err := s.refundedAmount(*payment)
+	if err != nil {
+		return nil, err
+	}
+	chargedBack := models.NewMoney(payment.PaidAmount.Amount-refunded.Amount, payment.Amount.Currency)
+
+	payment.PaymentStatus = models.Chargeback
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		err := s.paymentRepo.UpdatePaymentStatus(ctx, *payment, models.PaymentTransition{
+			Amount: chargedBack,
+			Actor:  models.UserActor(managerID),
+			Reason: reason,
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("payment_id", paymentID).Error("Failed to record chargeback")
+			return fmt.Errorf("failed to update payment status: %w", err)
+		}
+		return s.recordPaymentEntry(ctx, *payment, models.RefundEntry, chargedBack, "chargeback: "+reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
 }
 
-func (s *billServiceImpl) GetUnpaidBills(ctx context.Context, userID int) ([]models.Payment, error) {
-	return s.paymentRepo.GetPendingPaymentsByUser(userID)
+// the total refunded on the payment so far, from its transitions
+func (s *billServiceImpl) refundedAmount(payment models.Payment) (models.Money, error) {
+	transitions, err := s.paymentRepo.GetPaymentTransitions(payment.ID)
+	if err != nil {
+		return models.Money{}, fmt.Errorf("failed to get payment transitions: %w", err)
+	}
+	refunded := models.NewMoney(0, payment.Amount.Currency)
+	for _, transition := range transitions {
+		if transition.ToStatus == models.PartiallyRefunded || transition.ToStatus == models.Refunded {
+			if refunded, err = refunded.Add(transition.Amount); err != nil {
+				return models.Money{}, err
+			}
+		}
+	}
+	return refunded, nil
+}
+
+func (s *billServiceImpl) GetPaymentTransitions(ctx context.Context, managerID, paymentID int) ([]models.PaymentTransition, error) {
+	if _, err := s.getManagedPayment(ctx, managerID, paymentID, models.PermViewFinances); err != nil {
+		return nil, err
+	}
+
+	transitions, err := s.paymentRepo.GetPaymentTransitions(paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment transitions: %w", err)
+	}
+	return transitions, nil
+}
+
+// records a ledger entry for a payment in the apartment of its bill
+func (s *billServiceImpl) recordPaymentEntry(ctx context.Context, payment models.Payment, entryType models.LedgerEntryType, amount models.Money, description string) error {
+	bill, err := s.repo.GetBillByID(payment.BillID)
+	if err != nil {
+		logrus.WithError(err).WithField("payment_id", payment.ID).Error("Failed to get bill for ledger entry")
+		return fmt.Errorf("failed to get bill of payment %d: %w", payment.ID, err)
+	}
+
+	paymentID := payment.ID
+	return addLedgerEntry(ctx, s.ledgerRepo, models.LedgerEntry{
+		UserID:      payment.UserID,
+		ApartmentID: bill.ApartmentID,
+		PaymentID:   &paymentID,
+		EntryType:   entryType,
+		Amount:      models.NewMoney(amount.Amount, payment.Amount.Currency),
+		Description: description,
+	})
+}
+
+// loads a payment of a bill in an apartment where the user has perm
+func (s *billServiceImpl) getManagedPayment(ctx context.Context, managerID, paymentID int, perm models.Permission) (*models.Payment, error) {
+	payment, err := s.paymentRepo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("payment not found: %w", err)
+	}
+
+	bill, err := s.repo.GetBillByID(payment.BillID)
+	if err != nil {
+		return nil, fmt.Errorf("bill not found: %w", err)
+	}
+
+	if err := authorize(ctx, s.userApartmentRepo, managerID, bill.ApartmentID, perm); err != nil {
+		return nil, fmt.Errorf("not allowed to manage payments: %w", err)
+	}
+	return payment, nil
+}
+
+// the outstanding balance of every payment the user still has to pay,
+// including late fees, with the next installment when the manager set up a
+// plan
+func (s *billServiceImpl) GetUnpaidBills(ctx context.Context, userID int) ([]OutstandingBalance, error) {
+	payments, err := s.paymentRepo.GetPendingPaymentsByUser(userID)
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to get unpaid payments")
+		return nil, fmt.Errorf("failed to get unpaid payments: %w", err)
+	}
+
+	balances := make([]OutstandingBalance, 0, len(payments))
+	for _, payment := range payments {
+		balance := OutstandingBalance{
+			PaymentID:   payment.ID,
+			BillID:      payment.BillID,
+			Status:      payment.PaymentStatus,
+			Amount:      payment.Amount,
+			PaidAmount:  models.NewMoney(payment.PaidAmount.Amount, payment.Amount.Currency),
+			LateFee:     models.NewMoney(payment.LateFee.Amount, payment.Amount.Currency),
+			Outstanding: payment.Outstanding(),
+		}
+
+		installments, err := s.installmentRepo.GetInstallmentsByPayment(payment.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get installment plan: %w", err)
+		}
+		if next, remaining := models.NextInstallment(installments, payment.PaidAmount); next != nil {
+			balance.NextInstallment = next
+			balance.NextInstallmentDue = &remaining
+		}
+		balances = append(balances, balance)
+	}
+	return balances, nil
+}
+
+// sets up or replaces the installment plan of a payment. the installments
+// must add up to the whole payment amount, so a plan replaced halfway
+// through lists the installments already paid as well
+func (s *billServiceImpl) SetInstallmentPlan(ctx context.Context, managerID, paymentID int, req dto.InstallmentPlanRequest) ([]models.Installment, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"manager_id": managerID,
+		"payment_id": paymentID,
+	})
+
+	payment, err := s.getManagedPayment(ctx, managerID, paymentID, models.PermManagePayments)
+	if err != nil {
+		return nil, err
+	}
+	if payment.PaymentStatus != models.Pending && payment.PaymentStatus != models.PartiallyPaid && payment.PaymentStatus != models.Failed {
+		return nil, fmt.Errorf("installments can only be planned for unpaid payments")
+	}
+	if len(req.Installments) < 2 {
+		return nil, fmt.Errorf("an installment plan needs at least two installments")
+	}
+
+	var total int64
+	var lastDueDate string
+	installments := make([]models.Installment, 0, len(req.Installments))
+	for i, item := range req.Installments {
+		if item.Amount.Currency == "" {
+			item.Amount.Currency = payment.Amount.Currency
+		}
+		if !item.Amount.IsPositive() || item.Amount.Currency != payment.Amount.Currency {
+			return nil, fmt.Errorf("installment %d must be a positive amount in %s", i+1, payment.Amount.Currency)
+		}
+		if _, err := time.Parse("2006-01-02", item.DueDate); err != nil {
+			return nil, fmt.Errorf("invalid due date of installment %d (use YYYY-MM-DD)", i+1)
+		}
+		if item.DueDate < lastDueDate {
+			return nil, fmt.Errorf("installments must be ordered by due date")
+		}
+		lastDueDate = item.DueDate
+		total += item.Amount.Amount
+
+		installments = append(installments, models.Installment{
+			PaymentID: paymentID,
+			Sequence:  i + 1,
+			Amount:    item.Amount,
+			DueDate:   item.DueDate,
+		})
+	}
+	if total != payment.Amount.Amount {
+		return nil, fmt.Errorf("installments must add up to the payment amount of %s", payment.Amount)
+	}
+
+	if err := s.installmentRepo.ReplaceInstallmentPlan(ctx, paymentID, installments); err != nil {
+		logger.WithError(err).Error("Failed to save installment plan")
+		return nil, fmt.Errorf("failed to save installment plan: %w", err)
+	}
+
+	logger.WithField("installments_count", len(req.Installments)).Info("Installment plan saved")
+	return installments, nil
+}
+
+// the installment plan of a payment, visible to the resident who owes it and
+// to whoever can view the apartment's finances
+func (s *billServiceImpl) GetInstallmentPlan(ctx context.Context, userID, paymentID int) ([]models.Installment, error) {
+	payment, err := s.paymentRepo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("payment not found: %w", err)
+	}
+	if payment.UserID != userID {
+		if _, err := s.getManagedPayment(ctx, userID, paymentID, models.PermViewFinances); err != nil {
+			return nil, err
+		}
+	}
+
+	installments, err := s.installmentRepo.GetInstallmentsByPayment(paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get installment plan: %w", err)
+	}
+	return installments, nil
+}
+
+func (s *billServiceImpl) DeleteInstallmentPlan(ctx context.Context, managerID, paymentID int) error {
+	if _, err := s.getManagedPayment(ctx, managerID, paymentID, models.PermManagePayments); err != nil {
+		return err
+	}
+
+	if err := s.installmentRepo.DeleteInstallmentPlan(paymentID); err != nil {
+		logrus.WithError(err).WithField("payment_id", paymentID).Error("Failed to delete installment plan")
+		return fmt.Errorf("failed to delete installment plan: %w", err)
+	}
+	return nil
 }
 
 func (s *billServiceImpl) GetBillWithPaymentStatus(ctx context.Context, userID, billID int) (map[string]interface{}, error) {
@@ -651,6 +1391,7 @@ func (s *billServiceImpl) GetBillWithPaymentStatus(ctx context.Context, userID,
 		"bill":           bill,
 		"payment_status": payment.PaymentStatus,
 		"amount_due":     payment.Amount,
+		"late_fee":       payment.LateFee,
 		"paid_at":        payment.PaidAt,
 	}, nil
 }
@@ -688,3 +1429,136 @@ func (s *billServiceImpl) GetUserPaymentHistory(ctx context.Context, userID int)
 
 	return history, nil
 }
+
+// loads the apartment and its members together with one division weight per
+// member, according to the apartment's division policy
+func (s *billServiceImpl) getDivisionWeights(ctx context.Context, apartmentID int) (*models.Apartment, []models.User_apartment, []float64, error) {
+	apartment, err := s.apartmentRepo.GetApartmentByID(apartmentID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get apartment: %w", err)
+	}
+
+	members, err := s.userApartmentRepo.GetUserApartmentsByApartment(apartmentID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get residents: %w", err)
+	}
+	//viewers and anyone else without pay_bills get no share
+	var residents []models.User_apartment
+	for _, member := range members {
+		if member.EffectivePermissions().Has(models.PermPayBills) {
+			residents = append(residents, member)
+		}
+	}
+	if len(residents) == 0 {
+		return nil, nil, nil, fmt.Errorf("no residents found in apartment")
+	}
+
+	if apartment.DivisionPolicy.PerUnit() {
+		units, err := s.unitRepo.GetUnitsByApartment(ctx, apartmentID)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to get units: %w", err)
+		}
+		residents, weights, err := unitDivisionWeights(apartment.DivisionPolicy, residents, units)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return apartment, residents, weights, nil
+	}
+
+	weights, err := divisionWeights(apartment.DivisionPolicy, residents)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return apartment, residents, weights, nil
+}
+
+// weighs every unit by the policy and splits the unit's weight between the
+// members who pay for it: the ones living there, or its owners when nobody
+// does. returns the members who are charged with their weights, units nobody
+// pays for and members without a unit are left out
+func unitDivisionWeights(policy models.DivisionPolicy, residents []models.User_apartment, units []models.Unit) ([]models.User_apartment, []float64, error) {
+	byUnit := make(map[int][]models.User_apartment)
+	for _, resident := range residents {
+		if resident.UnitID != nil {
+			byUnit[*resident.UnitID] = append(byUnit[*resident.UnitID], resident)
+		}
+	}
+
+	var charged []models.User_apartment
+	var weights []float64
+	var total float64
+	for _, unit := range units {
+		var living, owning []models.User_apartment
+		for _, member := range byUnit[unit.ID] {
+			if member.UnitRelation == models.UnitOwner {
+				owning = append(owning, member)
+			} else {
+				living = append(living, member)
+			}
+		}
+		payers := living
+		if len(payers) == 0 {
+			payers = owning
+		}
+		if len(payers) == 0 {
+			continue
+		}
+
+		var weight float64
+		switch policy {
+		case models.UnitDivision:
+			weight = 1
+		case models.UnitAreaDivision:
+			weight = unit.Area
+		case models.UnitOccupantsDivision:
+			weight = float64(unit.OccupantsCount)
+		default:
+			return nil, nil, fmt.Errorf("unknown division policy %q", policy)
+		}
+		if weight < 0 {
+			return nil, nil, fmt.Errorf("unit %s has a negative share", unit.Number)
+		}
+
+		for _, payer := range payers {
+			charged = append(charged, payer)
+			weights = append(weights, weight/float64(len(payers)))
+		}
+		total += weight
+	}
+
+	if total == 0 {
+		return nil, nil, fmt.Errorf("no occupied units with shares configured for %s division", policy)
+	}
+	return charged, weights, nil
+}
+
+func divisionWeights(policy models.DivisionPolicy, residents []models.User_apartment) ([]float64, error) {
+	weights := make([]float64, len(residents))
+	var total float64
+	for i, resident := range residents {
+		switch policy {
+		case models.EqualDivision, "":
+			weights[i] = 1
+		case models.AreaDivision:
+			weights[i] = resident.UnitArea
+		case models.OccupantsDivision:
+			weights[i] = float64(resident.OccupantsCount)
+		case models.CustomDivision:
+			weights[i] = resident.SharePercent
+		default:
+			return nil, fmt.Errorf("unknown division policy %q", policy)
+		}
+		if weights[i] < 0 {
+			return nil, fmt.Errorf("resident %d has a negative share", resident.UserID)
+		}
+		total += weights[i]
+	}
+
+	if total == 0 {
+		return nil, fmt.Errorf("no resident shares configured for %s division", policy)
+	}
+	if policy == models.CustomDivision && math.Abs(total-100) > 0.001 {
+		return nil, fmt.Errorf("custom shares add up to %.2f%%, expected 100%%", total)
+	}
+	return weights, nil
+}
@@ -3,8 +3,12 @@ package services
 import (
 	"context"
 	"errors"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/image"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
@@ -15,6 +19,15 @@ import (
 )
 
 func TestPayBills(t *testing.T) {
+	pending := func(id, userID int, amount int64) *models.Payment {
+		return &models.Payment{
+			BaseModel:     models.BaseModel{ID: id},
+			UserID:        userID,
+			Amount:        models.NewMoney(amount, "IRR"),
+			PaymentStatus: models.Pending,
+		}
+	}
+
 	tests := []struct {
 		name          string
 		userID        int
@@ -24,38 +37,59 @@ func TestPayBills(t *testing.T) {
 		expectedError error
 	}{
 		{
-			name:          "successful payment",
+			name:          "successful checkout",
 			userID:        1,
 			paymentIDs:    []int{1, 2},
 			idempotentKey: "idemp123",
 			setupMocks: func(paymentRepo *repositories.MockPaymentRepository, paymentService *payment.MockPayment) {
-				paymentService.On("PayBills", []int{1, 2}, "idemp123").Return(nil)
+				paymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(pending(1, 1, 1000), nil)
+				paymentRepo.On("GetPaymentByID", mock.Anything, 2).Return(pending(2, 1, 500), nil)
+				paymentService.On("Initiate", mock.Anything, 1, []int{1, 2}, []models.Money{models.NewMoney(1000, "IRR"), models.NewMoney(500, "IRR")}, "idemp123").
+					Return(&models.PaymentTransaction{
+						BaseModel:   models.BaseModel{ID: 7},
+						Status:      models.TransactionInitiated,
+						RedirectURL: "http://gateway/pay",
+					}, nil)
 				paymentRepo.On("UpdatePaymentsStatus", mock.Anything, mock.MatchedBy(func(payments []models.Payment) bool {
-					return len(payments) == 2 && payments[0].PaymentStatus == models.Paid
+					return len(payments) == 2 && payments[0].PaymentStatus == models.Processing
+				}), mock.MatchedBy(func(transition models.PaymentTransition) bool {
+					return transition.Actor == "user:1"
 				})).Return(nil)
 			},
 			expectedError: nil,
 		},
 		{
-			name:          "payment service failure",
+			name:          "paid payment cannot be paid again",
 			userID:        1,
 			paymentIDs:    []int{1},
 			idempotentKey: "idemp123",
 			setupMocks: func(paymentRepo *repositories.MockPaymentRepository, paymentService *payment.MockPayment) {
-				paymentService.On("PayBills", []int{1}, "idemp123").Return(errors.New("payment failed"))
+				paid := pending(1, 1, 1000)
+				paid.PaymentStatus = models.Paid
+				paymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(paid, nil)
 			},
-			expectedError: errors.New("payment failed"),
+			expectedError: errors.New("cannot be paid"),
 		},
 		{
-			name:          "status update failure",
+			name:          "payment of another user",
 			userID:        1,
 			paymentIDs:    []int{1},
 			idempotentKey: "idemp123",
 			setupMocks: func(paymentRepo *repositories.MockPaymentRepository, paymentService *payment.MockPayment) {
-				paymentService.On("PayBills", []int{1}, "idemp123").Return(nil)
-				paymentRepo.On("UpdatePaymentsStatus", mock.Anything, mock.Anything).Return(errors.New("update failed"))
+				paymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(pending(1, 2, 1000), nil)
 			},
-			expectedError: errors.New("failed to update payments status"),
+			expectedError: errors.New("does not belong to user"),
+		},
+		{
+			name:          "payment service failure",
+			userID:        1,
+			paymentIDs:    []int{1},
+			idempotentKey: "idemp123",
+			setupMocks: func(paymentRepo *repositories.MockPaymentRepository, paymentService *payment.MockPayment) {
+				paymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(pending(1, 1, 1000), nil)
+				paymentService.ExpectInitiate(1, []int{1}, "idemp123", nil, errors.New("gateway down"))
+			},
+			expectedError: errors.New("payment failed"),
 		},
 	}
 
@@ -75,19 +109,24 @@ func TestPayBills(t *testing.T) {
 				nil,
 				nil,
 				nil,
+				nil,
 				mockPaymentRepo,
+				nil,
+				nil,
+				nil,
 				mockImageService,
 				mockPaymentService,
 				mockNotificationService,
 			)
 
-			err := billService.PayBills(context.Background(), tt.userID, tt.paymentIDs, tt.idempotentKey)
+			response, err := billService.PayBills(context.Background(), tt.userID, tt.paymentIDs, tt.idempotentKey)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.expectedError.Error())
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, "http://gateway/pay", response["redirect_url"])
 			}
 
 			mockPaymentRepo.AssertExpectations(t)
@@ -95,3 +134,848 @@ func TestPayBills(t *testing.T) {
 		})
 	}
 }
+
+func TestConfirmPayment(t *testing.T) {
+	processing := func(id int, amount, paid int64) *models.Payment {
+		return &models.Payment{
+			BaseModel:     models.BaseModel{ID: id},
+			UserID:        1,
+			Amount:        models.NewMoney(amount, "IRR"),
+			PaidAmount:    models.NewMoney(paid, "IRR"),
+			PaymentStatus: models.Processing,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		transaction   models.PaymentTransaction
+		settled       bool
+		setupMocks    func(*repositories.MockPaymentRepository)
+		expectedError error
+	}{
+		{
+			name: "batch payment pays what each payment was charged",
+			transaction: models.PaymentTransaction{
+				PaymentIDs:     []int{1, 2},
+				PaymentAmounts: []models.Money{models.NewMoney(5000, "IRR"), models.NewMoney(3000, "IRR")},
+				Amount:         models.NewMoney(8000, "IRR"),
+				Status:         models.TransactionSucceeded,
+			},
+			settled: true,
+			setupMocks: func(paymentRepo *repositories.MockPaymentRepository) {
+				paymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(processing(1, 5000, 0), nil)
+				paymentRepo.On("GetPaymentByID", mock.Anything, 2).Return(processing(2, 6000, 2000), nil)
+				paymentRepo.On("UpdatePaymentsStatus", mock.Anything, mock.MatchedBy(func(payments []models.Payment) bool {
+					return len(payments) == 2 &&
+						payments[0].PaymentStatus == models.Paid && payments[0].PaidAmount.Amount == 5000 &&
+						payments[1].PaymentStatus == models.PartiallyPaid && payments[1].PaidAmount.Amount == 5000
+				}), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "batch started before amounts were recorded pays every outstanding balance",
+			transaction: models.PaymentTransaction{
+				PaymentIDs: []int{1, 2},
+				Amount:     models.NewMoney(9000, "IRR"),
+				Status:     models.TransactionSucceeded,
+			},
+			settled: true,
+			setupMocks: func(paymentRepo *repositories.MockPaymentRepository) {
+				paymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(processing(1, 5000, 0), nil)
+				paymentRepo.On("GetPaymentByID", mock.Anything, 2).Return(processing(2, 6000, 2000), nil)
+				paymentRepo.On("UpdatePaymentsStatus", mock.Anything, mock.MatchedBy(func(payments []models.Payment) bool {
+					return len(payments) == 2 &&
+						payments[0].PaymentStatus == models.Paid && payments[0].PaidAmount.Amount == 5000 &&
+						payments[1].PaymentStatus == models.Paid && payments[1].PaidAmount.Amount == 6000
+				}), mock.MatchedBy(func(transition models.PaymentTransition) bool {
+					return transition.Actor == "provider:fake"
+				})).Return(nil)
+			},
+		},
+		{
+			name: "installment leaves the payment partially paid",
+			transaction: models.PaymentTransaction{
+				PaymentIDs: []int{1},
+				Amount:     models.NewMoney(2000, "IRR"),
+				Status:     models.TransactionSucceeded,
+			},
+			settled: true,
+			setupMocks: func(paymentRepo *repositories.MockPaymentRepository) {
+				paymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(processing(1, 5000, 1000), nil)
+				paymentRepo.On("UpdatePaymentsStatus", mock.Anything, mock.MatchedBy(func(payments []models.Payment) bool {
+					return payments[0].PaymentStatus == models.PartiallyPaid && payments[0].PaidAmount.Amount == 3000
+				}), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "declined payment marks payments as failed",
+			transaction: models.PaymentTransaction{
+				PaymentIDs: []int{1, 2},
+				Status:     models.TransactionFailed,
+			},
+			settled: true,
+			setupMocks: func(paymentRepo *repositories.MockPaymentRepository) {
+				paymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(processing(1, 5000, 0), nil)
+				paymentRepo.On("GetPaymentByID", mock.Anything, 2).Return(processing(2, 5000, 1000), nil)
+				paymentRepo.On("UpdatePaymentsStatus", mock.Anything, mock.MatchedBy(func(payments []models.Payment) bool {
+					return payments[0].PaymentStatus == models.Failed && payments[1].PaymentStatus == models.PartiallyPaid
+				}), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "repeated callback leaves payments untouched",
+			transaction: models.PaymentTransaction{
+				PaymentIDs: []int{1},
+				Status:     models.TransactionSucceeded,
+			},
+			settled:    false,
+			setupMocks: func(paymentRepo *repositories.MockPaymentRepository) {},
+		},
+		{
+			name: "status update failure",
+			transaction: models.PaymentTransaction{
+				PaymentIDs: []int{1},
+				Amount:     models.NewMoney(5000, "IRR"),
+				Status:     models.TransactionSucceeded,
+			},
+			settled: true,
+			setupMocks: func(paymentRepo *repositories.MockPaymentRepository) {
+				paymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(processing(1, 5000, 0), nil)
+				paymentRepo.On("UpdatePaymentsStatus", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("update failed"))
+			},
+			expectedError: errors.New("failed to update payments status"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPaymentRepo := new(repositories.MockPaymentRepository)
+			mockPaymentService := new(payment.MockPayment)
+			tt.setupMocks(mockPaymentRepo)
+
+			transaction := tt.transaction
+			transaction.ID = 7
+			transaction.UserID = 1
+			mockPaymentService.ExpectVerifyCallback("fake", &transaction, tt.settled, nil)
+			mockBillRepo := new(repositories.MockBillRepository)
+			mockBillRepo.On("GetBillByID", mock.Anything).Return(&models.Bill{ApartmentID: 3}, nil).Maybe()
+			mockLedgerRepo := new(repositories.MockLedgerRepository)
+			mockLedgerRepo.On("AddLedgerEntry", mock.Anything, mock.MatchedBy(func(entry models.LedgerEntry) bool {
+				return entry.EntryType == models.PaymentEntry && entry.ApartmentID == 3
+			})).Return(1, nil).Maybe()
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+
+			billService := NewBillService(mockBillRepo, nil, nil, nil, nil, mockPaymentRepo, nil, mockLedgerRepo, mockUOW, nil, mockPaymentService, nil)
+			response, err := billService.ConfirmPayment(context.Background(), "fake", url.Values{})
+
+			if tt.expectedError != nil {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.transaction.Status, response["status"])
+			}
+			mockPaymentRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConfirmPayment_OverpaymentBecomesCredit(t *testing.T) {
+	//the late fee of the first payment was waived while the resident was
+	//paying both
+	mockPaymentRepo := new(repositories.MockPaymentRepository)
+	mockPaymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(&models.Payment{
+		BaseModel:     models.BaseModel{ID: 1},
+		BillID:        9,
+		UserID:        1,
+		Amount:        models.NewMoney(5000, "IRR"),
+		PaymentStatus: models.Processing,
+	}, nil)
+	mockPaymentRepo.On("GetPaymentByID", mock.Anything, 2).Return(&models.Payment{
+		BaseModel:     models.BaseModel{ID: 2},
+		BillID:        9,
+		UserID:        1,
+		Amount:        models.NewMoney(3000, "IRR"),
+		PaymentStatus: models.Processing,
+	}, nil)
+	mockPaymentRepo.On("UpdatePaymentsStatus", mock.Anything, mock.MatchedBy(func(payments []models.Payment) bool {
+		return payments[0].PaymentStatus == models.Paid && payments[0].PaidAmount.Amount == 5000 &&
+			payments[1].PaymentStatus == models.Paid && payments[1].PaidAmount.Amount == 3000
+	}), mock.Anything).Return(nil)
+	mockBillRepo := new(repositories.MockBillRepository)
+	mockBillRepo.On("GetBillByID", 9).Return(&models.Bill{ApartmentID: 3}, nil)
+	mockLedgerRepo := new(repositories.MockLedgerRepository)
+	mockLedgerRepo.On("AddLedgerEntry", mock.Anything, mock.MatchedBy(func(entry models.LedgerEntry) bool {
+		return *entry.PaymentID == 1 && entry.EntryType == models.PaymentEntry && entry.Amount == models.NewMoney(5000, "IRR")
+	})).Return(1, nil).Once()
+	mockLedgerRepo.On("AddLedgerEntry", mock.Anything, mock.MatchedBy(func(entry models.LedgerEntry) bool {
+		return *entry.PaymentID == 1 && entry.EntryType == models.CreditEntry && entry.Amount == models.NewMoney(600, "IRR")
+	})).Return(2, nil).Once()
+	mockLedgerRepo.On("AddLedgerEntry", mock.Anything, mock.MatchedBy(func(entry models.LedgerEntry) bool {
+		return *entry.PaymentID == 2 && entry.EntryType == models.PaymentEntry && entry.Amount == models.NewMoney(3000, "IRR")
+	})).Return(3, nil).Once()
+	mockPaymentService := new(payment.MockPayment)
+	mockPaymentService.ExpectVerifyCallback("fake", &models.PaymentTransaction{
+		BaseModel:      models.BaseModel{ID: 7},
+		UserID:         1,
+		PaymentIDs:     []int{1, 2},
+		PaymentAmounts: []models.Money{models.NewMoney(5600, "IRR"), models.NewMoney(3000, "IRR")},
+		Amount:         models.NewMoney(8600, "IRR"),
+		Status:         models.TransactionSucceeded,
+	}, true, nil)
+	mockUOW := new(repositories.MockUnitOfWork)
+	mockUOW.On("Do", mock.Anything).Return(nil)
+
+	billService := NewBillService(mockBillRepo, nil, nil, nil, nil, mockPaymentRepo, nil, mockLedgerRepo, mockUOW, nil, mockPaymentService, nil)
+	_, err := billService.ConfirmPayment(context.Background(), "fake", url.Values{})
+
+	assert.NoError(t, err)
+	mockPaymentRepo.AssertExpectations(t)
+	mockLedgerRepo.AssertExpectations(t)
+}
+
+func TestExpireStalePayments(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	stale := []models.PaymentTransaction{
+		{BaseModel: models.BaseModel{ID: 7, CreatedAt: now.Add(-2 * time.Hour)}, UserID: 1, PaymentIDs: []int{1, 2}},
+		{BaseModel: models.BaseModel{ID: 8, CreatedAt: now.Add(-2 * time.Hour)}, UserID: 1, PaymentIDs: []int{3}},
+	}
+
+	mockPaymentService := new(payment.MockPayment)
+	mockPaymentService.On("StaleTransactions", mock.Anything, now).Return(stale, nil)
+	mockPaymentService.On("Expire", mock.Anything, 7).Return(true, nil)
+	//settled by its callback in the meantime
+	mockPaymentService.On("Expire", mock.Anything, 8).Return(false, nil)
+
+	mockPaymentRepo := new(repositories.MockPaymentRepository)
+	mockPaymentRepo.On("GetPaymentByID", mock.Anything, 1).Return(&models.Payment{
+		BaseModel:     models.BaseModel{ID: 1},
+		Amount:        models.NewMoney(5000, "IRR"),
+		PaymentStatus: models.Processing,
+	}, nil)
+	//an installment was paid before
+	mockPaymentRepo.On("GetPaymentByID", mock.Anything, 2).Return(&models.Payment{
+		BaseModel:     models.BaseModel{ID: 2},
+		Amount:        models.NewMoney(5000, "IRR"),
+		PaidAmount:    models.NewMoney(1000, "IRR"),
+		PaymentStatus: models.Processing,
+	}, nil)
+	mockPaymentRepo.On("UpdatePaymentsStatus", mock.Anything, mock.MatchedBy(func(payments []models.Payment) bool {
+		return len(payments) == 2 &&
+			payments[0].PaymentStatus == models.Failed &&
+			payments[1].PaymentStatus == models.PartiallyPaid && payments[1].PaidAmount.Amount == 1000
+	}), mock.MatchedBy(func(transition models.PaymentTransition) bool {
+		return transition.Actor == models.SystemActor && strings.Contains(transition.Reason, "payment transaction 7 expired")
+	})).Return(nil).Once()
+	mockUOW := new(repositories.MockUnitOfWork)
+	mockUOW.On("Do", mock.Anything).Return(nil)
+
+	billService := NewBillService(nil, nil, nil, nil, nil, mockPaymentRepo, nil, nil, mockUOW, nil, mockPaymentService, nil)
+	expired, err := billService.ExpireStalePayments(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	mockPaymentRepo.AssertExpectations(t)
+	mockPaymentRepo.AssertNotCalled(t, "GetPaymentByID", mock.Anything, 3)
+	mockUOW.AssertNumberOfCalls(t, "Do", 2)
+}
+
+func TestPayPartial(t *testing.T) {
+	partiallyPaid := &models.Payment{
+		BaseModel:     models.BaseModel{ID: 5},
+		UserID:        1,
+		Amount:        models.NewMoney(10000, "IRR"),
+		PaidAmount:    models.NewMoney(4500, "IRR"),
+		PaymentStatus: models.PartiallyPaid,
+	}
+	plan := []models.Installment{
+		{Sequence: 1, Amount: models.NewMoney(4000, "IRR")},
+		{Sequence: 2, Amount: models.NewMoney(3000, "IRR")},
+		{Sequence: 3, Amount: models.NewMoney(3000, "IRR")},
+	}
+
+	tests := []struct {
+		name           string
+		amount         models.Money
+		plan           []models.Installment
+		expectedAmount models.Money
+		expectedError  string
+	}{
+		{
+			name:           "explicit amount",
+			amount:         models.NewMoney(1000, ""),
+			expectedAmount: models.NewMoney(1000, "IRR"),
+		},
+		{
+			name:           "rest of the next installment",
+			plan:           plan,
+			expectedAmount: models.NewMoney(2500, "IRR"),
+		},
+		{
+			name:          "no amount and no plan",
+			expectedError: "an amount is required",
+		},
+		{
+			name:          "more than outstanding",
+			amount:        models.NewMoney(5501, "IRR"),
+			expectedError: "exceeds the outstanding balance",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPaymentRepo := new(repositories.MockPaymentRepository)
+			mockInstallmentRepo := new(repositories.MockInstallmentRepository)
+			mockPaymentService := new(payment.MockPayment)
+
+			payment := *partiallyPaid
+			mockPaymentRepo.On("GetPaymentByID", mock.Anything, 5).Return(&payment, nil)
+			mockInstallmentRepo.On("GetInstallmentsByPayment", 5).Return(tt.plan, nil).Maybe()
+			if tt.expectedError == "" {
+				mockPaymentService.On("Initiate", mock.Anything, 1, []int{5}, []models.Money{tt.expectedAmount}, "key").
+					Return(&models.PaymentTransaction{BaseModel: models.BaseModel{ID: 3}, Status: models.TransactionInitiated}, nil)
+				mockPaymentRepo.On("UpdatePaymentsStatus", mock.Anything, mock.MatchedBy(func(payments []models.Payment) bool {
+					return payments[0].PaymentStatus == models.Processing
+				}), mock.Anything).Return(nil)
+			}
+
+			billService := NewBillService(nil, nil, nil, nil, nil, mockPaymentRepo, mockInstallmentRepo, nil, nil, nil, mockPaymentService, nil)
+			_, err := billService.PayPartial(context.Background(), 1, 5, tt.amount, "key")
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			mockPaymentService.AssertExpectations(t)
+			mockPaymentRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetUnpaidBills(t *testing.T) {
+	mockPaymentRepo := new(repositories.MockPaymentRepository)
+	mockInstallmentRepo := new(repositories.MockInstallmentRepository)
+
+	mockPaymentRepo.On("GetPendingPaymentsByUser", 1).Return([]models.Payment{
+		{
+			BaseModel:     models.BaseModel{ID: 5},
+			BillID:        2,
+			Amount:        models.NewMoney(10000, "IRR"),
+			PaidAmount:    models.NewMoney(4000, "IRR"),
+			PaymentStatus: models.PartiallyPaid,
+		},
+		{
+			BaseModel:     models.BaseModel{ID: 6},
+			BillID:        3,
+			Amount:        models.NewMoney(500, "IRR"),
+			PaymentStatus: models.Pending,
+		},
+	}, nil)
+	mockInstallmentRepo.On("GetInstallmentsByPayment", 5).Return([]models.Installment{
+		{Sequence: 1, Amount: models.NewMoney(4000, "IRR"), DueDate: "2025-01-10"},
+		{Sequence: 2, Amount: models.NewMoney(6000, "IRR"), DueDate: "2025-02-10"},
+	}, nil)
+	mockInstallmentRepo.On("GetInstallmentsByPayment", 6).Return(nil, nil)
+
+	billService := NewBillService(nil, nil, nil, nil, nil, mockPaymentRepo, mockInstallmentRepo, nil, nil, nil, nil, nil)
+	balances, err := billService.GetUnpaidBills(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+	assert.Equal(t, models.NewMoney(6000, "IRR"), balances[0].Outstanding)
+	assert.Equal(t, "2025-02-10", balances[0].NextInstallment.DueDate)
+	assert.Equal(t, models.NewMoney(6000, "IRR"), *balances[0].NextInstallmentDue)
+	assert.Equal(t, models.NewMoney(500, "IRR"), balances[1].Outstanding)
+	assert.Nil(t, balances[1].NextInstallment)
+}
+
+func TestSetInstallmentPlan(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           dto.InstallmentPlanRequest
+		expectedError string
+	}{
+		{
+			name: "valid plan",
+			req: dto.InstallmentPlanRequest{Installments: []dto.InstallmentRequest{
+				{Amount: models.NewMoney(6000, ""), DueDate: "2025-01-10"},
+				{Amount: models.NewMoney(4000, ""), DueDate: "2025-02-10"},
+			}},
+		},
+		{
+			name: "does not add up",
+			req: dto.InstallmentPlanRequest{Installments: []dto.InstallmentRequest{
+				{Amount: models.NewMoney(6000, ""), DueDate: "2025-01-10"},
+				{Amount: models.NewMoney(3000, ""), DueDate: "2025-02-10"},
+			}},
+			expectedError: "must add up to the payment amount",
+		},
+		{
+			name: "out of order",
+			req: dto.InstallmentPlanRequest{Installments: []dto.InstallmentRequest{
+				{Amount: models.NewMoney(6000, ""), DueDate: "2025-02-10"},
+				{Amount: models.NewMoney(4000, ""), DueDate: "2025-01-10"},
+			}},
+			expectedError: "ordered by due date",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPaymentRepo := new(repositories.MockPaymentRepository)
+			mockBillRepo := new(repositories.MockBillRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockInstallmentRepo := new(repositories.MockInstallmentRepository)
+
+			mockPaymentRepo.On("GetPaymentByID", mock.Anything, 5).Return(&models.Payment{
+				BaseModel:     models.BaseModel{ID: 5},
+				BillID:        9,
+				Amount:        models.NewMoney(10000, "IRR"),
+				PaymentStatus: models.Pending,
+			}, nil)
+			mockBillRepo.On("GetBillByID", 9).Return(&models.Bill{ApartmentID: 3}, nil)
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 3).Return(models.ManagerRole.Permissions(), nil)
+			if tt.expectedError == "" {
+				mockInstallmentRepo.On("ReplaceInstallmentPlan", mock.Anything, 5, mock.MatchedBy(func(installments []models.Installment) bool {
+					return len(installments) == 2 && installments[1].Sequence == 2 && installments[1].Amount == models.NewMoney(4000, "IRR")
+				})).Return(nil)
+			}
+
+			billService := NewBillService(mockBillRepo, nil, nil, mockUserAptRepo, nil, mockPaymentRepo, mockInstallmentRepo, nil, nil, nil, nil, nil)
+			_, err := billService.SetInstallmentPlan(context.Background(), 1, 5, tt.req)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			mockInstallmentRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRefundPayment(t *testing.T) {
+	paidPayment := func() *models.Payment {
+		return &models.Payment{
+			BaseModel:     models.BaseModel{ID: 5},
+			BillID:        9,
+			UserID:        2,
+			Amount:        models.NewMoney(10000, "IRR"),
+			PaidAmount:    models.NewMoney(10000, "IRR"),
+			PaymentStatus: models.Paid,
+		}
+	}
+
+	tests := []struct {
+		name           string
+		payment        *models.Payment
+		reserved       int64 // pending and completed refunds before this one
+		completed      int64 // completed refunds, this one included
+		amount         models.Money
+		fromCredit     bool
+		providerError  error
+		expectRefund   bool
+		expectedStatus models.PaymentStatus
+		expectedError  string
+	}{
+		{
+			name:           "partial refund",
+			payment:        paidPayment(),
+			amount:         models.NewMoney(4000, "IRR"),
+			completed:      4000,
+			expectRefund:   true,
+			expectedStatus: models.PartiallyRefunded,
+		},
+		{
+			name:           "rest of a partially refunded payment",
+			payment:        paidPayment(),
+			reserved:       4000,
+			completed:      10000,
+			amount:         models.NewMoney(6000, "IRR"),
+			expectRefund:   true,
+			expectedStatus: models.Refunded,
+		},
+		{
+			name:          "more than was paid",
+			payment:       paidPayment(),
+			reserved:      4000,
+			amount:        models.NewMoney(6001, "IRR"),
+			expectedError: "refund exceeds the paid amount",
+		},
+		{
+			name: "late fee paid with the share",
+			payment: func() *models.Payment {
+				p := paidPayment()
+				p.LateFee = models.NewMoney(500, "IRR")
+				p.PaidAmount = models.NewMoney(10500, "IRR")
+				return p
+			}(),
+			reserved:       10000,
+			completed:      10500,
+			amount:         models.NewMoney(500, "IRR"),
+			expectRefund:   true,
+			expectedStatus: models.Refunded,
+		},
+		{
+			name:           "payment paid from credit is refunded as credit",
+			payment:        paidPayment(),
+			completed:      4000,
+			amount:         models.NewMoney(4000, "IRR"),
+			fromCredit:     true,
+			expectedStatus: models.PartiallyRefunded,
+		},
+		{
+			name:          "provider refund fails",
+			payment:       paidPayment(),
+			amount:        models.NewMoney(4000, "IRR"),
+			providerError: errors.New("gateway down"),
+			expectRefund:  true,
+			expectedError: "refund failed",
+		},
+		{
+			name: "pending payment",
+			payment: func() *models.Payment {
+				p := paidPayment()
+				p.PaymentStatus = models.Pending
+				return p
+			}(),
+			amount:        models.NewMoney(100, "IRR"),
+			expectedError: "invalid payment status transition",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPaymentRepo := new(repositories.MockPaymentRepository)
+			mockBillRepo := new(repositories.MockBillRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockPaymentService := new(payment.MockPayment)
+			mockLedgerRepo := new(repositories.MockLedgerRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+
+			mockPaymentRepo.On("GetPaymentByID", mock.Anything, 5).Return(tt.payment, nil)
+			mockPaymentRepo.On("GetPaymentForUpdate", mock.Anything, 5).Return(tt.payment, nil)
+			mockBillRepo.On("GetBillByID", 9).Return(&models.Bill{ApartmentID: 3}, nil)
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 3).Return(models.ManagerRole.Permissions(), nil)
+			mockPaymentRepo.On("SumRefunds", mock.Anything, 5, []models.RefundStatus{models.RefundPending, models.RefundCompleted}).
+				Return(tt.reserved, nil).Maybe()
+			mockPaymentService.On("PaidByProvider", mock.Anything, 5).Return(!tt.fromCredit, nil).Maybe()
+
+			expected := models.PaymentRefund{
+				PaymentID: 5,
+				Amount:    tt.amount,
+				ToCredit:  tt.fromCredit,
+				Status:    models.RefundPending,
+				Actor:     "user:1",
+				Reason:    "overcharged",
+			}
+			if tt.expectRefund || tt.fromCredit {
+				mockPaymentRepo.On("CreateRefund", mock.Anything, expected).Return(3, nil)
+			}
+			if tt.expectRefund {
+				mockPaymentService.On("RefundPayment", mock.Anything, 5, tt.amount).Return(&models.PaymentTransaction{}, tt.providerError)
+			}
+			if tt.providerError != nil {
+				mockPaymentRepo.On("UpdateRefundStatus", mock.Anything, 3, models.RefundFailed).Return(nil)
+			}
+			if tt.expectedError == "" {
+				entryType := models.RefundEntry
+				if tt.fromCredit {
+					entryType = models.CreditEntry
+				}
+				mockPaymentRepo.On("UpdateRefundStatus", mock.Anything, 3, models.RefundCompleted).Return(nil)
+				mockPaymentRepo.On("SumRefunds", mock.Anything, 5, []models.RefundStatus{models.RefundCompleted}).Return(tt.completed, nil)
+				mockPaymentRepo.On("UpdatePaymentStatus", mock.Anything, mock.MatchedBy(func(p models.Payment) bool {
+					return p.PaymentStatus == tt.expectedStatus
+				}), models.PaymentTransition{Amount: tt.amount, Actor: "user:1", Reason: "overcharged"}).Return(nil)
+				mockLedgerRepo.On("AddLedgerEntry", mock.Anything, mock.MatchedBy(func(entry models.LedgerEntry) bool {
+					return entry.EntryType == entryType && entry.UserID == 2 && entry.ApartmentID == 3 && entry.Amount == tt.amount
+				})).Return(1, nil)
+			}
+
+			billService := NewBillService(mockBillRepo, nil, nil, mockUserAptRepo, nil, mockPaymentRepo, nil, mockLedgerRepo, mockUOW, nil, mockPaymentService, nil)
+			refunded, err := billService.RefundPayment(context.Background(), 1, 5, tt.amount, "overcharged")
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				if !tt.expectRefund {
+					mockPaymentRepo.AssertNotCalled(t, "CreateRefund", mock.Anything, mock.Anything)
+					mockPaymentService.AssertNotCalled(t, "RefundPayment", mock.Anything, mock.Anything, mock.Anything)
+				}
+				mockPaymentRepo.AssertNotCalled(t, "UpdatePaymentStatus", mock.Anything, mock.Anything, mock.Anything)
+				mockPaymentRepo.AssertExpectations(t)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, refunded.PaymentStatus)
+			if tt.fromCredit {
+				mockPaymentService.AssertNotCalled(t, "RefundPayment", mock.Anything, mock.Anything, mock.Anything)
+			}
+			mockPaymentRepo.AssertExpectations(t)
+			mockPaymentService.AssertExpectations(t)
+			mockLedgerRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecordChargeback(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      models.PaymentStatus
+		previous    []models.PaymentTransition
+		chargedBack int64
+	}{
+		{
+			name:        "paid payment",
+			status:      models.Paid,
+			previous:    []models.PaymentTransition{{ToStatus: models.Processing}, {ToStatus: models.Paid}},
+			chargedBack: 10000,
+		},
+		{
+			//the refund is already on the ledger
+			name:   "partially refunded payment",
+			status: models.PartiallyRefunded,
+			previous: []models.PaymentTransition{
+				{ToStatus: models.Paid},
+				{ToStatus: models.PartiallyRefunded, Amount: models.NewMoney(3000, "IRR")},
+			},
+			chargedBack: 7000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chargedBack := models.NewMoney(tt.chargedBack, "IRR")
+			mockPaymentRepo := new(repositories.MockPaymentRepository)
+			mockBillRepo := new(repositories.MockBillRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockLedgerRepo := new(repositories.MockLedgerRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+
+			mockPaymentRepo.On("GetPaymentByID", mock.Anything, 5).Return(&models.Payment{
+				BaseModel:     models.BaseModel{ID: 5},
+				BillID:        9,
+				UserID:        2,
+				Amount:        models.NewMoney(10000, "IRR"),
+				PaidAmount:    models.NewMoney(10000, "IRR"),
+				PaymentStatus: tt.status,
+			}, nil)
+			mockBillRepo.On("GetBillByID", 9).Return(&models.Bill{ApartmentID: 3}, nil)
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 3).Return(models.ManagerRole.Permissions(), nil)
+			mockPaymentRepo.On("GetPaymentTransitions", 5).Return(tt.previous, nil)
+			mockPaymentRepo.On("UpdatePaymentStatus", mock.Anything, mock.MatchedBy(func(p models.Payment) bool {
+				return p.PaymentStatus == models.Chargeback
+			}), models.PaymentTransition{Amount: chargedBack, Actor: "user:1", Reason: "disputed"}).Return(nil)
+			mockLedgerRepo.On("AddLedgerEntry", mock.Anything, mock.MatchedBy(func(entry models.LedgerEntry) bool {
+				return entry.EntryType == models.RefundEntry && entry.UserID == 2 && entry.Amount == chargedBack
+			})).Return(1, nil)
+
+			billService := NewBillService(mockBillRepo, nil, nil, mockUserAptRepo, nil, mockPaymentRepo, nil, mockLedgerRepo, mockUOW, nil, nil, nil)
+			payment, err := billService.RecordChargeback(context.Background(), 1, 5, "disputed")
+
+			assert.NoError(t, err)
+			assert.Equal(t, models.Chargeback, payment.PaymentStatus)
+			mockPaymentRepo.AssertExpectations(t)
+			mockLedgerRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDivisionWeights(t *testing.T) {
+	residents := []models.User_apartment{
+		{UserID: 1, UnitArea: 100, OccupantsCount: 4, SharePercent: 70},
+		{UserID: 2, UnitArea: 50, OccupantsCount: 1, SharePercent: 30},
+	}
+
+	weights, err := divisionWeights(models.EqualDivision, residents)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, 1}, weights)
+
+	weights, err = divisionWeights(models.AreaDivision, residents)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{100, 50}, weights)
+
+	weights, err = divisionWeights(models.OccupantsDivision, residents)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{4, 1}, weights)
+
+	weights, err = divisionWeights(models.CustomDivision, residents)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{70, 30}, weights)
+
+	residents[1].SharePercent = 20
+	_, err = divisionWeights(models.CustomDivision, residents)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "expected 100%")
+
+	_, err = divisionWeights(models.AreaDivision, []models.User_apartment{{UserID: 1}})
+	assert.Error(t, err)
+
+	_, err = divisionWeights("unknown", residents)
+	assert.Error(t, err)
+}
+
+func TestUnitDivisionWeights(t *testing.T) {
+	unitID := func(id int) *int { return &id }
+	units := []models.Unit{
+		{BaseModel: models.BaseModel{ID: 1}, Number: "1", Area: 120, OccupantsCount: 3},
+		{BaseModel: models.BaseModel{ID: 2}, Number: "2", Area: 60, OccupantsCount: 1},
+		{BaseModel: models.BaseModel{ID: 3}, Number: "3", Area: 80, OccupantsCount: 2}, //empty
+	}
+	residents := []models.User_apartment{
+		{UserID: 1, UnitID: unitID(1), UnitRelation: models.UnitResident},
+		{UserID: 2, UnitID: unitID(1), UnitRelation: models.UnitResident},
+		{UserID: 3, UnitID: unitID(1), UnitRelation: models.UnitOwner}, //doesn't pay, the unit has residents
+		{UserID: 4, UnitID: unitID(2), UnitRelation: models.UnitOwner},
+		{UserID: 5}, //not in a unit
+	}
+	userIDs := func(members []models.User_apartment) []int {
+		ids := make([]int, len(members))
+		for i, member := range members {
+			ids[i] = member.UserID
+		}
+		return ids
+	}
+
+	charged, weights, err := unitDivisionWeights(models.UnitDivision, residents, units)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 4}, userIDs(charged))
+	assert.Equal(t, []float64{0.5, 0.5, 1}, weights)
+
+	charged, weights, err = unitDivisionWeights(models.UnitAreaDivision, residents, units)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 4}, userIDs(charged))
+	assert.Equal(t, []float64{60, 60, 60}, weights)
+
+	_, weights, err = unitDivisionWeights(models.UnitOccupantsDivision, residents, units)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1.5, 1.5, 1}, weights)
+
+	_, _, err = unitDivisionWeights(models.UnitDivision, []models.User_apartment{{UserID: 5}}, units)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no occupied units")
+}
+
+func TestDivideBillByType(t *testing.T) {
+	bill := models.Bill{
+		BaseModel:   models.BaseModel{ID: 10},
+		ApartmentID: 1,
+		BillType:    models.WaterBill,
+		TotalAmount: models.NewMoney(10000, "IRR"),
+	}
+
+	mockBillRepo := new(repositories.MockBillRepository)
+	mockAptRepo := new(repositories.MockApartmentRepo)
+	mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+	mockPaymentRepo := new(repositories.MockPaymentRepository)
+	mockLedgerRepo := new(repositories.MockLedgerRepository)
+	mockNotificationService := new(notification.MockNotification)
+	mockUOW := new(repositories.MockUnitOfWork)
+	mockUOW.On("Do", mock.Anything).Return(nil)
+
+	mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
+	mockAptRepo.On("GetApartmentByID", 1).Return(&models.Apartment{
+		BaseModel:      models.BaseModel{ID: 1},
+		DivisionPolicy: models.OccupantsDivision,
+	}, nil)
+	mockUserAptRepo.On("GetUserApartmentsByApartment", 1).Return([]models.User_apartment{
+		{UserID: 1, ApartmentID: 1, IsManager: true, OccupantsCount: 1},
+		{UserID: 2, ApartmentID: 1, OccupantsCount: 2},
+		{UserID: 3, ApartmentID: 1, OccupantsCount: 0},
+	}, nil)
+	mockBillRepo.On("GetUndividedBillsByTypeAndApartment", 1, models.WaterBill).Return([]models.Bill{bill}, nil)
+	mockPaymentRepo.On("GetPaymentByBillAndUser", 10, mock.Anything).Return(nil, errors.New("not found"))
+	//resident 2 has credit left over from an earlier overpayment
+	mockLedgerRepo.On("GetBalance", mock.Anything, 1, 1).Return(models.NewMoney(0, "IRR"), nil)
+	mockLedgerRepo.On("GetBalance", mock.Anything, 2, 1).Return(models.NewMoney(-5000, "IRR"), nil)
+	mockPaymentRepo.On("CreatePayment", mock.Anything, mock.MatchedBy(func(p models.Payment) bool {
+		return p.UserID == 1 && p.Amount == models.NewMoney(3333, "IRR") && p.PaymentStatus == models.Pending
+	})).Return(1, nil).Once()
+	mockPaymentRepo.On("CreatePayment", mock.Anything, mock.MatchedBy(func(p models.Payment) bool {
+		return p.UserID == 2 && p.Amount == models.NewMoney(6667, "IRR") && p.PaymentStatus == models.Pending
+	})).Return(2, nil).Once()
+	mockPaymentRepo.On("UpdatePaymentStatus", mock.Anything, mock.MatchedBy(func(p models.Payment) bool {
+		return p.ID == 2 && p.PaidAmount.Amount == 5000 && p.PaymentStatus == models.PartiallyPaid
+	}), models.PaymentTransition{Actor: models.SystemActor, Reason: "credit applied"}).Return(nil).Once()
+	mockLedgerRepo.On("AddLedgerEntry", mock.Anything, mock.MatchedBy(func(entry models.LedgerEntry) bool {
+		return entry.EntryType == models.ChargeEntry && entry.ApartmentID == 1 && *entry.PaymentID == entry.UserID
+	})).Return(1, nil).Twice()
+	mockNotificationService.ExpectAnyNotificationCall(nil)
+
+	billService := NewBillService(
+		mockBillRepo,
+		nil,
+		mockAptRepo,
+		mockUserAptRepo,
+		nil,
+		mockPaymentRepo,
+		nil,
+		mockLedgerRepo,
+		mockUOW,
+		nil,
+		nil,
+		mockNotificationService,
+	)
+
+	response, err := billService.DivideBillByType(context.Background(), 1, 1, models.WaterBill)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10}, response["processed_bills"])
+	assert.Equal(t, models.OccupantsDivision, response["division_policy"])
+
+	mockPaymentRepo.AssertExpectations(t)
+	mockPaymentRepo.AssertNumberOfCalls(t, "CreatePayment", 2)
+	mockLedgerRepo.AssertExpectations(t)
+}
+
+func TestDivideBillByType_FailureCreatesNothing(t *testing.T) {
+	bill := models.Bill{
+		BaseModel:   models.BaseModel{ID: 10},
+		ApartmentID: 1,
+		BillType:    models.WaterBill,
+		TotalAmount: models.NewMoney(10000, "IRR"),
+	}
+
+	mockBillRepo := new(repositories.MockBillRepository)
+	mockAptRepo := new(repositories.MockApartmentRepo)
+	mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+	mockPaymentRepo := new(repositories.MockPaymentRepository)
+	mockLedgerRepo := new(repositories.MockLedgerRepository)
+	mockNotificationService := new(notification.MockNotification)
+	mockUOW := new(repositories.MockUnitOfWork)
+	mockUOW.On("Do", mock.Anything).Return(nil)
+
+	mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 1).Return(models.ManagerRole.Permissions(), nil)
+	mockAptRepo.On("GetApartmentByID", 1).Return(&models.Apartment{
+		BaseModel:      models.BaseModel{ID: 1},
+		DivisionPolicy: models.EqualDivision,
+	}, nil)
+	mockUserAptRepo.On("GetUserApartmentsByApartment", 1).Return([]models.User_apartment{
+		{UserID: 1, ApartmentID: 1},
+		{UserID: 2, ApartmentID: 1},
+	}, nil)
+	mockBillRepo.On("GetUndividedBillsByTypeAndApartment", 1, models.WaterBill).Return([]models.Bill{bill}, nil)
+	mockPaymentRepo.On("GetPaymentByBillAndUser", 10, mock.Anything).Return(nil, errors.New("not found"))
+	mockLedgerRepo.On("GetBalance", mock.Anything, mock.Anything, 1).Return(models.NewMoney(0, "IRR"), nil)
+	mockPaymentRepo.On("CreatePayment", mock.Anything, mock.MatchedBy(func(p models.Payment) bool {
+		return p.UserID == 1
+	})).Return(1, nil)
+	mockPaymentRepo.On("CreatePayment", mock.Anything, mock.MatchedBy(func(p models.Payment) bool {
+		return p.UserID == 2
+	})).Return(0, errors.New("db down"))
+	mockLedgerRepo.On("AddLedgerEntry", mock.Anything, mock.Anything).Return(1, nil)
+
+	billService := NewBillService(mockBillRepo, nil, mockAptRepo, mockUserAptRepo, nil, mockPaymentRepo, nil, mockLedgerRepo, mockUOW, nil, nil, mockNotificationService)
+	_, err := billService.DivideBillByType(context.Background(), 1, 1, models.WaterBill)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to divide bills")
+	//the unit of work rolls back resident 1's payment, nobody is told about it
+	mockNotificationService.AssertNotCalled(t, "SendBillNotification", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+type BillTemplateService interface {
+	CreateTemplate(ctx context.Context, managerID, apartmentID int, req dto.CreateBillTemplateRequest) (int, error)
+	GetTemplatesByApartment(ctx context.Context, managerID, apartmentID int) ([]models.BillTemplate, error)
+	DeleteTemplate(ctx context.Context, managerID, templateID int) error
+	GenerateDueBills(ctx context.Context, now time.Time) (int, error)
+}
+
+type billTemplateServiceImpl struct {
+	templateRepo      repositories.BillTemplateRepository
+	billRepo          repositories.BillRepository
+	apartmentRepo     repositories.ApartmentRepository
+	userApartmentRepo repositories.UserApartmentRepository
+	uow               repositories.UnitOfWork
+	billService       BillService
+}
+
+func NewBillTemplateService(
+	templateRepo repositories.BillTemplateRepository,
+	billRepo repositories.BillRepository,
+	apartmentRepo repositories.ApartmentRepository,
+	userApartmentRepo repositories.UserApartmentRepository,
+	uow repositories.UnitOfWork,
+	billService BillService,
+) BillTemplateService {
+	return &billTemplateServiceImpl{
+		templateRepo:      templateRepo,
+		billRepo:          billRepo,
+		apartmentRepo:     apartmentRepo,
+		userApartmentRepo: userApartmentRepo,
+		uow:               uow,
+		billService:       billService,
+	}
+}
+
+func (s *billTemplateServiceImpl) CreateTemplate(ctx context.Context, managerID, apartmentID int, req dto.CreateBillTemplateRequest) (int, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"manager_id":   managerID,
+		"apartment_id": apartmentID,
+		"bill_type":    req.BillType,
+		"cadence":      req.Cadence,
+	})
+
+	logger.Info("Creating bill template")
+
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageBills); err != nil {
+		logger.WithError(err).Warn("User not allowed to create bill template")
+		return 0, fmt.Errorf("not allowed to create bill templates: %w", err)
+	}
+
+	validBillTypes := map[models.BillType]bool{
+		models.WaterBill:       true,
+		models.ElectricityBill: true,
+		models.GasBill:         true,
+		models.MaintenanceBill: true,
+		models.OtherBill:       true,
+	}
+	if !validBillTypes[req.BillType] {
+		return 0, fmt.Errorf("invalid bill type")
+	}
+	if !req.Amount.IsPositive() {
+		return 0, fmt.Errorf("amount must be positive")
+	}
+
+	if req.AmountFormula == "" {
+		req.AmountFormula = models.FixedAmount
+	}
+	validFormulas := map[models.AmountFormula]bool{
+		models.FixedAmount:       true,
+		models.PerUnitAmount:     true,
+		models.PerResidentAmount: true,
+	}
+	if !validFormulas[req.AmountFormula] {
+		return 0, fmt.Errorf("invalid amount formula")
+	}
+
+	validCadences := map[models.Cadence]bool{
+		models.Monthly:   true,
+		models.Quarterly: true,
+		models.Yearly:    true,
+	}
+	if !validCadences[req.Cadence] {
+		return 0, fmt.Errorf("invalid cadence")
+	}
+	if req.DueDayOffset < 0 {
+		return 0, fmt.Errorf("due day offset must not be negative")
+	}
+
+	//periods repeat on the day of the start date, which every month has to
+	//have
+	start := currentMonth(time.Now())
+	if req.StartDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return 0, fmt.Errorf("invalid start date format (use YYYY-MM-DD)")
+		}
+		if parsed.Day() > 28 {
+			return 0, fmt.Errorf("start date must be on one of the first 28 days of a month")
+		}
+		start = parsed
+	}
+
+	template := models.BillTemplate{
+		BaseModel: models.BaseModel{
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		ApartmentID:   apartmentID,
+		BillType:      req.BillType,
+		Amount:        req.Amount,
+		AmountFormula: req.AmountFormula,
+		Cadence:       req.Cadence,
+		DueDayOffset:  req.DueDayOffset,
+		Description:   req.Description,
+		AutoDivide:    req.AutoDivide,
+		Active:        true,
+		NextRunAt:     start,
+	}
+
+	id, err := s.templateRepo.CreateBillTemplate(ctx, template)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create bill template")
+		return 0, fmt.Errorf("failed to create bill template: %w", err)
+	}
+
+	logger.WithField("template_id", id).Info("Bill template created")
+	return id, nil
+}
+
+func (s *billTemplateServiceImpl) GetTemplatesByApartment(ctx context.Context, managerID, apartmentID int) ([]models.BillTemplate, error) {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermViewFinances); err != nil {
+		return nil, fmt.Errorf("not allowed to view bill templates: %w", err)
+	}
+
+	templates, err := s.templateRepo.GetBillTemplatesByApartment(apartmentID)
+	if err != nil {
+		logrus.WithError(err).WithField("apartment_id", apartmentID).Error("Failed to get bill templates")
+		return nil, fmt.Errorf("failed to get bill templates: %w", err)
+	}
+	return templates, nil
+}
+
+func (s *billTemplateServiceImpl) DeleteTemplate(ctx context.Context, managerID, templateID int) error {
+	template, err := s.templateRepo.GetBillTemplateByID(templateID)
+	if err != nil {
+		return fmt.Errorf("bill template not found: %w", err)
+	}
+
+	if err := authorize(ctx, s.userApartmentRepo, managerID, template.ApartmentID, models.PermManageBills); err != nil {
+		return fmt.Errorf("not allowed to delete bill templates: %w", err)
+	}
+
+	if err := s.templateRepo.DeleteBillTemplate(templateID); err != nil {
+		logrus.WithError(err).WithField("template_id", templateID).Error("Failed to delete bill template")
+		return fmt.Errorf("failed to delete bill template: %w", err)
+	}
+	return nil
+}
+
+// creates a bill for every template period that started up to now. periods
+// missed while the service was down are generated one by one, so nothing is
+// skipped after downtime. returns the number of generated bills
+func (s *billTemplateServiceImpl) GenerateDueBills(ctx context.Context, now time.Time) (int, error) {
+	templates, err := s.templateRepo.GetDueBillTemplates(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due bill templates: %w", err)
+	}
+
+	generated := 0
+	for _, template := range templates {
+		logger := logrus.WithFields(logrus.Fields{
+			"template_id":  template.ID,
+			"apartment_id": template.ApartmentID,
+		})
+
+		for period := template.NextRunAt; !period.After(now); period = template.Cadence.Next(period) {
+			//claiming the period and creating its bill commit together, a
+			//failed bill leaves the period to be retried on the next run
+			var bill models.Bill
+			var claimed bool
+			err := s.uow.Do(ctx, func(ctx context.Context) error {
+				var err error
+				claimed, err = s.templateRepo.AdvanceBillTemplate(ctx, template.ID, period, template.Cadence.Next(period))
+				if err != nil || !claimed {
+					return err
+				}
+				bill, err = s.generateBill(ctx, template, period)
+				return err
+			})
+			if err != nil {
+				logger.WithError(err).WithField("period", period).Error("Failed to generate bill from template")
+				break
+			}
+			if !claimed {
+				logger.Debug("Bill template period already generated by another instance")
+				break
+			}
+			generated++
+
+			if template.AutoDivide {
+				//the bill itself exists now, a failed division can still be
+				//done by hand
+				if err := s.billService.DivideBill(ctx, bill); err != nil {
+					logrus.WithError(err).WithField("bill_id", bill.ID).Warn("Failed to divide generated bill")
+				}
+			}
+		}
+	}
+
+	if generated > 0 {
+		logrus.WithField("bills_count", generated).Info("Generated bills from templates")
+	}
+	return generated, nil
+}
+
+func (s *billTemplateServiceImpl) generateBill(ctx context.Context, template models.BillTemplate, period time.Time) (models.Bill, error) {
+	amount, err := s.templateAmount(template)
+	if err != nil {
+		return models.Bill{}, err
+	}
+
+	dueDate := period.AddDate(0, 0, template.DueDayOffset).Format("2006-01-02")
+	description := period.Format("2006-01")
+	if template.Description != "" {
+		description = template.Description + " (" + description + ")"
+	}
+
+	bill := models.Bill{
+		BaseModel: models.BaseModel{
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		ApartmentID:     template.ApartmentID,
+		BillType:        template.BillType,
+		TotalAmount:     amount,
+		DueDate:         dueDate,
+		BillingDeadline: dueDate,
+		Description:     description,
+	}
+
+	bill.ID, err = s.billRepo.CreateBill(ctx, bill)
+	if err != nil {
+		return models.Bill{}, fmt.Errorf("failed to create bill: %w", err)
+	}
+	return bill, nil
+}
+
+func (s *billTemplateServiceImpl) templateAmount(template models.BillTemplate) (models.Money, error) {
+	switch template.AmountFormula {
+	case models.PerUnitAmount:
+		apartment, err := s.apartmentRepo.GetApartmentByID(template.ApartmentID)
+		if err != nil {
+			return models.Money{}, fmt.Errorf("failed to get apartment: %w", err)
+		}
+		return template.Amount.Multiply(int64(apartment.UnitsCount)), nil
+	case models.PerResidentAmount:
+		residents, err := s.userApartmentRepo.GetUserApartmentsByApartment(template.ApartmentID)
+		if err != nil {
+			return models.Money{}, fmt.Errorf("failed to get residents: %w", err)
+		}
+		return template.Amount.Multiply(int64(len(residents))), nil
+	default:
+		return template.Amount, nil
+	}
+}
+
+// the first day of t's month, where templates without a start date begin
+func currentMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
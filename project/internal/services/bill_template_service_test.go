@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateBillTemplate(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           dto.CreateBillTemplateRequest
+		setupMocks    func(*repositories.MockBillTemplateRepository, *repositories.MockUserApartmentRepository)
+		expectedError string
+	}{
+		{
+			name: "monthly template starts on its start date",
+			req: dto.CreateBillTemplateRequest{
+				BillType:     models.MaintenanceBill,
+				Amount:       models.NewMoney(500000, "IRR"),
+				Cadence:      models.Monthly,
+				DueDayOffset: 10,
+				StartDate:    "2025-03-17",
+			},
+			setupMocks: func(templateRepo *repositories.MockBillTemplateRepository, userAptRepo *repositories.MockUserApartmentRepository) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(models.ManagerRole.Permissions(), nil)
+				templateRepo.On("CreateBillTemplate", mock.Anything, mock.MatchedBy(func(template models.BillTemplate) bool {
+					return template.AmountFormula == models.FixedAmount && template.Active &&
+						template.NextRunAt.Equal(time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC))
+				})).Return(4, nil)
+			},
+		},
+		{
+			name: "not a manager",
+			req: dto.CreateBillTemplateRequest{
+				BillType: models.WaterBill,
+				Amount:   models.NewMoney(100, "IRR"),
+				Cadence:  models.Monthly,
+			},
+			setupMocks: func(templateRepo *repositories.MockBillTemplateRepository, userAptRepo *repositories.MockUserApartmentRepository) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(models.ResidentRole.Permissions(), nil)
+			},
+			expectedError: "not allowed to",
+		},
+		{
+			name: "start date some months do not have",
+			req: dto.CreateBillTemplateRequest{
+				BillType:  models.WaterBill,
+				Amount:    models.NewMoney(100, "IRR"),
+				Cadence:   models.Monthly,
+				StartDate: "2025-01-31",
+			},
+			setupMocks: func(templateRepo *repositories.MockBillTemplateRepository, userAptRepo *repositories.MockUserApartmentRepository) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(models.ManagerRole.Permissions(), nil)
+			},
+			expectedError: "first 28 days",
+		},
+		{
+			name: "invalid cadence",
+			req: dto.CreateBillTemplateRequest{
+				BillType: models.WaterBill,
+				Amount:   models.NewMoney(100, "IRR"),
+				Cadence:  "weekly",
+			},
+			setupMocks: func(templateRepo *repositories.MockBillTemplateRepository, userAptRepo *repositories.MockUserApartmentRepository) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(models.ManagerRole.Permissions(), nil)
+			},
+			expectedError: "invalid cadence",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTemplateRepo := new(repositories.MockBillTemplateRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			tt.setupMocks(mockTemplateRepo, mockUserAptRepo)
+
+			service := NewBillTemplateService(mockTemplateRepo, nil, nil, mockUserAptRepo, nil, nil)
+			id, err := service.CreateTemplate(context.Background(), 1, 2, tt.req)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 4, id)
+			mockTemplateRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGenerateDueBills_CatchesUpMissedPeriods(t *testing.T) {
+	now := time.Date(2025, 3, 5, 12, 0, 0, 0, time.UTC)
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	template := models.BillTemplate{
+		BaseModel:     models.BaseModel{ID: 9},
+		ApartmentID:   2,
+		BillType:      models.MaintenanceBill,
+		Amount:        models.NewMoney(100000, "IRR"),
+		AmountFormula: models.PerUnitAmount,
+		Cadence:       models.Monthly,
+		DueDayOffset:  9,
+		Description:   "Maintenance",
+		Active:        true,
+		NextRunAt:     jan,
+	}
+
+	mockTemplateRepo := new(repositories.MockBillTemplateRepository)
+	mockBillRepo := new(repositories.MockBillRepository)
+	mockAptRepo := new(repositories.MockApartmentRepo)
+
+	mockTemplateRepo.On("GetDueBillTemplates", mock.Anything, now).Return([]models.BillTemplate{template}, nil)
+	mockTemplateRepo.On("AdvanceBillTemplate", mock.Anything, 9, jan, feb).Return(true, nil).Once()
+	mockTemplateRepo.On("AdvanceBillTemplate", mock.Anything, 9, feb, mar).Return(true, nil).Once()
+	mockTemplateRepo.On("AdvanceBillTemplate", mock.Anything, 9, mar, apr).Return(true, nil).Once()
+	mockAptRepo.On("GetApartmentByID", 2).Return(&models.Apartment{UnitsCount: 4}, nil)
+
+	var dueDates []string
+	mockBillRepo.On("CreateBill", mock.Anything, mock.MatchedBy(func(bill models.Bill) bool {
+		return bill.TotalAmount == models.NewMoney(400000, "IRR") && bill.BillType == models.MaintenanceBill
+	})).Run(func(args mock.Arguments) {
+		dueDates = append(dueDates, args.Get(1).(models.Bill).DueDate)
+	}).Return(1, nil)
+
+	mockUOW := new(repositories.MockUnitOfWork)
+	mockUOW.On("Do", mock.Anything).Return(nil)
+
+	service := NewBillTemplateService(mockTemplateRepo, mockBillRepo, mockAptRepo, nil, mockUOW, nil)
+	generated, err := service.GenerateDueBills(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, generated)
+	assert.Equal(t, []string{"2025-01-10", "2025-02-10", "2025-03-10"}, dueDates)
+	mockTemplateRepo.AssertExpectations(t)
+}
+
+func TestGenerateDueBills_LeavesFailedPeriodForNextRun(t *testing.T) {
+	now := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	template := models.BillTemplate{
+		BaseModel:   models.BaseModel{ID: 3},
+		ApartmentID: 2,
+		BillType:    models.WaterBill,
+		Amount:      models.NewMoney(5000, "IRR"),
+		Cadence:     models.Quarterly,
+		Active:      true,
+		NextRunAt:   jan,
+	}
+
+	mockTemplateRepo := new(repositories.MockBillTemplateRepository)
+	mockBillRepo := new(repositories.MockBillRepository)
+
+	mockTemplateRepo.On("GetDueBillTemplates", mock.Anything, now).Return([]models.BillTemplate{template}, nil)
+	mockTemplateRepo.On("AdvanceBillTemplate", mock.Anything, 3, jan, apr).Return(true, nil).Once()
+	mockBillRepo.On("CreateBill", mock.Anything, mock.Anything).Return(0, errors.New("db down"))
+	//the claim rolls back with the bill
+	mockUOW := new(repositories.MockUnitOfWork)
+	mockUOW.On("Do", mock.Anything).Return(nil)
+
+	service := NewBillTemplateService(mockTemplateRepo, mockBillRepo, nil, nil, mockUOW, nil)
+	generated, err := service.GenerateDueBills(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, generated)
+	mockTemplateRepo.AssertExpectations(t)
+	mockTemplateRepo.AssertNumberOfCalls(t, "AdvanceBillTemplate", 1)
+	mockUOW.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestGenerateDueBills_MidMonthStart(t *testing.T) {
+	now := time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)
+	mar := time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2025, 4, 17, 0, 0, 0, 0, time.UTC)
+	may := time.Date(2025, 5, 17, 0, 0, 0, 0, time.UTC)
+
+	template := models.BillTemplate{
+		BaseModel:    models.BaseModel{ID: 5},
+		ApartmentID:  2,
+		BillType:     models.WaterBill,
+		Amount:       models.NewMoney(5000, "IRR"),
+		Cadence:      models.Monthly,
+		DueDayOffset: 3,
+		Active:       true,
+		NextRunAt:    mar,
+	}
+
+	mockTemplateRepo := new(repositories.MockBillTemplateRepository)
+	mockBillRepo := new(repositories.MockBillRepository)
+
+	mockTemplateRepo.On("GetDueBillTemplates", mock.Anything, now).Return([]models.BillTemplate{template}, nil)
+	mockTemplateRepo.On("AdvanceBillTemplate", mock.Anything, 5, mar, apr).Return(true, nil).Once()
+	mockTemplateRepo.On("AdvanceBillTemplate", mock.Anything, 5, apr, may).Return(true, nil).Once()
+
+	var dueDates []string
+	mockBillRepo.On("CreateBill", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		dueDates = append(dueDates, args.Get(1).(models.Bill).DueDate)
+	}).Return(1, nil)
+	mockUOW := new(repositories.MockUnitOfWork)
+	mockUOW.On("Do", mock.Anything).Return(nil)
+
+	service := NewBillTemplateService(mockTemplateRepo, mockBillRepo, nil, nil, mockUOW, nil)
+	generated, err := service.GenerateDueBills(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, generated)
+	assert.Equal(t, []string{"2025-03-20", "2025-04-20"}, dueDates)
+	mockTemplateRepo.AssertExpectations(t)
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrNoPendingHandover = errors.New("no pending handover for this apartment")
+
+// HandoverService moves an apartment from its manager to another member in
+// two steps: the manager offers, the member accepts or declines
+type HandoverService interface {
+	OfferHandover(ctx context.Context, managerID, apartmentID int, req dto.HandoverRequest) (*models.ManagerHandover, error)
+	GetPendingHandover(ctx context.Context, userID, apartmentID int) (*models.ManagerHandover, error)
+	CancelHandover(ctx context.Context, managerID, apartmentID int) error
+	AcceptHandover(ctx context.Context, userID, apartmentID int) (*models.ManagerHandover, error)
+	DeclineHandover(ctx context.Context, userID, apartmentID int) error
+}
+
+type handoverServiceImpl struct {
+	handoverRepo        repositories.HandoverRepository
+	apartmentRepo       repositories.ApartmentRepository
+	userApartmentRepo   repositories.UserApartmentRepository
+	uow                 repositories.UnitOfWork
+	notificationService notification.Notification
+}
+
+func NewHandoverService(
+	handoverRepo repositories.HandoverRepository,
+	apartmentRepo repositories.ApartmentRepository,
+	userApartmentRepo repositories.UserApartmentRepository,
+	uow repositories.UnitOfWork,
+	notificationService notification.Notification,
+) HandoverService {
+	return &handoverServiceImpl{
+		handoverRepo:        handoverRepo,
+		apartmentRepo:       apartmentRepo,
+		userApartmentRepo:   userApartmentRepo,
+		uow:                 uow,
+		notificationService: notificationService,
+	}
+}
+
+// offers the apartment to one of its members. only the apartment's manager
+// can, co-managers run the apartment but don't give it away
+func (s *handoverServiceImpl) OfferHandover(ctx context.Context, managerID, apartmentID int, req dto.HandoverRequest) (*models.ManagerHandover, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"managerID":   managerID,
+		"apartmentID": apartmentID,
+		"toUserID":    req.UserID,
+	})
+	logger.Info("Offering apartment handover")
+
+	apartment, err := s.apartmentRepo.GetApartmentByID(apartmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get apartment: %w", err)
+	}
+	if apartment.ManagerID != managerID {
+		return nil, fmt.Errorf("%w: only the apartment's manager can hand it over", ErrForbidden)
+	}
+	if req.UserID == managerID {
+		return nil, fmt.Errorf("you already manage this apartment")
+	}
+	if _, err := s.userApartmentRepo.GetUserApartmentByID(req.UserID, apartmentID); err != nil {
+		return nil, fmt.Errorf("user is not a member of this apartment")
+	}
+
+	if _, err := s.handoverRepo.GetPendingHandover(ctx, apartmentID); err == nil {
+		return nil, fmt.Errorf("a handover of this apartment is already pending")
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check pending handovers: %w", err)
+	}
+
+	handover := models.ManagerHandover{
+		ApartmentID:   apartmentID,
+		FromUserID:    managerID,
+		ToUserID:      req.UserID,
+		StayAsManager: req.StayAsManager,
+		Status:        models.HandoverPending,
+	}
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		id, err := s.handoverRepo.CreateHandover(ctx, handover)
+		if err != nil {
+			logger.WithError(err).Error("Failed to create handover")
+			return fmt.Errorf("failed to create handover: %w", err)
+		}
+		handover.ID = id
+
+		return s.notificationService.SendNotification(ctx, req.UserID, models.Notice{
+			Template:    models.HandoverOfferedTemplate,
+			ApartmentID: apartmentID,
+			Data:        map[string]string{"apartment": apartment.ApartmentName},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &handover, nil
+}
+
+// the apartment's open offer, for its members to see
+func (s *handoverServiceImpl) GetPendingHandover(ctx context.Context, userID, apartmentID int) (*models.ManagerHandover, error) {
+	if err := authorize(ctx, s.userApartmentRepo, userID, apartmentID, models.PermViewApartment); err != nil {
+		return nil, fmt.Errorf("not allowed to view the handover: %w", err)
+	}
+	return s.pendingHandover(ctx, apartmentID)
+}
+
+func (s *handoverServiceImpl) CancelHandover(ctx context.Context, managerID, apartmentID int) error {
+	handover, err := s.pendingHandover(ctx, apartmentID)
+	if err != nil {
+		return err
+	}
+	if handover.FromUserID != managerID {
+		return fmt.Errorf("%w: only the manager who offered the handover can cancel it", ErrForbidden)
+	}
+
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.handoverRepo.CloseHandover(ctx, handover.ID, models.HandoverCancelled); err != nil {
+			return fmt.Errorf("failed to cancel handover: %w", err)
+		}
+		return s.notificationService.SendNotification(ctx, handover.ToUserID, models.Notice{
+			Template:    models.HandoverCancelledTemplate,
+			ApartmentID: apartmentID,
+		})
+	})
+}
+
+// makes the incoming manager the apartment's manager and owner. the
+// outgoing one becomes a co-manager or a resident, as they offered
+func (s *handoverServiceImpl) AcceptHandover(ctx context.Context, userID, apartmentID int) (*models.ManagerHandover, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"userID":      userID,
+		"apartmentID": apartmentID,
+	})
+	logger.Info("Accepting apartment handover")
+
+	handover, err := s.pendingHandover(ctx, apartmentID)
+	if err != nil {
+		return nil, err
+	}
+	if handover.ToUserID != userID {
+		return nil, fmt.Errorf("%w: the handover was offered to someone else", ErrForbidden)
+	}
+
+	outgoingRole := models.ResidentRole
+	if handover.StayAsManager {
+		outgoingRole = models.ManagerRole
+	}
+
+	//the apartment can't be left with two owners or none
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.handoverRepo.CloseHandover(ctx, handover.ID, models.HandoverAccepted); err != nil {
+			return fmt.Errorf("failed to accept handover: %w", err)
+		}
+		if err := s.apartmentRepo.SetApartmentManager(ctx, apartmentID, userID); err != nil {
+			return fmt.Errorf("failed to change apartment manager: %w", err)
+		}
+		if err := s.userApartmentRepo.SetMemberRole(ctx, userID, apartmentID, models.OwnerRole, 0); err != nil {
+			return fmt.Errorf("failed to make %d owner: %w", userID, err)
+		}
+		if err := s.userApartmentRepo.SetMemberRole(ctx, handover.FromUserID, apartmentID, outgoingRole, 0); err != nil {
+			return fmt.Errorf("failed to change role of %d: %w", handover.FromUserID, err)
+		}
+
+		err := s.notificationService.SendNotification(ctx, handover.FromUserID, models.Notice{
+			Template:    models.HandoverCompletedTemplate,
+			ApartmentID: apartmentID,
+			Data:        map[string]string{"role": string(outgoingRole)},
+		})
+		if err != nil {
+			return err
+		}
+		return s.notificationService.SendNotification(ctx, userID, models.Notice{
+			Template:    models.NewManagerTemplate,
+			ApartmentID: apartmentID,
+		})
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to accept handover")
+		return nil, err
+	}
+	handover.Status = models.HandoverAccepted
+	return handover, nil
+}
+
+func (s *handoverServiceImpl) DeclineHandover(ctx context.Context, userID, apartmentID int) error {
+	handover, err := s.pendingHandover(ctx, apartmentID)
+	if err != nil {
+		return err
+	}
+	if handover.ToUserID != userID {
+		return fmt.Errorf("%w: the handover was offered to someone else", ErrForbidden)
+	}
+
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.handoverRepo.CloseHandover(ctx, handover.ID, models.HandoverDeclined); err != nil {
+			return fmt.Errorf("failed to decline handover: %w", err)
+		}
+		return s.notificationService.SendNotification(ctx, handover.FromUserID, models.Notice{
+			Template:    models.HandoverDeclinedTemplate,
+			ApartmentID: apartmentID,
+		})
+	})
+}
+
+func (s *handoverServiceImpl) pendingHandover(ctx context.Context, apartmentID int) (*models.ManagerHandover, error) {
+	handover, err := s.handoverRepo.GetPendingHandover(ctx, apartmentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoPendingHandover
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get handover: %w", err)
+	}
+	return handover, nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOfferHandover(t *testing.T) {
+	tests := []struct {
+		name          string
+		managerID     int
+		req           dto.HandoverRequest
+		mockSetup     func(*repositories.MockHandoverRepository, *repositories.MockUserApartmentRepository, *notification.MockNotification)
+		expectedError string
+	}{
+		{
+			name:      "offer to a co-manager",
+			managerID: 1,
+			req:       dto.HandoverRequest{UserID: 2, StayAsManager: true},
+			mockSetup: func(handoverRepo *repositories.MockHandoverRepository, userAptRepo *repositories.MockUserApartmentRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetUserApartmentByID", 2, 5).Return(&models.User_apartment{Role: models.ManagerRole}, nil)
+				handoverRepo.On("GetPendingHandover", mock.Anything, 5).Return(nil, sql.ErrNoRows)
+				handoverRepo.On("CreateHandover", mock.Anything, models.ManagerHandover{
+					ApartmentID:   5,
+					FromUserID:    1,
+					ToUserID:      2,
+					StayAsManager: true,
+					Status:        models.HandoverPending,
+				}).Return(7, nil)
+				notif.On("SendNotification", mock.Anything, 2, mock.Anything).Return(nil)
+			},
+		},
+		{
+			name:      "co-manager can't offer",
+			managerID: 3,
+			req:       dto.HandoverRequest{UserID: 2},
+			mockSetup: func(*repositories.MockHandoverRepository, *repositories.MockUserApartmentRepository, *notification.MockNotification) {
+			},
+			expectedError: "only the apartment's manager",
+		},
+		{
+			name:      "to themselves",
+			managerID: 1,
+			req:       dto.HandoverRequest{UserID: 1},
+			mockSetup: func(*repositories.MockHandoverRepository, *repositories.MockUserApartmentRepository, *notification.MockNotification) {
+			},
+			expectedError: "already manage",
+		},
+		{
+			name:      "not a member",
+			managerID: 1,
+			req:       dto.HandoverRequest{UserID: 2},
+			mockSetup: func(handoverRepo *repositories.MockHandoverRepository, userAptRepo *repositories.MockUserApartmentRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetUserApartmentByID", 2, 5).Return(nil, sql.ErrNoRows)
+			},
+			expectedError: "not a member",
+		},
+		{
+			name:      "already pending",
+			managerID: 1,
+			req:       dto.HandoverRequest{UserID: 2},
+			mockSetup: func(handoverRepo *repositories.MockHandoverRepository, userAptRepo *repositories.MockUserApartmentRepository, notif *notification.MockNotification) {
+				userAptRepo.On("GetUserApartmentByID", 2, 5).Return(&models.User_apartment{}, nil)
+				handoverRepo.On("GetPendingHandover", mock.Anything, 5).Return(&models.ManagerHandover{ToUserID: 3}, nil)
+			},
+			expectedError: "already pending",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockHandoverRepo := new(repositories.MockHandoverRepository)
+			mockAptRepo := new(repositories.MockApartmentRepo)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockNotif := new(notification.MockNotification)
+
+			mockUOW.On("Do", mock.Anything).Return(nil)
+			mockAptRepo.On("GetApartmentByID", 5).Return(&models.Apartment{ApartmentName: "Sunny", ManagerID: 1}, nil)
+			tt.mockSetup(mockHandoverRepo, mockUserAptRepo, mockNotif)
+
+			service := NewHandoverService(mockHandoverRepo, mockAptRepo, mockUserAptRepo, mockUOW, mockNotif)
+			handover, err := service.OfferHandover(context.Background(), tt.managerID, 5, tt.req)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				mockHandoverRepo.AssertNotCalled(t, "CreateHandover", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 7, handover.ID)
+			mockHandoverRepo.AssertExpectations(t)
+			mockNotif.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAcceptHandover(t *testing.T) {
+	tests := []struct {
+		name          string
+		stayAsManager bool
+		outgoingRole  models.Role
+	}{
+		{name: "outgoing manager steps down", outgoingRole: models.ResidentRole},
+		{name: "outgoing manager stays a co-manager", stayAsManager: true, outgoingRole: models.ManagerRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockHandoverRepo := new(repositories.MockHandoverRepository)
+			mockAptRepo := new(repositories.MockApartmentRepo)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockNotif := new(notification.MockNotification)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+
+			mockHandoverRepo.On("GetPendingHandover", mock.Anything, 5).Return(&models.ManagerHandover{
+				BaseModel:     models.BaseModel{ID: 7},
+				ApartmentID:   5,
+				FromUserID:    1,
+				ToUserID:      2,
+				StayAsManager: tt.stayAsManager,
+				Status:        models.HandoverPending,
+			}, nil)
+			mockHandoverRepo.On("CloseHandover", mock.Anything, 7, models.HandoverAccepted).Return(nil)
+			mockAptRepo.On("SetApartmentManager", mock.Anything, 5, 2).Return(nil)
+			mockUserAptRepo.On("SetMemberRole", mock.Anything, 2, 5, models.OwnerRole, models.Permission(0)).Return(nil)
+			mockUserAptRepo.On("SetMemberRole", mock.Anything, 1, 5, tt.outgoingRole, models.Permission(0)).Return(nil)
+			mockNotif.On("SendNotification", mock.Anything, 1, mock.Anything).Return(nil).Once()
+			mockNotif.On("SendNotification", mock.Anything, 2, mock.Anything).Return(nil).Once()
+
+			service := NewHandoverService(mockHandoverRepo, mockAptRepo, mockUserAptRepo, mockUOW, mockNotif)
+			handover, err := service.AcceptHandover(context.Background(), 2, 5)
+
+			assert.NoError(t, err)
+			assert.Equal(t, models.HandoverAccepted, handover.Status)
+			mockHandoverRepo.AssertExpectations(t)
+			mockAptRepo.AssertExpectations(t)
+			mockUserAptRepo.AssertExpectations(t)
+			mockNotif.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAcceptHandover_Rejected(t *testing.T) {
+	mockHandoverRepo := new(repositories.MockHandoverRepository)
+	mockHandoverRepo.On("GetPendingHandover", mock.Anything, 5).Return(&models.ManagerHandover{FromUserID: 1, ToUserID: 2}, nil)
+	mockHandoverRepo.On("GetPendingHandover", mock.Anything, 6).Return(nil, sql.ErrNoRows)
+
+	service := NewHandoverService(mockHandoverRepo, nil, nil, nil, nil)
+
+	_, err := service.AcceptHandover(context.Background(), 3, 5)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = service.AcceptHandover(context.Background(), 2, 6)
+	assert.ErrorIs(t, err, ErrNoPendingHandover)
+}
+
+func TestDeclineAndCancelHandover(t *testing.T) {
+	pending := &models.ManagerHandover{BaseModel: models.BaseModel{ID: 7}, FromUserID: 1, ToUserID: 2}
+
+	t.Run("incoming manager declines", func(t *testing.T) {
+		mockHandoverRepo := new(repositories.MockHandoverRepository)
+		mockUOW := new(repositories.MockUnitOfWork)
+		mockNotif := new(notification.MockNotification)
+		mockUOW.On("Do", mock.Anything).Return(nil)
+		mockHandoverRepo.On("GetPendingHandover", mock.Anything, 5).Return(pending, nil)
+		mockHandoverRepo.On("CloseHandover", mock.Anything, 7, models.HandoverDeclined).Return(nil)
+		mockNotif.On("SendNotification", mock.Anything, 1, models.Notice{
+			Template:    models.HandoverDeclinedTemplate,
+			ApartmentID: 5,
+		}).Return(nil)
+
+		service := NewHandoverService(mockHandoverRepo, nil, nil, mockUOW, mockNotif)
+		assert.NoError(t, service.DeclineHandover(context.Background(), 2, 5))
+		mockHandoverRepo.AssertExpectations(t)
+		mockNotif.AssertExpectations(t)
+	})
+
+	t.Run("outgoing manager cancels", func(t *testing.T) {
+		mockHandoverRepo := new(repositories.MockHandoverRepository)
+		mockUOW := new(repositories.MockUnitOfWork)
+		mockNotif := new(notification.MockNotification)
+		mockUOW.On("Do", mock.Anything).Return(nil)
+		mockHandoverRepo.On("GetPendingHandover", mock.Anything, 5).Return(pending, nil)
+		mockHandoverRepo.On("CloseHandover", mock.Anything, 7, models.HandoverCancelled).Return(nil)
+		mockNotif.On("SendNotification", mock.Anything, 2, mock.Anything).Return(nil)
+
+		service := NewHandoverService(mockHandoverRepo, nil, nil, mockUOW, mockNotif)
+		assert.NoError(t, service.CancelHandover(context.Background(), 1, 5))
+		mockHandoverRepo.AssertExpectations(t)
+		mockNotif.AssertExpectations(t)
+	})
+
+	t.Run("only the offerer cancels", func(t *testing.T) {
+		mockHandoverRepo := new(repositories.MockHandoverRepository)
+		mockHandoverRepo.On("GetPendingHandover", mock.Anything, 5).Return(pending, nil)
+
+		service := NewHandoverService(mockHandoverRepo, nil, nil, nil, nil)
+		assert.ErrorIs(t, service.CancelHandover(context.Background(), 2, 5), ErrForbidden)
+	})
+
+	t.Run("closed in the meantime", func(t *testing.T) {
+		mockHandoverRepo := new(repositories.MockHandoverRepository)
+		mockHandoverRepo.On("GetPendingHandover", mock.Anything, 5).Return(pending, nil)
+		mockHandoverRepo.On("CloseHandover", mock.Anything, 7, models.HandoverDeclined).Return(repositories.ErrHandoverNotPending)
+		mockUOW := new(repositories.MockUnitOfWork)
+		mockUOW.On("Do", mock.Anything).Return(nil)
+
+		service := NewHandoverService(mockHandoverRepo, nil, nil, mockUOW, nil)
+		err := service.DeclineHandover(context.Background(), 2, 5)
+		assert.True(t, errors.Is(err, repositories.ErrHandoverNotPending))
+	})
+}
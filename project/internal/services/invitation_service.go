@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+// how long an invitation can be accepted, counted again when it's resent
+const invitationTTL = 24 * time.Hour
+
+var ErrInvitationNotFound = errors.New("invitation not found")
+
+// InvitationService follows invitations after they were sent: managers
+// list, resend and revoke them, receivers see theirs and reject them.
+// sending and accepting are part of ApartmentService
+type InvitationService interface {
+	GetApartmentInvitations(ctx context.Context, managerID, apartmentID int) ([]models.InvitationLink, error)
+	ResendInvitation(ctx context.Context, managerID, invitationID int) (*models.InvitationLink, error)
+	RevokeInvitation(ctx context.Context, managerID, invitationID int) error
+	GetMyInvitations(ctx context.Context, userID int) ([]models.InvitationLink, error)
+	RejectInvitation(ctx context.Context, userID, invitationID int) error
+}
+
+type invitationServiceImpl struct {
+	inviteLinkRepo      repositories.InviteLinkRepo
+	userApartmentRepo   repositories.UserApartmentRepository
+	uow                 repositories.UnitOfWork
+	notificationService notification.Notification
+}
+
+func NewInvitationService(
+	inviteLinkRepo repositories.InviteLinkRepo,
+	userApartmentRepo repositories.UserApartmentRepository,
+	uow repositories.UnitOfWork,
+	notificationService notification.Notification,
+) InvitationService {
+	return &invitationServiceImpl{
+		inviteLinkRepo:      inviteLinkRepo,
+		userApartmentRepo:   userApartmentRepo,
+		uow:                 uow,
+		notificationService: notificationService,
+	}
+}
+
+// every invitation of the apartment whatever its status, newest first
+func (s *invitationServiceImpl) GetApartmentInvitations(ctx context.Context, managerID, apartmentID int) ([]models.InvitationLink, error) {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermInviteMembers); err != nil {
+		return nil, fmt.Errorf("not allowed to view invitations: %w", err)
+	}
+
+	invitations, err := s.inviteLinkRepo.GetInvitationsByApartment(ctx, apartmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+	for i := range invitations {
+		invitations[i].InviteURL = invitationURL(invitations[i].Token)
+	}
+	return invitations, nil
+}
+
+// sends an open invitation again with a new expiry. one that expired can be
+// resent as long as no newer invitation replaced it
+func (s *invitationServiceImpl) ResendInvitation(ctx context.Context, managerID, invitationID int) (*models.InvitationLink, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"managerID":    managerID,
+		"invitationID": invitationID,
+	})
+
+	invitation, err := s.managedInvitation(ctx, managerID, invitationID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(invitationTTL)
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.inviteLinkRepo.RenewInvitation(ctx, invitation.ID, expiresAt); err != nil {
+			return fmt.Errorf("failed to resend invitation: %w", err)
+		}
+		invitation.ExpiresAt = expiresAt
+		return sendInvitation(ctx, s.notificationService, invitation)
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to resend invitation")
+		return nil, err
+	}
+	if invitation.Status == models.InvitationStatusExpired {
+		invitation.Status = models.InvitationStatusPending
+	}
+
+	logger.Info("Invitation resent")
+	return invitation, nil
+}
+
+func (s *invitationServiceImpl) RevokeInvitation(ctx context.Context, managerID, invitationID int) error {
+	invitation, err := s.managedInvitation(ctx, managerID, invitationID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.inviteLinkRepo.CloseInvitation(ctx, invitation.ID, models.InvitationStatusRevoked); err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	logrus.Infof("Invitation %d revoked by %d", invitationID, managerID)
+	return nil
+}
+
+// the invitations the user can still accept or reject
+func (s *invitationServiceImpl) GetMyInvitations(ctx context.Context, userID int) ([]models.InvitationLink, error) {
+	invitations, err := s.inviteLinkRepo.GetOpenInvitationsForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+	for i := range invitations {
+		invitations[i].InviteURL = invitationURL(invitations[i].Token)
+	}
+	return invitations, nil
+}
+
+// turns an invitation down and lets its sender know. only open invitations
+// sent to the user can be rejected
+func (s *invitationServiceImpl) RejectInvitation(ctx context.Context, userID, invitationID int) error {
+	open, err := s.inviteLinkRepo.GetOpenInvitationsForUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get invitations: %w", err)
+	}
+	var invitation *models.InvitationLink
+	for i := range open {
+		if open[i].ID == invitationID {
+			invitation = &open[i]
+		}
+	}
+	if invitation == nil {
+		return ErrInvitationNotFound
+	}
+
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.inviteLinkRepo.CloseInvitation(ctx, invitation.ID, models.InvitationStatusRejected); err != nil {
+			return fmt.Errorf("failed to reject invitation: %w", err)
+		}
+		return s.notificationService.SendNotification(ctx, invitation.SenderID, models.Notice{
+			Template:    models.InvitationRejectedTemplate,
+			ApartmentID: invitation.ApartmentID,
+			Data:        map[string]string{"apartment": invitation.ApartmentName},
+		})
+	})
+}
+
+// the invitation, if the manager may act on the invitations of its apartment
+func (s *invitationServiceImpl) managedInvitation(ctx context.Context, managerID, invitationID int) (*models.InvitationLink, error) {
+	invitation, err := s.getInvitation(ctx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, s.userApartmentRepo, managerID, invitation.ApartmentID, models.PermInviteMembers); err != nil {
+		return nil, fmt.Errorf("not allowed to manage invitations: %w", err)
+	}
+	return invitation, nil
+}
+
+func (s *invitationServiceImpl) getInvitation(ctx context.Context, invitationID int) (*models.InvitationLink, error) {
+	invitation, err := s.inviteLinkRepo.GetInvitationByID(ctx, invitationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	invitation.InviteURL = invitationURL(invitation.Token)
+	return invitation, nil
+}
+
+// queues the invitation for its receiver, it becomes notified once the outbox
+// delivered it. ctx should be the unit of work that created or renewed it
+func sendInvitation(ctx context.Context, notifier notification.Notification, invitation *models.InvitationLink) error {
+	invitation.InviteURL = invitationURL(invitation.Token)
+	return notifier.SendInvitation(ctx, *invitation)
+}
+
+func newInvitationToken() (string, error) {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func invitationURL(token string) string {
+	return fmt.Sprintf("http://localhost:8080/api/v1/resident/apartment/invite/%s", token)
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testInvitation(status models.InvitationStatus) *models.InvitationLink {
+	return &models.InvitationLink{
+		BaseModel:        models.BaseModel{ID: 7},
+		SenderID:         1,
+		ReceiverID:       5,
+		ReceiverUsername: "resident_tg",
+		ApartmentID:      2,
+		ApartmentName:    "Sunset",
+		Token:            "abc",
+		Status:           status,
+	}
+}
+
+func TestGetApartmentInvitations(t *testing.T) {
+	tests := []struct {
+		name          string
+		isManager     bool
+		expectedError string
+	}{
+		{name: "manager", isManager: true},
+		{name: "resident", expectedError: "not allowed to view invitations"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockInviteRepo := new(repositories.MockInviteLinkRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(permissionsOf(tt.isManager), nil)
+			mockInviteRepo.On("GetInvitationsByApartment", mock.Anything, 2).
+				Return([]models.InvitationLink{*testInvitation(models.InvitationStatusNotified)}, nil)
+
+			service := NewInvitationService(mockInviteRepo, mockUserAptRepo, nil, nil)
+			invitations, err := service.GetApartmentInvitations(context.Background(), 1, 2)
+
+			if tt.expectedError != "" {
+				assert.ErrorIs(t, err, ErrForbidden)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, invitations, 1)
+			assert.Contains(t, invitations[0].InviteURL, "/resident/apartment/invite/abc")
+		})
+	}
+}
+
+func TestResendInvitation(t *testing.T) {
+	tests := []struct {
+		name          string
+		renewErr      error
+		sendErr       error
+		expectedError string
+	}{
+		{name: "expired invitation is renewed"},
+		{
+			name:          "closed invitation",
+			renewErr:      repositories.ErrInvitationNotOpen,
+			expectedError: "no longer open",
+		},
+		{
+			name:          "notification fails",
+			sendErr:       errors.New("failed to queue notification"),
+			expectedError: "failed to queue notification",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockInviteRepo := new(repositories.MockInviteLinkRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockNotif := new(notification.MockNotification)
+
+			mockUOW.On("Do", mock.Anything).Return(nil)
+			mockInviteRepo.On("GetInvitationByID", mock.Anything, 7).Return(testInvitation(models.InvitationStatusExpired), nil)
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(models.ManagerRole.Permissions(), nil)
+			mockInviteRepo.On("RenewInvitation", mock.Anything, 7, mock.MatchedBy(func(expiresAt time.Time) bool {
+				return expiresAt.After(time.Now().Add(23 * time.Hour))
+			})).Return(tt.renewErr)
+			mockNotif.On("SendInvitation", mock.Anything, mock.MatchedBy(func(invitation models.InvitationLink) bool {
+				return invitation.ID == 7 && invitation.ReceiverID == 5 && invitation.InviteURL != ""
+			})).Return(tt.sendErr)
+
+			service := NewInvitationService(mockInviteRepo, mockUserAptRepo, mockUOW, mockNotif)
+			invitation, err := service.ResendInvitation(context.Background(), 1, 7)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, models.InvitationStatusPending, invitation.Status)
+			mockInviteRepo.AssertExpectations(t)
+			mockNotif.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRevokeInvitation_NotAllowed(t *testing.T) {
+	mockInviteRepo := new(repositories.MockInviteLinkRepository)
+	mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+
+	mockInviteRepo.On("GetInvitationByID", mock.Anything, 7).Return(testInvitation(models.InvitationStatusPending), nil)
+	mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 3, 2).Return(models.ResidentRole.Permissions(), nil)
+
+	service := NewInvitationService(mockInviteRepo, mockUserAptRepo, nil, nil)
+	err := service.RevokeInvitation(context.Background(), 3, 7)
+
+	assert.ErrorIs(t, err, ErrForbidden)
+	mockInviteRepo.AssertNotCalled(t, "CloseInvitation", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRejectInvitation(t *testing.T) {
+	contactInvitation := testInvitation(models.InvitationStatusPending)
+	contactInvitation.ID = 8
+	contactInvitation.ReceiverID = 0
+	contactInvitation.ReceiverEmail = "neighbour@example.com"
+
+	tests := []struct {
+		name          string
+		invitationID  int
+		expectedError error
+	}{
+		{name: "sent to the user", invitationID: 7},
+		{name: "sent to the user's email", invitationID: 8},
+		{name: "not one of the user's open invitations", invitationID: 9, expectedError: ErrInvitationNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockInviteRepo := new(repositories.MockInviteLinkRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockNotif := new(notification.MockNotification)
+
+			mockUOW.On("Do", mock.Anything).Return(nil)
+			mockInviteRepo.On("GetOpenInvitationsForUser", mock.Anything, 5).
+				Return([]models.InvitationLink{*testInvitation(models.InvitationStatusNotified), *contactInvitation}, nil)
+			mockInviteRepo.On("CloseInvitation", mock.Anything, tt.invitationID, models.InvitationStatusRejected).Return(nil)
+			mockNotif.On("SendNotification", mock.Anything, 1, models.Notice{
+				Template:    models.InvitationRejectedTemplate,
+				ApartmentID: 2,
+				Data:        map[string]string{"apartment": "Sunset"},
+			}).Return(nil)
+
+			service := NewInvitationService(mockInviteRepo, nil, mockUOW, mockNotif)
+			err := service.RejectInvitation(context.Background(), 5, tt.invitationID)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				mockInviteRepo.AssertNotCalled(t, "CloseInvitation", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			mockInviteRepo.AssertExpectations(t)
+			mockNotif.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+type LateFeeService interface {
+	SetRule(ctx context.Context, managerID, apartmentID int, req dto.LateFeeRuleRequest) (*models.LateFeeRule, error)
+	GetRule(ctx context.Context, managerID, apartmentID int) (*models.LateFeeRule, error)
+	DeleteRule(ctx context.Context, managerID, apartmentID int) error
+	ApplyLateFees(ctx context.Context, now time.Time) (int, error)
+	WaiveLateFee(ctx context.Context, managerID, paymentID int, reason string) (*models.Payment, error)
+	GetLateFeeEvents(ctx context.Context, userID, paymentID int) ([]models.LateFeeEvent, error)
+}
+
+type lateFeeServiceImpl struct {
+	lateFeeRepo       repositories.LateFeeRepository
+	paymentRepo       repositories.PaymentRepository
+	billRepo          repositories.BillRepository
+	userApartmentRepo repositories.UserApartmentRepository
+	ledgerRepo        repositories.LedgerRepository
+	uow               repositories.UnitOfWork
+}
+
+func NewLateFeeService(
+	lateFeeRepo repositories.LateFeeRepository,
+	paymentRepo repositories.PaymentRepository,
+	billRepo repositories.BillRepository,
+	userApartmentRepo repositories.UserApartmentRepository,
+	ledgerRepo repositories.LedgerRepository,
+	uow repositories.UnitOfWork,
+) LateFeeService {
+	return &lateFeeServiceImpl{
+		lateFeeRepo:       lateFeeRepo,
+		paymentRepo:       paymentRepo,
+		billRepo:          billRepo,
+		userApartmentRepo: userApartmentRepo,
+		ledgerRepo:        ledgerRepo,
+		uow:               uow,
+	}
+}
+
+func (s *lateFeeServiceImpl) SetRule(ctx context.Context, managerID, apartmentID int, req dto.LateFeeRuleRequest) (*models.LateFeeRule, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"manager_id":   managerID,
+		"apartment_id": apartmentID,
+		"fee_type":     req.FeeType,
+	})
+
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageBills); err != nil {
+		logger.WithError(err).Warn("User not allowed to set late fee rule")
+		return nil, fmt.Errorf("not allowed to set late fees: %w", err)
+	}
+
+	if req.Amount.Currency == "" {
+		req.Amount.Currency = models.DefaultCurrency
+	}
+	if req.Cap.Currency == "" {
+		req.Cap.Currency = req.Amount.Currency
+	}
+
+	switch req.FeeType {
+	case models.FlatLateFee, models.DailyLateFee:
+		if !req.Amount.IsPositive() {
+			return nil, fmt.Errorf("amount must be positive")
+		}
+		req.RateBasisPoints = 0
+	case models.PercentageLateFee:
+		if req.RateBasisPoints <= 0 || req.RateBasisPoints > 10000 {
+			return nil, fmt.Errorf("rate must be between 1 and 10000 basis points")
+		}
+		req.Amount.Amount = 0
+	default:
+		return nil, fmt.Errorf("invalid late fee type")
+	}
+	if req.GraceDays < 0 {
+		return nil, fmt.Errorf("grace days must not be negative")
+	}
+	if req.Cap.Amount < 0 || req.Cap.Currency != req.Amount.Currency {
+		return nil, fmt.Errorf("cap must be a non-negative amount in %s", req.Amount.Currency)
+	}
+
+	rule := models.LateFeeRule{
+		ApartmentID:     apartmentID,
+		FeeType:         req.FeeType,
+		Amount:          req.Amount,
+		RateBasisPoints: req.RateBasisPoints,
+		GraceDays:       req.GraceDays,
+		Cap:             req.Cap,
+		Active:          req.Active == nil || *req.Active,
+	}
+
+	id, err := s.lateFeeRepo.SetLateFeeRule(ctx, rule)
+	if err != nil {
+		logger.WithError(err).Error("Failed to save late fee rule")
+		return nil, fmt.Errorf("failed to save late fee rule: %w", err)
+	}
+	rule.ID = id
+
+	logger.Info("Late fee rule saved")
+	return &rule, nil
+}
+
+func (s *lateFeeServiceImpl) GetRule(ctx context.Context, managerID, apartmentID int) (*models.LateFeeRule, error) {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermViewFinances); err != nil {
+		return nil, fmt.Errorf("not allowed to view late fees: %w", err)
+	}
+
+	rule, err := s.lateFeeRepo.GetLateFeeRule(apartmentID)
+	if err != nil {
+		return nil, fmt.Errorf("late fee rule not found: %w", err)
+	}
+	return rule, nil
+}
+
+func (s *lateFeeServiceImpl) DeleteRule(ctx context.Context, managerID, apartmentID int) error {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageBills); err != nil {
+		return fmt.Errorf("not allowed to delete late fees: %w", err)
+	}
+
+	if err := s.lateFeeRepo.DeleteLateFeeRule(apartmentID); err != nil {
+		logrus.WithError(err).WithField("apartment_id", apartmentID).Error("Failed to delete late fee rule")
+		return fmt.Errorf("failed to delete late fee rule: %w", err)
+	}
+	return nil
+}
+
+// brings the late fee of every overdue payment up to date with its
+// apartment's rule. fees are computed from the deadline, so runs missed
+// while the service was down are caught up. returns the number of payments
+// whose fee grew
+func (s *lateFeeServiceImpl) ApplyLateFees(ctx context.Context, now time.Time) (int, error) {
+	rules, err := s.lateFeeRepo.GetActiveLateFeeRules(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get late fee rules: %w", err)
+	}
+
+	charged := 0
+	for _, rule := range rules {
+		logger := logrus.WithField("apartment_id", rule.ApartmentID)
+
+		payments, err := s.lateFeeRepo.GetOverduePayments(ctx, rule.ApartmentID, now)
+		if err != nil {
+			logger.WithError(err).Error("Failed to get overdue payments")
+			continue
+		}
+
+		for _, payment := range payments {
+			deadline, err := time.Parse("2006-01-02", payment.Deadline)
+			if err != nil {
+				logger.WithError(err).WithField("payment_id", payment.ID).Warn("Invalid payment deadline")
+				continue
+			}
+
+			fee := rule.FeeFor(payment.Amount, deadline, now)
+			if fee.Amount <= payment.LateFee.Amount {
+				continue
+			}
+
+			reason := fmt.Sprintf("%s late fee, deadline %s", rule.FeeType, payment.Deadline)
+			var added models.Money
+			err = s.uow.Do(ctx, func(ctx context.Context) error {
+				if added, err = s.lateFeeRepo.AccrueLateFee(ctx, payment.ID, fee, reason); err != nil {
+					return fmt.Errorf("failed to accrue late fee: %w", err)
+				}
+				paymentID := payment.ID
+				return addLedgerEntry(ctx, s.ledgerRepo, models.LedgerEntry{
+					UserID:      payment.UserID,
+					ApartmentID: rule.ApartmentID,
+					PaymentID:   &paymentID,
+					EntryType:   models.FeeEntry,
+					Amount:      added,
+					Description: reason,
+				})
+			})
+			if err != nil {
+				logger.WithError(err).WithField("payment_id", payment.ID).Error("Failed to accrue late fee")
+				continue
+			}
+			if added.IsPositive() {
+				charged++
+			}
+		}
+	}
+
+	if charged > 0 {
+		logrus.WithField("payments_count", charged).Info("Applied late fees")
+	}
+	return charged, nil
+}
+
+// drops the unpaid late fee of a payment. the waiver is recorded with the
+// manager and reason, and a partially paid payment with nothing else left
+// to pay becomes paid
+func (s *lateFeeServiceImpl) WaiveLateFee(ctx context.Context, managerID, paymentID int, reason string) (*models.Payment, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"manager_id": managerID,
+		"payment_id": paymentID,
+	})
+
+	_, bill, err := s.getManagedPayment(ctx, managerID, paymentID, models.PermManagePayments)
+	if err != nil {
+		return nil, err
+	}
+
+	var waived models.Money
+	var payment *models.Payment
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		waived, err = s.lateFeeRepo.WaiveLateFee(ctx, paymentID, models.UserActor(managerID), reason)
+		if err != nil {
+			logger.WithError(err).Error("Failed to waive late fee")
+			return fmt.Errorf("failed to waive late fee: %w", err)
+		}
+
+		payment, err = s.paymentRepo.GetPaymentByID(ctx, paymentID)
+		if err != nil {
+			return fmt.Errorf("payment not found: %w", err)
+		}
+
+		err = addLedgerEntry(ctx, s.ledgerRepo, models.LedgerEntry{
+			UserID:      payment.UserID,
+			ApartmentID: bill.ApartmentID,
+			PaymentID:   &paymentID,
+			EntryType:   models.CreditEntry,
+			Amount:      waived,
+			Description: "late fee waived: " + reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		if payment.PaymentStatus == models.PartiallyPaid && !payment.Outstanding().IsPositive() {
+			payment.PaymentStatus = models.Paid
+			payment.PaidAt = time.Now()
+			err = s.paymentRepo.UpdatePaymentStatus(ctx, *payment, models.PaymentTransition{
+				Actor:  models.UserActor(managerID),
+				Reason: "late fee waived",
+			})
+			if err != nil {
+				logger.WithError(err).Error("Failed to settle payment after waiver")
+				return fmt.Errorf("failed to update payment status: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.WithField("amount", waived).Info("Late fee waived")
+	return payment, nil
+}
+
+// the late fee history of a payment, visible to the resident who owes it and
+// to whoever can view the apartment's finances
+func (s *lateFeeServiceImpl) GetLateFeeEvents(ctx context.Context, userID, paymentID int) ([]models.LateFeeEvent, error) {
+	payment, err := s.paymentRepo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("payment not found: %w", err)
+	}
+	if payment.UserID != userID {
+		if _, _, err := s.getManagedPayment(ctx, userID, paymentID, models.PermViewFinances); err != nil {
+			return nil, err
+		}
+	}
+
+	events, err := s.lateFeeRepo.GetLateFeeEvents(paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get late fee history: %w", err)
+	}
+	return events, nil
+}
+
+// loads a payment and its bill in an apartment where the user has perm
+func (s *lateFeeServiceImpl) getManagedPayment(ctx context.Context, managerID, paymentID int, perm models.Permission) (*models.Payment, *models.Bill, error) {
+	payment, err := s.paymentRepo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("payment not found: %w", err)
+	}
+
+	bill, err := s.billRepo.GetBillByID(payment.BillID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bill not found: %w", err)
+	}
+
+	if err := authorize(ctx, s.userApartmentRepo, managerID, bill.ApartmentID, perm); err != nil {
+		return nil, nil, fmt.Errorf("not allowed to manage late fees: %w", err)
+	}
+	return payment, bill, nil
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetLateFeeRule(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           dto.LateFeeRuleRequest
+		isManager     bool
+		expectedError string
+	}{
+		{
+			name: "daily rule with cap",
+			req: dto.LateFeeRuleRequest{
+				FeeType:   models.DailyLateFee,
+				Amount:    models.NewMoney(1000, ""),
+				GraceDays: 3,
+				Cap:       models.NewMoney(30000, ""),
+			},
+			isManager: true,
+		},
+		{
+			name:          "percentage rule without a rate",
+			req:           dto.LateFeeRuleRequest{FeeType: models.PercentageLateFee},
+			isManager:     true,
+			expectedError: "basis points",
+		},
+		{
+			name:          "unknown type",
+			req:           dto.LateFeeRuleRequest{FeeType: "weekly", Amount: models.NewMoney(1000, "")},
+			isManager:     true,
+			expectedError: "invalid late fee type",
+		},
+		{
+			name:          "not a manager",
+			req:           dto.LateFeeRuleRequest{FeeType: models.FlatLateFee, Amount: models.NewMoney(1000, "")},
+			expectedError: "not allowed to",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLateFeeRepo := new(repositories.MockLateFeeRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(permissionsOf(tt.isManager), nil)
+			if tt.expectedError == "" {
+				mockLateFeeRepo.On("SetLateFeeRule", mock.Anything, mock.MatchedBy(func(rule models.LateFeeRule) bool {
+					return rule.Active && rule.Amount == models.NewMoney(1000, "IRR") && rule.Cap == models.NewMoney(30000, "IRR")
+				})).Return(6, nil)
+			}
+
+			service := NewLateFeeService(mockLateFeeRepo, nil, nil, mockUserAptRepo, nil, nil)
+			rule, err := service.SetRule(context.Background(), 1, 2, tt.req)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 6, rule.ID)
+			mockLateFeeRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplyLateFees(t *testing.T) {
+	now := time.Date(2025, 3, 15, 6, 0, 0, 0, time.UTC)
+	rule := models.LateFeeRule{
+		ApartmentID: 2,
+		FeeType:     models.DailyLateFee,
+		Amount:      models.NewMoney(1000, "IRR"),
+		Cap:         models.NewMoney(4000, "IRR"),
+		Active:      true,
+	}
+	overdue := func(id int, deadline string, lateFee int64) models.OverduePayment {
+		return models.OverduePayment{
+			Payment: models.Payment{
+				BaseModel:     models.BaseModel{ID: id},
+				Amount:        models.NewMoney(100000, "IRR"),
+				LateFee:       models.NewMoney(lateFee, "IRR"),
+				PaymentStatus: models.Pending,
+			},
+			Deadline: deadline,
+		}
+	}
+
+	mockLateFeeRepo := new(repositories.MockLateFeeRepository)
+	mockLateFeeRepo.On("GetActiveLateFeeRules", mock.Anything).Return([]models.LateFeeRule{rule}, nil)
+	mockLateFeeRepo.On("GetOverduePayments", mock.Anything, 2, now).Return([]models.OverduePayment{
+		overdue(1, "2025-03-12", 2000), //three days late
+		overdue(2, "2025-03-01", 4000), //already at the cap
+		overdue(3, "2025-03-14", 0),    //one day late
+	}, nil)
+	mockLateFeeRepo.On("AccrueLateFee", mock.Anything, 1, models.NewMoney(3000, "IRR"), mock.Anything).
+		Return(models.NewMoney(1000, "IRR"), nil)
+	mockLateFeeRepo.On("AccrueLateFee", mock.Anything, 3, models.NewMoney(1000, "IRR"), mock.Anything).
+		Return(models.Money{}, errors.New("db down"))
+	mockLedgerRepo := new(repositories.MockLedgerRepository)
+	mockLedgerRepo.On("AddLedgerEntry", mock.Anything, mock.MatchedBy(func(entry models.LedgerEntry) bool {
+		return entry.EntryType == models.FeeEntry && *entry.PaymentID == 1 && entry.ApartmentID == 2 &&
+			entry.Amount == models.NewMoney(1000, "IRR")
+	})).Return(1, nil).Once()
+	mockUOW := new(repositories.MockUnitOfWork)
+	mockUOW.On("Do", mock.Anything).Return(nil)
+
+	service := NewLateFeeService(mockLateFeeRepo, nil, nil, nil, mockLedgerRepo, mockUOW)
+	charged, err := service.ApplyLateFees(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, charged)
+	mockLateFeeRepo.AssertExpectations(t)
+	mockLateFeeRepo.AssertNotCalled(t, "AccrueLateFee", mock.Anything, 2, mock.Anything, mock.Anything)
+	mockLedgerRepo.AssertExpectations(t)
+}
+
+func TestWaiveLateFee(t *testing.T) {
+	tests := []struct {
+		name          string
+		afterWaiver   models.Payment
+		expectSettled bool
+	}{
+		{
+			name: "unpaid payment keeps its status",
+			afterWaiver: models.Payment{
+				Amount:        models.NewMoney(10000, "IRR"),
+				PaidAmount:    models.NewMoney(4000, "IRR"),
+				PaymentStatus: models.PartiallyPaid,
+			},
+		},
+		{
+			name: "nothing left to pay settles the payment",
+			afterWaiver: models.Payment{
+				Amount:        models.NewMoney(10000, "IRR"),
+				PaidAmount:    models.NewMoney(10000, "IRR"),
+				PaymentStatus: models.PartiallyPaid,
+			},
+			expectSettled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLateFeeRepo := new(repositories.MockLateFeeRepository)
+			mockPaymentRepo := new(repositories.MockPaymentRepository)
+			mockBillRepo := new(repositories.MockBillRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockLedgerRepo := new(repositories.MockLedgerRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+
+			before := tt.afterWaiver
+			before.BaseModel.ID = 5
+			before.BillID = 9
+			before.LateFee = models.NewMoney(3000, "IRR")
+			after := tt.afterWaiver
+			after.BaseModel.ID = 5
+			after.BillID = 9
+
+			mockPaymentRepo.On("GetPaymentByID", mock.Anything, 5).Return(&before, nil).Once()
+			mockPaymentRepo.On("GetPaymentByID", mock.Anything, 5).Return(&after, nil).Once()
+			mockBillRepo.On("GetBillByID", 9).Return(&models.Bill{ApartmentID: 2}, nil)
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(models.ManagerRole.Permissions(), nil)
+			mockLateFeeRepo.On("WaiveLateFee", mock.Anything, 5, "user:1", "first time").
+				Return(models.NewMoney(3000, "IRR"), nil)
+			mockLedgerRepo.On("AddLedgerEntry", mock.Anything, mock.MatchedBy(func(entry models.LedgerEntry) bool {
+				return entry.EntryType == models.CreditEntry && entry.Amount == models.NewMoney(3000, "IRR")
+			})).Return(1, nil)
+			if tt.expectSettled {
+				mockPaymentRepo.On("UpdatePaymentStatus", mock.Anything, mock.MatchedBy(func(payment models.Payment) bool {
+					return payment.PaymentStatus == models.Paid
+				}), mock.Anything).Return(nil)
+			}
+
+			service := NewLateFeeService(mockLateFeeRepo, mockPaymentRepo, mockBillRepo, mockUserAptRepo, mockLedgerRepo, mockUOW)
+			payment, err := service.WaiveLateFee(context.Background(), 1, 5, "first time")
+
+			assert.NoError(t, err)
+			if tt.expectSettled {
+				assert.Equal(t, models.Paid, payment.PaymentStatus)
+			} else {
+				assert.Equal(t, models.PartiallyPaid, payment.PaymentStatus)
+			}
+			mockLateFeeRepo.AssertExpectations(t)
+			mockPaymentRepo.AssertExpectations(t)
+			mockLedgerRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// the permissions of a manager, or of a plain resident
+func permissionsOf(isManager bool) models.Permission {
+	if isManager {
+		return models.ManagerRole.Permissions()
+	}
+	return models.ResidentRole.Permissions()
+}
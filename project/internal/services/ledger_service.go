@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+type LedgerService interface {
+	GetBalance(ctx context.Context, requesterID, userID, apartmentID int) (models.Money, error)
+	GetStatement(ctx context.Context, requesterID, userID, apartmentID int, from, to string) (*models.Statement, error)
+}
+
+type ledgerServiceImpl struct {
+	ledgerRepo        repositories.LedgerRepository
+	userApartmentRepo repositories.UserApartmentRepository
+}
+
+func NewLedgerService(
+	ledgerRepo repositories.LedgerRepository,
+	userApartmentRepo repositories.UserApartmentRepository,
+) LedgerService {
+	return &ledgerServiceImpl{
+		ledgerRepo:        ledgerRepo,
+		userApartmentRepo: userApartmentRepo,
+	}
+}
+
+func (s *ledgerServiceImpl) GetBalance(ctx context.Context, requesterID, userID, apartmentID int) (models.Money, error) {
+	if err := s.checkAccess(ctx, requesterID, userID, apartmentID); err != nil {
+		return models.Money{}, err
+	}
+
+	balance, err := s.ledgerRepo.GetBalance(ctx, userID, apartmentID)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"user_id":      userID,
+			"apartment_id": apartmentID,
+		}).Error("Failed to get balance")
+		return models.Money{}, fmt.Errorf("failed to get balance: %w", err)
+	}
+	return balance, nil
+}
+
+// the entries between from and to (both inclusive, YYYY-MM-DD) with the
+// running balance after each of them. from defaults to the first day of the
+// current month and to to today
+func (s *ledgerServiceImpl) GetStatement(ctx context.Context, requesterID, userID, apartmentID int, from, to string) (*models.Statement, error) {
+	if err := s.checkAccess(ctx, requesterID, userID, apartmentID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	fromDate := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var err error
+	if from != "" {
+		if fromDate, err = time.Parse("2006-01-02", from); err != nil {
+			return nil, fmt.Errorf("invalid from date format (use YYYY-MM-DD)")
+		}
+	}
+	if to != "" {
+		if toDate, err = time.Parse("2006-01-02", to); err != nil {
+			return nil, fmt.Errorf("invalid to date format (use YYYY-MM-DD)")
+		}
+	}
+	if toDate.Before(fromDate) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+	end := toDate.AddDate(0, 0, 1)
+
+	opening, err := s.ledgerRepo.GetBalanceBefore(ctx, userID, apartmentID, fromDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opening balance: %w", err)
+	}
+
+	entries, err := s.ledgerRepo.GetLedgerEntries(ctx, userID, apartmentID, fromDate, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+
+	balance := opening.Amount
+	for i := range entries {
+		balance += entries[i].SignedAmount()
+		entries[i].Balance = models.NewMoney(balance, entries[i].Amount.Currency)
+	}
+
+	return &models.Statement{
+		UserID:         userID,
+		ApartmentID:    apartmentID,
+		From:           fromDate.Format("2006-01-02"),
+		To:             toDate.Format("2006-01-02"),
+		OpeningBalance: opening,
+		Entries:        entries,
+		ClosingBalance: models.NewMoney(balance, opening.Currency),
+	}, nil
+}
+
+// residents see their own account, members who can view the apartment's
+// finances the account of everyone in it
+func (s *ledgerServiceImpl) checkAccess(ctx context.Context, requesterID, userID, apartmentID int) error {
+	if requesterID == userID {
+		if ok, err := s.userApartmentRepo.IsUserInApartment(ctx, userID, apartmentID); err != nil || !ok {
+			return fmt.Errorf("user is not a member of this apartment")
+		}
+		return nil
+	}
+	if err := authorize(ctx, s.userApartmentRepo, requesterID, apartmentID, models.PermViewFinances); err != nil {
+		return fmt.Errorf("not allowed to view other residents' accounts: %w", err)
+	}
+	return nil
+}
+
+// adds an entry to a resident's ledger, skipping empty amounts. called
+// inside the unit of work of the change the entry records, so the two are
+// kept or rolled back together
+func addLedgerEntry(ctx context.Context, ledgerRepo repositories.LedgerRepository, entry models.LedgerEntry) error {
+	if entry.Amount.IsZero() {
+		return nil
+	}
+	if _, err := ledgerRepo.AddLedgerEntry(ctx, entry); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"user_id":      entry.UserID,
+			"apartment_id": entry.ApartmentID,
+			"entry_type":   entry.EntryType,
+			"amount":       entry.Amount,
+		}).Error("Failed to record ledger entry")
+		return fmt.Errorf("failed to record ledger entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetStatement(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	mockLedgerRepo := new(repositories.MockLedgerRepository)
+	mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+
+	mockUserAptRepo.On("IsUserInApartment", mock.Anything, 1, 2).Return(true, nil)
+	mockLedgerRepo.On("GetBalanceBefore", mock.Anything, 1, 2, from).Return(models.NewMoney(-2000, "IRR"), nil)
+	mockLedgerRepo.On("GetLedgerEntries", mock.Anything, 1, 2, from, end).Return([]models.LedgerEntry{
+		{EntryType: models.ChargeEntry, Amount: models.NewMoney(10000, "IRR")},
+		{EntryType: models.FeeEntry, Amount: models.NewMoney(500, "IRR")},
+		{EntryType: models.PaymentEntry, Amount: models.NewMoney(9000, "IRR")},
+		{EntryType: models.CreditEntry, Amount: models.NewMoney(500, "IRR")},
+	}, nil)
+
+	service := NewLedgerService(mockLedgerRepo, mockUserAptRepo)
+	statement, err := service.GetStatement(context.Background(), 1, 1, 2, "2025-03-01", "2025-03-31")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(-2000, "IRR"), statement.OpeningBalance)
+	var running []int64
+	for _, entry := range statement.Entries {
+		running = append(running, entry.Balance.Amount)
+	}
+	assert.Equal(t, []int64{8000, 8500, -500, -1000}, running)
+	assert.Equal(t, models.NewMoney(-1000, "IRR"), statement.ClosingBalance)
+}
+
+func TestGetStatement_Access(t *testing.T) {
+	tests := []struct {
+		name          string
+		requesterID   int
+		setupMocks    func(*repositories.MockUserApartmentRepository)
+		expectedError string
+	}{
+		{
+			name:        "resident outside the apartment",
+			requesterID: 1,
+			setupMocks: func(userAptRepo *repositories.MockUserApartmentRepository) {
+				userAptRepo.On("IsUserInApartment", mock.Anything, 1, 2).Return(false, nil)
+			},
+			expectedError: "not a member",
+		},
+		{
+			name:        "another resident",
+			requesterID: 4,
+			setupMocks: func(userAptRepo *repositories.MockUserApartmentRepository) {
+				userAptRepo.On("GetMemberPermissions", mock.Anything, 4, 2).Return(models.ResidentRole.Permissions(), nil)
+			},
+			expectedError: "not allowed to",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			tt.setupMocks(mockUserAptRepo)
+
+			service := NewLedgerService(nil, mockUserAptRepo)
+			_, err := service.GetStatement(context.Background(), tt.requesterID, 1, 2, "", "")
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
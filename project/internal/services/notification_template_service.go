@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrInvalidTemplate  = errors.New("invalid notification template")
+	ErrTemplateNotFound = errors.New("the apartment has no template of its own for this notification")
+)
+
+var templateLocales = []models.Locale{models.Persian, models.English}
+
+// ApartmentTemplate is the wording of a notification an apartment's members
+// get in one locale
+type ApartmentTemplate struct {
+	Name    string        `json:"name"`
+	Locale  models.Locale `json:"locale"`
+	Subject string        `json:"subject"`
+	Body    string        `json:"body"`
+	Custom  bool          `json:"custom"` // the apartment's own, false for the built-in one
+}
+
+type NotificationTemplateService interface {
+	GetTemplates(ctx context.Context, managerID, apartmentID int) ([]ApartmentTemplate, error)
+	SetTemplate(ctx context.Context, managerID, apartmentID int, name string, locale models.Locale, template notification.Template) (*ApartmentTemplate, error)
+	ResetTemplate(ctx context.Context, managerID, apartmentID int, name string, locale models.Locale) error
+}
+
+type notificationTemplateServiceImpl struct {
+	templateRepo      repositories.NotificationTemplateRepository
+	userApartmentRepo repositories.UserApartmentRepository
+}
+
+func NewNotificationTemplateService(
+	templateRepo repositories.NotificationTemplateRepository,
+	userApartmentRepo repositories.UserApartmentRepository,
+) NotificationTemplateService {
+	return &notificationTemplateServiceImpl{
+		templateRepo:      templateRepo,
+		userApartmentRepo: userApartmentRepo,
+	}
+}
+
+// every notification in every locale, in the apartment's words where it has
+// its own and the built-in ones otherwise
+func (s *notificationTemplateServiceImpl) GetTemplates(ctx context.Context, managerID, apartmentID int) ([]ApartmentTemplate, error) {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageApartment); err != nil {
+		return nil, fmt.Errorf("not allowed to see notification templates: %w", err)
+	}
+
+	custom, err := s.templateRepo.GetTemplates(ctx, apartmentID)
+	if err != nil {
+		logrus.WithError(err).WithField("apartment_id", apartmentID).Error("Failed to get notification templates")
+		return nil, fmt.Errorf("failed to get notification templates: %w", err)
+	}
+	own := make(map[string]models.NotificationTemplate, len(custom))
+	for _, template := range custom {
+		own[template.Name+":"+string(template.Locale)] = template
+	}
+
+	var templates []ApartmentTemplate
+	for _, name := range notification.TemplateNames() {
+		for _, locale := range templateLocales {
+			builtin, ok := notification.DefaultTemplate(name, locale)
+			if !ok {
+				continue
+			}
+			template := ApartmentTemplate{Name: name, Locale: locale, Subject: builtin.Subject, Body: builtin.Body}
+			if custom, ok := own[name+":"+string(locale)]; ok {
+				template.Subject, template.Body, template.Custom = custom.Subject, custom.Body, true
+			}
+			templates = append(templates, template)
+		}
+	}
+	return templates, nil
+}
+
+// words the notification in the locale the apartment's way. the template
+// must render, a broken one would leave the members with the built-in one
+func (s *notificationTemplateServiceImpl) SetTemplate(ctx context.Context, managerID, apartmentID int, name string, locale models.Locale, template notification.Template) (*ApartmentTemplate, error) {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageApartment); err != nil {
+		return nil, fmt.Errorf("not allowed to change notification templates: %w", err)
+	}
+	if err := notification.ValidateTemplate(name, locale, template); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	err := s.templateRepo.SetTemplate(ctx, models.NotificationTemplate{
+		ApartmentID: apartmentID,
+		Name:        name,
+		Locale:      locale,
+		Subject:     template.Subject,
+		Body:        template.Body,
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("apartment_id", apartmentID).Error("Failed to save notification template")
+		return nil, fmt.Errorf("failed to save notification template: %w", err)
+	}
+	return &ApartmentTemplate{Name: name, Locale: locale, Subject: template.Subject, Body: template.Body, Custom: true}, nil
+}
+
+// goes back to the built-in template of the notification in the locale
+func (s *notificationTemplateServiceImpl) ResetTemplate(ctx context.Context, managerID, apartmentID int, name string, locale models.Locale) error {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageApartment); err != nil {
+		return fmt.Errorf("not allowed to change notification templates: %w", err)
+	}
+
+	err := s.templateRepo.DeleteTemplate(ctx, apartmentID, name, locale)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTemplateNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete notification template: %w", err)
+	}
+	return nil
+}
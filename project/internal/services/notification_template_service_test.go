@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNotificationTemplates(t *testing.T) {
+	mockTemplateRepo := new(repositories.MockNotificationTemplateRepository)
+	mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+	mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(permissionsOf(true), nil)
+	mockTemplateRepo.On("GetTemplates", mock.Anything, 2).Return([]models.NotificationTemplate{{
+		ApartmentID: 2,
+		Name:        models.NewBillTemplate,
+		Locale:      models.Persian,
+		Body:        "قبض {{billType .bill_type}}",
+	}}, nil)
+
+	service := NewNotificationTemplateService(mockTemplateRepo, mockUserAptRepo)
+	templates, err := service.GetTemplates(context.Background(), 1, 2)
+
+	require.NoError(t, err)
+	assert.Len(t, templates, 2*len(notification.TemplateNames()))
+	for _, template := range templates {
+		if template.Name != models.NewBillTemplate {
+			assert.False(t, template.Custom)
+			continue
+		}
+		assert.Equal(t, template.Locale == models.Persian, template.Custom)
+		if template.Custom {
+			assert.Equal(t, "قبض {{billType .bill_type}}", template.Body)
+		}
+	}
+}
+
+func TestSetNotificationTemplate(t *testing.T) {
+	tests := []struct {
+		name          string
+		isManager     bool
+		template      string
+		locale        models.Locale
+		body          string
+		saveErr       error
+		expectedError error
+	}{
+		{name: "valid template", isManager: true, template: models.NewBillTemplate, locale: models.English, body: "New {{.bill_type}} bill, pay {{.amount}}"},
+		{name: "not a manager", template: models.NewBillTemplate, locale: models.English, body: "hi", expectedError: ErrForbidden},
+		{name: "unknown notification", isManager: true, template: "party", locale: models.English, body: "hi", expectedError: ErrInvalidTemplate},
+		{name: "unknown locale", isManager: true, template: models.NewBillTemplate, locale: "de", body: "hi", expectedError: ErrInvalidTemplate},
+		{name: "does not parse", isManager: true, template: models.NewBillTemplate, locale: models.English, body: "{{.amount", expectedError: ErrInvalidTemplate},
+		{name: "unknown function", isManager: true, template: models.NewBillTemplate, locale: models.English, body: "{{shout .amount}}", expectedError: ErrInvalidTemplate},
+		{name: "empty body", isManager: true, template: models.NewBillTemplate, locale: models.English, body: " ", expectedError: ErrInvalidTemplate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTemplateRepo := new(repositories.MockNotificationTemplateRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(permissionsOf(tt.isManager), nil)
+			mockTemplateRepo.On("SetTemplate", mock.Anything, models.NotificationTemplate{
+				ApartmentID: 2,
+				Name:        tt.template,
+				Locale:      tt.locale,
+				Subject:     "Bill",
+				Body:        tt.body,
+			}).Return(tt.saveErr)
+
+			service := NewNotificationTemplateService(mockTemplateRepo, mockUserAptRepo)
+			saved, err := service.SetTemplate(context.Background(), 1, 2, tt.template, tt.locale,
+				notification.Template{Subject: "Bill", Body: tt.body})
+
+			if tt.expectedError != nil {
+				assert.True(t, errors.Is(err, tt.expectedError), "got %v", err)
+				mockTemplateRepo.AssertNotCalled(t, "SetTemplate", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, saved.Custom)
+			mockTemplateRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestResetNotificationTemplate(t *testing.T) {
+	mockTemplateRepo := new(repositories.MockNotificationTemplateRepository)
+	mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+	mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(permissionsOf(true), nil)
+	mockTemplateRepo.On("DeleteTemplate", mock.Anything, 2, models.NewBillTemplate, models.Persian).Return(nil).Once()
+	mockTemplateRepo.On("DeleteTemplate", mock.Anything, 2, models.NewBillTemplate, models.Persian).Return(sql.ErrNoRows)
+
+	service := NewNotificationTemplateService(mockTemplateRepo, mockUserAptRepo)
+	assert.NoError(t, service.ResetTemplate(context.Background(), 1, 2, models.NewBillTemplate, models.Persian))
+	assert.ErrorIs(t, service.ResetTemplate(context.Background(), 1, 2, models.NewBillTemplate, models.Persian), ErrTemplateNotFound)
+}
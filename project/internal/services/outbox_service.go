@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+type OutboxService interface {
+	GetFailedDeliveries(ctx context.Context, managerID, apartmentID int) ([]models.OutboxMessage, error)
+}
+
+type outboxServiceImpl struct {
+	outboxRepo        repositories.OutboxRepository
+	userApartmentRepo repositories.UserApartmentRepository
+}
+
+func NewOutboxService(
+	outboxRepo repositories.OutboxRepository,
+	userApartmentRepo repositories.UserApartmentRepository,
+) OutboxService {
+	return &outboxServiceImpl{
+		outboxRepo:        outboxRepo,
+		userApartmentRepo: userApartmentRepo,
+	}
+}
+
+// the notifications about the apartment or its members that were given up
+// on after running out of attempts, newest first
+func (s *outboxServiceImpl) GetFailedDeliveries(ctx context.Context, managerID, apartmentID int) ([]models.OutboxMessage, error) {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageApartment); err != nil {
+		return nil, fmt.Errorf("not allowed to see failed deliveries: %w", err)
+	}
+
+	messages, err := s.outboxRepo.GetDeadMessages(ctx, apartmentID)
+	if err != nil {
+		logrus.WithError(err).WithField("apartment_id", apartmentID).Error("Failed to get dead outbox messages")
+		return nil, fmt.Errorf("failed to get failed deliveries: %w", err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetFailedDeliveries(t *testing.T) {
+	dead := []models.OutboxMessage{{
+		BaseModel: models.BaseModel{ID: 3},
+		Kind:      models.OutboxBillKind,
+		UserID:    5,
+		Status:    models.OutboxDead,
+		Attempts:  8,
+		LastError: "no channel could deliver the notification",
+	}}
+
+	tests := []struct {
+		name          string
+		isManager     bool
+		expectedError string
+	}{
+		{name: "manager sees dead messages", isManager: true},
+		{name: "not a manager", expectedError: "not allowed to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockOutboxRepo := new(repositories.MockOutboxRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(permissionsOf(tt.isManager), nil)
+			mockOutboxRepo.On("GetDeadMessages", mock.Anything, 2).Return(dead, nil)
+
+			service := NewOutboxService(mockOutboxRepo, mockUserAptRepo)
+			messages, err := service.GetFailedDeliveries(context.Background(), 1, 2)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				mockOutboxRepo.AssertNotCalled(t, "GetDeadMessages", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, dead, messages)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+)
+
+// ErrForbidden is returned when a user lacks the permission an action needs
+// in an apartment, including when they aren't a member of it at all
+var ErrForbidden = errors.New("permission denied")
+
+// the policy every apartment scoped action goes through: the user needs all
+// of perm in the apartment, from their role there or from permissions
+// granted to them
+func authorize(ctx context.Context, repo repositories.UserApartmentRepository, userID, apartmentID int, perm models.Permission) error {
+	permissions, err := repo.GetMemberPermissions(ctx, userID, apartmentID)
+	if err != nil {
+		return fmt.Errorf("failed to check permissions: %w", err)
+	}
+	if !permissions.Has(perm) {
+		return fmt.Errorf("%w: %s permission required", ErrForbidden, perm&^permissions)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+type ReminderService interface {
+	SendDueReminders(ctx context.Context, now time.Time) (int, error)
+}
+
+type reminderServiceImpl struct {
+	reminderRepo        repositories.ReminderRepository
+	uow                 repositories.UnitOfWork
+	notificationService notification.Notification
+	offsets             []int
+}
+
+func NewReminderService(
+	cfg config.Reminders,
+	reminderRepo repositories.ReminderRepository,
+	uow repositories.UnitOfWork,
+	notificationService notification.Notification,
+) ReminderService {
+	offsets := slices.Clone(cfg.Offsets)
+	if len(offsets) == 0 {
+		offsets = slices.Clone(models.DefaultReminderOffsets)
+	}
+	slices.Sort(offsets)
+
+	return &reminderServiceImpl{
+		reminderRepo:        reminderRepo,
+		uow:                 uow,
+		notificationService: notificationService,
+		offsets:             slices.Compact(offsets),
+	}
+}
+
+// reminds residents of the unpaid shares whose reminder day came. a share
+// only gets the latest reminder that is due, so a run after some downtime
+// doesn't send the ones it missed, and never gets the same one twice.
+// returns the number of reminders sent
+func (s *reminderServiceImpl) SendDueReminders(ctx context.Context, now time.Time) (int, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	first, last := s.offsets[0], s.offsets[len(s.offsets)-1]
+
+	//no reminder is due for a bill due after today minus the earliest offset
+	candidates, err := s.reminderRepo.GetReminderCandidates(ctx, today.AddDate(0, 0, -first), last)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get unpaid shares: %w", err)
+	}
+
+	sent := 0
+	for _, candidate := range candidates {
+		logger := logrus.WithFields(logrus.Fields{
+			"payment_id": candidate.ID,
+			"user_id":    candidate.UserID,
+		})
+
+		due, err := time.Parse("2006-01-02", candidate.DueDate)
+		if err != nil {
+			logger.WithError(err).Warn("Invalid bill due date")
+			continue
+		}
+		deadline, err := time.Parse("2006-01-02", candidate.Deadline)
+		if err != nil {
+			logger.WithError(err).Warn("Invalid bill deadline")
+			continue
+		}
+
+		offset, ok := s.dueOffset(candidate.LastOffset, due, deadline, today)
+		if !ok {
+			continue
+		}
+
+		recorded := false
+		err = s.uow.Do(ctx, func(ctx context.Context) error {
+			if recorded, err = s.reminderRepo.RecordReminder(ctx, candidate.ID, offset); err != nil || !recorded {
+				return err
+			}
+			return s.notificationService.SendNotification(ctx, candidate.UserID, reminderNotice(candidate, due, deadline, today))
+		})
+		if err != nil {
+			logger.WithError(err).Error("Failed to send bill reminder")
+			continue
+		}
+		if recorded {
+			sent++
+		}
+	}
+
+	if sent > 0 {
+		logrus.WithField("reminders_count", sent).Info("Sent bill reminders")
+	}
+	return sent, nil
+}
+
+// the latest offset whose reminder day is not after today, if it is past the
+// last reminder sent. offsets up to 0 count from the due date, later ones
+// from the deadline
+func (s *reminderServiceImpl) dueOffset(lastOffset *int, due, deadline, today time.Time) (int, bool) {
+	for i := len(s.offsets) - 1; i >= 0; i-- {
+		offset := s.offsets[i]
+		if lastOffset != nil && offset <= *lastOffset {
+			return 0, false
+		}
+
+		from := due
+		if offset > 0 {
+			from = deadline
+		}
+		if !from.AddDate(0, 0, offset).After(today) {
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
+// the reminder of the candidate's share, whose bill is due in days days
+// (negative once it is past), in the words of the bill reminder template
+func reminderNotice(candidate models.ReminderCandidate, due, deadline, today time.Time) models.Notice {
+	days := int(due.Sub(today).Hours() / 24)
+	var when string
+	switch {
+	case days == 1:
+		when = "tomorrow"
+	case days > 1:
+		when = "upcoming"
+	case days == 0:
+		when = "today"
+	case today.After(deadline):
+		when = "overdue"
+	default:
+		when = "grace"
+	}
+
+	return models.Notice{
+		Template:    models.BillReminderTemplate,
+		ApartmentID: candidate.ApartmentID,
+		Data: map[string]string{
+			"bill_type":   string(candidate.BillType),
+			"when":        when,
+			"days":        strconv.Itoa(days),
+			"due_date":    candidate.DueDate,
+			"deadline":    candidate.Deadline,
+			"outstanding": candidate.Outstanding().String(),
+		},
+	}
+}
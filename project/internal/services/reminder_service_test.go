@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/notification"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSendDueReminders(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	sentBefore := func(offset int) *int { return &offset }
+
+	tests := []struct {
+		name            string
+		dueDate         string
+		deadline        string
+		lastOffset      *int
+		alreadyRecorded bool
+		expectedOffset  *int
+		expectedWhen    string
+	}{
+		{
+			name:           "a week before the due date",
+			dueDate:        "2025-03-08",
+			deadline:       "2025-03-10",
+			expectedOffset: sentBefore(-7),
+			expectedWhen:   "upcoming",
+		},
+		{
+			name:       "week reminder already sent",
+			dueDate:    "2025-03-05",
+			deadline:   "2025-03-05",
+			lastOffset: sentBefore(-7),
+		},
+		{
+			name:           "on the due date",
+			dueDate:        "2025-03-01",
+			deadline:       "2025-03-05",
+			lastOffset:     sentBefore(-7),
+			expectedOffset: sentBefore(0),
+			expectedWhen:   "today",
+		},
+		{
+			name:           "between the due date and the deadline",
+			dueDate:        "2025-02-27",
+			deadline:       "2025-03-03",
+			expectedOffset: sentBefore(0),
+			expectedWhen:   "grace",
+		},
+		{
+			name:           "only the latest missed reminder",
+			dueDate:        "2025-02-20",
+			deadline:       "2025-02-25",
+			expectedOffset: sentBefore(3),
+			expectedWhen:   "overdue",
+		},
+		{
+			name:     "not due for a reminder yet",
+			dueDate:  "2025-03-20",
+			deadline: "2025-03-20",
+		},
+		{
+			name:            "sent by another instance",
+			dueDate:         "2025-03-01",
+			deadline:        "2025-03-01",
+			alreadyRecorded: true,
+			expectedOffset:  sentBefore(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockReminderRepo := new(repositories.MockReminderRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockNotif := new(notification.MockNotification)
+
+			candidate := models.ReminderCandidate{
+				Payment: models.Payment{
+					BaseModel:     models.BaseModel{ID: 4},
+					UserID:        5,
+					Amount:        models.NewMoney(50000, "IRR"),
+					PaidAmount:    models.NewMoney(20000, "IRR"),
+					PaymentStatus: models.PartiallyPaid,
+				},
+				BillType:   models.WaterBill,
+				DueDate:    tt.dueDate,
+				Deadline:   tt.deadline,
+				LastOffset: tt.lastOffset,
+			}
+			mockUOW.On("Do", mock.Anything).Return(nil)
+			mockReminderRepo.On("GetReminderCandidates", mock.Anything, time.Date(2025, 3, 8, 0, 0, 0, 0, time.UTC), 3).
+				Return([]models.ReminderCandidate{candidate}, nil)
+			if tt.expectedOffset != nil {
+				mockReminderRepo.On("RecordReminder", mock.Anything, 4, *tt.expectedOffset).Return(!tt.alreadyRecorded, nil)
+			}
+			if tt.expectedWhen != "" {
+				mockNotif.On("SendNotification", mock.Anything, 5, mock.MatchedBy(func(notice models.Notice) bool {
+					return notice.Template == models.BillReminderTemplate &&
+						notice.Data["when"] == tt.expectedWhen &&
+						notice.Data["bill_type"] == "water" &&
+						notice.Data["due_date"] == tt.dueDate &&
+						notice.Data["deadline"] == tt.deadline &&
+						notice.Data["outstanding"] == "300.00 IRR"
+				})).Return(nil)
+			}
+
+			service := NewReminderService(config.Reminders{Offsets: []int{3, -7, 0}}, mockReminderRepo, mockUOW, mockNotif)
+			sent, err := service.SendDueReminders(context.Background(), now)
+
+			assert.NoError(t, err)
+			if tt.expectedWhen != "" {
+				assert.Equal(t, 1, sent)
+			} else {
+				assert.Zero(t, sent)
+				mockNotif.AssertNotCalled(t, "SendNotification", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.expectedOffset == nil {
+				mockReminderRepo.AssertNotCalled(t, "RecordReminder", mock.Anything, mock.Anything, mock.Anything)
+			}
+			mockReminderRepo.AssertExpectations(t)
+			mockNotif.AssertExpectations(t)
+		})
+	}
+}
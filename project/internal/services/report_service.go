@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+type ReportService interface {
+	GetApartmentReport(ctx context.Context, managerID, apartmentID int, groupBy models.ReportGrouping, from, to string) (*models.ApartmentReport, error)
+}
+
+type reportServiceImpl struct {
+	reportRepo        repositories.ReportRepository
+	userApartmentRepo repositories.UserApartmentRepository
+}
+
+func NewReportService(
+	reportRepo repositories.ReportRepository,
+	userApartmentRepo repositories.UserApartmentRepository,
+) ReportService {
+	return &reportServiceImpl{
+		reportRepo:        reportRepo,
+		userApartmentRepo: userApartmentRepo,
+	}
+}
+
+// billed, collected and outstanding totals of the bills due between from and
+// to (YYYY-MM, both inclusive). the range defaults to the last twelve months
+// up to the current one
+func (s *reportServiceImpl) GetApartmentReport(ctx context.Context, managerID, apartmentID int, groupBy models.ReportGrouping, from, to string) (*models.ApartmentReport, error) {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermViewFinances); err != nil {
+		return nil, fmt.Errorf("not allowed to view reports: %w", err)
+	}
+
+	if groupBy == "" {
+		groupBy = models.ReportByMonth
+	}
+	if !groupBy.IsValid() {
+		return nil, fmt.Errorf("invalid grouping %q (use month, bill_type or resident)", groupBy)
+	}
+
+	now := time.Now().UTC()
+	toMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	fromMonth := toMonth.AddDate(0, -11, 0)
+	var err error
+	if from != "" {
+		if fromMonth, err = time.Parse("2006-01", from); err != nil {
+			return nil, fmt.Errorf("invalid from month format (use YYYY-MM)")
+		}
+	}
+	if to != "" {
+		if toMonth, err = time.Parse("2006-01", to); err != nil {
+			return nil, fmt.Errorf("invalid to month format (use YYYY-MM)")
+		}
+	}
+	if toMonth.Before(fromMonth) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+
+	rows, err := s.reportRepo.GetApartmentReport(ctx, apartmentID, groupBy, fromMonth, toMonth.AddDate(0, 1, 0))
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"apartment_id": apartmentID,
+			"group_by":     groupBy,
+		}).Error("Failed to build apartment report")
+		return nil, fmt.Errorf("failed to build report: %w", err)
+	}
+
+	total := models.ReportRow{Key: "total", Label: "Total"}
+	for _, row := range rows {
+		if total.Billed, err = total.Billed.Add(row.Billed); err != nil {
+			return nil, err
+		}
+		if total.Collected, err = total.Collected.Add(row.Collected); err != nil {
+			return nil, err
+		}
+		if total.Outstanding, err = total.Outstanding.Add(row.Outstanding); err != nil {
+			return nil, err
+		}
+	}
+	if rows == nil {
+		rows = []models.ReportRow{}
+	}
+
+	return &models.ApartmentReport{
+		ApartmentID: apartmentID,
+		GroupBy:     groupBy,
+		From:        fromMonth.Format("2006-01"),
+		To:          toMonth.Format("2006-01"),
+		Rows:        rows,
+		Total:       total,
+	}, nil
+}
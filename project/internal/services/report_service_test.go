@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetApartmentReport(t *testing.T) {
+	row := func(key string, billed, collected int64) models.ReportRow {
+		return models.ReportRow{
+			Key:         key,
+			Label:       key,
+			Billed:      models.NewMoney(billed, "IRR"),
+			Collected:   models.NewMoney(collected, "IRR"),
+			Outstanding: models.NewMoney(billed-collected, "IRR"),
+		}
+	}
+
+	tests := []struct {
+		name          string
+		groupBy       models.ReportGrouping
+		from, to      string
+		isManager     bool
+		expectedFrom  time.Time
+		expectedTo    time.Time
+		expectedError string
+	}{
+		{
+			name:         "explicit range",
+			groupBy:      models.ReportByBillType,
+			from:         "2025-01",
+			to:           "2025-03",
+			isManager:    true,
+			expectedFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "not a manager",
+			groupBy:       models.ReportByMonth,
+			expectedError: "not allowed to",
+		},
+		{
+			name:          "unknown grouping",
+			groupBy:       "floor",
+			isManager:     true,
+			expectedError: "invalid grouping",
+		},
+		{
+			name:          "bad month",
+			from:          "2025-01-01",
+			isManager:     true,
+			expectedError: "invalid from month",
+		},
+		{
+			name:          "from after to",
+			from:          "2025-05",
+			to:            "2025-02",
+			isManager:     true,
+			expectedError: "must not be after",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockReportRepo := new(repositories.MockReportRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(permissionsOf(tt.isManager), nil)
+			if tt.expectedError == "" {
+				mockReportRepo.On("GetApartmentReport", mock.Anything, 2, tt.groupBy, tt.expectedFrom, tt.expectedTo).
+					Return([]models.ReportRow{row("water", 150000, 100000), row("gas", 50000, 50000)}, nil)
+			}
+
+			service := NewReportService(mockReportRepo, mockUserAptRepo)
+			report, err := service.GetApartmentReport(context.Background(), 1, 2, tt.groupBy, tt.from, tt.to)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				mockReportRepo.AssertNotCalled(t, "GetApartmentReport")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "2025-01", report.From)
+			assert.Equal(t, "2025-03", report.To)
+			assert.Len(t, report.Rows, 2)
+			assert.Equal(t, models.NewMoney(200000, "IRR"), report.Total.Billed)
+			assert.Equal(t, models.NewMoney(150000, "IRR"), report.Total.Collected)
+			assert.Equal(t, models.NewMoney(50000, "IRR"), report.Total.Outstanding)
+			mockReportRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetApartmentReportDefaultsToLastTwelveMonths(t *testing.T) {
+	mockReportRepo := new(repositories.MockReportRepository)
+	mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+
+	mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(models.ManagerRole.Permissions(), nil)
+	mockReportRepo.On("GetApartmentReport", mock.Anything, 2, models.ReportByMonth, mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	service := NewReportService(mockReportRepo, mockUserAptRepo)
+	report, err := service.GetApartmentReport(context.Background(), 1, 2, "", "", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReportByMonth, report.GroupBy)
+	assert.Equal(t, time.Now().UTC().Format("2006-01"), report.To)
+	assert.NotNil(t, report.Rows)
+
+	from := mockReportRepo.Calls[0].Arguments.Get(3).(time.Time)
+	to := mockReportRepo.Calls[0].Arguments.Get(4).(time.Time)
+	assert.Equal(t, to.AddDate(-1, 0, 0), from)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+type UnitService interface {
+	CreateUnit(ctx context.Context, managerID, apartmentID int, req dto.UnitRequest) (*models.Unit, error)
+	GetUnits(ctx context.Context, userID, apartmentID int) ([]models.Unit, error)
+	UpdateUnit(ctx context.Context, managerID, unitID int, req dto.UnitRequest) (*models.Unit, error)
+	DeleteUnit(ctx context.Context, managerID, unitID int) error
+	AssignMember(ctx context.Context, managerID, apartmentID, memberID int, req dto.AssignUnitRequest) error
+}
+
+type unitServiceImpl struct {
+	unitRepo          repositories.UnitRepository
+	userApartmentRepo repositories.UserApartmentRepository
+}
+
+func NewUnitService(
+	unitRepo repositories.UnitRepository,
+	userApartmentRepo repositories.UserApartmentRepository,
+) UnitService {
+	return &unitServiceImpl{
+		unitRepo:          unitRepo,
+		userApartmentRepo: userApartmentRepo,
+	}
+}
+
+// adds a unit to the apartment, at most as many as its UnitsCount
+func (s *unitServiceImpl) CreateUnit(ctx context.Context, managerID, apartmentID int, req dto.UnitRequest) (*models.Unit, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"manager_id":   managerID,
+		"apartment_id": apartmentID,
+		"number":       req.Number,
+	})
+
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageApartment); err != nil {
+		return nil, fmt.Errorf("not allowed to manage units: %w", err)
+	}
+	if err := validateUnit(req); err != nil {
+		return nil, err
+	}
+
+	unit := unitFromRequest(req)
+	unit.ApartmentID = apartmentID
+	id, err := s.unitRepo.CreateUnit(ctx, unit)
+	if errors.Is(err, repositories.ErrApartmentFull) {
+		return nil, fmt.Errorf("%w, raise its units count first", err)
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to create unit")
+		return nil, fmt.Errorf("failed to create unit: %w", err)
+	}
+	unit.ID = id
+
+	logger.Info("Unit created")
+	return &unit, nil
+}
+
+func (s *unitServiceImpl) GetUnits(ctx context.Context, userID, apartmentID int) ([]models.Unit, error) {
+	if err := authorize(ctx, s.userApartmentRepo, userID, apartmentID, models.PermViewApartment); err != nil {
+		return nil, fmt.Errorf("not allowed to view units: %w", err)
+	}
+
+	units, err := s.unitRepo.GetUnitsByApartment(ctx, apartmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get units: %w", err)
+	}
+	return units, nil
+}
+
+func (s *unitServiceImpl) UpdateUnit(ctx context.Context, managerID, unitID int, req dto.UnitRequest) (*models.Unit, error) {
+	existing, err := s.getManagedUnit(ctx, managerID, unitID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateUnit(req); err != nil {
+		return nil, err
+	}
+
+	unit := unitFromRequest(req)
+	unit.BaseModel = existing.BaseModel
+	unit.ApartmentID = existing.ApartmentID
+	if err := s.unitRepo.UpdateUnit(ctx, unit); err != nil {
+		logrus.WithError(err).Errorf("Failed to update unit %d", unitID)
+		return nil, fmt.Errorf("failed to update unit: %w", err)
+	}
+	return &unit, nil
+}
+
+// deletes the unit, the members attached to it stay in the apartment
+func (s *unitServiceImpl) DeleteUnit(ctx context.Context, managerID, unitID int) error {
+	if _, err := s.getManagedUnit(ctx, managerID, unitID); err != nil {
+		return err
+	}
+
+	if err := s.unitRepo.DeleteUnit(ctx, unitID); err != nil {
+		logrus.WithError(err).Errorf("Failed to delete unit %d", unitID)
+		return fmt.Errorf("failed to delete unit: %w", err)
+	}
+	return nil
+}
+
+// attaches a member to a unit of the apartment as its owner or resident
+func (s *unitServiceImpl) AssignMember(ctx context.Context, managerID, apartmentID, memberID int, req dto.AssignUnitRequest) error {
+	if err := authorize(ctx, s.userApartmentRepo, managerID, apartmentID, models.PermManageApartment); err != nil {
+		return fmt.Errorf("not allowed to manage units: %w", err)
+	}
+
+	if req.Relation == "" {
+		req.Relation = models.UnitResident
+	}
+	if !req.Relation.IsValid() {
+		return fmt.Errorf("invalid unit relation %q", req.Relation)
+	}
+	if req.UnitID != nil {
+		unit, err := s.unitRepo.GetUnitByID(ctx, *req.UnitID)
+		if err != nil || unit.ApartmentID != apartmentID {
+			return fmt.Errorf("unit %d is not in this apartment", *req.UnitID)
+		}
+	}
+
+	if err := s.userApartmentRepo.SetMemberUnit(ctx, memberID, apartmentID, req.UnitID, req.Relation); err != nil {
+		return fmt.Errorf("failed to assign unit: %w", err)
+	}
+	return nil
+}
+
+func (s *unitServiceImpl) getManagedUnit(ctx context.Context, managerID, unitID int) (*models.Unit, error) {
+	unit, err := s.unitRepo.GetUnitByID(ctx, unitID)
+	if err != nil {
+		return nil, fmt.Errorf("unit not found: %w", err)
+	}
+	if err := authorize(ctx, s.userApartmentRepo, managerID, unit.ApartmentID, models.PermManageApartment); err != nil {
+		return nil, fmt.Errorf("not allowed to manage units: %w", err)
+	}
+	return unit, nil
+}
+
+func validateUnit(req dto.UnitRequest) error {
+	if strings.TrimSpace(req.Number) == "" {
+		return fmt.Errorf("unit number is required")
+	}
+	if req.Area < 0 || req.OccupantsCount < 0 || req.ParkingSpots < 0 {
+		return fmt.Errorf("area, occupants and parking spots must not be negative")
+	}
+	return nil
+}
+
+func unitFromRequest(req dto.UnitRequest) models.Unit {
+	return models.Unit{
+		Number:         strings.TrimSpace(req.Number),
+		Floor:          req.Floor,
+		Area:           req.Area,
+		OccupantsCount: req.OccupantsCount,
+		ParkingSpots:   req.ParkingSpots,
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateUnit(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           dto.UnitRequest
+		isManager     bool
+		repoErr       error
+		expectedError string
+	}{
+		{
+			name:      "created",
+			req:       dto.UnitRequest{Number: " 4 ", Floor: 2, Area: 85.5, OccupantsCount: 3, ParkingSpots: 1},
+			isManager: true,
+		},
+		{
+			name:          "apartment is full",
+			req:           dto.UnitRequest{Number: "4"},
+			isManager:     true,
+			repoErr:       repositories.ErrApartmentFull,
+			expectedError: "raise its units count",
+		},
+		{
+			name:          "missing number",
+			req:           dto.UnitRequest{Area: 50},
+			isManager:     true,
+			expectedError: "unit number is required",
+		},
+		{
+			name:          "negative area",
+			req:           dto.UnitRequest{Number: "4", Area: -1},
+			isManager:     true,
+			expectedError: "must not be negative",
+		},
+		{
+			name:          "not a manager",
+			req:           dto.UnitRequest{Number: "4"},
+			expectedError: "not allowed to",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUnitRepo := new(repositories.MockUnitRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(permissionsOf(tt.isManager), nil)
+			mockUnitRepo.On("CreateUnit", mock.Anything, mock.MatchedBy(func(unit models.Unit) bool {
+				return unit.ApartmentID == 2 && unit.Number == "4"
+			})).Return(7, tt.repoErr)
+
+			service := NewUnitService(mockUnitRepo, mockUserAptRepo)
+			unit, err := service.CreateUnit(context.Background(), 1, 2, tt.req)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 7, unit.ID)
+			assert.Equal(t, 85.5, unit.Area)
+		})
+	}
+}
+
+func TestAssignMemberToUnit(t *testing.T) {
+	unitID := 7
+	otherUnitID := 8
+	tests := []struct {
+		name          string
+		req           dto.AssignUnitRequest
+		expectedError string
+	}{
+		{
+			name: "as resident by default",
+			req:  dto.AssignUnitRequest{UnitID: &unitID},
+		},
+		{
+			name: "detached from the unit",
+			req:  dto.AssignUnitRequest{},
+		},
+		{
+			name:          "unit of another apartment",
+			req:           dto.AssignUnitRequest{UnitID: &otherUnitID},
+			expectedError: "not in this apartment",
+		},
+		{
+			name:          "unknown relation",
+			req:           dto.AssignUnitRequest{UnitID: &unitID, Relation: "tenant"},
+			expectedError: "invalid unit relation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUnitRepo := new(repositories.MockUnitRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+
+			mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(models.ManagerRole.Permissions(), nil)
+			mockUnitRepo.On("GetUnitByID", mock.Anything, 7).Return(&models.Unit{BaseModel: models.BaseModel{ID: 7}, ApartmentID: 2}, nil)
+			mockUnitRepo.On("GetUnitByID", mock.Anything, 8).Return(&models.Unit{BaseModel: models.BaseModel{ID: 8}, ApartmentID: 3}, nil)
+			mockUserAptRepo.On("SetMemberUnit", mock.Anything, 5, 2, tt.req.UnitID, models.UnitResident).Return(nil)
+
+			service := NewUnitService(mockUnitRepo, mockUserAptRepo)
+			err := service.AssignMember(context.Background(), 1, 2, 5, tt.req)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				mockUserAptRepo.AssertNotCalled(t, "SetMemberUnit", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			mockUserAptRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteUnit_NotAllowed(t *testing.T) {
+	mockUnitRepo := new(repositories.MockUnitRepository)
+	mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+
+	mockUnitRepo.On("GetUnitByID", mock.Anything, 7).Return(&models.Unit{BaseModel: models.BaseModel{ID: 7}, ApartmentID: 2}, nil)
+	mockUserAptRepo.On("GetMemberPermissions", mock.Anything, 1, 2).Return(models.ResidentRole.Permissions(), nil)
+
+	service := NewUnitService(mockUnitRepo, mockUserAptRepo)
+	err := service.DeleteUnit(context.Background(), 1, 7)
+
+	assert.True(t, errors.Is(err, ErrForbidden))
+	mockUnitRepo.AssertNotCalled(t, "DeleteUnit", mock.Anything, mock.Anything)
+}
@@ -2,10 +2,16 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
@@ -22,18 +28,66 @@ type UserService interface {
 	GetPublicUser(ctx context.Context, userID int) (*dto.PublicUserResponse, error)
 	GetAllPublicUsers(ctx context.Context) ([]dto.PublicUserResponse, error)
 	DeleteUser(ctx context.Context, userID int) error
+	RefreshToken(ctx context.Context, refreshToken string) (*dto.TokenResponse, error)
+	Logout(ctx context.Context, userID int, claims *middleware.CustomClaims, refreshToken string) error
+	GetNotificationPreferences(ctx context.Context, userID int) (*dto.NotificationPreferences, error)
+	UpdateNotificationPreferences(ctx context.Context, userID int, req dto.NotificationPreferences) (*dto.NotificationPreferences, error)
+	CreateTelegramLinkCode(ctx context.Context, userID int, botAddress string) (*dto.TelegramLinkCode, error)
+	LinkTelegramChat(ctx context.Context, code string, chatID int64) (*models.User, error)
+	UnlinkTelegramChat(ctx context.Context, userID int) error
 }
 
+var (
+	ErrInvalidNotificationChannels = errors.New("invalid notification channels")
+	ErrInvalidLocale               = errors.New("invalid locale")
+	ErrInvalidLinkCode             = errors.New("invalid or expired telegram link code")
+)
+
+const (
+	telegramLinkCodeTTL    = 10 * time.Minute
+	telegramLinkCodeLength = 8
+	// no 0, O, 1 or I, so codes are easy to copy by hand. 32 letters keep
+	// every one equally likely from a random byte
+	telegramLinkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
 type userServiceImpl struct {
 	userRepo          repositories.UserRepository
 	userApartmentRepo repositories.UserApartmentRepository
+	tokenRepo         repositories.TokenRepository
+	inviteLinkRepo    repositories.InviteLinkRepo
+	uow               repositories.UnitOfWork
+	keys              *middleware.KeySet
+	accessTokenTTL    time.Duration
+	refreshTokenTTL   time.Duration
 }
 
-func NewUserService(userRepo repositories.UserRepository, userApartmentRepo repositories.UserApartmentRepository) UserService {
-	return &userServiceImpl{
+func NewUserService(
+	userRepo repositories.UserRepository,
+	userApartmentRepo repositories.UserApartmentRepository,
+	tokenRepo repositories.TokenRepository,
+	inviteLinkRepo repositories.InviteLinkRepo,
+	uow repositories.UnitOfWork,
+	keys *middleware.KeySet,
+	cfg config.Auth,
+) UserService {
+	s := &userServiceImpl{
 		userRepo:          userRepo,
 		userApartmentRepo: userApartmentRepo,
+		tokenRepo:         tokenRepo,
+		inviteLinkRepo:    inviteLinkRepo,
+		uow:               uow,
+		keys:              keys,
+		accessTokenTTL:    cfg.AccessTokenTTL,
+		refreshTokenTTL:   cfg.RefreshTokenTTL,
 	}
+	if s.accessTokenTTL <= 0 {
+		s.accessTokenTTL = 15 * time.Minute
+	}
+	if s.refreshTokenTTL <= 0 {
+		s.refreshTokenTTL = 30 * 24 * time.Hour
+	}
+	return s
 }
 
 func (s *userServiceImpl) CreateUser(ctx context.Context, req dto.CreateUserRequest, botAddress string) (*dto.SignUpResponse, error) {
@@ -87,14 +141,19 @@ func (s *userServiceImpl) CreateUser(ctx context.Context, req dto.CreateUserRequ
 	user := models.User{
 		Username:     req.Username,
 		Password:     string(hashedPassword),
-		Email:        req.Email,
-		Phone:        req.Phone,
+		Email:        normalizeEmail(req.Email),
+		Phone:        normalizePhone(req.Phone),
 		FullName:     req.FullName,
 		UserType:     req.UserType,
 		TelegramUser: req.TelegramUser,
 	}
 
-	userID, err := s.userRepo.CreateUser(ctx, user)
+	var userID, joinedApartmentID int
+	if req.InvitationToken == "" {
+		userID, err = s.userRepo.CreateUser(ctx, user)
+	} else {
+		userID, joinedApartmentID, err = s.createInvitedUser(ctx, user, req.InvitationToken)
+	}
 	if err != nil {
 		logger.WithError(err).Error("Failed to create user in database")
 		return nil, fmt.Errorf("failed to create user: %w", err)
@@ -114,17 +173,46 @@ func (s *userServiceImpl) CreateUser(ctx context.Context, req dto.CreateUserRequ
 		},
 		TelegramSetupRequired:     req.TelegramUser != "",
 		TelegramSetupInstructions: "",
+		JoinedApartmentID:         joinedApartmentID,
 	}
 
 	//add bot address hereeeeee
 	if req.TelegramUser != "" {
-		response.TelegramSetupInstructions = "Please start a chat with our bot in Telegram to complete setup : " + botAddress
+		response.TelegramSetupInstructions = "Please get a code on your profile and send it to our bot in Telegram to complete setup : " + botAddress
 		logger.WithField("bot_address", botAddress).Debug("Telegram setup instructions provided")
 	}
 
 	return response, nil
 }
 
+// creates the user and accepts the invitation they signed up with, which
+// has to be sent to their email or phone number. neither happens when the
+// invitation can't be accepted
+func (s *userServiceImpl) createInvitedUser(ctx context.Context, user models.User, token string) (userID, apartmentID int, err error) {
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if userID, err = s.userRepo.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		if apartmentID, err = s.inviteLinkRepo.ValidateAndConsumeInvitation(ctx, token, userID); err != nil {
+			return err
+		}
+		return s.userApartmentRepo.CreateUserApartment(ctx, models.User_apartment{
+			UserID:      userID,
+			ApartmentID: apartmentID,
+			Role:        models.ResidentRole,
+		})
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":      userID,
+		"apartment_id": apartmentID,
+	}).Info("Invited user joined apartment on sign up")
+	return userID, apartmentID, nil
+}
+
 func (s *userServiceImpl) AuthenticateUser(ctx context.Context, req dto.LoginRequest) (*dto.LoginResponse, error) {
 	logger := logrus.WithField("username", req.Username)
 	logger.Info("Authentication attempt")
@@ -144,7 +232,7 @@ func (s *userServiceImpl) AuthenticateUser(ctx context.Context, req dto.LoginReq
 		return nil, fmt.Errorf("invalid username or password")
 	}
 
-	token, err := middleware.GenerateToken(strconv.Itoa(existingUser.ID), existingUser.UserType)
+	tokens, err := s.issueTokens(ctx, existingUser.ID, existingUser.UserType)
 	if err != nil {
 		logger.WithError(err).WithField("user_id", existingUser.ID).Error("Failed to generate authentication token")
 		return nil, fmt.Errorf("failed to generate token: %w", err)
@@ -156,12 +244,14 @@ func (s *userServiceImpl) AuthenticateUser(ctx context.Context, req dto.LoginReq
 	}).Info("Authentication successful")
 
 	response := &dto.LoginResponse{
-		Token:    "Bearer " + token,
-		UserID:   strconv.Itoa(existingUser.ID),
-		UserType: string(existingUser.UserType),
-		Username: existingUser.Username,
-		Email:    existingUser.Email,
-		FullName: existingUser.FullName,
+		Token:        tokens.Token,
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+		UserID:       strconv.Itoa(existingUser.ID),
+		UserType:     string(existingUser.UserType),
+		Username:     existingUser.Username,
+		Email:        existingUser.Email,
+		FullName:     existingUser.FullName,
 		Telegram: dto.TelegramInfo{
 			Username:  existingUser.TelegramUser,
 			Connected: existingUser.TelegramChatID != 0,
@@ -237,10 +327,10 @@ func (s *userServiceImpl) UpdateUserProfile(ctx context.Context, userID int, req
 		existingUser.Username = req.Username
 	}
 	if req.Email != "" {
-		existingUser.Email = req.Email
+		existingUser.Email = normalizeEmail(req.Email)
 	}
 	if req.Phone != "" {
-		existingUser.Phone = req.Phone
+		existingUser.Phone = normalizePhone(req.Phone)
 	}
 	if req.FullName != "" {
 		existingUser.FullName = req.FullName
@@ -320,19 +410,33 @@ func (s *userServiceImpl) DeleteUser(ctx context.Context, userID int) error {
 	logger := logrus.WithField("user_id", userID)
 	logger.Info("Starting user deletion")
 
-	if err := s.userRepo.DeleteUser(userID); err != nil {
-		if err == sql.ErrNoRows {
-			logger.Warn("Attempt to delete non-existent user")
-			return fmt.Errorf("user not found")
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.DeleteUser(ctx, userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				logger.Warn("Attempt to delete non-existent user")
+				return fmt.Errorf("user not found")
+			}
+			logger.WithError(err).Error("Failed to delete user from database")
+			return fmt.Errorf("failed to delete user: %w", err)
 		}
-		logger.WithError(err).Error("Failed to delete user from database")
-		return fmt.Errorf("failed to delete user: %w", err)
-	}
 
-	err := s.userApartmentRepo.DeleteUserFromApartments(userID)
+		if err := s.userApartmentRepo.DeleteUserFromApartments(ctx, userID); err != nil {
+			logger.WithError(err).Error("Failed to remove user from apartments")
+			return fmt.Errorf("failed to remove user from apartments: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		logger.WithError(err).Error("Failed to remove user from apartments")
-		return fmt.Errorf("failed to remove user from apartments: %w", err)
+		return err
+	}
+
+	//the tokens of the user would otherwise keep working until they expire.
+	//revoked once the deletion is committed, so a rolled back one leaves the
+	//user signed in
+	ttl := max(s.accessTokenTTL, s.refreshTokenTTL)
+	if err := s.tokenRepo.RevokeUserTokens(ctx, userID, ttl); err != nil {
+		logger.WithError(err).Error("Failed to revoke tokens of deleted user")
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
 	}
 
 	logger.WithField("user_id", userID).Info("User deleted successfully")
@@ -340,6 +444,263 @@ func (s *userServiceImpl) DeleteUser(ctx context.Context, userID int) error {
 	return nil
 }
 
+// the channels the user is notified on, the default order when they haven't
+// chosen
+func (s *userServiceImpl) GetNotificationPreferences(ctx context.Context, userID int) (*dto.NotificationPreferences, error) {
+	channels, err := s.userRepo.GetNotificationChannels(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification channels: %w", err)
+	}
+	if len(channels) == 0 {
+		channels = models.DefaultNotificationChannels
+	}
+
+	reminders, err := s.userRepo.GetBillReminders(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bill reminders: %w", err)
+	}
+	locale, err := s.userRepo.GetLocale(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locale: %w", err)
+	}
+	return &dto.NotificationPreferences{Channels: channels, BillReminders: &reminders, Locale: &locale}, nil
+}
+
+// replaces the channels the user is notified on and, when given, turns bill
+// reminders on or off and changes their locale. channels left out are never
+// used for them, so at least one is required
+func (s *userServiceImpl) UpdateNotificationPreferences(ctx context.Context, userID int, req dto.NotificationPreferences) (*dto.NotificationPreferences, error) {
+	if len(req.Channels) == 0 {
+		return nil, fmt.Errorf("%w: at least one channel is required", ErrInvalidNotificationChannels)
+	}
+	seen := make(map[models.NotificationChannel]bool, len(req.Channels))
+	for _, channel := range req.Channels {
+		if !channel.IsValid() {
+			return nil, fmt.Errorf("%w: unknown channel %q", ErrInvalidNotificationChannels, channel)
+		}
+		if seen[channel] {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidNotificationChannels, channel)
+		}
+		seen[channel] = true
+	}
+	if req.Locale != nil && !req.Locale.IsValid() {
+		return nil, fmt.Errorf("%w: %q, use fa or en", ErrInvalidLocale, *req.Locale)
+	}
+
+	reminders, locale := req.BillReminders, req.Locale
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.SetNotificationChannels(ctx, userID, req.Channels); err != nil {
+			return err
+		}
+
+		if reminders != nil {
+			if err := s.userRepo.SetBillReminders(ctx, userID, *reminders); err != nil {
+				return err
+			}
+		} else {
+			enabled, err := s.userRepo.GetBillReminders(ctx, userID)
+			if err != nil {
+				return err
+			}
+			reminders = &enabled
+		}
+
+		if locale != nil {
+			return s.userRepo.SetLocale(ctx, userID, *locale)
+		}
+		current, err := s.userRepo.GetLocale(ctx, userID)
+		locale = &current
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to save notification preferences")
+		return nil, fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+	return &dto.NotificationPreferences{Channels: req.Channels, BillReminders: reminders, Locale: locale}, nil
+}
+
+// a code that links the telegram chat it is sent from to the user. it works
+// once and expires after telegramLinkCodeTTL
+func (s *userServiceImpl) CreateTelegramLinkCode(ctx context.Context, userID int, botAddress string) (*dto.TelegramLinkCode, error) {
+	logger := logrus.WithField("user_id", userID)
+
+	if _, err := s.userRepo.GetUserByID(userID); err != nil {
+		logger.WithError(err).Warn("Telegram link code requested for a missing user")
+		return nil, fmt.Errorf("user not found")
+	}
+
+	secret := make([]byte, telegramLinkCodeLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate telegram link code: %w", err)
+	}
+	for i, b := range secret {
+		secret[i] = telegramLinkCodeAlphabet[int(b)%len(telegramLinkCodeAlphabet)]
+	}
+	code := string(secret)
+
+	if err := s.tokenRepo.SaveTelegramLinkCode(ctx, code, userID, telegramLinkCodeTTL); err != nil {
+		logger.WithError(err).Error("Failed to save telegram link code")
+		return nil, fmt.Errorf("failed to create telegram link code: %w", err)
+	}
+
+	logger.Debug("Telegram link code created")
+	return &dto.TelegramLinkCode{
+		Code:         code,
+		ExpiresAt:    time.Now().Add(telegramLinkCodeTTL),
+		Instructions: fmt.Sprintf("Send /link %s to our bot in Telegram within %d minutes: %s", code, int(telegramLinkCodeTTL.Minutes()), botAddress),
+	}, nil
+}
+
+// links the chat the code was sent from to the user who created it. the chat
+// is taken from any account it was linked to before
+func (s *userServiceImpl) LinkTelegramChat(ctx context.Context, code string, chatID int64) (*models.User, error) {
+	userID, err := s.tokenRepo.ConsumeTelegramLinkCode(ctx, strings.ToUpper(strings.TrimSpace(code)))
+	if errors.Is(err, repositories.ErrLinkCodeNotFound) {
+		return nil, ErrInvalidLinkCode
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to link telegram chat: %w", err)
+	}
+	logger := logrus.WithFields(logrus.Fields{"user_id": userID, "chat_id": chatID})
+
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		return s.userRepo.LinkTelegramChat(ctx, userID, chatID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidLinkCode
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to link telegram chat")
+		return nil, fmt.Errorf("failed to link telegram chat: %w", err)
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link telegram chat: %w", err)
+	}
+	logger.Info("Telegram chat linked")
+	return user, nil
+}
+
+// stops notifying the user on telegram and answering their chat until they
+// link it again
+func (s *userServiceImpl) UnlinkTelegramChat(ctx context.Context, userID int) error {
+	err := s.userRepo.UnlinkTelegramChat(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user not found")
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("user_id", userID).Error("Failed to unlink telegram chat")
+		return fmt.Errorf("failed to unlink telegram chat: %w", err)
+	}
+	logrus.WithField("user_id", userID).Info("Telegram chat unlinked")
+	return nil
+}
+
+// swaps a refresh token for a new access and refresh token. the old refresh
+// token and the access token issued with it stop working
+func (s *userServiceImpl) RefreshToken(ctx context.Context, refreshToken string) (*dto.TokenResponse, error) {
+	session, err := s.tokenRepo.ConsumeRefreshToken(ctx, refreshToken)
+	if errors.Is(err, repositories.ErrRefreshTokenNotFound) {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read refresh token")
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	logger := logrus.WithField("user_id", session.UserID)
+
+	revoked, err := s.tokenRepo.IsTokenRevoked(ctx, session.AccessTokenID, session.UserID, session.IssuedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	if revoked {
+		logger.Warn("Refresh attempted with a revoked session")
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	//the user may have been deleted or changed type since the last login
+	user, err := s.userRepo.GetUserByID(session.UserID)
+	if err != nil {
+		logger.WithError(err).Warn("Refresh attempted for a missing user")
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	if err := s.tokenRepo.RevokeToken(ctx, session.AccessTokenID, s.accessTokenTTL); err != nil {
+		logger.WithError(err).Error("Failed to revoke previous access token")
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	tokens, err := s.issueTokens(ctx, user.ID, user.UserType)
+	if err != nil {
+		logger.WithError(err).Error("Failed to generate authentication token")
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	logger.Debug("Token refreshed")
+	return tokens, nil
+}
+
+// revokes the access token of the request and, when given, the refresh
+// token. holding a refresh token is enough to revoke it, an unknown or used
+// one is ignored
+func (s *userServiceImpl) Logout(ctx context.Context, userID int, claims *middleware.CustomClaims, refreshToken string) error {
+	logger := logrus.WithField("user_id", userID)
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if err := s.tokenRepo.RevokeToken(ctx, claims.ID, ttl); err != nil {
+		logger.WithError(err).Error("Failed to revoke access token")
+		return fmt.Errorf("failed to log out: %w", err)
+	}
+
+	if refreshToken != "" {
+		_, err := s.tokenRepo.ConsumeRefreshToken(ctx, refreshToken)
+		if err != nil && !errors.Is(err, repositories.ErrRefreshTokenNotFound) {
+			logger.WithError(err).Error("Failed to revoke refresh token")
+			return fmt.Errorf("failed to log out: %w", err)
+		}
+	}
+
+	logger.Info("User logged out")
+	return nil
+}
+
+// a new access token with its refresh token
+func (s *userServiceImpl) issueTokens(ctx context.Context, userID int, userType models.UserType) (*dto.TokenResponse, error) {
+	token, claims, err := s.keys.GenerateToken(strconv.Itoa(userID), userType, s.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(secret)
+
+	err = s.tokenRepo.SaveRefreshToken(ctx, refreshToken, models.RefreshSession{
+		UserID:        userID,
+		UserType:      userType,
+		AccessTokenID: claims.ID,
+		IssuedAt:      claims.IssuedAt.Time,
+	}, s.refreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TokenResponse{
+		Token:        "Bearer " + token,
+		ExpiresIn:    int(s.accessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
 func isValidTelegramUsername(username string) bool {
 	if len(username) < 5 || len(username) > 32 {
 		return false
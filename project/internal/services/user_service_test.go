@@ -3,13 +3,19 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/config"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/dto"
+	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/http/middleware"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/models"
 	"github.com/nedaZarei/arcaptcha-internship-2025/neda-arcaptcha-internship-2025/internal/repositories"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -126,7 +132,7 @@ func TestUserService_CreateUser(t *testing.T) {
 			mockRepo := &repositories.MockUserRepository{}
 			tt.mockSetup(mockRepo)
 
-			service := NewUserService(mockRepo, nil) // Assuming userApartmentRepo is not needed for this test
+			service := NewUserService(mockRepo, nil, nil, nil, nil, testKeys(t), config.Auth{}) // Assuming userApartmentRepo is not needed for this test
 
 			response, err := service.CreateUser(context.Background(), tt.request, tt.botAddress)
 
@@ -154,6 +160,56 @@ func TestUserService_CreateUser(t *testing.T) {
 	}
 }
 
+func TestUserService_CreateUser_WithInvitation(t *testing.T) {
+	request := dto.CreateUserRequest{
+		Username:        "neighbour",
+		Password:        "password123",
+		Email:           "neighbour@example.com",
+		UserType:        models.Resident,
+		InvitationToken: "abc",
+	}
+
+	tests := []struct {
+		name        string
+		acceptErr   error
+		expectError bool
+	}{
+		{name: "joins the apartment"},
+		{name: "invitation for another email", acceptErr: errors.New("invitation not found or already used"), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositories.MockUserRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockInviteRepo := new(repositories.MockInviteLinkRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+
+			mockRepo.On("GetUserByUsername", "neighbour").Return(nil, sql.ErrNoRows)
+			mockRepo.On("CreateUser", mock.Anything, mock.AnythingOfType("models.User")).Return(9, nil)
+			mockInviteRepo.On("ValidateAndConsumeInvitation", mock.Anything, "abc", 9).Return(2, tt.acceptErr)
+			mockUserAptRepo.On("CreateUserApartment", mock.Anything, mock.MatchedBy(func(ua models.User_apartment) bool {
+				return ua.UserID == 9 && ua.ApartmentID == 2 && ua.Role == models.ResidentRole
+			})).Return(nil)
+
+			service := NewUserService(mockRepo, mockUserAptRepo, nil, mockInviteRepo, mockUOW, testKeys(t), config.Auth{})
+			response, err := service.CreateUser(context.Background(), request, "")
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, response)
+				mockUserAptRepo.AssertNotCalled(t, "CreateUserApartment", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 9, response.User.ID)
+			assert.Equal(t, 2, response.JoinedApartmentID)
+			mockUserAptRepo.AssertExpectations(t)
+		})
+	}
+}
+
 func TestUserService_AuthenticateUser(t *testing.T) {
 	// Create a hashed password for testing
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
@@ -223,7 +279,12 @@ func TestUserService_AuthenticateUser(t *testing.T) {
 			mockRepo := &repositories.MockUserRepository{}
 			tt.mockSetup(mockRepo)
 
-			service := NewUserService(mockRepo, nil)
+			mockTokenRepo := new(repositories.MockTokenRepository)
+			mockTokenRepo.On("SaveRefreshToken", mock.Anything, mock.Anything, mock.MatchedBy(func(session models.RefreshSession) bool {
+				return session.UserID == 1 && session.AccessTokenID != ""
+			}), 720*time.Hour).Return(nil).Maybe()
+
+			service := NewUserService(mockRepo, nil, mockTokenRepo, nil, nil, testKeys(t), config.Auth{})
 
 			response, err := service.AuthenticateUser(context.Background(), tt.request)
 
@@ -235,6 +296,8 @@ func TestUserService_AuthenticateUser(t *testing.T) {
 				assert.NoError(t, err)
 				assert.NotNil(t, response)
 				assert.NotEmpty(t, response.Token)
+				assert.NotEmpty(t, response.RefreshToken)
+				assert.Equal(t, 900, response.ExpiresIn)
 				assert.NotEmpty(t, response.UserID)
 				assert.Equal(t, tt.request.Username, response.Username)
 			}
@@ -284,7 +347,7 @@ func TestUserService_GetUserProfile(t *testing.T) {
 			mockRepo := &repositories.MockUserRepository{}
 			tt.mockSetup(mockRepo)
 
-			service := NewUserService(mockRepo, nil)
+			service := NewUserService(mockRepo, nil, nil, nil, nil, testKeys(t), config.Auth{})
 
 			response, err := service.GetUserProfile(context.Background(), tt.userID)
 
@@ -386,7 +449,7 @@ func TestUserService_UpdateUserProfile(t *testing.T) {
 			mockRepo := &repositories.MockUserRepository{}
 			tt.mockSetup(mockRepo)
 
-			service := NewUserService(mockRepo, nil)
+			service := NewUserService(mockRepo, nil, nil, nil, nil, testKeys(t), config.Auth{})
 
 			response, err := service.UpdateUserProfile(context.Background(), tt.userID, tt.request)
 
@@ -441,7 +504,7 @@ func TestUserService_GetPublicUser(t *testing.T) {
 			mockRepo := &repositories.MockUserRepository{}
 			tt.mockSetup(mockRepo)
 
-			service := NewUserService(mockRepo, nil)
+			service := NewUserService(mockRepo, nil, nil, nil, nil, testKeys(t), config.Auth{})
 
 			response, err := service.GetPublicUser(context.Background(), tt.userID)
 
@@ -490,7 +553,7 @@ func TestUserService_GetAllPublicUsers(t *testing.T) {
 			mockRepo := &repositories.MockUserRepository{}
 			tt.mockSetup(mockRepo)
 
-			service := NewUserService(mockRepo, nil)
+			service := NewUserService(mockRepo, nil, nil, nil, nil, testKeys(t), config.Auth{})
 
 			response, err := service.GetAllPublicUsers(context.Background())
 
@@ -508,6 +571,329 @@ func TestUserService_GetAllPublicUsers(t *testing.T) {
 	}
 }
 
+func TestUserService_RefreshToken(t *testing.T) {
+	session := &models.RefreshSession{
+		UserID:        1,
+		UserType:      models.Resident,
+		AccessTokenID: "old-access",
+		IssuedAt:      time.Now().Add(-time.Hour),
+	}
+
+	tests := []struct {
+		name        string
+		mockSetup   func(*repositories.MockUserRepository, *repositories.MockTokenRepository)
+		expectError string
+	}{
+		{
+			name: "rotates the tokens",
+			mockSetup: func(users *repositories.MockUserRepository, tokens *repositories.MockTokenRepository) {
+				tokens.On("ConsumeRefreshToken", mock.Anything, "refresh").Return(session, nil)
+				tokens.On("IsTokenRevoked", mock.Anything, "old-access", 1, session.IssuedAt).Return(false, nil)
+				users.On("GetUserByID", 1).Return(&models.User{BaseModel: models.BaseModel{ID: 1}, UserType: models.Manager}, nil)
+				tokens.On("RevokeToken", mock.Anything, "old-access", 15*time.Minute).Return(nil)
+				tokens.On("SaveRefreshToken", mock.Anything, mock.Anything, mock.MatchedBy(func(s models.RefreshSession) bool {
+					return s.UserID == 1 && s.UserType == models.Manager && s.AccessTokenID != "old-access"
+				}), 720*time.Hour).Return(nil)
+			},
+		},
+		{
+			name: "used or unknown token",
+			mockSetup: func(users *repositories.MockUserRepository, tokens *repositories.MockTokenRepository) {
+				tokens.On("ConsumeRefreshToken", mock.Anything, "refresh").Return(nil, repositories.ErrRefreshTokenNotFound)
+			},
+			expectError: "invalid refresh token",
+		},
+		{
+			name: "revoked user",
+			mockSetup: func(users *repositories.MockUserRepository, tokens *repositories.MockTokenRepository) {
+				tokens.On("ConsumeRefreshToken", mock.Anything, "refresh").Return(session, nil)
+				tokens.On("IsTokenRevoked", mock.Anything, "old-access", 1, session.IssuedAt).Return(true, nil)
+			},
+			expectError: "invalid refresh token",
+		},
+		{
+			name: "deleted user",
+			mockSetup: func(users *repositories.MockUserRepository, tokens *repositories.MockTokenRepository) {
+				tokens.On("ConsumeRefreshToken", mock.Anything, "refresh").Return(session, nil)
+				tokens.On("IsTokenRevoked", mock.Anything, "old-access", 1, session.IssuedAt).Return(false, nil)
+				users.On("GetUserByID", 1).Return(nil, sql.ErrNoRows)
+			},
+			expectError: "invalid refresh token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &repositories.MockUserRepository{}
+			mockTokenRepo := new(repositories.MockTokenRepository)
+			tt.mockSetup(mockRepo, mockTokenRepo)
+
+			service := NewUserService(mockRepo, nil, mockTokenRepo, nil, nil, testKeys(t), config.Auth{})
+			response, err := service.RefreshToken(context.Background(), "refresh")
+
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				mockTokenRepo.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEmpty(t, response.Token)
+			assert.NotEqual(t, "refresh", response.RefreshToken)
+			mockRepo.AssertExpectations(t)
+			mockTokenRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func testKeys(t *testing.T) *middleware.KeySet {
+	keys, err := middleware.NewKeySet(config.Auth{
+		EncryptionKey: "test-encryption-key",
+		SigningKeys: []config.SigningKey{
+			{ID: "test", Algorithm: "HS256", Secret: "test-secret-that-is-at-least-32-chars"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	return keys
+}
+
+func TestUserService_Logout(t *testing.T) {
+	_, claims, err := testKeys(t).GenerateToken("1", models.Resident, time.Minute)
+	assert.NoError(t, err)
+
+	mockTokenRepo := new(repositories.MockTokenRepository)
+	mockTokenRepo.On("RevokeToken", mock.Anything, claims.ID, mock.MatchedBy(func(ttl time.Duration) bool {
+		return ttl > 0 && ttl <= time.Minute
+	})).Return(nil)
+	mockTokenRepo.On("ConsumeRefreshToken", mock.Anything, "refresh").Return(nil, repositories.ErrRefreshTokenNotFound)
+
+	service := NewUserService(nil, nil, mockTokenRepo, nil, nil, testKeys(t), config.Auth{})
+	assert.NoError(t, service.Logout(context.Background(), 1, claims, "refresh"))
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockSetup   func(*repositories.MockUserRepository, *repositories.MockUserApartmentRepository)
+		expectError string
+	}{
+		{
+			name: "user and memberships deleted, then tokens revoked",
+			mockSetup: func(users *repositories.MockUserRepository, memberships *repositories.MockUserApartmentRepository) {
+				users.On("DeleteUser", mock.Anything, 1).Return(nil)
+				memberships.On("DeleteUserFromApartments", mock.Anything, 1).Return(nil)
+			},
+		},
+		{
+			name: "user not found",
+			mockSetup: func(users *repositories.MockUserRepository, memberships *repositories.MockUserApartmentRepository) {
+				users.On("DeleteUser", mock.Anything, 1).Return(fmt.Errorf("no user found with id 1: %w", sql.ErrNoRows))
+			},
+			expectError: "user not found",
+		},
+		{
+			name: "memberships not removed",
+			mockSetup: func(users *repositories.MockUserRepository, memberships *repositories.MockUserApartmentRepository) {
+				users.On("DeleteUser", mock.Anything, 1).Return(nil)
+				memberships.On("DeleteUserFromApartments", mock.Anything, 1).Return(errors.New("db down"))
+			},
+			expectError: "failed to remove user from apartments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := new(repositories.MockUserRepository)
+			mockUserAptRepo := new(repositories.MockUserApartmentRepository)
+			mockTokenRepo := new(repositories.MockTokenRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+			tt.mockSetup(mockUserRepo, mockUserAptRepo)
+			mockTokenRepo.On("RevokeUserTokens", mock.Anything, 1, mock.Anything).Return(nil).Maybe()
+
+			service := NewUserService(mockUserRepo, mockUserAptRepo, mockTokenRepo, nil, mockUOW, testKeys(t), config.Auth{})
+			err := service.DeleteUser(context.Background(), 1)
+
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				//the deletion rolled back, the user stays signed in
+				mockTokenRepo.AssertNotCalled(t, "RevokeUserTokens", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			mockUserRepo.AssertExpectations(t)
+			mockUserAptRepo.AssertExpectations(t)
+			mockTokenRepo.AssertCalled(t, "RevokeUserTokens", mock.Anything, 1, mock.Anything)
+			mockUOW.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_UpdateNotificationPreferences(t *testing.T) {
+	off := false
+	english, german := models.English, models.Locale("de")
+	tests := []struct {
+		name          string
+		channels      []models.NotificationChannel
+		reminders     *bool
+		locale        *models.Locale
+		expectedErr   error
+		expectedError string
+	}{
+		{name: "email before sms", channels: []models.NotificationChannel{models.EmailChannel, models.SMSChannel}},
+		{name: "turn reminders off", channels: []models.NotificationChannel{models.TelegramChannel}, reminders: &off},
+		{name: "switch to english", channels: []models.NotificationChannel{models.TelegramChannel}, locale: &english},
+		{
+			name:          "unknown locale",
+			channels:      []models.NotificationChannel{models.TelegramChannel},
+			locale:        &german,
+			expectedErr:   ErrInvalidLocale,
+			expectedError: "use fa or en",
+		},
+		{name: "no channels", expectedError: "at least one channel is required"},
+		{
+			name:          "unknown channel",
+			channels:      []models.NotificationChannel{"pigeon"},
+			expectedError: "unknown channel",
+		},
+		{
+			name:          "channel listed twice",
+			channels:      []models.NotificationChannel{models.SMSChannel, models.SMSChannel},
+			expectedError: "listed twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserRepo := new(repositories.MockUserRepository)
+			mockUOW := new(repositories.MockUnitOfWork)
+			mockUOW.On("Do", mock.Anything).Return(nil)
+			mockUserRepo.On("SetNotificationChannels", mock.Anything, 1, tt.channels).Return(nil)
+			if tt.reminders != nil {
+				mockUserRepo.On("SetBillReminders", mock.Anything, 1, *tt.reminders).Return(nil)
+			} else {
+				mockUserRepo.On("GetBillReminders", mock.Anything, 1).Return(true, nil)
+			}
+			if tt.locale != nil {
+				mockUserRepo.On("SetLocale", mock.Anything, 1, *tt.locale).Return(nil)
+			} else {
+				mockUserRepo.On("GetLocale", mock.Anything, 1).Return(models.Persian, nil)
+			}
+
+			service := NewUserService(mockUserRepo, nil, nil, nil, mockUOW, testKeys(t), config.Auth{})
+			preferences, err := service.UpdateNotificationPreferences(context.Background(), 1, dto.NotificationPreferences{
+				Channels:      tt.channels,
+				BillReminders: tt.reminders,
+				Locale:        tt.locale,
+			})
+
+			if tt.expectedError != "" {
+				expectedErr := tt.expectedErr
+				if expectedErr == nil {
+					expectedErr = ErrInvalidNotificationChannels
+				}
+				assert.ErrorIs(t, err, expectedErr)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				mockUserRepo.AssertNotCalled(t, "SetNotificationChannels", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.channels, preferences.Channels)
+			assert.Equal(t, tt.reminders == nil, *preferences.BillReminders)
+			if tt.locale != nil {
+				assert.Equal(t, *tt.locale, *preferences.Locale)
+			} else {
+				assert.Equal(t, models.Persian, *preferences.Locale)
+			}
+			mockUserRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_GetNotificationPreferences_Default(t *testing.T) {
+	mockUserRepo := new(repositories.MockUserRepository)
+	mockUserRepo.On("GetNotificationChannels", mock.Anything, 1).Return([]models.NotificationChannel{}, nil)
+	mockUserRepo.On("GetBillReminders", mock.Anything, 1).Return(true, nil)
+	mockUserRepo.On("GetLocale", mock.Anything, 1).Return(models.Persian, nil)
+
+	service := NewUserService(mockUserRepo, nil, nil, nil, nil, testKeys(t), config.Auth{})
+	preferences, err := service.GetNotificationPreferences(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.DefaultNotificationChannels, preferences.Channels)
+	assert.True(t, *preferences.BillReminders)
+	assert.Equal(t, models.Persian, *preferences.Locale)
+}
+
+func TestUserService_TelegramLink(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{BaseModel: models.BaseModel{ID: 1}, Username: "neda", TelegramChatID: 42}
+
+	t.Run("code is saved for the user", func(t *testing.T) {
+		mockUserRepo := new(repositories.MockUserRepository)
+		mockTokenRepo := new(repositories.MockTokenRepository)
+		mockUserRepo.On("GetUserByID", 1).Return(user, nil)
+		var saved string
+		mockTokenRepo.On("SaveTelegramLinkCode", mock.Anything, mock.AnythingOfType("string"), 1, 10*time.Minute).
+			Run(func(args mock.Arguments) { saved = args.String(1) }).
+			Return(nil)
+
+		service := NewUserService(mockUserRepo, nil, mockTokenRepo, nil, nil, testKeys(t), config.Auth{})
+		code, err := service.CreateTelegramLinkCode(ctx, 1, "https://t.me/testbot")
+
+		require.NoError(t, err)
+		assert.Regexp(t, `^[A-HJ-NP-Z2-9]{8}$`, code.Code)
+		assert.Equal(t, saved, code.Code)
+		assert.Contains(t, code.Instructions, "/link "+code.Code)
+		assert.Contains(t, code.Instructions, "https://t.me/testbot")
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), code.ExpiresAt, time.Minute)
+	})
+
+	t.Run("code links the chat", func(t *testing.T) {
+		mockUserRepo := new(repositories.MockUserRepository)
+		mockTokenRepo := new(repositories.MockTokenRepository)
+		mockUOW := new(repositories.MockUnitOfWork)
+		mockUOW.On("Do", mock.Anything).Return(nil)
+		mockTokenRepo.On("ConsumeTelegramLinkCode", mock.Anything, "K7M2Q9XA").Return(1, nil)
+		mockUserRepo.On("LinkTelegramChat", mock.Anything, 1, int64(42)).Return(nil)
+		mockUserRepo.On("GetUserByID", 1).Return(user, nil)
+
+		service := NewUserService(mockUserRepo, nil, mockTokenRepo, nil, mockUOW, testKeys(t), config.Auth{})
+		linked, err := service.LinkTelegramChat(ctx, " k7m2q9xa ", 42)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, linked.ID)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong, used or expired code", func(t *testing.T) {
+		mockUserRepo := new(repositories.MockUserRepository)
+		mockTokenRepo := new(repositories.MockTokenRepository)
+		mockTokenRepo.On("ConsumeTelegramLinkCode", mock.Anything, "AAAAAAAA").Return(0, repositories.ErrLinkCodeNotFound)
+
+		service := NewUserService(mockUserRepo, nil, mockTokenRepo, nil, nil, testKeys(t), config.Auth{})
+		_, err := service.LinkTelegramChat(ctx, "AAAAAAAA", 42)
+
+		assert.ErrorIs(t, err, ErrInvalidLinkCode)
+		mockUserRepo.AssertNotCalled(t, "LinkTelegramChat", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unlink", func(t *testing.T) {
+		mockUserRepo := new(repositories.MockUserRepository)
+		mockUserRepo.On("UnlinkTelegramChat", mock.Anything, 1).Return(nil)
+		mockUserRepo.On("UnlinkTelegramChat", mock.Anything, 2).Return(sql.ErrNoRows)
+
+		service := NewUserService(mockUserRepo, nil, nil, nil, nil, testKeys(t), config.Auth{})
+
+		assert.NoError(t, service.UnlinkTelegramChat(ctx, 1))
+		assert.EqualError(t, service.UnlinkTelegramChat(ctx, 2), "user not found")
+	})
+}
+
 func TestIsValidTelegramUsername(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS payments;
+DROP TABLE IF EXISTS bills;
+DROP TABLE IF EXISTS user_apartments;
+DROP TABLE IF EXISTS apartments;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,59 @@
+-- the schema as the baseline repositories created it on start. tables are
+-- only created when missing, so databases set up that way are adopted as they
+-- are and brought up to date by the migrations after it
+CREATE TABLE IF NOT EXISTS users(
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) UNIQUE NOT NULL,
+    password VARCHAR(2000) NOT NULL,
+    email VARCHAR(100) UNIQUE NOT NULL,
+    phone VARCHAR(20) UNIQUE NOT NULL,
+    full_name VARCHAR(100) NOT NULL,
+    user_type VARCHAR(20) NOT NULL,
+    telegram_user VARCHAR(100) UNIQUE,
+    telegram_chat_id BIGINT DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS apartments(
+    id SERIAL PRIMARY KEY,
+    apartment_name VARCHAR(100) NOT NULL,
+    address TEXT NOT NULL,
+    units_count INTEGER NOT NULL,
+    manager_id INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_apartments(
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    apartment_id INTEGER REFERENCES apartments(id) ON DELETE CASCADE,
+    is_manager BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, apartment_id)
+);
+
+CREATE TABLE IF NOT EXISTS bills(
+    id SERIAL PRIMARY KEY,
+    apartment_id INTEGER NOT NULL REFERENCES apartments(id),
+    bill_type VARCHAR(50) NOT NULL,
+    total_amount DECIMAL(10,2) NOT NULL,
+    due_date DATE NOT NULL,
+    billing_deadline DATE,
+    description TEXT,
+    image_url VARCHAR(2000),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS payments(
+    id SERIAL PRIMARY KEY,
+    bill_id INTEGER REFERENCES bills(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(12, 2) NOT NULL,
+    paid_at TIMESTAMP WITH TIME ZONE,
+    payment_status VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
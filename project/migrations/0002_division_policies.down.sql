@@ -0,0 +1,6 @@
+ALTER TABLE user_apartments
+    DROP COLUMN IF EXISTS share_percent,
+    DROP COLUMN IF EXISTS occupants_count,
+    DROP COLUMN IF EXISTS unit_area;
+
+ALTER TABLE apartments DROP COLUMN IF EXISTS division_policy;
@@ -0,0 +1,9 @@
+-- how an apartment divides its bills, and what each member's share is
+-- weighted by under the area, occupants and custom policies
+ALTER TABLE apartments
+    ADD COLUMN IF NOT EXISTS division_policy VARCHAR(20) NOT NULL DEFAULT 'equal';
+
+ALTER TABLE user_apartments
+    ADD COLUMN IF NOT EXISTS unit_area DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS occupants_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS share_percent DECIMAL(5,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+ALTER TABLE payments DROP COLUMN IF EXISTS currency;
+ALTER TABLE bills DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE payments ALTER COLUMN amount TYPE DECIMAL(12, 2) USING amount/100.0;
+ALTER TABLE bills ALTER COLUMN total_amount TYPE DECIMAL(10,2) USING total_amount/100.0;
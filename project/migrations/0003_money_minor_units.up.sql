@@ -0,0 +1,17 @@
+-- amounts were DECIMAL major units and are BIGINT minor units now, in the
+-- currency next to them. columns that already are BIGINT were created that
+-- way and are left alone, converting them again would multiply them by 100
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'bills' AND column_name = 'total_amount') = 'numeric' THEN
+        ALTER TABLE bills ALTER COLUMN total_amount TYPE BIGINT USING (total_amount*100)::bigint;
+    END IF;
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'payments' AND column_name = 'amount') = 'numeric' THEN
+        ALTER TABLE payments ALTER COLUMN amount TYPE BIGINT USING (amount*100)::bigint;
+    END IF;
+END $$;
+
+ALTER TABLE bills ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'IRR';
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'IRR';
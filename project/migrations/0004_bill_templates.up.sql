@@ -0,0 +1,17 @@
+-- recurring bills the scheduler generates when next_run_at comes
+CREATE TABLE IF NOT EXISTS bill_templates(
+    id SERIAL PRIMARY KEY,
+    apartment_id INTEGER NOT NULL REFERENCES apartments(id) ON DELETE CASCADE,
+    bill_type VARCHAR(50) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IRR',
+    amount_formula VARCHAR(20) NOT NULL DEFAULT 'fixed',
+    cadence VARCHAR(20) NOT NULL DEFAULT 'monthly',
+    due_day_offset INTEGER NOT NULL DEFAULT 0,
+    description TEXT,
+    auto_divide BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,21 @@
+-- the payments started with a provider, one per checkout of one or more
+-- payments
+CREATE TABLE IF NOT EXISTS payment_transactions(
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    payment_ids INTEGER[] NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    reference VARCHAR(255),
+    redirect_url TEXT,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IRR',
+    refunded_amount BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(50) NOT NULL,
+    tracking_code VARCHAR(255),
+    idempotency_key VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, idempotency_key)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS payment_transactions_reference_idx
+    ON payment_transactions(provider, reference) WHERE reference IS NOT NULL;
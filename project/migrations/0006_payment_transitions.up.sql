@@ -0,0 +1,12 @@
+-- every change of a payment's status, with who made it and why
+CREATE TABLE IF NOT EXISTS payment_transitions(
+    id SERIAL PRIMARY KEY,
+    payment_id INTEGER REFERENCES payments(id) ON DELETE CASCADE,
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IRR',
+    actor VARCHAR(100) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
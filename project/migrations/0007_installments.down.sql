@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS installments;
+ALTER TABLE payments DROP COLUMN IF EXISTS paid_amount;
@@ -0,0 +1,16 @@
+-- what was paid of a share so far, and the installments it can be split in
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS paid_amount BIGINT NOT NULL DEFAULT 0;
+-- shares settled before it was tracked were paid in full
+UPDATE payments SET paid_amount = amount
+    WHERE paid_amount = 0 AND payment_status IN ('paid', 'partially_refunded', 'refunded', 'chargeback');
+
+CREATE TABLE IF NOT EXISTS installments(
+    id SERIAL PRIMARY KEY,
+    payment_id INTEGER REFERENCES payments(id) ON DELETE CASCADE,
+    sequence INTEGER NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IRR',
+    due_date DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(payment_id, sequence)
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS late_fee_events;
+DROP TABLE IF EXISTS late_fee_rules;
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS late_fee_waived,
+    DROP COLUMN IF EXISTS late_fee;
@@ -0,0 +1,30 @@
+-- the late fee charged on a share and whether it was waived, the rule of
+-- each apartment and the history of charges and waivers
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS late_fee BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS late_fee_waived BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS late_fee_rules(
+    id SERIAL PRIMARY KEY,
+    apartment_id INTEGER NOT NULL UNIQUE REFERENCES apartments(id) ON DELETE CASCADE,
+    fee_type VARCHAR(20) NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IRR',
+    rate_basis_points BIGINT NOT NULL DEFAULT 0,
+    grace_days INTEGER NOT NULL DEFAULT 0,
+    cap BIGINT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS late_fee_events(
+    id SERIAL PRIMARY KEY,
+    payment_id INTEGER REFERENCES payments(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IRR',
+    actor VARCHAR(100) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,14 @@
+-- payment_id is kept when the payment goes away with its bill, the entries
+-- stay the resident's history
+CREATE TABLE IF NOT EXISTS ledger_entries(
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    apartment_id INTEGER NOT NULL REFERENCES apartments(id) ON DELETE CASCADE,
+    payment_id INTEGER,
+    entry_type VARCHAR(20) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IRR',
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS ledger_entries_account_idx ON ledger_entries (user_id, apartment_id, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE user_apartments
+    DROP COLUMN IF EXISTS permissions,
+    DROP COLUMN IF EXISTS role;
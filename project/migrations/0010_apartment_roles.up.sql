@@ -0,0 +1,11 @@
+-- every member gets a role in the apartment, managers become owners of the
+-- apartments they created and managers of the rest. permissions overrides
+-- the role's defaults when it isn't 0
+ALTER TABLE user_apartments
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'resident',
+    ADD COLUMN permissions INTEGER NOT NULL DEFAULT 0;
+
+UPDATE user_apartments ua
+SET role = CASE WHEN ua.user_id = a.manager_id THEN 'owner' ELSE 'manager' END
+FROM apartments a
+WHERE a.id = ua.apartment_id AND ua.is_manager;
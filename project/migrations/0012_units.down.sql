@@ -0,0 +1,8 @@
+ALTER TABLE user_apartments
+    DROP COLUMN IF EXISTS unit_relation,
+    DROP COLUMN IF EXISTS unit_id;
+
+DROP TABLE IF EXISTS units;
+
+UPDATE apartments SET division_policy = 'equal'
+WHERE division_policy IN ('unit', 'unit_area', 'unit_occupants');
@@ -0,0 +1,19 @@
+-- the flats of an apartment building. members are attached to the unit they
+-- own or live in, and per unit division policies charge units instead of
+-- members
+CREATE TABLE IF NOT EXISTS units(
+    id SERIAL PRIMARY KEY,
+    apartment_id INTEGER NOT NULL REFERENCES apartments(id) ON DELETE CASCADE,
+    number VARCHAR(20) NOT NULL,
+    floor INTEGER NOT NULL DEFAULT 0,
+    area DECIMAL(10,2) NOT NULL DEFAULT 0,
+    occupants_count INTEGER NOT NULL DEFAULT 0,
+    parking_spots INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (apartment_id, number)
+);
+
+ALTER TABLE user_apartments
+    ADD COLUMN unit_id INTEGER REFERENCES units(id) ON DELETE SET NULL,
+    ADD COLUMN unit_relation VARCHAR(20) NOT NULL DEFAULT '';
@@ -0,0 +1,19 @@
+-- invitations of users to apartments, replacing the codes kept in redis. an
+-- apartment has at most one open invitation per user
+CREATE TABLE IF NOT EXISTS invitation_links(
+    id SERIAL PRIMARY KEY,
+    sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    receiver_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    apartment_id INTEGER NOT NULL REFERENCES apartments(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    notification_sent_at TIMESTAMP WITH TIME ZONE,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS invitation_links_open_idx
+    ON invitation_links (apartment_id, receiver_id) WHERE status IN ('pending', 'notified');
+CREATE INDEX IF NOT EXISTS invitation_links_receiver_idx ON invitation_links (receiver_id);
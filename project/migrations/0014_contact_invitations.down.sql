@@ -0,0 +1,7 @@
+DELETE FROM invitation_links WHERE receiver_id IS NULL;
+DROP INDEX IF EXISTS invitation_links_open_phone_idx;
+DROP INDEX IF EXISTS invitation_links_open_email_idx;
+ALTER TABLE invitation_links DROP CONSTRAINT IF EXISTS invitation_links_receiver_check;
+ALTER TABLE invitation_links DROP COLUMN IF EXISTS receiver_phone;
+ALTER TABLE invitation_links DROP COLUMN IF EXISTS receiver_email;
+ALTER TABLE invitation_links ALTER COLUMN receiver_id SET NOT NULL;
@@ -0,0 +1,14 @@
+-- invitations to people who haven't signed up yet are addressed to an email
+-- or phone number and get their receiver when they accept
+ALTER TABLE invitation_links ALTER COLUMN receiver_id DROP NOT NULL;
+ALTER TABLE invitation_links ADD COLUMN IF NOT EXISTS receiver_email VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE invitation_links ADD COLUMN IF NOT EXISTS receiver_phone VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE invitation_links ADD CONSTRAINT invitation_links_receiver_check
+    CHECK (receiver_id IS NOT NULL OR receiver_email <> '' OR receiver_phone <> '');
+
+CREATE UNIQUE INDEX IF NOT EXISTS invitation_links_open_email_idx
+    ON invitation_links (apartment_id, receiver_email)
+    WHERE status IN ('pending', 'notified') AND receiver_id IS NULL AND receiver_email <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS invitation_links_open_phone_idx
+    ON invitation_links (apartment_id, receiver_phone)
+    WHERE status IN ('pending', 'notified') AND receiver_id IS NULL AND receiver_phone <> '';
@@ -0,0 +1,3 @@
+-- the channels a user wants to be notified on, in the order they are tried.
+-- empty means the default order
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notification_channels TEXT[] NOT NULL DEFAULT '{}';
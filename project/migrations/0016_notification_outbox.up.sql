@@ -0,0 +1,21 @@
+-- notifications written together with the change they tell about and
+-- delivered by the outbox worker
+CREATE TABLE IF NOT EXISTS notification_outbox(
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    apartment_id INTEGER REFERENCES apartments(id) ON DELETE CASCADE,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS notification_outbox_due_idx
+    ON notification_outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS notification_outbox_dead_idx
+    ON notification_outbox (apartment_id) WHERE status = 'dead';
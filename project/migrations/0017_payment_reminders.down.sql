@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payment_reminders;
+ALTER TABLE users DROP COLUMN IF EXISTS bill_reminders;
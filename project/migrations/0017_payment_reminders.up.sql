@@ -0,0 +1,11 @@
+-- residents are reminded of their unpaid shares unless they turned it off
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bill_reminders BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- the reminders sent for a payment, one per offset in days from its bill's
+-- due date, so none is sent twice
+CREATE TABLE IF NOT EXISTS payment_reminders(
+    payment_id INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    offset_days INTEGER NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (payment_id, offset_days)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification_templates;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
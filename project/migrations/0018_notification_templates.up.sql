@@ -0,0 +1,15 @@
+-- the language notifications are written in for the user
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(5) NOT NULL DEFAULT 'fa';
+
+-- an apartment's own wording of a notification, used instead of the built-in
+-- template of the same name and locale
+CREATE TABLE IF NOT EXISTS notification_templates(
+    apartment_id INTEGER NOT NULL REFERENCES apartments(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    locale VARCHAR(5) NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (apartment_id, name, locale)
+);
@@ -0,0 +1,2 @@
+-- the chats unlinked by the up migration stay unlinked
+DROP INDEX IF EXISTS users_telegram_chat_id_key;
@@ -0,0 +1,5 @@
+-- chats were linked to whichever account named the sender's telegram
+-- username, they are linked again with a code from the profile
+UPDATE users SET telegram_chat_id = 0 WHERE telegram_chat_id <> 0;
+
+CREATE UNIQUE INDEX users_telegram_chat_id_key ON users (telegram_chat_id) WHERE telegram_chat_id <> 0;
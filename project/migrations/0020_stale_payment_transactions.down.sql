@@ -0,0 +1 @@
+DROP INDEX IF EXISTS payment_transactions_initiated_idx;
@@ -0,0 +1,3 @@
+-- the scheduler looks for transactions left initiated to expire them
+CREATE INDEX IF NOT EXISTS payment_transactions_initiated_idx
+    ON payment_transactions(created_at) WHERE status = 'initiated';
@@ -0,0 +1 @@
+ALTER TABLE payment_transactions DROP COLUMN IF EXISTS payment_amounts;
@@ -0,0 +1,4 @@
+-- what each payment of a transaction was charged, in the order of
+-- payment_ids, so a batch is split the way it was priced. empty for
+-- transactions started before
+ALTER TABLE payment_transactions ADD COLUMN IF NOT EXISTS payment_amounts BIGINT[] NOT NULL DEFAULT '{}';
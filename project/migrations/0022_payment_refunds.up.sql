@@ -0,0 +1,22 @@
+-- refunds of payments, recorded pending before the provider is called so
+-- that concurrent refunds cannot together exceed what was paid
+CREATE TABLE IF NOT EXISTS payment_refunds(
+    id SERIAL PRIMARY KEY,
+    payment_id INTEGER NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'IRR',
+    to_credit BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    actor VARCHAR(100) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS payment_refunds_payment_idx ON payment_refunds (payment_id);
+
+-- refunds made so far are only on the payment transitions
+INSERT INTO payment_refunds (payment_id, amount, currency, status, actor, reason, created_at, updated_at)
+SELECT payment_id, amount, currency, 'completed', actor, reason, created_at, created_at
+FROM payment_transitions
+WHERE to_status IN ('partially_refunded', 'refunded') AND payment_id IS NOT NULL;
@@ -0,0 +1,9 @@
+// Package migrations holds the database schema as ordered SQL files, named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, compiled into the
+// binary
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS